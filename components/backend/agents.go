@@ -66,11 +66,13 @@ func readAllAgentYAMLs(dir string) ([]yamlAgent, error) {
 	return out, nil
 }
 
+// GET /api/projects/:projectName/agents
+// listAgents returns the agent personas available to RFE workflows.
 func listAgents(c *gin.Context) {
 	dir := resolveAgentsDir()
 	agents, err := readAllAgentYAMLs(dir)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to read agents: %v", err)})
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to read agents: %v", err))
 		return
 	}
 	resp := make([]agentSummary, 0, len(agents))
@@ -90,19 +92,21 @@ func listAgents(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// GET /api/projects/:projectName/agents/:persona/markdown
+// getAgentMarkdown returns a persona's prompt as markdown.
 func getAgentMarkdown(c *gin.Context) {
 	persona := c.Param("persona")
 	if persona == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "persona required"})
+		respondError(c, http.StatusBadRequest, "persona required")
 		return
 	}
 	md, err := renderAgentMarkdownContent(persona)
 	if err != nil {
 		if strings.Contains(strings.ToLower(err.Error()), "not found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "persona not found"})
+			respondError(c, http.StatusNotFound, "persona not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to render agent markdown: %v", err)})
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("failed to render agent markdown: %v", err))
 		return
 	}
 	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(md))
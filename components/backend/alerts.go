@@ -0,0 +1,390 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Alerting triggers start diagnostic sessions for incidents. One endpoint per
+// source serves every project: ProjectSettings spec.triggers.alertmanager
+// matches alert labels and spec.triggers.pagerduty matches service IDs, and each
+// matching project authenticates the request itself, with one of its webhook
+// API keys (Alertmanager) or the subscription's signing secret stored in its
+// ambient-pagerduty-trigger Secret (PagerDuty).
+const (
+	pagerDutyTriggerSecret    = "ambient-pagerduty-trigger"
+	pagerDutyTriggerSecretKey = "signingSecret"
+	// maxAlertsInPrompt bounds how many alerts of a group are described
+	maxAlertsInPrompt = 20
+)
+
+// alertmanagerPayload is the Alertmanager webhook body (version 4)
+type alertmanagerPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     string            `json:"startsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+		Fingerprint  string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// pagerDutyWebhook is the part of a PagerDuty V3 webhook the trigger reads
+type pagerDutyWebhook struct {
+	Event struct {
+		ID         string `json:"id"`
+		EventType  string `json:"event_type"`
+		OccurredAt string `json:"occurred_at"`
+		Data       struct {
+			ID      string `json:"id"`
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+			Urgency string `json:"urgency"`
+			Service struct {
+				ID      string `json:"id"`
+				Summary string `json:"summary"`
+			} `json:"service"`
+		} `json:"data"`
+	} `json:"event"`
+}
+
+// alertLabelsMatch reports whether every label in spec.triggers.alertmanager
+// matchLabels has the same value among the group's common labels
+func alertLabelsMatch(cfg map[string]interface{}, labels map[string]string) bool {
+	match, _ := cfg["matchLabels"].(map[string]interface{})
+	for k, v := range match {
+		if want, _ := v.(string); labels[k] != want {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// triggerPrompt places the project's instructions, or a default request to
+// triage, before the incident details
+func triggerPrompt(cfg map[string]interface{}, details string) string {
+	instructions, _ := cfg["instructions"].(string)
+	if strings.TrimSpace(instructions) == "" {
+		instructions = "Triage the incident below: find the likely cause from the cluster state, logs and recent changes, and recommend a fix. Do not make changes."
+	}
+	return strings.TrimSpace(instructions) + "\n\n" + details
+}
+
+func truncateDisplayName(s string) string {
+	if r := []rune(s); len(r) > 80 {
+		return string(r[:79]) + "…"
+	}
+	return s
+}
+
+// alertmanagerDetails describes the firing alerts for the prompt
+func alertmanagerDetails(p alertmanagerPayload) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Alertmanager alert group %s is firing.\n", p.GroupKey)
+	for _, k := range sortedKeys(p.CommonLabels) {
+		fmt.Fprintf(&b, "- %s: %s\n", k, p.CommonLabels[k])
+	}
+	for _, k := range sortedKeys(p.CommonAnnotations) {
+		fmt.Fprintf(&b, "- %s: %s\n", k, p.CommonAnnotations[k])
+	}
+	shown := 0
+	for _, a := range p.Alerts {
+		if a.Status != "firing" {
+			continue
+		}
+		if shown == maxAlertsInPrompt {
+			b.WriteString("\nFurther alerts omitted.\n")
+			break
+		}
+		shown++
+		fmt.Fprintf(&b, "\nAlert %d, firing since %s:\n", shown, a.StartsAt)
+		for _, k := range sortedKeys(a.Labels) {
+			if _, common := p.CommonLabels[k]; !common {
+				fmt.Fprintf(&b, "- %s: %s\n", k, a.Labels[k])
+			}
+		}
+		for _, k := range sortedKeys(a.Annotations) {
+			if _, common := p.CommonAnnotations[k]; !common {
+				fmt.Fprintf(&b, "- %s: %s\n", k, a.Annotations[k])
+			}
+		}
+		if a.GeneratorURL != "" {
+			fmt.Fprintf(&b, "- source: %s\n", a.GeneratorURL)
+		}
+	}
+	return b.String()
+}
+
+// alertmanagerDeliveryID identifies the set of firing alerts, so Alertmanager's
+// repeat notifications for an unchanged group reuse the first session
+func alertmanagerDeliveryID(p alertmanagerPayload) string {
+	var firing []string
+	for _, a := range p.Alerts {
+		if a.Status == "firing" {
+			firing = append(firing, a.Fingerprint+"@"+a.StartsAt)
+		}
+	}
+	sort.Strings(firing)
+	sum := sha256.Sum256([]byte(p.GroupKey + "\n" + strings.Join(firing, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// POST /api/webhooks/alertmanager
+// alertmanagerTrigger creates a diagnostic session for a firing alert group in
+// every project whose spec.triggers.alertmanager.matchLabels match the group's
+// common labels and that accepts the webhook API key sent as the bearer token.
+// Resolved notifications are ignored, and repeat notifications for the same
+// firing alerts return the session created for the first one. With a webhook
+// queue the sessions are queued and the response is 202.
+func alertmanagerTrigger(c *gin.Context) {
+	body, ok := readWebhookPayload(c, "alertmanager")
+	if !ok {
+		return
+	}
+	var payload alertmanagerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid Alertmanager payload")
+		return
+	}
+	if payload.Status != "firing" {
+		c.JSON(http.StatusOK, TriggerResponse{Sessions: []TriggeredSession{}, Message: "Only firing alerts start sessions"})
+		return
+	}
+
+	triggers, err := enabledTriggers(c.Request.Context(), "alertmanager")
+	if err != nil {
+		logErrorf(c, "Failed to read Alertmanager triggers: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	dyn, err := triggerClient()
+	if err != nil {
+		logErrorf(c, "Failed to create trigger client: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	key := bearerTokenFromRequest(c)
+	var matched, authenticated []triggerConfig
+	for _, t := range triggers {
+		if !alertLabelsMatch(t.Config, payload.CommonLabels) {
+			continue
+		}
+		matched = append(matched, t)
+		if _, err := verifyWebhookAPIKey(c.Request.Context(), dyn, t.Project, key); err == nil {
+			authenticated = append(authenticated, t)
+		}
+	}
+	if len(matched) > 0 && len(authenticated) == 0 {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing webhook API key")
+		return
+	}
+
+	details := alertmanagerDetails(payload)
+	alertname := payload.CommonLabels["alertname"]
+	if alertname == "" {
+		alertname = payload.GroupKey
+	}
+	auditDetail(c, "alertmanager", gin.H{"groupKey": payload.GroupKey, "receiver": payload.Receiver, "alerts": len(payload.Alerts)})
+	resp := TriggerResponse{Sessions: []TriggeredSession{}}
+	for _, t := range authenticated {
+		req, err := triggerRequest(t.Config)
+		if err != nil {
+			logWarnf(c, "Alertmanager trigger in %s: %v", t.Project, err)
+			resp.Sessions = append(resp.Sessions, TriggeredSession{Project: t.Project, Error: "invalid spec.triggers.alertmanager.session"})
+			continue
+		}
+		req.Prompt = triggerPrompt(t.Config, details)
+		if req.DisplayName == "" {
+			req.DisplayName = truncateDisplayName("Alert: " + alertname)
+		}
+		req.Trigger = &SessionTrigger{
+			Source:     "alertmanager",
+			Event:      "firing",
+			Incident:   payload.GroupKey,
+			DeliveryID: alertmanagerDeliveryID(payload),
+		}
+		resp.Sessions = append(resp.Sessions, startTrigger(c, t, req))
+	}
+	if len(matched) == 0 {
+		resp.Message = "No project's Alertmanager trigger matches these labels"
+	}
+	if anyTriggerQueued(resp) {
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// verifyPagerDutySignature checks X-PagerDuty-Signature, which lists one
+// v1=<hex HMAC-SHA256 of the body> per signing secret while a secret rotates
+func verifyPagerDutySignature(body []byte, header, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, part := range strings.Split(header, ",") {
+		sig, ok := strings.CutPrefix(strings.TrimSpace(part), "v1=")
+		if !ok {
+			continue
+		}
+		if got, err := hex.DecodeString(sig); err == nil && hmac.Equal(got, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// pagerDutyServiceMatch reports whether spec.triggers.pagerduty.serviceIds lists id
+func pagerDutyServiceMatch(cfg map[string]interface{}, id string) bool {
+	ids, _ := cfg["serviceIds"].([]interface{})
+	for _, raw := range ids {
+		if s, _ := raw.(string); s != "" && s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// pagerDutyDetails describes the incident for the prompt
+func pagerDutyDetails(w pagerDutyWebhook) string {
+	d := w.Event.Data
+	var b strings.Builder
+	fmt.Fprintf(&b, "PagerDuty incident #%d was triggered at %s.\n", d.Number, w.Event.OccurredAt)
+	fmt.Fprintf(&b, "- title: %s\n", d.Title)
+	fmt.Fprintf(&b, "- service: %s (%s)\n", d.Service.Summary, d.Service.ID)
+	if d.Urgency != "" {
+		fmt.Fprintf(&b, "- urgency: %s\n", d.Urgency)
+	}
+	if d.HTMLURL != "" {
+		fmt.Fprintf(&b, "- incident: %s\n", d.HTMLURL)
+	}
+	return b.String()
+}
+
+// POST /api/webhooks/pagerduty
+// pagerDutyTrigger creates a diagnostic session for a triggered PagerDuty
+// incident in every project whose spec.triggers.pagerduty.serviceIds list the
+// incident's service and whose signing secret verifies the request. Other event
+// types are acknowledged and ignored. With a webhook queue the sessions are
+// queued and the response is 202.
+func pagerDutyTrigger(c *gin.Context) {
+	body, ok := readWebhookPayload(c, "pagerduty")
+	if !ok {
+		return
+	}
+	var webhook pagerDutyWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid PagerDuty webhook")
+		return
+	}
+	if webhook.Event.EventType != "incident.triggered" {
+		c.JSON(http.StatusOK, TriggerResponse{Sessions: []TriggeredSession{}, Message: "Only incident.triggered events start sessions"})
+		return
+	}
+	var envelope struct {
+		Event struct {
+			Data interface{} `json:"data"`
+		} `json:"event"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	if fieldErrors := validateWebhookValue("pagerduty-incident", envelope.Event.Data); len(fieldErrors) > 0 {
+		for i := range fieldErrors {
+			if fieldErrors[i].Field == "(root)" {
+				fieldErrors[i].Field = "event.data"
+			} else {
+				fieldErrors[i].Field = "event.data." + fieldErrors[i].Field
+			}
+		}
+		respondInvalidWebhook(c, "pagerduty", fieldErrors)
+		return
+	}
+	incident := webhook.Event.Data
+
+	triggers, err := enabledTriggers(c.Request.Context(), "pagerduty")
+	if err != nil {
+		logErrorf(c, "Failed to read PagerDuty triggers: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	signature := c.GetHeader("X-PagerDuty-Signature")
+	var matched, authenticated []triggerConfig
+	for _, t := range triggers {
+		if !pagerDutyServiceMatch(t.Config, incident.Service.ID) {
+			continue
+		}
+		matched = append(matched, t)
+		sec, err := k8sClient.CoreV1().Secrets(t.Project).Get(c.Request.Context(), pagerDutyTriggerSecret, v1.GetOptions{})
+		if err != nil {
+			logWarnf(c, "PagerDuty trigger in %s has no readable %s Secret: %v", t.Project, pagerDutyTriggerSecret, err)
+			continue
+		}
+		if verifyPagerDutySignature(body, signature, string(sec.Data[pagerDutyTriggerSecretKey])) {
+			authenticated = append(authenticated, t)
+		}
+	}
+	if len(matched) > 0 && len(authenticated) == 0 {
+		respondError(c, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	details := pagerDutyDetails(webhook)
+	auditDetail(c, "pagerduty", gin.H{"incident": incident.ID, "service": incident.Service.ID, "eventId": webhook.Event.ID})
+	resp := TriggerResponse{Sessions: []TriggeredSession{}}
+	for _, t := range authenticated {
+		req, err := triggerRequest(t.Config)
+		if err != nil {
+			logWarnf(c, "PagerDuty trigger in %s: %v", t.Project, err)
+			resp.Sessions = append(resp.Sessions, TriggeredSession{Project: t.Project, Error: "invalid spec.triggers.pagerduty.session"})
+			continue
+		}
+		req.Prompt = triggerPrompt(t.Config, details)
+		if req.DisplayName == "" {
+			req.DisplayName = truncateDisplayName("Incident: " + incident.Title)
+		}
+		req.Trigger = &SessionTrigger{
+			Source:     "pagerduty",
+			Event:      webhook.Event.EventType,
+			Incident:   incident.ID,
+			DeliveryID: webhook.Event.ID,
+		}
+		resp.Sessions = append(resp.Sessions, startTrigger(c, t, req))
+	}
+	if len(matched) == 0 {
+		resp.Message = "No project's PagerDuty trigger lists this service"
+	}
+	if anyTriggerQueued(resp) {
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
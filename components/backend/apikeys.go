@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// Webhook API keys look like vtk_<id>_<secret>. ProjectSettings spec.webhookAuth.apiKeys
+// stores only the id, a random salt and sha256(salt || secret); the plaintext key is
+// returned once, when it is minted.
+const (
+	webhookKeyPrefix     = "vtk_"
+	webhookKeyIDBytes    = 6
+	webhookKeySecretSize = 32
+	webhookKeySaltSize   = 16
+)
+
+var (
+	errWebhookKeyInvalid  = fmt.Errorf("invalid or revoked API key")
+	errWebhookKeyNotFound = fmt.Errorf("API key not found")
+)
+
+// WebhookAPIKey is a stored key entry as returned by the API (never the secret or hash)
+type WebhookAPIKey struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	CreatedAt   string `json:"createdAt,omitempty"`
+	CreatedBy   string `json:"createdBy,omitempty"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+	RotatedFrom string `json:"rotatedFrom,omitempty"`
+}
+
+type CreateWebhookAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	// TTLSeconds optionally expires the key; 0 means no expiry
+	TTLSeconds int64 `json:"ttlSeconds,omitempty"`
+}
+
+type RotateWebhookAPIKeyRequest struct {
+	// GracePeriodSeconds keeps the old key valid for this long; 0 revokes it immediately
+	GracePeriodSeconds int64 `json:"gracePeriodSeconds,omitempty"`
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+func hashWebhookKeySecret(salt []byte, secret string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return base64.RawStdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// webhookKeyFingerprint identifies a key in listings and audit records without
+// revealing it
+func webhookKeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// mintWebhookAPIKey returns the plaintext key and the entry to store for it
+func mintWebhookAPIKey(name, createdBy string, ttlSeconds int64) (string, map[string]interface{}, error) {
+	idBytes, err := randomBytes(webhookKeyIDBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	secretBytes, err := randomBytes(webhookKeySecretSize)
+	if err != nil {
+		return "", nil, err
+	}
+	salt, err := randomBytes(webhookKeySaltSize)
+	if err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	key := webhookKeyPrefix + id + "_" + secret
+
+	now := time.Now().UTC()
+	entry := map[string]interface{}{
+		"id":          id,
+		"name":        name,
+		"fingerprint": webhookKeyFingerprint(key),
+		"salt":        base64.RawStdEncoding.EncodeToString(salt),
+		"hash":        hashWebhookKeySecret(salt, secret),
+		"createdAt":   now.Format(time.RFC3339),
+		"createdBy":   createdBy,
+	}
+	if ttlSeconds > 0 {
+		entry["expiresAt"] = now.Add(time.Duration(ttlSeconds) * time.Second).Format(time.RFC3339)
+	}
+	return key, entry, nil
+}
+
+func webhookAPIKeyFromEntry(entry map[string]interface{}) WebhookAPIKey {
+	k := WebhookAPIKey{}
+	k.ID, _ = entry["id"].(string)
+	k.Name, _ = entry["name"].(string)
+	k.Fingerprint, _ = entry["fingerprint"].(string)
+	k.CreatedAt, _ = entry["createdAt"].(string)
+	k.CreatedBy, _ = entry["createdBy"].(string)
+	k.ExpiresAt, _ = entry["expiresAt"].(string)
+	k.RotatedFrom, _ = entry["rotatedFrom"].(string)
+	return k
+}
+
+func webhookKeyExpired(entry map[string]interface{}, now time.Time) bool {
+	exp, _ := entry["expiresAt"].(string)
+	if exp == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, exp)
+	return err != nil || !now.Before(t)
+}
+
+// verifyWebhookAPIKey checks a presented key against the project's stored hashes in
+// constant time and returns the matching entry. dyn must be able to read the
+// project's ProjectSettings; webhook callers have no Kubernetes identity, so this
+// is normally the backend's own client.
+func verifyWebhookAPIKey(ctx context.Context, dyn dynamic.Interface, project, presented string) (*WebhookAPIKey, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(presented), webhookKeyPrefix)
+	if !ok {
+		return nil, errWebhookKeyInvalid
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, errWebhookKeyInvalid
+	}
+	spec, err := getProjectSettingsSpec(ctx, dyn, project)
+	if err != nil {
+		return nil, err
+	}
+	keys, _, _ := unstructured.NestedSlice(spec, "webhookAuth", "apiKeys")
+	now := time.Now()
+	for _, raw := range keys {
+		entry, ok := raw.(map[string]interface{})
+		if !ok || entry["id"] != id {
+			continue
+		}
+		if webhookKeyExpired(entry, now) {
+			return nil, errWebhookKeyInvalid
+		}
+		saltStr, _ := entry["salt"].(string)
+		stored, _ := entry["hash"].(string)
+		salt, err := base64.RawStdEncoding.DecodeString(saltStr)
+		if err != nil || stored == "" {
+			return nil, errWebhookKeyInvalid
+		}
+		if subtle.ConstantTimeCompare([]byte(hashWebhookKeySecret(salt, secret)), []byte(stored)) != 1 {
+			return nil, errWebhookKeyInvalid
+		}
+		k := webhookAPIKeyFromEntry(entry)
+		return &k, nil
+	}
+	return nil, errWebhookKeyInvalid
+}
+
+// updateWebhookAPIKeys applies mutate to spec.webhookAuth.apiKeys and writes the
+// ProjectSettings back with the caller's token, retrying on conflicts. Expired
+// keys are dropped on every write.
+func updateWebhookAPIKeys(ctx context.Context, dyn dynamic.Interface, project string, mutate func([]interface{}) ([]interface{}, error)) error {
+	gvr := getProjectSettingsResource()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := dyn.Resource(gvr).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		keys, _, _ := unstructured.NestedSlice(obj.Object, "spec", "webhookAuth", "apiKeys")
+		now := time.Now()
+		live := make([]interface{}, 0, len(keys))
+		for _, raw := range keys {
+			if entry, ok := raw.(map[string]interface{}); ok && !webhookKeyExpired(entry, now) {
+				live = append(live, entry)
+			}
+		}
+		updated, err := mutate(live)
+		if err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, updated, "spec", "webhookAuth", "apiKeys"); err != nil {
+			return err
+		}
+		declareRemovedWebhookKeys(obj, keys, updated)
+		_, err = dyn.Resource(gvr).Namespace(project).Update(ctx, obj, v1.UpdateOptions{})
+		return err
+	})
+}
+
+// removedWebhookKeysAnnotation lists the key ids an update removes on purpose.
+// The projectsettings-update admission policy rejects updates that drop keys
+// without naming them here, other than by rotation.
+const removedWebhookKeysAnnotation = "vteam.ambient-code/removed-api-keys"
+
+// declareRemovedWebhookKeys sets removedWebhookKeysAnnotation to the ids in
+// before that are neither kept nor replaced by a rotation in after
+func declareRemovedWebhookKeys(obj *unstructured.Unstructured, before, after []interface{}) {
+	kept := map[string]bool{}
+	for _, raw := range after {
+		if entry, ok := raw.(map[string]interface{}); ok {
+			for _, field := range []string{"id", "rotatedFrom"} {
+				if id, _ := entry[field].(string); id != "" {
+					kept[id] = true
+				}
+			}
+		}
+	}
+	var removed []string
+	for _, raw := range before {
+		if entry, ok := raw.(map[string]interface{}); ok {
+			if id, _ := entry["id"].(string); id != "" && !kept[id] {
+				removed = append(removed, id)
+			}
+		}
+	}
+	annotations := obj.GetAnnotations()
+	if len(removed) == 0 {
+		if _, ok := annotations[removedWebhookKeysAnnotation]; ok {
+			delete(annotations, removedWebhookKeysAnnotation)
+			obj.SetAnnotations(annotations)
+		}
+		return
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[removedWebhookKeysAnnotation] = strings.Join(removed, ",")
+	obj.SetAnnotations(annotations)
+}
+
+func respondWebhookKeyUpdateError(c *gin.Context, project string, err error) {
+	switch {
+	case err == errWebhookKeyNotFound:
+		respondError(c, http.StatusNotFound, "API key not found")
+	case errors.IsNotFound(err):
+		respondError(c, http.StatusNotFound, "ProjectSettings not found for project")
+	case errors.IsForbidden(err):
+		respondError(c, http.StatusForbidden, "Not permitted to manage API keys in this project")
+	default:
+		logErrorf(c, "Failed to update webhook API keys in %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to update API keys")
+	}
+}
+
+// GET /api/projects/:projectName/apikeys
+// listWebhookAPIKeys returns key metadata and fingerprints only
+func listWebhookAPIKeys(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	keys, _, _ := unstructured.NestedSlice(spec, "webhookAuth", "apiKeys")
+	now := time.Now()
+	items := make([]WebhookAPIKey, 0, len(keys))
+	for _, raw := range keys {
+		if entry, ok := raw.(map[string]interface{}); ok && !webhookKeyExpired(entry, now) {
+			items = append(items, webhookAPIKeyFromEntry(entry))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// POST /api/projects/:projectName/apikeys { name, ttlSeconds }
+// createWebhookAPIKey mints a key and returns the plaintext exactly once
+func createWebhookAPIKey(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req CreateWebhookAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.TTLSeconds < 0 {
+		respondError(c, http.StatusBadRequest, "ttlSeconds must not be negative")
+		return
+	}
+
+	key, entry, err := mintWebhookAPIKey(strings.TrimSpace(req.Name), requesterFromContext(c), req.TTLSeconds)
+	if err != nil {
+		logErrorf(c, "Failed to generate API key: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
+	err = updateWebhookAPIKeys(c.Request.Context(), reqDyn, project, func(keys []interface{}) ([]interface{}, error) {
+		return append(keys, entry), nil
+	})
+	if err != nil {
+		respondWebhookKeyUpdateError(c, project, err)
+		return
+	}
+
+	info := webhookAPIKeyFromEntry(entry)
+	auditDetail(c, "keyId", info.ID)
+	auditDetail(c, "fingerprint", info.Fingerprint)
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     key,
+		"apiKey":  info,
+		"message": "Store this key now; it cannot be retrieved again",
+	})
+}
+
+// POST /api/projects/:projectName/apikeys/:keyId/rotate { gracePeriodSeconds }
+// rotateWebhookAPIKey mints a replacement key with the same name. The old key is
+// revoked immediately or, with a grace period, expires after it.
+func rotateWebhookAPIKey(c *gin.Context) {
+	project := c.GetString("project")
+	keyID := c.Param("keyId")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req RotateWebhookAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.GracePeriodSeconds < 0 {
+		respondError(c, http.StatusBadRequest, "gracePeriodSeconds must not be negative")
+		return
+	}
+
+	var key string
+	var entry map[string]interface{}
+	var oldFingerprint string
+	err := updateWebhookAPIKeys(c.Request.Context(), reqDyn, project, func(keys []interface{}) ([]interface{}, error) {
+		found := false
+		out := make([]interface{}, 0, len(keys)+1)
+		for _, raw := range keys {
+			old, _ := raw.(map[string]interface{})
+			if old == nil || old["id"] != keyID {
+				out = append(out, raw)
+				continue
+			}
+			found = true
+			oldFingerprint, _ = old["fingerprint"].(string)
+			name, _ := old["name"].(string)
+			var err error
+			key, entry, err = mintWebhookAPIKey(name, requesterFromContext(c), 0)
+			if err != nil {
+				return nil, err
+			}
+			entry["rotatedFrom"] = keyID
+			if req.GracePeriodSeconds > 0 {
+				old["expiresAt"] = time.Now().UTC().Add(time.Duration(req.GracePeriodSeconds) * time.Second).Format(time.RFC3339)
+				out = append(out, old)
+			}
+			out = append(out, entry)
+		}
+		if !found {
+			return nil, errWebhookKeyNotFound
+		}
+		return out, nil
+	})
+	if err != nil {
+		respondWebhookKeyUpdateError(c, project, err)
+		return
+	}
+
+	info := webhookAPIKeyFromEntry(entry)
+	auditDetail(c, "keyId", info.ID)
+	auditDetail(c, "fingerprint", info.Fingerprint)
+	auditDetail(c, "rotatedFrom", keyID)
+	auditDetail(c, "rotatedFromFingerprint", oldFingerprint)
+	auditDetail(c, "gracePeriodSeconds", req.GracePeriodSeconds)
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     key,
+		"apiKey":  info,
+		"message": "Store this key now; it cannot be retrieved again",
+	})
+}
+
+// DELETE /api/projects/:projectName/apikeys/:keyId
+// revokeWebhookAPIKey removes the key's stored hash so it no longer verifies
+func revokeWebhookAPIKey(c *gin.Context) {
+	project := c.GetString("project")
+	keyID := c.Param("keyId")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	var fingerprint string
+	err := updateWebhookAPIKeys(c.Request.Context(), reqDyn, project, func(keys []interface{}) ([]interface{}, error) {
+		found := false
+		out := make([]interface{}, 0, len(keys))
+		for _, raw := range keys {
+			if entry, ok := raw.(map[string]interface{}); ok && entry["id"] == keyID {
+				found = true
+				fingerprint, _ = entry["fingerprint"].(string)
+				continue
+			}
+			out = append(out, raw)
+		}
+		if !found {
+			return nil, errWebhookKeyNotFound
+		}
+		return out, nil
+	})
+	if err != nil {
+		respondWebhookKeyUpdateError(c, project, err)
+		return
+	}
+	auditDetail(c, "keyId", keyID)
+	auditDetail(c, "fingerprint", fingerprint)
+	c.Status(http.StatusNoContent)
+}
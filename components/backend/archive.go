@@ -0,0 +1,135 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errArchiveEntrySkipped means an entry was left out before any of it was written
+var errArchiveEntrySkipped = errors.New("archive entry skipped")
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/artifacts/archive?format=zip|tar.gz
+// downloadSessionArtifactsArchive streams every artifact of a session as one archive.
+// Entries are copied straight from the store into the response, so the archive is
+// never held in memory. Once streaming has started errors can only be logged.
+func downloadSessionArtifactsArchive(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "tar.gz" {
+		respondError(c, http.StatusBadRequest, "format must be zip or tar.gz")
+		return
+	}
+
+	names, err := artifacts.List(c, project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to list artifacts for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "Failed to list artifacts")
+		return
+	}
+	if len(names) == 0 {
+		respondError(c, http.StatusNotFound, "Session has no artifacts")
+		return
+	}
+
+	// Index entries supply modification times and sizes when present
+	indexed := map[string]Artifact{}
+	if index, err := artifacts.LoadIndex(c, project, sessionName); err == nil {
+		for _, a := range index {
+			indexed[a.Name] = a
+		}
+	}
+	modTime := func(name string) time.Time {
+		if a, ok := indexed[name]; ok {
+			if t, err := time.Parse(time.RFC3339, a.UploadedAt); err == nil {
+				return t
+			}
+		}
+		return time.Now()
+	}
+
+	filename := fmt.Sprintf("%s-artifacts.%s", sessionName, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	var writeEntry func(name string, content *artifactContent) error
+	var closeArchive func() error
+	if format == "zip" {
+		c.Header("Content-Type", "application/zip")
+		zw := zip.NewWriter(c.Writer)
+		writeEntry = func(name string, content *artifactContent) error {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime(name)})
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, content.Body)
+			return err
+		}
+		closeArchive = zw.Close
+	} else {
+		c.Header("Content-Type", "application/gzip")
+		gz := gzip.NewWriter(c.Writer)
+		tw := tar.NewWriter(gz)
+		writeEntry = func(name string, content *artifactContent) error {
+			size := content.ContentLength
+			if size < 0 {
+				// tar headers need the size up front
+				a, ok := indexed[name]
+				if !ok {
+					return errArchiveEntrySkipped
+				}
+				size = a.Size
+			}
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size, ModTime: modTime(name), Typeflag: tar.TypeReg}); err != nil {
+				return err
+			}
+			_, err := io.CopyN(tw, content.Body, size)
+			return err
+		}
+		closeArchive = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return gz.Close()
+		}
+	}
+
+	c.Status(http.StatusOK)
+	for _, name := range names {
+		var entry *Artifact
+		if a, ok := indexed[name]; ok {
+			entry = &a
+		}
+		content, err := retrieveArtifact(c, project, sessionName, name, "", entry)
+		if err != nil {
+			if !errors.Is(err, errArtifactNotFound) {
+				logWarnf(c, "artifacts: archive of %s/%s skipped %s: %v", project, sessionName, name, err)
+			}
+			continue
+		}
+		err = writeEntry(name, content)
+		content.Body.Close()
+		if errors.Is(err, errArchiveEntrySkipped) {
+			logWarnf(c, "artifacts: archive of %s/%s skipped %s: size unknown", project, sessionName, name)
+			continue
+		}
+		if err != nil {
+			// The response is already partially written; abort the stream
+			logWarnf(c, "artifacts: archive of %s/%s aborted at %s: %v", project, sessionName, name, err)
+			c.Abort()
+			return
+		}
+		c.Writer.Flush()
+	}
+	if err := closeArchive(); err != nil {
+		logErrorf(c, "artifacts: failed to finish archive for %s/%s: %v", project, sessionName, err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Uploaded artifacts are stored once per project PVC as content-addressed
+// blobs under artifactBlobsRoot. Each session's artifact path is a hard link to
+// its blob, so runners, inputs and archives keep reading ordinary files while
+// identical uploads share storage. The blob's link count is its reference
+// count: a blob left with no link but its own is removed.
+const artifactBlobsRoot = "/artifact-blobs"
+
+var artifactStorageKeyPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// artifactStorageKey names the blob holding content with this SHA-256
+func artifactStorageKey(sum string) string {
+	return "sha256:" + sum
+}
+
+// artifactBlobPath is where the blob of a storage key lives on the project PVC
+func artifactBlobPath(key string) string {
+	sum := strings.TrimPrefix(key, "sha256:")
+	return fmt.Sprintf("%s/%s/%s", artifactBlobsRoot, sum[:2], sum)
+}
+
+// linkCount returns how many directory entries refer to a file
+func linkCount(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 1
+}
+
+// blobGCMu keeps garbage collection from racing blob creation in this
+// content service; links made by other processes fail and are retried
+var blobGCMu sync.Mutex
+
+// contentLinkBlob handles POST /content/blob when running in CONTENT_SERVICE_MODE
+// Body: { key: "sha256:<hex>", path: "/sessions/<name>/...", content: "...", encoding: "utf8"|"base64" }
+// links path to the blob of key, storing content as that blob first when it
+// does not exist yet. Without content a missing blob is 404, so callers can
+// try linking before sending bytes.
+func contentLinkBlob(c *gin.Context) {
+	var req struct {
+		Key      string  `json:"key"`
+		Path     string  `json:"path"`
+		Content  *string `json:"content"`
+		Encoding string  `json:"encoding"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	path := filepath.Clean("/" + strings.TrimSpace(req.Path))
+	if !artifactStorageKeyPattern.MatchString(req.Key) || path == "/" || strings.Contains(path, "..") || strings.HasPrefix(path, artifactBlobsRoot+"/") {
+		respondError(c, http.StatusBadRequest, "invalid key or path")
+		return
+	}
+	blob := filepath.Join(stateBaseDir, artifactBlobPath(req.Key))
+	abs := filepath.Join(stateBaseDir, path)
+
+	blobGCMu.Lock()
+	defer blobGCMu.Unlock()
+	_, err := os.Stat(blob)
+	existed := err == nil
+	if !existed {
+		if req.Content == nil {
+			respondError(c, http.StatusNotFound, "blob not found")
+			return
+		}
+		data := []byte(*req.Content)
+		if strings.EqualFold(req.Encoding, "base64") {
+			if data, err = base64.StdEncoding.DecodeString(*req.Content); err != nil {
+				respondError(c, http.StatusBadRequest, "invalid base64 content")
+				return
+			}
+		}
+		if sum := sha256.Sum256(data); artifactStorageKey(hex.EncodeToString(sum[:])) != req.Key {
+			respondError(c, http.StatusBadRequest, "content does not match key")
+			return
+		}
+		// Blobs are read-only so no writer can change every link at once
+		if err := writeFileAtomic(blob, data, 0444); err != nil {
+			logErrorf(c, "content: failed to store blob %s: %v", req.Key, err)
+			respondError(c, http.StatusInternalServerError, "failed to store blob")
+			return
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to create directory")
+		return
+	}
+	// Link beside the target and rename over it, replacing any earlier version.
+	// An earlier version sharing another blob may leave that blob unreferenced.
+	if prev, err := os.Stat(abs); err == nil && linkCount(prev) > 1 {
+		if blobInfo, err := os.Stat(blob); err == nil && !os.SameFile(prev, blobInfo) {
+			// Collected once this handler releases blobGCMu
+			defer func() { go collectOrphanBlobs() }()
+		}
+	}
+	tmp := fmt.Sprintf("%s.link-%d", abs, time.Now().UnixNano())
+	if err := os.Link(blob, tmp); err != nil {
+		logErrorf(c, "content: failed to link blob %s: %v", req.Key, err)
+		respondError(c, http.StatusInternalServerError, "failed to link blob")
+		return
+	}
+	if err := os.Rename(tmp, abs); err != nil {
+		os.Remove(tmp)
+		respondError(c, http.StatusInternalServerError, "failed to link blob")
+		return
+	}
+	info, err := os.Stat(blob)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "stat failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deduplicated": existed, "references": linkCount(info) - 1})
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// into place, so a path that is a hard link is replaced rather than modified
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// sharesBlobs reports whether the tree at abs holds a file with other links,
+// which may leave a blob unreferenced once the tree is removed
+func sharesBlobs(abs string) bool {
+	found := false
+	filepath.WalkDir(abs, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && linkCount(info) > 1 {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// collectOrphanBlobs removes the content service's blobs that no artifact
+// path links to any more and returns how many bytes that freed
+func collectOrphanBlobs() int64 {
+	blobGCMu.Lock()
+	defer blobGCMu.Unlock()
+	freed, _ := removeUnlinkedBlobs(filepath.Join(stateBaseDir, artifactBlobsRoot))
+	return freed
+}
+
+// removeUnlinkedBlobs removes the blobs under dir with no other link and
+// returns the bytes and blobs removed. Callers keep blob creation out.
+func removeUnlinkedBlobs(dir string) (int64, int64) {
+	var freed, removed int64
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || linkCount(info) > 1 {
+			return nil
+		}
+		if err := os.Remove(p); err == nil {
+			freed += info.Size()
+			removed++
+		}
+		return nil
+	})
+	return freed, removed
+}
+
+// storeArtifactBlob links the artifact path to the blob of key through the
+// project's content service, sending data only when the blob is not stored
+// yet. It reports whether existing content was reused.
+func storeArtifactBlob(c *gin.Context, project, key, path string, data []byte) (bool, error) {
+	type linkReq struct {
+		Key      string  `json:"key"`
+		Path     string  `json:"path"`
+		Content  *string `json:"content,omitempty"`
+		Encoding string  `json:"encoding,omitempty"`
+	}
+	var out struct {
+		Deduplicated bool `json:"deduplicated"`
+	}
+	status, err := postProjectContent(c, project, "/content/blob", linkReq{Key: key, Path: path}, &out)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusNotFound {
+		content := base64.StdEncoding.EncodeToString(data)
+		if status, err = postProjectContent(c, project, "/content/blob", linkReq{Key: key, Path: path, Content: &content, Encoding: "base64"}, &out); err != nil {
+			return false, err
+		}
+	}
+	if status < 200 || status >= 300 {
+		return false, fmt.Errorf("blob link failed: status %d", status)
+	}
+	return out.Deduplicated, nil
+}
+
+// deleteProjectContent removes a path through the project's content service,
+// releasing any blobs only it referenced
+func deleteProjectContent(c *gin.Context, project, absPath string) error {
+	status, err := postProjectContent(c, project, "/content/delete", map[string]string{"path": absPath}, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("content delete failed: status %d", status)
+	}
+	return nil
+}
+
+// postProjectContent posts body as JSON to the project's content service with
+// the caller's token and decodes a 2xx response into out. It returns the status.
+func postProjectContent(c *gin.Context, project, endpoint string, body, out interface{}) (int, error) {
+	token := c.GetHeader("Authorization")
+	if strings.TrimSpace(token) == "" {
+		token = c.GetHeader("X-Forwarded-Access-Token")
+	}
+	base := os.Getenv("CONTENT_SERVICE_BASE")
+	if base == "" {
+		base = "http://ambient-content.%s.svc:8080"
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, fmt.Sprintf(base, project)+endpoint, strings.NewReader(string(b)))
+	if strings.TrimSpace(token) != "" {
+		req.Header.Set("Authorization", token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
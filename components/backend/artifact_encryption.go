@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// artifactKeysSecret holds a namespace's artifact key-encryption keys for the
+// "secret" provider: "current" names the active key and every other entry is a
+// key id mapped to 32 bytes, raw or base64. The backend may read only this name.
+const artifactKeysSecret = "ambient-artifact-keys"
+
+// kmsHTTPClient does not follow redirects, so a key service cannot send the
+// backend on to an address outside ARTIFACT_KMS_ALLOWED_URLS
+var kmsHTTPClient = &http.Client{
+	Timeout: 15 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// kmsAllowedURLs are the key service base URLs projects may name in
+// artifactEncryption.kms.url, from the comma-separated ARTIFACT_KMS_ALLOWED_URLS.
+// The kms provider is unavailable while none are configured.
+func kmsAllowedURLs() []string {
+	var out []string
+	for _, u := range strings.Split(os.Getenv("ARTIFACT_KMS_ALLOWED_URLS"), ",") {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+func kmsURLAllowed(u string) bool {
+	return slices.Contains(kmsAllowedURLs(), strings.TrimRight(u, "/"))
+}
+
+// ArtifactEncryption records how an artifact's stored bytes are sealed: its
+// AES-256-GCM data key, wrapped by key KeyID of Provider
+type ArtifactEncryption struct {
+	Provider   string `json:"provider"`
+	KeyID      string `json:"keyId"`
+	WrappedKey string `json:"wrappedKey"`
+}
+
+// artifactEncryptionPolicy mirrors ProjectSettings spec.artifactEncryption
+type artifactEncryptionPolicy struct {
+	Enabled  bool
+	Provider string
+	KMSURL   string
+	KMSKeyID string
+}
+
+func artifactEncryptionPolicyFromSpec(spec map[string]interface{}) artifactEncryptionPolicy {
+	p := artifactEncryptionPolicy{Provider: "secret"}
+	p.Enabled, _, _ = unstructured.NestedBool(spec, "artifactEncryption", "enabled")
+	if v, _, _ := unstructured.NestedString(spec, "artifactEncryption", "provider"); v != "" {
+		p.Provider = v
+	}
+	p.KMSURL, _, _ = unstructured.NestedString(spec, "artifactEncryption", "kms", "url")
+	p.KMSURL = strings.TrimRight(p.KMSURL, "/")
+	p.KMSKeyID, _, _ = unstructured.NestedString(spec, "artifactEncryption", "kms", "keyId")
+	return p
+}
+
+// namespaceArtifactKeys reads the project's key-encryption keys and the id of
+// the current one
+func namespaceArtifactKeys(ctx context.Context, project string) (map[string][]byte, string, error) {
+	sec, err := k8sClient.CoreV1().Secrets(project).Get(ctx, artifactKeysSecret, v1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("read secret %s: %v", artifactKeysSecret, err)
+	}
+	keys := map[string][]byte{}
+	for id, raw := range sec.Data {
+		if id == "current" {
+			continue
+		}
+		key := bytes.TrimSpace(raw)
+		if len(key) != 32 {
+			if decoded, err := base64.StdEncoding.DecodeString(string(key)); err == nil {
+				key = decoded
+			}
+		}
+		if len(key) == 32 {
+			keys[id] = key
+		}
+	}
+	current := strings.TrimSpace(string(sec.Data["current"]))
+	if _, ok := keys[current]; !ok {
+		return nil, "", fmt.Errorf("secret %s has no 32-byte key named by current", artifactKeysSecret)
+	}
+	return keys, current, nil
+}
+
+func gcmSeal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func gcmOpen(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// kmsCall posts to the KMS provider's wrap or unwrap endpoint. The provider
+// accepts {"keyId", "plaintext"} at /v1/wrap and {"keyId", "ciphertext"} at
+// /v1/unwrap, base64 encoded. Only allowed key services are called, with the
+// credential in ARTIFACT_KMS_TOKEN_FILE as bearer token when set; the file is
+// read on every call so rotated projected tokens are picked up.
+func kmsCall(ctx context.Context, p artifactEncryptionPolicy, op string, body map[string]string, out interface{}) error {
+	if p.KMSURL == "" || p.KMSKeyID == "" {
+		return errors.New("artifactEncryption.kms needs url and keyId")
+	}
+	if !kmsURLAllowed(p.KMSURL) {
+		return fmt.Errorf("key service %s is not in ARTIFACT_KMS_ALLOWED_URLS", p.KMSURL)
+	}
+	b, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.KMSURL+"/v1/"+op, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tokenFile := strings.TrimSpace(os.Getenv("ARTIFACT_KMS_TOKEN_FILE")); tokenFile != "" {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return fmt.Errorf("read KMS credential: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := kmsHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("KMS %s: %s: %s", op, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// wrapDataKey wraps dek with the project's current key-encryption key
+func wrapDataKey(ctx context.Context, project string, p artifactEncryptionPolicy, dek []byte) (*ArtifactEncryption, error) {
+	switch p.Provider {
+	case "secret":
+		keys, current, err := namespaceArtifactKeys(ctx, project)
+		if err != nil {
+			return nil, err
+		}
+		wrapped, err := gcmSeal(keys[current], dek)
+		if err != nil {
+			return nil, err
+		}
+		return &ArtifactEncryption{Provider: "secret", KeyID: current, WrappedKey: base64.StdEncoding.EncodeToString(wrapped)}, nil
+	case "kms":
+		var out struct {
+			Ciphertext string `json:"ciphertext"`
+			KeyID      string `json:"keyId"`
+		}
+		if err := kmsCall(ctx, p, "wrap", map[string]string{"keyId": p.KMSKeyID, "plaintext": base64.StdEncoding.EncodeToString(dek)}, &out); err != nil {
+			return nil, err
+		}
+		if out.KeyID == "" {
+			out.KeyID = p.KMSKeyID
+		}
+		return &ArtifactEncryption{Provider: "kms", KeyID: out.KeyID, WrappedKey: out.Ciphertext}, nil
+	}
+	return nil, fmt.Errorf("unknown artifact encryption provider %q", p.Provider)
+}
+
+// unwrapDataKey recovers an artifact's data key. Keys rotated out of the
+// secret stay readable as long as their entry is kept.
+func unwrapDataKey(ctx context.Context, project string, p artifactEncryptionPolicy, enc *ArtifactEncryption) ([]byte, error) {
+	switch enc.Provider {
+	case "secret":
+		keys, _, err := namespaceArtifactKeys(ctx, project)
+		if err != nil {
+			return nil, err
+		}
+		kek, ok := keys[enc.KeyID]
+		if !ok {
+			return nil, fmt.Errorf("key %q is no longer in secret %s", enc.KeyID, artifactKeysSecret)
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(enc.WrappedKey)
+		if err != nil {
+			return nil, err
+		}
+		return gcmOpen(kek, wrapped)
+	case "kms":
+		var out struct {
+			Plaintext string `json:"plaintext"`
+		}
+		if err := kmsCall(ctx, p, "unwrap", map[string]string{"keyId": enc.KeyID, "ciphertext": enc.WrappedKey}, &out); err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(out.Plaintext)
+	}
+	return nil, fmt.Errorf("unknown artifact encryption provider %q", enc.Provider)
+}
+
+// sealArtifact encrypts data under a fresh data key when the project enables
+// spec.artifactEncryption, returning data unchanged and nil otherwise
+func sealArtifact(ctx context.Context, project string, spec map[string]interface{}, data []byte) ([]byte, *ArtifactEncryption, error) {
+	p := artifactEncryptionPolicyFromSpec(spec)
+	if !p.Enabled {
+		return data, nil, nil
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, err
+	}
+	enc, err := wrapDataKey(ctx, project, p, dek)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed, err := gcmSeal(dek, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sealed, enc, nil
+}
+
+// retrieveArtifact is artifacts.Retrieve with encrypted artifacts decrypted.
+// entry is the artifact's index entry, loaded when nil. Encrypted artifacts are
+// read whole and byteRange is applied to the plaintext.
+func retrieveArtifact(c *gin.Context, project, sessionName, name, byteRange string, entry *Artifact) (*artifactContent, error) {
+	if entry == nil {
+		if index, err := artifacts.LoadIndex(c, project, sessionName); err == nil {
+			for i := range index {
+				if index[i].Name == name {
+					entry = &index[i]
+					break
+				}
+			}
+		}
+	}
+	if entry == nil || entry.Encryption == nil {
+		return artifacts.Retrieve(c, project, sessionName, name, byteRange)
+	}
+
+	content, err := artifacts.Retrieve(c, project, sessionName, name, "")
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := io.ReadAll(content.Body)
+	content.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	_, reqDyn := getK8sClientsForRequest(c)
+	spec := map[string]interface{}{}
+	if reqDyn != nil {
+		if s, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project); err == nil {
+			spec = s
+		}
+	}
+	dek, err := unwrapDataKey(c.Request.Context(), project, artifactEncryptionPolicyFromSpec(spec), entry.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key of %s: %v", name, err)
+	}
+	data, err := gcmOpen(dek, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %v", name, err)
+	}
+
+	out := &artifactContent{ContentLength: int64(len(data)), LastModified: content.LastModified}
+	if byteRange != "" {
+		start, end, ok := parseByteRange(byteRange, int64(len(data)))
+		if !ok {
+			return nil, errArtifactRangeNotSatisf
+		}
+		out.Partial = true
+		out.ContentRange = fmt.Sprintf("bytes %d-%d/%d", start, end, len(data))
+		data = data[start : end+1]
+		out.ContentLength = int64(len(data))
+	}
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	return out, nil
+}
+
+// ArtifactKeyRotation reports a re-wrap of a project's artifact data keys
+type ArtifactKeyRotation struct {
+	KeyID     string `json:"keyId"`
+	Sessions  int    `json:"sessions"`
+	Rewrapped int    `json:"rewrapped"`
+	Failed    int    `json:"failed"`
+}
+
+// POST /api/projects/:projectName/artifact-keys/rotate
+// rotateArtifactKeys re-wraps the data key of every encrypted artifact in the
+// project with the current key-encryption key: the entry named by current in
+// the ambient-artifact-keys Secret, or artifactEncryption.kms.keyId. Artifact
+// bytes are not rewritten. Retired keys can be removed from the Secret once a
+// rotation reports no failures. Requires permission to update project settings.
+func rotateArtifactKeys(c *gin.Context) {
+	project := c.GetString("project")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	ctx := c.Request.Context()
+	access, err := projectAccessForCaller(ctx, reqK8s, callerCredential(c), project)
+	if err != nil {
+		logErrorf(c, "Failed to resolve access to %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to check permissions")
+		return
+	}
+	if !access.has("settings:update") {
+		respondError(c, http.StatusForbidden, "Only project admins may rotate artifact keys")
+		return
+	}
+	spec, err := getProjectSettingsSpec(ctx, reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	policy := artifactEncryptionPolicyFromSpec(spec)
+	currentKey := policy.KMSKeyID
+	if policy.Provider == "secret" {
+		if _, currentKey, err = namespaceArtifactKeys(ctx, project); err != nil {
+			respondError(c, http.StatusConflict, err.Error())
+			return
+		}
+	}
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		sessions = list.Items
+	}
+
+	report := ArtifactKeyRotation{KeyID: currentKey}
+	for i := range sessions {
+		sessionName := sessions[i].GetName()
+		rewrapped, failed, err := rewrapSessionArtifactKeys(c, project, sessionName, policy, currentKey)
+		if err != nil {
+			logWarnf(c, "artifacts: key rotation skipped %s/%s: %v", project, sessionName, err)
+			report.Failed++
+			continue
+		}
+		if rewrapped > 0 || failed > 0 {
+			report.Sessions++
+		}
+		report.Rewrapped += rewrapped
+		report.Failed += failed
+	}
+	logInfof(c, "artifacts: %s rotated artifact keys of %s to %s: %d re-wrapped, %d failed", requesterFromContext(c), project, currentKey, report.Rewrapped, report.Failed)
+	c.JSON(http.StatusOK, report)
+}
+
+// rewrapSessionArtifactKeys moves one session's encrypted artifacts to currentKey
+func rewrapSessionArtifactKeys(c *gin.Context, project, sessionName string, policy artifactEncryptionPolicy, currentKey string) (int, int, error) {
+	unlock, err := lockArtifactIndex(project, sessionName)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+	index, err := artifacts.LoadIndex(c, project, sessionName)
+	if err != nil {
+		return 0, 0, err
+	}
+	rewrapped, failed := 0, 0
+	ctx := c.Request.Context()
+	for i := range index {
+		enc := index[i].Encryption
+		if enc == nil || (enc.KeyID == currentKey && enc.Provider == policy.Provider) {
+			continue
+		}
+		dek, err := unwrapDataKey(ctx, project, policy, enc)
+		if err == nil {
+			enc, err = wrapDataKey(ctx, project, policy, dek)
+		}
+		if err != nil {
+			logErrorf(c, "artifacts: failed to re-wrap key of %s in %s/%s: %v", index[i].Name, project, sessionName, err)
+			failed++
+			continue
+		}
+		index[i].Encryption = enc
+		rewrapped++
+	}
+	if rewrapped > 0 {
+		if err := artifacts.SaveIndex(c, project, sessionName, index); err != nil {
+			return 0, 0, err
+		}
+	}
+	return rewrapped, failed, nil
+}
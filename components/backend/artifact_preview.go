@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPreviewLines   = 200
+	maxPreviewLines       = 2000
+	defaultThumbnailSize  = 256
+	maxThumbnailSize      = 1024
+	previewMaxTextBytes   = 1 << 20
+	previewMaxImageBytes  = 20 << 20
+	previewMaxImagePixels = 40_000_000
+)
+
+// ArtifactPreview is a small rendering of an artifact for cards and lists.
+// Type says which field is set: html for markdown, text for logs and other
+// text (pretty-printed for JSON), thumbnail (a PNG data URL) for images, and
+// none for kinds without a preview.
+type ArtifactPreview struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"contentType"`
+	Type        string `json:"type"`
+	HTML        string `json:"html,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// Truncated is set when only the start of the artifact was rendered
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/artifacts/preview/*name?lines=N&size=PX
+// previewSessionArtifact renders a preview without sending the artifact:
+// markdown as sanitized HTML, images as a thumbnail at most size pixels on a
+// side, JSON pretty-printed, and the first lines of logs and other text. Only
+// the start of text artifacts is read. Previews carry the artifact's checksum
+// as ETag.
+func previewSessionArtifact(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	name, ok := sanitizeArtifactName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid artifact name")
+		return
+	}
+	lines, ok := previewIntParam(c, "lines", defaultPreviewLines, maxPreviewLines)
+	if !ok {
+		return
+	}
+	size, ok := previewIntParam(c, "size", defaultThumbnailSize, maxThumbnailSize)
+	if !ok {
+		return
+	}
+
+	var entry *Artifact
+	if index, err := artifacts.LoadIndex(c, project, sessionName); err == nil {
+		for i := range index {
+			if index[i].Name == name {
+				entry = &index[i]
+				break
+			}
+		}
+	}
+	preview := ArtifactPreview{Name: name}
+	if entry != nil {
+		preview.Kind, preview.ContentType = entry.Kind, entry.ContentType
+		etag := fmt.Sprintf("\"%s-%d-%d\"", entry.SHA256, lines, size)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Header("ETag", etag)
+	} else {
+		preview.ContentType, preview.Kind = inferArtifactType(name, nil)
+	}
+
+	limit := int64(previewMaxTextBytes)
+	if preview.Kind == "image" {
+		limit = previewMaxImageBytes
+		if entry != nil && entry.Size > limit {
+			preview.Type = "none"
+			c.JSON(http.StatusOK, preview)
+			return
+		}
+	}
+	data, truncated, err := readArtifactPrefix(c, project, sessionName, name, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, errArtifactNotFound):
+			respondError(c, http.StatusNotFound, "Artifact not found")
+		default:
+			logErrorf(c, "artifacts: failed to retrieve %s for preview in %s/%s: %v", name, project, sessionName, err)
+			respondError(c, http.StatusBadGateway, "Failed to retrieve artifact")
+		}
+		return
+	}
+	if entry == nil {
+		preview.ContentType, preview.Kind = inferArtifactType(name, data)
+	}
+
+	base := strings.TrimSpace(strings.SplitN(preview.ContentType, ";", 2)[0])
+	switch {
+	case preview.Kind == "image":
+		if truncated {
+			preview.Type = "none"
+			break
+		}
+		thumb, w, h, err := artifactThumbnail(data, size)
+		if err != nil {
+			logWarnf(c, "artifacts: no thumbnail for %s in %s/%s: %v", name, project, sessionName, err)
+			preview.Type = "none"
+			break
+		}
+		preview.Type, preview.Thumbnail, preview.Width, preview.Height = "image", thumb, w, h
+	case !utf8.Valid(trimPartialRune(data)):
+		preview.Type = "none"
+	case preview.Kind == "report" || base == "text/markdown" || strings.HasSuffix(strings.ToLower(name), ".md"):
+		preview.Type, preview.HTML = "html", renderMarkdown(string(trimPartialRune(data)))
+		preview.Truncated = truncated
+	case base == "application/json" && !truncated && json.Valid(data):
+		var out bytes.Buffer
+		json.Indent(&out, data, "", "  ")
+		preview.Type = "json"
+		preview.Text, preview.Truncated = firstLines(out.String(), lines)
+	case preview.Kind == "binary" || preview.Kind == "archive":
+		preview.Type = "none"
+	default:
+		text, cut := firstLines(string(trimPartialRune(data)), lines)
+		preview.Type, preview.Text, preview.Truncated = "text", text, cut || truncated
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// previewIntParam reads an optional positive integer query parameter, writing
+// a 400 response when it is out of range
+func previewIntParam(c *gin.Context, key string, def, upper int) (int, bool) {
+	raw := c.Query(key)
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > upper {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("%s must be between 1 and %d", key, upper))
+		return 0, false
+	}
+	return n, true
+}
+
+// readArtifactPrefix reads at most limit bytes of an artifact with a range
+// request and reports whether there was more
+func readArtifactPrefix(c *gin.Context, project, sessionName, name string, limit int64) ([]byte, bool, error) {
+	content, err := retrieveArtifact(c, project, sessionName, name, fmt.Sprintf("bytes=0-%d", limit), nil)
+	if errors.Is(err, errArtifactRangeNotSatisf) {
+		// An empty artifact has no byte 0
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer content.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(content.Body, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limit {
+		return data[:limit], true, nil
+	}
+	return data, false, nil
+}
+
+// trimPartialRune drops a multi-byte character cut off at the end of data
+func trimPartialRune(data []byte) []byte {
+	for i := 0; i < utf8.UTFMax-1 && len(data) > 0; i++ {
+		if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size != 1 {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// firstLines returns the first n lines of text and whether any were left out
+func firstLines(text string, n int) (string, bool) {
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 0, 64*1024), previewMaxTextBytes)
+	var b strings.Builder
+	for i := 0; i < n && sc.Scan(); i++ {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(sc.Text())
+	}
+	return b.String(), sc.Scan()
+}
+
+// artifactThumbnail decodes a PNG, JPEG or GIF and scales it down by box
+// averaging to fit within size pixels, returning a PNG data URL and the
+// thumbnail's dimensions
+func artifactThumbnail(data []byte, size int) (string, int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if cfg.Width*cfg.Height > previewMaxImagePixels {
+		return "", 0, 0, fmt.Errorf("image of %dx%d pixels is too large", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	sb := src.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+	if w == 0 || h == 0 {
+		return "", 0, 0, fmt.Errorf("empty image")
+	}
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/sb.Dx())
+		} else {
+			w, h = max(1, w*size/sb.Dy()), size
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := sb.Min.Y+y*sb.Dy()/h, sb.Min.Y+(y+1)*sb.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := sb.Min.X+x*sb.Dx()/w, sb.Min.X+(x+1)*sb.Dx()/w
+			var r, g, b, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			// RGBA() is premultiplied; Set converts the average back to straight alpha
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
+		}
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, dst); err != nil {
+		return "", 0, 0, err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(out.Bytes()), w, h, nil
+}
+
+var (
+	mdHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItem    = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(.*)$`)
+	mdRule        = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	mdCodeSpan    = regexp.MustCompile("`([^`]+)`")
+	mdStrong      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdEmphasis    = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	mdLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdSafeLinkURL = regexp.MustCompile(`^(https?://|mailto:|#|/|\./|[A-Za-z0-9_.-]+(/|$))`)
+)
+
+// renderMarkdown converts the common subset of markdown used in reports
+// (headings, paragraphs, lists, quotes, rules, fenced code, code spans,
+// emphasis and links) to HTML. All text is escaped and only http(s), mailto
+// and relative links are kept, so the output is safe to insert as is.
+func renderMarkdown(src string) string {
+	var b strings.Builder
+	var para []string
+	listTag := ""
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + renderMarkdownInline(strings.Join(para, " ")) + "</p>\n")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			b.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flushPara()
+			closeList()
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(trimmed[3:])
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			if lang != "" {
+				fmt.Fprintf(&b, "<pre><code class=\"language-%s\">", html.EscapeString(strings.Fields(lang)[0]))
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case trimmed == "":
+			flushPara()
+			closeList()
+		case mdHeading.MatchString(trimmed):
+			flushPara()
+			closeList()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", len(m[1]), renderMarkdownInline(m[2]), len(m[1]))
+		case mdRule.MatchString(trimmed):
+			flushPara()
+			closeList()
+			b.WriteString("<hr>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flushPara()
+			closeList()
+			b.WriteString("<blockquote>" + renderMarkdownInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))) + "</blockquote>\n")
+		case mdListItem.MatchString(line):
+			flushPara()
+			m := mdListItem.FindStringSubmatch(line)
+			tag := "ul"
+			if m[1][0] >= '0' && m[1][0] <= '9' {
+				tag = "ol"
+			}
+			if tag != listTag {
+				closeList()
+				b.WriteString("<" + tag + ">\n")
+				listTag = tag
+			}
+			b.WriteString("<li>" + renderMarkdownInline(m[2]) + "</li>\n")
+		default:
+			closeList()
+			para = append(para, trimmed)
+		}
+	}
+	flushPara()
+	closeList()
+	return b.String()
+}
+
+// renderMarkdownInline escapes text and renders code spans, links, strong and
+// emphasis. Code spans are rendered first and kept out of the other rules.
+func renderMarkdownInline(text string) string {
+	var spans []string
+	text = mdCodeSpan.ReplaceAllStringFunc(text, func(m string) string {
+		spans = append(spans, "<code>"+html.EscapeString(mdCodeSpan.FindStringSubmatch(m)[1])+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+	text = html.EscapeString(text)
+	text = mdLink.ReplaceAllStringFunc(text, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		href := html.UnescapeString(sub[2])
+		if !mdSafeLinkURL.MatchString(href) {
+			return sub[1]
+		}
+		return fmt.Sprintf("<a href=\"%s\" rel=\"noopener noreferrer\">%s</a>", html.EscapeString(href), sub[1])
+	})
+	text = mdStrong.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = mdEmphasis.ReplaceAllString(text, "<em>$1$2</em>")
+	for i, span := range spans {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), span, 1)
+	}
+	return text
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// artifactHoldTag exempts an artifact from retention until the tag is removed.
+// Only project admins may add or remove it.
+const artifactHoldTag = "hold"
+
+// artifactExpiryFromUpload reads the optional ttl ("72h", "30d") of an upload
+// and returns the artifact's expiresAt, "" when none was given. It returns a
+// user-facing message for invalid input.
+func artifactExpiryFromUpload(c *gin.Context, now time.Time) (string, string) {
+	raw := c.PostForm("ttl")
+	if raw == "" {
+		raw = c.Query("ttl")
+	}
+	ttl, err := parseRetentionDuration(raw)
+	if err != nil {
+		return "", "ttl must be a duration such as 72h or 30d"
+	}
+	if ttl == 0 {
+		return "", ""
+	}
+	return now.Add(ttl).UTC().Format(time.RFC3339), ""
+}
+
+// PUT /api/projects/:projectName/agentic-sessions/:sessionName/artifacts/tags/*name
+// updateArtifactTags replaces an artifact's tags. Anyone who may update the
+// session may retag it, but adding or removing the hold tag takes permission
+// to update the project's settings.
+func updateArtifactTags(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	if !canWriteSession(c, project, sessionName) {
+		respondError(c, http.StatusForbidden, "Not permitted to tag artifacts of this session")
+		return
+	}
+	name, ok := sanitizeArtifactName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid artifact name")
+		return
+	}
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var tags []string
+	for _, t := range req.Tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || slices.Contains(tags, t) {
+			continue
+		}
+		if !artifactTagPattern.MatchString(t) {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid tag %q", t))
+			return
+		}
+		tags = append(tags, t)
+	}
+	if len(tags) > maxArtifactTags {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("at most %d tags are allowed", maxArtifactTags))
+		return
+	}
+	sort.Strings(tags)
+
+	unlock, err := lockArtifactIndex(project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to lock index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to lock artifact index")
+		return
+	}
+	defer unlock()
+
+	index, err := artifacts.LoadIndex(c, project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to load index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to load artifact index")
+		return
+	}
+	i := slices.IndexFunc(index, func(a Artifact) bool { return a.Name == name })
+	if i < 0 {
+		respondError(c, http.StatusNotFound, "Artifact not found")
+		return
+	}
+	if slices.Contains(index[i].Tags, artifactHoldTag) != slices.Contains(tags, artifactHoldTag) {
+		reqK8s, _ := getK8sClientsForRequest(c)
+		access, err := projectAccessForCaller(c.Request.Context(), reqK8s, callerCredential(c), project)
+		if err != nil {
+			logErrorf(c, "artifacts: failed to resolve access to %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to check permissions")
+			return
+		}
+		if !access.has("settings:update") {
+			respondError(c, http.StatusForbidden, "Only project admins may place or release a hold")
+			return
+		}
+	}
+	index[i].Tags = tags
+	if err := artifacts.SaveIndex(c, project, sessionName, index); err != nil {
+		logErrorf(c, "artifacts: failed to save index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to update artifact index")
+		return
+	}
+	artifactSearchCache.invalidate(project, sessionName)
+	c.JSON(http.StatusOK, index[i])
+}
+
+// RetentionReportItem is an artifact the next retention pass would delete
+type RetentionReportItem struct {
+	Session   string `json:"session"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	ExpiredAt string `json:"expiredAt"`
+}
+
+// RetentionReport previews artifact retention for a project
+type RetentionReport struct {
+	ArtifactRetention string                `json:"artifactRetention,omitempty"`
+	Artifacts         int64                 `json:"artifacts"`
+	Bytes             int64                 `json:"bytes"`
+	Held              int64                 `json:"held"`
+	Items             []RetentionReportItem `json:"items"`
+}
+
+// artifactExpiry returns when an artifact falls due, following the operator's
+// sweep: its own expiresAt, kept at least floor after upload, or else retention
+// after the session finished. It reports false for held artifacts and ones
+// kept indefinitely.
+func artifactExpiry(a Artifact, finishedAt time.Time, retention, floor time.Duration) (time.Time, bool) {
+	if slices.Contains(a.Tags, artifactHoldTag) {
+		return time.Time{}, false
+	}
+	if a.ExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, a.ExpiresAt); err == nil {
+			if uploaded, err := time.Parse(time.RFC3339, a.UploadedAt); err == nil && t.Before(uploaded.Add(floor)) {
+				t = uploaded.Add(floor)
+			}
+			return t, true
+		}
+	}
+	if retention <= 0 {
+		return time.Time{}, false
+	}
+	return finishedAt.Add(retention), true
+}
+
+// expiredArtifact is an artifact due for deletion and when it fell due
+type expiredArtifact struct {
+	Artifact
+	due time.Time
+}
+
+// expiredArtifacts returns the artifacts of a session finished at finishedAt
+// that retention deletes at now, and how many are held. Past the session-wide
+// retention with nothing held, every indexed artifact goes, as the operator
+// then removes the whole artifacts directory.
+func expiredArtifacts(index []Artifact, finishedAt time.Time, retention, floor time.Duration, now time.Time) ([]expiredArtifact, int64) {
+	var held int64
+	var expired []expiredArtifact
+	for _, a := range index {
+		if slices.Contains(a.Tags, artifactHoldTag) {
+			held++
+		}
+		if due, ok := artifactExpiry(a, finishedAt, retention, floor); ok && now.After(due) {
+			expired = append(expired, expiredArtifact{Artifact: a, due: due})
+		}
+	}
+	if retention > 0 && now.Sub(finishedAt) > retention && held == 0 {
+		expired = expired[:0]
+		for _, a := range index {
+			expired = append(expired, expiredArtifact{Artifact: a, due: finishedAt.Add(retention)})
+		}
+	}
+	return expired, held
+}
+
+// sessionFinishedAt returns when a session reached a terminal phase, falling
+// back to its creation for sessions without a completion time
+func sessionFinishedAt(obj *unstructured.Unstructured) (time.Time, bool) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Completed", "Failed", "Stopped", "Error":
+	default:
+		return time.Time{}, false
+	}
+	if ts, _, _ := unstructured.NestedString(obj.Object, "status", "completionTime"); ts != "" {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			return t, true
+		}
+	}
+	return obj.GetCreationTimestamp().Time, true
+}
+
+// GET /api/projects/:projectName/retention/report
+// getRetentionReport lists the artifacts of finished sessions that the next
+// retention pass would delete under retention.artifacts, per-artifact TTLs and
+// the cluster floors, with the bytes reclaimed and how many artifacts are held.
+// Files never recorded in an artifact index are not counted.
+func getRetentionReport(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	ctx := c.Request.Context()
+
+	spec, err := getProjectSettingsSpec(ctx, reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	clusterPolicy, err := loadClusterPolicy(ctx)
+	if err != nil {
+		logErrorf(c, "Failed to read cluster policy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return
+	}
+	report := RetentionReport{Items: []RetentionReportItem{}}
+	raw, _, _ := unstructured.NestedString(spec, "retention", "artifacts")
+	retention, err := parseRetentionDuration(raw)
+	if err != nil {
+		respondError(c, http.StatusUnprocessableEntity, fmt.Sprintf("invalid retention.artifacts: %v", err))
+		return
+	}
+	floor := clusterPolicy.retentionFloor("artifacts")
+	if retention > 0 {
+		retention = max(retention, floor)
+		report.ArtifactRetention = retention.String()
+	}
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		sessions = list.Items
+	}
+
+	now := time.Now()
+	for i := range sessions {
+		finishedAt, ok := sessionFinishedAt(&sessions[i])
+		if !ok {
+			continue
+		}
+		sessionName := sessions[i].GetName()
+		index, err := artifacts.LoadIndex(c, project, sessionName)
+		if err != nil {
+			logWarnf(c, "artifacts: retention report skipped %s/%s: %v", project, sessionName, err)
+			continue
+		}
+		expired, held := expiredArtifacts(index, finishedAt, retention, floor, now)
+		report.Held += held
+		for _, e := range expired {
+			report.Artifacts++
+			report.Bytes += e.Size
+			report.Items = append(report.Items, RetentionReportItem{Session: sessionName, Name: e.Name, Size: e.Size, ExpiredAt: e.due.UTC().Format(time.RFC3339)})
+		}
+	}
+	sort.Slice(report.Items, func(i, j int) bool { return report.Items[i].ExpiredAt < report.Items[j].ExpiredAt })
+
+	c.JSON(http.StatusOK, report)
+}
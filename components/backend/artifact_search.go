@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// artifactSearchMaxTextBytes bounds how much of an artifact is tokenized
+	artifactSearchMaxTextBytes = 1 << 20
+	// artifactSearchMaxTerms bounds the distinct terms kept per artifact
+	artifactSearchMaxTerms = 10000
+	maxArtifactTags        = 20
+	defaultSearchLimit     = 50
+	maxSearchLimit         = 200
+)
+
+var artifactTagPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9_.:]{0,61}[a-z0-9])?$`)
+
+// artifactLabelsFromUpload reads the tool and comma-separated tags of an
+// upload from form fields or query parameters. It returns a user-facing
+// message for invalid input.
+func artifactLabelsFromUpload(c *gin.Context) (string, []string, string) {
+	value := func(key string) string {
+		if v := c.PostForm(key); v != "" {
+			return v
+		}
+		return c.Query(key)
+	}
+	tool := strings.TrimSpace(value("tool"))
+	if len(tool) > 128 {
+		return "", nil, "tool must be at most 128 characters"
+	}
+	var tags []string
+	for _, t := range strings.Split(value("tags"), ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || slices.Contains(tags, t) {
+			continue
+		}
+		if !artifactTagPattern.MatchString(t) {
+			return "", nil, fmt.Sprintf("invalid tag %q", t)
+		}
+		tags = append(tags, t)
+	}
+	if len(tags) > maxArtifactTags {
+		return "", nil, fmt.Sprintf("at most %d tags are allowed", maxArtifactTags)
+	}
+	sort.Strings(tags)
+	return tool, tags, ""
+}
+
+// searchTerms splits text into its distinct lowercase words of 2 to 64
+// characters, at most limit of them
+func searchTerms(text string, limit int) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if n := utf8.RuneCountInString(word); n < 2 || n > 64 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) >= limit {
+			break
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// artifactText returns the searchable text of an artifact, or "" for kinds
+// that are not text or content that is not UTF-8
+func artifactText(kind string, data []byte) string {
+	switch kind {
+	case "report", "document", "log", "patch", "data":
+	default:
+		return ""
+	}
+	if len(data) > artifactSearchMaxTextBytes {
+		data = data[:artifactSearchMaxTextBytes]
+		// Drop a multi-byte character cut in half
+		for i := 0; i < utf8.UTFMax-1; i++ {
+			if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size != 1 {
+				break
+			}
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return ""
+	}
+	return string(data)
+}
+
+// indexArtifactContent records the content terms of a just-stored artifact.
+// Callers hold the session's artifact index lock.
+func indexArtifactContent(c *gin.Context, project, sessionName string, artifact Artifact, data []byte) error {
+	defer artifactSearchCache.invalidate(project, sessionName)
+	terms, err := artifacts.LoadSearchTerms(c, project, sessionName)
+	if err != nil {
+		return err
+	}
+	text := artifactText(artifact.Kind, data)
+	if text == "" {
+		if _, ok := terms[artifact.Name]; !ok {
+			return nil
+		}
+		// A replaced text artifact must not stay findable by its old content
+		delete(terms, artifact.Name)
+	} else {
+		terms[artifact.Name] = searchTerms(text, artifactSearchMaxTerms)
+	}
+	return artifacts.SaveSearchTerms(c, project, sessionName, terms)
+}
+
+// artifactSearchDoc is one artifact as seen by search
+type artifactSearchDoc struct {
+	artifact Artifact
+	terms    map[string]bool
+}
+
+type cachedSessionDocs struct {
+	docs    []artifactSearchDoc
+	expires time.Time
+}
+
+// sessionDocsCache keeps each session's artifact index and terms for
+// ARTIFACT_SEARCH_CACHE_TTL (default 1m) so repeated searches do not re-read
+// every session from the content service. Uploads through this replica
+// invalidate their session at once; those through others show after the TTL.
+type sessionDocsCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedSessionDocs
+}
+
+var artifactSearchCache = &sessionDocsCache{entries: map[string]cachedSessionDocs{}}
+
+func (sc *sessionDocsCache) invalidate(project, sessionName string) {
+	sc.mu.Lock()
+	delete(sc.entries, project+"/"+sessionName)
+	sc.mu.Unlock()
+}
+
+func (sc *sessionDocsCache) docs(c *gin.Context, project, sessionName string) ([]artifactSearchDoc, error) {
+	key := project + "/" + sessionName
+	now := time.Now()
+	sc.mu.Lock()
+	if e, ok := sc.entries[key]; ok && now.Before(e.expires) {
+		sc.mu.Unlock()
+		return e.docs, nil
+	}
+	sc.mu.Unlock()
+
+	index, err := artifacts.LoadIndex(c, project, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	var terms map[string][]string
+	if len(index) > 0 {
+		if terms, err = artifacts.LoadSearchTerms(c, project, sessionName); err != nil {
+			return nil, err
+		}
+	}
+	docs := make([]artifactSearchDoc, 0, len(index))
+	for _, a := range index {
+		doc := artifactSearchDoc{artifact: a, terms: map[string]bool{}}
+		for _, t := range terms[a.Name] {
+			doc.terms[t] = true
+		}
+		docs = append(docs, doc)
+	}
+
+	sc.mu.Lock()
+	sc.entries[key] = cachedSessionDocs{docs: docs, expires: now.Add(durationFromEnv("ARTIFACT_SEARCH_CACHE_TTL", time.Minute))}
+	sc.mu.Unlock()
+	return docs, nil
+}
+
+// ArtifactSearchHit is an artifact matching a search, with the session holding it
+type ArtifactSearchHit struct {
+	Session  string   `json:"session"`
+	Artifact Artifact `json:"artifact"`
+	Score    int      `json:"score"`
+}
+
+// artifactQuery is a parsed search. Every word of q must match the artifact's
+// name, a tag, its tool or a content term; the other fields filter exactly.
+type artifactQuery struct {
+	words   []string
+	kind    string
+	tool    string
+	tag     string
+	session string
+	after   time.Time
+}
+
+// score returns how well doc matches, 0 for no match. A word weighs 3 in the
+// name, 2 in a tag or the tool and 1 in the content.
+func (q artifactQuery) score(doc artifactSearchDoc) int {
+	a := doc.artifact
+	if q.kind != "" && a.Kind != q.kind {
+		return 0
+	}
+	if q.tool != "" && !strings.EqualFold(a.Tool, q.tool) {
+		return 0
+	}
+	if q.tag != "" && !slices.Contains(a.Tags, q.tag) {
+		return 0
+	}
+	if !q.after.IsZero() {
+		uploaded, err := time.Parse(time.RFC3339, a.UploadedAt)
+		if err != nil || !uploaded.After(q.after) {
+			return 0
+		}
+	}
+	if len(q.words) == 0 {
+		return 1
+	}
+	name := strings.ToLower(a.Name)
+	total := 0
+	for _, w := range q.words {
+		switch {
+		case strings.Contains(name, w):
+			total += 3
+		case slices.Contains(a.Tags, w) || strings.EqualFold(a.Tool, w):
+			total += 2
+		case doc.terms[w]:
+			total++
+		default:
+			return 0
+		}
+	}
+	return total
+}
+
+// GET /api/projects/:projectName/artifacts/search?q=WORDS&type=KIND&tool=T&tag=T&session=S&after=RFC3339&limit=N
+// searchArtifacts finds artifacts across the project's sessions by name, tags,
+// tool and the text of text artifacts, best matches first and newest first
+// among equals. Text is indexed on upload, so artifacts uploaded before search
+// existed match on name and metadata only.
+func searchArtifacts(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	q := artifactQuery{
+		words:   searchTerms(c.Query("q"), 32),
+		kind:    strings.TrimSpace(c.Query("type")),
+		tool:    strings.TrimSpace(c.Query("tool")),
+		tag:     strings.ToLower(strings.TrimSpace(c.Query("tag"))),
+		session: strings.TrimSpace(c.Query("session")),
+	}
+	if raw := c.Query("after"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "after must be an RFC 3339 timestamp")
+			return
+		}
+		q.after = t
+	}
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	var sessionNames []string
+	if q.session != "" {
+		sessionNames = []string{q.session}
+	} else if sessions, cached := cachedSessions(project, labels.Everything()); cached {
+		for _, s := range sessions {
+			sessionNames = append(sessionNames, s.GetName())
+		}
+	} else {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		for _, s := range list.Items {
+			sessionNames = append(sessionNames, s.GetName())
+		}
+	}
+
+	hits := []ArtifactSearchHit{}
+	for _, sessionName := range sessionNames {
+		docs, err := artifactSearchCache.docs(c, project, sessionName)
+		if err != nil {
+			logWarnf(c, "artifacts: search skipped %s/%s: %v", project, sessionName, err)
+			continue
+		}
+		for _, doc := range docs {
+			if score := q.score(doc); score > 0 {
+				hits = append(hits, ArtifactSearchHit{Session: sessionName, Artifact: doc.artifact, Score: score})
+			}
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Artifact.UploadedAt > hits[j].Artifact.UploadedAt
+	})
+	total := len(hits)
+	if total > limit {
+		hits = hits[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": hits, "total": total})
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+)
+
+var errArtifactQuotaExceeded = errors.New("artifact storage quota exceeded")
+
+// pvcArtifactStore keeps artifacts on a ReadWriteMany PVC mounted into every
+// backend replica, for clusters without per-namespace content services or
+// object storage. Each project has its own directory:
+//
+//	<root>/<project>/sessions/<session>/artifacts/<name>   hard link to a blob
+//	<root>/<project>/sessions/<session>/artifacts-index.json
+//	<root>/<project>/artifact-blobs/<xx>/<sha256>
+//	<root>/<project>/usage.json                          bytes and blobs stored
+//
+// Index updates and blob changes hold flock(2) locks on files beside them, so
+// replicas sharing the volume do not interleave. usage.json counts each blob
+// once and is checked against ARTIFACT_PVC_PROJECT_QUOTA_BYTES (0 for none).
+type pvcArtifactStore struct {
+	root       string
+	quotaBytes int64
+}
+
+// initArtifactStore selects the artifact store from ARTIFACT_STORE: "content"
+// (default) uses each project's content service and "pvc" the shared volume at
+// ARTIFACT_PVC_ROOT (default <PVC_BASE_DIR>/artifacts), which the backend then
+// also applies artifact retention to.
+func initArtifactStore() error {
+	switch os.Getenv("ARTIFACT_STORE") {
+	case "", "content":
+		return nil
+	case "pvc":
+	default:
+		return fmt.Errorf("unknown ARTIFACT_STORE %q", os.Getenv("ARTIFACT_STORE"))
+	}
+	root := os.Getenv("ARTIFACT_PVC_ROOT")
+	if root == "" {
+		root = filepath.Join(pvcBaseDir, "artifacts")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("artifact PVC root %s: %v", root, err)
+	}
+	store := pvcArtifactStore{root: root, quotaBytes: intFromEnv("ARTIFACT_PVC_PROJECT_QUOTA_BYTES", 0)}
+	artifacts = store
+	go store.runRetentionLoop()
+	log.Printf("Artifacts stored on the shared volume at %s", root)
+	return nil
+}
+
+func (s pvcArtifactStore) projectDir(project string) string {
+	return filepath.Join(s.root, filepath.Base(project))
+}
+
+func (s pvcArtifactStore) sessionDir(project, sessionName string) string {
+	return filepath.Join(s.projectDir(project), "sessions", filepath.Base(sessionName))
+}
+
+func (s pvcArtifactStore) artifactPath(project, sessionName, name string) string {
+	return filepath.Join(s.sessionDir(project, sessionName), "artifacts", filepath.FromSlash(name))
+}
+
+// lockFile takes an exclusive flock on path, creating it if needed
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (s pvcArtifactStore) LockIndex(project, sessionName string) (func(), error) {
+	return lockFile(filepath.Join(s.sessionDir(project, sessionName), ".index.lock"))
+}
+
+// lockBlobs serializes blob creation, linking and collection in a project
+func (s pvcArtifactStore) lockBlobs(project string) (func(), error) {
+	return lockFile(filepath.Join(s.projectDir(project), ".blobs.lock"))
+}
+
+// pvcUsage is the bytes and blobs a project stores. Callers hold the blob lock.
+type pvcUsage struct {
+	Bytes int64 `json:"bytes"`
+	Blobs int64 `json:"blobs"`
+}
+
+func (s pvcArtifactStore) loadUsage(project string) (pvcUsage, error) {
+	var u pvcUsage
+	b, err := os.ReadFile(filepath.Join(s.projectDir(project), "usage.json"))
+	if err == nil {
+		if err := json.Unmarshal(b, &u); err == nil {
+			return u, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return u, err
+	}
+	// Missing or corrupt: recount the blobs
+	err = filepath.WalkDir(filepath.Join(s.projectDir(project), strings.TrimPrefix(artifactBlobsRoot, "/")), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			u.Bytes += info.Size()
+			u.Blobs++
+		}
+		return nil
+	})
+	return u, err
+}
+
+func (s pvcArtifactStore) saveUsage(project string, u pvcUsage) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.projectDir(project), "usage.json"), b, 0644)
+}
+
+// Usage reports the bytes and blobs stored for a project, for storage quotas
+func (s pvcArtifactStore) Usage(project string) (contentUsage, error) {
+	unlock, err := s.lockBlobs(project)
+	if err != nil {
+		return contentUsage{}, err
+	}
+	defer unlock()
+	u, err := s.loadUsage(project)
+	return contentUsage{Path: s.projectDir(project), Bytes: u.Bytes, Files: u.Blobs}, err
+}
+
+// link points path at the blob of key, storing data as that blob first when it
+// is new, and releases a blob the replaced path was the last link to
+func (s pvcArtifactStore) link(project, key, path string, data []byte) (bool, error) {
+	unlock, err := s.lockBlobs(project)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	usage, err := s.loadUsage(project)
+	if err != nil {
+		return false, err
+	}
+	blob := filepath.Join(s.projectDir(project), filepath.FromSlash(artifactBlobPath(key)))
+	_, err = os.Stat(blob)
+	existed := err == nil
+	if !existed {
+		if s.quotaBytes > 0 && usage.Bytes+int64(len(data)) > s.quotaBytes {
+			return false, errArtifactQuotaExceeded
+		}
+		if err := writeFileAtomic(blob, data, 0444); err != nil {
+			return false, err
+		}
+		usage.Bytes += int64(len(data))
+		usage.Blobs++
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	shared := false
+	if prev, err := os.Stat(path); err == nil && linkCount(prev) > 1 {
+		shared = true
+	}
+	tmp := fmt.Sprintf("%s.link-%d", path, time.Now().UnixNano())
+	if err := os.Link(blob, tmp); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	if shared {
+		freed, removed := removeUnlinkedBlobs(filepath.Join(s.projectDir(project), strings.TrimPrefix(artifactBlobsRoot, "/")))
+		usage.Bytes, usage.Blobs = usage.Bytes-freed, usage.Blobs-removed
+	}
+	return existed, s.saveUsage(project, usage)
+}
+
+// remove deletes path and any blob left without another link
+func (s pvcArtifactStore) remove(project, path string) error {
+	unlock, err := s.lockBlobs(project)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	shared := sharesBlobs(path)
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if !shared {
+		return nil
+	}
+	usage, err := s.loadUsage(project)
+	if err != nil {
+		return err
+	}
+	freed, removed := removeUnlinkedBlobs(filepath.Join(s.projectDir(project), strings.TrimPrefix(artifactBlobsRoot, "/")))
+	usage.Bytes, usage.Blobs = usage.Bytes-freed, usage.Blobs-removed
+	return s.saveUsage(project, usage)
+}
+
+func (s pvcArtifactStore) Put(c *gin.Context, project, sessionName, name, key string, data []byte) (string, bool, error) {
+	deduplicated, err := s.link(project, key, s.artifactPath(project, sessionName, name), data)
+	return sessionArtifactsPath(sessionName) + "/" + name, deduplicated, err
+}
+
+func (s pvcArtifactStore) Delete(c *gin.Context, project, sessionName, name string) error {
+	return s.remove(project, s.artifactPath(project, sessionName, name))
+}
+
+// Retrieve serves a single byte range ("bytes=a-b", "bytes=a-" or "bytes=-n")
+// or the whole file
+func (s pvcArtifactStore) Retrieve(c *gin.Context, project, sessionName, name, byteRange string) (*artifactContent, error) {
+	f, err := os.Open(s.artifactPath(project, sessionName, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errArtifactNotFound
+		}
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, errArtifactNotFound
+	}
+	content := &artifactContent{Body: f, ContentLength: info.Size(), LastModified: info.ModTime().UTC().Format(http.TimeFormat)}
+	if byteRange == "" {
+		return content, nil
+	}
+	start, end, ok := parseByteRange(byteRange, info.Size())
+	if !ok {
+		f.Close()
+		return nil, errArtifactRangeNotSatisf
+	}
+	content.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, start, end-start+1), f}
+	content.Partial = true
+	content.ContentLength = end - start + 1
+	content.ContentRange = fmt.Sprintf("bytes %d-%d/%d", start, end, info.Size())
+	return content, nil
+}
+
+// parseByteRange resolves a single-range Range header against size
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		return max(size-n, 0), size - 1, true
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end, true
+}
+
+func (s pvcArtifactStore) List(c *gin.Context, project, sessionName string) ([]string, error) {
+	root := filepath.Join(s.sessionDir(project, sessionName), "artifacts")
+	names := []string{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() && !strings.Contains(d.Name(), ".link-") {
+			rel, _ := filepath.Rel(root, p)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (s pvcArtifactStore) readJSON(path string, out interface{}) (bool, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(b, out)
+}
+
+func (s pvcArtifactStore) LoadIndex(c *gin.Context, project, sessionName string) ([]Artifact, error) {
+	index := []Artifact{}
+	if _, err := s.readJSON(filepath.Join(s.sessionDir(project, sessionName), "artifacts-index.json"), &index); err != nil {
+		return nil, fmt.Errorf("corrupt artifact index: %v", err)
+	}
+	return index, nil
+}
+
+func (s pvcArtifactStore) SaveIndex(c *gin.Context, project, sessionName string, index []Artifact) error {
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.sessionDir(project, sessionName), "artifacts-index.json"), b, 0644)
+}
+
+func (s pvcArtifactStore) LoadSearchTerms(c *gin.Context, project, sessionName string) (map[string][]string, error) {
+	terms := map[string][]string{}
+	if _, err := s.readJSON(filepath.Join(s.sessionDir(project, sessionName), "artifacts-search.json"), &terms); err != nil {
+		return nil, fmt.Errorf("corrupt artifact search terms: %v", err)
+	}
+	return terms, nil
+}
+
+func (s pvcArtifactStore) SaveSearchTerms(c *gin.Context, project, sessionName string, terms map[string][]string) error {
+	b, err := json.Marshal(terms)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.sessionDir(project, sessionName), "artifacts-search.json"), b, 0644)
+}
+
+// runRetentionLoop applies artifact retention to the shared volume every
+// RETENTION_INTERVAL (default 1h), as the operator does for content services.
+// Every replica runs it; the index locks keep passes from colliding.
+func (s pvcArtifactStore) runRetentionLoop() {
+	dyn, err := dynamic.NewForConfig(baseKubeConfig)
+	if err != nil {
+		log.Printf("artifacts: retention disabled, failed to create dynamic client: %v", err)
+		return
+	}
+	interval := durationFromEnv("RETENTION_INTERVAL", time.Hour)
+	for {
+		time.Sleep(interval)
+		entries, err := os.ReadDir(s.root)
+		if err != nil {
+			log.Printf("artifacts: retention failed to read %s: %v", s.root, err)
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				if err := s.applyRetention(dyn, e.Name()); err != nil {
+					log.Printf("artifacts: retention failed in %s: %v", e.Name(), err)
+				}
+			}
+		}
+	}
+}
+
+// applyRetention deletes the project's expired artifacts. Artifacts of
+// sessions that no longer exist are removed with their directory.
+func (s pvcArtifactStore) applyRetention(dyn dynamic.Interface, project string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	spec, err := getProjectSettingsSpec(ctx, dyn, project)
+	if err != nil {
+		return err
+	}
+	clusterPolicy, err := loadClusterPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	raw, _, _ := unstructured.NestedString(spec, "retention", "artifacts")
+	retention, err := parseRetentionDuration(raw)
+	if err != nil {
+		return err
+	}
+	floor := clusterPolicy.retentionFloor("artifacts")
+	if retention > 0 {
+		retention = max(retention, floor)
+	}
+	dryRun, _, _ := unstructured.NestedBool(spec, "retention", "dryRun")
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := dyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		sessions = list.Items
+	}
+	known := map[string]bool{}
+	now := time.Now()
+	for i := range sessions {
+		known[sessions[i].GetName()] = true
+		finishedAt, ok := sessionFinishedAt(&sessions[i])
+		if !ok {
+			continue
+		}
+		if err := s.sweepSession(project, sessions[i].GetName(), finishedAt, retention, floor, now, dryRun); err != nil {
+			log.Printf("artifacts: retention failed for %s/%s: %v", project, sessions[i].GetName(), err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.projectDir(project), "sessions"))
+	if err != nil {
+		return nil
+	}
+	for _, e := range entries {
+		if !e.IsDir() || known[e.Name()] {
+			continue
+		}
+		// The session cache may not hold a session created moments ago yet
+		if info, err := e.Info(); err != nil || now.Sub(info.ModTime()) < time.Hour {
+			continue
+		}
+		if err := s.removeDeletedSession(project, e.Name(), dryRun); err != nil {
+			log.Printf("artifacts: failed to remove artifacts of deleted session %s/%s: %v", project, e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// removeDeletedSession removes the artifacts of a session that no longer
+// exists. Held artifacts and their index entries outlive the session; the
+// directory goes once nothing is held.
+func (s pvcArtifactStore) removeDeletedSession(project, sessionName string, dryRun bool) error {
+	unlock, err := s.LockIndex(project, sessionName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	index, err := s.LoadIndex(nil, project, sessionName)
+	if err != nil {
+		return err
+	}
+	var held []Artifact
+	for _, a := range index {
+		if slices.Contains(a.Tags, artifactHoldTag) {
+			held = append(held, a)
+		}
+	}
+	if len(held) == 0 {
+		if dryRun {
+			log.Printf("artifacts: retention (dry-run) would remove artifacts of deleted session %s/%s", project, sessionName)
+			return nil
+		}
+		return s.remove(project, s.sessionDir(project, sessionName))
+	}
+
+	names, err := s.List(nil, project, sessionName)
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for _, name := range names {
+		if slices.ContainsFunc(held, func(a Artifact) bool { return a.Name == name }) {
+			continue
+		}
+		if dryRun {
+			log.Printf("artifacts: retention (dry-run) would delete %s of deleted session %s/%s", name, project, sessionName)
+			continue
+		}
+		if err := s.remove(project, s.artifactPath(project, sessionName, name)); err != nil {
+			return err
+		}
+		removed++
+	}
+	if dryRun {
+		return nil
+	}
+	if removed > 0 {
+		log.Printf("artifacts: retention deleted %d artifacts of deleted session %s/%s, keeping %d held", removed, project, sessionName, len(held))
+	}
+	if len(held) == len(index) {
+		return nil
+	}
+	return s.SaveIndex(nil, project, sessionName, held)
+}
+
+func (s pvcArtifactStore) sweepSession(project, sessionName string, finishedAt time.Time, retention, floor time.Duration, now time.Time, dryRun bool) error {
+	unlock, err := s.LockIndex(project, sessionName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	index, err := s.LoadIndex(nil, project, sessionName)
+	if err != nil {
+		return err
+	}
+	expired, _ := expiredArtifacts(index, finishedAt, retention, floor, now)
+	if len(expired) == 0 {
+		return nil
+	}
+	gone := map[string]bool{}
+	var bytes int64
+	for _, a := range expired {
+		if dryRun {
+			log.Printf("artifacts: retention (dry-run) would delete %s of %s/%s", a.Name, project, sessionName)
+			continue
+		}
+		if err := s.remove(project, s.artifactPath(project, sessionName, a.Name)); err != nil {
+			log.Printf("artifacts: retention failed to delete %s of %s/%s: %v", a.Name, project, sessionName, err)
+			continue
+		}
+		gone[a.Name] = true
+		bytes += a.Size
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+	kept := make([]Artifact, 0, len(index)-len(gone))
+	for _, a := range index {
+		if !gone[a.Name] {
+			kept = append(kept, a)
+		}
+	}
+	log.Printf("artifacts: retention deleted %d artifacts (%d bytes) of %s/%s", len(gone), bytes, project, sessionName)
+	return s.SaveIndex(nil, project, sessionName, kept)
+}
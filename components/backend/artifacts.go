@@ -0,0 +1,693 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+const defaultArtifactMaxUploadBytes = 100 << 20
+
+// Artifact is one entry in a session's artifact index
+type Artifact struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"contentType"`
+	Kind        string `json:"kind"`
+	UploadedAt  string `json:"uploadedAt"`
+	UploadedBy  string `json:"uploadedBy,omitempty"`
+	// Tool names what produced the artifact; Tags are free-form lowercase labels
+	Tool string   `json:"tool,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// StorageKey names the content-addressed blob holding the bytes, shared by
+	// every artifact with the same content in the project
+	StorageKey string `json:"storageKey,omitempty"`
+	// ExpiresAt is when retention deletes the artifact ahead of its session's
+	// retention.artifacts; the hold tag overrides it
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	// Redactions counts the values spec.redaction replaced before storage
+	Redactions int64 `json:"redactions,omitempty"`
+	// Encryption is set when spec.artifactEncryption sealed the stored bytes;
+	// Size and SHA256 still describe the plaintext
+	Encryption *ArtifactEncryption `json:"encryption,omitempty"`
+}
+
+// artifactStore persists artifact bytes and the per-session index. The default
+// implementation goes through the per-namespace content service.
+type artifactStore interface {
+	// Put stores data under the storage key of its SHA-256, reusing a stored
+	// copy when there is one, and returns the artifact's path
+	Put(c *gin.Context, project, sessionName, name, key string, data []byte) (string, bool, error)
+	// Delete removes the artifact's path, releasing its blob when unreferenced
+	Delete(c *gin.Context, project, sessionName, name string) error
+	Retrieve(c *gin.Context, project, sessionName, name, byteRange string) (*artifactContent, error)
+	// List returns the names of all stored artifacts, including ones never indexed
+	List(c *gin.Context, project, sessionName string) ([]string, error)
+	LoadIndex(c *gin.Context, project, sessionName string) ([]Artifact, error)
+	SaveIndex(c *gin.Context, project, sessionName string, index []Artifact) error
+	// LoadSearchTerms and SaveSearchTerms persist the content terms of a
+	// session's text artifacts, keyed by artifact name
+	LoadSearchTerms(c *gin.Context, project, sessionName string) (map[string][]string, error)
+	SaveSearchTerms(c *gin.Context, project, sessionName string, terms map[string][]string) error
+}
+
+var artifacts artifactStore = contentServiceArtifactStore{}
+
+var (
+	errArtifactNotFound       = errors.New("artifact not found")
+	errArtifactRangeNotSatisf = errors.New("requested range not satisfiable")
+)
+
+// artifactContent is a streamed, possibly partial (206), artifact body
+type artifactContent struct {
+	Body          io.ReadCloser
+	Partial       bool
+	ContentLength int64
+	ContentRange  string
+	LastModified  string
+}
+
+// artifactIndexLocks serializes index read-modify-write per session within this replica
+var artifactIndexLocks sync.Map
+
+func artifactIndexLock(project, sessionName string) *sync.Mutex {
+	l, _ := artifactIndexLocks.LoadOrStore(project+"/"+sessionName, &sync.Mutex{})
+	return l.(*sync.Mutex)
+}
+
+// artifactIndexLocker is implemented by stores that other replicas write to
+// directly, to serialize index updates between them
+type artifactIndexLocker interface {
+	LockIndex(project, sessionName string) (unlock func(), err error)
+}
+
+// lockArtifactIndex serializes a read-modify-write of the session's artifact
+// index within this replica and, when the store supports it, across replicas
+func lockArtifactIndex(project, sessionName string) (func(), error) {
+	l := artifactIndexLock(project, sessionName)
+	l.Lock()
+	locker, ok := artifacts.(artifactIndexLocker)
+	if !ok {
+		return l.Unlock, nil
+	}
+	unlock, err := locker.LockIndex(project, sessionName)
+	if err != nil {
+		l.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		l.Unlock()
+	}, nil
+}
+
+type contentServiceArtifactStore struct{}
+
+func artifactIndexPath(sessionName string) string {
+	return fmt.Sprintf("/sessions/%s/artifacts-index.json", sessionName)
+}
+
+func (contentServiceArtifactStore) Put(c *gin.Context, project, sessionName, name, key string, data []byte) (string, bool, error) {
+	p := sessionArtifactsPath(sessionName) + "/" + name
+	deduplicated, err := storeArtifactBlob(c, project, key, p, data)
+	return p, deduplicated, err
+}
+
+func (contentServiceArtifactStore) Delete(c *gin.Context, project, sessionName, name string) error {
+	return deleteProjectContent(c, project, sessionArtifactsPath(sessionName)+"/"+name)
+}
+
+// Retrieve streams the artifact from the content service, forwarding byteRange
+// (an HTTP Range header value) so large files can be fetched in pieces.
+func (contentServiceArtifactStore) Retrieve(c *gin.Context, project, sessionName, name, byteRange string) (*artifactContent, error) {
+	token := c.GetHeader("Authorization")
+	if strings.TrimSpace(token) == "" {
+		token = c.GetHeader("X-Forwarded-Access-Token")
+	}
+	base := os.Getenv("CONTENT_SERVICE_BASE")
+	if base == "" {
+		base = "http://ambient-content.%s.svc:8080"
+	}
+	u := fmt.Sprintf("%s/content/file?path=%s", fmt.Sprintf(base, project), url.QueryEscape(sessionArtifactsPath(sessionName)+"/"+name))
+	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u, nil)
+	if strings.TrimSpace(token) != "" {
+		req.Header.Set("Authorization", token)
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	// No client timeout: large downloads are bounded by the caller's request context
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return &artifactContent{
+			Body:          resp.Body,
+			Partial:       resp.StatusCode == http.StatusPartialContent,
+			ContentLength: resp.ContentLength,
+			ContentRange:  resp.Header.Get("Content-Range"),
+			LastModified:  resp.Header.Get("Last-Modified"),
+		}, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, errArtifactNotFound
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, errArtifactRangeNotSatisf
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("content read failed: status %d", resp.StatusCode)
+}
+
+func (contentServiceArtifactStore) List(c *gin.Context, project, sessionName string) ([]string, error) {
+	root := sessionArtifactsPath(sessionName)
+	var names []string
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		items, err := listProjectContent(c, project, dir)
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			if it.IsDir {
+				if err := walk(it.Path); err != nil {
+					return err
+				}
+				continue
+			}
+			names = append(names, strings.TrimPrefix(it.Path, root+"/"))
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		// No artifacts directory yet
+		if strings.Contains(err.Error(), "status 404") {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (contentServiceArtifactStore) LoadIndex(c *gin.Context, project, sessionName string) ([]Artifact, error) {
+	b, err := readProjectContentFile(c, project, artifactIndexPath(sessionName))
+	if err != nil {
+		// No index yet
+		if strings.Contains(err.Error(), "status 404") {
+			return []Artifact{}, nil
+		}
+		return nil, err
+	}
+	var index []Artifact
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("corrupt artifact index: %v", err)
+	}
+	return index, nil
+}
+
+func (contentServiceArtifactStore) SaveIndex(c *gin.Context, project, sessionName string, index []Artifact) error {
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return writeProjectContentFile(c, project, artifactIndexPath(sessionName), b)
+}
+
+func artifactSearchTermsPath(sessionName string) string {
+	return fmt.Sprintf("/sessions/%s/artifacts-search.json", sessionName)
+}
+
+func (contentServiceArtifactStore) LoadSearchTerms(c *gin.Context, project, sessionName string) (map[string][]string, error) {
+	b, err := readProjectContentFile(c, project, artifactSearchTermsPath(sessionName))
+	if err != nil {
+		// Nothing indexed yet
+		if strings.Contains(err.Error(), "status 404") {
+			return map[string][]string{}, nil
+		}
+		return nil, err
+	}
+	terms := map[string][]string{}
+	if err := json.Unmarshal(b, &terms); err != nil {
+		return nil, fmt.Errorf("corrupt artifact search terms: %v", err)
+	}
+	return terms, nil
+}
+
+func (contentServiceArtifactStore) SaveSearchTerms(c *gin.Context, project, sessionName string, terms map[string][]string) error {
+	b, err := json.Marshal(terms)
+	if err != nil {
+		return err
+	}
+	return writeProjectContentFile(c, project, artifactSearchTermsPath(sessionName), b)
+}
+
+// sanitizeArtifactName cleans a relative artifact name, rejecting traversal and absolute paths
+func sanitizeArtifactName(name string) (string, bool) {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "\\", "/"))
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" || strings.Contains(name, "..") {
+		return "", false
+	}
+	return strings.TrimPrefix(cleaned, "/"), true
+}
+
+// inferArtifactType returns a MIME type and a coarse kind for UI grouping
+func inferArtifactType(name string, data []byte) (string, string) {
+	ct := mime.TypeByExtension(strings.ToLower(path.Ext(name)))
+	if ct == "" {
+		sniff := data
+		if len(sniff) > 512 {
+			sniff = sniff[:512]
+		}
+		ct = http.DetectContentType(sniff)
+	}
+	base := strings.TrimSpace(strings.SplitN(ct, ";", 2)[0])
+	lower := strings.ToLower(path.Base(name))
+	switch {
+	case lower == "report.md":
+		return ct, "report"
+	case strings.HasPrefix(base, "image/"):
+		return ct, "image"
+	case strings.HasSuffix(lower, ".log"):
+		return ct, "log"
+	case strings.HasSuffix(lower, ".patch") || strings.HasSuffix(lower, ".diff"):
+		return ct, "patch"
+	case base == "application/gzip" || base == "application/x-gzip" || base == "application/zip" || base == "application/x-tar" ||
+		strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		return ct, "archive"
+	case base == "application/json" || base == "text/csv" || strings.HasSuffix(lower, ".yaml") || strings.HasSuffix(lower, ".yml"):
+		return ct, "data"
+	case strings.HasPrefix(base, "text/"):
+		return ct, "document"
+	}
+	return ct, "binary"
+}
+
+// canWriteSession checks via SSAR that the caller may update this session. Runners
+// authenticate with their per-session ServiceAccount token, which is granted this.
+func canWriteSession(c *gin.Context, project, sessionName string) bool {
+	reqK8s, _ := getK8sClientsForRequest(c)
+	if reqK8s == nil {
+		return false
+	}
+	ssar := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Group:     "vteam.ambient-code",
+				Resource:  "agenticsessions",
+				Verb:      "update",
+				Namespace: project,
+				Name:      sessionName,
+			},
+		},
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
+	if err != nil {
+		logErrorf(c, "artifacts: SSAR failed for %s/%s: %v", project, sessionName, err)
+		return false
+	}
+	return res.Status.Allowed
+}
+
+// readArtifactUpload returns the artifact name and bytes from either a multipart
+// form (field "file", optional field "name") or a raw body with ?name=.
+func readArtifactUpload(c *gin.Context, maxBytes int64) (string, []byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1)
+	ct := c.GetHeader("Content-Type")
+	if strings.HasPrefix(ct, "multipart/form-data") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return "", nil, fmt.Errorf("multipart field 'file' is required")
+		}
+		name := c.PostForm("name")
+		if name == "" {
+			name = fh.Filename
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxBytes+1))
+		return name, data, err
+	}
+	// Raw (possibly chunked transfer-encoded) body
+	data, err := io.ReadAll(c.Request.Body)
+	return c.Query("name"), data, err
+}
+
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/artifacts
+// uploadSessionArtifact stores an artifact via the artifact store, computes its
+// checksum and type, and records it in the session's artifact index. The
+// optional tool and tags (comma-separated) form fields or query parameters
+// label it for search; an optional ttl expires it early. Content already
+// stored in the project is linked rather than written again.
+func uploadSessionArtifact(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+
+	if !canWriteSession(c, project, sessionName) {
+		respondError(c, http.StatusForbidden, "Not permitted to upload artifacts for this session")
+		return
+	}
+
+	maxBytes := intFromEnv("ARTIFACT_MAX_UPLOAD_BYTES", defaultArtifactMaxUploadBytes)
+	rawName, data, err := readArtifactUpload(c, maxBytes)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to read upload: %v", err))
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("artifact exceeds the %d byte upload limit", maxBytes))
+		return
+	}
+	name, ok := sanitizeArtifactName(rawName)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "a valid relative artifact name is required")
+		return
+	}
+
+	spec := map[string]interface{}{}
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn != nil {
+		s, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+		if err != nil {
+			logErrorf(c, "artifacts: failed to read ProjectSettings for %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+			return
+		}
+		spec = s
+	}
+
+	// Text artifacts are redacted before anything is stored or indexed
+	var redactions map[string]int64
+	if _, kind := inferArtifactType(name, data); kind != "image" && kind != "archive" && utf8.Valid(data) {
+		data, redactions = redactionPolicyFromSpec(spec).redact(data)
+	}
+
+	// With spec.artifactEncryption only ciphertext reaches the store. Its
+	// storage key is the ciphertext digest, so encrypted uploads never share blobs.
+	stored, encryption, err := sealArtifact(c.Request.Context(), project, spec, data)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to encrypt %s for %s/%s: %v", name, project, sessionName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to encrypt artifact")
+		return
+	}
+
+	storePath := sessionArtifactsPath(sessionName) + "/" + name
+	if !enforceStorageQuota(c, project, sessionName, storePath, int64(len(stored))) {
+		return
+	}
+
+	tool, tags, msg := artifactLabelsFromUpload(c)
+	if msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	now := time.Now()
+	expiresAt, msg := artifactExpiryFromUpload(c, now)
+	if msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	contentType, kind := inferArtifactType(name, data)
+	artifact := Artifact{
+		Name:        name,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		StorageKey:  artifactStorageKey(hex.EncodeToString(sum[:])),
+		ContentType: contentType,
+		Kind:        kind,
+		UploadedAt:  now.UTC().Format(time.RFC3339),
+		UploadedBy:  requesterFromContext(c),
+		Tool:        tool,
+		Tags:        tags,
+		ExpiresAt:   expiresAt,
+	}
+	for _, n := range redactions {
+		artifact.Redactions += n
+	}
+	if encryption != nil {
+		storedSum := sha256.Sum256(stored)
+		artifact.StorageKey = artifactStorageKey(hex.EncodeToString(storedSum[:]))
+		artifact.Encryption = encryption
+	}
+
+	unlock, err := lockArtifactIndex(project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to lock index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to lock artifact index")
+		return
+	}
+	defer unlock()
+
+	index, err := artifacts.LoadIndex(c, project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to load index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to load artifact index")
+		return
+	}
+
+	var deduplicated bool
+	artifact.Path, deduplicated, err = artifacts.Put(c, project, sessionName, name, artifact.StorageKey, stored)
+	if errors.Is(err, errArtifactQuotaExceeded) {
+		auditDeny(c, "artifactStore.quota")
+		respondProblem(c, problem.New(http.StatusRequestEntityTooLarge, problem.CodeQuotaExceeded, "Project artifact storage is full").With("quota", "artifactStore"))
+		return
+	}
+	if err != nil {
+		logErrorf(c, "artifacts: failed to store %s for %s/%s: %v", name, project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to store artifact")
+		return
+	}
+	if deduplicated {
+		logInfof(c, "artifacts: %s for %s/%s reuses stored content %s", name, project, sessionName, artifact.StorageKey)
+	}
+
+	replaced := false
+	for i := range index {
+		if index[i].Name == name {
+			index[i] = artifact
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		index = append(index, artifact)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Name < index[j].Name })
+	if err := artifacts.SaveIndex(c, project, sessionName, index); err != nil {
+		logErrorf(c, "artifacts: failed to save index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "artifact stored but index update failed")
+		return
+	}
+	// Search is best effort: the artifact stays findable by name and metadata.
+	// Encrypted content is not indexed, which would store its terms in the clear.
+	searchable := data
+	if encryption != nil {
+		searchable = nil
+	}
+	if err := indexArtifactContent(c, project, sessionName, artifact, searchable); err != nil {
+		logErrorf(c, "artifacts: failed to index %s for search in %s/%s: %v", name, project, sessionName, err)
+	}
+	if artifact.Redactions > 0 {
+		logInfof(c, "artifacts: redacted %s in %s for %s/%s", describeRedactions(redactions), name, project, sessionName)
+		recordSessionRedactions(c, reqDyn, project, sessionName, redactions)
+	}
+
+	c.JSON(http.StatusCreated, artifact)
+}
+
+// DELETE /api/projects/:projectName/agentic-sessions/:sessionName/artifacts/*name
+// deleteSessionArtifact removes an artifact and its index entry. Held artifacts
+// are kept; the stored content goes once no other artifact references it.
+func deleteSessionArtifact(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	if !canWriteSession(c, project, sessionName) {
+		respondError(c, http.StatusForbidden, "Not permitted to delete artifacts of this session")
+		return
+	}
+	name, ok := sanitizeArtifactName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid artifact name")
+		return
+	}
+
+	unlock, err := lockArtifactIndex(project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to lock index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to lock artifact index")
+		return
+	}
+	defer unlock()
+
+	index, err := artifacts.LoadIndex(c, project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to load index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to load artifact index")
+		return
+	}
+	i := slices.IndexFunc(index, func(a Artifact) bool { return a.Name == name })
+	if i >= 0 && slices.Contains(index[i].Tags, artifactHoldTag) {
+		respondError(c, http.StatusConflict, "Artifact is on hold; release the hold first")
+		return
+	}
+	if err := artifacts.Delete(c, project, sessionName, name); err != nil {
+		logErrorf(c, "artifacts: failed to delete %s for %s/%s: %v", name, project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to delete artifact")
+		return
+	}
+	if i < 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	index = slices.Delete(index, i, i+1)
+	if err := artifacts.SaveIndex(c, project, sessionName, index); err != nil {
+		logErrorf(c, "artifacts: failed to save index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "artifact deleted but index update failed")
+		return
+	}
+	if terms, err := artifacts.LoadSearchTerms(c, project, sessionName); err == nil {
+		if _, ok := terms[name]; ok {
+			delete(terms, name)
+			if err := artifacts.SaveSearchTerms(c, project, sessionName, terms); err != nil {
+				logErrorf(c, "artifacts: failed to drop %s from search in %s/%s: %v", name, project, sessionName, err)
+			}
+		}
+	}
+	artifactSearchCache.invalidate(project, sessionName)
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/artifacts
+func listSessionArtifacts(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	index, err := artifacts.LoadIndex(c, project, sessionName)
+	if err != nil {
+		logErrorf(c, "artifacts: failed to load index for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "failed to load artifact index")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": index})
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/artifacts/download/*name
+func downloadSessionArtifact(c *gin.Context) {
+	serveSessionArtifact(c, false)
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/artifacts/view/*name
+func viewSessionArtifact(c *gin.Context) {
+	serveSessionArtifact(c, true)
+}
+
+// serveSessionArtifact streams an artifact to the client. Range requests are passed
+// through to the store. inline selects Content-Disposition; active content types
+// are downgraded to text/plain when viewed inline so artifacts cannot run script.
+func serveSessionArtifact(c *gin.Context, inline bool) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	name, ok := sanitizeArtifactName(c.Param("name"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, "invalid artifact name")
+		return
+	}
+
+	var entry *Artifact
+	if index, err := artifacts.LoadIndex(c, project, sessionName); err == nil {
+		for i := range index {
+			if index[i].Name == name {
+				entry = &index[i]
+				break
+			}
+		}
+	}
+
+	content, err := retrieveArtifact(c, project, sessionName, name, c.GetHeader("Range"), entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, errArtifactNotFound):
+			respondError(c, http.StatusNotFound, "Artifact not found")
+		case errors.Is(err, errArtifactRangeNotSatisf):
+			respondError(c, http.StatusRequestedRangeNotSatisfiable, "Requested range not satisfiable")
+		default:
+			logErrorf(c, "artifacts: failed to retrieve %s for %s/%s: %v", name, project, sessionName, err)
+			respondError(c, http.StatusBadGateway, "Failed to retrieve artifact")
+		}
+		return
+	}
+	defer content.Body.Close()
+
+	contentType := ""
+	if entry != nil {
+		contentType = entry.ContentType
+		c.Header("ETag", fmt.Sprintf("\"%s\"", entry.SHA256))
+	} else {
+		contentType, _ = inferArtifactType(name, nil)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+		base := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+		switch base {
+		case "text/html", "application/xhtml+xml", "image/svg+xml", "application/javascript", "text/javascript":
+			contentType = "text/plain; charset=utf-8"
+		}
+		c.Header("Content-Security-Policy", "sandbox")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": path.Base(name)}))
+	c.Header("Accept-Ranges", "bytes")
+	if content.LastModified != "" {
+		c.Header("Last-Modified", content.LastModified)
+	}
+	status := http.StatusOK
+	if content.Partial {
+		status = http.StatusPartialContent
+		c.Header("Content-Range", content.ContentRange)
+	}
+	if content.ContentLength < 0 {
+		// Unknown length: stream without Content-Length
+		c.Header("Content-Type", contentType)
+		c.Status(status)
+		if _, err := io.Copy(c.Writer, content.Body); err != nil {
+			logWarnf(c, "artifacts: stream of %s for %s/%s interrupted: %v", name, project, sessionName, err)
+		}
+		return
+	}
+	c.DataFromReader(status, content.ContentLength, contentType, content.Body, nil)
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	auditClusterScope        = "_cluster"
+	auditFilePrefix          = "audit-"
+	auditFileSuffix          = ".jsonl"
+	auditRetentionInterval   = time.Hour
+	defaultAuditRetention    = "90d"
+	defaultAuditHTTPBuffer   = 1000
+	auditDenyReasonKey       = "auditDenyReason"
+	auditDetailsKey          = "auditDetails"
+	auditOutcomeSuccess      = "success"
+	auditOutcomeFailure      = "failure"
+	auditOutcomeDenied       = "denied"
+	auditResourceProject     = "Project"
+	auditResourceSession     = "AgenticSession"
+	auditResourceRFEWorkflow = "RFEWorkflow"
+)
+
+// AuditResource identifies the object an audited action targeted
+type AuditResource struct {
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// AuditEvent is one structured audit record, written as a single JSON line
+type AuditEvent struct {
+	Time      string                 `json:"time"`
+	Actor     string                 `json:"actor"`
+	Groups    []string               `json:"groups,omitempty"`
+	Action    string                 `json:"action"`
+	Resource  AuditResource          `json:"resource"`
+	Outcome   string                 `json:"outcome"`
+	Status    int                    `json:"status,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
+	Method    string                 `json:"method,omitempty"`
+	Route     string                 `json:"route,omitempty"`
+	SourceIP  string                 `json:"sourceIP,omitempty"`
+	UserAgent string                 `json:"userAgent,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// auditSink is a destination for audit events. Write must be safe for concurrent use.
+type auditSink interface {
+	Write(ev AuditEvent) error
+}
+
+var (
+	auditSinks   []auditSink
+	auditLogDir  string
+	auditDropped atomic.Int64
+)
+
+// auditActions names the audited routes (method + route relative to /api). Other
+// mutating routes are recorded under "<method> <route>".
+var auditActions = map[string]string{
+	"POST /projects":                "project.create",
+	"PUT /projects/:projectName":    "project.update",
+	"DELETE /projects/:projectName": "project.delete",
+
+	"POST /projects/:projectName/agentic-sessions":                                      "session.create",
+	"PUT /projects/:projectName/agentic-sessions/:sessionName":                          "session.update",
+	"DELETE /projects/:projectName/agentic-sessions/:sessionName":                       "session.delete",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/clone":                   "session.clone",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/start":                   "session.start",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/stop":                    "session.stop",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/extend":                  "session.extend",
+	"PUT /projects/:projectName/agentic-sessions/:sessionName/status":                   "session.status",
+	"GET /projects/:projectName/agentic-sessions/:sessionName/debug/exec":               "session.debug.exec",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/pull-requests":           "session.pullrequest",
+	"PUT /projects/:projectName/agentic-sessions/:sessionName/displayname":              "session.rename",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/messages":                "session.message",
+	"PUT /projects/:projectName/agentic-sessions/:sessionName/workspace/*path":          "session.workspace.write",
+	"POST /projects/:projectName/agentic-sessions/:sessionName/artifacts":               "artifact.upload",
+	"GET /projects/:projectName/agentic-sessions/:sessionName/artifacts/download/*name": "artifact.download",
+	"GET /projects/:projectName/agentic-sessions/:sessionName/artifacts/view/*name":     "artifact.view",
+	"GET /projects/:projectName/agentic-sessions/:sessionName/artifacts/archive":        "artifact.archive",
+	"GET /projects/:projectName/agentic-sessions/:sessionName/artifacts/preview/*name":  "artifact.preview",
+	"PUT /projects/:projectName/agentic-sessions/:sessionName/artifacts/tags/*name":     "artifact.tag",
+	"DELETE /projects/:projectName/agentic-sessions/:sessionName/artifacts/*name":       "artifact.delete",
+
+	"POST /projects/:projectName/permissions":                             "permission.grant",
+	"DELETE /projects/:projectName/permissions/:subjectType/:subjectName": "permission.revoke",
+	"POST /projects/:projectName/keys":                                    "key.create",
+	"DELETE /projects/:projectName/keys/:keyId":                           "key.delete",
+	"POST /projects/:projectName/apikeys":                                 "apikey.create",
+	"POST /projects/:projectName/apikeys/:keyId/rotate":                   "apikey.rotate",
+	"DELETE /projects/:projectName/apikeys/:keyId":                        "apikey.revoke",
+	"PUT /projects/:projectName/settings":                                 "policy.update",
+	"POST /projects/:projectName/artifact-keys/rotate":                    "artifact.keys.rotate",
+	"PUT /projects/:projectName/runner-secrets/config":                    "runnersecrets.config.update",
+	"PUT /projects/:projectName/runner-secrets":                           "runnersecrets.update",
+
+	"PUT /log-level": "backend.loglevel",
+}
+
+// stdoutAuditSink writes JSON lines to stdout, where the cluster's log pipeline picks them up
+type stdoutAuditSink struct {
+	mu sync.Mutex
+}
+
+func (s *stdoutAuditSink) Write(ev AuditEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = os.Stdout.Write(append(b, '\n'))
+	return err
+}
+
+// fileAuditSink appends to one file per namespace per day under dir, so retention
+// can be applied per namespace by deleting whole files.
+type fileAuditSink struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func (s *fileAuditSink) Write(ev AuditEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	scope := ev.Resource.Namespace
+	if scope == "" {
+		scope = auditClusterScope
+	}
+	dir := filepath.Join(s.dir, scope)
+	day := time.Now().UTC().Format("2006-01-02")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, auditFilePrefix+day+auditFileSuffix), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// httpAuditSink posts events to an external collector from a background goroutine.
+// Events are dropped, and counted, when the buffer is full so a slow collector
+// never blocks API requests.
+type httpAuditSink struct {
+	url    string
+	token  string
+	client *http.Client
+	events chan AuditEvent
+}
+
+func newHTTPAuditSink(url, token string, buffer int) *httpAuditSink {
+	s := &httpAuditSink{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+		events: make(chan AuditEvent, buffer),
+	}
+	go s.run()
+	return s
+}
+
+func (s *httpAuditSink) Write(ev AuditEvent) error {
+	select {
+	case s.events <- ev:
+		return nil
+	default:
+		auditDropped.Add(1)
+		return fmt.Errorf("audit HTTP sink buffer full")
+	}
+}
+
+func (s *httpAuditSink) run() {
+	for ev := range s.events {
+		b, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(b))
+		if err != nil {
+			log.Printf("audit: invalid HTTP sink URL: %v", err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if s.token != "" {
+			req.Header.Set("Authorization", "Bearer "+s.token)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			auditDropped.Add(1)
+			log.Printf("audit: failed to deliver event to HTTP sink: %v", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			auditDropped.Add(1)
+			log.Printf("audit: HTTP sink returned status %d", resp.StatusCode)
+		}
+	}
+}
+
+// initAuditLogging configures sinks from AUDIT_SINKS, a comma-separated list of
+// stdout, file and http (default stdout; "none" disables auditing).
+func initAuditLogging() {
+	sinks := os.Getenv("AUDIT_SINKS")
+	if sinks == "" {
+		sinks = "stdout"
+	}
+	for _, name := range strings.Split(sinks, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "", "none":
+		case "stdout":
+			auditSinks = append(auditSinks, &stdoutAuditSink{})
+		case "file":
+			auditLogDir = os.Getenv("AUDIT_LOG_DIR")
+			if auditLogDir == "" {
+				auditLogDir = filepath.Join(stateBaseDir, "audit")
+			}
+			auditSinks = append(auditSinks, &fileAuditSink{dir: auditLogDir})
+			go runAuditRetentionLoop()
+		case "http":
+			u := os.Getenv("AUDIT_HTTP_URL")
+			if u == "" {
+				log.Printf("audit: http sink requested but AUDIT_HTTP_URL is not set")
+				continue
+			}
+			buffer := defaultAuditHTTPBuffer
+			if v, err := strconv.Atoi(os.Getenv("AUDIT_HTTP_BUFFER")); err == nil && v > 0 {
+				buffer = v
+			}
+			auditSinks = append(auditSinks, newHTTPAuditSink(u, os.Getenv("AUDIT_HTTP_TOKEN"), buffer))
+		default:
+			log.Printf("audit: ignoring unknown sink %q", name)
+		}
+	}
+	log.Printf("Audit logging sinks: %s", sinks)
+}
+
+// recordAudit fans an event out to every configured sink
+func recordAudit(ev AuditEvent) {
+	if ev.Time == "" {
+		ev.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	for _, s := range auditSinks {
+		if err := s.Write(ev); err != nil {
+			log.Printf("audit: failed to write %s event: %v", ev.Action, err)
+		}
+	}
+}
+
+// auditDeny records why the request was refused by policy. The audit middleware
+// marks the event as denied and includes the reason.
+func auditDeny(c *gin.Context, reason string) {
+	c.Set(auditDenyReasonKey, reason)
+}
+
+// auditDetail attaches an extra field to the request's audit event
+func auditDetail(c *gin.Context, key string, value interface{}) {
+	details, _ := c.Get(auditDetailsKey)
+	m, ok := details.(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		c.Set(auditDetailsKey, m)
+	}
+	m[key] = value
+}
+
+// auditMiddleware records mutating requests, artifact downloads and every
+// authentication or authorization failure once the handler has finished.
+func auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(auditSinks) == 0 {
+			return
+		}
+
+		route := strings.TrimPrefix(c.FullPath(), "/api")
+		status := c.Writer.Status()
+		denyReason := c.GetString(auditDenyReasonKey)
+		key := c.Request.Method + " " + route
+		action, known := auditActions[key]
+		mutating := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead && c.Request.Method != http.MethodOptions
+		denied := denyReason != "" || status == http.StatusUnauthorized || status == http.StatusForbidden
+		if !known && !mutating && !denied {
+			return
+		}
+		if !known {
+			action = key
+		}
+
+		ev := AuditEvent{
+			Actor:     requesterFromContext(c),
+			Action:    action,
+			Resource:  auditResourceFromContext(c),
+			Status:    status,
+			Reason:    denyReason,
+			Method:    c.Request.Method,
+			Route:     route,
+			SourceIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		if groups, ok := c.Get("userGroups"); ok {
+			ev.Groups, _ = groups.([]string)
+		}
+		switch {
+		case denied:
+			ev.Outcome = auditOutcomeDenied
+		case status >= 400:
+			ev.Outcome = auditOutcomeFailure
+		default:
+			ev.Outcome = auditOutcomeSuccess
+		}
+		if details, ok := c.Get(auditDetailsKey); ok {
+			ev.Details, _ = details.(map[string]interface{})
+		}
+		if name := strings.TrimPrefix(c.Param("name"), "/"); name != "" {
+			if ev.Details == nil {
+				ev.Details = map[string]interface{}{}
+			}
+			ev.Details["artifact"] = name
+		}
+		if ev.Reason == "" && len(c.Errors) > 0 {
+			ev.Reason = c.Errors.Last().Error()
+		}
+		recordAudit(ev)
+	}
+}
+
+func auditResourceFromContext(c *gin.Context) AuditResource {
+	project := c.Param("projectName")
+	if s := c.Param("sessionName"); s != "" && strings.Contains(c.FullPath(), "/agentic-sessions/") {
+		return AuditResource{Kind: auditResourceSession, Namespace: project, Name: s}
+	}
+	if id := c.Param("id"); id != "" && strings.Contains(c.FullPath(), "/rfe-workflows/") {
+		return AuditResource{Kind: auditResourceRFEWorkflow, Namespace: project, Name: id}
+	}
+	if strings.HasSuffix(c.FullPath(), "/agentic-sessions") {
+		return AuditResource{Kind: auditResourceSession, Namespace: project}
+	}
+	return AuditResource{Kind: auditResourceProject, Namespace: project, Name: project}
+}
+
+// runAuditRetentionLoop deletes daily audit files older than each namespace's
+// ProjectSettings spec.retention.auditLogs (AUDIT_RETENTION, default 90d, otherwise).
+func runAuditRetentionLoop() {
+	dyn, err := dynamic.NewForConfig(baseKubeConfig)
+	if err != nil {
+		log.Printf("audit: retention disabled, failed to create dynamic client: %v", err)
+		return
+	}
+	for {
+		pruneAuditLogs(dyn)
+		time.Sleep(auditRetentionInterval)
+	}
+}
+
+func pruneAuditLogs(dyn dynamic.Interface) {
+	defaultRetention, err := parseRetentionDuration(os.Getenv("AUDIT_RETENTION"))
+	if err != nil || defaultRetention == 0 {
+		defaultRetention, _ = parseRetentionDuration(defaultAuditRetention)
+	}
+	entries, err := os.ReadDir(auditLogDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("audit: failed to read %s: %v", auditLogDir, err)
+		}
+		return
+	}
+	clusterPolicy, err := loadClusterPolicy(context.Background())
+	if err != nil {
+		log.Printf("audit: failed to read cluster policy, skipping pruning: %v", err)
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		retention := defaultRetention
+		if e.Name() != auditClusterScope {
+			retention = auditRetentionForProject(dyn, e.Name(), defaultRetention)
+		}
+		// Never prune below the organization-wide floor
+		retention = max(retention, clusterPolicy.retentionFloor("auditLogs"))
+		cutoff := time.Now().UTC().Add(-retention).Format("2006-01-02")
+		dir := filepath.Join(auditLogDir, e.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			day := strings.TrimSuffix(strings.TrimPrefix(f.Name(), auditFilePrefix), auditFileSuffix)
+			if f.IsDir() || len(day) != len("2006-01-02") || day >= cutoff {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+				log.Printf("audit: failed to remove expired log %s/%s: %v", e.Name(), f.Name(), err)
+			}
+		}
+	}
+}
+
+func auditRetentionForProject(dyn dynamic.Interface, project string, fallback time.Duration) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	obj, err := dyn.Resource(getProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+	if err != nil {
+		return fallback
+	}
+	raw, _, _ := unstructured.NestedString(obj.Object, "spec", "retention", "auditLogs")
+	if raw == "" {
+		return fallback
+	}
+	d, err := parseRetentionDuration(raw)
+	if err != nil || d == 0 {
+		log.Printf("audit: ignoring retention.auditLogs %q in %s: %v", raw, project, err)
+		return fallback
+	}
+	return d
+}
+
+// parseRetentionDuration accepts Go durations ("720h") and whole days ("30d").
+func parseRetentionDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authnv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errTokenInvalid means the token was checked and rejected, as opposed to the
+// check itself being unavailable.
+var errTokenInvalid = errors.New("invalid token")
+
+const (
+	jwksRefreshInterval    = time.Hour
+	jwksMinForcedRefresh   = 30 * time.Second
+	tokenClockLeeway       = time.Minute
+	identityCacheTTL       = time.Minute
+	identityCacheMaxTokens = 2048
+)
+
+// UserIdentity is the caller resolved from their bearer token
+type UserIdentity struct {
+	UserID   string
+	UserName string
+	Email    string
+	Groups   []string
+	// Source is "oidc", "tokenreview", "runner" or "runner-cert"
+	Source string
+	// RunnerNamespace and RunnerSession name the session whose runner token
+	// or client certificate this is
+	RunnerNamespace string
+	RunnerSession   string
+	// UID and Extra are the Kubernetes user's as reported by TokenReview,
+	// passed on when impersonating the caller
+	UID   string
+	Extra map[string][]string
+}
+
+// oidcVerifier validates JWTs from a single OIDC issuer against its published JWKS.
+type oidcVerifier struct {
+	issuer        string
+	audience      string
+	usernameClaim string
+	groupsClaim   string
+
+	mu          sync.RWMutex
+	jwksURI     string
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+var (
+	oidc     *oidcVerifier
+	oidcOnce sync.Once
+
+	identityCacheMu sync.Mutex
+	identityCache   = map[string]cachedIdentity{}
+)
+
+type cachedIdentity struct {
+	identity *UserIdentity
+	expires  time.Time
+}
+
+// getOIDCVerifier returns the verifier configured by OIDC_ISSUER_URL, or nil
+func getOIDCVerifier() *oidcVerifier {
+	oidcOnce.Do(func() {
+		issuer := strings.TrimRight(strings.TrimSpace(os.Getenv("OIDC_ISSUER_URL")), "/")
+		if issuer == "" {
+			return
+		}
+		oidc = &oidcVerifier{
+			issuer:        issuer,
+			audience:      strings.TrimSpace(os.Getenv("OIDC_AUDIENCE")),
+			usernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+			groupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+		}
+		if oidc.usernameClaim == "" {
+			oidc.usernameClaim = "preferred_username"
+		}
+		if oidc.groupsClaim == "" {
+			oidc.groupsClaim = "groups"
+		}
+	})
+	return oidc
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	dec := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := dec.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := dec.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := dec.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := dec.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func fetchJSON(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// refreshKeys runs OIDC discovery (once) and reloads the JWKS. Forced refreshes,
+// used when a token names an unknown kid, are rate limited.
+func (v *oidcVerifier) refreshKeys(ctx context.Context, force bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	if !force && v.keys != nil && now.Sub(v.fetchedAt) < jwksRefreshInterval {
+		return nil
+	}
+	if now.Sub(v.lastAttempt) < jwksMinForcedRefresh && v.keys != nil {
+		return nil
+	}
+	v.lastAttempt = now
+
+	if v.jwksURI == "" {
+		var disc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := fetchJSON(ctx, v.issuer+"/.well-known/openid-configuration", &disc); err != nil {
+			return fmt.Errorf("oidc discovery: %v", err)
+		}
+		if strings.TrimRight(disc.Issuer, "/") != v.issuer || disc.JWKSURI == "" {
+			return fmt.Errorf("oidc discovery: issuer mismatch or missing jwks_uri")
+		}
+		v.jwksURI = disc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := fetchJSON(ctx, v.jwksURI, &set); err != nil {
+		return fmt.Errorf("fetch jwks: %v", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pk, err := k.publicKey()
+		if err != nil {
+			log.Printf("oidc: skipping jwk %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = pk
+	}
+	v.keys = keys
+	v.fetchedAt = now
+	return nil
+}
+
+func (v *oidcVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if err := v.refreshKeys(ctx, false); err != nil {
+		return nil, err
+	}
+	v.mu.RLock()
+	k, ok := v.keys[kid]
+	v.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+	// Unknown kid: the issuer may have rotated keys
+	if err := v.refreshKeys(ctx, true); err != nil {
+		return nil, err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", errTokenInvalid, kid)
+}
+
+// unverifiedClaims decodes a JWT's claims without checking its signature,
+// used to route a token to a validator
+func unverifiedClaims(token string) map[string]interface{} {
+	segs := strings.Split(token, ".")
+	if len(segs) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segs[1])
+	if err != nil {
+		return nil
+	}
+	var claims map[string]interface{}
+	_ = json.Unmarshal(payload, &claims)
+	return claims
+}
+
+// issuerOf returns the unverified iss claim
+func issuerOf(token string) string {
+	iss, _ := unverifiedClaims(token)["iss"].(string)
+	return strings.TrimRight(iss, "/")
+}
+
+// verify checks signature, issuer, audience and validity window, then maps claims to a UserIdentity.
+func (v *oidcVerifier) verify(ctx context.Context, token string) (*UserIdentity, time.Time, error) {
+	segs := strings.Split(token, ".")
+	if len(segs) != 3 {
+		return nil, time.Time{}, fmt.Errorf("%w: malformed JWT", errTokenInvalid)
+	}
+	dec := base64.RawURLEncoding
+	headerJSON, err1 := dec.DecodeString(segs[0])
+	payloadJSON, err2 := dec.DecodeString(segs[1])
+	sig, err3 := dec.DecodeString(segs[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: malformed JWT encoding", errTokenInvalid)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: malformed JWT header", errTokenInvalid)
+	}
+
+	var hash crypto.Hash
+	switch header.Alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	default:
+		return nil, time.Time{}, fmt.Errorf("%w: unsupported alg %q", errTokenInvalid, header.Alg)
+	}
+	pub, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	h := hash.New()
+	h.Write([]byte(segs[0] + "." + segs[1]))
+	digest := h.Sum(nil)
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(header.Alg, "RS") || rsa.VerifyPKCS1v15(k, hash, digest, sig) != nil {
+			return nil, time.Time{}, fmt.Errorf("%w: bad signature", errTokenInvalid)
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(header.Alg, "ES") || len(sig) != 2*size ||
+			!ecdsa.Verify(k, digest, new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])) {
+			return nil, time.Time{}, fmt.Errorf("%w: bad signature", errTokenInvalid)
+		}
+	default:
+		return nil, time.Time{}, fmt.Errorf("%w: unsupported key", errTokenInvalid)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: malformed claims", errTokenInvalid)
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != v.issuer {
+		return nil, time.Time{}, fmt.Errorf("%w: issuer mismatch", errTokenInvalid)
+	}
+	if v.audience != "" && !audienceContains(claims["aud"], v.audience) {
+		return nil, time.Time{}, fmt.Errorf("%w: audience mismatch", errTokenInvalid)
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("%w: missing exp", errTokenInvalid)
+	}
+	expires := time.Unix(int64(exp), 0)
+	if now.After(expires.Add(tokenClockLeeway)) {
+		return nil, time.Time{}, fmt.Errorf("%w: token expired", errTokenInvalid)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(tokenClockLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, time.Time{}, fmt.Errorf("%w: token not yet valid", errTokenInvalid)
+	}
+
+	ident := &UserIdentity{Source: "oidc"}
+	ident.UserID, _ = claims["sub"].(string)
+	ident.UserName, _ = claims[v.usernameClaim].(string)
+	if ident.UserName == "" {
+		ident.UserName = ident.UserID
+	}
+	ident.Email, _ = claims["email"].(string)
+	switch g := claims[v.groupsClaim].(type) {
+	case []interface{}:
+		for _, item := range g {
+			if s, ok := item.(string); ok {
+				ident.Groups = append(ident.Groups, s)
+			}
+		}
+	case string:
+		ident.Groups = strings.Split(g, ",")
+	}
+	return ident, expires, nil
+}
+
+func audienceContains(aud interface{}, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []interface{}:
+		for _, item := range a {
+			if s, ok := item.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reviewToken authenticates a token with the Kubernetes TokenReview API. This covers
+// OpenShift OAuth access tokens and ServiceAccount tokens. Audiences, when set,
+// replace the API server's own as the audiences the token must be bound to.
+func reviewToken(ctx context.Context, token string, audiences ...string) (*UserIdentity, error) {
+	if k8sClient == nil {
+		return nil, fmt.Errorf("no backend client for TokenReview")
+	}
+	tr := &authnv1.TokenReview{Spec: authnv1.TokenReviewSpec{Token: token, Audiences: audiences}}
+	res, err := k8sClient.AuthenticationV1().TokenReviews().Create(ctx, tr, v1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("token review: %v", err)
+	}
+	if !res.Status.Authenticated {
+		return nil, fmt.Errorf("%w: %s", errTokenInvalid, res.Status.Error)
+	}
+	for _, want := range audiences {
+		if !slices.Contains(res.Status.Audiences, want) {
+			return nil, fmt.Errorf("%w: token is not bound to audience %s", errTokenInvalid, want)
+		}
+	}
+	u := res.Status.User
+	ident := &UserIdentity{UserID: u.UID, UserName: u.Username, Groups: u.Groups, Source: "tokenreview", UID: u.UID}
+	if len(u.Extra) > 0 {
+		ident.Extra = make(map[string][]string, len(u.Extra))
+		for k, v := range u.Extra {
+			ident.Extra[k] = v
+		}
+	}
+	if ident.UserID == "" {
+		ident.UserID = u.Username
+	}
+	if emails := u.Extra["email"]; len(emails) > 0 {
+		ident.Email = emails[0]
+	}
+	return ident, nil
+}
+
+// authenticateToken resolves the caller's identity. Tokens issued by the configured
+// OIDC issuer are verified locally, runner tokens are reviewed for the backend's
+// audience and everything else goes to TokenReview. Results are cached briefly
+// by token hash.
+func authenticateToken(ctx context.Context, token string) (*UserIdentity, error) {
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(sum[:])
+	now := time.Now()
+	identityCacheMu.Lock()
+	if cached, ok := identityCache[cacheKey]; ok && now.Before(cached.expires) {
+		identityCacheMu.Unlock()
+		return cached.identity, nil
+	}
+	identityCacheMu.Unlock()
+
+	var ident *UserIdentity
+	var err error
+	expires := now.Add(identityCacheTTL)
+	if v := getOIDCVerifier(); v != nil && issuerOf(token) == v.issuer {
+		var tokenExp time.Time
+		ident, tokenExp, err = v.verify(ctx, token)
+		if err == nil && tokenExp.Before(expires) {
+			expires = tokenExp
+		}
+	} else if isRunnerToken(token) {
+		ident, err = reviewRunnerToken(ctx, token)
+	} else {
+		ident, err = reviewToken(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identityCacheMu.Lock()
+	if len(identityCache) >= identityCacheMaxTokens {
+		identityCache = map[string]cachedIdentity{}
+	}
+	identityCache[cacheKey] = cachedIdentity{identity: ident, expires: expires}
+	identityCacheMu.Unlock()
+	return ident, nil
+}
+
+// bearerTokenFromRequest mirrors getK8sClientsForRequest's token selection
+func bearerTokenFromRequest(c *gin.Context) string {
+	if raw := strings.TrimSpace(c.GetHeader("Authorization")); raw != "" {
+		parts := strings.SplitN(raw, " ", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "bearer") {
+			return strings.TrimSpace(parts[1])
+		}
+		return raw
+	}
+	return strings.TrimSpace(c.GetHeader("X-Forwarded-Access-Token"))
+}
+
+// tokenIdentityMiddleware validates the caller's bearer token and sets userID,
+// userName, userEmail and userGroups from it, replacing forwarded headers,
+// which any client can send. Requests without a token carry no identity.
+// Rejected tokens get 401, and 503 while validation is unavailable, so no
+// request proceeds under an unverified identity.
+func tokenIdentityMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// A verified runner client certificate identifies the session by itself
+		if ident, ok := runnerCertIdentity(c); ok {
+			setRequestIdentity(c, ident)
+			c.Next()
+			return
+		}
+		token := bearerTokenFromRequest(c)
+		if token == "" {
+			clearRequestIdentity(c)
+			c.Next()
+			return
+		}
+		ident, err := authenticateToken(c.Request.Context(), token)
+		if err != nil {
+			clearRequestIdentity(c)
+			if errors.Is(err, errTokenInvalid) {
+				log.Printf("Rejected token for %s: %v", c.FullPath(), err)
+				abortWithError(c, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+			log.Printf("Token validation unavailable for %s: %v", c.FullPath(), err)
+			abortWithError(c, http.StatusServiceUnavailable, "Token validation is unavailable, retry later")
+			return
+		}
+		setRequestIdentity(c, ident)
+		c.Next()
+	}
+}
+
+// requestIdentityKey holds the request's validated *UserIdentity
+const requestIdentityKey = "identity"
+
+// clearRequestIdentity drops the identity set from forwarded headers
+func clearRequestIdentity(c *gin.Context) {
+	for _, k := range []string{"userID", "userName", "userEmail", "userGroups", requestIdentityKey} {
+		delete(c.Keys, k)
+	}
+}
+
+// setRequestIdentity records an authenticated identity on the request. Fields
+// the identity lacks stay unset rather than falling back to forwarded headers.
+func setRequestIdentity(c *gin.Context, ident *UserIdentity) {
+	clearRequestIdentity(c)
+	if ident.UserID != "" {
+		c.Set("userID", ident.UserID)
+	}
+	if ident.UserName != "" {
+		c.Set("userName", ident.UserName)
+	}
+	if ident.Email != "" {
+		c.Set("userEmail", ident.Email)
+	}
+	if len(ident.Groups) > 0 {
+		c.Set("userGroups", ident.Groups)
+	}
+	c.Set("authSource", ident.Source)
+	c.Set(requestIdentityKey, ident)
+	if ident.RunnerSession != "" {
+		c.Set(runnerSessionKey, ident.RunnerSession)
+		c.Set(runnerNamespaceKey, ident.RunnerNamespace)
+	}
+}
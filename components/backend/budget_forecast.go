@@ -0,0 +1,221 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"ambient-code-shared/money"
+)
+
+const (
+	defaultAnomalyZScore = 3.0
+	// Daily spend is compared with up to this many previous days, and only
+	// once a namespace has spent on at least anomalyMinDays of them
+	anomalyLookbackDays = 28
+	anomalyMinDays      = 7
+)
+
+// budgetPeriod returns the budget month containing now per the project's
+// budget.resetDay and budget.timezone (default the 1st, UTC), as the operator
+// computes it
+func budgetPeriod(obj *unstructured.Unstructured, now time.Time) (time.Time, time.Time) {
+	resetDay, loc := 1, time.UTC
+	if obj != nil {
+		if budget, ok, _ := unstructured.NestedMap(obj.Object, "spec", "budget"); ok {
+			if v, ok := intFromSpec(budget, "resetDay"); ok && v >= 1 && v <= 28 {
+				resetDay = int(v)
+			}
+			if tz, _ := budget["timezone"].(string); tz != "" {
+				if l, err := time.LoadLocation(tz); err == nil {
+					loc = l
+				}
+			}
+		}
+	}
+	local := now.In(loc)
+	start := time.Date(local.Year(), local.Month(), resetDay, 0, 0, 0, 0, loc)
+	if local.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start, start.AddDate(0, 1, 0)
+}
+
+// DailySpend is the cost of the sessions started on one day
+type DailySpend struct {
+	Date     string    `json:"date"`
+	SpentUSD money.USD `json:"spentUSD"`
+}
+
+// SpendAnomaly compares today's spend with the previous days'
+type SpendAnomaly struct {
+	Date      string    `json:"date"`
+	SpentUSD  money.USD `json:"spentUSD"`
+	MeanUSD   money.USD `json:"meanUSD"`
+	StdDevUSD money.USD `json:"stdDevUSD"`
+	ZScore    float64   `json:"zScore"`
+	Threshold float64   `json:"threshold"`
+	Anomalous bool      `json:"anomalous"`
+}
+
+// BudgetForecast is the response of GET /api/projects/:projectName/budget/forecast
+type BudgetForecast struct {
+	Namespace    string    `json:"namespace"`
+	Month        string    `json:"month"`
+	PeriodStart  string    `json:"periodStart"`
+	PeriodEnd    string    `json:"periodEnd"`
+	LimitUSD     money.USD `json:"limitUSD,omitempty"`
+	SpentUSD     money.USD `json:"spentUSD"`
+	DailyRateUSD money.USD `json:"dailyRateUSD"`
+	ProjectedUSD money.USD `json:"projectedUSD"`
+	// OverLimit is set when the projection exceeds a configured limit
+	OverLimit bool         `json:"overLimit"`
+	Daily     []DailySpend `json:"daily"`
+	// Today is omitted until there is enough history to compare against
+	Today *SpendAnomaly `json:"today,omitempty"`
+}
+
+// anomalyThreshold is the project's budget.anomalyZScore, default 3
+func anomalyThreshold(obj *unstructured.Unstructured) float64 {
+	if obj != nil {
+		if raw, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "budget", "anomalyZScore"); found && numberFromSpec(raw) > 0 {
+			return numberFromSpec(raw)
+		}
+	}
+	return defaultAnomalyZScore
+}
+
+// forecastSpend projects the period's spend at its run rate so far and scores
+// today's spend against the previous days in loc
+func forecastSpend(sessions []unstructured.Unstructured, start, end, now time.Time, threshold float64) BudgetForecast {
+	loc := start.Location()
+	day := func(t time.Time) string { return t.In(loc).Format("2006-01-02") }
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	lookback := today.AddDate(0, 0, -anomalyLookbackDays)
+
+	f := BudgetForecast{
+		Month:       start.Format("2006-01"),
+		PeriodStart: start.UTC().Format(time.RFC3339),
+		PeriodEnd:   end.UTC().Format(time.RFC3339),
+		Daily:       []DailySpend{},
+	}
+	byDay := map[string]money.USD{}
+	var first time.Time
+	for i := range sessions {
+		started := sessionStartTime(&sessions[i])
+		if started.After(now) {
+			continue
+		}
+		cost := sessionCostUSD(&sessions[i])
+		if !started.Before(start) && started.Before(end) {
+			f.SpentUSD += cost
+		}
+		if !started.Before(lookback) {
+			byDay[day(started)] += cost
+		}
+		if first.IsZero() || started.Before(first) {
+			first = started
+		}
+	}
+
+	// Run rate over at least a day, so the first hours do not extrapolate wildly
+	elapsed := int64(max(now.Sub(start), 24*time.Hour) / time.Second)
+	f.DailyRateUSD = f.SpentUSD.MulDiv(86400, elapsed)
+	f.ProjectedUSD = f.SpentUSD + f.SpentUSD.MulDiv(int64(max(end.Sub(now), 0)/time.Second), elapsed)
+	for d := start; !d.After(today); d = d.AddDate(0, 0, 1) {
+		f.Daily = append(f.Daily, DailySpend{Date: day(d), SpentUSD: byDay[day(d)]})
+	}
+
+	// Days before the namespace's first session are not history
+	var history []float64
+	for d := today.AddDate(0, 0, -1); !d.Before(lookback); d = d.AddDate(0, 0, -1) {
+		if first.IsZero() || d.AddDate(0, 0, 1).Before(first) {
+			break
+		}
+		history = append(history, byDay[day(d)].Float64())
+	}
+	if len(history) < anomalyMinDays {
+		return f
+	}
+	var mean, variance float64
+	for _, v := range history {
+		mean += v
+	}
+	mean /= float64(len(history))
+	for _, v := range history {
+		variance += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(history)))
+	a := &SpendAnomaly{
+		Date:      day(today),
+		SpentUSD:  byDay[day(today)],
+		MeanUSD:   money.FromFloat(mean),
+		StdDevUSD: money.FromFloat(stddev),
+		Threshold: threshold,
+	}
+	// Flat history has no spread to measure against
+	if stddev > 0 {
+		a.ZScore = (a.SpentUSD.Float64() - mean) / stddev
+		a.Anomalous = a.ZScore > threshold
+	}
+	f.Today = a
+	return f
+}
+
+// GET /api/projects/:projectName/budget/forecast
+// getBudgetForecast projects the budget month's spend to its end at the daily
+// run rate so far, and scores today's spend against up to 28 previous days as a
+// z-score (flagged above budget.anomalyZScore, default 3). The operator sends
+// budget.warning when either crosses its threshold.
+func getBudgetForecast(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	ctx := c.Request.Context()
+	settings, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logErrorf(c, "Failed to get project settings of %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to get project settings")
+			return
+		}
+		settings = nil
+	}
+	clusterPolicy, err := loadClusterPolicy(ctx)
+	if err != nil {
+		logErrorf(c, "Failed to load cluster policy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to load cluster policy")
+		return
+	}
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		sessions = list.Items
+	}
+
+	now := time.Now()
+	start, end := budgetPeriod(settings, now)
+	f := forecastSpend(sessions, start, end, now, anomalyThreshold(settings))
+	f.Namespace = project
+	if limit, _ := projectBudget(settings, clusterPolicy); limit > 0 {
+		f.LimitUSD = limit
+		f.OverLimit = f.ProjectedUSD > limit
+	}
+	c.JSON(http.StatusOK, f)
+}
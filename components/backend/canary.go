@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SessionRunnerStatus mirrors status.runner, set by the operator when it creates
+// the runner Job.
+type SessionRunnerStatus struct {
+	Image string `json:"image,omitempty"`
+	Track string `json:"track,omitempty"`
+}
+
+// GET /api/projects/:projectName/runner-canary
+// getRunnerCanary returns the namespace's runner canary configuration together with
+// the operator's latest evaluation (per-track failure rate and cost, rollback state).
+func getRunnerCanary(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	obj, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+
+	config, found, _ := unstructured.NestedMap(obj.Object, "spec", "runnerCanary")
+	if !found {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	status, _, _ := unstructured.NestedMap(obj.Object, "status", "runnerCanary")
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": true,
+		"config":  config,
+		"status":  status,
+	})
+}
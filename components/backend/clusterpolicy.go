@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"ambient-code-shared/money"
+)
+
+// clusterPolicyName is the singleton ClusterAmbientPolicy evaluated by the backend and operator
+const clusterPolicyName = "default"
+
+// ClusterPolicy is the organization-wide ClusterAmbientPolicy as returned by
+// GET /api/cluster-policy. Namespaces inherit it and may only tighten it.
+type ClusterPolicy struct {
+	BlockedModels []string `json:"blockedModels,omitempty"`
+	BlockedTools  []string `json:"blockedTools,omitempty"`
+	// MaxMonthlyCostUSD caps every namespace's budget.monthlyLimitUSD
+	MaxMonthlyCostUSD money.USD `json:"maxMonthlyCostUSD,omitempty"`
+	// Retention floors keyed by ProjectSettings retention field (sessions, artifacts, auditLogs)
+	RetentionFloors map[string]string `json:"retentionFloors,omitempty"`
+	// ExternalPolicy is not shown to projects
+	ExternalPolicy *ExternalPolicy `json:"-"`
+}
+
+// getClusterPolicyResource returns the GroupVersionResource for the cluster-scoped ClusterAmbientPolicy
+func getClusterPolicyResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "clusterambientpolicies",
+	}
+}
+
+var (
+	clusterPolicyClientOnce sync.Once
+	clusterPolicyClient     dynamic.Interface
+	clusterPolicyClientErr  error
+)
+
+// clusterPolicyFieldFloors maps ProjectSettings retention fields to the
+// ClusterAmbientPolicy retention floor that bounds them
+var clusterPolicyFieldFloors = map[string]string{
+	"sessions":  "minSessions",
+	"artifacts": "minArtifacts",
+	"auditLogs": "minAuditLogs",
+}
+
+// loadClusterPolicy reads the ClusterAmbientPolicy with the backend ServiceAccount;
+// every user is subject to it, whether or not they can read it. A missing
+// policy imposes no constraints.
+func loadClusterPolicy(ctx context.Context) (ClusterPolicy, error) {
+	var p ClusterPolicy
+	clusterPolicyClientOnce.Do(func() {
+		clusterPolicyClient, clusterPolicyClientErr = dynamic.NewForConfig(baseKubeConfig)
+	})
+	if clusterPolicyClientErr != nil {
+		return p, clusterPolicyClientErr
+	}
+	obj, err := clusterPolicyClient.Resource(getClusterPolicyResource()).Get(ctx, clusterPolicyName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	p.BlockedModels, _, _ = unstructured.NestedStringSlice(spec, "blockedModels")
+	p.BlockedTools, _, _ = unstructured.NestedStringSlice(spec, "blockedTools")
+	if raw, found, _ := unstructured.NestedFieldNoCopy(spec, "budget", "maxMonthlyCostUSD"); found {
+		p.MaxMonthlyCostUSD, _ = money.FromSpec(raw)
+	}
+	for _, floor := range clusterPolicyFieldFloors {
+		if v, _, _ := unstructured.NestedString(spec, "retention", floor); v != "" {
+			if p.RetentionFloors == nil {
+				p.RetentionFloors = map[string]string{}
+			}
+			p.RetentionFloors[floor] = v
+		}
+	}
+	p.ExternalPolicy = parseExternalPolicy(spec)
+	return p, nil
+}
+
+// numberFromSpec converts a JSON number decoded as int64 or float64
+func numberFromSpec(raw interface{}) float64 {
+	switch n := raw.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// retentionFloor returns the parsed floor for a ProjectSettings retention field
+func (p ClusterPolicy) retentionFloor(field string) time.Duration {
+	d, err := parseRetentionDuration(p.RetentionFloors[clusterPolicyFieldFloors[field]])
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// blockedModels unions the cluster blocklist with the project's sessionPolicy.blockedModels
+func (p ClusterPolicy) blockedModels(projectSpec map[string]interface{}) []string {
+	return mergedBlocklist(p.BlockedModels, projectSpec, "blockedModels")
+}
+
+// blockedTools unions the cluster blocklist with the project's sessionPolicy.blockedTools
+func (p ClusterPolicy) blockedTools(projectSpec map[string]interface{}) []string {
+	return mergedBlocklist(p.BlockedTools, projectSpec, "blockedTools")
+}
+
+func mergedBlocklist(cluster []string, projectSpec map[string]interface{}, field string) []string {
+	out := slices.Clone(cluster)
+	ns, _, _ := unstructured.NestedStringSlice(projectSpec, "sessionPolicy", field)
+	for _, item := range ns {
+		if item = strings.TrimSpace(item); item != "" && !slices.Contains(out, item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// validateAgainstClusterPolicy rejects ProjectSettings that loosen the cluster
+// policy: retention below a floor or a budget above the cluster maximum. Only
+// fields that differ from current are checked, so a project configured before
+// the cluster policy tightened can still save unrelated changes.
+func validateAgainstClusterPolicy(current, spec map[string]interface{}, p ClusterPolicy) []PolicyFieldError {
+	v := &policyValidator{}
+	if rt, ok := spec["retention"].(map[string]interface{}); ok {
+		for _, field := range []string{"sessions", "artifacts", "auditLogs"} {
+			floor := p.retentionFloor(field)
+			raw, _ := rt[field].(string)
+			if floor == 0 || raw == "" {
+				continue
+			}
+			if old, _, _ := unstructured.NestedString(current, "retention", field); old == raw {
+				continue
+			}
+			if d, err := parseRetentionDuration(raw); err == nil && d > 0 && d < floor {
+				v.add("retention."+field, "must be at least %s (cluster policy)", p.RetentionFloors[clusterPolicyFieldFloors[field]])
+			}
+		}
+	}
+	if p.MaxMonthlyCostUSD > 0 {
+		raw, found, _ := unstructured.NestedFieldNoCopy(spec, "budget", "monthlyLimitUSD")
+		old, _, _ := unstructured.NestedFieldNoCopy(current, "budget", "monthlyLimitUSD")
+		limit, _ := money.FromSpec(raw)
+		oldLimit, _ := money.FromSpec(old)
+		if found && limit != oldLimit && limit > p.MaxMonthlyCostUSD {
+			v.add("budget.monthlyLimitUSD", "must be at most %s (cluster policy)", p.MaxMonthlyCostUSD)
+		}
+	}
+	return v.errs
+}
+
+// checkBlockedModel rejects models on the cluster or project blocklist
+func checkBlockedModel(ctx context.Context, projectSpec map[string]interface{}, model string) (*sessionPolicyViolation, error) {
+	if strings.TrimSpace(model) == "" {
+		return nil, nil
+	}
+	p, err := loadClusterPolicy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read ClusterAmbientPolicy: %v", err)
+	}
+	for _, pattern := range p.blockedModels(projectSpec) {
+		if ok, _ := path.Match(pattern, model); ok {
+			return &sessionPolicyViolation{
+				Status:  http.StatusForbidden,
+				Message: fmt.Sprintf("model %q is blocked by policy", model),
+				Audit:   fmt.Sprintf("blockedModels: %s matches %s", model, pattern),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// GET /api/cluster-policy
+// getClusterPolicy returns the organization-wide policy so the settings form can
+// show the bounds a project may not loosen.
+func getClusterPolicy(c *gin.Context) {
+	if reqK8s, _ := getK8sClientsForRequest(c); reqK8s == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	p, err := loadClusterPolicy(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
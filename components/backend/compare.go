@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxResultDiffLines bounds the line diff of result documents
+const maxResultDiffLines = 2000
+
+type FieldChange struct {
+	Path  string      `json:"path"`
+	Left  interface{} `json:"left,omitempty"`
+	Right interface{} `json:"right,omitempty"`
+}
+
+type SessionMetrics struct {
+	Phase           string   `json:"phase,omitempty"`
+	TotalCostUSD    *float64 `json:"totalCostUsd,omitempty"`
+	DurationSeconds *float64 `json:"durationSeconds,omitempty"`
+	NumTurns        int      `json:"numTurns,omitempty"`
+}
+
+type SessionComparison struct {
+	Left          string            `json:"left"`
+	Right         string            `json:"right"`
+	SpecChanges   []FieldChange     `json:"specChanges"`
+	ConfigChanges []FieldChange     `json:"configChanges"`
+	LeftMetrics   SessionMetrics    `json:"leftMetrics"`
+	RightMetrics  SessionMetrics    `json:"rightMetrics"`
+	CostDeltaUSD  *float64          `json:"costDeltaUsd,omitempty"`
+	DurationDelta *float64          `json:"durationDeltaSeconds,omitempty"`
+	ResultChanged bool              `json:"resultChanged"`
+	ResultDiff    []string          `json:"resultDiff,omitempty"`
+	UsageChanges  []FieldChange     `json:"usageChanges,omitempty"`
+	Notes         map[string]string `json:"notes,omitempty"`
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/compare/:otherName
+// compareSessions diffs two sessions' specs, resolved runner configuration, costs,
+// durations and result documents.
+func compareSessions(c *gin.Context) {
+	project := c.GetString("project")
+	leftName := c.Param("sessionName")
+	rightName := c.Param("otherName")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	gvr := getAgenticSessionV1Alpha1Resource()
+	sessions := make([]*unstructured.Unstructured, 0, 2)
+	for _, name := range []string{leftName, rightName} {
+		item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				respondError(c, http.StatusNotFound, fmt.Sprintf("Session %s not found", name))
+				return
+			}
+			logErrorf(c, "Failed to get agentic session %s in project %s: %v", name, project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+			return
+		}
+		sessions = append(sessions, item)
+	}
+
+	c.JSON(http.StatusOK, buildSessionComparison(c, reqK8s, project, sessions[0], sessions[1]))
+}
+
+func buildSessionComparison(c *gin.Context, reqK8s *kubernetes.Clientset, project string, left, right *unstructured.Unstructured) SessionComparison {
+	out := SessionComparison{Left: left.GetName(), Right: right.GetName(), Notes: map[string]string{}}
+
+	leftSpec, _, _ := unstructured.NestedMap(left.Object, "spec")
+	rightSpec, _, _ := unstructured.NestedMap(right.Object, "spec")
+	out.SpecChanges = diffMaps(leftSpec, rightSpec)
+
+	leftEnv, lok := resolvedRunnerConfig(c, reqK8s, project, left)
+	rightEnv, rok := resolvedRunnerConfig(c, reqK8s, project, right)
+	if lok && rok {
+		out.ConfigChanges = diffMaps(leftEnv, rightEnv)
+	} else {
+		out.ConfigChanges = []FieldChange{}
+		out.Notes["config"] = "runner Job no longer exists for one or both sessions"
+	}
+
+	leftStatus, _, _ := unstructured.NestedMap(left.Object, "status")
+	rightStatus, _, _ := unstructured.NestedMap(right.Object, "status")
+	out.LeftMetrics = sessionMetrics(leftStatus)
+	out.RightMetrics = sessionMetrics(rightStatus)
+	if out.LeftMetrics.TotalCostUSD != nil && out.RightMetrics.TotalCostUSD != nil {
+		d := *out.RightMetrics.TotalCostUSD - *out.LeftMetrics.TotalCostUSD
+		out.CostDeltaUSD = &d
+	}
+	if out.LeftMetrics.DurationSeconds != nil && out.RightMetrics.DurationSeconds != nil {
+		d := *out.RightMetrics.DurationSeconds - *out.LeftMetrics.DurationSeconds
+		out.DurationDelta = &d
+	}
+
+	leftUsage, _ := leftStatus["usage"].(map[string]interface{})
+	rightUsage, _ := rightStatus["usage"].(map[string]interface{})
+	out.UsageChanges = diffMaps(leftUsage, rightUsage)
+
+	leftResult := sessionResultDocument(c, project, leftStatus)
+	rightResult := sessionResultDocument(c, project, rightStatus)
+	out.ResultChanged = leftResult != rightResult
+	if out.ResultChanged {
+		out.ResultDiff = lineDiff(leftResult, rightResult)
+	}
+	if len(out.Notes) == 0 {
+		out.Notes = nil
+	}
+	return out
+}
+
+// resolvedRunnerConfig returns the literal env of the runner container, which
+// reflects defaults and policy the operator resolved at launch. Secret-backed
+// values are reported by reference only.
+func resolvedRunnerConfig(c *gin.Context, reqK8s *kubernetes.Clientset, project string, session *unstructured.Unstructured) (map[string]interface{}, bool) {
+	jobName, _, _ := unstructured.NestedString(session.Object, "status", "jobName")
+	if jobName == "" {
+		jobName = fmt.Sprintf("%s-job", session.GetName())
+	}
+	job, err := reqK8s.BatchV1().Jobs(project).Get(c.Request.Context(), jobName, v1.GetOptions{})
+	if err != nil || len(job.Spec.Template.Spec.Containers) == 0 {
+		return nil, false
+	}
+	out := map[string]interface{}{}
+	container := job.Spec.Template.Spec.Containers[0]
+	out["image"] = container.Image
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		out["activeDeadlineSeconds"] = *job.Spec.ActiveDeadlineSeconds
+	}
+	for _, e := range container.Env {
+		// Per-session identifiers always differ and are noise in a comparison
+		switch e.Name {
+		case "AGENTIC_SESSION_NAME", "WORKSPACE_STORE_PATH", "MESSAGE_STORE_PATH", "INBOX_STORE_PATH":
+			continue
+		}
+		if e.ValueFrom != nil {
+			if e.ValueFrom.SecretKeyRef != nil {
+				out["env."+e.Name] = "secret:" + e.ValueFrom.SecretKeyRef.Key
+			}
+			continue
+		}
+		out["env."+e.Name] = e.Value
+	}
+	return out, true
+}
+
+func sessionMetrics(status map[string]interface{}) SessionMetrics {
+	m := SessionMetrics{}
+	m.Phase, _ = status["phase"].(string)
+	if v, ok := status["total_cost_usd"].(float64); ok {
+		m.TotalCostUSD = &v
+	}
+	if v, ok := intFromSpec(status, "num_turns"); ok {
+		m.NumTurns = int(v)
+	}
+	start, _ := status["startTime"].(string)
+	end, _ := status["completionTime"].(string)
+	if st, err := time.Parse(time.RFC3339, start); err == nil {
+		if et, err := time.Parse(time.RFC3339, end); err == nil {
+			d := et.Sub(st).Seconds()
+			m.DurationSeconds = &d
+		}
+	}
+	return m
+}
+
+// sessionResultDocument prefers the summary report artifact and falls back to status.result
+func sessionResultDocument(c *gin.Context, project string, status map[string]interface{}) string {
+	if report, ok := status["report"].(map[string]interface{}); ok {
+		if p, ok := report["path"].(string); ok && p != "" {
+			if b, err := readProjectContentFile(c, project, p); err == nil {
+				doc := string(b)
+				// The report heading names the session and always differs
+				if strings.HasPrefix(doc, "# Session Report:") {
+					if i := strings.Index(doc, "\n"); i >= 0 {
+						doc = doc[i+1:]
+					}
+				}
+				return strings.TrimSpace(doc)
+			}
+		}
+	}
+	res, _ := status["result"].(string)
+	return res
+}
+
+// diffMaps flattens both maps to dotted paths and reports added, removed and changed leaves.
+func diffMaps(left, right map[string]interface{}) []FieldChange {
+	lf := map[string]interface{}{}
+	rf := map[string]interface{}{}
+	flattenInto(lf, "", left)
+	flattenInto(rf, "", right)
+
+	keys := map[string]struct{}{}
+	for k := range lf {
+		keys[k] = struct{}{}
+	}
+	for k := range rf {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	changes := []FieldChange{}
+	for _, k := range sorted {
+		lv, lok := lf[k]
+		rv, rok := rf[k]
+		if lok && rok && reflect.DeepEqual(lv, rv) {
+			continue
+		}
+		changes = append(changes, FieldChange{Path: k, Left: lv, Right: rv})
+	}
+	return changes
+}
+
+func flattenInto(out map[string]interface{}, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			flattenInto(out, p, val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			flattenInto(out, fmt.Sprintf("%s[%d]", prefix, i), val)
+		}
+	default:
+		if prefix != "" {
+			out[prefix] = v
+		}
+	}
+}
+
+// lineDiff returns a unified-style line diff ("  ", "- ", "+ " prefixes) based on LCS.
+func lineDiff(a, b string) []string {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+	if len(al) > maxResultDiffLines || len(bl) > maxResultDiffLines {
+		return []string{fmt.Sprintf("result documents too large to diff (%d vs %d lines)", len(al), len(bl))}
+	}
+	n, m := len(al), len(bl)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case al[i] == bl[j]:
+			out = append(out, "  "+al[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+al[i])
+			i++
+		default:
+			out = append(out, "+ "+bl[j])
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, "- "+al[i])
+	}
+	for ; j < m; j++ {
+		out = append(out, "+ "+bl[j])
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// SessionDebug keeps the runner pod of a failed session alive for
+// KeepAliveSeconds so its workspace can be inspected through debug/exec
+type SessionDebug struct {
+	KeepAliveSeconds int64 `json:"keepAliveSeconds" binding:"gte=0"`
+}
+
+// checkSessionDebug rejects debug keep-alive unless the project allows it
+// with sessionPolicy.maxDebugKeepAliveSeconds, and beyond that maximum
+func checkSessionDebug(spec map[string]interface{}, d *SessionDebug) *sessionPolicyViolation {
+	if d == nil || d.KeepAliveSeconds == 0 {
+		return nil
+	}
+	if d.KeepAliveSeconds < 0 {
+		return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: "debug.keepAliveSeconds must not be negative"}
+	}
+	max, _, _ := unstructured.NestedInt64(spec, "sessionPolicy", "maxDebugKeepAliveSeconds")
+	if max <= 0 {
+		return &sessionPolicyViolation{
+			Status:  http.StatusForbidden,
+			Message: "debug mode is not enabled for this project",
+			Audit:   "sessionPolicy.maxDebugKeepAliveSeconds: debug mode not enabled",
+		}
+	}
+	if d.KeepAliveSeconds > max {
+		return &sessionPolicyViolation{
+			Status:  http.StatusForbidden,
+			Message: fmt.Sprintf("debug.keepAliveSeconds %d exceeds the project maximum of %d", d.KeepAliveSeconds, max),
+			Audit:   fmt.Sprintf("sessionPolicy.maxDebugKeepAliveSeconds: %d > %d", d.KeepAliveSeconds, max),
+		}
+	}
+	return nil
+}
+
+// enforceSessionDebugPolicy applies checkSessionDebug. It writes the error
+// response and returns false on rejection.
+func enforceSessionDebugPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, d *SessionDebug) bool {
+	if d == nil || d.KeepAliveSeconds == 0 {
+		return true
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := checkSessionDebug(spec, d); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/debug/exec?command=sh&tty=true
+// execSessionDebug opens a terminal in the runner pod a failed session keeps
+// alive under spec.debug. The WebSocket is proxied to the pod's exec
+// subresource as the caller, so the client speaks the channel.k8s.io
+// protocol of kubectl exec and the caller needs create on pods/exec.
+func execSessionDebug(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	token := bearerTokenFromRequest(c)
+	if reqK8s == nil || reqDyn == nil || token == "" || baseKubeConfig == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		respondError(c, http.StatusBadRequest, "debug exec requires a WebSocket upgrade")
+		return
+	}
+
+	item, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+	if keepAlive, _, _ := unstructured.NestedInt64(item.Object, "spec", "debug", "keepAliveSeconds"); keepAlive <= 0 {
+		respondError(c, http.StatusConflict, "Session was not started in debug mode")
+		return
+	}
+	if phase, _, _ := unstructured.NestedString(item.Object, "status", "phase"); phase != "Failed" {
+		respondError(c, http.StatusConflict, "Debug access is only available after the session failed")
+		return
+	}
+
+	pods, err := reqK8s.CoreV1().Pods(project).List(context.TODO(), v1.ListOptions{LabelSelector: "agentic-session=" + sessionName})
+	if err != nil {
+		logErrorf(c, "Failed to list runner pods of %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to find the runner pod")
+		return
+	}
+	podName := ""
+	for _, p := range pods.Items {
+		if p.Status.Phase == corev1.PodRunning && p.DeletionTimestamp == nil {
+			podName = p.Name
+			break
+		}
+	}
+	if podName == "" {
+		respondError(c, http.StatusGone, "The runner pod is no longer kept alive")
+		return
+	}
+
+	target, err := url.Parse(baseKubeConfig.Host)
+	if err != nil || target.Host == "" {
+		target, err = url.Parse("https://" + baseKubeConfig.Host)
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Invalid cluster address")
+		return
+	}
+	query := url.Values{"stdin": {"true"}, "stdout": {"true"}, "stderr": {"true"}}
+	query.Set("container", c.DefaultQuery("container", "ambient-code-runner"))
+	query.Set("tty", c.DefaultQuery("tty", "true"))
+	for _, arg := range c.QueryArray("command") {
+		query.Add("command", arg)
+	}
+	if !query.Has("command") {
+		query.Set("command", "/bin/sh")
+	}
+
+	// Exec as the caller: impersonated with IMPERSONATE_USERS, with their
+	// token otherwise. The transport adds the credentials.
+	cfg, ok := impersonationConfig(c)
+	if !ok {
+		cfg = rest.CopyConfig(baseKubeConfig)
+		cfg.BearerToken, cfg.BearerTokenFile = token, ""
+		cfg.AuthProvider, cfg.ExecProvider = nil, nil
+		cfg.Username, cfg.Password = "", ""
+	}
+	transport, err := rest.TransportFor(cfg)
+	if err != nil {
+		logErrorf(c, "debug exec: cluster transport: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to reach the cluster")
+		return
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+			r.URL.Path = strings.TrimSuffix(target.Path, "/") + fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/exec", project, podName)
+			r.URL.RawQuery = query.Encode()
+			r.Host = target.Host
+			r.Header.Del("X-Forwarded-Access-Token")
+			r.Header.Del("Cookie")
+			r.Header.Del("Authorization")
+			for k := range r.Header {
+				if strings.HasPrefix(k, "Impersonate-") {
+					r.Header.Del(k)
+				}
+			}
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logErrorf(c, "debug exec: proxy to %s/%s failed: %v", project, podName, err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	logInfof(c, "debug exec: %s opened a terminal in %s/%s", requesterFromContext(c), project, podName)
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+)
+
+// webhookDeliveriesConfigMap records recent webhook delivery IDs per project so
+// replayed events return the session created for the first delivery.
+const webhookDeliveriesConfigMap = "ambient-webhook-deliveries"
+
+// deliveryHeaders are checked in order when the trigger carries no deliveryId.
+// Slack sends no delivery header; forwarders pass its event_id as Idempotency-Key.
+var deliveryHeaders = []struct{ header, source string }{
+	{"X-GitHub-Delivery", "github"},
+	{"X-Atlassian-Webhook-Identifier", "jira"},
+	{"Idempotency-Key", ""},
+}
+
+var webhookDeliveriesDeduplicated atomic.Int64
+
+type deliveryRecord struct {
+	Session string    `json:"session"`
+	At      time.Time `json:"at"`
+}
+
+// webhookDeliveryTTL is how long a delivery ID is remembered (WEBHOOK_DELIVERY_TTL, default 24h)
+func webhookDeliveryTTL() time.Duration {
+	if v := os.Getenv("WEBHOOK_DELIVERY_TTL"); v != "" {
+		if d, err := parseRetentionDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 24 * time.Hour
+}
+
+// webhookDeliveryKey returns the ConfigMap key for the request's delivery ID, or ""
+// when the request carries none. IDs are hashed with their source so arbitrary
+// header values fit ConfigMap key rules and GitHub and Jira IDs never collide.
+func webhookDeliveryKey(c *gin.Context, trigger *SessionTrigger) (key, id string) {
+	source := ""
+	if trigger != nil {
+		source = strings.ToLower(strings.TrimSpace(trigger.Source))
+		id = strings.TrimSpace(trigger.DeliveryID)
+	}
+	if id == "" {
+		for _, h := range deliveryHeaders {
+			if v := strings.TrimSpace(c.GetHeader(h.header)); v != "" {
+				id = v
+				if h.source != "" {
+					source = h.source
+				}
+				break
+			}
+		}
+	}
+	if id == "" {
+		return "", ""
+	}
+	sum := sha256.Sum256([]byte(source + "\n" + id))
+	return "d-" + hex.EncodeToString(sum[:20]), id
+}
+
+func activeDelivery(raw string, now time.Time, ttl time.Duration) string {
+	if raw == "" {
+		return ""
+	}
+	var rec deliveryRecord
+	if err := json.Unmarshal([]byte(raw), &rec); err != nil || now.Sub(rec.At) > ttl {
+		return ""
+	}
+	return rec.Session
+}
+
+// lookupWebhookDelivery returns the session recorded for key within the TTL, or "".
+func lookupWebhookDelivery(ctx context.Context, project, key string) (string, error) {
+	cm, err := k8sClient.CoreV1().ConfigMaps(project).Get(ctx, webhookDeliveriesConfigMap, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return activeDelivery(cm.Data[key], time.Now(), webhookDeliveryTTL()), nil
+}
+
+// claimWebhookDelivery atomically records key for session. If another request
+// already claimed the key within the TTL, its session is returned instead and
+// nothing is written. Expired entries are pruned on every write.
+func claimWebhookDelivery(ctx context.Context, project, key, session string) (string, error) {
+	ttl := webhookDeliveryTTL()
+	existing := ""
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		now := time.Now()
+		b, _ := json.Marshal(deliveryRecord{Session: session, At: now.UTC()})
+		cms := k8sClient.CoreV1().ConfigMaps(project)
+		cm, err := cms.Get(ctx, webhookDeliveriesConfigMap, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      webhookDeliveriesConfigMap,
+					Namespace: project,
+					Labels:    map[string]string{"app": "ambient-webhook-deliveries"},
+				},
+				Data: map[string]string{key: string(b)},
+			}
+			_, err = cms.Create(ctx, cm, v1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// Lost the race to create it; retry against the winner's copy
+				return errors.NewConflict(schema.GroupResource{Resource: "configmaps"}, webhookDeliveriesConfigMap, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if s := activeDelivery(cm.Data[key], now, ttl); s != "" {
+			existing = s
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		for k, raw := range cm.Data {
+			if activeDelivery(raw, now, ttl) == "" {
+				delete(cm.Data, k)
+			}
+		}
+		cm.Data[key] = string(b)
+		_, err = cms.Update(ctx, cm, v1.UpdateOptions{})
+		return err
+	})
+	return existing, err
+}
+
+// releaseWebhookDelivery forgets key if it still points at session, so a retry
+// of a delivery whose session could not be created is not treated as a duplicate.
+func releaseWebhookDelivery(ctx context.Context, project, key, session string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cms := k8sClient.CoreV1().ConfigMaps(project)
+		cm, err := cms.Get(ctx, webhookDeliveriesConfigMap, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		var rec deliveryRecord
+		if err := json.Unmarshal([]byte(cm.Data[key]), &rec); err != nil || rec.Session != session {
+			return nil
+		}
+		delete(cm.Data, key)
+		_, err = cms.Update(ctx, cm, v1.UpdateOptions{})
+		return err
+	})
+}
+
+// respondDuplicateDelivery answers a replayed delivery with the original session
+func respondDuplicateDelivery(c *gin.Context, deliveryID, session string) {
+	webhookDeliveriesDeduplicated.Add(1)
+	auditDetail(c, "duplicateDelivery", gin.H{"deliveryId": deliveryID, "session": session})
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Duplicate delivery; session already created",
+		"name":      session,
+		"duplicate": true,
+	})
+}
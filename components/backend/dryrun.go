@@ -0,0 +1,114 @@
+package main
+
+import (
+	"ambient-code-shared/money"
+)
+
+// policyEvaluationAnnotation carries the admission rules evaluated for a dry-run
+// session to the operator, which merges them into status.plan.policy
+const policyEvaluationAnnotation = "ambient-code.io/policy-evaluation"
+
+// SessionPlan is what the operator would have created for a dry-run session
+// (spec.dryRun), reported in status.plan instead of running it
+type SessionPlan struct {
+	// Allowed is false when any policy rule failed; the session would be rejected
+	Allowed      bool   `json:"allowed"`
+	Workload     string `json:"workload"`
+	WorkloadName string `json:"workloadName"`
+	Image        string `json:"image,omitempty"`
+	Track        string `json:"track,omitempty"`
+	// Resources of the runner container by requests and limits
+	Resources             map[string]map[string]string `json:"resources,omitempty"`
+	ActiveDeadlineSeconds int64                        `json:"activeDeadlineSeconds,omitempty"`
+	ServiceAccountName    string                       `json:"serviceAccountName,omitempty"`
+	PriorityClassName     string                       `json:"priorityClassName,omitempty"`
+	NodeSelector          map[string]string            `json:"nodeSelector,omitempty"`
+	InitContainers        []string                     `json:"initContainers,omitempty"`
+	// Env lists the runner's environment variable names; values are not recorded
+	Env           []string            `json:"env,omitempty"`
+	EstimatedCost SessionCostEstimate `json:"estimatedCost"`
+	Policy        []PolicyRuleResult  `json:"policy"`
+	GeneratedAt   string              `json:"generatedAt,omitempty"`
+}
+
+// SessionCostEstimate is the mean usage of the project's recent finished
+// sessions, preferring those that ran the same model
+type SessionCostEstimate struct {
+	CostUSD      *money.USD `json:"costUSD,omitempty"`
+	InputTokens  int64      `json:"inputTokens,omitempty"`
+	OutputTokens int64      `json:"outputTokens,omitempty"`
+	Samples      int64      `json:"samples"`
+	Basis        string     `json:"basis,omitempty"`
+}
+
+func parseSessionPlan(m map[string]interface{}) *SessionPlan {
+	p := &SessionPlan{Policy: []PolicyRuleResult{}}
+	p.Allowed, _ = m["allowed"].(bool)
+	p.Workload, _ = m["workload"].(string)
+	p.WorkloadName, _ = m["workloadName"].(string)
+	p.Image, _ = m["image"].(string)
+	p.Track, _ = m["track"].(string)
+	p.ActiveDeadlineSeconds, _ = intFromSpec(m, "activeDeadlineSeconds")
+	p.ServiceAccountName, _ = m["serviceAccountName"].(string)
+	p.PriorityClassName, _ = m["priorityClassName"].(string)
+	p.GeneratedAt, _ = m["generatedAt"].(string)
+	if resources, ok := m["resources"].(map[string]interface{}); ok {
+		p.Resources = map[string]map[string]string{}
+		for kind, raw := range resources {
+			list, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			p.Resources[kind] = stringMap(list)
+		}
+	}
+	if selector, ok := m["nodeSelector"].(map[string]interface{}); ok {
+		p.NodeSelector = stringMap(selector)
+	}
+	p.InitContainers = stringSlice(m["initContainers"])
+	p.Env = stringSlice(m["env"])
+	if est, ok := m["estimatedCost"].(map[string]interface{}); ok {
+		if v, ok := money.FromSpec(est["costUSD"]); ok {
+			p.EstimatedCost.CostUSD = &v
+		}
+		p.EstimatedCost.InputTokens, _ = intFromSpec(est, "inputTokens")
+		p.EstimatedCost.OutputTokens, _ = intFromSpec(est, "outputTokens")
+		p.EstimatedCost.Samples, _ = intFromSpec(est, "samples")
+		p.EstimatedCost.Basis, _ = est["basis"].(string)
+	}
+	if rules, ok := m["policy"].([]interface{}); ok {
+		for _, raw := range rules {
+			r, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			rule := PolicyRuleResult{}
+			rule.Rule, _ = r["rule"].(string)
+			rule.Passed, _ = r["passed"].(bool)
+			rule.Message, _ = r["message"].(string)
+			p.Policy = append(p.Policy, rule)
+		}
+	}
+	return p
+}
+
+func stringMap(m map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+func stringSlice(raw interface{}) []string {
+	items, _ := raw.([]interface{})
+	var out []string
+	for _, v := range items {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
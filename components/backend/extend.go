@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultMaxSessionExtensions       = 3
+	defaultMaxSessionExtensionSeconds = 3600
+	// defaultJobDeadlineSeconds is the operator's Job deadline for sessions
+	// without a timeout
+	defaultJobDeadlineSeconds = 1800
+)
+
+type ExtendSessionRequest struct {
+	Seconds int64  `json:"seconds" binding:"required"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type SessionExtension struct {
+	Seconds               int64  `json:"seconds"`
+	Reason                string `json:"reason,omitempty"`
+	RequestedBy           string `json:"requestedBy"`
+	RequestedAt           string `json:"requestedAt"`
+	ActiveDeadlineSeconds int64  `json:"activeDeadlineSeconds"`
+}
+
+// sessionExtensionLimits resolves the extension policy from ProjectSettings
+// spec.sessionPolicy, falling back to MAX_SESSION_EXTENSIONS and
+// MAX_SESSION_EXTENSION_SECONDS on the backend.
+func sessionExtensionLimits(spec map[string]interface{}) (maxCount int64, maxSeconds int64) {
+	maxCount = intFromEnv("MAX_SESSION_EXTENSIONS", defaultMaxSessionExtensions)
+	maxSeconds = intFromEnv("MAX_SESSION_EXTENSION_SECONDS", defaultMaxSessionExtensionSeconds)
+	if policy, ok := spec["sessionPolicy"].(map[string]interface{}); ok {
+		if v, ok := intFromSpec(policy, "maxExtensions"); ok {
+			maxCount = v
+		}
+		if v, ok := intFromSpec(policy, "maxExtensionSeconds"); ok {
+			maxSeconds = v
+		}
+	}
+	return maxCount, maxSeconds
+}
+
+// requesterFromContext returns the best available identity of the caller.
+func requesterFromContext(c *gin.Context) string {
+	if v := c.GetString("userName"); v != "" {
+		return v
+	}
+	if v := c.GetString("userID"); v != "" {
+		return v
+	}
+	return "unknown"
+}
+
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/extend { seconds, reason }
+// extendSession records the extension in status.extensions and then bumps the
+// running Job's activeDeadlineSeconds. The extended deadline may not exceed the
+// project's maxTimeoutSeconds. Callers need update on the session; the status
+// write uses their token and the session's resourceVersion, so concurrent
+// extensions cannot both pass the limits, and only then does the backend patch
+// the Job, which project roles may not.
+func extendSession(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	var req ExtendSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Seconds <= 0 {
+		respondError(c, http.StatusBadRequest, "seconds must be positive")
+		return
+	}
+
+	gvr := getAgenticSessionV1Alpha1Resource()
+	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+
+	status, _ := item.Object["status"].(map[string]interface{})
+	if status == nil {
+		status = map[string]interface{}{}
+		item.Object["status"] = status
+	}
+	phase, _ := status["phase"].(string)
+	if phase != "Running" && phase != "Creating" {
+		respondError(c, http.StatusConflict, fmt.Sprintf("Cannot extend session in %s state", phase))
+		return
+	}
+	jobName, _ := status["jobName"].(string)
+	if runName, _ := status["pipelineRunName"].(string); runName != "" {
+		respondError(c, http.StatusConflict, "Sessions running as a Tekton PipelineRun cannot be extended")
+		return
+	}
+	if jobName == "" {
+		respondError(c, http.StatusConflict, "Session has no running job")
+		return
+	}
+
+	ssar := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Group:     "vteam.ambient-code",
+				Resource:  "agenticsessions",
+				Verb:      "update",
+				Namespace: project,
+				Name:      sessionName,
+			},
+		},
+	}
+	review, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
+	if err != nil {
+		logErrorf(c, "Failed to check extend permission on %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to check permissions")
+		return
+	}
+	if !review.Status.Allowed {
+		respondError(c, http.StatusForbidden, "Not permitted to extend sessions in this project")
+		return
+	}
+
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project policy")
+		return
+	}
+	maxCount, maxSeconds := sessionExtensionLimits(spec)
+
+	history, _ := status["extensions"].([]interface{})
+	if int64(len(history)) >= maxCount {
+		respondError(c, http.StatusForbidden, fmt.Sprintf("Session has reached the maximum of %d extensions", maxCount))
+		return
+	}
+	if req.Seconds > maxSeconds {
+		respondError(c, http.StatusForbidden, fmt.Sprintf("Extension of %ds exceeds the maximum of %ds", req.Seconds, maxSeconds))
+		return
+	}
+
+	job, err := reqK8s.BatchV1().Jobs(project).Get(context.TODO(), jobName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusConflict, "Session job no longer exists")
+			return
+		}
+		logErrorf(c, "Failed to get job %s in project %s: %v", jobName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get session job")
+		return
+	}
+	if job.Status.CompletionTime != nil {
+		respondError(c, http.StatusConflict, "Session job already finished")
+		return
+	}
+
+	// Jobs without a deadline get the one the operator derives from the
+	// session timeout, so an extension never shortens a session
+	maxTimeout := sessionMaxTimeout(spec)
+	current, _, _ := unstructured.NestedInt64(item.Object, "spec", "timeout")
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		current = *job.Spec.ActiveDeadlineSeconds
+	} else if current <= 0 {
+		current = defaultJobDeadlineSeconds
+	}
+	// The last recorded extension wins over a Job patch that did not land
+	if n := len(history); n > 0 {
+		if last, ok := history[n-1].(map[string]interface{}); ok {
+			if v, ok := intFromSpec(last, "activeDeadlineSeconds"); ok && v > current {
+				current = v
+			}
+		}
+	}
+	if maxTimeout > 0 && current > maxTimeout {
+		current = maxTimeout
+	}
+	newDeadline := current + req.Seconds
+	if maxTimeout > 0 && newDeadline > maxTimeout {
+		auditDeny(c, fmt.Sprintf("sessionPolicy.maxTimeout: extended deadline %ds > %ds", newDeadline, maxTimeout))
+		respondError(c, http.StatusForbidden, fmt.Sprintf("Extending by %ds would set the deadline to %ds, beyond the project maximum of %ds; at most %ds remain", req.Seconds, newDeadline, maxTimeout, maxTimeout-current))
+		return
+	}
+
+	ext := SessionExtension{
+		Seconds:               req.Seconds,
+		Reason:                strings.TrimSpace(req.Reason),
+		RequestedBy:           requesterFromContext(c),
+		RequestedAt:           time.Now().UTC().Format(time.RFC3339),
+		ActiveDeadlineSeconds: newDeadline,
+	}
+	entry := map[string]interface{}{
+		"seconds":               ext.Seconds,
+		"requestedBy":           ext.RequestedBy,
+		"requestedAt":           ext.RequestedAt,
+		"activeDeadlineSeconds": ext.ActiveDeadlineSeconds,
+	}
+	if ext.Reason != "" {
+		entry["reason"] = ext.Reason
+	}
+	status["extensions"] = append(history, entry)
+
+	// item carries the resourceVersion the limits were checked against
+	if _, err := reqDyn.Resource(gvr).Namespace(project).UpdateStatus(context.TODO(), item, v1.UpdateOptions{}); err != nil {
+		switch {
+		case errors.IsConflict(err):
+			respondError(c, http.StatusConflict, "Session changed while extending it, retry")
+		case errors.IsForbidden(err):
+			respondError(c, http.StatusForbidden, "Not permitted to extend sessions in this project")
+		default:
+			logErrorf(c, "Failed to record extension on session %s/%s: %v", project, sessionName, err)
+			respondError(c, http.StatusInternalServerError, "Failed to extend session deadline")
+		}
+		return
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"activeDeadlineSeconds":%d}}`, newDeadline))
+	if _, err := k8sClient.BatchV1().Jobs(project).Patch(context.TODO(), jobName, types.MergePatchType, patch, v1.PatchOptions{}); err != nil {
+		logErrorf(c, "Recorded extension on session %s/%s but failed to patch job %s deadline: %v", project, sessionName, jobName, err)
+		respondError(c, http.StatusInternalServerError, "Extension recorded but the session deadline could not be updated")
+		return
+	}
+
+	logInfof(c, "Extended session %s/%s by %ds (deadline %ds) for %s", project, sessionName, req.Seconds, newDeadline, ext.RequestedBy)
+	c.JSON(http.StatusOK, gin.H{
+		"extension":           ext,
+		"extensionsUsed":      len(history) + 1,
+		"extensionsRemaining": maxCount - int64(len(history)) - 1,
+	})
+}
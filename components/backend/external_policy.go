@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+// Organizations that keep policy in OPA set ClusterAmbientPolicy
+// spec.externalPolicy: the model, tools and budget decisions for new sessions
+// are then made by the rules of that name in the policy package at its URL
+// (OPA's data API), instead of by the built-in checks. Each query's input is
+// the candidate session, the project's ProjectSettings spec and the cluster
+// policy; a rule answers true/false or {"allow": bool, "reasons": [...]}.
+// When the endpoint fails or the rule is undefined, failurePolicy Fallback
+// (the default) applies the built-in rule and Fail rejects the session.
+
+const (
+	externalPolicyFallback       = "Fallback"
+	externalPolicyFail           = "Fail"
+	defaultExternalPolicyTimeout = 5 * time.Second
+	maxExternalPolicyResponse    = 1 << 20
+)
+
+// ExternalPolicy is ClusterAmbientPolicy spec.externalPolicy
+type ExternalPolicy struct {
+	URL           string
+	Timeout       time.Duration
+	FailurePolicy string
+}
+
+// parseExternalPolicy reads spec.externalPolicy; nil when it is not configured
+func parseExternalPolicy(spec map[string]interface{}) *ExternalPolicy {
+	url, _, _ := unstructured.NestedString(spec, "externalPolicy", "url")
+	if strings.TrimSpace(url) == "" {
+		return nil
+	}
+	p := &ExternalPolicy{URL: strings.TrimRight(strings.TrimSpace(url), "/"), Timeout: defaultExternalPolicyTimeout, FailurePolicy: externalPolicyFallback}
+	if secs, ok, _ := unstructured.NestedInt64(spec, "externalPolicy", "timeoutSeconds"); ok && secs > 0 {
+		p.Timeout = time.Duration(secs) * time.Second
+	}
+	if fp, _, _ := unstructured.NestedString(spec, "externalPolicy", "failurePolicy"); fp == externalPolicyFail {
+		p.FailurePolicy = externalPolicyFail
+	}
+	return p
+}
+
+// externalPolicyDecision is a rule's answer: a bare boolean or an object
+type externalPolicyDecision struct {
+	Allow   bool     `json:"allow"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+var externalPolicyClient = &http.Client{}
+
+// query evaluates the named rule for input. An undefined rule is an error, so a
+// policy that was not loaded does not admit everything.
+func (p *ExternalPolicy) query(ctx context.Context, rule string, input map[string]interface{}) (*externalPolicyDecision, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL+"/"+rule, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := externalPolicyClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExternalPolicyResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s/%s answered %d", p.URL, rule, resp.StatusCode)
+	}
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("%s/%s: %v", p.URL, rule, err)
+	}
+	if len(out.Result) == 0 {
+		return nil, fmt.Errorf("%s/%s is undefined", p.URL, rule)
+	}
+	var allow bool
+	if err := json.Unmarshal(out.Result, &allow); err == nil {
+		return &externalPolicyDecision{Allow: allow}, nil
+	}
+	var d externalPolicyDecision
+	if err := json.Unmarshal(out.Result, &d); err != nil {
+		return nil, fmt.Errorf("%s/%s: result is neither a boolean nor {allow, reasons}: %v", p.URL, rule, err)
+	}
+	return &d, nil
+}
+
+// sessionPolicyInput is the input common to every decision
+func sessionPolicyInput(c *gin.Context, project string, spec map[string]interface{}, cp ClusterPolicy, session map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"project":       project,
+		"user":          map[string]interface{}{"name": c.GetString("userName"), "groups": c.GetStringSlice("userGroups")},
+		"session":       session,
+		"projectPolicy": spec,
+		"clusterPolicy": cp,
+	}
+}
+
+// decideSessionPolicy asks cp's external policy for the rule's decision, or
+// runs builtin when none is configured or, with failurePolicy Fallback, when
+// it is unavailable
+func decideSessionPolicy(c *gin.Context, cp ClusterPolicy, rule string, input map[string]interface{}, builtin func() (*sessionPolicyViolation, error)) (*sessionPolicyViolation, error) {
+	p := cp.ExternalPolicy
+	if p == nil {
+		return builtin()
+	}
+	d, err := p.query(c.Request.Context(), rule, input)
+	if err != nil {
+		if p.FailurePolicy == externalPolicyFail {
+			logErrorf(c, "External %s policy unavailable, rejecting: %v", rule, err)
+			return &sessionPolicyViolation{
+				Status:  http.StatusServiceUnavailable,
+				Message: fmt.Sprintf("the %s policy service is unavailable", rule),
+				Code:    problem.CodeUnavailable,
+			}, nil
+		}
+		logWarnf(c, "External %s policy unavailable, applying built-in rules: %v", rule, err)
+		return builtin()
+	}
+	if d.Allow {
+		return nil, nil
+	}
+	msg := fmt.Sprintf("%s denied by external policy", rule)
+	if len(d.Reasons) > 0 {
+		msg = strings.Join(d.Reasons, "; ")
+	}
+	return &sessionPolicyViolation{
+		Status:  http.StatusForbidden,
+		Message: msg,
+		Audit:   fmt.Sprintf("externalPolicy.%s: %s", rule, msg),
+	}, nil
+}
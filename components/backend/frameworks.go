@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// defaultSessionFramework runs without a registry entry, on the operator's runner image
+const defaultSessionFramework = "claude-code"
+
+// Framework is a registered runner as returned by GET /api/frameworks
+type Framework struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Versions    []string `json:"versions,omitempty"`
+	// DeprecatedVersions are still accepted but warned about on session create
+	DeprecatedVersions []string          `json:"deprecatedVersions,omitempty"`
+	DefaultVersion     string            `json:"defaultVersion,omitempty"`
+	Requests           map[string]string `json:"requests,omitempty"`
+	Limits             map[string]string `json:"limits,omitempty"`
+	// Providers lists the model provider types the runner supports; empty means any
+	Providers []string `json:"providers,omitempty"`
+	// Workload is Job or PipelineRun; empty runs a Job
+	Workload string `json:"workload,omitempty"`
+}
+
+// getFrameworkResource returns the GroupVersionResource for the cluster-scoped Framework registry
+func getFrameworkResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "frameworks",
+	}
+}
+
+var (
+	frameworkClientOnce sync.Once
+	frameworkClient     dynamic.Interface
+	frameworkClientErr  error
+)
+
+// frameworkRegistry returns a backend ServiceAccount client for Frameworks, which
+// are cluster-scoped and readable by every authenticated user of the API.
+func frameworkRegistry() (dynamic.ResourceInterface, error) {
+	frameworkClientOnce.Do(func() {
+		frameworkClient, frameworkClientErr = dynamic.NewForConfig(baseKubeConfig)
+	})
+	if frameworkClientErr != nil {
+		return nil, frameworkClientErr
+	}
+	return frameworkClient.Resource(getFrameworkResource()), nil
+}
+
+func frameworkFromUnstructured(obj *unstructured.Unstructured) Framework {
+	fw := Framework{Name: obj.GetName()}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	fw.DisplayName, _, _ = unstructured.NestedString(spec, "displayName")
+	fw.Description, _, _ = unstructured.NestedString(spec, "description")
+	fw.DefaultVersion, _, _ = unstructured.NestedString(spec, "defaultVersion")
+	versions, _, _ := unstructured.NestedSlice(spec, "versions")
+	for _, raw := range versions {
+		if m, ok := raw.(map[string]interface{}); ok {
+			if name, _ := m["name"].(string); name != "" {
+				fw.Versions = append(fw.Versions, name)
+				if deprecated, _ := m["deprecated"].(bool); deprecated {
+					fw.DeprecatedVersions = append(fw.DeprecatedVersions, name)
+				}
+			}
+		}
+	}
+	fw.Requests, _, _ = unstructured.NestedStringMap(spec, "resources", "requests")
+	fw.Limits, _, _ = unstructured.NestedStringMap(spec, "resources", "limits")
+	fw.Providers, _, _ = unstructured.NestedStringSlice(spec, "providers")
+	fw.Workload, _, _ = unstructured.NestedString(spec, "workload")
+	return fw
+}
+
+// frameworkProviders returns the model provider types a framework supports, or
+// nil when it accepts any (including unregistered default framework).
+func frameworkProviders(ctx context.Context, framework string) ([]string, error) {
+	framework = strings.TrimSpace(framework)
+	if framework == "" {
+		framework = defaultSessionFramework
+	}
+	registry, err := frameworkRegistry()
+	if err != nil {
+		return nil, err
+	}
+	obj, err := registry.Get(ctx, framework, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return frameworkFromUnstructured(obj).Providers, nil
+}
+
+// GET /api/frameworks
+// listFrameworks returns the registered runner frameworks sessions can select.
+func listFrameworks(c *gin.Context) {
+	if reqK8s, _ := getK8sClientsForRequest(c); reqK8s == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	registry, err := frameworkRegistry()
+	if err != nil {
+		logErrorf(c, "Failed to create framework registry client: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to list frameworks")
+		return
+	}
+	list, err := registry.List(c.Request.Context(), v1.ListOptions{})
+	if err != nil {
+		logErrorf(c, "Failed to list frameworks: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to list frameworks")
+		return
+	}
+	frameworks := make([]Framework, 0, len(list.Items)+1)
+	hasDefault := false
+	for i := range list.Items {
+		fw := frameworkFromUnstructured(&list.Items[i])
+		hasDefault = hasDefault || fw.Name == defaultSessionFramework
+		frameworks = append(frameworks, fw)
+	}
+	if !hasDefault {
+		frameworks = append(frameworks, Framework{Name: defaultSessionFramework, DisplayName: "Claude Code"})
+	}
+	sort.Slice(frameworks, func(i, j int) bool { return frameworks[i].Name < frameworks[j].Name })
+	c.JSON(http.StatusOK, gin.H{"items": frameworks})
+}
+
+// validateSessionFramework checks that a requested framework is registered and
+// supports the requested version. It returns a user-facing message when invalid.
+func validateSessionFramework(ctx context.Context, framework, version string) (string, error) {
+	fe, err := checkSessionFramework(ctx, framework, version)
+	if err != nil || fe == nil {
+		return "", err
+	}
+	return fe.Message, nil
+}
+
+// checkSessionFramework is validateSessionFramework reporting the field at fault
+func checkSessionFramework(ctx context.Context, framework, version string) (*PolicyFieldError, error) {
+	framework, version = strings.TrimSpace(framework), strings.TrimSpace(version)
+	if framework == "" {
+		framework = defaultSessionFramework
+	}
+	registry, err := frameworkRegistry()
+	if err != nil {
+		return nil, err
+	}
+	obj, err := registry.Get(ctx, framework, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if framework == defaultSessionFramework && version == "" {
+			return nil, nil
+		}
+		if framework == defaultSessionFramework {
+			return &PolicyFieldError{Field: "frameworkVersion", Message: fmt.Sprintf("framework %q has no registered versions", framework)}, nil
+		}
+		return &PolicyFieldError{Field: "framework", Message: fmt.Sprintf("framework %q is not registered", framework)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		return nil, nil
+	}
+	fw := frameworkFromUnstructured(obj)
+	for _, v := range fw.Versions {
+		if v == version {
+			return nil, nil
+		}
+	}
+	return &PolicyFieldError{Field: "frameworkVersion", Message: fmt.Sprintf("framework %q does not support version %q (supported: %s)", framework, version, strings.Join(fw.Versions, ", "))}, nil
+}
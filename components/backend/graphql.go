@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"ambient-code-backend/pkg/graphql"
+)
+
+const (
+	// defaultGraphQLSessionLimit is how many sessions Project.sessions returns
+	// without a limit argument
+	defaultGraphQLSessionLimit = 50
+	// graphqlArtifactLoads bounds the artifact indexes read at once
+	graphqlArtifactLoads = 8
+)
+
+// graphqlSchemaSDL describes dashboardSchema for clients; keep the two in step
+const graphqlSchemaSDL = `type Query {
+  # Projects the caller has at least view access to
+  projects: [Project!]!
+  project(name: String!): Project
+}
+
+type Project {
+  name: String!
+  displayName: String
+  description: String
+  creationTimestamp: String!
+  status: String
+  # The caller's role (admin, edit or view) and permissions
+  role: String!
+  permissions: [String!]!
+  # Requires sessions:list
+  stats: ProjectStats
+  # Newest first; requires sessions:list. limit defaults to 50, at most 500.
+  sessions(phase: String, limit: Int): [Session!]
+}
+
+type ProjectStats {
+  sessions: SessionCounts!
+  budget: Budget!
+}
+
+type SessionCounts {
+  total: Int!
+  pending: Int!
+  queued: Int!
+  running: Int!
+  completed: Int!
+  failed: Int!
+  stopped: Int!
+}
+
+type Budget {
+  month: String!
+  spentUSD: Float!
+  limitUSD: Float
+  warnPercent: Int
+  resetsAt: String
+}
+
+type Session {
+  name: String!
+  displayName: String
+  phase: String
+  message: String
+  model: String
+  framework: String
+  createdAt: String!
+  startTime: String
+  completionTime: String
+  usage: SessionUsage!
+  artifacts: [Artifact!]
+}
+
+type SessionUsage {
+  costUSD: Float!
+  inputTokens: Int!
+  outputTokens: Int!
+  apiCalls: Int!
+}
+
+type Artifact {
+  name: String!
+  size: Int!
+  contentType: String
+  kind: String
+  tool: String
+  tags: [String!]
+  uploadedAt: String
+}
+`
+
+// graphqlCaller is the request a query runs for; resolvers read it from the
+// context so every load uses the caller's credentials
+type graphqlCaller struct {
+	c          *gin.Context
+	k8s        kubernetes.Interface
+	dyn        dynamic.Interface
+	credential string
+	access     map[string]ProjectAccess
+	mu         sync.Mutex
+}
+
+type graphqlCallerKey struct{}
+
+func callerFrom(ctx context.Context) *graphqlCaller {
+	return ctx.Value(graphqlCallerKey{}).(*graphqlCaller)
+}
+
+// projectAccess memoizes projectAccessForCaller for the query
+func (g *graphqlCaller) projectAccess(ctx context.Context, project string) (ProjectAccess, error) {
+	g.mu.Lock()
+	access, ok := g.access[project]
+	g.mu.Unlock()
+	if ok {
+		return access, nil
+	}
+	access, err := projectAccessForCaller(ctx, g.k8s, g.credential, project)
+	if err != nil {
+		return ProjectAccess{}, err
+	}
+	g.mu.Lock()
+	g.access[project] = access
+	g.mu.Unlock()
+	return access, nil
+}
+
+type graphqlProject struct {
+	ns     corev1.Namespace
+	access ProjectAccess
+}
+
+type graphqlSession struct {
+	project string
+	obj     *unstructured.Unstructured
+}
+
+// requireSessionList resolves fn for the projects whose caller may list
+// sessions and a per-project error for the rest
+func requireSessionList(parents []interface{}, fn func(p *graphqlProject) interface{}) []interface{} {
+	out := make([]interface{}, len(parents))
+	for i, raw := range parents {
+		p := raw.(*graphqlProject)
+		if !p.access.has("sessions:list") {
+			out[i] = fmt.Errorf("not allowed to list sessions in project %s", p.ns.Name)
+			continue
+		}
+		out[i] = fn(p)
+	}
+	return out
+}
+
+func sessionString(fields ...string) *graphql.Field {
+	return graphql.Value(func(p interface{}) interface{} {
+		v, _, _ := unstructured.NestedString(p.(*graphqlSession).obj.Object, fields...)
+		if v == "" {
+			return nil
+		}
+		return v
+	})
+}
+
+func newDashboardSchema() *graphql.Schema {
+	artifact := &graphql.Object{Name: "Artifact", Fields: map[string]*graphql.Field{
+		"name":        graphql.Value(func(p interface{}) interface{} { return p.(Artifact).Name }),
+		"size":        graphql.Value(func(p interface{}) interface{} { return p.(Artifact).Size }),
+		"contentType": graphql.Value(func(p interface{}) interface{} { return p.(Artifact).ContentType }),
+		"kind":        graphql.Value(func(p interface{}) interface{} { return p.(Artifact).Kind }),
+		"tool":        graphql.Value(func(p interface{}) interface{} { return p.(Artifact).Tool }),
+		"tags":        graphql.Value(func(p interface{}) interface{} { return p.(Artifact).Tags }),
+		"uploadedAt":  graphql.Value(func(p interface{}) interface{} { return p.(Artifact).UploadedAt }),
+	}}
+
+	sessionUsage := &graphql.Object{Name: "SessionUsage", Fields: map[string]*graphql.Field{
+		"costUSD":      graphql.Value(func(p interface{}) interface{} { return p.(UsageTotals).CostUSD }),
+		"inputTokens":  graphql.Value(func(p interface{}) interface{} { return p.(UsageTotals).InputTokens }),
+		"outputTokens": graphql.Value(func(p interface{}) interface{} { return p.(UsageTotals).OutputTokens }),
+		"apiCalls":     graphql.Value(func(p interface{}) interface{} { return p.(UsageTotals).APICalls }),
+	}}
+
+	session := &graphql.Object{Name: "Session", Fields: map[string]*graphql.Field{
+		"name":           graphql.Value(func(p interface{}) interface{} { return p.(*graphqlSession).obj.GetName() }),
+		"displayName":    sessionString("spec", "displayName"),
+		"phase":          sessionString("status", "phase"),
+		"message":        sessionString("status", "message"),
+		"model":          sessionString("spec", "llmSettings", "model"),
+		"framework":      sessionString("spec", "framework"),
+		"startTime":      sessionString("status", "startTime"),
+		"completionTime": sessionString("status", "completionTime"),
+		"createdAt": graphql.Value(func(p interface{}) interface{} {
+			return p.(*graphqlSession).obj.GetCreationTimestamp().UTC().Format(time.RFC3339)
+		}),
+		"usage": {Type: sessionUsage, Resolve: func(_ context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+			out := make([]interface{}, len(parents))
+			for i, p := range parents {
+				var t UsageTotals
+				t.add(p.(*graphqlSession).obj)
+				out[i] = t
+			}
+			return out, nil
+		}},
+		"artifacts": {Type: artifact, List: true, Resolve: resolveSessionArtifacts},
+	}}
+
+	counts := &graphql.Object{Name: "SessionCounts", Fields: map[string]*graphql.Field{
+		"total":     graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Total }),
+		"pending":   graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Pending }),
+		"queued":    graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Queued }),
+		"running":   graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Running }),
+		"completed": graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Completed }),
+		"failed":    graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Failed }),
+		"stopped":   graphql.Value(func(p interface{}) interface{} { return p.(SessionCounts).Stopped }),
+	}}
+	budget := &graphql.Object{Name: "Budget", Fields: map[string]*graphql.Field{
+		"month":    graphql.Value(func(p interface{}) interface{} { return p.(BudgetUsage).Month }),
+		"spentUSD": graphql.Value(func(p interface{}) interface{} { return p.(BudgetUsage).SpentUSD }),
+		"limitUSD": graphql.Value(func(p interface{}) interface{} {
+			if b := p.(BudgetUsage); b.LimitUSD > 0 {
+				return b.LimitUSD
+			}
+			return nil
+		}),
+		"warnPercent": graphql.Value(func(p interface{}) interface{} {
+			if b := p.(BudgetUsage); b.WarnPercent > 0 {
+				return b.WarnPercent
+			}
+			return nil
+		}),
+		"resetsAt": graphql.Value(func(p interface{}) interface{} {
+			if b := p.(BudgetUsage); b.ResetsAt != "" {
+				return b.ResetsAt
+			}
+			return nil
+		}),
+	}}
+	stats := &graphql.Object{Name: "ProjectStats", Fields: map[string]*graphql.Field{
+		"sessions": {Type: counts, Resolve: func(_ context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+			out := make([]interface{}, len(parents))
+			for i, p := range parents {
+				out[i] = p.(ProjectStats).Sessions
+			}
+			return out, nil
+		}},
+		"budget": {Type: budget, Resolve: func(_ context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+			out := make([]interface{}, len(parents))
+			for i, p := range parents {
+				out[i] = p.(ProjectStats).Budget
+			}
+			return out, nil
+		}},
+	}}
+
+	annotation := func(key string) *graphql.Field {
+		return graphql.Value(func(p interface{}) interface{} {
+			if v := p.(*graphqlProject).ns.Annotations[key]; v != "" {
+				return v
+			}
+			return nil
+		})
+	}
+	project := &graphql.Object{Name: "Project", Fields: map[string]*graphql.Field{
+		"name":        graphql.Value(func(p interface{}) interface{} { return p.(*graphqlProject).ns.Name }),
+		"displayName": annotation("openshift.io/display-name"),
+		"description": annotation("openshift.io/description"),
+		"creationTimestamp": graphql.Value(func(p interface{}) interface{} {
+			return p.(*graphqlProject).ns.CreationTimestamp.UTC().Format(time.RFC3339)
+		}),
+		"status":      graphql.Value(func(p interface{}) interface{} { return string(p.(*graphqlProject).ns.Status.Phase) }),
+		"role":        graphql.Value(func(p interface{}) interface{} { return p.(*graphqlProject).access.Role }),
+		"permissions": graphql.Value(func(p interface{}) interface{} { return p.(*graphqlProject).access.Permissions }),
+		"stats": {Type: stats, Resolve: func(ctx context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+			g := callerFrom(ctx)
+			return requireSessionList(parents, func(p *graphqlProject) interface{} {
+				s, err := projectStats(ctx, g.dyn, p.ns.Name)
+				if err != nil {
+					logErrorf(g.c, "graphql: failed to compute stats for %s: %v", p.ns.Name, err)
+					return fmt.Errorf("failed to compute project stats")
+				}
+				return s
+			}), nil
+		}},
+		"sessions": {
+			Type: session, List: true,
+			Args:    map[string]interface{}{"phase": nil, "limit": int64(defaultGraphQLSessionLimit)},
+			Resolve: resolveProjectSessions,
+		},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"projects": {Type: project, List: true, Resolve: func(ctx context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+			projects, err := accessibleProjects(ctx, "")
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{projects}, nil
+		}},
+		"project": {Type: project, Args: map[string]interface{}{"name": nil}, Resolve: func(ctx context.Context, parents []interface{}, args map[string]interface{}) ([]interface{}, error) {
+			name := graphql.StringArg(args, "name")
+			if name == "" {
+				return nil, fmt.Errorf("name is required")
+			}
+			projects, err := accessibleProjects(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			// Unknown and inaccessible projects look the same, as on the REST routes
+			if len(projects) == 0 {
+				return []interface{}{nil}, nil
+			}
+			return []interface{}{projects[0]}, nil
+		}},
+	}}
+	return &graphql.Schema{Query: query}
+}
+
+var dashboardSchema = newDashboardSchema()
+
+// accessibleProjects returns the managed namespaces the caller has a role in,
+// only the one named only when set
+func accessibleProjects(ctx context.Context, only string) ([]*graphqlProject, error) {
+	g := callerFrom(ctx)
+	namespaces, err := managedNamespaces(ctx)
+	if err != nil {
+		logErrorf(g.c, "graphql: failed to list project namespaces: %v", err)
+		return nil, fmt.Errorf("failed to list projects")
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+	out := []*graphqlProject{}
+	for _, ns := range namespaces {
+		if only != "" && ns.Name != only {
+			continue
+		}
+		access, err := g.projectAccess(ctx, ns.Name)
+		if err != nil {
+			logErrorf(g.c, "graphql: failed to resolve access to project %s: %v", ns.Name, err)
+			continue
+		}
+		if access.Role == "" {
+			continue
+		}
+		out = append(out, &graphqlProject{ns: ns, access: access})
+	}
+	return out, nil
+}
+
+// resolveProjectSessions lists the sessions of every project in the level from
+// the informer cache, newest first
+func resolveProjectSessions(ctx context.Context, parents []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	g := callerFrom(ctx)
+	limit, _ := graphql.IntArg(args, "limit")
+	if limit < 1 || limit > maxSessionListLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxSessionListLimit)
+	}
+	selectorParts, msg := sessionListSelector(graphql.StringArg(args, "phase"), "", "")
+	if msg != "" {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	selector, err := labels.Parse(strings.Join(selectorParts, ","))
+	if err != nil {
+		return nil, err
+	}
+	return requireSessionList(parents, func(p *graphqlProject) interface{} {
+		items, cached := cachedSessions(p.ns.Name, selector)
+		if !cached {
+			list, err := g.dyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(p.ns.Name).List(ctx, v1.ListOptions{LabelSelector: selector.String()})
+			if err != nil {
+				logErrorf(g.c, "graphql: failed to list agentic sessions in project %s: %v", p.ns.Name, err)
+				return fmt.Errorf("failed to list agentic sessions")
+			}
+			items = list.Items
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].GetCreationTimestamp().After(items[j].GetCreationTimestamp().Time)
+		})
+		if int64(len(items)) > limit {
+			items = items[:limit]
+		}
+		sessions := make([]*graphqlSession, len(items))
+		for i := range items {
+			sessions[i] = &graphqlSession{project: p.ns.Name, obj: &items[i]}
+		}
+		return sessions
+	}), nil
+}
+
+// resolveSessionArtifacts reads the artifact indexes of every session in the
+// level concurrently, graphqlArtifactLoads at a time
+func resolveSessionArtifacts(ctx context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+	g := callerFrom(ctx)
+	out := make([]interface{}, len(parents))
+	sem := make(chan struct{}, graphqlArtifactLoads)
+	var wg sync.WaitGroup
+	for i, raw := range parents {
+		s := raw.(*graphqlSession)
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, s *graphqlSession) {
+			defer func() { <-sem; wg.Done() }()
+			index, err := artifacts.LoadIndex(g.c, s.project, s.obj.GetName())
+			if err != nil {
+				logErrorf(g.c, "graphql: failed to load artifact index for %s/%s: %v", s.project, s.obj.GetName(), err)
+				out[i] = fmt.Errorf("failed to load artifact index")
+				return
+			}
+			out[i] = index
+		}(i, s)
+	}
+	wg.Wait()
+	return out, nil
+}
+
+// POST /api/graphql
+// graphqlQuery runs a GraphQL query over projects, sessions, their usage and
+// artifacts so dashboards load nested views in one request. Every load uses
+// the caller's token and fields the caller may not read are null with an
+// error. The schema is served by GET /api/graphql/schema.
+func graphqlQuery(c *gin.Context) {
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req graphql.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	caller := &graphqlCaller{c: c, k8s: reqK8s, dyn: reqDyn, credential: callerCredential(c), access: map[string]ProjectAccess{}}
+	ctx := context.WithValue(c.Request.Context(), graphqlCallerKey{}, caller)
+	c.JSON(http.StatusOK, dashboardSchema.Execute(ctx, req))
+}
+
+// GET /api/graphql/schema
+// getGraphQLSchema returns the GraphQL schema in SDL
+func getGraphQLSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(graphqlSchemaSDL))
+}
@@ -16,8 +16,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	authnv1 "k8s.io/api/authentication/v1"
@@ -27,9 +31,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+
+	"ambient-code-backend/pkg/problem"
 )
 
 // feature flags and small helpers
@@ -68,6 +76,30 @@ func getK8sClientsForRequest(c *gin.Context) (*kubernetes.Clientset, dynamic.Int
 	hasAuthHeader := strings.TrimSpace(rawAuth) != ""
 	hasFwdToken := strings.TrimSpace(rawFwd) != ""
 
+	// Runner tokens are bound to the backend's audience and runner certificates
+	// carry none; the API server gets a token of the same session
+	// ServiceAccount instead
+	if session, ns, ok := runnerIdentity(c); ok {
+		apiToken, err := runnerAPIToken(c.Request.Context(), ns, session, c.GetString("userID"))
+		if err != nil {
+			logErrorf(c, "Failed to mint API token for the runner of %s/%s: %v", ns, session, err)
+			return nil, nil
+		}
+		token = apiToken
+	}
+
+	// With IMPERSONATE_USERS the backend acts as the validated caller
+	if cfg, ok := impersonationConfig(c); ok && token != "" {
+		kc, err1 := kubernetes.NewForConfig(cfg)
+		dc, err2 := dynamic.NewForConfig(cfg)
+		if err1 == nil && err2 == nil {
+			updateAccessKeyLastUsedAnnotation(c)
+			return kc, dc
+		}
+		logErrorf(c, "Failed to build impersonating k8s clients for %s: typedErr=%v dynamicErr=%v", c.GetString("userName"), err1, err2)
+		return nil, nil
+	}
+
 	if token != "" && baseKubeConfig != nil {
 		cfg := *baseKubeConfig
 		cfg.BearerToken = token
@@ -88,11 +120,11 @@ func getK8sClientsForRequest(c *gin.Context) (*kubernetes.Clientset, dynamic.Int
 			return kc, dc
 		}
 		// Token provided but client build failed – treat as invalid token
-		log.Printf("Failed to build user-scoped k8s clients (source=%s tokenLen=%d) typedErr=%v dynamicErr=%v for %s", tokenSource, len(token), err1, err2, c.FullPath())
+		logErrorf(c, "Failed to build user-scoped k8s clients (source=%s tokenLen=%d) typedErr=%v dynamicErr=%v for %s", tokenSource, len(token), err1, err2, c.FullPath())
 		return nil, nil
 	} else {
 		// No token provided
-		log.Printf("No user token found for %s (hasAuthHeader=%t hasFwdToken=%t)", c.FullPath(), hasAuthHeader, hasFwdToken)
+		logWarnf(c, "No user token found for %s (hasAuthHeader=%t hasFwdToken=%t)", c.FullPath(), hasAuthHeader, hasFwdToken)
 		return nil, nil
 	}
 }
@@ -173,22 +205,23 @@ func updateAccessKeyLastUsedAnnotation(c *gin.Context) {
 	}
 	_, err = k8sClient.CoreV1().ServiceAccounts(ns).Patch(c.Request.Context(), saName, types.MergePatchType, b, v1.PatchOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		log.Printf("Failed to update last-used annotation for SA %s/%s: %v", ns, saName, err)
+		logErrorf(c, "Failed to update last-used annotation for SA %s/%s: %v", ns, saName, err)
 	}
 }
 
 // Middleware for project context validation
 func validateProjectContext() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Require user/API key token; do not fall back to service account
-		if c.GetHeader("Authorization") == "" && c.GetHeader("X-Forwarded-Access-Token") == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User token required"})
+		// Require user/API key token or a runner certificate; do not fall back to service account
+		_, _, isRunner := runnerIdentity(c)
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-Forwarded-Access-Token") == "" && !isRunner {
+			respondError(c, http.StatusUnauthorized, "User token required")
 			c.Abort()
 			return
 		}
 		reqK8s, _ := getK8sClientsForRequest(c)
 		if reqK8s == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+			respondError(c, http.StatusUnauthorized, "Invalid or missing token")
 			c.Abort()
 			return
 		}
@@ -198,31 +231,29 @@ func validateProjectContext() gin.HandlerFunc {
 			projectHeader = c.GetHeader("X-OpenShift-Project")
 		}
 		if projectHeader == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Project is required in path /api/projects/:projectName or X-OpenShift-Project header"})
+			respondError(c, http.StatusBadRequest, "Project is required in path /api/projects/:projectName or X-OpenShift-Project header")
 			c.Abort()
 			return
 		}
 
 		// Ensure the caller has at least list permission on agenticsessions in the namespace
-		ssar := &authv1.SelfSubjectAccessReview{
-			Spec: authv1.SelfSubjectAccessReviewSpec{
-				ResourceAttributes: &authv1.ResourceAttributes{
-					Group:     "vteam.ambient-code",
-					Resource:  "agenticsessions",
-					Verb:      "list",
-					Namespace: projectHeader,
-				},
-			},
-		}
-		res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
+		allowed, err := canListSessions(c.Request.Context(), reqK8s, projectHeader)
 		if err != nil {
 			log.Printf("validateProjectContext: SSAR failed for %s: %v", projectHeader, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform access review"})
+			respondError(c, http.StatusInternalServerError, "Failed to perform access review")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			respondError(c, http.StatusForbidden, "Unauthorized to access project")
 			c.Abort()
 			return
 		}
-		if !res.Status.Allowed {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to access project"})
+		// Checked after access so the answer does not reveal which namespaces exist
+		if exists, err := projectNamespaceExists(c.Request.Context(), projectHeader); err != nil {
+			log.Printf("validateProjectContext: failed to look up namespace %s: %v", projectHeader, err)
+		} else if !exists {
+			respondError(c, http.StatusNotFound, "Project not found")
 			c.Abort()
 			return
 		}
@@ -233,6 +264,7 @@ func validateProjectContext() gin.HandlerFunc {
 	}
 }
 
+// GET /api/projects/:projectName/access
 // accessCheck verifies if the caller has write access to ProjectSettings in the project namespace
 // It performs a Kubernetes SelfSubjectAccessReview using the caller token (user or API key).
 func accessCheck(c *gin.Context) {
@@ -254,8 +286,8 @@ func accessCheck(c *gin.Context) {
 	// Perform the review
 	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
 	if err != nil {
-		log.Printf("SSAR failed for project %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to perform access review"})
+		logErrorf(c, "SSAR failed for project %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "failed to perform access review")
 		return
 	}
 
@@ -301,6 +333,69 @@ func parseSpec(spec map[string]interface{}) AgenticSessionSpec {
 		result.Interactive = interactive
 	}
 
+	if summaryReport, ok := spec["summaryReport"].(bool); ok {
+		result.SummaryReport = summaryReport
+	}
+
+	if trigger, ok := spec["trigger"].(map[string]interface{}); ok {
+		result.Trigger = parseTrigger(trigger)
+	}
+
+	if framework, ok := spec["framework"].(string); ok {
+		result.Framework = framework
+	}
+	if version, ok := spec["frameworkVersion"].(string); ok {
+		result.FrameworkVersion = version
+	}
+
+	if scratch, ok := spec["scratch"].(map[string]interface{}); ok {
+		result.Scratch = &SessionScratch{}
+		result.Scratch.Size, _ = scratch["size"].(string)
+	}
+
+	if scheduling, ok := spec["scheduling"].(map[string]interface{}); ok {
+		sched := &SessionScheduling{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(scheduling, sched); err == nil {
+			result.Scheduling = sched
+		}
+	}
+
+	if inputs, ok := spec["inputs"].([]interface{}); ok {
+		result.Inputs = parseSessionInputs(inputs)
+	}
+
+	if ttl, ok := intFromSpec(spec, "ttlSecondsAfterFinished"); ok {
+		result.TTLSecondsAfterFinished = &ttl
+	}
+
+	if retry, ok := spec["retryPolicy"].(map[string]interface{}); ok {
+		result.RetryPolicy = &SessionRetryPolicy{}
+		result.RetryPolicy.MaxRetries, _ = intFromSpec(retry, "maxRetries")
+		result.RetryPolicy.BackoffSeconds, _ = intFromSpec(retry, "backoffSeconds")
+	}
+	if liveness, ok := spec["liveness"].(map[string]interface{}); ok {
+		result.Liveness = &SessionLiveness{}
+		result.Liveness.TimeoutSeconds, _ = intFromSpec(liveness, "timeoutSeconds")
+		result.Liveness.KillOnStall, _ = liveness["killOnStall"].(bool)
+	}
+	if debug, ok := spec["debug"].(map[string]interface{}); ok {
+		result.Debug = &SessionDebug{}
+		result.Debug.KeepAliveSeconds, _ = intFromSpec(debug, "keepAliveSeconds")
+	}
+	if tools, ok := spec["tools"].(map[string]interface{}); ok {
+		result.Tools = &SessionTools{}
+		result.Tools.Allowed, _, _ = unstructured.NestedStringSlice(tools, "allowed")
+		result.Tools.Blocked, _, _ = unstructured.NestedStringSlice(tools, "blocked")
+	}
+	if budget, ok := spec["budget"].(map[string]interface{}); ok {
+		result.Budget = &SessionBudget{}
+		result.Budget.MaxCostUSD, _ = budget["maxCostUSD"].(string)
+	}
+	result.RetryOf, _ = spec["retryOf"].(string)
+	result.Priority, _ = spec["priority"].(string)
+	result.DryRun, _ = spec["dryRun"].(bool)
+	result.RetryAttempt, _ = intFromSpec(spec, "retryAttempt")
+
 	if paths, ok := spec["paths"].(map[string]interface{}); ok {
 		p := &Paths{}
 		if ws, ok := paths["workspace"].(string); ok {
@@ -337,6 +432,9 @@ func parseSpec(spec map[string]interface{}) AgenticSessionSpec {
 		if maxTokens, ok := llmSettings["maxTokens"].(float64); ok {
 			result.LLMSettings.MaxTokens = int(maxTokens)
 		}
+		if provider, ok := llmSettings["provider"].(string); ok {
+			result.LLMSettings.Provider = provider
+		}
 	}
 
 	if userContext, ok := spec["userContext"].(map[string]interface{}); ok {
@@ -373,6 +471,12 @@ func parseSpec(spec map[string]interface{}) AgenticSessionSpec {
 		if memory, ok := resourceOverrides["memory"].(string); ok {
 			ro.Memory = memory
 		}
+		if storage, ok := resourceOverrides["ephemeralStorage"].(string); ok {
+			ro.EphemeralStorage = storage
+		}
+		if gpu, ok := resourceOverrides["gpu"].(string); ok {
+			ro.GPU = gpu
+		}
 		if storageClass, ok := resourceOverrides["storageClass"].(string); ok {
 			ro.StorageClass = storageClass
 		}
@@ -427,6 +531,7 @@ func parseSpec(spec map[string]interface{}) AgenticSessionSpec {
 				}
 			}
 		}
+		result.GitConfig.PullRequests, _ = gitConfig["pullRequests"].(bool)
 	}
 
 	return result
@@ -434,72 +539,236 @@ func parseSpec(spec map[string]interface{}) AgenticSessionSpec {
 
 // V2 API Handlers - Multi-tenant session management
 
+// maxSessionListLimit caps the page size of GET agentic-sessions
+const maxSessionListLimit = 500
+
+// GET /api/projects/:projectName/agentic-sessions?phase=P1,P2&framework=F&labelSelector=SEL&sort=-createdAt&limit=N&continue=TOKEN&watch=true&resourceVersion=RV&timeoutSeconds=N
+// listSessions returns the sessions in the project. phase and framework filter
+// through the session's index labels, so sessions created before those labels
+// existed only match once the operator next updates their phase. With limit it
+// returns one page and a continue token for the next. Sessions are served from
+// the informer cache once it has synced: unsorted lists come in name order, as
+// from the API server, and the token is a cursor into that order. With sort
+// (createdAt, phase or name, "-" for descending) the token is a cursor into the
+// sorted order. Either cursor is only valid with the same sort and filters.
+// Before the cache syncs, unsorted pages use a Kubernetes list continuation.
+// The response's resourceVersion is where watch=true resumes from: a watch
+// streams the filtered sessions' changes as JSON lines (see watchSessions).
 func listSessions(c *gin.Context) {
 	project := c.GetString("project")
 	reqK8s, reqDyn := getK8sClientsForRequest(c)
 	_ = reqK8s
 	gvr := getAgenticSessionV1Alpha1Resource()
 
-	list, err := reqDyn.Resource(gvr).Namespace(project).List(context.TODO(), v1.ListOptions{})
+	selector, msg := sessionListSelector(c.Query("phase"), c.Query("framework"), c.Query("labelSelector"))
+	if msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
+	}
+	// Optional trigger fingerprint filter via the label index
+	if fp := strings.TrimSpace(c.Query("fingerprint")); fp != "" {
+		selector = append(selector, fmt.Sprintf("%s=%s", triggerFingerprintLabel, triggerFingerprintLabelValue(strings.ToLower(fp))))
+	}
+	labelSelector, err := labels.Parse(strings.Join(selector, ","))
 	if err != nil {
-		log.Printf("Failed to list agentic sessions in project %s: %v", project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list agentic sessions"})
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid labelSelector: %v", err))
+		return
+	}
+	if c.Query("watch") == "true" {
+		watchSessions(c, project, labelSelector)
 		return
 	}
 
-	var sessions []AgenticSession
-	for _, item := range list.Items {
-		session := AgenticSession{
-			APIVersion: item.GetAPIVersion(),
-			Kind:       item.GetKind(),
-			Metadata:   item.Object["metadata"].(map[string]interface{}),
+	var limit int64
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit < 1 || limit > maxSessionListLimit {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSessionListLimit))
+			return
 		}
-
-		if spec, ok := item.Object["spec"].(map[string]interface{}); ok {
-			session.Spec = parseSpec(spec)
+	}
+	order := sessionSort{field: "name"}
+	sorted := c.Query("sort") != ""
+	if sorted {
+		var ok bool
+		if order, ok = parseSessionSort(c.Query("sort")); !ok {
+			respondError(c, http.StatusBadRequest, "sort must be createdAt, phase or name, optionally prefixed with -")
+			return
 		}
+	}
 
-		if status, ok := item.Object["status"].(map[string]interface{}); ok {
-			session.Status = parseStatus(status)
+	// Read before the cache so a watch from it replays anything listed late
+	resourceVersion := sessionWatches.currentVersion()
+	items, cached := cachedSessions(project, labelSelector)
+	var next string
+	if !cached {
+		listOpts := v1.ListOptions{LabelSelector: labelSelector.String()}
+		if !sorted && limit > 0 {
+			listOpts.Limit = limit
+			listOpts.Continue = c.Query("continue")
 		}
+		list, err := reqDyn.Resource(gvr).Namespace(project).List(context.TODO(), listOpts)
+		if errors.IsResourceExpired(err) {
+			respondError(c, http.StatusGone, "continue token expired; list again from the first page")
+			return
+		}
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		items, next, resourceVersion = list.Items, list.GetContinue(), list.GetResourceVersion()
+	}
+	if sorted || cached {
+		// Sorting needs the whole filtered set; pages are cut from it by cursor
+		if items, next, err = order.page(items, c.Query("continue"), limit); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
-		sessions = append(sessions, session)
+	var sessions []AgenticSession
+	for i := range items {
+		sessions = append(sessions, agenticSessionFromObject(&items[i]))
 	}
 
-	c.JSON(http.StatusOK, gin.H{"items": sessions})
+	resp := gin.H{"items": sessions}
+	if next != "" {
+		resp["continue"] = next
+	}
+	if resourceVersion != "" {
+		resp["resourceVersion"] = resourceVersion
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// sessionLLMSettings fills in defaults for LLM settings not provided on create
+func sessionLLMSettings(req *LLMSettings) LLMSettings {
+	llmSettings := LLMSettings{
+		Model:       "sonnet",
+		Temperature: 0.7,
+		MaxTokens:   4000,
+	}
+	if req != nil {
+		if req.Model != "" {
+			llmSettings.Model = req.Model
+		}
+		if req.Temperature != 0 {
+			llmSettings.Temperature = req.Temperature
+		}
+		if req.MaxTokens != 0 {
+			llmSettings.MaxTokens = req.MaxTokens
+		}
+		llmSettings.Provider = strings.TrimSpace(req.Provider)
+	}
+	return llmSettings
+}
+
+// sessionTimeout returns the requested timeout, or 0 when the request leaves
+// it to the operator's default
+func sessionTimeout(req *int) int {
+	if req != nil {
+		return *req
+	}
+	return 0
 }
 
+// POST /api/projects/:projectName/agentic-sessions
+// createSession admits a new session against the project and cluster policy and creates it.
 func createSession(c *gin.Context) {
 	project := c.GetString("project")
 	reqK8s, reqDyn := getK8sClientsForRequest(c)
 	_ = reqK8s
 	var req CreateAgenticSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondSessionBindError(c, err)
+		return
+	}
+	if fieldErrors, err := validateSessionRequest(c.Request.Context(), &req); err != nil {
+		logErrorf(c, "Failed to validate session request in %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to validate session request")
+		return
+	} else if len(fieldErrors) > 0 {
+		respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", "Invalid session request").With("fieldErrors", fieldErrors))
 		return
 	}
 
-	// Set defaults for LLM settings if not provided
-	llmSettings := LLMSettings{
-		Model:       "sonnet",
-		Temperature: 0.7,
-		MaxTokens:   4000,
+	// Replayed webhook deliveries return the session created for the first one
+	deliveryKey, deliveryID := webhookDeliveryKey(c, req.Trigger)
+	if deliveryKey != "" {
+		if existing, err := lookupWebhookDelivery(c.Request.Context(), project, deliveryKey); err != nil {
+			logErrorf(c, "Failed to look up webhook delivery in %s: %v", project, err)
+		} else if existing != "" {
+			respondDuplicateDelivery(c, deliveryID, existing)
+			return
+		}
+		if req.Trigger != nil {
+			req.Trigger.DeliveryID = deliveryID
+		}
 	}
-	if req.LLMSettings != nil {
-		if req.LLMSettings.Model != "" {
-			llmSettings.Model = req.LLMSettings.Model
+
+	llmSettings := sessionLLMSettings(req.LLMSettings)
+	timeout := sessionTimeout(req.Timeout)
+	// Dry runs are created whatever the policy says; the evaluation goes to the
+	// operator, which reports it in status.plan
+	var policyEvaluation []PolicyRuleResult
+	if req.DryRun {
+		sim, ok := evaluateSessionPolicy(c, reqDyn, project, req)
+		if !ok {
+			return
 		}
-		if req.LLMSettings.Temperature != 0 {
-			llmSettings.Temperature = req.LLMSettings.Temperature
+		policyEvaluation = sim.Rules
+	} else {
+		if req.Timeout != nil && !enforceSessionTimeoutPolicy(c, reqDyn, project, int64(timeout)) {
+			return
 		}
-		if req.LLMSettings.MaxTokens != 0 {
-			llmSettings.MaxTokens = req.LLMSettings.MaxTokens
+		if !enforceSessionResourcePolicy(c, reqDyn, project, req.ResourceOverrides) {
+			return
+		}
+		if !enforceSessionSchedulingPolicy(c, reqDyn, project, req.Scheduling) {
+			return
+		}
+		if !enforceSessionScratchPolicy(c, reqDyn, project, req.Scratch) {
+			return
+		}
+		if !enforceSessionModelPolicy(c, reqDyn, project, req.Framework, llmSettings) {
+			return
+		}
+		if !enforceSessionBudgetPolicy(c, reqDyn, project, req.Budget) {
+			return
+		}
+		if !enforceSessionTTLPolicy(c, req.TTLSecondsAfterFinished) {
+			return
+		}
+		if !enforceSessionPriorityPolicy(c, reqDyn, project, req.Priority) {
+			return
+		}
+		if !enforceSessionToolsPolicy(c, reqDyn, project, req.Tools) {
+			return
+		}
+		if !enforceSessionDebugPolicy(c, reqDyn, project, req.Debug) {
+			return
+		}
+		if len(req.Inputs) > 0 {
+			msg, err := validateSessionInputs(c, reqDyn, project, req.Inputs)
+			if err != nil {
+				logErrorf(c, "Failed to validate session inputs in %s: %v", project, err)
+				respondError(c, http.StatusInternalServerError, "Failed to validate session inputs")
+				return
+			}
+			if msg != "" {
+				respondError(c, http.StatusBadRequest, msg)
+				return
+			}
 		}
 	}
-
-	timeout := 300
-	if req.Timeout != nil {
-		timeout = *req.Timeout
+	if msg := validateSessionRetryPolicy(req.RetryPolicy); msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := validateSessionLiveness(req.Liveness); msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
 	}
 
 	// Generate unique name
@@ -519,11 +788,20 @@ func createSession(c *gin.Context) {
 		}
 		metadata["labels"] = labels
 	}
-	if len(req.Annotations) > 0 {
-		annotations := map[string]interface{}{}
-		for k, v := range req.Annotations {
-			annotations[k] = v
+	annotations := map[string]interface{}{}
+	for k, v := range req.Annotations {
+		annotations[k] = v
+	}
+	// The operator and runner continue the trace of the creating request
+	if tp := traceparentFromContext(c); tp != "" {
+		annotations[traceparentAnnotation] = tp
+	}
+	if policyEvaluation != nil {
+		if b, err := json.Marshal(policyEvaluation); err == nil {
+			annotations[policyEvaluationAnnotation] = string(b)
 		}
+	}
+	if len(annotations) > 0 {
 		metadata["annotations"] = annotations
 	}
 
@@ -540,13 +818,17 @@ func createSession(c *gin.Context) {
 				"temperature": llmSettings.Temperature,
 				"maxTokens":   llmSettings.MaxTokens,
 			},
-			"timeout": timeout,
 		},
 		"status": map[string]interface{}{
 			"phase": "Pending",
 		},
 	}
 
+	// Sessions without a timeout get the operator's default Job deadline
+	if req.Timeout != nil {
+		session["spec"].(map[string]interface{})["timeout"] = timeout
+	}
+
 	// Only include paths if a workspacePath was provided
 	if strings.TrimSpace(req.WorkspacePath) != "" {
 		spec := session["spec"].(map[string]interface{})
@@ -566,9 +848,108 @@ func createSession(c *gin.Context) {
 		session["spec"].(map[string]interface{})["interactive"] = *req.Interactive
 	}
 
+	// Optional post-run executive summary report
+	if req.SummaryReport != nil {
+		session["spec"].(map[string]interface{})["summaryReport"] = *req.SummaryReport
+	}
+
+	if req.DryRun {
+		session["spec"].(map[string]interface{})["dryRun"] = true
+	}
+
+	if llmSettings.Provider != "" {
+		session["spec"].(map[string]interface{})["llmSettings"].(map[string]interface{})["provider"] = llmSettings.Provider
+	}
+
+	// Registered runner framework, also used for per-framework concurrency limits
+	if strings.TrimSpace(req.Framework) != "" {
+		session["spec"].(map[string]interface{})["framework"] = strings.TrimSpace(req.Framework)
+	}
+	if strings.TrimSpace(req.FrameworkVersion) != "" {
+		session["spec"].(map[string]interface{})["frameworkVersion"] = strings.TrimSpace(req.FrameworkVersion)
+	}
+
+	if req.Scratch != nil && strings.TrimSpace(req.Scratch.Size) != "" {
+		session["spec"].(map[string]interface{})["scratch"] = map[string]interface{}{"size": strings.TrimSpace(req.Scratch.Size)}
+	}
+
+	if req.TTLSecondsAfterFinished != nil {
+		session["spec"].(map[string]interface{})["ttlSecondsAfterFinished"] = *req.TTLSecondsAfterFinished
+	}
+
+	if p := strings.TrimSpace(req.Priority); p != "" {
+		session["spec"].(map[string]interface{})["priority"] = p
+	}
+
+	if req.Tools != nil && (len(req.Tools.Allowed) > 0 || len(req.Tools.Blocked) > 0) {
+		session["spec"].(map[string]interface{})["tools"] = sessionToolsToSpec(req.Tools)
+	}
+
+	if req.Budget != nil {
+		session["spec"].(map[string]interface{})["budget"] = map[string]interface{}{"maxCostUSD": strings.TrimSpace(req.Budget.MaxCostUSD)}
+	}
+
+	if req.RetryPolicy != nil && req.RetryPolicy.MaxRetries > 0 {
+		retry := map[string]interface{}{"maxRetries": req.RetryPolicy.MaxRetries}
+		if req.RetryPolicy.BackoffSeconds > 0 {
+			retry["backoffSeconds"] = req.RetryPolicy.BackoffSeconds
+		}
+		session["spec"].(map[string]interface{})["retryPolicy"] = retry
+	}
+
+	if req.Debug != nil && req.Debug.KeepAliveSeconds > 0 {
+		session["spec"].(map[string]interface{})["debug"] = map[string]interface{}{"keepAliveSeconds": req.Debug.KeepAliveSeconds}
+	}
+
+	if req.Liveness != nil && (req.Liveness.TimeoutSeconds > 0 || req.Liveness.KillOnStall) {
+		liveness := map[string]interface{}{"killOnStall": req.Liveness.KillOnStall}
+		if req.Liveness.TimeoutSeconds > 0 {
+			liveness["timeoutSeconds"] = req.Liveness.TimeoutSeconds
+		}
+		session["spec"].(map[string]interface{})["liveness"] = liveness
+	}
+
+	// Node placement for GPU or otherwise specialized runners
+	if req.Scheduling != nil {
+		scheduling, err := runtime.DefaultUnstructuredConverter.ToUnstructured(req.Scheduling)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid scheduling: %v", err))
+			return
+		}
+		session["spec"].(map[string]interface{})["scheduling"] = scheduling
+	}
+
+	if len(req.Inputs) > 0 {
+		session["spec"].(map[string]interface{})["inputs"] = sessionInputsToSpec(req.Inputs)
+	}
+
+	// Trigger metadata with a server-computed fingerprint, indexed by label for lookup
+	if req.Trigger != nil && strings.TrimSpace(req.Trigger.Source) != "" {
+		trigger := triggerToSpec(*req.Trigger)
+		session["spec"].(map[string]interface{})["trigger"] = trigger
+		labels, _ := metadata["labels"].(map[string]interface{})
+		if labels == nil {
+			labels = map[string]interface{}{}
+			metadata["labels"] = labels
+		}
+		labels[triggerFingerprintLabel] = triggerFingerprintLabelValue(trigger["fingerprint"].(string))
+		auditDetail(c, "trigger", gin.H{"source": req.Trigger.Source, "event": req.Trigger.Event, "fingerprint": trigger["fingerprint"]})
+	}
+
+	// Index labels for server-side list filtering; the operator keeps the phase current
+	indexLabels, _ := metadata["labels"].(map[string]interface{})
+	if indexLabels == nil {
+		indexLabels = map[string]interface{}{}
+		metadata["labels"] = indexLabels
+	}
+	indexLabels[sessionPhaseLabel] = "Pending"
+	if fw := sessionFrameworkFromSpec(map[string]interface{}{"framework": req.Framework}); labelValuePattern.MatchString(fw) {
+		indexLabels[sessionFrameworkLabel] = fw
+	}
+
 	// Load Git configuration from ConfigMap and merge with user-provided config
 	if defaultGitConfig, err := loadGitConfigFromConfigMapForProject(c, reqK8s, project); err != nil {
-		log.Printf("Warning: failed to load Git config from ConfigMap in %s: %v", project, err)
+		logErrorf(c, "Warning: failed to load Git config from ConfigMap in %s: %v", project, err)
 	} else {
 		mergedGitConfig := mergeGitConfigs(req.GitConfig, defaultGitConfig)
 		if mergedGitConfig != nil {
@@ -608,6 +989,9 @@ func createSession(c *gin.Context) {
 				}
 				gitConfig["repositories"] = repos
 			}
+			if mergedGitConfig.PullRequests {
+				gitConfig["pullRequests"] = true
+			}
 			if len(gitConfig) > 0 {
 				session["spec"].(map[string]interface{})["gitConfig"] = gitConfig
 			}
@@ -639,6 +1023,12 @@ func createSession(c *gin.Context) {
 		if req.ResourceOverrides.Memory != "" {
 			resourceOverrides["memory"] = req.ResourceOverrides.Memory
 		}
+		if req.ResourceOverrides.EphemeralStorage != "" {
+			resourceOverrides["ephemeralStorage"] = req.ResourceOverrides.EphemeralStorage
+		}
+		if req.ResourceOverrides.GPU != "" {
+			resourceOverrides["gpu"] = req.ResourceOverrides.GPU
+		}
 		if req.ResourceOverrides.StorageClass != "" {
 			resourceOverrides["storageClass"] = req.ResourceOverrides.StorageClass
 		}
@@ -653,12 +1043,37 @@ func createSession(c *gin.Context) {
 	gvr := getAgenticSessionV1Alpha1Resource()
 	obj := &unstructured.Unstructured{Object: session}
 
+	// Claim the delivery just before creating so concurrent replays cannot both create
+	if deliveryKey != "" {
+		existing, err := claimWebhookDelivery(c.Request.Context(), project, deliveryKey, name)
+		if err != nil {
+			logErrorf(c, "Failed to record webhook delivery in %s: %v", project, err)
+		} else if existing != "" {
+			respondDuplicateDelivery(c, deliveryID, existing)
+			return
+		}
+	}
+
 	created, err := reqDyn.Resource(gvr).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
 	if err != nil {
-		log.Printf("Failed to create agentic session in project %s: %v", project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agentic session"})
+		logErrorf(c, "Failed to create agentic session in project %s: %v", project, err)
+		if deliveryKey != "" {
+			if rerr := releaseWebhookDelivery(context.Background(), project, deliveryKey, name); rerr != nil {
+				logErrorf(c, "Failed to release webhook delivery in %s: %v", project, rerr)
+			}
+		}
+		// Admission policies, such as the namespace's generated one, deny with Invalid
+		switch {
+		case errors.IsInvalid(err):
+			respondError(c, http.StatusUnprocessableEntity, err.Error())
+		case errors.IsForbidden(err):
+			respondError(c, http.StatusForbidden, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, "Failed to create agentic session")
+		}
 		return
 	}
+	c.Set("createdSession", created.GetName())
 
 	// Best-effort prefill of agent markdown into PVC workspace for immediate UI availability
 	// Uses AGENT_PERSONAS or AGENT_PERSONA if provided in request environment variables
@@ -696,145 +1111,20 @@ func createSession(c *gin.Context) {
 		}
 	}()
 
-	// Preferred method: provision a per-session ServiceAccount token for the runner
-	if err := provisionRunnerTokenForSession(c, reqK8s, reqDyn, project, name); err != nil {
-		// Non-fatal: log and continue. Operator may retry later if implemented.
-		log.Printf("Warning: failed to provision runner token for session %s/%s: %v", project, name, err)
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
+	auditDetail(c, "created", name)
+	resp := gin.H{
 		"message": "Agentic session created successfully",
 		"name":    name,
 		"uid":     created.GetUID(),
-	})
-}
-
-// provisionRunnerTokenForSession creates a per-session ServiceAccount, grants minimal RBAC,
-// mints a short-lived token, stores it in a Secret, and annotates the AgenticSession with the Secret name.
-func provisionRunnerTokenForSession(c *gin.Context, reqK8s *kubernetes.Clientset, reqDyn dynamic.Interface, project string, sessionName string) error {
-	// Load owning AgenticSession to parent all resources
-	gvr := getAgenticSessionV1Alpha1Resource()
-	obj, err := reqDyn.Resource(gvr).Namespace(project).Get(c.Request.Context(), sessionName, v1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("get AgenticSession: %w", err)
-	}
-	ownerRef := v1.OwnerReference{
-		APIVersion: obj.GetAPIVersion(),
-		Kind:       obj.GetKind(),
-		Name:       obj.GetName(),
-		UID:        obj.GetUID(),
-		Controller: boolPtr(true),
-	}
-
-	// Create ServiceAccount
-	saName := fmt.Sprintf("ambient-session-%s", sessionName)
-	sa := &corev1.ServiceAccount{
-		ObjectMeta: v1.ObjectMeta{
-			Name:            saName,
-			Namespace:       project,
-			Labels:          map[string]string{"app": "ambient-runner"},
-			OwnerReferences: []v1.OwnerReference{ownerRef},
-		},
-	}
-	if _, err := reqK8s.CoreV1().ServiceAccounts(project).Create(c.Request.Context(), sa, v1.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("create SA: %w", err)
-		}
 	}
-
-	// Create Role with least-privilege for updating AgenticSession status
-	roleName := fmt.Sprintf("ambient-session-%s-role", sessionName)
-	role := &rbacv1.Role{
-		ObjectMeta: v1.ObjectMeta{
-			Name:            roleName,
-			Namespace:       project,
-			OwnerReferences: []v1.OwnerReference{ownerRef},
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{"vteam.ambient-code"},
-				Resources: []string{"agenticsessions"},
-				Verbs:     []string{"get", "list", "watch", "update", "patch"},
-			},
-			{
-				APIGroups: []string{"vteam.ambient-code"},
-				Resources: []string{"agenticsessions/status"},
-				Verbs:     []string{"update", "patch", "get"},
-			},
-		},
+	if warnings := sessionAdmissionWarnings(c.Request.Context(), reqDyn, project, req.Framework, req.FrameworkVersion); len(warnings) > 0 {
+		setWarningHeaders(c, warnings)
+		resp["warnings"] = warnings
 	}
-	if _, err := reqK8s.RbacV1().Roles(project).Create(c.Request.Context(), role, v1.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("create Role: %w", err)
-		}
-	}
-
-	// Bind Role to the ServiceAccount
-	rbName := fmt.Sprintf("ambient-session-%s-rb", sessionName)
-	rb := &rbacv1.RoleBinding{
-		ObjectMeta: v1.ObjectMeta{
-			Name:            rbName,
-			Namespace:       project,
-			OwnerReferences: []v1.OwnerReference{ownerRef},
-		},
-		RoleRef:  rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: roleName},
-		Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Name: saName, Namespace: project}},
-	}
-	if _, err := reqK8s.RbacV1().RoleBindings(project).Create(c.Request.Context(), rb, v1.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("create RoleBinding: %w", err)
-		}
-	}
-
-	// Mint short-lived token for the ServiceAccount
-	tr := &authnv1.TokenRequest{Spec: authnv1.TokenRequestSpec{}}
-	tok, err := reqK8s.CoreV1().ServiceAccounts(project).CreateToken(c.Request.Context(), saName, tr, v1.CreateOptions{})
-	if err != nil {
-		return fmt.Errorf("mint token: %w", err)
-	}
-	token := tok.Status.Token
-	if strings.TrimSpace(token) == "" {
-		return fmt.Errorf("received empty token for SA %s", saName)
-	}
-
-	// Store token in a Secret
-	secretName := fmt.Sprintf("ambient-runner-token-%s", sessionName)
-	sec := &corev1.Secret{
-		ObjectMeta: v1.ObjectMeta{
-			Name:            secretName,
-			Namespace:       project,
-			Labels:          map[string]string{"app": "ambient-runner-token"},
-			OwnerReferences: []v1.OwnerReference{ownerRef},
-		},
-		Type:       corev1.SecretTypeOpaque,
-		StringData: map[string]string{"token": token},
-	}
-	if _, err := reqK8s.CoreV1().Secrets(project).Create(c.Request.Context(), sec, v1.CreateOptions{}); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("create Secret: %w", err)
-		}
-	}
-
-	// Annotate the AgenticSession with the Secret and SA names
-	meta, _ := obj.Object["metadata"].(map[string]interface{})
-	if meta == nil {
-		meta = map[string]interface{}{}
-		obj.Object["metadata"] = meta
-	}
-	anns, _ := meta["annotations"].(map[string]interface{})
-	if anns == nil {
-		anns = map[string]interface{}{}
-		meta["annotations"] = anns
-	}
-	anns["ambient-code.io/runner-token-secret"] = secretName
-	anns["ambient-code.io/runner-sa"] = saName
-	if _, err := reqDyn.Resource(gvr).Namespace(project).Update(c.Request.Context(), obj, v1.UpdateOptions{}); err != nil {
-		return fmt.Errorf("annotate AgenticSession: %w", err)
-	}
-
-	return nil
+	c.JSON(http.StatusCreated, resp)
 }
 
+// GET /api/projects/:projectName/agentic-sessions/:sessionName
 func getSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -842,15 +1132,19 @@ func getSession(c *gin.Context) {
 	_ = reqK8s
 	gvr := getAgenticSessionV1Alpha1Resource()
 
-	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	item, cached := cachedSession(project, sessionName)
+	if !cached {
+		var err error
+		item, err = reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				respondError(c, http.StatusNotFound, "Session not found")
+				return
+			}
+			logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
 			return
 		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
-		return
 	}
 
 	session := AgenticSession{
@@ -873,6 +1167,7 @@ func getSession(c *gin.Context) {
 // GET /api/projects/:projectName/agentic-sessions/:sessionName/messages
 // Returns the messages.json content for a session by fetching from the per-project content service
 // and falling back to local state directory if the content service is unavailable.
+// With any of ?offset, limit, role, tool or contentType it returns a filtered page instead.
 func getSessionMessages(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -880,33 +1175,66 @@ func getSessionMessages(c *gin.Context) {
 	// First try via per-namespace content service using caller's token
 	data, err := readProjectContentFile(c, project, fmt.Sprintf("/sessions/%s/messages.json", sessionName))
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch messages"})
+		respondError(c, http.StatusBadGateway, "failed to fetch messages")
+		return
+	}
+	if sessionMessagesQuery(c) {
+		respondSessionMessagesPage(c, data)
 		return
 	}
 	c.Data(http.StatusOK, "application/json", data)
 }
 
 // POST /api/projects/:projectName/agentic-sessions/:sessionName/messages
-// Appends a user message to the session inbox (JSONL) using the per-project content service
+// Appends a user message to the session inbox (JSONL) using the per-project content service.
+// The runner receives it through the inbox long-poll and records it in messages.json.
 func postSessionMessage(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
+	_, reqDyn := getK8sClientsForRequest(c)
 
 	var body struct {
 		Content string `json:"content" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.Content) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+		respondError(c, http.StatusBadRequest, "content is required")
 		return
 	}
 
-	entry := map[string]interface{}{
-		"type":      "user_message",
-		"content":   body.Content,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	// Only interactive sessions that have not finished read their inbox
+	item, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).Get(c.Request.Context(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+	if interactive, _, _ := unstructured.NestedBool(item.Object, "spec", "interactive"); !interactive {
+		respondError(c, http.StatusConflict, "Session is not interactive")
+		return
+	}
+	switch phase, _, _ := unstructured.NestedString(item.Object, "status", "phase"); phase {
+	case "Completed", "Failed", "Stopped", "Error":
+		respondError(c, http.StatusConflict, fmt.Sprintf("Session has already finished (%s)", phase))
+		return
 	}
 
-	inboxPath := fmt.Sprintf("/sessions/%s/inbox.jsonl", sessionName)
+	entry := SessionInboxMessage{
+		ID:        newInboxMessageID(),
+		Type:      "user_message",
+		Content:   body.Content,
+		Sender:    requesterFromContext(c),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	inboxPath := sessionInboxPath(sessionName)
+	key := inboxKey(project, sessionName)
+	mu := inboxWriteLock(key)
+	mu.Lock()
+	defer mu.Unlock()
 
 	// Read current inbox (best effort)
 	cur, _ := readProjectContentFile(c, project, inboxPath)
@@ -918,11 +1246,13 @@ func postSessionMessage(c *gin.Context) {
 	newContent := curStr + string(b) + "\n"
 
 	if err := writeProjectContentFile(c, project, inboxPath, []byte(newContent)); err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to write inbox"})
+		respondError(c, http.StatusBadGateway, "failed to write inbox")
 		return
 	}
+	inboxNotify(key)
+	auditDetail(c, "messageId", entry.ID)
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	c.JSON(http.StatusOK, gin.H{"ok": true, "id": entry.ID})
 }
 
 // resolveWorkspaceAbsPath normalizes a workspace-relative or absolute path to the
@@ -957,7 +1287,7 @@ func getSessionWorkspace(c *gin.Context) {
 		if len(items) == 1 && strings.TrimRight(items[0].Path, "/") == absPath && !items[0].IsDir {
 			b, ferr := readProjectContentFile(c, project, absPath)
 			if ferr != nil {
-				c.JSON(http.StatusBadGateway, gin.H{"error": "failed to read workspace file"})
+				respondError(c, http.StatusBadGateway, "failed to read workspace file")
 				return
 			}
 			c.Data(http.StatusOK, "application/octet-stream", b)
@@ -969,7 +1299,7 @@ func getSessionWorkspace(c *gin.Context) {
 	// Fallback: try file read directly
 	b, ferr := readProjectContentFile(c, project, absPath)
 	if ferr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to access workspace"})
+		respondError(c, http.StatusBadGateway, "failed to access workspace")
 		return
 	}
 	c.Data(http.StatusOK, "application/octet-stream", b)
@@ -991,7 +1321,7 @@ func getSessionWorkspaceFile(c *gin.Context) {
 			// It's a file
 			b, ferr := readProjectContentFile(c, project, absPath)
 			if ferr != nil {
-				c.JSON(http.StatusBadGateway, gin.H{"error": "failed to read workspace file"})
+				respondError(c, http.StatusBadGateway, "failed to read workspace file")
 				return
 			}
 			c.Data(http.StatusOK, "application/octet-stream", b)
@@ -1004,7 +1334,7 @@ func getSessionWorkspaceFile(c *gin.Context) {
 	// Fallback to file read
 	b, ferr := readProjectContentFile(c, project, absPath)
 	if ferr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to access workspace"})
+		respondError(c, http.StatusBadGateway, "failed to access workspace")
 		return
 	}
 	c.Data(http.StatusOK, "application/octet-stream", b)
@@ -1022,12 +1352,16 @@ func putSessionWorkspaceFile(c *gin.Context) {
 	// Read raw request body and forward as-is (treat as text/binary pass-through)
 	data, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		respondError(c, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	if !enforceStorageQuota(c, project, sessionName, absPath, int64(len(data))) {
 		return
 	}
 
 	if err := writeProjectContentFile(c, project, absPath, data); err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to write workspace file"})
+		respondError(c, http.StatusBadGateway, "failed to write workspace file")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "ok"})
@@ -1065,7 +1399,7 @@ func getRFEWorkflowWorkspace(c *gin.Context) {
 		if len(items) == 1 && strings.TrimRight(items[0].Path, "/") == absPath && !items[0].IsDir {
 			b, ferr := readProjectContentFile(c, project, absPath)
 			if ferr != nil {
-				c.JSON(http.StatusBadGateway, gin.H{"error": "failed to read workspace file"})
+				respondError(c, http.StatusBadGateway, "failed to read workspace file")
 				return
 			}
 			c.Data(http.StatusOK, "application/octet-stream", b)
@@ -1077,7 +1411,7 @@ func getRFEWorkflowWorkspace(c *gin.Context) {
 	// Fallback: try file read directly
 	b, ferr := readProjectContentFile(c, project, absPath)
 	if ferr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to access workspace"})
+		respondError(c, http.StatusBadGateway, "failed to access workspace")
 		return
 	}
 	c.Data(http.StatusOK, "application/octet-stream", b)
@@ -1099,7 +1433,7 @@ func getRFEWorkflowWorkspaceFile(c *gin.Context) {
 			// It's a file
 			b, ferr := readProjectContentFile(c, project, absPath)
 			if ferr != nil {
-				c.JSON(http.StatusBadGateway, gin.H{"error": "failed to read workspace file"})
+				respondError(c, http.StatusBadGateway, "failed to read workspace file")
 				return
 			}
 			c.Data(http.StatusOK, "application/octet-stream", b)
@@ -1112,7 +1446,7 @@ func getRFEWorkflowWorkspaceFile(c *gin.Context) {
 	// Fallback to file read
 	b, ferr := readProjectContentFile(c, project, absPath)
 	if ferr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to access workspace"})
+		respondError(c, http.StatusBadGateway, "failed to access workspace")
 		return
 	}
 	c.Data(http.StatusOK, "application/octet-stream", b)
@@ -1130,12 +1464,12 @@ func putRFEWorkflowWorkspaceFile(c *gin.Context) {
 	// Read raw request body and forward as-is (treat as text/binary pass-through)
 	data, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		respondError(c, http.StatusBadRequest, "failed to read request body")
 		return
 	}
 
 	if err := writeProjectContentFile(c, project, absPath, data); err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to write workspace file"})
+		respondError(c, http.StatusBadGateway, "failed to write workspace file")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "ok"})
@@ -1212,7 +1546,7 @@ func mergeGitConfigs(userConfig, defaultConfig *GitConfig) *GitConfig {
 		return userConfig
 	}
 
-	merged := &GitConfig{}
+	merged := &GitConfig{PullRequests: userConfig.PullRequests}
 	if userConfig.User != nil {
 		merged.User = userConfig.User
 	} else if defaultConfig.User != nil {
@@ -1269,6 +1603,8 @@ func countArtifacts(artifactsDir string) int {
 	return count
 }
 
+// PUT /api/projects/:projectName/agentic-sessions/:sessionName
+// updateSession replaces the prompt and display name of a session and merges its LLM settings.
 func updateSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -1277,7 +1613,7 @@ func updateSession(c *gin.Context) {
 
 	var req CreateAgenticSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondSessionBindError(c, err)
 		return
 	}
 
@@ -1295,17 +1631,21 @@ func updateSession(c *gin.Context) {
 			time.Sleep(300 * time.Millisecond)
 			continue
 		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		respondError(c, http.StatusNotFound, "Session not found")
 		return
 	}
 
 	// Update spec
 	spec := item.Object["spec"].(map[string]interface{})
+	if v := checkImmutableSessionFields(spec, &req); v != nil {
+		rejectSession(c, v)
+		return
+	}
 	spec["prompt"] = req.Prompt
 	spec["displayName"] = req.DisplayName
 
@@ -1320,18 +1660,29 @@ func updateSession(c *gin.Context) {
 		if req.LLMSettings.MaxTokens != 0 {
 			llmSettings["maxTokens"] = req.LLMSettings.MaxTokens
 		}
+		if req.LLMSettings.Provider != "" {
+			llmSettings["provider"] = strings.TrimSpace(req.LLMSettings.Provider)
+		}
+		parsed := parseSpec(map[string]interface{}{"llmSettings": llmSettings})
+		framework, _ := spec["framework"].(string)
+		if !enforceSessionModelPolicy(c, reqDyn, project, framework, parsed.LLMSettings) {
+			return
+		}
 		spec["llmSettings"] = llmSettings
 	}
 
 	if req.Timeout != nil {
+		if !enforceSessionTimeoutPolicy(c, reqDyn, project, int64(*req.Timeout)) {
+			return
+		}
 		spec["timeout"] = *req.Timeout
 	}
 
 	// Update the resource
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
-		log.Printf("Failed to update agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agentic session"})
+		logErrorf(c, "Failed to update agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to update agentic session")
 		return
 	}
 
@@ -1364,7 +1715,7 @@ func updateSessionDisplayName(c *gin.Context) {
 		DisplayName string `json:"displayName" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -1374,11 +1725,11 @@ func updateSessionDisplayName(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
 		return
 	}
 
@@ -1393,8 +1744,8 @@ func updateSessionDisplayName(c *gin.Context) {
 	// Persist the change
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
-		log.Printf("Failed to update display name for agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update display name"})
+		logErrorf(c, "Failed to update display name for agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to update display name")
 		return
 	}
 
@@ -1414,6 +1765,7 @@ func updateSessionDisplayName(c *gin.Context) {
 	c.JSON(http.StatusOK, session)
 }
 
+// DELETE /api/projects/:projectName/agentic-sessions/:sessionName
 func deleteSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -1424,17 +1776,19 @@ func deleteSession(c *gin.Context) {
 	err := reqDyn.Resource(gvr).Namespace(project).Delete(context.TODO(), sessionName, v1.DeleteOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		log.Printf("Failed to delete agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete agentic session"})
+		logErrorf(c, "Failed to delete agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete agentic session")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/clone
+// cloneSession copies a session's spec into a new session, optionally in another project.
 func cloneSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -1442,7 +1796,7 @@ func cloneSession(c *gin.Context) {
 
 	var req CloneSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -1452,11 +1806,11 @@ func cloneSession(c *gin.Context) {
 	sourceItem, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Source session not found"})
+			respondError(c, http.StatusNotFound, "Source session not found")
 			return
 		}
-		log.Printf("Failed to get source agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get source agentic session"})
+		logErrorf(c, "Failed to get source agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get source agentic session")
 		return
 	}
 
@@ -1465,10 +1819,10 @@ func cloneSession(c *gin.Context) {
 	projObj, err := reqDyn.Resource(projGvr).Get(context.TODO(), req.TargetProject, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Target project not found"})
+			respondError(c, http.StatusNotFound, "Target project not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate target project"})
+		respondError(c, http.StatusInternalServerError, "Failed to validate target project")
 		return
 	}
 
@@ -1481,7 +1835,7 @@ func cloneSession(c *gin.Context) {
 		}
 	}
 	if !isAmbient {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Target project is not managed by Ambient"})
+		respondError(c, http.StatusForbidden, "Target project is not managed by Ambient")
 		return
 	}
 
@@ -1499,7 +1853,7 @@ func cloneSession(c *gin.Context) {
 		}
 		if getErr != nil && !errors.IsNotFound(getErr) {
 			// On unexpected error, still attempt to proceed with a duplicate suffix to reduce collision chance
-			log.Printf("cloneSession: name check encountered error for %s/%s: %v", req.TargetProject, finalName, getErr)
+			logErrorf(c, "cloneSession: name check encountered error for %s/%s: %v", req.TargetProject, finalName, getErr)
 		}
 		conflicted = true
 		if i == 0 {
@@ -1526,6 +1880,9 @@ func cloneSession(c *gin.Context) {
 	// Update project in spec
 	clonedSpec := clonedSession["spec"].(map[string]interface{})
 	clonedSpec["project"] = req.TargetProject
+	// A clone starts its own retry history
+	delete(clonedSpec, "retryOf")
+	delete(clonedSpec, "retryAttempt")
 	if conflicted {
 		if dn, ok := clonedSpec["displayName"].(string); ok && strings.TrimSpace(dn) != "" {
 			clonedSpec["displayName"] = fmt.Sprintf("%s (Duplicate)", dn)
@@ -1534,12 +1891,26 @@ func cloneSession(c *gin.Context) {
 		}
 	}
 
+	if !enforceCopiedSessionPolicy(c, reqDyn, req.TargetProject, clonedSpec) {
+		return
+	}
+
 	obj := &unstructured.Unstructured{Object: clonedSession}
+	if tp := traceparentFromContext(c); tp != "" {
+		obj.SetAnnotations(map[string]string{traceparentAnnotation: tp})
+	}
 
 	created, err := reqDyn.Resource(gvr).Namespace(req.TargetProject).Create(context.TODO(), obj, v1.CreateOptions{})
 	if err != nil {
-		log.Printf("Failed to create cloned agentic session in project %s: %v", req.TargetProject, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cloned agentic session"})
+		logErrorf(c, "Failed to create cloned agentic session in project %s: %v", req.TargetProject, err)
+		switch {
+		case errors.IsInvalid(err):
+			respondError(c, http.StatusUnprocessableEntity, err.Error())
+		case errors.IsForbidden(err):
+			respondError(c, http.StatusForbidden, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, "Failed to create cloned agentic session")
+		}
 		return
 	}
 
@@ -1558,9 +1929,12 @@ func cloneSession(c *gin.Context) {
 		session.Status = parseStatus(status)
 	}
 
+	setWarningHeaders(c, sessionAdmissionWarnings(c.Request.Context(), reqDyn, req.TargetProject, session.Spec.Framework, session.Spec.FrameworkVersion))
 	c.JSON(http.StatusCreated, session)
 }
 
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/start
+// startSession marks the session Creating with a fresh start time.
 func startSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -1572,11 +1946,11 @@ func startSession(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
 		return
 	}
 
@@ -1593,8 +1967,8 @@ func startSession(c *gin.Context) {
 	// Update the resource
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
 	if err != nil {
-		log.Printf("Failed to start agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start agentic session"})
+		logErrorf(c, "Failed to start agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to start agentic session")
 		return
 	}
 
@@ -1616,6 +1990,8 @@ func startSession(c *gin.Context) {
 	c.JSON(http.StatusAccepted, session)
 }
 
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/stop
+// stopSession deletes the runner Job and marks the session Stopped.
 func stopSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -1626,11 +2002,11 @@ func stopSession(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
 		return
 	}
 
@@ -1643,11 +2019,11 @@ func stopSession(c *gin.Context) {
 
 	currentPhase, _ := status["phase"].(string)
 	if currentPhase == "Completed" || currentPhase == "Failed" || currentPhase == "Stopped" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot stop session in %s state", currentPhase)})
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("Cannot stop session in %s state", currentPhase))
 		return
 	}
 
-	log.Printf("Attempting to stop agentic session %s in project %s (current phase: %s)", sessionName, project, currentPhase)
+	logInfof(c, "Attempting to stop agentic session %s in project %s (current phase: %s)", sessionName, project, currentPhase)
 
 	// Get job name from status
 	jobName, jobExists := status["jobName"].(string)
@@ -1655,15 +2031,15 @@ func stopSession(c *gin.Context) {
 		// Delete the job
 		err := reqK8s.BatchV1().Jobs(project).Delete(context.TODO(), jobName, v1.DeleteOptions{})
 		if err != nil && !errors.IsNotFound(err) {
-			log.Printf("Failed to delete job %s: %v", jobName, err)
+			logErrorf(c, "Failed to delete job %s: %v", jobName, err)
 			// Don't fail the request if job deletion fails - continue with status update
-			log.Printf("Continuing with status update despite job deletion failure")
+			logWarnf(c, "Continuing with status update despite job deletion failure")
 		} else {
-			log.Printf("Deleted job %s for agentic session %s", jobName, sessionName)
+			logInfof(c, "Deleted job %s for agentic session %s", jobName, sessionName)
 		}
 	} else {
 		// Handle case where job was never created or jobName is missing
-		log.Printf("No job found to delete for agentic session %s", sessionName)
+		logInfof(c, "No job found to delete for agentic session %s", sessionName)
 	}
 
 	// Update status to Stopped
@@ -1676,12 +2052,12 @@ func stopSession(c *gin.Context) {
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Session was deleted while we were trying to update it
-			log.Printf("Agentic session %s was deleted during stop operation", sessionName)
+			logInfof(c, "Agentic session %s was deleted during stop operation", sessionName)
 			c.JSON(http.StatusOK, gin.H{"message": "Session no longer exists (already deleted)"})
 			return
 		}
-		log.Printf("Failed to update agentic session status %s: %v", sessionName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agentic session status"})
+		logErrorf(c, "Failed to update agentic session status %s: %v", sessionName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to update agentic session status")
 		return
 	}
 
@@ -1700,7 +2076,7 @@ func stopSession(c *gin.Context) {
 		session.Status = parseStatus(status)
 	}
 
-	log.Printf("Successfully stopped agentic session %s", sessionName)
+	logInfof(c, "Successfully stopped agentic session %s", sessionName)
 	c.JSON(http.StatusAccepted, session)
 }
 
@@ -1713,7 +2089,7 @@ func updateSessionStatus(c *gin.Context) {
 
 	var statusUpdate map[string]interface{}
 	if err := c.ShouldBindJSON(&statusUpdate); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -1723,11 +2099,11 @@ func updateSessionStatus(c *gin.Context) {
 	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		log.Printf("Failed to get agentic session %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get agentic session"})
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
 		return
 	}
 
@@ -1737,17 +2113,48 @@ func updateSessionStatus(c *gin.Context) {
 	}
 	status := item.Object["status"].(map[string]interface{})
 
+	// Finished sessions stay finished unless the operator restarts them
+	if to, ok := statusUpdate["phase"].(string); ok && !isOperatorServiceAccount(c) {
+		from, _ := status["phase"].(string)
+		if v := checkSessionPhaseTransition(from, to); v != nil {
+			rejectSession(c, v)
+			return
+		}
+	}
+
 	// Accept standard fields and result summary fields from runner
 	allowed := map[string]struct{}{
 		"phase": {}, "completionTime": {}, "cost": {}, "message": {},
 		"subtype": {}, "duration_ms": {}, "duration_api_ms": {}, "is_error": {},
 		"num_turns": {}, "session_id": {}, "total_cost_usd": {}, "usage": {}, "result": {},
+		"report": {}, "firstOutputTime": {},
 	}
 	for k := range statusUpdate {
 		if _, ok := allowed[k]; !ok {
 			delete(statusUpdate, k)
 		}
 	}
+	// The first output is recorded once; later reports are ignored
+	if _, ok := status["firstOutputTime"]; ok {
+		delete(statusUpdate, "firstOutputTime")
+	} else if raw, ok := statusUpdate["firstOutputTime"].(string); ok {
+		if _, err := time.Parse(time.RFC3339, raw); err != nil {
+			delete(statusUpdate, "firstOutputTime")
+		}
+	}
+
+	// Usage is reported in parts (resource usage on failure, token usage with
+	// the result), so it merges into what the runner reported before
+	if usage, ok := statusUpdate["usage"].(map[string]interface{}); ok {
+		if prev, ok := status["usage"].(map[string]interface{}); ok {
+			for k, v := range usage {
+				prev[k] = v
+			}
+			statusUpdate["usage"] = prev
+		}
+	}
+
+	appendSessionHistory(status, statusUpdate, time.Now())
 
 	// Merge remaining fields into status
 	for k, v := range statusUpdate {
@@ -1756,8 +2163,8 @@ func updateSessionStatus(c *gin.Context) {
 
 	// Update only the status subresource (requires agenticsessions/status perms)
 	if _, err := reqDyn.Resource(gvr).Namespace(project).UpdateStatus(context.TODO(), item, v1.UpdateOptions{}); err != nil {
-		log.Printf("Failed to update agentic session status %s in project %s: %v", sessionName, project, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agentic session status"})
+		logErrorf(c, "Failed to update agentic session status %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to update agentic session status")
 		return
 	}
 
@@ -1768,7 +2175,7 @@ func updateSessionStatus(c *gin.Context) {
 func proxyContentWrites(c *gin.Context, project, sessionName string, statusUpdate map[string]interface{}) error {
 	token := c.GetHeader("Authorization")
 	if strings.TrimSpace(token) == "" {
-		log.Printf("content proxy: skip write (no Authorization token) project=%s session=%s", project, sessionName)
+		logWarnf(c, "content proxy: skip write (no Authorization token) project=%s session=%s", project, sessionName)
 		return nil
 	}
 	base := os.Getenv("CONTENT_SERVICE_BASE")
@@ -1776,7 +2183,7 @@ func proxyContentWrites(c *gin.Context, project, sessionName string, statusUpdat
 		base = "http://ambient-content.%s.svc:8080"
 	}
 	endpoint := fmt.Sprintf(base, project)
-	log.Printf("content proxy: preparing writes project=%s session=%s endpoint=%s tokenLen=%d", project, sessionName, endpoint, len(token))
+	logDebugf(c, "content proxy: preparing writes project=%s session=%s endpoint=%s tokenLen=%d", project, sessionName, endpoint, len(token))
 
 	type writeReq struct {
 		Path     string `json:"path"`
@@ -1798,25 +2205,25 @@ func proxyContentWrites(c *gin.Context, project, sessionName string, statusUpdat
 		writes = append(writes, writeReq{Path: fmt.Sprintf("/sessions/%s/status.json", sessionName), Content: string(b), Encoding: "utf8"})
 	}
 
-	log.Printf("content proxy: total writes=%d project=%s session=%s", len(writes), project, sessionName)
+	logDebugf(c, "content proxy: total writes=%d project=%s session=%s", len(writes), project, sessionName)
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	for _, w := range writes {
 		b, _ := json.Marshal(w)
-		log.Printf("content proxy: POST /content/write path=%s encoding=%s contentLen=%d", w.Path, w.Encoding, len(w.Content))
+		logDebugf(c, "content proxy: POST /content/write path=%s encoding=%s contentLen=%d", w.Path, w.Encoding, len(w.Content))
 		req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint+"/content/write", strings.NewReader(string(b)))
 		req.Header.Set("Authorization", token)
 		req.Header.Set("Content-Type", "application/json")
 		if resp, err := client.Do(req); err != nil {
-			log.Printf("content proxy: write failed path=%s err=%v", w.Path, err)
+			logErrorf(c, "content proxy: write failed path=%s err=%v", w.Path, err)
 			continue
 		} else {
 			code := resp.StatusCode
 			_ = resp.Body.Close()
 			if code >= 200 && code < 300 {
-				log.Printf("content proxy: write ok path=%s status=%d", w.Path, code)
+				logDebugf(c, "content proxy: write ok path=%s status=%d", w.Path, code)
 			} else {
-				log.Printf("content proxy: write non-2xx path=%s status=%d", w.Path, code)
+				logWarnf(c, "content proxy: write non-2xx path=%s status=%d", w.Path, code)
 			}
 		}
 	}
@@ -1845,6 +2252,10 @@ func writeProjectContentFile(c *gin.Context, project string, absPath string, dat
 		Encoding string `json:"encoding"`
 	}
 	reqBody := writeReq{Path: absPath, Content: string(data), Encoding: "utf8"}
+	if !utf8.Valid(data) {
+		// Binary content does not survive a JSON string round trip
+		reqBody = writeReq{Path: absPath, Content: base64.StdEncoding.EncodeToString(data), Encoding: "base64"}
+	}
 	b, _ := json.Marshal(reqBody)
 	httpReq, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, endpoint+"/content/write", strings.NewReader(string(b)))
 	if strings.TrimSpace(token) != "" {
@@ -1949,34 +2360,42 @@ func contentWrite(c *gin.Context) {
 		Encoding string `json:"encoding"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	path := filepath.Clean("/" + strings.TrimSpace(req.Path))
 	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+		respondError(c, http.StatusBadRequest, "invalid path")
 		return
 	}
 	abs := filepath.Join(stateBaseDir, path)
 	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create directory"})
+		respondError(c, http.StatusInternalServerError, "failed to create directory")
 		return
 	}
 	var data []byte
 	if strings.EqualFold(req.Encoding, "base64") {
 		b, err := base64.StdEncoding.DecodeString(req.Content)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid base64 content"})
+			respondError(c, http.StatusBadRequest, "invalid base64 content")
 			return
 		}
 		data = b
 	} else {
 		data = []byte(req.Content)
 	}
-	if err := ioutil.WriteFile(abs, data, 0644); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write file"})
+	// Replace rather than truncate: the path may be a hard link to a shared artifact blob
+	shared := false
+	if info, err := os.Stat(abs); err == nil && linkCount(info) > 1 {
+		shared = true
+	}
+	if err := writeFileAtomic(abs, data, 0644); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to write file")
 		return
 	}
+	if shared {
+		collectOrphanBlobs()
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "ok"})
 }
 
@@ -1984,20 +2403,28 @@ func contentWrite(c *gin.Context) {
 func contentRead(c *gin.Context) {
 	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
 	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+		respondError(c, http.StatusBadRequest, "invalid path")
 		return
 	}
 	abs := filepath.Join(stateBaseDir, path)
-	b, err := ioutil.ReadFile(abs)
+	f, err := os.Open(abs)
 	if err != nil {
 		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			respondError(c, http.StatusNotFound, "not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "read failed"})
+			respondError(c, http.StatusInternalServerError, "read failed")
 		}
 		return
 	}
-	c.Data(http.StatusOK, "application/octet-stream", b)
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		respondError(c, http.StatusBadRequest, "not a file")
+		return
+	}
+	// ServeContent streams the file and honors Range / If-Modified-Since
+	c.Header("Content-Type", "application/octet-stream")
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
 }
 
 // contentList handles GET /content/list?path=
@@ -2005,16 +2432,16 @@ func contentRead(c *gin.Context) {
 func contentList(c *gin.Context) {
 	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
 	if path == "/" || strings.Contains(path, "..") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid path"})
+		respondError(c, http.StatusBadRequest, "invalid path")
 		return
 	}
 	abs := filepath.Join(stateBaseDir, path)
 	info, err := os.Stat(abs)
 	if err != nil {
 		if os.IsNotExist(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			respondError(c, http.StatusNotFound, "not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "stat failed"})
+			respondError(c, http.StatusInternalServerError, "stat failed")
 		}
 		return
 	}
@@ -2031,7 +2458,7 @@ func contentList(c *gin.Context) {
 	}
 	entries, err := ioutil.ReadDir(abs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "readdir failed"})
+		respondError(c, http.StatusInternalServerError, "readdir failed")
 		return
 	}
 	items := make([]gin.H, 0, len(entries))
@@ -2047,76 +2474,96 @@ func contentList(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// contentDelete handles POST /content/delete when running in CONTENT_SERVICE_MODE
+// Body: { path: "/sessions/<name>" } removes the file or directory tree. Missing paths are not an error.
+// Artifact blobs no longer linked from anywhere are removed with it.
+func contentDelete(c *gin.Context) {
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	path := filepath.Clean("/" + strings.TrimSpace(req.Path))
+	if path == "/" || strings.Contains(path, "..") || strings.Count(path, "/") < 2 {
+		respondError(c, http.StatusBadRequest, "invalid path")
+		return
+	}
+	abs := filepath.Join(stateBaseDir, path)
+	if path == artifactBlobsRoot || strings.HasPrefix(path, artifactBlobsRoot+"/") {
+		respondError(c, http.StatusBadRequest, "artifact blobs are removed when no longer referenced")
+		return
+	}
+	shared := sharesBlobs(abs)
+	if err := os.RemoveAll(abs); err != nil {
+		respondError(c, http.StatusInternalServerError, "delete failed")
+		return
+	}
+	if shared {
+		if freed := collectOrphanBlobs(); freed > 0 {
+			logInfof(c, "content: released %d bytes of unreferenced artifact blobs", freed)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
+// GET /api/projects?include=stats
 // Project management handlers
+// listProjects returns the Ambient projects the caller has at least view
+// access to, each with the caller's role and permissions there as resolved
+// from RBAC. With include=stats projects the caller may list sessions in
+// carry their session counts and budget use.
 func listProjects(c *gin.Context) {
-	_, reqDyn := getK8sClientsForRequest(c)
-
-	// List OpenShift Projects the user can see; filter to Ambient-managed
-	projGvr := getOpenShiftProjectResource()
-	list, err := reqDyn.Resource(projGvr).List(context.TODO(), v1.ListOptions{})
-	if err != nil {
-		log.Printf("Failed to list OpenShift Projects: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list projects"})
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqK8s == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
 		return
 	}
+	withStats := slices.Contains(strings.Split(c.Query("include"), ","), "stats")
+	credential := callerCredential(c)
 
-	toStringMap := func(in map[string]interface{}) map[string]string {
-		if in == nil {
-			return map[string]string{}
-		}
-		out := make(map[string]string, len(in))
-		for k, v := range in {
-			if s, ok := v.(string); ok {
-				out[k] = s
-			}
-		}
-		return out
+	namespaces, err := managedNamespaces(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to list project namespaces: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to list projects")
+		return
 	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
 
-	var projects []AmbientProject
-	for _, item := range list.Items {
-		meta, _ := item.Object["metadata"].(map[string]interface{})
-		name := item.GetName()
-		if name == "" && meta != nil {
-			if n, ok := meta["name"].(string); ok {
-				name = n
-			}
-		}
-		labels := map[string]string{}
-		annotations := map[string]string{}
-		if meta != nil {
-			if raw, ok := meta["labels"].(map[string]interface{}); ok {
-				labels = toStringMap(raw)
-			}
-			if raw, ok := meta["annotations"].(map[string]interface{}); ok {
-				annotations = toStringMap(raw)
-			}
-		}
-
-		// Filter to Ambient-managed projects when label is present
-		if v, ok := labels["ambient-code.io/managed"]; !ok || v != "true" {
+	projects := []AmbientProject{}
+	for _, ns := range namespaces {
+		access, err := projectAccessForCaller(c.Request.Context(), reqK8s, credential, ns.Name)
+		if err != nil {
+			logErrorf(c, "Failed to resolve access to project %s: %v", ns.Name, err)
 			continue
 		}
-
-		displayName := annotations["openshift.io/display-name"]
-		description := annotations["openshift.io/description"]
-		created := item.GetCreationTimestamp().Time
-
-		status := ""
-		if st, ok := item.Object["status"].(map[string]interface{}); ok {
-			if phase, ok := st["phase"].(string); ok {
-				status = phase
-			}
+		if access.Role == "" {
+			continue
 		}
-
 		project := AmbientProject{
-			Name:              name,
-			DisplayName:       displayName,
-			Description:       description,
-			Labels:            labels,
-			Annotations:       annotations,
-			CreationTimestamp: created.Format(time.RFC3339),
-			Status:            status,
+			Name:              ns.Name,
+			DisplayName:       ns.Annotations["openshift.io/display-name"],
+			Description:       ns.Annotations["openshift.io/description"],
+			Labels:            ns.Labels,
+			Annotations:       ns.Annotations,
+			CreationTimestamp: ns.CreationTimestamp.Format(time.RFC3339),
+			Status:            string(ns.Status.Phase),
+			Role:              access.Role,
+			Permissions:       access.Permissions,
+		}
+		if project.Labels == nil {
+			project.Labels = map[string]string{}
+		}
+		if project.Annotations == nil {
+			project.Annotations = map[string]string{}
+		}
+		if withStats && access.has("sessions:list") {
+			if stats, err := projectStats(c.Request.Context(), reqDyn, ns.Name); err == nil {
+				project.Stats = &stats
+			} else {
+				logErrorf(c, "Failed to compute stats for %s: %v", ns.Name, err)
+			}
 		}
 		projects = append(projects, project)
 	}
@@ -2124,11 +2571,13 @@ func listProjects(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": projects})
 }
 
+// POST /api/projects
+// createProject creates a namespace labeled as an Ambient project.
 func createProject(c *gin.Context) {
 	reqK8s, _ := getK8sClientsForRequest(c)
 	var req CreateProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -2162,8 +2611,8 @@ func createProject(c *gin.Context) {
 
 	created, err := reqK8s.CoreV1().Namespaces().Create(context.TODO(), ns, v1.CreateOptions{})
 	if err != nil {
-		log.Printf("Failed to create project %s: %v", req.Name, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		logErrorf(c, "Failed to create project %s: %v", req.Name, err)
+		respondError(c, http.StatusInternalServerError, "Failed to create project")
 		return
 	}
 
@@ -2183,6 +2632,7 @@ func createProject(c *gin.Context) {
 	c.JSON(http.StatusCreated, project)
 }
 
+// GET /api/projects/:projectName
 func getProject(c *gin.Context) {
 	projectName := c.Param("projectName")
 	_, reqDyn := getK8sClientsForRequest(c)
@@ -2192,15 +2642,15 @@ func getProject(c *gin.Context) {
 	projObj, err := reqDyn.Resource(projGvr).Get(context.TODO(), projectName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			respondError(c, http.StatusNotFound, "Project not found")
 			return
 		}
 		if errors.IsUnauthorized(err) || errors.IsForbidden(err) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to access project"})
+			respondError(c, http.StatusForbidden, "Unauthorized to access project")
 			return
 		}
-		log.Printf("Failed to get OpenShift Project %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project"})
+		logErrorf(c, "Failed to get OpenShift Project %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get project")
 		return
 	}
 
@@ -2224,7 +2674,7 @@ func getProject(c *gin.Context) {
 		}
 	}
 	if labels["ambient-code.io/managed"] != "true" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found or not an Ambient project"})
+		respondError(c, http.StatusNotFound, "Project not found or not an Ambient project")
 		return
 	}
 
@@ -2251,23 +2701,25 @@ func getProject(c *gin.Context) {
 	c.JSON(http.StatusOK, project)
 }
 
+// DELETE /api/projects/:projectName
 func deleteProject(c *gin.Context) {
 	projectName := c.Param("projectName")
 	reqK8s, _ := getK8sClientsForRequest(c)
 	err := reqK8s.CoreV1().Namespaces().Delete(context.TODO(), projectName, v1.DeleteOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			respondError(c, http.StatusNotFound, "Project not found")
 			return
 		}
-		log.Printf("Failed to delete project %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		logErrorf(c, "Failed to delete project %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete project")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// PUT /api/projects/:projectName
 // Update basic project metadata (annotations)
 func updateProject(c *gin.Context) {
 	projectName := c.Param("projectName")
@@ -2280,12 +2732,12 @@ func updateProject(c *gin.Context) {
 		Annotations map[string]string `json:"annotations"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	if req.Name != "" && req.Name != projectName {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project name in URL does not match request body"})
+		respondError(c, http.StatusBadRequest, "project name in URL does not match request body")
 		return
 	}
 
@@ -2294,11 +2746,11 @@ func updateProject(c *gin.Context) {
 	projObj, err := reqDyn.Resource(projGvr).Get(context.TODO(), projectName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			respondError(c, http.StatusNotFound, "Project not found")
 			return
 		}
-		log.Printf("Failed to get OpenShift Project %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get OpenShift Project"})
+		logErrorf(c, "Failed to get OpenShift Project %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get OpenShift Project")
 		return
 	}
 	isAmbient := false
@@ -2310,7 +2762,7 @@ func updateProject(c *gin.Context) {
 		}
 	}
 	if !isAmbient {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found or not an Ambient project"})
+		respondError(c, http.StatusNotFound, "Project not found or not an Ambient project")
 		return
 	}
 
@@ -2338,8 +2790,8 @@ func updateProject(c *gin.Context) {
 	// Persist Project changes
 	_, updateErr := reqDyn.Resource(projGvr).Update(context.TODO(), projObj, v1.UpdateOptions{})
 	if updateErr != nil {
-		log.Printf("Failed to update OpenShift Project %s: %v", projectName, updateErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		logErrorf(c, "Failed to update OpenShift Project %s: %v", projectName, updateErr)
+		respondError(c, http.StatusInternalServerError, "Failed to update project")
 		return
 	}
 
@@ -2452,8 +2904,8 @@ func listProjectPermissions(c *gin.Context) {
 	// Prefer new label, but also include legacy group-access for backward-compat listing
 	rbsAll, err := reqK8s.RbacV1().RoleBindings(projectName).List(context.TODO(), v1.ListOptions{})
 	if err != nil {
-		log.Printf("Failed to list RoleBindings in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list permissions"})
+		logErrorf(c, "Failed to list RoleBindings in %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to list permissions")
 		return
 	}
 
@@ -2524,13 +2976,13 @@ func addProjectPermission(c *gin.Context) {
 		Role        string `json:"role" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	st := strings.ToLower(strings.TrimSpace(req.SubjectType))
 	if st != "group" && st != "user" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectType must be one of: group, user"})
+		respondError(c, http.StatusBadRequest, "subjectType must be one of: group, user")
 		return
 	}
 	subjectKind := "Group"
@@ -2547,7 +2999,7 @@ func addProjectPermission(c *gin.Context) {
 	case "view":
 		roleRefName = ambientRoleView
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of: admin, edit, view"})
+		respondError(c, http.StatusBadRequest, "role must be one of: admin, edit, view")
 		return
 	}
 
@@ -2571,11 +3023,11 @@ func addProjectPermission(c *gin.Context) {
 
 	if _, err := reqK8s.RbacV1().RoleBindings(projectName).Create(context.TODO(), rb, v1.CreateOptions{}); err != nil {
 		if errors.IsAlreadyExists(err) {
-			c.JSON(http.StatusConflict, gin.H{"error": "permission already exists for this subject and role"})
+			respondError(c, http.StatusConflict, "permission already exists for this subject and role")
 			return
 		}
-		log.Printf("Failed to create RoleBinding in %s for %s %s: %v", projectName, st, req.SubjectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant permission"})
+		logErrorf(c, "Failed to create RoleBinding in %s for %s %s: %v", projectName, st, req.SubjectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to grant permission")
 		return
 	}
 
@@ -2590,18 +3042,18 @@ func removeProjectPermission(c *gin.Context) {
 	reqK8s, _ := getK8sClientsForRequest(c)
 
 	if subjectType != "group" && subjectType != "user" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectType must be one of: group, user"})
+		respondError(c, http.StatusBadRequest, "subjectType must be one of: group, user")
 		return
 	}
 	if strings.TrimSpace(subjectName) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "subjectName is required"})
+		respondError(c, http.StatusBadRequest, "subjectName is required")
 		return
 	}
 
 	rbs, err := reqK8s.RbacV1().RoleBindings(projectName).List(context.TODO(), v1.ListOptions{LabelSelector: "app=ambient-permission"})
 	if err != nil {
-		log.Printf("Failed to list RoleBindings in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove permission"})
+		logErrorf(c, "Failed to list RoleBindings in %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to remove permission")
 		return
 	}
 
@@ -2621,6 +3073,7 @@ func removeProjectPermission(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// GET /api/projects/:projectName/keys
 // Webhook handlers - placeholder implementations
 // Access key management: list/create/delete keys stored as Secrets with hashed value
 func listProjectKeys(c *gin.Context) {
@@ -2630,8 +3083,8 @@ func listProjectKeys(c *gin.Context) {
 	// List ServiceAccounts with label app=ambient-access-key
 	sas, err := reqK8s.CoreV1().ServiceAccounts(projectName).List(context.TODO(), v1.ListOptions{LabelSelector: "app=ambient-access-key"})
 	if err != nil {
-		log.Printf("Failed to list access keys in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list access keys"})
+		logErrorf(c, "Failed to list access keys in %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to list access keys")
 		return
 	}
 
@@ -2681,6 +3134,8 @@ func listProjectKeys(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
+// POST /api/projects/:projectName/keys
+// createProjectKey creates an access key; the token is only returned in this response.
 func createProjectKey(c *gin.Context) {
 	projectName := c.Param("projectName")
 	reqK8s, _ := getK8sClientsForRequest(c)
@@ -2691,7 +3146,7 @@ func createProjectKey(c *gin.Context) {
 		Role        string `json:"role"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -2709,7 +3164,7 @@ func createProjectKey(c *gin.Context) {
 	case "view":
 		roleRefName = ambientRoleView
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of: admin, edit, view"})
+		respondError(c, http.StatusBadRequest, "role must be one of: admin, edit, view")
 		return
 	}
 
@@ -2730,8 +3185,8 @@ func createProjectKey(c *gin.Context) {
 		},
 	}
 	if _, err := reqK8s.CoreV1().ServiceAccounts(projectName).Create(context.TODO(), sa, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
-		log.Printf("Failed to create ServiceAccount %s in %s: %v", saName, projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		logErrorf(c, "Failed to create ServiceAccount %s in %s: %v", saName, projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to create service account")
 		return
 	}
 
@@ -2752,8 +3207,8 @@ func createProjectKey(c *gin.Context) {
 		Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Name: saName, Namespace: projectName}},
 	}
 	if _, err := reqK8s.RbacV1().RoleBindings(projectName).Create(context.TODO(), rb, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
-		log.Printf("Failed to create RoleBinding %s in %s: %v", rbName, projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bind service account"})
+		logErrorf(c, "Failed to create RoleBinding %s in %s: %v", rbName, projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to bind service account")
 		return
 	}
 
@@ -2761,8 +3216,8 @@ func createProjectKey(c *gin.Context) {
 	tr := &authnv1.TokenRequest{Spec: authnv1.TokenRequestSpec{}}
 	tok, err := reqK8s.CoreV1().ServiceAccounts(projectName).CreateToken(context.TODO(), saName, tr, v1.CreateOptions{})
 	if err != nil {
-		log.Printf("Failed to create token for SA %s/%s: %v", projectName, saName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		logErrorf(c, "Failed to create token for SA %s/%s: %v", projectName, saName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to generate access token")
 		return
 	}
 
@@ -2776,6 +3231,7 @@ func createProjectKey(c *gin.Context) {
 	})
 }
 
+// DELETE /api/projects/:projectName/keys/:keyId
 func deleteProjectKey(c *gin.Context) {
 	projectName := c.Param("projectName")
 	keyID := c.Param("keyId")
@@ -2792,8 +3248,8 @@ func deleteProjectKey(c *gin.Context) {
 	// Delete the ServiceAccount itself
 	if err := reqK8s.CoreV1().ServiceAccounts(projectName).Delete(context.TODO(), keyID, v1.DeleteOptions{}); err != nil {
 		if !errors.IsNotFound(err) {
-			log.Printf("Failed to delete service account %s in %s: %v", keyID, projectName, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete access key"})
+			logErrorf(c, "Failed to delete service account %s in %s: %v", keyID, projectName, err)
+			respondError(c, http.StatusInternalServerError, "Failed to delete access key")
 			return
 		}
 	}
@@ -2810,6 +3266,10 @@ func getMetrics(c *gin.Context) {
 # TYPE agenticsession_total counter
 agenticsession_total 0
 `
+	metrics += fmt.Sprintf("# HELP audit_events_dropped_total Audit events that could not be delivered to a sink\n# TYPE audit_events_dropped_total counter\naudit_events_dropped_total %d\n", auditDropped.Load())
+	metrics += fmt.Sprintf("# HELP webhook_deliveries_deduplicated_total Replayed webhook deliveries answered with an existing session\n# TYPE webhook_deliveries_deduplicated_total counter\nwebhook_deliveries_deduplicated_total %d\n", webhookDeliveriesDeduplicated.Load())
+	metrics += webhookQueueMetrics()
+	metrics += sessionSLOMetrics()
 	c.String(http.StatusOK, metrics)
 }
 
@@ -2872,6 +3332,7 @@ func rfeFromUnstructured(item *unstructured.Unstructured) *RFEWorkflow {
 	return wf
 }
 
+// GET /api/projects/:projectName/rfe-workflows
 func listProjectRFEWorkflows(c *gin.Context) {
 	project := c.Param("projectName")
 	var workflows []RFEWorkflow
@@ -2923,13 +3384,14 @@ func listProjectRFEWorkflows(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"workflows": summaries})
 }
 
+// POST /api/projects/:projectName/rfe-workflows
 func createProjectRFEWorkflow(c *gin.Context) {
 	project := c.Param("projectName")
 	var req CreateRFEWorkflowRequest
 	bodyBytes, _ := c.GetRawData()
 	c.Request.Body = ioutil.NopCloser(strings.NewReader(string(bodyBytes)))
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+		respondError(c, http.StatusBadRequest, "Validation failed: " + err.Error())
 		return
 	}
 	now := time.Now().UTC().Format(time.RFC3339)
@@ -2946,7 +3408,7 @@ func createProjectRFEWorkflow(c *gin.Context) {
 	}
 	_, reqDyn := getK8sClientsForRequest(c)
 	if err := upsertProjectRFEWorkflowCR(reqDyn, workflow); err != nil {
-		log.Printf("⚠️ Failed to upsert RFEWorkflow CR: %v", err)
+		logErrorf(c, "⚠️ Failed to upsert RFEWorkflow CR: %v", err)
 	}
 
 	// Initialize workspace structure and optionally seed repositories
@@ -2954,7 +3416,7 @@ func createProjectRFEWorkflow(c *gin.Context) {
 
 	// Initialize Spec Kit template into workspace (version via SPEC_KIT_VERSION)
 	if err := initSpecKitInWorkspace(c, project, workspaceRoot); err != nil {
-		log.Printf("spec-kit init failed for %s/%s: %v", project, workflowID, err)
+		logErrorf(c, "spec-kit init failed for %s/%s: %v", project, workflowID, err)
 	}
 
 	// Clone repositories into workspace (full repo contents); preserve dot-prefixed paths
@@ -2974,7 +3436,7 @@ func createProjectRFEWorkflow(c *gin.Context) {
 		// Perform shallow clone to a temp dir on backend container filesystem
 		tmpDir, terr := os.MkdirTemp("", "clone-*")
 		if terr != nil {
-			log.Printf("repo clone: temp dir failed for %s: %v", r.URL, terr)
+			logErrorf(c, "repo clone: temp dir failed for %s: %v", r.URL, terr)
 			continue
 		}
 		defer os.RemoveAll(tmpDir)
@@ -2988,7 +3450,7 @@ func createProjectRFEWorkflow(c *gin.Context) {
 		cmd := exec.Command("git", args...)
 		cmd.Env = os.Environ()
 		if out, cerr := cmd.CombinedOutput(); cerr != nil {
-			log.Printf("repo clone failed: %s: %v output=%s", r.URL, cerr, string(out))
+			logErrorf(c, "repo clone failed: %s: %v output=%s", r.URL, cerr, string(out))
 			continue
 		}
 
@@ -3090,24 +3552,24 @@ func initSpecKitInWorkspace(c *gin.Context, project, workspaceRoot string) error
 	// Extract files
 	total := len(zr.File)
 	var filesWritten, skippedDirs, openErrors, readErrors, writeErrors int
-	log.Printf("initSpecKitInWorkspace: extracting spec-kit template: %d entries", total)
+	logDebugf(c, "initSpecKitInWorkspace: extracting spec-kit template: %d entries", total)
 	for _, f := range zr.File {
 		if f.FileInfo().IsDir() {
 			skippedDirs++
-			log.Printf("spec-kit: skipping directory: %s", f.Name)
+			logWarnf(c, "spec-kit: skipping directory: %s", f.Name)
 			continue
 		}
 		rc, err := f.Open()
 		if err != nil {
 			openErrors++
-			log.Printf("spec-kit: open failed: %s: %v", f.Name, err)
+			logErrorf(c, "spec-kit: open failed: %s: %v", f.Name, err)
 			continue
 		}
 		b, err := io.ReadAll(rc)
 		rc.Close()
 		if err != nil {
 			readErrors++
-			log.Printf("spec-kit: read failed: %s: %v", f.Name, err)
+			logErrorf(c, "spec-kit: read failed: %s: %v", f.Name, err)
 			continue
 		}
 		// Normalize path: keep leading dots intact; only trim explicit "./" prefix
@@ -3120,21 +3582,22 @@ func initSpecKitInWorkspace(c *gin.Context, project, workspaceRoot string) error
 			rel = strings.ReplaceAll(rel, "../", "")
 		}
 		if rel != origRel {
-			log.Printf("spec-kit: normalized path %q -> %q", origRel, rel)
+			logDebugf(c, "spec-kit: normalized path %q -> %q", origRel, rel)
 		}
 		target := filepath.Join(workspaceRoot, rel)
 		if err := writeProjectContentFile(c, project, target, b); err != nil {
 			writeErrors++
-			log.Printf("write spec-kit file failed: %s: %v", target, err)
+			logErrorf(c, "write spec-kit file failed: %s: %v", target, err)
 		} else {
 			filesWritten++
-			log.Printf("spec-kit: wrote %s (%d bytes)", target, len(b))
+			logDebugf(c, "spec-kit: wrote %s (%d bytes)", target, len(b))
 		}
 	}
-	log.Printf("initSpecKitInWorkspace: extraction summary: written=%d, skipped_dirs=%d, open_errors=%d, read_errors=%d, write_errors=%d", filesWritten, skippedDirs, openErrors, readErrors, writeErrors)
+	logErrorf(c, "initSpecKitInWorkspace: extraction summary: written=%d, skipped_dirs=%d, open_errors=%d, read_errors=%d, write_errors=%d", filesWritten, skippedDirs, openErrors, readErrors, writeErrors)
 	return nil
 }
 
+// GET /api/projects/:projectName/rfe-workflows/:id
 func getProjectRFEWorkflow(c *gin.Context) {
 	project := c.Param("projectName")
 	id := c.Param("id")
@@ -3152,7 +3615,7 @@ func getProjectRFEWorkflow(c *gin.Context) {
 		}
 	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		respondError(c, http.StatusNotFound, "Workflow not found")
 		return
 	}
 	// Return slim object without artifacts/agentSessions/phaseResults/status/currentPhase
@@ -3314,6 +3777,7 @@ func getProjectRFEWorkflowSummary(c *gin.Context) {
 	})
 }
 
+// DELETE /api/projects/:projectName/rfe-workflows/:id
 func deleteProjectRFEWorkflow(c *gin.Context) {
 	id := c.Param("id")
 	// Delete CR
@@ -3348,7 +3812,7 @@ func publishWorkflowFileToJira(c *gin.Context) {
 		Path string `json:"path" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Path) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		respondError(c, http.StatusBadRequest, "path is required")
 		return
 	}
 
@@ -3357,7 +3821,7 @@ func publishWorkflowFileToJira(c *gin.Context) {
 	_, reqDyn := getK8sClientsForRequest(c)
 	reqK8s, _ := getK8sClientsForRequest(c)
 	if reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid user token"})
+		respondError(c, http.StatusUnauthorized, "Missing or invalid user token")
 		return
 	}
 
@@ -3379,7 +3843,7 @@ func publishWorkflowFileToJira(c *gin.Context) {
 
 	sec, err := reqK8s.CoreV1().Secrets(project).Get(c.Request.Context(), secretName, v1.GetOptions{})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read runner secret", "details": err.Error()})
+		respondError(c, http.StatusBadRequest, "Failed to read runner secret: "+err.Error())
 		return
 	}
 	get := func(k string) string {
@@ -3392,19 +3856,19 @@ func publishWorkflowFileToJira(c *gin.Context) {
 	jiraProject := strings.TrimSpace(get("JIRA_PROJECT"))
 	jiraToken := strings.TrimSpace(get("JIRA_API_TOKEN"))
 	if jiraURL == "" || jiraProject == "" || jiraToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing Jira configuration in runner secret (JIRA_URL, JIRA_PROJECT, JIRA_API_TOKEN required)"})
+		respondError(c, http.StatusBadRequest, "Missing Jira configuration in runner secret (JIRA_URL, JIRA_PROJECT, JIRA_API_TOKEN required)")
 		return
 	}
 
 	// Load workflow for title
 	gvrWf := getRFEWorkflowResource()
 	if reqDyn == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid user token"})
+		respondError(c, http.StatusUnauthorized, "Missing or invalid user token")
 		return
 	}
 	item, err := reqDyn.Resource(gvrWf).Namespace(project).Get(c.Request.Context(), id, v1.GetOptions{})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		respondError(c, http.StatusNotFound, "Workflow not found")
 		return
 	}
 	wf := rfeFromUnstructured(item)
@@ -3413,7 +3877,7 @@ func publishWorkflowFileToJira(c *gin.Context) {
 	absPath := resolveWorkflowWorkspaceAbsPath(id, req.Path)
 	b, ferr := readProjectContentFile(c, project, absPath)
 	if ferr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read workspace file"})
+		respondError(c, http.StatusBadGateway, "Failed to read workspace file")
 		return
 	}
 	content := string(b)
@@ -3471,7 +3935,7 @@ func publishWorkflowFileToJira(c *gin.Context) {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 	httpResp, httpErr := httpClient.Do(httpReq)
 	if httpErr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Jira request failed", "details": httpErr.Error()})
+		respondError(c, http.StatusBadGateway, "Jira request failed: "+httpErr.Error())
 		return
 	}
 	defer httpResp.Body.Close()
@@ -3487,7 +3951,7 @@ func publishWorkflowFileToJira(c *gin.Context) {
 		}
 		_ = json.Unmarshal(respBody, &created)
 		if strings.TrimSpace(created.Key) == "" {
-			c.JSON(http.StatusBadGateway, gin.H{"error": "Jira creation returned no key"})
+			respondError(c, http.StatusBadGateway, "Jira creation returned no key")
 			return
 		}
 		outKey = created.Key
@@ -3522,13 +3986,14 @@ func publishWorkflowFileToJira(c *gin.Context) {
 	}
 	spec["jiraLinks"] = links
 	if _, err := reqDyn.Resource(gvrWf).Namespace(project).Update(c.Request.Context(), obj, v1.UpdateOptions{}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow with Jira link", "details": err.Error()})
+		respondError(c, http.StatusInternalServerError, "Failed to update workflow with Jira link: "+err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"key": outKey, "url": fmt.Sprintf("%s/browse/%s", jiraBase, outKey)})
 }
 
+// GET /api/projects/:projectName/rfe-workflows/:id/sessions
 // List sessions linked to a project-scoped RFE workflow by label selector
 func listProjectRFEWorkflowSessions(c *gin.Context) {
 	project := c.Param("projectName")
@@ -3537,12 +4002,12 @@ func listProjectRFEWorkflowSessions(c *gin.Context) {
 	selector := fmt.Sprintf("rfe-workflow=%s,project=%s", id, project)
 	_, reqDyn := getK8sClientsForRequest(c)
 	if reqDyn == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid user token"})
+		respondError(c, http.StatusUnauthorized, "Missing or invalid user token")
 		return
 	}
 	list, err := reqDyn.Resource(gvr).Namespace(project).List(context.TODO(), v1.ListOptions{LabelSelector: selector})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions", "details": err.Error()})
+		respondError(c, http.StatusInternalServerError, "Failed to list sessions: "+err.Error())
 		return
 	}
 
@@ -3559,32 +4024,33 @@ type rfeLinkSessionRequest struct {
 	Phase        string `json:"phase"`
 }
 
+// POST /api/projects/:projectName/rfe-workflows/:id/sessions
 // Add/link an existing session to an RFE by applying labels
 func addProjectRFEWorkflowSession(c *gin.Context) {
 	project := c.Param("projectName")
 	id := c.Param("id")
 	var req rfeLinkSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		respondError(c, http.StatusBadRequest, "Invalid request: " + err.Error())
 		return
 	}
 	if req.ExistingName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "existingName is required for linking in this version"})
+		respondError(c, http.StatusBadRequest, "existingName is required for linking in this version")
 		return
 	}
 	gvr := getAgenticSessionV1Alpha1Resource()
 	_, reqDyn := getK8sClientsForRequest(c)
 	if reqDyn == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid user token"})
+		respondError(c, http.StatusUnauthorized, "Missing or invalid user token")
 		return
 	}
 	obj, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), req.ExistingName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch session", "details": err.Error()})
+		respondError(c, http.StatusInternalServerError, "Failed to fetch session: "+err.Error())
 		return
 	}
 	meta, _ := obj.Object["metadata"].(map[string]interface{})
@@ -3601,13 +4067,14 @@ func addProjectRFEWorkflowSession(c *gin.Context) {
 	// Update the resource
 	updated, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), obj, v1.UpdateOptions{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session labels", "details": err.Error()})
+		respondError(c, http.StatusInternalServerError, "Failed to update session labels: "+err.Error())
 		return
 	}
 	_ = updated
 	c.JSON(http.StatusOK, gin.H{"message": "Session linked to RFE", "session": req.ExistingName})
 }
 
+// DELETE /api/projects/:projectName/rfe-workflows/:id/sessions/:sessionName
 // Remove/unlink a session from an RFE by clearing linkage labels (non-destructive)
 func removeProjectRFEWorkflowSession(c *gin.Context) {
 	project := c.Param("projectName")
@@ -3617,16 +4084,16 @@ func removeProjectRFEWorkflowSession(c *gin.Context) {
 	gvr := getAgenticSessionV1Alpha1Resource()
 	_, reqDyn := getK8sClientsForRequest(c)
 	if reqDyn == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid user token"})
+		respondError(c, http.StatusUnauthorized, "Missing or invalid user token")
 		return
 	}
 	obj, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			respondError(c, http.StatusNotFound, "Session not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch session", "details": err.Error()})
+		respondError(c, http.StatusInternalServerError, "Failed to fetch session: "+err.Error())
 		return
 	}
 	meta, _ := obj.Object["metadata"].(map[string]interface{})
@@ -3636,7 +4103,7 @@ func removeProjectRFEWorkflowSession(c *gin.Context) {
 		delete(labels, "rfe-phase")
 	}
 	if _, err := reqDyn.Resource(gvr).Namespace(project).Update(context.TODO(), obj, v1.UpdateOptions{}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session labels", "details": err.Error()})
+		respondError(c, http.StatusInternalServerError, "Failed to update session labels: "+err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Session unlinked from RFE", "session": sessionName, "rfe": id})
@@ -3649,20 +4116,20 @@ func getWorkflowJira(c *gin.Context) {
 	id := c.Param("id")
 	reqPath := strings.TrimSpace(c.Query("path"))
 	if reqPath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		respondError(c, http.StatusBadRequest, "path is required")
 		return
 	}
 	_, reqDyn := getK8sClientsForRequest(c)
 	reqK8s, _ := getK8sClientsForRequest(c)
 	if reqDyn == nil || reqK8s == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid user token"})
+		respondError(c, http.StatusUnauthorized, "Missing or invalid user token")
 		return
 	}
 	// Load workflow to find key
 	gvrWf := getRFEWorkflowResource()
 	item, err := reqDyn.Resource(gvrWf).Namespace(project).Get(c.Request.Context(), id, v1.GetOptions{})
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		respondError(c, http.StatusNotFound, "Workflow not found")
 		return
 	}
 	wf := rfeFromUnstructured(item)
@@ -3674,7 +4141,7 @@ func getWorkflowJira(c *gin.Context) {
 		}
 	}
 	if key == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No Jira linked for path"})
+		respondError(c, http.StatusNotFound, "No Jira linked for path")
 		return
 	}
 	// Load Jira creds
@@ -3689,7 +4156,7 @@ func getWorkflowJira(c *gin.Context) {
 	}
 	sec, err := reqK8s.CoreV1().Secrets(project).Get(c.Request.Context(), secretName, v1.GetOptions{})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read runner secret", "details": err.Error()})
+		respondError(c, http.StatusBadRequest, "Failed to read runner secret: "+err.Error())
 		return
 	}
 	get := func(k string) string {
@@ -3701,7 +4168,7 @@ func getWorkflowJira(c *gin.Context) {
 	jiraURL := strings.TrimSpace(get("JIRA_URL"))
 	jiraToken := strings.TrimSpace(get("JIRA_API_TOKEN"))
 	if jiraURL == "" || jiraToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing Jira configuration in runner secret (JIRA_URL, JIRA_API_TOKEN required)"})
+		respondError(c, http.StatusBadRequest, "Missing Jira configuration in runner secret (JIRA_URL, JIRA_API_TOKEN required)")
 		return
 	}
 	jiraBase := strings.TrimRight(jiraURL, "/")
@@ -3711,7 +4178,7 @@ func getWorkflowJira(c *gin.Context) {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 	httpResp, httpErr := httpClient.Do(httpReq)
 	if httpErr != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Jira request failed", "details": httpErr.Error()})
+		respondError(c, http.StatusBadGateway, "Jira request failed: "+httpErr.Error())
 		return
 	}
 	defer httpResp.Body.Close()
@@ -3730,8 +4197,8 @@ func listNamespaceSecrets(c *gin.Context) {
 
 	list, err := reqK8s.CoreV1().Secrets(projectName).List(c.Request.Context(), v1.ListOptions{})
 	if err != nil {
-		log.Printf("Failed to list secrets in %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list secrets"})
+		logErrorf(c, "Failed to list secrets in %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to list secrets")
 		return
 	}
 
@@ -3767,8 +4234,8 @@ func getRunnerSecretsConfig(c *gin.Context) {
 	// ProjectSettings is a singleton per namespace named 'projectsettings'
 	obj, err := reqDyn.Resource(gvr).Namespace(projectName).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		log.Printf("Failed to read ProjectSettings for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read runner secrets config"})
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read runner secrets config")
 		return
 	}
 
@@ -3792,11 +4259,11 @@ func updateRunnerSecretsConfig(c *gin.Context) {
 		SecretName string `json:"secretName" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	if strings.TrimSpace(req.SecretName) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "secretName is required"})
+		respondError(c, http.StatusBadRequest, "secretName is required")
 		return
 	}
 
@@ -3804,12 +4271,12 @@ func updateRunnerSecretsConfig(c *gin.Context) {
 	gvr := getProjectSettingsResource()
 	obj, err := reqDyn.Resource(gvr).Namespace(projectName).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
 	if errors.IsNotFound(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "ProjectSettings not found. Ensure the namespace is labeled ambient-code.io/managed=true and wait for operator."})
+		respondError(c, http.StatusNotFound, "ProjectSettings not found. Ensure the namespace is labeled ambient-code.io/managed=true and wait for operator.")
 		return
 	}
 	if err != nil {
-		log.Printf("Failed to read ProjectSettings for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read runner secrets config"})
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read runner secrets config")
 		return
 	}
 
@@ -3822,8 +4289,8 @@ func updateRunnerSecretsConfig(c *gin.Context) {
 	spec["runnerSecretsName"] = req.SecretName
 
 	if _, err := reqDyn.Resource(gvr).Namespace(projectName).Update(c.Request.Context(), obj, v1.UpdateOptions{}); err != nil {
-		log.Printf("Failed to update ProjectSettings for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update runner secrets config"})
+		logErrorf(c, "Failed to update ProjectSettings for %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to update runner secrets config")
 		return
 	}
 
@@ -3839,8 +4306,8 @@ func listRunnerSecrets(c *gin.Context) {
 	gvr := getProjectSettingsResource()
 	obj, err := reqDyn.Resource(gvr).Namespace(projectName).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		log.Printf("Failed to read ProjectSettings for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read runner secrets config"})
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read runner secrets config")
 		return
 	}
 	secretName := ""
@@ -3862,8 +4329,8 @@ func listRunnerSecrets(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"data": map[string]string{}})
 			return
 		}
-		log.Printf("Failed to get Secret %s/%s: %v", projectName, secretName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read runner secrets"})
+		logErrorf(c, "Failed to get Secret %s/%s: %v", projectName, secretName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read runner secrets")
 		return
 	}
 
@@ -3883,7 +4350,7 @@ func updateRunnerSecrets(c *gin.Context) {
 		Data map[string]string `json:"data" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -3891,8 +4358,8 @@ func updateRunnerSecrets(c *gin.Context) {
 	gvr := getProjectSettingsResource()
 	obj, err := reqDyn.Resource(gvr).Namespace(projectName).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		log.Printf("Failed to read ProjectSettings for %s: %v", projectName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read runner secrets config"})
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", projectName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read runner secrets config")
 		return
 	}
 	secretName := ""
@@ -3926,13 +4393,13 @@ func updateRunnerSecrets(c *gin.Context) {
 			StringData: req.Data,
 		}
 		if _, err := reqK8s.CoreV1().Secrets(projectName).Create(c.Request.Context(), newSec, v1.CreateOptions{}); err != nil {
-			log.Printf("Failed to create Secret %s/%s: %v", projectName, secretName, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create runner secrets"})
+			logErrorf(c, "Failed to create Secret %s/%s: %v", projectName, secretName, err)
+			respondError(c, http.StatusInternalServerError, "Failed to create runner secrets")
 			return
 		}
 	} else if err != nil {
-		log.Printf("Failed to get Secret %s/%s: %v", projectName, secretName, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read runner secrets"})
+		logErrorf(c, "Failed to get Secret %s/%s: %v", projectName, secretName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read runner secrets")
 		return
 	} else {
 		// Update existing - replace Data
@@ -3942,8 +4409,8 @@ func updateRunnerSecrets(c *gin.Context) {
 			sec.Data[k] = []byte(v)
 		}
 		if _, err := reqK8s.CoreV1().Secrets(projectName).Update(c.Request.Context(), sec, v1.UpdateOptions{}); err != nil {
-			log.Printf("Failed to update Secret %s/%s: %v", projectName, secretName, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update runner secrets"})
+			logErrorf(c, "Failed to update Secret %s/%s: %v", projectName, secretName, err)
+			respondError(c, http.StatusInternalServerError, "Failed to update runner secrets")
 			return
 		}
 	}
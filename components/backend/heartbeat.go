@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// minLivenessTimeoutSeconds keeps a stall timeout above a few runner heartbeat intervals
+const minLivenessTimeoutSeconds = 60
+
+// SessionLiveness tunes how the operator treats a runner whose heartbeats stop:
+// after TimeoutSeconds without one the session is marked Stalled, and with
+// KillOnStall its workload is killed and the session failed, so retryPolicy applies
+type SessionLiveness struct {
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+	KillOnStall    bool  `json:"killOnStall,omitempty"`
+}
+
+// validateSessionLiveness returns a user-facing message for an invalid liveness spec
+func validateSessionLiveness(l *SessionLiveness) string {
+	if l == nil || l.TimeoutSeconds == 0 {
+		return ""
+	}
+	if l.TimeoutSeconds < minLivenessTimeoutSeconds {
+		return "liveness.timeoutSeconds must be at least 60"
+	}
+	return ""
+}
+
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/heartbeat
+// sessionHeartbeat records that the session's runner is alive in
+// status.lastHeartbeatTime. Runners call it periodically while they work; the
+// operator marks a running session Stalled once heartbeats stop. Finished
+// sessions answer 409 so a runner left behind can stop.
+func sessionHeartbeat(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	gvr := getAgenticSessionV1Alpha1Resource()
+	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+	if _, finished := sessionFinishedAt(item); finished {
+		respondError(c, http.StatusConflict, "Session is no longer running")
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	patch, _ := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"lastHeartbeatTime": now},
+	})
+	if _, err := reqDyn.Resource(gvr).Namespace(project).Patch(context.TODO(), sessionName, types.MergePatchType, patch, v1.PatchOptions{}, "status"); err != nil {
+		logErrorf(c, "Failed to record heartbeat of %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to record heartbeat")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lastHeartbeatTime": now})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/rest"
+)
+
+// With IMPERSONATE_USERS=true, request clients authenticate as the backend's
+// ServiceAccount and impersonate the user and groups of the caller's validated
+// token instead of forwarding the token. RBAC applies to the caller as before,
+// the API server's audit log records the caller alongside the backend, and
+// OIDC tokens the API server does not accept itself still reach the cluster.
+// Runners keep their session ServiceAccount tokens, and requests whose token
+// could not be validated forward it unchanged.
+//
+// OIDC identities are impersonated under IMPERSONATE_USERNAME_PREFIX and
+// IMPERSONATE_GROUPS_PREFIX (default "oidc:", "-" for none), the equivalents of
+// the API server's --oidc-username-prefix and --oidc-groups-prefix, so an
+// issuer's claims cannot name cluster users. TokenReview identities are
+// already the API server's names and keep their UID and extra. Identities
+// under system: are never impersonated; their token is forwarded, so the API
+// server decides what it is worth.
+
+const systemPrefix = "system:"
+
+func impersonateUsers() bool {
+	return os.Getenv("IMPERSONATE_USERS") == "true"
+}
+
+// impersonationPrefix reads a prefix variable; unset means "oidc:" and "-" none
+func impersonationPrefix(name string) string {
+	v, set := os.LookupEnv(name)
+	v = strings.TrimSpace(v)
+	switch {
+	case !set || v == "":
+		return "oidc:"
+	case v == "-":
+		return ""
+	}
+	return v
+}
+
+// impersonatedUser maps a validated identity to the user to impersonate; ok is
+// false for identities that must not be impersonated
+func impersonatedUser(ident *UserIdentity) (rest.ImpersonationConfig, bool) {
+	if ident == nil || ident.UserName == "" {
+		return rest.ImpersonationConfig{}, false
+	}
+	var out rest.ImpersonationConfig
+	switch ident.Source {
+	case "oidc":
+		out.UserName = impersonationPrefix("IMPERSONATE_USERNAME_PREFIX") + ident.UserName
+		groupsPrefix := impersonationPrefix("IMPERSONATE_GROUPS_PREFIX")
+		for _, g := range ident.Groups {
+			if g = strings.TrimSpace(g); g != "" {
+				out.Groups = append(out.Groups, groupsPrefix+g)
+			}
+		}
+	case "tokenreview":
+		out.UserName, out.UID, out.Extra = ident.UserName, ident.UID, ident.Extra
+		for _, g := range ident.Groups {
+			// The API server adds system:authenticated to impersonated users itself
+			if g != "system:authenticated" {
+				out.Groups = append(out.Groups, g)
+			}
+		}
+	default:
+		return rest.ImpersonationConfig{}, false
+	}
+	if strings.HasPrefix(out.UserName, systemPrefix) {
+		return rest.ImpersonationConfig{}, false
+	}
+	for _, g := range out.Groups {
+		if strings.HasPrefix(g, systemPrefix) {
+			return rest.ImpersonationConfig{}, false
+		}
+	}
+	return out, true
+}
+
+// requestIdentity returns the identity tokenIdentityMiddleware validated
+func requestIdentity(c *gin.Context) *UserIdentity {
+	v, _ := c.Get(requestIdentityKey)
+	ident, _ := v.(*UserIdentity)
+	return ident
+}
+
+// impersonationConfig returns the backend's client config impersonating the
+// request's validated identity; ok is false when impersonation is off or the
+// identity did not come from a validated token or may not be impersonated
+func impersonationConfig(c *gin.Context) (cfg *rest.Config, ok bool) {
+	if !impersonateUsers() || baseKubeConfig == nil {
+		return nil, false
+	}
+	user, ok := impersonatedUser(requestIdentity(c))
+	if !ok {
+		return nil, false
+	}
+	cfg = rest.CopyConfig(baseKubeConfig)
+	cfg.Impersonate = user
+	return cfg, true
+}
+
+// callerCredential identifies the credential the request's clients act with:
+// the impersonated user with IMPERSONATE_USERS, the bearer token otherwise.
+// projectAccessForCaller caches access under it.
+func callerCredential(c *gin.Context) string {
+	cfg, ok := impersonationConfig(c)
+	if !ok {
+		return bearerTokenFromRequest(c)
+	}
+	u := cfg.Impersonate
+	parts := []string{"impersonate", u.UserName, u.UID, strings.Join(u.Groups, ",")}
+	keys := make([]string, 0, len(u.Extra))
+	for k := range u.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+strings.Join(u.Extra[k], ","))
+	}
+	return strings.Join(parts, "\x00")
+}
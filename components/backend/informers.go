@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Shared informers serve the hottest reads (sessions, ProjectSettings and
+// namespaces) from memory. They watch with the backend ServiceAccount, so a
+// cached read must sit behind a check of the caller's own access: project
+// routes pass validateProjectContext, which requires list on agenticsessions.
+var (
+	sessionLister         cache.GenericLister
+	projectSettingsLister cache.GenericLister
+	namespaceLister       corelisters.NamespaceLister
+
+	// informersSynced is set once every cache has its initial list; until then
+	// reads go to the API server and /ready reports not ready
+	informersSynced atomic.Bool
+)
+
+// startInformers starts the shared informers (resynced every INFORMER_RESYNC,
+// default 10m) and marks the caches usable once they have synced.
+func startInformers(ctx context.Context) error {
+	dyn, err := dynamic.NewForConfig(baseKubeConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+	resync := durationFromEnv("INFORMER_RESYNC", 10*time.Minute)
+
+	dynFactory := dynamicinformer.NewDynamicSharedInformerFactory(dyn, resync)
+	sessions := dynFactory.ForResource(getAgenticSessionV1Alpha1Resource())
+	settings := dynFactory.ForResource(getProjectSettingsResource())
+	factory := informers.NewSharedInformerFactory(k8sClient, resync)
+	namespaces := factory.Core().V1().Namespaces()
+
+	// Informers must be requested before Start for the factories to run them
+	synced := []cache.InformerSynced{
+		sessions.Informer().HasSynced,
+		settings.Informer().HasSynced,
+		namespaces.Informer().HasSynced,
+	}
+	sessionLister = sessions.Lister()
+	if _, err := sessions.Informer().AddEventHandler(sessionWatches.handler()); err != nil {
+		return fmt.Errorf("failed to watch session changes: %v", err)
+	}
+	projectSettingsLister = settings.Lister()
+	namespaceLister = namespaces.Lister()
+
+	dynFactory.Start(ctx.Done())
+	factory.Start(ctx.Done())
+	go func() {
+		start := time.Now()
+		if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+			return
+		}
+		sessionWatches.synced(sessions.Informer().LastSyncResourceVersion())
+		informersSynced.Store(true)
+		log.Printf("Informer caches synced in %s", time.Since(start).Round(time.Millisecond))
+	}()
+	return nil
+}
+
+// cachedSessions returns copies of the project's sessions matching selector.
+// ok is false until the caches have synced; callers then list with the
+// caller's client.
+func cachedSessions(project string, selector labels.Selector) ([]unstructured.Unstructured, bool) {
+	if !informersSynced.Load() {
+		return nil, false
+	}
+	objs, err := sessionLister.ByNamespace(project).List(selector)
+	if err != nil {
+		return nil, false
+	}
+	items := make([]unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			items = append(items, *u.DeepCopy())
+		}
+	}
+	return items, true
+}
+
+// cachedSession returns a copy of the named session. ok is false when the
+// caches have not synced or do not hold the session yet, as just after it was
+// created; callers then read it with the caller's client.
+func cachedSession(project, name string) (*unstructured.Unstructured, bool) {
+	if !informersSynced.Load() {
+		return nil, false
+	}
+	obj, err := sessionLister.ByNamespace(project).Get(name)
+	if err != nil {
+		return nil, false
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+	return u.DeepCopy(), true
+}
+
+// cachedProjectSettings returns a copy of the namespace's ProjectSettings, nil
+// when there is none. ok is false until the caches have synced.
+func cachedProjectSettings(project string) (*unstructured.Unstructured, bool) {
+	if !informersSynced.Load() {
+		return nil, false
+	}
+	obj, err := projectSettingsLister.ByNamespace(project).Get("projectsettings")
+	if errors.IsNotFound(err) {
+		return nil, true
+	}
+	if err != nil {
+		return nil, false
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+	return u.DeepCopy(), true
+}
+
+// projectNamespaceExists reports whether the project's namespace exists. A
+// namespace missing from the cache is confirmed with the API server in case it
+// was only just created; before the caches sync every namespace is assumed to
+// exist and handlers report what they find.
+func projectNamespaceExists(ctx context.Context, project string) (bool, error) {
+	if !informersSynced.Load() {
+		return true, nil
+	}
+	if _, err := namespaceLister.Get(project); err == nil {
+		return true, nil
+	}
+	_, err := k8sClient.CoreV1().Namespaces().Get(ctx, project, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+const maxSessionInputs = 10
+
+var sessionNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// SessionInput copies artifacts of an earlier session in the same project into
+// the new session's workspace before the runner starts.
+type SessionInput struct {
+	SessionRef string `json:"sessionRef" binding:"required,dnsname"`
+	// ArtifactSelector holds glob patterns relative to the source artifacts
+	// directory; "*" also matches "/" so "reports/*" includes subdirectories.
+	// Empty selects every artifact.
+	ArtifactSelector []string `json:"artifactSelector,omitempty"`
+	// Path is the destination inside the workspace (default inputs/<sessionRef>)
+	Path string `json:"path,omitempty"`
+}
+
+func cleanRelativePath(p string) (string, bool) {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return "", true
+	}
+	cleaned := path.Clean(p)
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// validateSessionInputs checks input declarations and that each referenced session
+// exists in the project. It returns a message for a 400 response, or "".
+func validateSessionInputs(c *gin.Context, reqDyn dynamic.Interface, project string, inputs []SessionInput) (string, error) {
+	if len(inputs) > maxSessionInputs {
+		return fmt.Sprintf("at most %d inputs are allowed", maxSessionInputs), nil
+	}
+	seen := map[string]bool{}
+	for i := range inputs {
+		in := &inputs[i]
+		in.SessionRef = strings.TrimSpace(in.SessionRef)
+		if !sessionNamePattern.MatchString(in.SessionRef) {
+			return fmt.Sprintf("inputs[%d].sessionRef must be a session name", i), nil
+		}
+		for j, pattern := range in.ArtifactSelector {
+			cleaned, ok := cleanRelativePath(pattern)
+			if !ok || cleaned == "" {
+				return fmt.Sprintf("inputs[%d].artifactSelector[%d] must be a relative glob", i, j), nil
+			}
+			if _, err := path.Match(cleaned, ""); err != nil {
+				return fmt.Sprintf("inputs[%d].artifactSelector[%d]: %v", i, j, err), nil
+			}
+			in.ArtifactSelector[j] = cleaned
+		}
+		dest, ok := cleanRelativePath(in.Path)
+		if !ok {
+			return fmt.Sprintf("inputs[%d].path must be relative to the workspace", i), nil
+		}
+		if dest == "" || dest == "." {
+			dest = "inputs/" + in.SessionRef
+		}
+		in.Path = dest
+		if seen[dest] {
+			return fmt.Sprintf("inputs[%d].path %q is used by another input", i, dest), nil
+		}
+		seen[dest] = true
+
+		if _, cached := cachedSession(project, in.SessionRef); cached {
+			continue
+		}
+		if _, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).Get(c.Request.Context(), in.SessionRef, v1.GetOptions{}); err != nil {
+			if errors.IsNotFound(err) {
+				return fmt.Sprintf("inputs[%d].sessionRef: session %q not found", i, in.SessionRef), nil
+			}
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func sessionInputsToSpec(inputs []SessionInput) []interface{} {
+	out := make([]interface{}, 0, len(inputs))
+	for _, in := range inputs {
+		m := map[string]interface{}{"sessionRef": in.SessionRef, "path": in.Path}
+		if len(in.ArtifactSelector) > 0 {
+			sel := make([]interface{}, 0, len(in.ArtifactSelector))
+			for _, p := range in.ArtifactSelector {
+				sel = append(sel, p)
+			}
+			m["artifactSelector"] = sel
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func parseSessionInputs(raw []interface{}) []SessionInput {
+	out := make([]SessionInput, 0, len(raw))
+	for _, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		in := SessionInput{}
+		in.SessionRef, _ = m["sessionRef"].(string)
+		in.Path, _ = m["path"].(string)
+		if sel, ok := m["artifactSelector"].([]interface{}); ok {
+			for _, s := range sel {
+				if p, ok := s.(string); ok {
+					in.ArtifactSelector = append(in.ArtifactSelector, p)
+				}
+			}
+		}
+		out = append(out, in)
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// logLevel is the minimum level written; LOG_LEVEL sets it at startup and
+// PUT /api/log-level changes it at runtime
+var logLevel = new(slog.LevelVar)
+
+// initLogging makes every line a structured record: JSON by default, text with
+// LOG_FORMAT=text. Output of the standard log package goes through the same
+// handler at info level, so background loops are structured too.
+func initLogging() {
+	if lvl, ok := parseLogLevel(os.Getenv("LOG_LEVEL")); ok {
+		logLevel.Set(lvl)
+	}
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var h slog.Handler = slog.NewJSONHandler(os.Stdout, opts)
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		h = slog.NewTextHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(h))
+	// slog.SetDefault already routes the log package; drop its own timestamp
+	log.SetFlags(0)
+}
+
+func parseLogLevel(s string) (slog.Level, bool) {
+	var lvl slog.Level
+	if strings.TrimSpace(s) == "" || lvl.UnmarshalText([]byte(strings.TrimSpace(s))) != nil {
+		return 0, false
+	}
+	return lvl, true
+}
+
+// requestLogAttrs correlates a line with the request's trace, namespace,
+// session and caller
+func requestLogAttrs(c *gin.Context) []any {
+	var attrs []any
+	if v, ok := c.Get("span"); ok {
+		if s, ok := v.(*span); ok {
+			attrs = append(attrs, "traceID", s.traceID, "spanID", s.spanID)
+		}
+	}
+	if ns := c.Param("projectName"); ns != "" {
+		attrs = append(attrs, "namespace", ns)
+	}
+	session := c.Param("sessionName")
+	if session == "" {
+		session = c.GetString("createdSession")
+	}
+	if session != "" {
+		attrs = append(attrs, "sessionID", session)
+	}
+	if user := requesterFromContext(c); user != "" {
+		attrs = append(attrs, "user", user)
+	}
+	return attrs
+}
+
+func logRequestf(c *gin.Context, level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	slog.Default().Log(ctx, level, fmt.Sprintf(format, args...), requestLogAttrs(c)...)
+}
+
+// logDebugf, logInfof, logWarnf and logErrorf log from request handlers with
+// the request's correlation fields. They must not be called after the handler
+// returned, as gin reuses the context; background work logs with log.Printf.
+func logDebugf(c *gin.Context, format string, args ...interface{}) {
+	logRequestf(c, slog.LevelDebug, format, args...)
+}
+
+func logInfof(c *gin.Context, format string, args ...interface{}) {
+	logRequestf(c, slog.LevelInfo, format, args...)
+}
+
+func logWarnf(c *gin.Context, format string, args ...interface{}) {
+	logRequestf(c, slog.LevelWarn, format, args...)
+}
+
+func logErrorf(c *gin.Context, format string, args ...interface{}) {
+	logRequestf(c, slog.LevelError, format, args...)
+}
+
+// accessLogMiddleware replaces gin's text access log with one structured line
+// per request
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case c.Request.URL.Path == "/health" || c.Request.URL.Path == "/ready" || c.Request.URL.Path == "/metrics":
+			level = slog.LevelDebug
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		attrs := append([]any{
+			"method", c.Request.Method,
+			"route", route,
+			"status", status,
+			"durationMs", time.Since(start).Milliseconds(),
+			"clientIP", c.ClientIP(),
+		}, requestLogAttrs(c)...)
+		slog.Default().Log(context.Background(), level, "request", attrs...)
+	}
+}
+
+// LogLevel is the body of GET and PUT /api/log-level
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// GET /api/log-level
+// getLogLevel returns the backend's current minimum log level.
+func getLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, LogLevel{Level: strings.ToLower(logLevel.Level().String())})
+}
+
+// PUT /api/log-level
+// setLogLevel changes the minimum log level (debug, info, warn or error) of
+// this replica until it restarts. Only callers who may update the
+// ClusterAmbientPolicy, i.e. cluster administrators, may change it.
+func setLogLevel(c *gin.Context) {
+	reqK8s, _ := getK8sClientsForRequest(c)
+	if reqK8s == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req LogLevel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "level is required")
+		return
+	}
+	lvl, ok := parseLogLevel(req.Level)
+	if !ok {
+		respondError(c, http.StatusBadRequest, "level must be debug, info, warn or error")
+		return
+	}
+	ssar := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Group:    "vteam.ambient-code",
+				Resource: "clusterambientpolicies",
+				Verb:     "update",
+			},
+		},
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
+	if err != nil {
+		logErrorf(c, "Failed to check log level permission: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to check permissions")
+		return
+	}
+	if !res.Status.Allowed {
+		auditDeny(c, "logLevel.admin")
+		respondError(c, http.StatusForbidden, "Only cluster administrators may change the log level")
+		return
+	}
+	logLevel.Set(lvl)
+	logWarnf(c, "Log level set to %s", lvl)
+	c.JSON(http.StatusOK, LogLevel{Level: strings.ToLower(lvl.String())})
+}
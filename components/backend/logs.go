@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+const runnerContainerName = "ambient-code-runner"
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/logs?follow=&since=&tail=&timestamps=
+// streamSessionLogs returns the runner container's log as plain text, one line per
+// log line, flushing as lines arrive when following. Options match kubectl logs:
+// since is a duration (5m, 1h), tail a line count. Reading pod logs uses the
+// caller's token. With spec.redaction enabled each line is redacted before it
+// is sent.
+func streamSessionLogs(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	redaction := redactionPolicyFromSpec(spec)
+
+	opts := &corev1.PodLogOptions{Container: runnerContainerName}
+	opts.Follow, _ = strconv.ParseBool(c.Query("follow"))
+	opts.Timestamps, _ = strconv.ParseBool(c.Query("timestamps"))
+	if v := c.Query("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			respondError(c, http.StatusBadRequest, "since must be a positive duration such as 5m or 1h")
+			return
+		}
+		secs := int64(d.Seconds())
+		opts.SinceSeconds = &secs
+	}
+	if v := c.Query("tail"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "tail must be a number of lines")
+			return
+		}
+		// kubectl uses -1 for "all lines"
+		if n >= 0 {
+			opts.TailLines = &n
+		}
+	}
+
+	pods, err := reqK8s.CoreV1().Pods(project).List(c.Request.Context(), v1.ListOptions{
+		LabelSelector: "app=ambient-code-runner,agentic-session=" + sessionName,
+	})
+	if err != nil {
+		logErrorf(c, "Failed to list runner pods for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusInternalServerError, "Failed to find runner pod")
+		return
+	}
+	if len(pods.Items) == 0 {
+		respondError(c, http.StatusNotFound, "No runner pod found for session; it may not have started or its Job was cleaned up")
+		return
+	}
+	// Job retries leave several pods; the newest holds the current attempt
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Time.After(pods.Items[j].CreationTimestamp.Time)
+	})
+	pod := pods.Items[0]
+	if pod.Status.Phase == corev1.PodPending {
+		respondProblem(c, problem.New(http.StatusConflict, "", "Runner pod is still pending").With("pod", pod.Name))
+		return
+	}
+
+	stream, err := reqK8s.CoreV1().Pods(project).GetLogs(pod.Name, opts).Stream(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to stream logs for %s/%s: %v", project, pod.Name, err)
+		respondError(c, http.StatusBadGateway, "Failed to read runner logs")
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("X-Runner-Pod", pod.Name)
+	// Disable proxy buffering so followed lines arrive promptly
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line, _ := redaction.redact(scanner.Bytes())
+		if _, err := c.Writer.Write(append(line, '\n')); err != nil {
+			return
+		}
+		if opts.Follow {
+			c.Writer.Flush()
+		}
+	}
+	if err := scanner.Err(); err != nil && c.Request.Context().Err() == nil {
+		logInfof(c, "Log stream for %s/%s ended: %v", project, pod.Name, err)
+	}
+}
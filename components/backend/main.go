@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -29,6 +30,16 @@ var (
 )
 
 func main() {
+	// `backend check-openapi` verifies openapi.json documents exactly the
+	// registered /api routes, without a cluster
+	if len(os.Args) > 1 && os.Args[1] == "check-openapi" {
+		gin.SetMode(gin.ReleaseMode)
+		os.Exit(checkOpenAPIRoutes(newRouter()))
+	}
+
+	// Structured JSON logs at LOG_LEVEL
+	initLogging()
+
 	// Initialize Kubernetes clients
 	if err := initK8sClients(); err != nil {
 		log.Fatalf("Failed to initialize Kubernetes clients: %v", err)
@@ -53,9 +64,72 @@ func main() {
 	}
 
 	// Project-scoped storage; no global preload required
+	if err := initArtifactStore(); err != nil {
+		log.Fatalf("Failed to initialize artifact store: %v", err)
+	}
+
+	// Audit sinks (stdout, file, http) from AUDIT_SINKS
+	initAuditLogging()
+
+	// OTLP trace export from the OTEL_* environment
+	initTracing("ambient-backend")
+
+	// Session, ProjectSettings and Namespace caches; reads use the API server until synced
+	if err := startInformers(context.Background()); err != nil {
+		log.Fatalf("Failed to start informers: %v", err)
+	}
+
+	// Custom rules for the binding tags of session requests
+	if err := registerSessionValidators(); err != nil {
+		log.Fatalf("Failed to register request validators: %v", err)
+	}
+
+	// Optional queue between inbound triggers and session creation (WEBHOOK_QUEUE)
+	if err := initWebhookQueue(); err != nil {
+		log.Fatalf("Failed to initialize webhook queue: %v", err)
+	}
+
+	// Replays inbound triggers whose session could not be created
+	go runDeadLetterRetries()
+
+	r := newRouter()
 
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Server starting on port %s", port)
+	log.Printf("Using namespace: %s", namespace)
+
+	// Optional mutual TLS port for runners (RUNNER_MTLS)
+	var tlsServers []*http.Server
+	if runnerMTLSEnabled() {
+		log.Printf("Serving runners over mutual TLS on port %s", runnerMTLSPort())
+		tlsServers = append(tlsServers, newRunnerMTLSServer(r))
+	}
+
+	if err := runServer(":"+port, r, tlsServers...); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+// newRouter registers every route. Handlers on /api carry a "// METHOD /api/..."
+// annotation that tools/openapi-gen turns into openapi.json.
+func newRouter() *gin.Engine {
 	// Setup Gin router
-	r := gin.Default()
+	r := gin.New()
+
+	// Server span per request, continuing an incoming traceparent
+	r.Use(tracingMiddleware())
+
+	// One structured access log line per request; panics become 500 problems
+	r.Use(accessLogMiddleware(), gin.CustomRecovery(recoveryProblem))
+
+	// Unknown routes and methods answer with problem documents like handlers do
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(noRouteProblem(http.StatusNotFound))
+	r.NoMethod(noRouteProblem(http.StatusMethodNotAllowed))
 
 	// Middleware to populate user context from forwarded headers
 	r.Use(forwardedIdentityMiddleware())
@@ -64,7 +138,7 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "traceparent"}
 	r.Use(cors.New(config))
 
 	// Content service mode: expose minimal file APIs for per-namespace writer service
@@ -72,10 +146,23 @@ func main() {
 		r.POST("/content/write", contentWrite)
 		r.GET("/content/file", contentRead)
 		r.GET("/content/list", contentList)
+		r.GET("/content/usage", contentUsageHandler)
+		r.POST("/content/delete", contentDelete)
+		r.POST("/content/blob", contentLinkBlob)
 	}
 
+	// Inbound triggers authenticate their callers themselves (message signature
+	// or webhook API key) instead of with a Kubernetes token, and create sessions
+	// as the access key their project names
+	r.POST("/api/projects/:projectName/triggers/teams", auditMiddleware(), teamsTrigger)
+	r.POST("/api/webhooks/alertmanager", auditMiddleware(), alertmanagerTrigger)
+	r.POST("/api/webhooks/pagerduty", auditMiddleware(), pagerDutyTrigger)
+
 	// API routes (all consolidated under /api) remain available
-	api := r.Group("/api")
+	// Identity comes from the validated bearer token (OIDC or TokenReview) when present
+	// Mutations, artifact downloads and denials are written to the audit log
+	// Runner tokens only reach their own session's runner endpoints
+	api := r.Group("/api", tokenIdentityMiddleware(), runnerScopeMiddleware(), auditMiddleware())
 	{
 		// Legacy non-project agentic session routes removed
 
@@ -85,19 +172,56 @@ func main() {
 		{
 			// Access check (SSAR based)
 			projectGroup.GET("/access", accessCheck)
+			// Namespace usage and quotas
+			projectGroup.GET("/stats", getProjectStats)
+			// Queue time, time to first output and duration percentiles
+			projectGroup.GET("/slo", getProjectSLO)
+			// Monthly chargeback report
+			projectGroup.GET("/reports/usage", getUsageReport)
+			// Projected month-end spend and daily spend anomalies
+			projectGroup.GET("/budget/forecast", getBudgetForecast)
 			// Agentic sessions under a project
 			projectGroup.GET("/agentic-sessions", listSessions)
 			projectGroup.POST("/agentic-sessions", createSession)
+			projectGroup.POST("/policy/simulate", simulateSessionPolicy)
 			projectGroup.GET("/agentic-sessions/:sessionName", getSession)
 			projectGroup.PUT("/agentic-sessions/:sessionName", updateSession)
 			projectGroup.DELETE("/agentic-sessions/:sessionName", deleteSession)
 			projectGroup.POST("/agentic-sessions/:sessionName/clone", cloneSession)
+			projectGroup.POST("/agentic-sessions/:sessionName/retry", retrySession)
+			projectGroup.GET("/pipelines", listSessionPipelines)
+			projectGroup.POST("/pipelines", createSessionPipeline)
+			projectGroup.GET("/pipelines/:pipelineName", getSessionPipeline)
+			projectGroup.DELETE("/pipelines/:pipelineName", deleteSessionPipeline)
 			projectGroup.POST("/agentic-sessions/:sessionName/start", startSession)
 			projectGroup.POST("/agentic-sessions/:sessionName/stop", stopSession)
+			projectGroup.POST("/agentic-sessions/:sessionName/extend", extendSession)
+			projectGroup.GET("/agentic-sessions/:sessionName/compare/:otherName", compareSessions)
+			projectGroup.POST("/agentic-sessions/:sessionName/artifacts", uploadSessionArtifact)
+			projectGroup.GET("/agentic-sessions/:sessionName/artifacts", listSessionArtifacts)
+			projectGroup.GET("/agentic-sessions/:sessionName/artifacts/archive", downloadSessionArtifactsArchive)
+			projectGroup.GET("/agentic-sessions/:sessionName/artifacts/download/*name", downloadSessionArtifact)
+			projectGroup.GET("/agentic-sessions/:sessionName/artifacts/view/*name", viewSessionArtifact)
+			projectGroup.GET("/agentic-sessions/:sessionName/artifacts/preview/*name", previewSessionArtifact)
+			projectGroup.PUT("/agentic-sessions/:sessionName/artifacts/tags/*name", updateArtifactTags)
+			projectGroup.DELETE("/agentic-sessions/:sessionName/artifacts/*name", deleteSessionArtifact)
+			projectGroup.GET("/artifacts/search", searchArtifacts)
+			projectGroup.GET("/retention/report", getRetentionReport)
+			projectGroup.POST("/trigger-fingerprints", computeTriggerFingerprint)
+			projectGroup.GET("/trigger-fingerprints/:fingerprint", getSessionsByFingerprint)
+			projectGroup.GET("/queue", getSessionQueue)
+			projectGroup.GET("/runner-canary", getRunnerCanary)
 			projectGroup.PUT("/agentic-sessions/:sessionName/status", updateSessionStatus)
+			projectGroup.POST("/agentic-sessions/:sessionName/heartbeat", sessionHeartbeat)
+			projectGroup.GET("/agentic-sessions/:sessionName/debug/exec", execSessionDebug)
+			projectGroup.POST("/agentic-sessions/:sessionName/pull-requests", requestSessionPullRequest)
 			projectGroup.PUT("/agentic-sessions/:sessionName/displayname", updateSessionDisplayName)
+			projectGroup.GET("/agentic-sessions/:sessionName/logs", streamSessionLogs)
+			projectGroup.GET("/agentic-sessions/:sessionName/events", streamSessionEvents)
+			projectGroup.GET("/agentic-sessions/:sessionName/timeline", getSessionTimeline)
 			projectGroup.GET("/agentic-sessions/:sessionName/messages", getSessionMessages)
 			projectGroup.POST("/agentic-sessions/:sessionName/messages", postSessionMessage)
+			projectGroup.GET("/agentic-sessions/:sessionName/inbox", getSessionInbox)
 			// Session workspace APIs
 			projectGroup.GET("/agentic-sessions/:sessionName/workspace", getSessionWorkspace)
 			projectGroup.GET("/agentic-sessions/:sessionName/workspace/*path", getSessionWorkspaceFile)
@@ -135,6 +259,19 @@ func main() {
 			projectGroup.POST("/keys", createProjectKey)
 			projectGroup.DELETE("/keys/:keyId", deleteProjectKey)
 
+			// Webhook API keys (hashed in ProjectSettings spec.webhookAuth)
+			projectGroup.GET("/apikeys", listWebhookAPIKeys)
+			projectGroup.POST("/apikeys", createWebhookAPIKey)
+			projectGroup.POST("/apikeys/:keyId/rotate", rotateWebhookAPIKey)
+			projectGroup.DELETE("/apikeys/:keyId", revokeWebhookAPIKey)
+
+			// Project policy (ProjectSettings) editing with validation
+			projectGroup.GET("/settings", getProjectPolicy)
+			projectGroup.PUT("/settings", updateProjectPolicy)
+
+			// Re-wrap encrypted artifact data keys with the current key
+			projectGroup.POST("/artifact-keys/rotate", rotateArtifactKeys)
+
 			// Runner secrets configuration and CRUD
 			projectGroup.GET("/secrets", listNamespaceSecrets)
 			projectGroup.GET("/runner-secrets/config", getRunnerSecretsConfig)
@@ -145,6 +282,34 @@ func main() {
 
 		// Project management (cluster-wide)
 		api.GET("/projects", listProjects)
+
+		// Nested dashboard queries across projects, sessions and artifacts
+		api.POST("/graphql", graphqlQuery)
+		api.GET("/graphql/schema", getGraphQLSchema)
+
+		// Registered runner frameworks (cluster-wide)
+		api.GET("/frameworks", listFrameworks)
+
+		// Organization-wide policy projects may only tighten (cluster-wide)
+		api.GET("/cluster-policy", getClusterPolicy)
+
+		// Per-model token prices used for cost tracking and estimates (cluster-wide;
+		// updates need update on modelpricings)
+		api.GET("/model-pricing", getModelPricing)
+		api.PUT("/model-pricing", updateModelPricing)
+		api.PUT("/model-pricing/models/:model", setModelPrice)
+		api.DELETE("/model-pricing/models/:model", deleteModelPrice)
+
+		// Backend log level, changeable at runtime by cluster administrators
+		api.GET("/log-level", getLogLevel)
+		api.PUT("/log-level", setLogLevel)
+
+		// Inbound triggers waiting to be replayed (cluster administrators only)
+		api.GET("/admin/webhooks/deadletter", listWebhookDeadLetters)
+		api.GET("/admin/webhooks/deadletter/:name", getWebhookDeadLetter)
+		api.POST("/admin/webhooks/deadletter/:name/replay", replayWebhookDeadLetter)
+		api.DELETE("/admin/webhooks/deadletter/:name", deleteWebhookDeadLetter)
+
 		api.POST("/projects", createProject)
 		api.GET("/projects/:projectName", getProject)
 		api.PUT("/projects/:projectName", updateProject)
@@ -155,21 +320,15 @@ func main() {
 	r.GET("/metrics", getMetrics)
 
 	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
-	})
+	r.GET("/health", healthCheck)
+	// Readiness additionally waits for the informer caches
+	r.GET("/ready", readinessCheck)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	// OpenAPI document for the /api routes and a Swagger UI to browse it
+	r.GET("/openapi.json", serveOpenAPISpec)
+	r.GET("/docs", serveSwaggerUI)
 
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Using namespace: %s", namespace)
-
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+	return r
 }
 
 func initK8sClients() error {
@@ -254,12 +413,36 @@ type AgenticSessionSpec struct {
 	Project           string             `json:"project,omitempty"`
 	GitConfig         *GitConfig         `json:"gitConfig,omitempty"`
 	Paths             *Paths             `json:"paths,omitempty"`
+	SummaryReport     bool               `json:"summaryReport,omitempty"`
+	Trigger           *SessionTrigger    `json:"trigger,omitempty"`
+	Framework         string             `json:"framework,omitempty"`
+	FrameworkVersion  string             `json:"frameworkVersion,omitempty"`
+	Scheduling        *SessionScheduling `json:"scheduling,omitempty"`
+	Scratch           *SessionScratch    `json:"scratch,omitempty"`
+	Inputs            []SessionInput     `json:"inputs,omitempty"`
+	// TTLSecondsAfterFinished deletes the session this long after it finishes;
+	// unset falls back to the project's retention.sessions
+	TTLSecondsAfterFinished *int64              `json:"ttlSecondsAfterFinished,omitempty"`
+	RetryPolicy             *SessionRetryPolicy `json:"retryPolicy,omitempty"`
+	Liveness                *SessionLiveness    `json:"liveness,omitempty"`
+	Debug                   *SessionDebug       `json:"debug,omitempty"`
+	// RetryOf names the original session this one retries; RetryAttempt counts from 1
+	RetryOf      string `json:"retryOf,omitempty"`
+	RetryAttempt int64  `json:"retryAttempt,omitempty"`
+	// Priority orders queued sessions: low, normal (default) or high
+	Priority string         `json:"priority,omitempty"`
+	Tools    *SessionTools  `json:"tools,omitempty"`
+	Budget   *SessionBudget `json:"budget,omitempty"`
+	// DryRun sessions are planned but never run; see status.plan
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 type LLMSettings struct {
-	Model       string  `json:"model"`
-	Temperature float64 `json:"temperature"`
-	MaxTokens   int     `json:"maxTokens"`
+	Model       string  `json:"model" binding:"max=200"`
+	Temperature float64 `json:"temperature" binding:"gte=0,lte=2"`
+	MaxTokens   int     `json:"maxTokens" binding:"gte=0"`
+	// Provider names an entry of ProjectSettings spec.modelProviders; empty uses the project default
+	Provider string `json:"provider,omitempty"`
 }
 
 type GitUser struct {
@@ -282,6 +465,8 @@ type GitConfig struct {
 	User           *GitUser           `json:"user,omitempty"`
 	Authentication *GitAuthentication `json:"authentication,omitempty"`
 	Repositories   []GitRepository    `json:"repositories,omitempty"`
+	// PullRequests lets the runner open a pull request with its changes to each repository
+	PullRequests bool `json:"pullRequests,omitempty"`
 }
 
 type Paths struct {
@@ -296,7 +481,11 @@ type AgenticSessionStatus struct {
 	StartTime      *string `json:"startTime,omitempty"`
 	CompletionTime *string `json:"completionTime,omitempty"`
 	JobName        string  `json:"jobName,omitempty"`
-	StateDir       string  `json:"stateDir,omitempty"`
+	// FirstOutputTime is when the agent first produced output, reported once by the runner
+	FirstOutputTime string `json:"firstOutputTime,omitempty"`
+	// PipelineRunName is set instead of JobName when the session runs as a Tekton PipelineRun
+	PipelineRunName string `json:"pipelineRunName,omitempty"`
+	StateDir        string `json:"stateDir,omitempty"`
 	// Result summary fields from runner
 	Subtype      string                 `json:"subtype,omitempty"`
 	IsError      bool                   `json:"is_error,omitempty"`
@@ -305,22 +494,83 @@ type AgenticSessionStatus struct {
 	TotalCostUSD *float64               `json:"total_cost_usd,omitempty"`
 	Usage        map[string]interface{} `json:"usage,omitempty"`
 	Result       *string                `json:"result,omitempty"`
+	// Deadline extensions granted while running
+	Extensions []SessionExtension `json:"extensions,omitempty"`
+	// Executive summary generated by the runner after completion
+	Report *SessionReport `json:"report,omitempty"`
+	// Set while the session waits for capacity under concurrency limits
+	Queue *SessionQueueStatus `json:"queue,omitempty"`
+	// Runner image and rollout track (stable or canary) chosen by the operator
+	Runner *SessionRunnerStatus `json:"runner,omitempty"`
+	// Scratch PVC created for the session, if any
+	Scratch *SessionScratchStatus `json:"scratch,omitempty"`
+	// Outcome of reporting the result to outbound integrations, keyed by integration
+	Integrations map[string]SessionIntegrationStatus `json:"integrations,omitempty"`
+	// Automatic retry scheduled or created after a failure
+	Retry *SessionRetryStatus `json:"retry,omitempty"`
+	// Last time the runner reported it was alive, and whether heartbeats have stopped
+	LastHeartbeatTime string `json:"lastHeartbeatTime,omitempty"`
+	Stalled           bool   `json:"stalled,omitempty"`
+	// Pull requests the runner asked to open with the session's changes
+	PullRequests []SessionPullRequest `json:"pullRequests,omitempty"`
+	// Values redaction removed from the session's artifacts
+	Redactions *SessionRedactions `json:"redactions,omitempty"`
+	// What a dry-run session would have run
+	Plan *SessionPlan `json:"plan,omitempty"`
+}
+
+type SessionScratchStatus struct {
+	ClaimName string `json:"claimName"`
+	Size      string `json:"size,omitempty"`
+}
+
+type SessionIntegrationStatus struct {
+	State       string `json:"state"`
+	URL         string `json:"url,omitempty"`
+	AttemptedAt string `json:"attemptedAt,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+type SessionReport struct {
+	Path        string `json:"path"`
+	Preview     string `json:"preview,omitempty"`
+	GeneratedAt string `json:"generatedAt,omitempty"`
 }
 
 type CreateAgenticSessionRequest struct {
 	Prompt               string             `json:"prompt" binding:"required"`
-	DisplayName          string             `json:"displayName,omitempty"`
+	DisplayName          string             `json:"displayName,omitempty" binding:"max=253"`
 	LLMSettings          *LLMSettings       `json:"llmSettings,omitempty"`
-	Timeout              *int               `json:"timeout,omitempty"`
+	Timeout              *int               `json:"timeout,omitempty" binding:"omitempty,gt=0"`
 	Interactive          *bool              `json:"interactive,omitempty"`
 	WorkspacePath        string             `json:"workspacePath,omitempty"`
 	GitConfig            *GitConfig         `json:"gitConfig,omitempty"`
 	UserContext          *UserContext       `json:"userContext,omitempty"`
 	BotAccount           *BotAccountRef     `json:"botAccount,omitempty"`
 	ResourceOverrides    *ResourceOverrides `json:"resourceOverrides,omitempty"`
-	EnvironmentVariables map[string]string  `json:"environmentVariables,omitempty"`
-	Labels               map[string]string  `json:"labels,omitempty"`
-	Annotations          map[string]string  `json:"annotations,omitempty"`
+	EnvironmentVariables map[string]string  `json:"environmentVariables,omitempty" binding:"omitempty,dive,keys,envname,endkeys"`
+	Labels               map[string]string  `json:"labels,omitempty" binding:"omitempty,dive,keys,labelkey,endkeys,labelvalue"`
+	Annotations          map[string]string  `json:"annotations,omitempty" binding:"omitempty,dive,keys,labelkey,endkeys"`
+	SummaryReport        *bool              `json:"summaryReport,omitempty"`
+	Trigger              *SessionTrigger    `json:"trigger,omitempty"`
+	Framework            string             `json:"framework,omitempty" binding:"omitempty,dnsname"`
+	FrameworkVersion     string             `json:"frameworkVersion,omitempty" binding:"max=63"`
+	Scheduling           *SessionScheduling `json:"scheduling,omitempty"`
+	Scratch              *SessionScratch    `json:"scratch,omitempty"`
+	// Artifacts of earlier sessions to place in the workspace before start
+	Inputs                  []SessionInput      `json:"inputs,omitempty" binding:"omitempty,max=32,dive"`
+	TTLSecondsAfterFinished *int64              `json:"ttlSecondsAfterFinished,omitempty" binding:"omitempty,gte=0"`
+	RetryPolicy             *SessionRetryPolicy `json:"retryPolicy,omitempty"`
+	Liveness                *SessionLiveness    `json:"liveness,omitempty"`
+	Debug                   *SessionDebug       `json:"debug,omitempty"`
+	Priority                string              `json:"priority,omitempty"`
+	// Tools narrows the runner's tools below the project's policy
+	Tools *SessionTools `json:"tools,omitempty"`
+	// Budget is checked against what remains of the project's monthly budget
+	Budget *SessionBudget `json:"budget,omitempty"`
+	// DryRun evaluates policy, estimates cost and plans the workload without
+	// running it; policy violations are reported in status.plan, not rejected
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 type CloneSessionRequest struct {
@@ -369,12 +619,56 @@ type BotAccountRef struct {
 }
 
 type ResourceOverrides struct {
-	CPU           string `json:"cpu,omitempty"`
-	Memory        string `json:"memory,omitempty"`
+	CPU              string `json:"cpu,omitempty" binding:"omitempty,quantity"`
+	Memory           string `json:"memory,omitempty" binding:"omitempty,quantity"`
+	EphemeralStorage string `json:"ephemeralStorage,omitempty" binding:"omitempty,quantity"`
+	// GPU is a count of the namespace's GPU resource (sessionPolicy.gpuResourceName)
+	GPU           string `json:"gpu,omitempty" binding:"omitempty,quantity"`
 	StorageClass  string `json:"storageClass,omitempty"`
 	PriorityClass string `json:"priorityClass,omitempty"`
 }
 
+// SessionScratch requests a per-session PVC mounted at /scratch in the runner
+type SessionScratch struct {
+	Size string `json:"size" binding:"omitempty,quantity"`
+}
+
+// SessionTools narrows the runner's tools for one session. Allowed must stay
+// within the project's sessionPolicy.allowedTools; Blocked adds to the cluster
+// and project blocklists.
+type SessionTools struct {
+	Allowed []string `json:"allowed,omitempty" binding:"omitempty,max=64,unique,dive,required,toolname"`
+	Blocked []string `json:"blocked,omitempty" binding:"omitempty,max=64,unique,dive,required,toolname"`
+}
+
+// SessionBudget caps what one session may be expected to spend. A session is
+// refused when its MaxCostUSD exceeds what remains of the project's monthly
+// budget.
+type SessionBudget struct {
+	// MaxCostUSD is a plain decimal amount, e.g. "2.50"
+	MaxCostUSD string `json:"maxCostUSD" binding:"required,usd"`
+}
+
+// SessionRetryPolicy lets the operator recreate a session whose runner failed,
+// waiting BackoffSeconds before the first retry and doubling it for each next one
+type SessionRetryPolicy struct {
+	MaxRetries     int64 `json:"maxRetries" binding:"gte=0"`
+	BackoffSeconds int64 `json:"backoffSeconds,omitempty" binding:"gte=0"`
+}
+
+type SessionRetryStatus struct {
+	NextAttemptTime string `json:"nextAttemptTime,omitempty"`
+	// Session is the retry created for this session
+	Session string `json:"session,omitempty"`
+}
+
+// SessionScheduling places the runner pod; namespace nodeSelector keys take precedence
+type SessionScheduling struct {
+	NodeSelector map[string]string    `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration  `json:"tolerations,omitempty"`
+	NodeAffinity *corev1.NodeAffinity `json:"nodeAffinity,omitempty"`
+}
+
 // Project management types
 type AmbientProject struct {
 	Name              string            `json:"name"`
@@ -384,6 +678,11 @@ type AmbientProject struct {
 	Annotations       map[string]string `json:"annotations"`
 	CreationTimestamp string            `json:"creationTimestamp"`
 	Status            string            `json:"status"`
+	// Role and Permissions are the caller's access, set by GET /api/projects
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Stats is set by GET /api/projects?include=stats
+	Stats *ProjectStats `json:"stats,omitempty"`
 }
 
 type CreateProjectRequest struct {
@@ -523,10 +822,16 @@ func parseStatus(status map[string]interface{}) *AgenticSessionStatus {
 		result.CompletionTime = &completionTime
 	}
 
+	result.FirstOutputTime, _ = status["firstOutputTime"].(string)
+
 	if jobName, ok := status["jobName"].(string); ok {
 		result.JobName = jobName
 	}
 
+	if runName, ok := status["pipelineRunName"].(string); ok {
+		result.PipelineRunName = runName
+	}
+
 	// New: result summary fields (top-level in status)
 	if st, ok := status["subtype"].(string); ok {
 		result.Subtype = st
@@ -555,5 +860,113 @@ func parseStatus(status map[string]interface{}) *AgenticSessionStatus {
 		result.StateDir = stateDir
 	}
 
+	if queue, ok := status["queue"].(map[string]interface{}); ok {
+		q := &SessionQueueStatus{}
+		q.Reason, _ = queue["reason"].(string)
+		q.Framework, _ = queue["framework"].(string)
+		q.Priority, _ = queue["priority"].(string)
+		q.QueuedAt, _ = queue["queuedAt"].(string)
+		result.Queue = q
+	}
+
+	if runner, ok := status["runner"].(map[string]interface{}); ok {
+		r := &SessionRunnerStatus{}
+		r.Image, _ = runner["image"].(string)
+		r.Track, _ = runner["track"].(string)
+		result.Runner = r
+	}
+
+	if scratch, ok := status["scratch"].(map[string]interface{}); ok {
+		s := &SessionScratchStatus{}
+		s.ClaimName, _ = scratch["claimName"].(string)
+		s.Size, _ = scratch["size"].(string)
+		result.Scratch = s
+	}
+
+	result.LastHeartbeatTime, _ = status["lastHeartbeatTime"].(string)
+	if conditions, ok := status["conditions"].([]interface{}); ok {
+		for _, raw := range conditions {
+			if c, ok := raw.(map[string]interface{}); ok && c["type"] == "Stalled" {
+				result.Stalled = c["status"] == string(v1.ConditionTrue)
+			}
+		}
+	}
+
+	if retry, ok := status["retry"].(map[string]interface{}); ok {
+		r := &SessionRetryStatus{}
+		r.NextAttemptTime, _ = retry["nextAttemptTime"].(string)
+		r.Session, _ = retry["session"].(string)
+		result.Retry = r
+	}
+
+	if integrations, ok := status["integrations"].(map[string]interface{}); ok {
+		result.Integrations = map[string]SessionIntegrationStatus{}
+		for name, raw := range integrations {
+			m, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			in := SessionIntegrationStatus{}
+			in.State, _ = m["state"].(string)
+			in.URL, _ = m["url"].(string)
+			in.AttemptedAt, _ = m["attemptedAt"].(string)
+			in.Message, _ = m["message"].(string)
+			result.Integrations[name] = in
+		}
+	}
+
+	if prs, ok := status["pullRequests"].([]interface{}); ok {
+		for _, raw := range prs {
+			if m, ok := raw.(map[string]interface{}); ok {
+				result.PullRequests = append(result.PullRequests, parseSessionPullRequest(m))
+			}
+		}
+	}
+
+	if raw, ok := status["redactions"].(map[string]interface{}); ok {
+		r := &SessionRedactions{}
+		r.Total, _ = intFromSpec(raw, "total")
+		r.Artifacts, _ = intFromSpec(raw, "artifacts")
+		if by, ok := raw["byDetector"].(map[string]interface{}); ok {
+			r.ByDetector = map[string]int64{}
+			for k := range by {
+				r.ByDetector[k], _ = intFromSpec(by, k)
+			}
+		}
+		result.Redactions = r
+	}
+
+	if plan, ok := status["plan"].(map[string]interface{}); ok {
+		result.Plan = parseSessionPlan(plan)
+	}
+
+	if report, ok := status["report"].(map[string]interface{}); ok {
+		r := &SessionReport{}
+		r.Path, _ = report["path"].(string)
+		r.Preview, _ = report["preview"].(string)
+		r.GeneratedAt, _ = report["generatedAt"].(string)
+		result.Report = r
+	}
+
+	if exts, ok := status["extensions"].([]interface{}); ok {
+		for _, e := range exts {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ext := SessionExtension{}
+			if v, ok := intFromSpec(m, "seconds"); ok {
+				ext.Seconds = v
+			}
+			if v, ok := intFromSpec(m, "activeDeadlineSeconds"); ok {
+				ext.ActiveDeadlineSeconds = v
+			}
+			ext.Reason, _ = m["reason"].(string)
+			ext.RequestedBy, _ = m["requestedBy"].(string)
+			ext.RequestedAt, _ = m["requestedAt"].(string)
+			result.Extensions = append(result.Extensions, ext)
+		}
+	}
+
 	return result
 }
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// inboxMaxWait bounds a runner's long-poll; runners reconnect after each response
+	inboxMaxWait = 60 * time.Second
+	// inboxRecheckInterval re-reads the inbox during a long-poll so messages posted
+	// through another backend replica are picked up
+	inboxRecheckInterval = 2 * time.Second
+)
+
+// SessionInboxMessage is one line of a session's inbox.jsonl
+type SessionInboxMessage struct {
+	ID        string `json:"id,omitempty"`
+	Type      string `json:"type"`
+	Content   string `json:"content"`
+	Sender    string `json:"sender,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+func sessionInboxPath(sessionName string) string {
+	return fmt.Sprintf("/sessions/%s/inbox.jsonl", sessionName)
+}
+
+// inboxWaiters wakes long-polling runners on this replica when a message is posted
+var (
+	inboxWaitersMu sync.Mutex
+	inboxWaiters   = map[string]chan struct{}{}
+	// inboxWriteLocks serializes read-modify-write appends to the same inbox
+	inboxWriteLocks sync.Map
+)
+
+func inboxKey(project, sessionName string) string {
+	return project + "/" + sessionName
+}
+
+func inboxWaitChannel(key string) <-chan struct{} {
+	inboxWaitersMu.Lock()
+	defer inboxWaitersMu.Unlock()
+	ch, ok := inboxWaiters[key]
+	if !ok {
+		ch = make(chan struct{})
+		inboxWaiters[key] = ch
+	}
+	return ch
+}
+
+func inboxNotify(key string) {
+	inboxWaitersMu.Lock()
+	defer inboxWaitersMu.Unlock()
+	if ch, ok := inboxWaiters[key]; ok {
+		close(ch)
+		delete(inboxWaiters, key)
+	}
+}
+
+func inboxWriteLock(key string) *sync.Mutex {
+	mu, _ := inboxWriteLocks.LoadOrStore(key, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+func newInboxMessageID() string {
+	b, err := randomBytes(8)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// parseInboxLines returns the messages after the first `after` lines and the new line count
+func parseInboxLines(data []byte, after int) ([]SessionInboxMessage, int) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		lines = nil
+	}
+	if after < 0 || after > len(lines) {
+		after = len(lines)
+	}
+	msgs := []SessionInboxMessage{}
+	for _, ln := range lines[after:] {
+		var m SessionInboxMessage
+		if strings.TrimSpace(ln) == "" || json.Unmarshal([]byte(ln), &m) != nil {
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, len(lines)
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/inbox?after=N&wait=30
+// getSessionInbox is the runner's channel for follow-up messages. It returns the
+// inbox messages after line N and the cursor to pass next time, holding the request
+// open for up to `wait` seconds until a message arrives.
+func getSessionInbox(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	if !canWriteSession(c, project, sessionName) {
+		respondError(c, http.StatusForbidden, "Not allowed to read this session's inbox")
+		return
+	}
+	after, _ := strconv.Atoi(c.DefaultQuery("after", "0"))
+	wait, _ := strconv.Atoi(c.DefaultQuery("wait", "0"))
+	deadline := time.Now().Add(min(time.Duration(wait)*time.Second, inboxMaxWait))
+	key := inboxKey(project, sessionName)
+
+	for {
+		// Subscribe before reading so a message posted in between still wakes us
+		notify := inboxWaitChannel(key)
+		data, err := readProjectContentFile(c, project, sessionInboxPath(sessionName))
+		if err != nil {
+			// A missing inbox means no messages yet
+			data = nil
+		}
+		msgs, next := parseInboxLines(data, after)
+		remaining := time.Until(deadline)
+		if len(msgs) > 0 || remaining <= 0 {
+			c.JSON(http.StatusOK, gin.H{"messages": msgs, "next": next})
+			return
+		}
+		select {
+		case <-notify:
+		case <-time.After(min(remaining, inboxRecheckInterval)):
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+const (
+	defaultMessagesPageSize = 100
+	maxMessagesPageSize     = 1000
+)
+
+// SessionMessagesPage is a filtered page of a session transcript. Each item keeps
+// the runner's message fields and adds its position in the full transcript.
+type SessionMessagesPage struct {
+	Items      []map[string]interface{} `json:"items"`
+	Total      int                      `json:"total"`
+	Offset     int                      `json:"offset"`
+	Limit      int                      `json:"limit"`
+	NextOffset *int                     `json:"nextOffset,omitempty"`
+}
+
+// sessionMessageFilter selects transcript messages by role (user, assistant,
+// system, result), content block type (text, thinking, tool_use, tool_result)
+// and tool name. Tool results match when their tool_use_id belongs to a matching
+// tool call.
+type sessionMessageFilter struct {
+	Roles        map[string]bool
+	ContentTypes map[string]bool
+	Tools        map[string]bool
+}
+
+func csvSet(v string) map[string]bool {
+	out := map[string]bool{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out[s] = true
+		}
+	}
+	return out
+}
+
+func (f sessionMessageFilter) matches(m map[string]interface{}, toolUseIDs map[string]bool) bool {
+	if len(f.Roles) > 0 {
+		kind, _ := m["type"].(string)
+		if !f.Roles[strings.TrimSuffix(kind, "_message")] {
+			return false
+		}
+	}
+	block, _ := m["content"].(map[string]interface{})
+	blockType, _ := block["type"].(string)
+	blockType = strings.TrimSuffix(blockType, "_block")
+	if len(f.ContentTypes) > 0 && !f.ContentTypes[blockType] {
+		return false
+	}
+	if len(f.Tools) > 0 {
+		switch blockType {
+		case "tool_use":
+			name, _ := block["name"].(string)
+			return f.Tools[name]
+		case "tool_result":
+			id, _ := block["tool_use_id"].(string)
+			return toolUseIDs[id]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// filterSessionMessages returns the page of messages matching the filter
+func filterSessionMessages(all []map[string]interface{}, f sessionMessageFilter, offset, limit int) SessionMessagesPage {
+	toolUseIDs := map[string]bool{}
+	if len(f.Tools) > 0 {
+		for _, m := range all {
+			if block, ok := m["content"].(map[string]interface{}); ok && block["type"] == "tool_use_block" {
+				if name, _ := block["name"].(string); f.Tools[name] {
+					if id, _ := block["id"].(string); id != "" {
+						toolUseIDs[id] = true
+					}
+				}
+			}
+		}
+	}
+	page := SessionMessagesPage{Items: []map[string]interface{}{}, Offset: offset, Limit: limit}
+	for i, m := range all {
+		if !f.matches(m, toolUseIDs) {
+			continue
+		}
+		if page.Total >= offset && len(page.Items) < limit {
+			item := make(map[string]interface{}, len(m)+1)
+			for k, v := range m {
+				item[k] = v
+			}
+			item["index"] = i
+			page.Items = append(page.Items, item)
+		}
+		page.Total++
+	}
+	if next := offset + len(page.Items); next < page.Total {
+		page.NextOffset = &next
+	}
+	return page
+}
+
+// sessionMessagesQuery reports whether the request asks for a filtered page rather
+// than the raw messages.json array
+func sessionMessagesQuery(c *gin.Context) bool {
+	for _, k := range []string{"offset", "limit", "role", "tool", "contentType"} {
+		if _, ok := c.GetQuery(k); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// respondSessionMessagesPage writes the filtered page for ?offset, limit, role, tool and contentType
+func respondSessionMessagesPage(c *gin.Context, data []byte) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondError(c, http.StatusBadRequest, "offset must be a non-negative integer")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultMessagesPageSize)))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, "limit must be a positive integer")
+		return
+	}
+	limit = min(limit, maxMessagesPageSize)
+	var all []map[string]interface{}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &all); err != nil {
+			respondError(c, http.StatusBadGateway, "session messages are not valid JSON")
+			return
+		}
+	}
+	filter := sessionMessageFilter{
+		Roles:        csvSet(c.Query("role")),
+		ContentTypes: csvSet(c.Query("contentType")),
+		Tools:        csvSet(c.Query("tool")),
+	}
+	c.JSON(http.StatusOK, filterSessionMessages(all, filter, offset, limit))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ./tools/openapi-gen
+
+// openAPISpec is generated by tools/openapi-gen from the "// METHOD /api/..."
+// annotation on each handler's doc comment
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// GET /openapi.json
+func serveOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
+
+// GET /docs
+// serveSwaggerUI renders the spec with Swagger UI loaded from a CDN; requests
+// made from the page need a bearer token entered under Authorize.
+func serveSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Ambient Code backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// openAPIRouteMismatches compares the /api routes registered on the router with
+// the operations in the embedded spec, in both directions
+func openAPIRouteMismatches(routes gin.RoutesInfo) ([]string, error) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, fmt.Errorf("parse openapi.json: %v", err)
+	}
+	documented := map[string]bool{}
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var problems []string
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+		segments := strings.Split(r.Path, "/")
+		for i, s := range segments {
+			if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+				segments[i] = "{" + s[1:] + "}"
+			}
+		}
+		key := r.Method + " " + strings.Join(segments, "/")
+		if documented[key] {
+			delete(documented, key)
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s %s (%s) is not in openapi.json", r.Method, r.Path, r.Handler))
+	}
+	for key := range documented {
+		problems = append(problems, fmt.Sprintf("%s is in openapi.json but not registered", key))
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// checkOpenAPIRoutes backs `backend check-openapi`, which needs no cluster and
+// exits non-zero when a route is missing from the spec or the spec lists a route
+// that no longer exists
+func checkOpenAPIRoutes(r *gin.Engine) int {
+	problems, err := openAPIRouteMismatches(r.Routes())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintln(os.Stderr, "annotate the handlers and run make openapi")
+		return 1
+	}
+	fmt.Println("openapi.json matches the registered routes")
+	return 0
+}
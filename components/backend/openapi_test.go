@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	problems, err := openAPIRouteMismatches(newRouter().Routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		t.Error(p)
+	}
+	if len(problems) > 0 {
+		t.Log("annotate the handlers and run make openapi")
+	}
+}
+
+func TestOpenAPIRouteMismatchesReportsDrift(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	routes := newRouter().Routes()
+	if len(routes) == 0 {
+		t.Fatal("router has no routes")
+	}
+
+	undocumented := append(routes, gin.RouteInfo{Method: "GET", Path: "/api/undocumented/:id", Handler: "test"})
+	problems, err := openAPIRouteMismatches(undocumented)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0] != "GET /api/undocumented/:id (test) is not in openapi.json" {
+		t.Errorf("undocumented route: got %q", problems)
+	}
+
+	var removed gin.RoutesInfo
+	var dropped string
+	for _, r := range routes {
+		if dropped == "" && r.Method == "GET" && r.Path == "/api/projects/:projectName/agentic-sessions" {
+			dropped = "GET /api/projects/{projectName}/agentic-sessions is in openapi.json but not registered"
+			continue
+		}
+		removed = append(removed, r)
+	}
+	if dropped == "" {
+		t.Fatal("session list route not registered")
+	}
+	problems, err = openAPIRouteMismatches(removed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0] != dropped {
+		t.Errorf("unregistered route: got %q", problems)
+	}
+}
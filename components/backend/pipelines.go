@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// pipelineLabel and pipelineStepLabel mark the sessions the operator creates
+	// for a SessionPipeline
+	pipelineLabel     = "ambient-code.io/pipeline"
+	pipelineStepLabel = "ambient-code.io/pipeline-step"
+	maxPipelineSteps  = 50
+	// maxPipelineSessionName leaves room for the operator's -job and -retry-N suffixes
+	maxPipelineSessionName = 50
+)
+
+// getSessionPipelineResource returns the GroupVersionResource for SessionPipeline
+func getSessionPipelineResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "sessionpipelines",
+	}
+}
+
+// PipelineStepInput places artifacts of an upstream step in the step's workspace
+type PipelineStepInput struct {
+	Step             string   `json:"step"`
+	ArtifactSelector []string `json:"artifactSelector,omitempty"`
+	Path             string   `json:"path,omitempty"`
+}
+
+// SessionPipelineStep is one node of the DAG; Template is an AgenticSession spec
+type SessionPipelineStep struct {
+	Name      string                 `json:"name"`
+	DependsOn []string               `json:"dependsOn,omitempty"`
+	Inputs    []PipelineStepInput    `json:"inputs,omitempty"`
+	Template  map[string]interface{} `json:"template"`
+}
+
+type SessionPipelineStepStatus struct {
+	Name    string `json:"name"`
+	Phase   string `json:"phase"`
+	Session string `json:"session,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type SessionPipelineStatus struct {
+	Phase          string                      `json:"phase,omitempty"`
+	Message        string                      `json:"message,omitempty"`
+	StartTime      string                      `json:"startTime,omitempty"`
+	CompletionTime string                      `json:"completionTime,omitempty"`
+	Steps          []SessionPipelineStepStatus `json:"steps,omitempty"`
+}
+
+type SessionPipeline struct {
+	Name              string                 `json:"name"`
+	CreationTimestamp string                 `json:"creationTimestamp,omitempty"`
+	Steps             []SessionPipelineStep  `json:"steps"`
+	Status            *SessionPipelineStatus `json:"status,omitempty"`
+}
+
+type CreateSessionPipelineRequest struct {
+	Name  string                `json:"name" binding:"required"`
+	Steps []SessionPipelineStep `json:"steps" binding:"required"`
+}
+
+func sessionPipelineFromUnstructured(obj *unstructured.Unstructured) SessionPipeline {
+	p := SessionPipeline{Name: obj.GetName(), CreationTimestamp: obj.GetCreationTimestamp().UTC().Format(time.RFC3339)}
+	if spec, ok, _ := unstructured.NestedMap(obj.Object, "spec"); ok {
+		var parsed struct {
+			Steps []SessionPipelineStep `json:"steps"`
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &parsed); err == nil {
+			p.Steps = parsed.Steps
+		}
+	}
+	if status, ok, _ := unstructured.NestedMap(obj.Object, "status"); ok {
+		st := &SessionPipelineStatus{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(status, st); err == nil {
+			p.Status = st
+		}
+	}
+	return p
+}
+
+// validatePipelineSteps checks the DAG the operator will run: unique step names,
+// known dependencies, inputs only from dependencies, no cycles, and session names
+// that fit. It returns a user-facing message.
+func validatePipelineSteps(pipeline string, steps []SessionPipelineStep) string {
+	if len(steps) == 0 || len(steps) > maxPipelineSteps {
+		return fmt.Sprintf("a pipeline needs between 1 and %d steps", maxPipelineSteps)
+	}
+	deps := map[string][]string{}
+	for i, step := range steps {
+		if !sessionNamePattern.MatchString(step.Name) {
+			return fmt.Sprintf("steps[%d].name must be lowercase letters, digits and dashes", i)
+		}
+		if _, dup := deps[step.Name]; dup {
+			return fmt.Sprintf("step %q is defined twice", step.Name)
+		}
+		if n := len(pipeline) + 1 + len(step.Name); n > maxPipelineSessionName {
+			return fmt.Sprintf("session name %s-%s is longer than %d characters", pipeline, step.Name, maxPipelineSessionName)
+		}
+		if prompt, _ := step.Template["prompt"].(string); strings.TrimSpace(prompt) == "" {
+			return fmt.Sprintf("step %q: template.prompt is required", step.Name)
+		}
+		deps[step.Name] = step.DependsOn
+	}
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := deps[dep]; !ok || dep == step.Name {
+				return fmt.Sprintf("step %q depends on unknown step %q", step.Name, dep)
+			}
+		}
+		for _, in := range step.Inputs {
+			if !slices.Contains(step.DependsOn, in.Step) {
+				return fmt.Sprintf("step %q takes inputs from %q, which is not in its dependsOn", step.Name, in.Step)
+			}
+		}
+	}
+	// Depth-first search for a cycle
+	state := map[string]int{}
+	var visit func(string) bool
+	visit = func(name string) bool {
+		switch state[name] {
+		case 1:
+			return false
+		case 2:
+			return true
+		}
+		state[name] = 1
+		for _, dep := range deps[name] {
+			if !visit(dep) {
+				return false
+			}
+		}
+		state[name] = 2
+		return true
+	}
+	for _, step := range steps {
+		if !visit(step.Name) {
+			return "steps contain a dependency cycle"
+		}
+	}
+	return ""
+}
+
+// GET /api/projects/:projectName/pipelines
+func listSessionPipelines(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	list, err := reqDyn.Resource(getSessionPipelineResource()).Namespace(project).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		logErrorf(c, "Failed to list session pipelines in project %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to list session pipelines")
+		return
+	}
+	items := make([]SessionPipeline, 0, len(list.Items))
+	for i := range list.Items {
+		items = append(items, sessionPipelineFromUnstructured(&list.Items[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// POST /api/projects/:projectName/pipelines
+// createSessionPipeline validates the DAG and every step template against the
+// project's session policy, then hands the pipeline to the operator, which
+// creates each step's session once its dependencies complete.
+func createSessionPipeline(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req CreateSessionPipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if !sessionNamePattern.MatchString(req.Name) {
+		respondError(c, http.StatusBadRequest, "name must be lowercase letters, digits and dashes")
+		return
+	}
+	if msg := validatePipelineSteps(req.Name, req.Steps); msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
+	}
+	steps := make([]interface{}, 0, len(req.Steps))
+	for _, step := range req.Steps {
+		if !enforceCopiedSessionPolicy(c, reqDyn, project, step.Template) {
+			return
+		}
+		parsed := parseSpec(step.Template)
+		if msg := validateSessionRetryPolicy(parsed.RetryPolicy); msg != "" {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("step %q: %s", step.Name, msg))
+			return
+		}
+		if msg := validateSessionLiveness(parsed.Liveness); msg != "" {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("step %q: %s", step.Name, msg))
+			return
+		}
+		if msg, err := validateSessionFramework(c.Request.Context(), parsed.Framework, parsed.FrameworkVersion); err != nil {
+			logErrorf(c, "Failed to validate framework %q in %s: %v", parsed.Framework, project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to validate framework")
+			return
+		} else if msg != "" {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("step %q: %s", step.Name, msg))
+			return
+		}
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&step)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("step %q: %v", step.Name, err))
+			return
+		}
+		steps = append(steps, m)
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "SessionPipeline",
+		"metadata": map[string]interface{}{
+			"name":      req.Name,
+			"namespace": project,
+		},
+		"spec": map[string]interface{}{"steps": steps},
+	}}
+	created, err := reqDyn.Resource(getSessionPipelineResource()).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			respondError(c, http.StatusConflict, fmt.Sprintf("pipeline %q already exists", req.Name))
+			return
+		}
+		logErrorf(c, "Failed to create session pipeline %s in project %s: %v", req.Name, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to create session pipeline")
+		return
+	}
+	auditDetail(c, "created", req.Name)
+	c.JSON(http.StatusCreated, sessionPipelineFromUnstructured(created))
+}
+
+// GET /api/projects/:projectName/pipelines/:pipelineName
+func getSessionPipeline(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("pipelineName")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	obj, err := reqDyn.Resource(getSessionPipelineResource()).Namespace(project).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Pipeline not found")
+			return
+		}
+		logErrorf(c, "Failed to get session pipeline %s in project %s: %v", name, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get session pipeline")
+		return
+	}
+	c.JSON(http.StatusOK, sessionPipelineFromUnstructured(obj))
+}
+
+// DELETE /api/projects/:projectName/pipelines/:pipelineName
+// deleteSessionPipeline removes the pipeline; its step sessions are garbage
+// collected through their owner reference.
+func deleteSessionPipeline(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("pipelineName")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	err := reqDyn.Resource(getSessionPipelineResource()).Namespace(project).Delete(context.TODO(), name, v1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Pipeline not found")
+			return
+		}
+		logErrorf(c, "Failed to delete session pipeline %s in project %s: %v", name, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete session pipeline")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,448 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Schema is the root Query type of an API
+type Schema struct {
+	Query *Object
+}
+
+// Object is a GraphQL object type
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is a field of an object type. A field with Type set is an object (or,
+// with List, a list of objects) selected further; without it the resolved
+// values are returned as JSON.
+type Field struct {
+	Type *Object
+	List bool
+	// Args names the accepted arguments and their default values
+	Args map[string]interface{}
+	// Resolve returns the field's value for each of parents, in order. A value
+	// that is an error nulls the field of that parent alone and is reported
+	// with its path; a returned error does so for every parent.
+	Resolve func(ctx context.Context, parents []interface{}, args map[string]interface{}) ([]interface{}, error)
+}
+
+// Value builds a field resolved from each parent on its own, for fields that
+// do not load anything
+func Value(fn func(parent interface{}) interface{}) *Field {
+	return &Field{Resolve: func(_ context.Context, parents []interface{}, _ map[string]interface{}) ([]interface{}, error) {
+		out := make([]interface{}, len(parents))
+		for i, p := range parents {
+			out[i] = fn(p)
+		}
+		return out, nil
+	}}
+}
+
+// Request is the body of a GraphQL-over-HTTP POST
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of executing a request. Data is absent when the
+// request failed before execution.
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Error is a request or field error; Path locates a field error in Data
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// maxDepth bounds how deeply selections may nest
+const maxDepth = 12
+
+// Execute parses, validates and runs a query against the schema
+func (s *Schema) Execute(ctx context.Context, req Request) Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: "Syntax error: " + err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	if op.Kind != "query" {
+		return Response{Errors: []Error{{Message: fmt.Sprintf("%s operations are not supported", op.Kind)}}}
+	}
+	vars, err := variableValues(op, req.Variables)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	e := &execution{doc: doc, vars: vars}
+	if errs := e.validateSet(s.Query, op.Selections, nil, 1); len(errs) > 0 {
+		return Response{Errors: errs}
+	}
+	root := e.selectSet(ctx, s.Query, op.Selections, []interface{}{nil}, [][]interface{}{nil})
+	return Response{Data: root[0], Errors: e.errors}
+}
+
+func (d *Document) operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return d.Operations[0], nil
+	}
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+func variableValues(op *Operation, given map[string]interface{}) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for _, def := range op.Variables {
+		v, ok := given[def.Name]
+		if !ok {
+			v = def.Default
+		}
+		if v == nil && strings.HasSuffix(def.Type, "!") {
+			return nil, fmt.Errorf("variable $%s of required type %s was not provided", def.Name, def.Type)
+		}
+		vars[def.Name] = v
+	}
+	return vars, nil
+}
+
+type execution struct {
+	doc    *Document
+	vars   map[string]interface{}
+	errors []Error
+}
+
+// resolveValue replaces variable references in an argument value
+func (e *execution) resolveValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case Variable:
+		return e.vars[string(t)]
+	case Enum:
+		return string(t)
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = e.resolveValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			out[k] = e.resolveValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// included evaluates @skip and @include
+func (e *execution) included(directives []Directive) bool {
+	for _, d := range directives {
+		cond, _ := e.resolveValue(d.Arguments["if"]).(bool)
+		if (d.Name == "skip" && cond) || (d.Name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldGroup is the selections of one response key, merged across fragments
+type fieldGroup struct {
+	key    string
+	fields []*Selection
+}
+
+func (e *execution) collectFields(obj *Object, sels []*Selection, groups []*fieldGroup, visited map[string]bool) []*fieldGroup {
+	for _, sel := range sels {
+		if !e.included(sel.Directives) {
+			continue
+		}
+		switch {
+		case sel.FragmentName != "":
+			f := e.doc.Fragments[sel.FragmentName]
+			if f == nil || visited[f.Name] || f.TypeCondition != obj.Name {
+				continue
+			}
+			visited[f.Name] = true
+			groups = e.collectFields(obj, f.Selections, groups, visited)
+		case sel.Inline:
+			if sel.TypeCondition == "" || sel.TypeCondition == obj.Name {
+				groups = e.collectFields(obj, sel.Selections, groups, visited)
+			}
+		default:
+			key := sel.Name
+			if sel.Alias != "" {
+				key = sel.Alias
+			}
+			found := false
+			for _, g := range groups {
+				if g.key == key {
+					g.fields = append(g.fields, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, &fieldGroup{key: key, fields: []*Selection{sel}})
+			}
+		}
+	}
+	return groups
+}
+
+// validateSet validates the selection set of an object, including that the
+// fields merged into one response key agree on the field and its arguments
+func (e *execution) validateSet(obj *Object, sels []*Selection, fragments []string, depth int) []Error {
+	errs := e.validate(obj, sels, fragments, depth)
+	if len(errs) > 0 {
+		return errs
+	}
+	for _, g := range e.collectFields(obj, sels, nil, map[string]bool{}) {
+		first := g.fields[0]
+		for _, f := range g.fields[1:] {
+			if f.Name != first.Name || !reflect.DeepEqual(f.Arguments, first.Arguments) {
+				errs = append(errs, Error{Message: fmt.Sprintf("fields selected as %s conflict: use different aliases", g.key)})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// validate checks selections against the schema before anything is resolved
+func (e *execution) validate(obj *Object, sels []*Selection, fragments []string, depth int) []Error {
+	var errs []Error
+	if depth > maxDepth {
+		return []Error{{Message: fmt.Sprintf("query is nested more than %d levels deep", maxDepth)}}
+	}
+	for _, sel := range sels {
+		for _, d := range sel.Directives {
+			if d.Name != "skip" && d.Name != "include" {
+				errs = append(errs, Error{Message: fmt.Sprintf("unknown directive @%s", d.Name)})
+			}
+		}
+		switch {
+		case sel.FragmentName != "":
+			f := e.doc.Fragments[sel.FragmentName]
+			if f == nil {
+				errs = append(errs, Error{Message: fmt.Sprintf("unknown fragment %s", sel.FragmentName)})
+				continue
+			}
+			for _, name := range fragments {
+				if name == f.Name {
+					return append(errs, Error{Message: fmt.Sprintf("fragment %s spreads itself", f.Name)})
+				}
+			}
+			if f.TypeCondition != obj.Name {
+				errs = append(errs, Error{Message: fmt.Sprintf("fragment %s on %s cannot be spread on %s", f.Name, f.TypeCondition, obj.Name)})
+				continue
+			}
+			errs = append(errs, e.validate(obj, f.Selections, append(fragments, f.Name), depth)...)
+		case sel.Inline:
+			if sel.TypeCondition != "" && sel.TypeCondition != obj.Name {
+				errs = append(errs, Error{Message: fmt.Sprintf("inline fragment on %s cannot be spread on %s", sel.TypeCondition, obj.Name)})
+				continue
+			}
+			errs = append(errs, e.validate(obj, sel.Selections, fragments, depth)...)
+		case sel.Name == "__typename":
+			if sel.Selections != nil {
+				errs = append(errs, Error{Message: "__typename has no subfields"})
+			}
+		default:
+			field := obj.Fields[sel.Name]
+			if field == nil {
+				errs = append(errs, Error{Message: fmt.Sprintf("cannot query field %s on type %s", sel.Name, obj.Name)})
+				continue
+			}
+			for arg := range sel.Arguments {
+				if _, ok := field.Args[arg]; !ok {
+					errs = append(errs, Error{Message: fmt.Sprintf("unknown argument %s on field %s.%s", arg, obj.Name, sel.Name)})
+				}
+			}
+			switch {
+			case field.Type == nil && sel.Selections != nil:
+				errs = append(errs, Error{Message: fmt.Sprintf("field %s.%s has no subfields", obj.Name, sel.Name)})
+			case field.Type != nil && sel.Selections == nil:
+				errs = append(errs, Error{Message: fmt.Sprintf("field %s.%s of type %s must have a selection of subfields", obj.Name, sel.Name, field.Type.Name)})
+			case field.Type != nil:
+				errs = append(errs, e.validateSet(field.Type, sel.Selections, fragments, depth+1)...)
+			}
+		}
+	}
+	return errs
+}
+
+func (e *execution) fieldError(path []interface{}, key string, err error) {
+	p := append(append([]interface{}{}, path...), key)
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: p})
+}
+
+// selectSet resolves sels on every parent at once, so each field's resolver is
+// called once per level rather than once per parent
+func (e *execution) selectSet(ctx context.Context, obj *Object, sels []*Selection, parents []interface{}, paths [][]interface{}) []*orderedObject {
+	out := make([]*orderedObject, len(parents))
+	for i := range out {
+		out[i] = &orderedObject{values: map[string]interface{}{}}
+	}
+	for _, g := range e.collectFields(obj, sels, nil, map[string]bool{}) {
+		sel := g.fields[0]
+		if sel.Name == "__typename" {
+			for _, o := range out {
+				o.set(g.key, obj.Name)
+			}
+			continue
+		}
+		field := obj.Fields[sel.Name]
+		args := make(map[string]interface{}, len(field.Args))
+		for name, def := range field.Args {
+			args[name] = def
+		}
+		for name, v := range sel.Arguments {
+			if v := e.resolveValue(v); v != nil {
+				args[name] = v
+			}
+		}
+
+		values, err := field.Resolve(ctx, parents, args)
+		if err == nil && len(values) != len(parents) {
+			err = fmt.Errorf("internal error: %s.%s resolved %d values for %d objects", obj.Name, sel.Name, len(values), len(parents))
+		}
+		if err != nil {
+			for i, o := range out {
+				o.set(g.key, nil)
+				e.fieldError(paths[i], g.key, err)
+			}
+			continue
+		}
+		for i, v := range values {
+			if err, isErr := v.(error); isErr {
+				values[i] = nil
+				e.fieldError(paths[i], g.key, err)
+			}
+		}
+		if field.Type == nil {
+			for i, o := range out {
+				o.set(g.key, values[i])
+			}
+			continue
+		}
+
+		// Merge the subselections of every selection of this key
+		var sub []*Selection
+		for _, f := range g.fields {
+			sub = append(sub, f.Selections...)
+		}
+		type slot struct{ parent, index int }
+		var children []interface{}
+		var childPaths [][]interface{}
+		var slots []slot
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			if !field.List {
+				children = append(children, v)
+				childPaths = append(childPaths, append(append([]interface{}{}, paths[i]...), g.key))
+				slots = append(slots, slot{i, -1})
+				continue
+			}
+			list := reflect.ValueOf(v)
+			if list.Kind() != reflect.Slice {
+				values[i] = nil
+				e.fieldError(paths[i], g.key, fmt.Errorf("internal error: %s.%s is not a list", obj.Name, sel.Name))
+				continue
+			}
+			values[i] = make([]interface{}, list.Len())
+			for j := 0; j < list.Len(); j++ {
+				children = append(children, list.Index(j).Interface())
+				childPaths = append(childPaths, append(append([]interface{}{}, paths[i]...), g.key, j))
+				slots = append(slots, slot{i, j})
+			}
+		}
+		if len(children) > 0 {
+			resolved := e.selectSet(ctx, field.Type, sub, children, childPaths)
+			for k, s := range slots {
+				if s.index < 0 {
+					values[s.parent] = resolved[k]
+				} else {
+					values[s.parent].([]interface{})[s.index] = resolved[k]
+				}
+			}
+		}
+		for i, o := range out {
+			o.set(g.key, values[i])
+		}
+	}
+	return out
+}
+
+// orderedObject is a response object whose keys keep the query's order
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *orderedObject) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// StringArg returns a string argument
+func StringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+// IntArg returns an integer argument, which arrives as float64 from JSON
+// variables and int64 from literals
+func IntArg(args map[string]interface{}, name string) (int64, bool) {
+	switch v := args[name].(type) {
+	case int64:
+		return v, true
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,633 @@
+// Package graphql executes GraphQL queries against a schema of Go resolvers.
+// It implements the query language a dashboard needs: operations with
+// variables, aliases, arguments, named and inline fragments and the @skip and
+// @include directives. Mutations, subscriptions and introspection beyond
+// __typename are not supported.
+//
+// Resolvers are batched: a field is resolved once per level of the response
+// for every parent object at that level, so a resolver can load the children
+// of all its parents in one call, the way a dataloader would.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is a parsed GraphQL request document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query, mutation or subscription of a document
+type Operation struct {
+	Kind       string
+	Name       string
+	Variables  []VariableDefinition
+	Selections []*Selection
+}
+
+// VariableDefinition declares an operation variable, e.g. ($limit: Int = 10)
+type VariableDefinition struct {
+	Name    string
+	Type    string
+	Default interface{}
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []*Selection
+}
+
+// Selection is a field, a fragment spread (FragmentName set) or an inline
+// fragment (Inline set)
+type Selection struct {
+	Alias        string
+	Name         string
+	Arguments    map[string]interface{}
+	Directives   []Directive
+	Selections   []*Selection
+	FragmentName string
+	Inline       bool
+	// TypeCondition of an inline fragment; empty applies to any type
+	TypeCondition string
+}
+
+// Directive is @name(args) on a selection
+type Directive struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Variable is a $name reference in an argument value
+type Variable string
+
+// Enum is an enum literal in an argument value
+type Enum string
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	// Whitespace, commas and comments are insignificant
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',' {
+			l.pos++
+			continue
+		}
+		if ch == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+	ch := l.src[l.pos]
+	switch {
+	case strings.ContainsRune("!$&():=@[]{}|", rune(ch)):
+		l.pos++
+		return token{kind: tokPunct, value: string(ch), pos: start}, nil
+	case ch == '.':
+		if strings.HasPrefix(l.src[l.pos:], "...") {
+			l.pos += 3
+			return token{kind: tokPunct, value: "...", pos: start}, nil
+		}
+	case ch == '_' || isLetter(ch):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, value: l.src[start:l.pos], pos: start}, nil
+	case ch == '-' || isDigit(ch):
+		return l.number()
+	case ch == '"':
+		return l.string()
+	}
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", ch, start)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, fmt.Errorf("invalid number at offset %d", start)
+	}
+	kind := tokInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		kind = tokFloat
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		kind = tokFloat
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return token{}, fmt.Errorf("unterminated string at offset %d", start)
+		}
+		raw := l.src[l.pos+3 : l.pos+3+end]
+		l.pos += 3 + end + 3
+		return token{kind: tokString, value: blockString(raw), pos: start}, nil
+	}
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '\n', '\r':
+			return token{}, fmt.Errorf("unterminated string at offset %d", start)
+		case '"':
+			l.pos++
+			s, err := strconv.Unquote(l.src[start:l.pos])
+			if err != nil {
+				return token{}, fmt.Errorf("invalid string at offset %d: %v", start, err)
+			}
+			return token{kind: tokString, value: s, pos: start}, nil
+		}
+		l.pos++
+	}
+	return token{}, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// blockString strips the common indentation and blank first and last lines of
+// a """block string"""
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(ch byte) bool { return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' }
+func isDigit(ch byte) bool  { return ch >= '0' && ch <= '9' }
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// Parse parses a GraphQL request document
+func Parse(src string) (*Document, error) {
+	p := &parser{lex: &lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.is(tokPunct, "{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Kind: "query", Selections: sels})
+		case p.is(tokName, "query"), p.is(tokName, "mutation"), p.is(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.is(tokName, "fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.Fragments[f.Name]; dup {
+				return nil, fmt.Errorf("fragment %s is defined more than once", f.Name)
+			}
+			doc.Fragments[f.Name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) is(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of document")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.tok.value, p.tok.pos)
+}
+
+func (p *parser) expect(value string) error {
+	if !p.is(tokPunct, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	n := p.tok.value
+	return n, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.Name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.is(tokPunct, "(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.is(tokPunct, ")") {
+			def, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.Variables = append(op.Variables, def)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = sels
+	return op, nil
+}
+
+func (p *parser) variableDefinition() (VariableDefinition, error) {
+	var def VariableDefinition
+	if err := p.expect("$"); err != nil {
+		return def, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return def, err
+	}
+	def.Name = name
+	if err := p.expect(":"); err != nil {
+		return def, err
+	}
+	if def.Type, err = p.typeRef(); err != nil {
+		return def, err
+	}
+	if p.is(tokPunct, "=") {
+		if err := p.advance(); err != nil {
+			return def, err
+		}
+		if def.Default, err = p.value(true); err != nil {
+			return def, err
+		}
+	}
+	return def, nil
+}
+
+func (p *parser) typeRef() (string, error) {
+	var t string
+	if p.is(tokPunct, "[") {
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		t = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		t = name
+	}
+	if p.is(tokPunct, "!") {
+		t += "!"
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+	}
+	return t, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("fragment cannot be named on")
+	}
+	if !p.is(tokName, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	typ, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &Fragment{Name: name, TypeCondition: typ, Selections: sels}, nil
+}
+
+func (p *parser) selectionSet() ([]*Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []*Selection
+	for !p.is(tokPunct, "}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("empty selection set at offset %d", p.tok.pos)
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (*Selection, error) {
+	sel := &Selection{}
+	var err error
+	if p.is(tokPunct, "...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			sel.FragmentName = p.tok.value
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			sel.Directives, err = p.directives()
+			return sel, err
+		}
+		sel.Inline = true
+		if p.is(tokName, "on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if sel.TypeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if sel.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		sel.Selections, err = p.selectionSet()
+		return sel, err
+	}
+
+	if sel.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.is(tokPunct, ":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		sel.Alias = sel.Name
+		if sel.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if sel.Arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if sel.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.is(tokPunct, "{") {
+		if sel.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+func (p *parser) arguments() (map[string]interface{}, error) {
+	if !p.is(tokPunct, "(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	args := map[string]interface{}{}
+	for !p.is(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := args[name]; dup {
+			return nil, fmt.Errorf("argument %s is given more than once", name)
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]Directive, error) {
+	var out []Directive
+	for p.is(tokPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Directive{Name: name, Arguments: args})
+	}
+	return out, nil
+}
+
+// value parses a literal; variables are not allowed in constant positions
+// such as variable defaults
+func (p *parser) value(constant bool) (interface{}, error) {
+	t := p.tok
+	switch {
+	case t.kind == tokPunct && t.value == "$" && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return Variable(name), err
+	case t.kind == tokInt:
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("integer %s out of range", t.value)
+		}
+		return n, p.advance()
+	case t.kind == tokFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s", t.value)
+		}
+		return f, p.advance()
+	case t.kind == tokString:
+		return t.value, p.advance()
+	case t.kind == tokName:
+		var v interface{}
+		switch t.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = Enum(t.value)
+		}
+		return v, p.advance()
+	case t.kind == tokPunct && t.value == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.is(tokPunct, "]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.advance()
+	case t.kind == tokPunct && t.value == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		obj := map[string]interface{}{}
+		for !p.is(tokPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.advance()
+	}
+	return nil, p.unexpected()
+}
@@ -0,0 +1,156 @@
+// Package problem is the backend's error response: an RFC 7807
+// application/problem+json document with a stable machine-readable code.
+// Clients branch on Code; Title describes the code and Detail the occurrence.
+//
+// Documents also repeat Detail as "error" for clients written against the
+// earlier {"error": "..."} responses.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem documents
+const ContentType = "application/problem+json"
+
+// TypePrefix turns a code into the problem's type URI
+const TypePrefix = "urn:ambient-code:problem:"
+
+// Codes. Each status has a default code; the others narrow a status down to a
+// cause clients act on differently.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeGone             = "gone"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeValidationFailed = "validation_failed"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+	CodeNotImplemented   = "not_implemented"
+	CodeBadGateway       = "bad_gateway"
+	CodeUnavailable      = "unavailable"
+	CodeGatewayTimeout   = "gateway_timeout"
+
+	// CodePolicyDenied: the project's or the cluster's policy forbids the request
+	CodePolicyDenied = "policy_denied"
+	// CodeQuotaExceeded: a storage, artifact or session quota is used up
+	CodeQuotaExceeded = "quota_exceeded"
+	// CodeBudgetExceeded: the project's spend budget is used up
+	CodeBudgetExceeded = "budget_exceeded"
+	// CodeEditConflict: the object changed since the caller read it
+	CodeEditConflict = "edit_conflict"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeInvalidRequest,
+	http.StatusUnauthorized:          CodeUnauthenticated,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusGone:                  CodeGone,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   CodeValidationFailed,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusNotImplemented:        CodeNotImplemented,
+	http.StatusBadGateway:            CodeBadGateway,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeGatewayTimeout,
+}
+
+var titles = map[string]string{
+	CodePolicyDenied:     "Denied by policy",
+	CodeQuotaExceeded:    "Quota exceeded",
+	CodeBudgetExceeded:   "Budget exceeded",
+	CodeEditConflict:     "Edit conflict",
+	CodeValidationFailed: "Validation failed",
+}
+
+// CodeForStatus is the default code of an error status
+func CodeForStatus(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// Problem is one error response. Extensions are extra members, such as
+// fieldErrors, written beside the standard ones.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Code       string
+	TraceID    string
+	Extensions map[string]interface{}
+}
+
+// New describes an error; an empty code is the status's default
+func New(status int, code, detail string) *Problem {
+	if code == "" {
+		code = CodeForStatus(status)
+	}
+	title, ok := titles[code]
+	if !ok {
+		title = http.StatusText(status)
+	}
+	return &Problem{
+		Type:   TypePrefix + code,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// With adds an extension member
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error makes a Problem usable as an error
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON writes the extensions beside the standard members, which they
+// may not replace
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+8)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	m["code"] = p.Code
+	m["error"] = p.Error()
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	if p.TraceID != "" {
+		m["traceId"] = p.TraceID
+	}
+	return json.Marshal(m)
+}
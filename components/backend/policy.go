@@ -0,0 +1,1049 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	// budget.timezone must resolve in images without a zoneinfo database
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"ambient-code-backend/pkg/problem"
+	"ambient-code-shared/money"
+)
+
+// policyFieldManager owns the ProjectSettings fields applied through the settings API
+const policyFieldManager = "ambient-policy-editor"
+
+var (
+	dnsSubdomainPattern    = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	imageRepositoryPattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)*$`)
+	imageTagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	imageDigestPattern     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	allowedDomainPattern   = regexp.MustCompile(`^(\*\.)?[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+$`)
+)
+
+func validCIDR(s string) bool {
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// PolicyFieldError reports one invalid field using its JSON path within spec,
+// e.g. "sessionPolicy.maxTimeoutSeconds", so the UI can place it next to the input.
+type PolicyFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// UpdateProjectPolicyRequest is the body of PUT /settings. ResourceVersion, when
+// set, must match the current object, so edits made since the form was loaded
+// are not overwritten.
+type UpdateProjectPolicyRequest struct {
+	Spec            map[string]interface{} `json:"spec" binding:"required"`
+	ResourceVersion string                 `json:"resourceVersion,omitempty"`
+}
+
+// policyValidator collects field errors while walking a ProjectSettings spec
+type policyValidator struct {
+	errs []PolicyFieldError
+}
+
+func (v *policyValidator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, PolicyFieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// object returns m[key] as a map, reporting a type error when present but not an object
+func (v *policyValidator) object(m map[string]interface{}, parent, key string) (map[string]interface{}, bool) {
+	raw, ok := m[key]
+	if !ok || raw == nil {
+		return nil, false
+	}
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		v.add(joinField(parent, key), "must be an object")
+		return nil, false
+	}
+	return obj, true
+}
+
+// known reports fields that the schema does not define
+func (v *policyValidator) known(m map[string]interface{}, parent string, allowed ...string) {
+	set := map[string]bool{}
+	for _, a := range allowed {
+		set[a] = true
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !set[k] {
+			v.add(joinField(parent, k), "unknown field")
+		}
+	}
+}
+
+func (v *policyValidator) integer(m map[string]interface{}, parent, key string, min, max int64) {
+	if _, ok := m[key]; !ok {
+		return
+	}
+	field := joinField(parent, key)
+	n, ok := intFromSpec(m, key)
+	if f, isFloat := m[key].(float64); !ok || (isFloat && f != float64(int64(f))) {
+		v.add(field, "must be an integer")
+		return
+	}
+	if n < min {
+		v.add(field, "must be at least %d", min)
+	}
+	if max > 0 && n > max {
+		v.add(field, "must be at most %d", max)
+	}
+}
+
+// amount checks a positive dollar amount given as a JSON number with at most
+// six decimals
+func (v *policyValidator) amount(m map[string]interface{}, parent, key string) {
+	raw, ok := m[key]
+	if !ok {
+		return
+	}
+	field := joinField(parent, key)
+	switch n := raw.(type) {
+	case int64:
+	case float64:
+		if money.FromFloat(n).Float64() != n {
+			v.add(field, "must have at most 6 decimals")
+			return
+		}
+	default:
+		v.add(field, "must be a number")
+		return
+	}
+	if a, ok := money.FromSpec(raw); !ok || a <= 0 {
+		v.add(field, "must be greater than 0")
+	}
+}
+
+func (v *policyValidator) boolean(m map[string]interface{}, parent, key string) {
+	if raw, ok := m[key]; ok {
+		if _, ok := raw.(bool); !ok {
+			v.add(joinField(parent, key), "must be true or false")
+		}
+	}
+}
+
+func (v *policyValidator) str(m map[string]interface{}, parent, key string, required bool) string {
+	raw, ok := m[key]
+	if !ok {
+		if required {
+			v.add(joinField(parent, key), "is required")
+		}
+		return ""
+	}
+	s, ok := raw.(string)
+	if !ok {
+		v.add(joinField(parent, key), "must be a string")
+		return ""
+	}
+	if required && strings.TrimSpace(s) == "" {
+		v.add(joinField(parent, key), "is required")
+	}
+	return s
+}
+
+func (v *policyValidator) retentionDuration(m map[string]interface{}, parent, key string) {
+	if s := v.str(m, parent, key, false); s != "" {
+		if _, err := parseRetentionDuration(s); err != nil {
+			v.add(joinField(parent, key), "must be a duration such as 720h or 30d")
+		}
+	}
+}
+
+// validateProjectPolicy checks a ProjectSettings spec against the fields the
+// operator and backend understand, with the same bounds as the CRD schema.
+func validateProjectPolicy(spec map[string]interface{}) []PolicyFieldError {
+	v := &policyValidator{}
+	v.known(spec, "", "groupAccess", "runnerSecretsName", "runnerWorkload", "sessionPolicy", "runnerDisruption",
+		"runnerCanary", "providerKeys", "retention", "storageQuota", "integrations", "notifications", "triggers",
+		"runnerImages", "imagePullSecrets", "runnerScheduling", "gitBootstrap", "network", "modelProviders", "budget", "redaction", "artifactEncryption")
+
+	if raw, ok := spec["groupAccess"]; !ok {
+		v.add("groupAccess", "is required")
+	} else if list, ok := raw.([]interface{}); !ok {
+		v.add("groupAccess", "must be a list")
+	} else {
+		seen := map[string]bool{}
+		for i, item := range list {
+			field := fmt.Sprintf("groupAccess[%d]", i)
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				v.add(field, "must be an object")
+				continue
+			}
+			v.known(m, field, "groupName", "role")
+			group := v.str(m, field, "groupName", true)
+			switch role := v.str(m, field, "role", true); role {
+			case "", "admin", "edit", "view":
+			default:
+				v.add(field+".role", "must be one of admin, edit, view")
+			}
+			if group != "" && seen[group] {
+				v.add(field+".groupName", "group %q is listed more than once", group)
+			}
+			seen[group] = true
+		}
+	}
+
+	if name := v.str(spec, "", "runnerSecretsName", false); name != "" && !dnsSubdomainPattern.MatchString(name) {
+		v.add("runnerSecretsName", "must be a valid Secret name")
+	}
+
+	switch workload := v.str(spec, "", "runnerWorkload", false); workload {
+	case "", "Job", "PipelineRun":
+	default:
+		v.add("runnerWorkload", "must be Job or PipelineRun")
+	}
+
+	if images, ok := v.object(spec, "", "runnerImages"); ok {
+		for fw := range images {
+			field := "runnerImages." + fw
+			o, ok := v.object(images, "runnerImages", fw)
+			if !ok {
+				continue
+			}
+			v.known(o, field, "repository", "tag", "digest")
+			if repo := v.str(o, field, "repository", false); repo != "" && !imageRepositoryPattern.MatchString(repo) {
+				v.add(field+".repository", "must be an image repository without tag or digest, e.g. registry.example.com/ambient/runner")
+			}
+			if tag := v.str(o, field, "tag", false); tag != "" && !imageTagPattern.MatchString(tag) {
+				v.add(field+".tag", "must be a valid image tag")
+			}
+			if digest := v.str(o, field, "digest", false); digest != "" && !imageDigestPattern.MatchString(digest) {
+				v.add(field+".digest", "must be a sha256:<64 hex> digest")
+			}
+		}
+	}
+	if raw, ok := spec["imagePullSecrets"]; ok {
+		list, ok := raw.([]interface{})
+		if !ok {
+			v.add("imagePullSecrets", "must be a list of Secret names")
+		}
+		for i, item := range list {
+			if name, _ := item.(string); !dnsSubdomainPattern.MatchString(name) {
+				v.add(fmt.Sprintf("imagePullSecrets[%d]", i), "must be a valid Secret name")
+			}
+		}
+	}
+
+	if gb, ok := v.object(spec, "", "gitBootstrap"); ok {
+		const p = "gitBootstrap"
+		v.known(gb, p, "enabled", "credentialsSecret", "depth", "baseURLs")
+		v.boolean(gb, p, "enabled")
+		if name := v.str(gb, p, "credentialsSecret", false); name != "" && !dnsSubdomainPattern.MatchString(name) {
+			v.add(p+".credentialsSecret", "must be a valid Secret name")
+		}
+		v.integer(gb, p, "depth", 1, 0)
+		if urls, ok := v.object(gb, p, "baseURLs"); ok {
+			v.known(urls, p+".baseURLs", "github", "gitlab")
+			for _, key := range []string{"github", "gitlab"} {
+				if u := v.str(urls, p+".baseURLs", key, false); u != "" && !strings.Contains(u, "://") {
+					v.add(p+".baseURLs."+key, "must be a URL such as https://github.example.com or ssh://git@github.com")
+				}
+			}
+		}
+	}
+
+	if nw, ok := v.object(spec, "", "network"); ok {
+		const p = "network"
+		v.known(nw, p, "egress", "allowedCIDRs", "allowedDomains")
+		switch egress := v.str(nw, p, "egress", false); egress {
+		case "", "unrestricted", "restricted", "none":
+		default:
+			v.add(p+".egress", "must be one of unrestricted, restricted, none")
+		}
+		if raw, ok := nw["allowedCIDRs"]; ok {
+			list, ok := raw.([]interface{})
+			if !ok {
+				v.add(p+".allowedCIDRs", "must be a list of CIDRs")
+			}
+			for i, item := range list {
+				if cidr, _ := item.(string); !validCIDR(cidr) {
+					v.add(fmt.Sprintf("%s.allowedCIDRs[%d]", p, i), "must be a CIDR such as 10.0.0.0/8")
+				}
+			}
+		}
+		if raw, ok := nw["allowedDomains"]; ok {
+			list, ok := raw.([]interface{})
+			if !ok {
+				v.add(p+".allowedDomains", "must be a list of domains")
+			}
+			for i, item := range list {
+				if d, _ := item.(string); !allowedDomainPattern.MatchString(d) {
+					v.add(fmt.Sprintf("%s.allowedDomains[%d]", p, i), "must be a domain such as api.example.com or *.example.com")
+				}
+			}
+		}
+	}
+
+	if rs, ok := v.object(spec, "", "runnerScheduling"); ok {
+		const p = "runnerScheduling"
+		v.known(rs, p, "nodeSelector", "tolerations", "nodeAffinity", "allowSessionScheduling", "priorityClasses")
+		v.boolean(rs, p, "allowSessionScheduling")
+		if pc, ok := v.object(rs, p, "priorityClasses"); ok {
+			v.known(pc, p+".priorityClasses", sessionPriorities...)
+			for _, key := range sessionPriorities {
+				if name := v.str(pc, p+".priorityClasses", key, false); name != "" && !dnsSubdomainPattern.MatchString(name) {
+					v.add(p+".priorityClasses."+key, "must be a valid PriorityClass name")
+				}
+			}
+		}
+		placement := map[string]interface{}{}
+		for _, key := range []string{"nodeSelector", "tolerations", "nodeAffinity"} {
+			if val, ok := rs[key]; ok {
+				placement[key] = val
+			}
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(placement, &SessionScheduling{}); err != nil {
+			v.add(p, "invalid nodeSelector, tolerations or nodeAffinity: %v", err)
+		}
+	}
+
+	if sp, ok := v.object(spec, "", "sessionPolicy"); ok {
+		const p = "sessionPolicy"
+		v.known(sp, p, "maxExtensions", "maxExtensionSeconds", "maxTimeoutSeconds", "maxConcurrentSessions", "frameworkLimits",
+			"maxResources", "gpuResourceName", "scratch", "blockedModels", "blockedTools", "allowedTools", "maxToolViolations",
+			"maxPriority", "maxDebugKeepAliveSeconds", "workspaceSnapshot", "allowedFrameworks", "blockedFrameworks",
+			"namePattern", "requiredLabels")
+		if mp := v.str(sp, p, "maxPriority", false); mp != "" && !slices.Contains(sessionPriorities, mp) {
+			v.add(p+".maxPriority", "must be one of %s", strings.Join(sessionPriorities, ", "))
+		}
+		for _, key := range []string{"blockedModels", "blockedTools", "allowedTools"} {
+			raw, ok := sp[key]
+			if !ok {
+				continue
+			}
+			list, ok := raw.([]interface{})
+			if !ok {
+				v.add(p+"."+key, "must be a list")
+			}
+			for i, item := range list {
+				s, _ := item.(string)
+				if _, err := path.Match(s, ""); strings.TrimSpace(s) == "" || err != nil {
+					v.add(fmt.Sprintf("%s.%s[%d]", p, key, i), "must be a name or glob")
+				}
+			}
+		}
+		for _, key := range []string{"allowedFrameworks", "blockedFrameworks", "requiredLabels"} {
+			raw, ok := sp[key]
+			if !ok {
+				continue
+			}
+			list, ok := raw.([]interface{})
+			if !ok {
+				v.add(p+"."+key, "must be a list")
+			}
+			for i, item := range list {
+				s, _ := item.(string)
+				switch {
+				case key == "requiredLabels" && len(validation.IsQualifiedName(s)) > 0:
+					v.add(fmt.Sprintf("%s.%s[%d]", p, key, i), "must be a label key")
+				case key != "requiredLabels" && !dnsSubdomainPattern.MatchString(s):
+					v.add(fmt.Sprintf("%s.%s[%d]", p, key, i), "must be a framework name")
+				}
+			}
+		}
+		if pattern := v.str(sp, p, "namePattern", false); pattern != "" {
+			if _, err := regexp.Compile(pattern); err != nil {
+				v.add(p+".namePattern", "must be a valid regular expression: %v", err)
+			}
+		}
+		v.integer(sp, p, "maxToolViolations", 0, 0)
+		if ws, ok := v.object(sp, p, "workspaceSnapshot"); ok {
+			v.known(ws, p+".workspaceSnapshot", "enabled", "maxSize", "ignore")
+			v.boolean(ws, p+".workspaceSnapshot", "enabled")
+			if q := v.str(ws, p+".workspaceSnapshot", "maxSize", false); q != "" {
+				if parsed, err := resource.ParseQuantity(q); err != nil || parsed.Sign() <= 0 {
+					v.add(p+".workspaceSnapshot.maxSize", "must be a positive quantity such as 200Mi")
+				}
+			}
+			if raw, ok := ws["ignore"]; ok {
+				list, ok := raw.([]interface{})
+				if !ok {
+					v.add(p+".workspaceSnapshot.ignore", "must be a list")
+				}
+				for i, item := range list {
+					s, _ := item.(string)
+					if _, err := path.Match(s, ""); strings.TrimSpace(s) == "" || err != nil {
+						v.add(fmt.Sprintf("%s.workspaceSnapshot.ignore[%d]", p, i), "must be a path or glob")
+					}
+				}
+			}
+		}
+		if sc, ok := v.object(sp, p, "scratch"); ok {
+			v.known(sc, p+".scratch", "defaultSize", "maxSize", "storageClass")
+			for _, key := range []string{"defaultSize", "maxSize"} {
+				if q := v.str(sc, p+".scratch", key, false); q != "" {
+					if parsed, err := resource.ParseQuantity(q); err != nil || parsed.Sign() <= 0 {
+						v.add(p+".scratch."+key, "must be a positive quantity such as 20Gi")
+					}
+				}
+			}
+			if name := v.str(sc, p+".scratch", "storageClass", false); name != "" && !dnsSubdomainPattern.MatchString(name) {
+				v.add(p+".scratch.storageClass", "must be a valid StorageClass name")
+			}
+		}
+		v.str(sp, p, "gpuResourceName", false)
+		if mr, ok := v.object(sp, p, "maxResources"); ok {
+			v.known(mr, p+".maxResources", "cpu", "memory", "ephemeralStorage", "gpu")
+			for _, key := range []string{"cpu", "memory", "ephemeralStorage", "gpu"} {
+				if q := v.str(mr, p+".maxResources", key, false); q != "" {
+					if parsed, err := resource.ParseQuantity(q); err != nil || parsed.Sign() <= 0 {
+						v.add(p+".maxResources."+key, "must be a positive quantity such as 4 or 8Gi")
+					}
+				}
+			}
+		}
+		v.integer(sp, p, "maxExtensions", 0, 0)
+		v.integer(sp, p, "maxExtensionSeconds", 1, 0)
+		v.integer(sp, p, "maxTimeoutSeconds", 1, 0)
+		v.integer(sp, p, "maxConcurrentSessions", 1, 0)
+		v.integer(sp, p, "maxDebugKeepAliveSeconds", 0, 0)
+		if fl, ok := v.object(sp, p, "frameworkLimits"); ok {
+			for fw := range fl {
+				field := p + ".frameworkLimits." + fw
+				limits, ok := v.object(fl, p+".frameworkLimits", fw)
+				if !ok {
+					continue
+				}
+				v.known(limits, field, "maxConcurrent", "burst")
+				v.integer(limits, field, "maxConcurrent", 1, 0)
+				v.integer(limits, field, "burst", 1, 0)
+			}
+		}
+	}
+
+	if rd, ok := v.object(spec, "", "runnerDisruption"); ok {
+		const p = "runnerDisruption"
+		v.known(rd, p, "maxUnavailable", "longRunningOnly", "longRunningSeconds", "spread")
+		v.integer(rd, p, "maxUnavailable", 0, 0)
+		v.boolean(rd, p, "longRunningOnly")
+		v.integer(rd, p, "longRunningSeconds", 1, 0)
+		if spread, ok := v.object(rd, p, "spread"); ok {
+			v.known(spread, p+".spread", "topologyKey", "maxSkew", "required")
+			v.str(spread, p+".spread", "topologyKey", false)
+			v.integer(spread, p+".spread", "maxSkew", 1, 0)
+			v.boolean(spread, p+".spread", "required")
+		}
+	}
+
+	if rc, ok := v.object(spec, "", "runnerCanary"); ok {
+		const p = "runnerCanary"
+		v.known(rc, p, "image", "percent", "minSamples", "window", "maxFailureRateDelta", "maxCostIncreasePercent")
+		v.str(rc, p, "image", false)
+		if _, ok := rc["percent"]; ok {
+			v.integer(rc, p, "percent", 0, 100)
+		}
+		v.integer(rc, p, "minSamples", 1, 0)
+		v.integer(rc, p, "window", 1, 0)
+		v.integer(rc, p, "maxFailureRateDelta", 0, 0)
+		v.integer(rc, p, "maxCostIncreasePercent", 0, 0)
+	}
+
+	if pk, ok := v.object(spec, "", "providerKeys"); ok {
+		v.known(pk, "providerKeys", "vault")
+		if vault, ok := v.object(pk, "providerKeys", "vault"); ok {
+			const p = "providerKeys.vault"
+			v.known(vault, p, "address", "authMount", "role", "path", "key", "envName", "ttl")
+			if addr := v.str(vault, p, "address", false); addr != "" && !strings.HasPrefix(addr, "https://") && !strings.HasPrefix(addr, "http://") {
+				v.add(p+".address", "must be an http(s) URL")
+			}
+			v.str(vault, p, "authMount", false)
+			v.str(vault, p, "role", true)
+			v.str(vault, p, "path", true)
+			v.str(vault, p, "key", false)
+			v.str(vault, p, "envName", false)
+			if ttl := v.str(vault, p, "ttl", false); ttl != "" {
+				if _, err := time.ParseDuration(ttl); err != nil {
+					if _, err := strconv.Atoi(ttl); err != nil {
+						v.add(p+".ttl", "must be a duration such as 2h")
+					}
+				}
+			}
+		}
+	}
+
+	if mp, ok := v.object(spec, "", "modelProviders"); ok {
+		const p = "modelProviders"
+		v.known(mp, p, "default", "providers")
+		providers, _ := v.object(mp, p, "providers")
+		if def := v.str(mp, p, "default", false); def != "" {
+			if _, ok := providers[def]; !ok {
+				v.add(p+".default", "must name an entry of providers")
+			}
+		}
+		names := make([]string, 0, len(providers))
+		for name := range providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			field := p + ".providers." + name
+			o, ok := v.object(providers, p+".providers", name)
+			if !ok {
+				continue
+			}
+			v.known(o, field, "type", "secretName", "region", "projectID", "baseURL", "endpoint", "apiVersion", "deployment", "allowedModels")
+			providerType := v.str(o, field, "type", false)
+			if providerType == "" {
+				providerType = name
+			}
+			if !slices.Contains(modelProviderTypes, providerType) {
+				v.add(field+".type", "must be one of %s", strings.Join(modelProviderTypes, ", "))
+			}
+			if secret := v.str(o, field, "secretName", false); secret != "" && !dnsSubdomainPattern.MatchString(secret) {
+				v.add(field+".secretName", "must be a valid Secret name")
+			}
+			for _, key := range []string{"region", "projectID", "apiVersion", "deployment"} {
+				v.str(o, field, key, false)
+			}
+			for _, key := range []string{"baseURL", "endpoint"} {
+				if u := v.str(o, field, key, false); u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+					v.add(field+"."+key, "must be an http(s) URL")
+				}
+			}
+			switch providerType {
+			case "bedrock", "vertex":
+				if v.str(o, field, "region", false) == "" {
+					v.add(field+".region", "is required for %s", providerType)
+				}
+			case "azure-openai":
+				if v.str(o, field, "endpoint", false) == "" {
+					v.add(field+".endpoint", "is required for azure-openai")
+				}
+			}
+			if providerType == "vertex" && v.str(o, field, "projectID", false) == "" {
+				v.add(field+".projectID", "is required for vertex")
+			}
+			if raw, ok := o["allowedModels"]; ok {
+				list, ok := raw.([]interface{})
+				if !ok {
+					v.add(field+".allowedModels", "must be a list of model names or globs")
+				}
+				for i, item := range list {
+					pattern, _ := item.(string)
+					if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+						v.add(fmt.Sprintf("%s.allowedModels[%d]", field, i), "must be a model name or glob such as claude-sonnet-*")
+					}
+				}
+			}
+		}
+	}
+
+	if b, ok := v.object(spec, "", "budget"); ok {
+		v.known(b, "budget", "monthlyLimitUSD", "warnPercent", "resetDay", "timezone", "anomalyZScore")
+		v.amount(b, "budget", "monthlyLimitUSD")
+		v.integer(b, "budget", "warnPercent", 1, 100)
+		if raw, ok := b["anomalyZScore"]; ok {
+			switch raw.(type) {
+			case float64, int64:
+				if numberFromSpec(raw) <= 0 {
+					v.add("budget.anomalyZScore", "must be greater than 0")
+				}
+			default:
+				v.add("budget.anomalyZScore", "must be a number")
+			}
+		}
+		// Days 29-31 do not occur in every month
+		v.integer(b, "budget", "resetDay", 1, 28)
+		if tz := v.str(b, "budget", "timezone", false); tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				v.add("budget.timezone", "must be an IANA time zone such as Europe/Berlin")
+			}
+		}
+	}
+
+	if rd, ok := v.object(spec, "", "redaction"); ok {
+		const p = "redaction"
+		v.known(rd, p, "enabled", "detectors", "patterns")
+		v.boolean(rd, p, "enabled")
+		for _, key := range []string{"detectors", "patterns"} {
+			raw, ok := rd[key]
+			if !ok {
+				continue
+			}
+			list, ok := raw.([]interface{})
+			if !ok {
+				v.add(p+"."+key, "must be a list")
+			}
+			for i, item := range list {
+				field := fmt.Sprintf("%s.%s[%d]", p, key, i)
+				s, _ := item.(string)
+				if key == "detectors" {
+					if !slices.Contains(redactionDetectors, s) {
+						v.add(field, "must be one of %s", strings.Join(redactionDetectors, ", "))
+					}
+				} else if _, err := regexp.Compile(s); s == "" || err != nil {
+					v.add(field, "must be a valid regular expression")
+				}
+			}
+		}
+	}
+
+	if ae, ok := v.object(spec, "", "artifactEncryption"); ok {
+		const p = "artifactEncryption"
+		v.known(ae, p, "enabled", "provider", "kms")
+		v.boolean(ae, p, "enabled")
+		provider := v.str(ae, p, "provider", false)
+		if provider != "" && provider != "secret" && provider != "kms" {
+			v.add(p+".provider", "must be secret or kms")
+		}
+		kms, hasKMS := v.object(ae, p, "kms")
+		if hasKMS {
+			v.known(kms, p+".kms", "url", "keyId")
+			if u := v.str(kms, p+".kms", "url", true); u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+				v.add(p+".kms.url", "must be an http(s) URL")
+			} else if u != "" && !kmsURLAllowed(u) {
+				v.add(p+".kms.url", "must be one of the key services in ARTIFACT_KMS_ALLOWED_URLS")
+			}
+			v.str(kms, p+".kms", "keyId", true)
+		}
+		if provider == "kms" && !hasKMS {
+			v.add(p+".kms", "is required when provider is kms")
+		}
+	}
+
+	if rt, ok := v.object(spec, "", "retention"); ok {
+		v.known(rt, "retention", "sessions", "artifacts", "auditLogs", "scratch", "dryRun")
+		v.retentionDuration(rt, "retention", "scratch")
+		v.retentionDuration(rt, "retention", "sessions")
+		v.retentionDuration(rt, "retention", "artifacts")
+		v.retentionDuration(rt, "retention", "auditLogs")
+		v.boolean(rt, "retention", "dryRun")
+	}
+
+	if sq, ok := v.object(spec, "", "storageQuota"); ok {
+		v.known(sq, "storageQuota", "maxTotalBytes", "maxArtifactsPerSession")
+		v.integer(sq, "storageQuota", "maxTotalBytes", 0, 0)
+		v.integer(sq, "storageQuota", "maxArtifactsPerSession", 0, 0)
+	}
+
+	if in, ok := v.object(spec, "", "integrations"); ok {
+		v.known(in, "integrations", "github", "jira")
+		if gh, ok := v.object(in, "integrations", "github"); ok {
+			const p = "integrations.github"
+			v.known(gh, p, "enabled", "mode", "credentialsSecret", "apiURL", "pullRequests")
+			v.boolean(gh, p, "enabled")
+			v.boolean(gh, p, "pullRequests")
+			switch mode := v.str(gh, p, "mode", false); mode {
+			case "", "comment", "check-run":
+			default:
+				v.add(p+".mode", "must be comment or check-run")
+			}
+			if name := v.str(gh, p, "credentialsSecret", false); name != "" && !dnsSubdomainPattern.MatchString(name) {
+				v.add(p+".credentialsSecret", "must be a valid Secret name")
+			}
+			if u := v.str(gh, p, "apiURL", false); u != "" && !strings.HasPrefix(u, "https://") {
+				v.add(p+".apiURL", "must be an https URL")
+			}
+		}
+		if jira, ok := v.object(in, "integrations", "jira"); ok {
+			const p = "integrations.jira"
+			v.known(jira, p, "enabled", "comment", "transitions")
+			v.boolean(jira, p, "enabled")
+			v.boolean(jira, p, "comment")
+			if raw, ok := jira["transitions"]; ok {
+				rules, ok := raw.([]interface{})
+				if !ok {
+					v.add(p+".transitions", "must be a list")
+				}
+				for i, r := range rules {
+					field := fmt.Sprintf("%s.transitions[%d]", p, i)
+					m, ok := r.(map[string]interface{})
+					if !ok {
+						v.add(field, "must be an object")
+						continue
+					}
+					v.known(m, field, "onPhase", "to")
+					switch phase := v.str(m, field, "onPhase", true); phase {
+					case "", "Completed", "Failed", "Stopped", "Error":
+					default:
+						v.add(field+".onPhase", "must be one of Completed, Failed, Stopped, Error")
+					}
+					v.str(m, field, "to", true)
+				}
+			}
+		}
+	}
+
+	if tr, ok := v.object(spec, "", "triggers"); ok {
+		v.known(tr, "triggers", "teams", "alertmanager", "pagerduty")
+		if teams, ok := v.object(tr, "triggers", "teams"); ok {
+			v.known(teams, "triggers.teams", "enabled", "serviceAccount", "session")
+			v.inboundTrigger(teams, "triggers.teams")
+		}
+		if am, ok := v.object(tr, "triggers", "alertmanager"); ok {
+			const p = "triggers.alertmanager"
+			v.known(am, p, "enabled", "serviceAccount", "session", "instructions", "matchLabels")
+			v.inboundTrigger(am, p)
+			v.str(am, p, "instructions", false)
+			if match, ok := v.object(am, p, "matchLabels"); ok {
+				if len(match) == 0 {
+					v.add(p+".matchLabels", "must not be empty")
+				}
+				for k, val := range match {
+					if _, ok := val.(string); !ok {
+						v.add(p+".matchLabels."+k, "must be a string")
+					}
+				}
+			} else if enabled, _ := am["enabled"].(bool); enabled {
+				v.add(p+".matchLabels", "is required")
+			}
+		}
+		if pd, ok := v.object(tr, "triggers", "pagerduty"); ok {
+			const p = "triggers.pagerduty"
+			v.known(pd, p, "enabled", "serviceAccount", "session", "instructions", "serviceIds")
+			v.inboundTrigger(pd, p)
+			v.str(pd, p, "instructions", false)
+			ids, ok := pd["serviceIds"].([]interface{})
+			if _, present := pd["serviceIds"]; present && !ok {
+				v.add(p+".serviceIds", "must be a list")
+			}
+			for i, raw := range ids {
+				if id, _ := raw.(string); strings.TrimSpace(id) == "" {
+					v.add(fmt.Sprintf("%s.serviceIds[%d]", p, i), "must be a non-empty string")
+				}
+			}
+			if enabled, _ := pd["enabled"].(bool); enabled && len(ids) == 0 {
+				v.add(p+".serviceIds", "must list at least one service")
+			}
+		}
+	}
+
+	if nt, ok := v.object(spec, "", "notifications"); ok {
+		v.known(nt, "notifications", "webhooks", "email", "usageReport")
+		v.boolean(nt, "notifications", "usageReport")
+		if raw, ok := nt["webhooks"]; ok {
+			hooks, ok := raw.([]interface{})
+			if !ok {
+				v.add("notifications.webhooks", "must be a list")
+			}
+			for i, h := range hooks {
+				field := fmt.Sprintf("notifications.webhooks[%d]", i)
+				m, ok := h.(map[string]interface{})
+				if !ok {
+					v.add(field, "must be an object")
+					continue
+				}
+				v.known(m, field, "name", "url", "type", "events", "secretRef")
+				v.str(m, field, "name", false)
+				if u := v.str(m, field, "url", true); u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+					v.add(field+".url", "must be an http(s) URL")
+				}
+				switch t := v.str(m, field, "type", false); t {
+				case "", "slack", "teams", "http":
+				default:
+					v.add(field+".type", "must be slack, teams or http")
+				}
+				v.notificationEvents(m, field)
+				if ref, ok := v.object(m, field, "secretRef"); ok {
+					v.known(ref, field+".secretRef", "name", "key")
+					v.str(ref, field+".secretRef", "name", true)
+					v.str(ref, field+".secretRef", "key", true)
+				}
+			}
+		}
+		if email, ok := v.object(nt, "notifications", "email"); ok {
+			v.emailSettings(email)
+		}
+	}
+
+	return v.errs
+}
+
+// inboundTrigger checks the fields every spec.triggers entry shares
+func (v *policyValidator) inboundTrigger(m map[string]interface{}, field string) {
+	v.boolean(m, field, "enabled")
+	enabled, _ := m["enabled"].(bool)
+	if sa := v.str(m, field, "serviceAccount", enabled); sa != "" && !dnsSubdomainPattern.MatchString(sa) {
+		v.add(field+".serviceAccount", "must be a valid ServiceAccount name")
+	}
+	if session, ok := v.object(m, field, "session"); ok {
+		if err := triggerSessionTemplate(session, &CreateAgenticSessionRequest{}); err != nil {
+			v.add(field+".session", "%v", err)
+		}
+	}
+}
+
+func (v *policyValidator) emailSettings(m map[string]interface{}) {
+	const field = "notifications.email"
+	v.known(m, field, "recipients", "events", "mode", "digestInterval", "templates")
+	raw, ok := m["recipients"].([]interface{})
+	if !ok || len(raw) == 0 {
+		v.add(field+".recipients", "must be a non-empty list of email addresses")
+	}
+	for i, r := range raw {
+		addr, _ := r.(string)
+		if _, err := mail.ParseAddress(addr); err != nil {
+			v.add(fmt.Sprintf("%s.recipients[%d]", field, i), "must be an email address")
+		}
+	}
+	v.notificationEvents(m, field)
+	switch mode := v.str(m, field, "mode", false); mode {
+	case "", "immediate", "digest":
+	default:
+		v.add(field+".mode", "must be immediate or digest")
+	}
+	if d := v.str(m, field, "digestInterval", false); d != "" {
+		if parsed, err := time.ParseDuration(d); err != nil || parsed < time.Minute {
+			v.add(field+".digestInterval", "must be a duration of at least 1m, e.g. 1h")
+		}
+	}
+	if templates, ok := v.object(m, field, "templates"); ok {
+		events := make([]string, 0, len(templates))
+		for event := range templates {
+			events = append(events, event)
+		}
+		sort.Strings(events)
+		for _, event := range events {
+			t := templates[event]
+			tf := field + ".templates." + event
+			known := false
+			for _, name := range notificationEventNames {
+				known = known || name == event
+			}
+			if !known {
+				v.add(tf, "must be one of %s", strings.Join(notificationEventNames, ", "))
+				continue
+			}
+			tm, ok := t.(map[string]interface{})
+			if !ok {
+				v.add(tf, "must be an object")
+				continue
+			}
+			v.known(tm, tf, "subject", "body")
+			for _, key := range []string{"subject", "body"} {
+				if text := v.str(tm, tf, key, false); text != "" {
+					if _, err := template.New(key).Parse(text); err != nil {
+						v.add(tf+"."+key, "invalid template: %v", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// notificationEventNames are the events a notification channel can subscribe to
+var notificationEventNames = []string{"session.created", "session.completed", "session.failed", "budget.warning", "budget.exceeded", "report.usage"}
+
+func (v *policyValidator) notificationEvents(m map[string]interface{}, parent string) {
+	raw, ok := m["events"]
+	if !ok {
+		return
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		v.add(parent+".events", "must be a list")
+		return
+	}
+	for i, e := range list {
+		name, _ := e.(string)
+		valid := false
+		for _, known := range notificationEventNames {
+			if name == known || (strings.HasSuffix(name, ".*") && strings.HasPrefix(known, strings.TrimSuffix(name, "*"))) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			v.add(fmt.Sprintf("%s.events[%d]", parent, i), "must be one of %s", strings.Join(notificationEventNames, ", "))
+		}
+	}
+}
+
+// GET /api/projects/:projectName/settings
+// getProjectPolicy returns the ProjectSettings spec with its resourceVersion for a
+// later conditional PUT.
+func getProjectPolicy(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	obj, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "ProjectSettings not found for project")
+			return
+		}
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	delete(spec, "webhookAuth")
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+	c.JSON(http.StatusOK, gin.H{
+		"spec":            spec,
+		"status":          status,
+		"resourceVersion": obj.GetResourceVersion(),
+	})
+}
+
+// validatePolicyUpdate compares a submitted spec with the current ProjectSettings
+// (nil when none exists yet): changed fields must respect the cluster policy, and
+// the monthly budget may not be lowered below this month's spend.
+func validatePolicyUpdate(current *unstructured.Unstructured, spec map[string]interface{}, cp ClusterPolicy) []PolicyFieldError {
+	var currentSpec map[string]interface{}
+	if current != nil {
+		currentSpec, _, _ = unstructured.NestedMap(current.Object, "spec")
+	}
+	errs := validateAgainstClusterPolicy(currentSpec, spec, cp)
+	raw, found, _ := unstructured.NestedFieldNoCopy(spec, "budget", "monthlyLimitUSD")
+	old, _, _ := unstructured.NestedFieldNoCopy(currentSpec, "budget", "monthlyLimitUSD")
+	limit, _ := money.FromSpec(raw)
+	oldLimit, _ := money.FromSpec(old)
+	if found && limit != oldLimit {
+		if spent := monthlySpend(current); limit < spent {
+			errs = append(errs, PolicyFieldError{
+				Field:   "budget.monthlyLimitUSD",
+				Message: fmt.Sprintf("must be at least %s, the amount already spent this month", spent.Cents()),
+			})
+		}
+	}
+	return errs
+}
+
+// PUT /api/projects/:projectName/settings?dryRun=true&force=true
+// updateProjectPolicy validates the submitted spec and applies it with server-side
+// apply under the caller's identity. Validation failures return 422 with
+// fieldErrors; a stale resourceVersion or fields owned by another manager (for
+// example a kubectl edit) return 409 unless force is set.
+func updateProjectPolicy(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req UpdateProjectPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	// API keys are managed through /apikeys; leaving them out of the applied
+	// configuration keeps them untouched, since the editor never owns them.
+	delete(req.Spec, "webhookAuth")
+	if fieldErrors := validateProjectPolicy(req.Spec); len(fieldErrors) > 0 {
+		auditDeny(c, "policy validation failed")
+		respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", "Project settings are invalid").With("fieldErrors", fieldErrors))
+		return
+	}
+
+	// Projects may only tighten the organization-wide policy, and limits may not
+	// drop below what has already been used
+	clusterPolicy, err := loadClusterPolicy(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return
+	}
+	current, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Get(c.Request.Context(), "projectsettings", v1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	if errors.IsNotFound(err) {
+		current = nil
+	}
+	if fieldErrors := validatePolicyUpdate(current, req.Spec, clusterPolicy); len(fieldErrors) > 0 {
+		auditDeny(c, "project settings loosen the cluster policy or undercut current usage")
+		respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", "Project settings are invalid").With("fieldErrors", fieldErrors))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+	force, _ := strconv.ParseBool(c.Query("force"))
+
+	metadata := map[string]interface{}{"name": "projectsettings", "namespace": project}
+	if req.ResourceVersion != "" {
+		metadata["resourceVersion"] = req.ResourceVersion
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ProjectSettings",
+		"metadata":   metadata,
+		"spec":       req.Spec,
+	}}
+	opts := v1.ApplyOptions{FieldManager: policyFieldManager, Force: force}
+	if dryRun {
+		opts.DryRun = []string{v1.DryRunAll}
+	}
+
+	applied, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Apply(c.Request.Context(), "projectsettings", obj, opts)
+	if err != nil {
+		switch {
+		case errors.IsConflict(err):
+			p := problem.New(http.StatusConflict, problem.CodeEditConflict, "Project settings were changed by someone else; reload and retry, or force to take ownership")
+			if status, ok := err.(errors.APIStatus); ok && status.Status().Details != nil {
+				conflicts := make([]PolicyFieldError, 0, len(status.Status().Details.Causes))
+				for _, cause := range status.Status().Details.Causes {
+					conflicts = append(conflicts, PolicyFieldError{Field: strings.TrimPrefix(cause.Field, ".spec."), Message: cause.Message})
+				}
+				p.With("conflicts", conflicts)
+			}
+			respondProblem(c, p)
+		case errors.IsInvalid(err):
+			fieldErrors := []PolicyFieldError{}
+			if status, ok := err.(errors.APIStatus); ok && status.Status().Details != nil {
+				for _, cause := range status.Status().Details.Causes {
+					fieldErrors = append(fieldErrors, PolicyFieldError{Field: strings.TrimPrefix(cause.Field, "spec."), Message: cause.Message})
+				}
+			}
+			respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", "Project settings are invalid").With("fieldErrors", fieldErrors))
+		case errors.IsForbidden(err):
+			respondError(c, http.StatusForbidden, "Not permitted to edit project settings")
+		default:
+			logErrorf(c, "Failed to apply ProjectSettings in %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to update project settings")
+		}
+		return
+	}
+
+	if dryRun {
+		auditDetail(c, "dryRun", true)
+	}
+	spec, _, _ := unstructured.NestedMap(applied.Object, "spec")
+	delete(spec, "webhookAuth")
+	c.JSON(http.StatusOK, gin.H{
+		"spec":            spec,
+		"resourceVersion": applied.GetResourceVersion(),
+		"dryRun":          dryRun,
+	})
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"ambient-code-backend/pkg/problem"
+	"ambient-code-shared/money"
+)
+
+// modelPricingName is the singleton ModelPricing the backend and operator
+// price token usage with
+const modelPricingName = "default"
+
+const pricingFieldManager = "ambient-pricing-editor"
+
+// tokensPerPriceUnit is the number of tokens prices are quoted for
+const tokensPerPriceUnit = 1_000_000
+
+// ModelPrice is the price of a model, or of every model a glob such as
+// claude-sonnet-* matches, in USD per million tokens
+type ModelPrice struct {
+	Model               string    `json:"model"`
+	InputUSDPerMillion  money.USD `json:"inputUSDPerMillion"`
+	OutputUSDPerMillion money.USD `json:"outputUSDPerMillion"`
+}
+
+// ModelPricing is the body of GET and PUT /api/model-pricing. A session's model
+// is priced by the first entry that matches it; sessions of unpriced models
+// keep the cost their runner reported. ResourceVersion, when sent back on PUT,
+// rejects the update if the pricing changed in between.
+type ModelPricing struct {
+	Models          []ModelPrice `json:"models"`
+	ResourceVersion string       `json:"resourceVersion,omitempty"`
+}
+
+// getModelPricingResource returns the GroupVersionResource for the cluster-scoped ModelPricing
+func getModelPricingResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "modelpricings",
+	}
+}
+
+var (
+	modelPricingClientOnce sync.Once
+	modelPricingClient     dynamic.Interface
+	modelPricingClientErr  error
+
+	modelPricingMu       sync.Mutex
+	modelPricingCache    ModelPricing
+	modelPricingLoadedAt time.Time
+)
+
+// loadModelPricing reads the ModelPricing with the backend ServiceAccount; a
+// missing object (or CRD) prices nothing
+func loadModelPricing(ctx context.Context) (ModelPricing, error) {
+	p := ModelPricing{Models: []ModelPrice{}}
+	modelPricingClientOnce.Do(func() {
+		modelPricingClient, modelPricingClientErr = dynamic.NewForConfig(baseKubeConfig)
+	})
+	if modelPricingClientErr != nil {
+		return p, modelPricingClientErr
+	}
+	obj, err := modelPricingClient.Resource(getModelPricingResource()).Get(ctx, modelPricingName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	return modelPricingFromObject(obj), nil
+}
+
+func modelPricingFromObject(obj *unstructured.Unstructured) ModelPricing {
+	p := ModelPricing{Models: []ModelPrice{}, ResourceVersion: obj.GetResourceVersion()}
+	models, _, _ := unstructured.NestedSlice(obj.Object, "spec", "models")
+	for _, raw := range models {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		price := ModelPrice{}
+		price.Model, _ = m["model"].(string)
+		price.InputUSDPerMillion, _ = money.FromSpec(m["inputUSDPerMillion"])
+		price.OutputUSDPerMillion, _ = money.FromSpec(m["outputUSDPerMillion"])
+		if price.Model != "" {
+			p.Models = append(p.Models, price)
+		}
+	}
+	return p
+}
+
+// currentModelPricing returns the pricing, reloaded at most every
+// MODEL_PRICING_REFRESH (default 30s) so price changes apply without a restart.
+// A failed reload keeps the last pricing read.
+func currentModelPricing() ModelPricing {
+	modelPricingMu.Lock()
+	defer modelPricingMu.Unlock()
+	if time.Since(modelPricingLoadedAt) < durationFromEnv("MODEL_PRICING_REFRESH", 30*time.Second) {
+		return modelPricingCache
+	}
+	modelPricingLoadedAt = time.Now()
+	p, err := loadModelPricing(context.Background())
+	if err != nil {
+		log.Printf("Failed to reload ModelPricing, keeping the previous prices: %v", err)
+		return modelPricingCache
+	}
+	modelPricingCache = p
+	return p
+}
+
+// invalidateModelPricing makes the next currentModelPricing reload, so this
+// replica applies its own updates at once
+func invalidateModelPricing() {
+	modelPricingMu.Lock()
+	modelPricingLoadedAt = time.Time{}
+	modelPricingMu.Unlock()
+}
+
+// price returns the first entry matching model, by name or glob
+func (p ModelPricing) price(model string) (ModelPrice, bool) {
+	if strings.TrimSpace(model) == "" {
+		return ModelPrice{}, false
+	}
+	for _, m := range p.Models {
+		if ok, _ := path.Match(m.Model, model); ok {
+			return m, true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// cost prices token counts exactly, rounding each direction to the nearest
+// millionth of a dollar
+func (m ModelPrice) cost(inputTokens, outputTokens int64) money.USD {
+	return m.InputUSDPerMillion.MulDiv(inputTokens, tokensPerPriceUnit) + m.OutputUSDPerMillion.MulDiv(outputTokens, tokensPerPriceUnit)
+}
+
+// pricedSessionCost prices a session's reported token usage at its model's
+// ModelPricing entry, and the runner's title and summary calls (usage.helper_models)
+// at theirs or else the session model's. ok is false for unpriced models and
+// sessions without token counts.
+func pricedSessionCost(obj *unstructured.Unstructured) (money.USD, bool) {
+	usage, found, _ := unstructured.NestedMap(obj.Object, "status", "usage")
+	if !found {
+		return 0, false
+	}
+	_, hasInput := usage["input_tokens"]
+	_, hasOutput := usage["output_tokens"]
+	if !hasInput && !hasOutput {
+		return 0, false
+	}
+	model, _, _ := unstructured.NestedString(obj.Object, "spec", "llmSettings", "model")
+	pricing := currentModelPricing()
+	price, ok := pricing.price(model)
+	if !ok {
+		return 0, false
+	}
+	cost := price.cost(int64(numberFromSpec(usage["input_tokens"])), int64(numberFromSpec(usage["output_tokens"])))
+	helpers, _, _ := unstructured.NestedMap(usage, "helper_models")
+	for helperModel, raw := range helpers {
+		tokens, _ := raw.(map[string]interface{})
+		helperPrice, ok := pricing.price(helperModel)
+		if !ok {
+			helperPrice = price
+		}
+		cost += helperPrice.cost(int64(numberFromSpec(tokens["input_tokens"])), int64(numberFromSpec(tokens["output_tokens"])))
+	}
+	return cost, true
+}
+
+// validateModelPrices checks an update: models named once each by a valid glob,
+// with non-negative prices
+func validateModelPrices(models []ModelPrice) []PolicyFieldError {
+	errs := []PolicyFieldError{}
+	seen := map[string]bool{}
+	for i, m := range models {
+		field := fmt.Sprintf("models[%d]", i)
+		if _, err := path.Match(m.Model, ""); strings.TrimSpace(m.Model) == "" || err != nil {
+			errs = append(errs, PolicyFieldError{Field: field + ".model", Message: "must be a model name or glob"})
+		} else if seen[m.Model] {
+			errs = append(errs, PolicyFieldError{Field: field + ".model", Message: fmt.Sprintf("%s is priced more than once", m.Model)})
+		}
+		seen[m.Model] = true
+		if m.InputUSDPerMillion < 0 {
+			errs = append(errs, PolicyFieldError{Field: field + ".inputUSDPerMillion", Message: "must not be negative"})
+		}
+		if m.OutputUSDPerMillion < 0 {
+			errs = append(errs, PolicyFieldError{Field: field + ".outputUSDPerMillion", Message: "must not be negative"})
+		}
+	}
+	return errs
+}
+
+// applyModelPricing writes the whole table with the caller's client, so only
+// those allowed to update ModelPricing (cluster administrators) can
+func applyModelPricing(c *gin.Context, reqDyn dynamic.Interface, p ModelPricing) {
+	if fieldErrors := validateModelPrices(p.Models); len(fieldErrors) > 0 {
+		auditDeny(c, "model pricing validation failed")
+		respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", "Model pricing is invalid").With("fieldErrors", fieldErrors))
+		return
+	}
+	models := make([]interface{}, 0, len(p.Models))
+	for _, m := range p.Models {
+		models = append(models, map[string]interface{}{
+			"model":               m.Model,
+			"inputUSDPerMillion":  m.InputUSDPerMillion.String(),
+			"outputUSDPerMillion": m.OutputUSDPerMillion.String(),
+		})
+	}
+	metadata := map[string]interface{}{"name": modelPricingName}
+	if p.ResourceVersion != "" {
+		metadata["resourceVersion"] = p.ResourceVersion
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ModelPricing",
+		"metadata":   metadata,
+		"spec":       map[string]interface{}{"models": models},
+	}}
+	applied, err := reqDyn.Resource(getModelPricingResource()).Apply(c.Request.Context(), modelPricingName, obj, v1.ApplyOptions{FieldManager: pricingFieldManager, Force: true})
+	if err != nil {
+		switch {
+		case errors.IsConflict(err):
+			respondError(c, http.StatusConflict, "Model pricing was changed by someone else; reload and retry")
+		case errors.IsForbidden(err):
+			auditDeny(c, "modelPricing.admin")
+			respondError(c, http.StatusForbidden, "Only cluster administrators may update model pricing")
+		default:
+			logErrorf(c, "Failed to apply ModelPricing: %v", err)
+			respondError(c, http.StatusInternalServerError, "Failed to update model pricing")
+		}
+		return
+	}
+	invalidateModelPricing()
+	c.JSON(http.StatusOK, modelPricingFromObject(applied))
+}
+
+// currentModelPricingForUpdate reads the pricing to modify, with its
+// resourceVersion so a concurrent update conflicts
+func currentModelPricingForUpdate(c *gin.Context, reqDyn dynamic.Interface) (ModelPricing, bool) {
+	obj, err := reqDyn.Resource(getModelPricingResource()).Get(c.Request.Context(), modelPricingName, v1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return ModelPricing{Models: []ModelPrice{}}, true
+	case errors.IsForbidden(err):
+		respondError(c, http.StatusForbidden, "Only cluster administrators may update model pricing")
+		return ModelPricing{}, false
+	case err != nil:
+		logErrorf(c, "Failed to read ModelPricing: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read model pricing")
+		return ModelPricing{}, false
+	}
+	return modelPricingFromObject(obj), true
+}
+
+// GET /api/model-pricing
+// getModelPricing returns the per-model token prices session costs are
+// computed with.
+func getModelPricing(c *gin.Context) {
+	if reqK8s, _ := getK8sClientsForRequest(c); reqK8s == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	p, err := loadModelPricing(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ModelPricing: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read model pricing")
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// PUT /api/model-pricing
+// updateModelPricing replaces the price table. New prices apply to cost
+// tracking and estimates within MODEL_PRICING_REFRESH on every replica and the
+// operator, including to sessions already finished.
+func updateModelPricing(c *gin.Context) {
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req ModelPricing
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Models == nil {
+		req.Models = []ModelPrice{}
+	}
+	applyModelPricing(c, reqDyn, req)
+}
+
+// PUT /api/model-pricing/models/:model
+// setModelPrice adds or replaces the entry for one model name or glob; new
+// entries are matched after the existing ones.
+func setModelPrice(c *gin.Context) {
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req ModelPrice
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Model = c.Param("model")
+	p, ok := currentModelPricingForUpdate(c, reqDyn)
+	if !ok {
+		return
+	}
+	replaced := false
+	for i := range p.Models {
+		if p.Models[i].Model == req.Model {
+			p.Models[i] = req
+			replaced = true
+		}
+	}
+	if !replaced {
+		p.Models = append(p.Models, req)
+	}
+	auditDetail(c, "model", req.Model)
+	applyModelPricing(c, reqDyn, p)
+}
+
+// DELETE /api/model-pricing/models/:model
+// deleteModelPrice removes the entry for one model name or glob; its sessions
+// fall back to the next matching entry or their reported cost.
+func deleteModelPrice(c *gin.Context) {
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	model := c.Param("model")
+	p, ok := currentModelPricingForUpdate(c, reqDyn)
+	if !ok {
+		return
+	}
+	kept := make([]ModelPrice, 0, len(p.Models))
+	for _, m := range p.Models {
+		if m.Model != model {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == len(p.Models) {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("%s is not priced", model))
+		return
+	}
+	p.Models = kept
+	auditDetail(c, "model", model)
+	applyModelPricing(c, reqDyn, p)
+}
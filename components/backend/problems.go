@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+// respondProblem writes p as application/problem+json, with the request path
+// as its instance and the request's trace ID
+func respondProblem(c *gin.Context, p *problem.Problem) {
+	if p.Instance == "" && c.Request != nil && c.Request.URL != nil {
+		p.Instance = c.Request.URL.Path
+	}
+	if v, ok := c.Get("span"); ok {
+		if s, ok := v.(*span); ok {
+			p.TraceID = s.traceID
+		}
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		body, _ = json.Marshal(problem.New(http.StatusInternalServerError, "", "Failed to encode error response"))
+	}
+	c.Data(p.Status, problem.ContentType, body)
+}
+
+// respondError answers status with its default problem code
+func respondError(c *gin.Context, status int, detail string) {
+	respondProblem(c, problem.New(status, "", detail))
+}
+
+// abortWithError answers status and skips the remaining handlers
+func abortWithError(c *gin.Context, status int, detail string) {
+	c.Abort()
+	respondError(c, status, detail)
+}
+
+// abortWithProblem answers p and skips the remaining handlers
+func abortWithProblem(c *gin.Context, p *problem.Problem) {
+	c.Abort()
+	respondProblem(c, p)
+}
+
+// recoveryProblem answers a panicking handler with a problem document rather
+// than an empty 500
+func recoveryProblem(c *gin.Context, _ any) {
+	abortWithError(c, http.StatusInternalServerError, "Internal server error")
+}
+
+// noRouteProblem answers unknown routes and methods with problem documents
+func noRouteProblem(status int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status == http.StatusMethodNotAllowed {
+			respondError(c, status, c.Request.Method+" is not allowed on "+c.Request.URL.Path)
+			return
+		}
+		respondError(c, status, "No route for "+c.Request.URL.Path)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const projectAccessCacheMaxEntries = 4096
+
+// projectPermissions are the capabilities reported per project, each backed by
+// one RBAC check in the project namespace
+var projectPermissions = []struct {
+	name                  string
+	group, resource, verb string
+}{
+	{"sessions:list", "vteam.ambient-code", "agenticsessions", "list"},
+	{"sessions:create", "vteam.ambient-code", "agenticsessions", "create"},
+	{"sessions:delete", "vteam.ambient-code", "agenticsessions", "delete"},
+	{"settings:update", "vteam.ambient-code", "projectsettings", "update"},
+	{"permissions:manage", "rbac.authorization.k8s.io", "rolebindings", "create"},
+}
+
+// ProjectAccess is what the caller may do in a project according to RBAC.
+// Role summarizes it: admin may update ProjectSettings, edit may create
+// sessions and view may list them; it is empty without any of those.
+type ProjectAccess struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+func (a ProjectAccess) has(permission string) bool {
+	return slices.Contains(a.Permissions, permission)
+}
+
+type cachedProjectAccess struct {
+	access  ProjectAccess
+	expires time.Time
+}
+
+var (
+	projectAccessCacheMu sync.Mutex
+	projectAccessCache   = map[string]cachedProjectAccess{}
+)
+
+// projectAccessForCaller resolves the caller's permissions in namespace with a
+// SelfSubjectRulesReview, confirming with SelfSubjectAccessReviews whatever an
+// incomplete rule list leaves open. Results are cached per hash of the
+// caller's credential (see callerCredential) and namespace for
+// PROJECT_ACCESS_CACHE_TTL (default 30s), so RBAC changes show up within that time.
+func projectAccessForCaller(ctx context.Context, reqK8s kubernetes.Interface, credential, namespace string) (ProjectAccess, error) {
+	sum := sha256.Sum256([]byte(credential))
+	cacheKey := hex.EncodeToString(sum[:]) + "/" + namespace
+	now := time.Now()
+	projectAccessCacheMu.Lock()
+	if cached, ok := projectAccessCache[cacheKey]; ok && now.Before(cached.expires) {
+		projectAccessCacheMu.Unlock()
+		return cached.access, nil
+	}
+	projectAccessCacheMu.Unlock()
+
+	review := &authv1.SelfSubjectRulesReview{Spec: authv1.SelfSubjectRulesReviewSpec{Namespace: namespace}}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, review, v1.CreateOptions{})
+	if err != nil {
+		return ProjectAccess{}, err
+	}
+	access := ProjectAccess{Permissions: []string{}}
+	for _, p := range projectPermissions {
+		allowed := rulesAllow(res.Status.ResourceRules, p.group, p.resource, p.verb)
+		if !allowed && res.Status.Incomplete {
+			ssar := &authv1.SelfSubjectAccessReview{
+				Spec: authv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authv1.ResourceAttributes{Group: p.group, Resource: p.resource, Verb: p.verb, Namespace: namespace},
+				},
+			}
+			r, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, ssar, v1.CreateOptions{})
+			if err != nil {
+				return ProjectAccess{}, err
+			}
+			allowed = r.Status.Allowed
+		}
+		if allowed {
+			access.Permissions = append(access.Permissions, p.name)
+		}
+	}
+	switch {
+	case access.has("settings:update"):
+		access.Role = "admin"
+	case access.has("sessions:create"):
+		access.Role = "edit"
+	case access.has("sessions:list"):
+		access.Role = "view"
+	}
+
+	projectAccessCacheMu.Lock()
+	if len(projectAccessCache) >= projectAccessCacheMaxEntries {
+		projectAccessCache = map[string]cachedProjectAccess{}
+	}
+	projectAccessCache[cacheKey] = cachedProjectAccess{access: access, expires: now.Add(durationFromEnv("PROJECT_ACCESS_CACHE_TTL", 30*time.Second))}
+	projectAccessCacheMu.Unlock()
+	return access, nil
+}
+
+// rulesAllow reports whether a rule grants verb on every object of the
+// resource; rules limited to resourceNames do not count
+func rulesAllow(rules []authv1.ResourceRule, group, resource, verb string) bool {
+	matches := func(values []string, want string) bool {
+		return slices.Contains(values, want) || slices.Contains(values, "*")
+	}
+	for _, r := range rules {
+		if len(r.ResourceNames) == 0 && matches(r.APIGroups, group) && matches(r.Resources, resource) && matches(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// managedNamespaces returns the namespaces labeled as Ambient projects, from
+// the informer cache once synced
+func managedNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	selector := labels.SelectorFromSet(labels.Set{"ambient-code.io/managed": "true"})
+	if informersSynced.Load() {
+		cached, err := namespaceLister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]corev1.Namespace, 0, len(cached))
+		for _, ns := range cached {
+			out = append(out, *ns.DeepCopy())
+		}
+		return out, nil
+	}
+	list, err := k8sClient.CoreV1().Namespaces().List(ctx, v1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// maxPullRequestChangeBytes caps the decoded file contents of one change set
+	maxPullRequestChangeBytes = 20 << 20
+	maxPullRequestFiles       = 1000
+	maxPullRequestsPerSession = 20
+)
+
+var (
+	gitBranchPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,199}$`)
+	gitSHAPattern    = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// PullRequestFileChange is one file of a change set: its new content, base64
+// encoded, or its deletion
+type PullRequestFileChange struct {
+	Path       string `json:"path"`
+	Content    string `json:"content,omitempty"`
+	Deleted    bool   `json:"deleted,omitempty"`
+	Executable bool   `json:"executable,omitempty"`
+}
+
+// CreatePullRequestRequest is a runner's request to commit Files on top of
+// BaseSHA of BaseBranch, push them as Branch and open a pull request
+type CreatePullRequestRequest struct {
+	Repo       string                  `json:"repo" binding:"required"`
+	BaseBranch string                  `json:"baseBranch" binding:"required"`
+	BaseSHA    string                  `json:"baseSha" binding:"required"`
+	Branch     string                  `json:"branch" binding:"required"`
+	Title      string                  `json:"title" binding:"required"`
+	Body       string                  `json:"body,omitempty"`
+	Draft      bool                    `json:"draft,omitempty"`
+	Files      []PullRequestFileChange `json:"files" binding:"required"`
+}
+
+// SessionPullRequest is an entry of status.pullRequests. The operator opens
+// Pending entries with the project's GitHub credentials and records the outcome.
+type SessionPullRequest struct {
+	Repo        string `json:"repo"`
+	Branch      string `json:"branch"`
+	BaseBranch  string `json:"baseBranch"`
+	Title       string `json:"title,omitempty"`
+	ChangeSet   string `json:"changeSet,omitempty"`
+	State       string `json:"state"`
+	Number      int64  `json:"number,omitempty"`
+	URL         string `json:"url,omitempty"`
+	CommitSHA   string `json:"commitSha,omitempty"`
+	RequestedAt string `json:"requestedAt,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+func parseSessionPullRequest(m map[string]interface{}) SessionPullRequest {
+	pr := SessionPullRequest{}
+	pr.Repo, _ = m["repo"].(string)
+	pr.Branch, _ = m["branch"].(string)
+	pr.BaseBranch, _ = m["baseBranch"].(string)
+	pr.Title, _ = m["title"].(string)
+	pr.ChangeSet, _ = m["changeSet"].(string)
+	pr.State, _ = m["state"].(string)
+	pr.Number, _ = intFromSpec(m, "number")
+	pr.URL, _ = m["url"].(string)
+	pr.CommitSHA, _ = m["commitSha"].(string)
+	pr.RequestedAt, _ = m["requestedAt"].(string)
+	pr.Message, _ = m["message"].(string)
+	return pr
+}
+
+// normalizeRepoURL reduces https, ssh and scp-style git URLs to host/owner/repo
+// so a requested repository can be matched against spec.gitConfig.repositories
+func normalizeRepoURL(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	u = strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if strings.HasPrefix(u, "git@") {
+		u = strings.Replace(u, ":", "/", 1)
+	}
+	if i := strings.LastIndex(u, "@"); i >= 0 {
+		u = u[i+1:]
+	}
+	return u
+}
+
+// validatePullRequestChange returns a user-facing message for an invalid request
+func validatePullRequestChange(req *CreatePullRequestRequest) string {
+	if !gitSHAPattern.MatchString(req.BaseSHA) {
+		return "baseSha must be a full commit SHA"
+	}
+	for _, b := range []string{req.Branch, req.BaseBranch} {
+		if !gitBranchPattern.MatchString(b) || strings.Contains(b, "..") || strings.Contains(b, "//") ||
+			strings.HasSuffix(b, "/") || strings.HasSuffix(b, ".lock") {
+			return fmt.Sprintf("invalid branch name %q", b)
+		}
+	}
+	if req.Branch == req.BaseBranch {
+		return "branch must differ from baseBranch"
+	}
+	if len(req.Files) == 0 {
+		return "files must list at least one change"
+	}
+	if len(req.Files) > maxPullRequestFiles {
+		return fmt.Sprintf("at most %d files may change", maxPullRequestFiles)
+	}
+	var total int64
+	seen := map[string]bool{}
+	for i := range req.Files {
+		f := &req.Files[i]
+		clean := path.Clean(f.Path)
+		if f.Path == "" || clean != f.Path || strings.HasPrefix(clean, "/") || clean == ".." || strings.HasPrefix(clean, "../") ||
+			clean == ".git" || strings.HasPrefix(clean, ".git/") {
+			return fmt.Sprintf("invalid file path %q", f.Path)
+		}
+		if seen[clean] {
+			return fmt.Sprintf("file %q is listed twice", f.Path)
+		}
+		seen[clean] = true
+		if f.Deleted {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return fmt.Sprintf("content of %q is not base64", f.Path)
+		}
+		total += int64(len(data))
+	}
+	if total > maxPullRequestChangeBytes {
+		return fmt.Sprintf("changes exceed %d bytes", maxPullRequestChangeBytes)
+	}
+	return ""
+}
+
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/pull-requests
+// requestSessionPullRequest lets a session's runner propose its changes to one
+// of the session's repositories. The change set is stored with the session and
+// a Pending entry added to status.pullRequests; the operator then commits it,
+// pushes the branch and opens the pull request with the namespace's GitHub
+// credentials (integrations.github.credentialsSecret), recording its link in
+// the entry. The session must opt in with gitConfig.pullRequests and the
+// project with integrations.github.pullRequests.
+func requestSessionPullRequest(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	if !canWriteSession(c, project, sessionName) {
+		respondError(c, http.StatusForbidden, "Not permitted to open pull requests for this session")
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPullRequestChangeBytes*2)
+	var req CreatePullRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if msg := validatePullRequestChange(&req); msg != "" {
+		respondError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	gvr := getAgenticSessionV1Alpha1Resource()
+	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+	if _, finished := sessionFinishedAt(item); finished {
+		respondError(c, http.StatusConflict, "Session is no longer running")
+		return
+	}
+	if enabled, _, _ := unstructured.NestedBool(item.Object, "spec", "gitConfig", "pullRequests"); !enabled {
+		respondError(c, http.StatusForbidden, "Session was not created with gitConfig.pullRequests")
+		return
+	}
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	ghEnabled, _, _ := unstructured.NestedBool(spec, "integrations", "github", "enabled")
+	prEnabled, _, _ := unstructured.NestedBool(spec, "integrations", "github", "pullRequests")
+	if !ghEnabled || !prEnabled {
+		auditDeny(c, "integrations.github.pullRequests: pull requests not enabled")
+		respondError(c, http.StatusForbidden, "Pull requests are not enabled for this project")
+		return
+	}
+
+	repos, _, _ := unstructured.NestedSlice(item.Object, "spec", "gitConfig", "repositories")
+	known := false
+	for _, r := range repos {
+		if m, ok := r.(map[string]interface{}); ok {
+			if u, _ := m["url"].(string); u != "" && normalizeRepoURL(u) == normalizeRepoURL(req.Repo) {
+				known = true
+				break
+			}
+		}
+	}
+	if !known {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("%s is not a repository of this session", req.Repo))
+		return
+	}
+
+	var entries []interface{}
+	if raw, ok, _ := unstructured.NestedSlice(item.Object, "status", "pullRequests"); ok {
+		entries = raw
+	}
+	index := -1
+	for i, raw := range entries {
+		m, _ := raw.(map[string]interface{})
+		pr := parseSessionPullRequest(m)
+		if normalizeRepoURL(pr.Repo) != normalizeRepoURL(req.Repo) || pr.Branch != req.Branch {
+			continue
+		}
+		if pr.State == "Pending" {
+			respondError(c, http.StatusConflict, "A pull request for this branch is still being opened")
+			return
+		}
+		index = i
+	}
+	if index < 0 && len(entries) >= maxPullRequestsPerSession {
+		respondError(c, http.StatusConflict, fmt.Sprintf("a session may open at most %d pull requests", maxPullRequestsPerSession))
+		return
+	}
+
+	now := time.Now().UTC()
+	changeSetPath := fmt.Sprintf("/sessions/%s/pull-requests/%d.json", sessionName, now.UnixNano())
+	changeSet, _ := json.Marshal(map[string]interface{}{
+		"baseSha": req.BaseSHA,
+		"body":    req.Body,
+		"draft":   req.Draft,
+		"files":   req.Files,
+	})
+	if !enforceStorageQuota(c, project, sessionName, changeSetPath, int64(len(changeSet))) {
+		return
+	}
+	if err := writeProjectContentFile(c, project, changeSetPath, changeSet); err != nil {
+		logErrorf(c, "Failed to store pull request change set for %s/%s: %v", project, sessionName, err)
+		respondError(c, http.StatusBadGateway, "Failed to store the change set")
+		return
+	}
+
+	entry := SessionPullRequest{
+		Repo:        req.Repo,
+		Branch:      req.Branch,
+		BaseBranch:  req.BaseBranch,
+		Title:       req.Title,
+		ChangeSet:   changeSetPath,
+		State:       "Pending",
+		RequestedAt: now.Format(time.RFC3339),
+	}
+	if index >= 0 {
+		// Pushing to the branch again updates the pull request already open for it
+		prev := parseSessionPullRequest(entries[index].(map[string]interface{}))
+		entry.Number, entry.URL = prev.Number, prev.URL
+	}
+	raw := map[string]interface{}{}
+	b, _ := json.Marshal(entry)
+	_ = json.Unmarshal(b, &raw)
+	if index >= 0 {
+		entries[index] = raw
+	} else {
+		entries = append(entries, raw)
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"pullRequests": entries},
+	})
+	if _, err := reqDyn.Resource(gvr).Namespace(project).Patch(context.TODO(), sessionName, types.MergePatchType, patch, v1.PatchOptions{}, "status"); err != nil {
+		logErrorf(c, "Failed to record pull request of %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to record pull request")
+		return
+	}
+	c.JSON(http.StatusAccepted, entry)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// SessionQueueStatus mirrors status.queue, set by the operator while a session
+// waits for capacity under the namespace's concurrency limits.
+type SessionQueueStatus struct {
+	Reason    string `json:"reason,omitempty"`
+	Framework string `json:"framework,omitempty"`
+	Priority  string `json:"priority,omitempty"`
+	QueuedAt  string `json:"queuedAt,omitempty"`
+}
+
+type QueuedSession struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName,omitempty"`
+	Framework   string `json:"framework"`
+	Priority    string `json:"priority"`
+	Reason      string `json:"reason,omitempty"`
+	Message     string `json:"message,omitempty"`
+	QueuedAt    string `json:"queuedAt,omitempty"`
+	// Position is 1-based within the session's framework
+	Position int `json:"position"`
+}
+
+// sessionFrameworkFromSpec matches the operator's default framework
+func sessionFrameworkFromSpec(spec map[string]interface{}) string {
+	if f, ok := spec["framework"].(string); ok && f != "" {
+		return f
+	}
+	return defaultSessionFramework
+}
+
+// queuePriorityAging matches the operator's QUEUE_PRIORITY_AGING: a queued
+// session rises one priority level per interval waited
+var queuePriorityAging = func() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("QUEUE_PRIORITY_AGING")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}()
+
+// sessionPriorityFromSpec matches the operator's default priority
+func sessionPriorityFromSpec(spec map[string]interface{}) string {
+	if p, _ := spec["priority"].(string); slices.Contains(sessionPriorities, p) {
+		return p
+	}
+	return "normal"
+}
+
+// queuedAhead mirrors the operator's admission order: higher priority (raised
+// while waiting) first, then older first
+func queuedAhead(a, b *unstructured.Unstructured, now time.Time) bool {
+	rank := func(s *unstructured.Unstructured) int {
+		spec, _, _ := unstructured.NestedMap(s.Object, "spec")
+		r := slices.Index(sessionPriorities, sessionPriorityFromSpec(spec))
+		return min(r+int(now.Sub(s.GetCreationTimestamp().Time)/queuePriorityAging), len(sessionPriorities)-1)
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra > rb
+	}
+	return a.GetCreationTimestamp().Time.Before(b.GetCreationTimestamp().Time)
+}
+
+// GET /api/projects/:projectName/queue
+// getSessionQueue reports queued sessions in admission order along with current
+// per-framework usage and the configured limits.
+func getSessionQueue(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		sessions = list.Items
+	}
+
+	running := map[string]int64{}
+	var runningTotal int64
+	queued := make([]unstructured.Unstructured, 0)
+	for _, item := range sessions {
+		spec, _, _ := unstructured.NestedMap(item.Object, "spec")
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		switch phase {
+		case "Creating", "Running":
+			running[sessionFrameworkFromSpec(spec)]++
+			runningTotal++
+		case "Pending":
+			if _, found, _ := unstructured.NestedMap(item.Object, "status", "queue"); found {
+				queued = append(queued, item)
+			}
+		}
+	}
+	now := time.Now()
+	sort.Slice(queued, func(i, j int) bool {
+		return queuedAhead(&queued[i], &queued[j], now)
+	})
+
+	positions := map[string]int{}
+	items := make([]QueuedSession, 0, len(queued))
+	for _, item := range queued {
+		spec, _, _ := unstructured.NestedMap(item.Object, "spec")
+		framework := sessionFrameworkFromSpec(spec)
+		positions[framework]++
+		q := QueuedSession{Name: item.GetName(), Framework: framework, Priority: sessionPriorityFromSpec(spec), Position: positions[framework]}
+		q.DisplayName, _ = spec["displayName"].(string)
+		q.Reason, _, _ = unstructured.NestedString(item.Object, "status", "queue", "reason")
+		q.QueuedAt, _, _ = unstructured.NestedString(item.Object, "status", "queue", "queuedAt")
+		q.Message, _, _ = unstructured.NestedString(item.Object, "status", "message")
+		items = append(items, q)
+	}
+
+	limits := gin.H{}
+	if spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project); err == nil {
+		if policy, ok := spec["sessionPolicy"].(map[string]interface{}); ok {
+			if v, ok := intFromSpec(policy, "maxConcurrentSessions"); ok {
+				limits["maxConcurrentSessions"] = v
+			}
+			if v, ok := policy["frameworkLimits"].(map[string]interface{}); ok {
+				limits["frameworkLimits"] = v
+			}
+		}
+	} else {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"running": gin.H{
+			"total":       runningTotal,
+			"byFramework": running,
+		},
+		"limits": limits,
+	})
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+// StorageQuota mirrors ProjectSettings spec.storageQuota. Zero means unlimited.
+type StorageQuota struct {
+	MaxTotalBytes          int64 `json:"maxTotalBytes,omitempty"`
+	MaxArtifactsPerSession int64 `json:"maxArtifactsPerSession,omitempty"`
+}
+
+type contentUsage struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Files int64  `json:"files"`
+}
+
+// sessionArtifactsPath is where a session's artifacts live on the project PVC.
+// The runner writes into <workdir>/artifacts which is mirrored under the workspace.
+func sessionArtifactsPath(sessionName string) string {
+	return fmt.Sprintf("/sessions/%s/workspace/artifacts", sessionName)
+}
+
+// storageQuotaFromSpec reads spec.storageQuota from ProjectSettings.
+func storageQuotaFromSpec(spec map[string]interface{}) StorageQuota {
+	q := StorageQuota{}
+	if m, ok := spec["storageQuota"].(map[string]interface{}); ok {
+		if v, ok := intFromSpec(m, "maxTotalBytes"); ok {
+			q.MaxTotalBytes = v
+		}
+		if v, ok := intFromSpec(m, "maxArtifactsPerSession"); ok {
+			q.MaxArtifactsPerSession = v
+		}
+	}
+	return q
+}
+
+// contentUsageHandler handles GET /content/usage?path= when running in CONTENT_SERVICE_MODE.
+// Returns total bytes and file count under path, recursively. A missing path reports zero.
+func contentUsageHandler(c *gin.Context) {
+	path := filepath.Clean("/" + strings.TrimSpace(c.Query("path")))
+	if strings.Contains(path, "..") {
+		respondError(c, http.StatusBadRequest, "invalid path")
+		return
+	}
+	abs := filepath.Join(stateBaseDir, path)
+	usage := contentUsage{Path: path}
+	err := filepath.WalkDir(abs, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		usage.Bytes += info.Size()
+		usage.Files++
+		return nil
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "usage walk failed")
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// projectContentUsage asks the per-namespace content service for usage under absPath
+func projectContentUsage(c *gin.Context, project string, absPath string) (contentUsage, error) {
+	var out contentUsage
+	token := c.GetHeader("Authorization")
+	if strings.TrimSpace(token) == "" {
+		token = c.GetHeader("X-Forwarded-Access-Token")
+	}
+	base := os.Getenv("CONTENT_SERVICE_BASE")
+	if base == "" {
+		base = "http://ambient-content.%s.svc:8080"
+	}
+	endpoint := fmt.Sprintf(base, project)
+	u := fmt.Sprintf("%s/content/usage?path=%s", endpoint, url.QueryEscape(absPath))
+	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, u, nil)
+	if strings.TrimSpace(token) != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return out, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return out, fmt.Errorf("usage failed: status %d", resp.StatusCode)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(b, &out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+const (
+	storageQuotaFail   = "Fail"
+	storageQuotaIgnore = "Ignore"
+	// projectUsageCacheTTL bounds how long a project's walked usage is reused
+	// before the content service walks /sessions again
+	projectUsageCacheTTL = 30 * time.Second
+)
+
+// storageQuotaFailurePolicy is STORAGE_QUOTA_FAILURE_POLICY: with Fail (the
+// default) writes are rejected while usage cannot be read, with Ignore they
+// are allowed unchecked
+func storageQuotaFailurePolicy() string {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("STORAGE_QUOTA_FAILURE_POLICY")), storageQuotaIgnore) {
+		return storageQuotaIgnore
+	}
+	return storageQuotaFail
+}
+
+type cachedProjectUsage struct {
+	bytes   int64
+	expires time.Time
+}
+
+var (
+	projectUsageMu    sync.Mutex
+	projectUsageCache = map[string]cachedProjectUsage{}
+)
+
+// projectSessionsUsage returns the bytes stored under /sessions, walking the
+// project's content service at most once per projectUsageCacheTTL. Writes
+// admitted meanwhile are added by recordProjectUsage, so back-to-back uploads
+// still add up; each backend replica keeps its own cache.
+func projectSessionsUsage(c *gin.Context, project string) (int64, error) {
+	projectUsageMu.Lock()
+	cached, ok := projectUsageCache[project]
+	projectUsageMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.bytes, nil
+	}
+	usage, err := projectContentUsage(c, project, "/sessions")
+	if err != nil {
+		return 0, err
+	}
+	projectUsageMu.Lock()
+	projectUsageCache[project] = cachedProjectUsage{bytes: usage.Bytes, expires: time.Now().Add(projectUsageCacheTTL)}
+	projectUsageMu.Unlock()
+	return usage.Bytes, nil
+}
+
+// recordProjectUsage adds an admitted write to the cached usage
+func recordProjectUsage(project string, delta int64) {
+	projectUsageMu.Lock()
+	defer projectUsageMu.Unlock()
+	if cached, ok := projectUsageCache[project]; ok {
+		cached.bytes = max(cached.bytes+delta, 0)
+		projectUsageCache[project] = cached
+	}
+}
+
+// existingFile describes the file a write to absPath replaces: its bytes, and
+// whether it lives on the shared artifact volume rather than the content service
+type existingFile struct {
+	exists bool
+	bytes  int64
+	onPVC  bool
+}
+
+// existingFileSize looks up the file a write to absPath replaces. An artifact
+// on the shared volume that other paths link to frees nothing, so it counts as
+// zero bytes.
+func existingFileSize(c *gin.Context, project, sessionName, absPath string) (existingFile, error) {
+	if store, ok := artifacts.(pvcArtifactStore); ok {
+		if name, found := strings.CutPrefix(absPath, sessionArtifactsPath(sessionName)+"/"); found {
+			f := existingFile{onPVC: true}
+			if info, err := os.Stat(store.artifactPath(project, sessionName, name)); err == nil {
+				f.exists = true
+				if linkCount(info) <= 1 {
+					f.bytes = info.Size()
+				}
+			}
+			return f, nil
+		}
+	}
+	usage, err := projectContentUsage(c, project, absPath)
+	return existingFile{exists: usage.Files > 0, bytes: usage.Bytes}, err
+}
+
+// quotaUnavailable applies the failure policy when usage cannot be read. It
+// writes a 503 response and returns false under Fail.
+func quotaUnavailable(c *gin.Context, what string, err error) bool {
+	if storageQuotaFailurePolicy() == storageQuotaIgnore {
+		logWarnf(c, "quota: %s unavailable, allowing the write: %v", what, err)
+		return true
+	}
+	logErrorf(c, "quota: %s unavailable, rejecting the write: %v", what, err)
+	respondProblem(c, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable,
+		fmt.Sprintf("Storage quota cannot be checked: %s is unavailable", what)))
+	return false
+}
+
+// enforceStorageQuota checks the project's storage quota before writing size bytes
+// to absPath for sessionName, charging only what the write adds to a file it
+// replaces. It writes a 413 response and returns false when the write would
+// exceed the quota, and a 503 under STORAGE_QUOTA_FAILURE_POLICY=Fail when
+// usage cannot be read.
+func enforceStorageQuota(c *gin.Context, project, sessionName, absPath string, size int64) bool {
+	// Without a client for the caller the quota cannot be read whatever the
+	// failure policy, and the write is not theirs to make
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		logErrorf(c, "quota: no client for the caller, rejecting the write to %s/%s", project, sessionName)
+		respondProblem(c, problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable,
+			"Storage quota cannot be checked: no client for the caller"))
+		return false
+	}
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		return quotaUnavailable(c, "project settings", err)
+	}
+	quota := storageQuotaFromSpec(spec)
+	if quota.MaxTotalBytes <= 0 && quota.MaxArtifactsPerSession <= 0 {
+		return true
+	}
+
+	existing, err := existingFileSize(c, project, sessionName, absPath)
+	if err != nil {
+		return quotaUnavailable(c, "content usage", err)
+	}
+
+	if quota.MaxTotalBytes > 0 {
+		used, err := projectSessionsUsage(c, project)
+		if err == nil {
+			// Artifacts kept outside the content service count too
+			if store, ok := artifacts.(pvcArtifactStore); ok {
+				var stored contentUsage
+				if stored, err = store.Usage(project); err == nil {
+					used += stored.Bytes
+				}
+			}
+		}
+		if err != nil {
+			return quotaUnavailable(c, "content usage", err)
+		}
+		if added := size - existing.bytes; added > 0 && used+added > quota.MaxTotalBytes {
+			auditDeny(c, "storageQuota.maxTotalBytes")
+			respondProblem(c, problem.New(http.StatusRequestEntityTooLarge, problem.CodeQuotaExceeded,
+				fmt.Sprintf("Project storage quota exceeded: %d of %d bytes used, upload adds %d bytes", used, quota.MaxTotalBytes, added)).
+				With("quota", "maxTotalBytes").
+				With("limit", quota.MaxTotalBytes).
+				With("used", used).
+				With("requestedSize", size))
+			return false
+		}
+	}
+
+	artifactsRoot := sessionArtifactsPath(sessionName)
+	// Overwriting an existing artifact does not add to the count
+	if quota.MaxArtifactsPerSession > 0 && strings.HasPrefix(absPath, artifactsRoot+"/") && !existing.exists {
+		usage, err := projectContentUsage(c, project, artifactsRoot)
+		if err != nil {
+			return quotaUnavailable(c, "artifact usage", err)
+		}
+		if usage.Files+1 > quota.MaxArtifactsPerSession {
+			auditDeny(c, "storageQuota.maxArtifactsPerSession")
+			respondProblem(c, problem.New(http.StatusRequestEntityTooLarge, problem.CodeQuotaExceeded,
+				fmt.Sprintf("Session artifact quota exceeded: %d of %d artifacts stored", usage.Files, quota.MaxArtifactsPerSession)).
+				With("quota", "maxArtifactsPerSession").
+				With("limit", quota.MaxArtifactsPerSession).
+				With("used", usage.Files))
+			return false
+		}
+	}
+	// store.Usage is read fresh on every write; only content service bytes are cached
+	if quota.MaxTotalBytes > 0 && !existing.onPVC {
+		recordProjectUsage(project, size-existing.bytes)
+	}
+	return true
+}
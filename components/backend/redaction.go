@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	redactionEntropyMinLength = 24
+	// redactionEntropyThreshold is in bits per character; hex digests stay
+	// below it at 4, random base64 and alphanumeric secrets exceed it
+	redactionEntropyThreshold = 4.2
+)
+
+// redactionDetectors are the built-in detector names of ProjectSettings
+// spec.redaction.detectors; custom patterns are counted as "custom"
+var redactionDetectors = []string{"apiKeys", "tokens", "emails", "entropy"}
+
+type redactionRule struct {
+	detector string
+	re       *regexp.Regexp
+	// group is the submatch replaced, 0 for the whole match
+	group int
+}
+
+var builtinRedactionRules = []redactionRule{
+	{detector: "apiKeys", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{detector: "apiKeys", re: regexp.MustCompile(`\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}`)},
+	{detector: "apiKeys", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{detector: "apiKeys", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{detector: "apiKeys", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{detector: "tokens", re: regexp.MustCompile(`\bgh[opsur]_[A-Za-z0-9]{36,}\b`)},
+	{detector: "tokens", re: regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`)},
+	{detector: "tokens", re: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{detector: "tokens", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{detector: "tokens", re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`), group: 1},
+	{detector: "tokens", re: regexp.MustCompile(`(?i)\b(?:password|passwd|secret|api[_-]?key|access[_-]?key|token)["']?\s*[:=]\s*["']?([^\s"',;]{8,})`), group: 1},
+	{detector: "emails", re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+}
+
+var redactionEntropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/_=-]{24,}`)
+
+// redactionPolicy mirrors ProjectSettings spec.redaction: whether stored text
+// artifacts and streamed runner logs are redacted, with which detectors
+type redactionPolicy struct {
+	Enabled bool
+	rules   []redactionRule
+	entropy bool
+}
+
+func redactionPolicyFromSpec(spec map[string]interface{}) redactionPolicy {
+	p := redactionPolicy{}
+	p.Enabled, _, _ = unstructured.NestedBool(spec, "redaction", "enabled")
+	if !p.Enabled {
+		return p
+	}
+	detectors, found, _ := unstructured.NestedStringSlice(spec, "redaction", "detectors")
+	if !found {
+		detectors = redactionDetectors
+	}
+	for _, r := range builtinRedactionRules {
+		if slices.Contains(detectors, r.detector) {
+			p.rules = append(p.rules, r)
+		}
+	}
+	p.entropy = slices.Contains(detectors, "entropy")
+	patterns, _, _ := unstructured.NestedStringSlice(spec, "redaction", "patterns")
+	for _, raw := range patterns {
+		// Validated on save; anything that no longer compiles is skipped
+		if re, err := regexp.Compile(raw); err == nil {
+			p.rules = append(p.rules, redactionRule{detector: "custom", re: re})
+		}
+	}
+	return p
+}
+
+// redact replaces detected secrets and personal data in data with
+// [REDACTED:<detector>] and counts the replacements per detector
+func (p redactionPolicy) redact(data []byte) ([]byte, map[string]int64) {
+	counts := map[string]int64{}
+	if !p.Enabled {
+		return data, counts
+	}
+	s := string(data)
+	for _, r := range p.rules {
+		mask := "[REDACTED:" + r.detector + "]"
+		s = r.re.ReplaceAllStringFunc(s, func(m string) string {
+			if r.group == 0 {
+				counts[r.detector]++
+				return mask
+			}
+			sub := r.re.FindStringSubmatchIndex(m)
+			if len(sub) <= 2*r.group+1 || sub[2*r.group] < 0 {
+				return m
+			}
+			counts[r.detector]++
+			return m[:sub[2*r.group]] + mask + m[sub[2*r.group+1]:]
+		})
+	}
+	if p.entropy {
+		s = redactionEntropyCandidate.ReplaceAllStringFunc(s, func(m string) string {
+			if strings.Contains(m, "REDACTED") || !highEntropySecret(m) {
+				return m
+			}
+			counts["entropy"]++
+			return "[REDACTED:entropy]"
+		})
+	}
+	if len(counts) == 0 {
+		return data, counts
+	}
+	return []byte(s), counts
+}
+
+// highEntropySecret reports whether s looks like a random credential: long,
+// mixing letters and digits, with Shannon entropy above the threshold
+func highEntropySecret(s string) bool {
+	if len(s) < redactionEntropyMinLength || !strings.ContainsAny(s, "0123456789") ||
+		strings.IndexFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' }) < 0 {
+		return false
+	}
+	freq := map[rune]float64{}
+	for _, r := range s {
+		freq[r]++
+	}
+	n := float64(len(s))
+	entropy := 0.0
+	for _, f := range freq {
+		entropy -= f / n * math.Log2(f/n)
+	}
+	return entropy >= redactionEntropyThreshold
+}
+
+// SessionRedactions counts what redaction removed from a session's stored artifacts
+type SessionRedactions struct {
+	Total      int64            `json:"total"`
+	Artifacts  int64            `json:"artifacts"`
+	ByDetector map[string]int64 `json:"byDetector,omitempty"`
+}
+
+// recordSessionRedactions adds counts to the session's status.redactions. It is
+// best effort: the artifact index already records each artifact's own count.
+func recordSessionRedactions(c *gin.Context, reqDyn dynamic.Interface, project, sessionName string, counts map[string]int64) {
+	gvr := getAgenticSessionV1Alpha1Resource()
+	item, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		logErrorf(c, "redaction: failed to read session %s/%s: %v", project, sessionName, err)
+		return
+	}
+	current := SessionRedactions{ByDetector: map[string]int64{}}
+	if raw, ok, _ := unstructured.NestedMap(item.Object, "status", "redactions"); ok {
+		current.Total, _ = intFromSpec(raw, "total")
+		current.Artifacts, _ = intFromSpec(raw, "artifacts")
+		if by, ok := raw["byDetector"].(map[string]interface{}); ok {
+			for k := range by {
+				current.ByDetector[k], _ = intFromSpec(by, k)
+			}
+		}
+	}
+	current.Artifacts++
+	for detector, n := range counts {
+		current.Total += n
+		current.ByDetector[detector] += n
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"redactions": current},
+	})
+	if _, err := reqDyn.Resource(gvr).Namespace(project).Patch(context.TODO(), sessionName, types.MergePatchType, patch, v1.PatchOptions{}, "status"); err != nil {
+		logErrorf(c, "redaction: failed to record counts for %s/%s: %v", project, sessionName, err)
+	}
+}
+
+// describeRedactions renders counts as "emails=2, tokens=1" for logs
+func describeRedactions(counts map[string]int64) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, counts[k]))
+	}
+	return strings.Join(parts, ", ")
+}
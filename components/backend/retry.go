@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// retryOfLabel on a retry names the original session, so every attempt can be listed
+	retryOfLabel = "ambient-code.io/retry-of"
+	// maxSessionRetries bounds spec.retryPolicy.maxRetries
+	maxSessionRetries = 10
+)
+
+// validateSessionRetryPolicy returns a user-facing message for an invalid retryPolicy
+func validateSessionRetryPolicy(p *SessionRetryPolicy) string {
+	if p == nil {
+		return ""
+	}
+	if p.MaxRetries < 0 || p.MaxRetries > maxSessionRetries {
+		return fmt.Sprintf("retryPolicy.maxRetries must be between 0 and %d", maxSessionRetries)
+	}
+	if p.BackoffSeconds < 0 {
+		return "retryPolicy.backoffSeconds must not be negative"
+	}
+	return ""
+}
+
+// retryLabels marks a retry with its original session and keeps the pipeline
+// step labels, so a pipeline follows the newest attempt of a step
+func retryLabels(source *unstructured.Unstructured, original string) map[string]interface{} {
+	labels := map[string]interface{}{retryOfLabel: original}
+	for _, key := range []string{pipelineLabel, pipelineStepLabel} {
+		if v := source.GetLabels()[key]; v != "" {
+			labels[key] = v
+		}
+	}
+	return labels
+}
+
+// retrySessionObject builds the next attempt of source: the same spec, named
+// <original>-retry-<attempt> and referencing the original session. The operator
+// builds automatic retries the same way, so a manual and an automatic retry of
+// one failure collide on the name instead of both running.
+func retrySessionObject(source *unstructured.Unstructured) *unstructured.Unstructured {
+	spec := runtime.DeepCopyJSONValue(source.Object["spec"]).(map[string]interface{})
+	original, _ := spec["retryOf"].(string)
+	if original == "" {
+		original = source.GetName()
+	}
+	attempt, _ := intFromSpec(spec, "retryAttempt")
+	attempt++
+	spec["retryOf"] = original
+	spec["retryAttempt"] = attempt
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": source.GetAPIVersion(),
+		"kind":       source.GetKind(),
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("%s-retry-%d", original, attempt),
+			"namespace": source.GetNamespace(),
+			"labels":    retryLabels(source, original),
+		},
+		"spec": spec,
+	}}
+	return obj
+}
+
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/retry
+// retrySession starts a new attempt of a Failed session with the same spec.
+func retrySession(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	gvr := getAgenticSessionV1Alpha1Resource()
+
+	source, err := reqDyn.Resource(gvr).Namespace(project).Get(context.TODO(), sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+	if phase, _, _ := unstructured.NestedString(source.Object, "status", "phase"); phase != "Failed" {
+		respondError(c, http.StatusConflict, fmt.Sprintf("only Failed sessions can be retried (phase is %q)", phase))
+		return
+	}
+
+	obj := retrySessionObject(source)
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	if !enforceCopiedSessionPolicy(c, reqDyn, project, spec) {
+		return
+	}
+
+	created, err := reqDyn.Resource(gvr).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			respondError(c, http.StatusConflict, fmt.Sprintf("session was already retried as %s", obj.GetName()))
+			return
+		}
+		logErrorf(c, "Failed to create retry of %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to create retry session")
+		return
+	}
+
+	auditDetail(c, "retryOf", sessionName)
+	auditDetail(c, "created", created.GetName())
+	session := AgenticSession{
+		APIVersion: created.GetAPIVersion(),
+		Kind:       created.GetKind(),
+		Metadata:   created.Object["metadata"].(map[string]interface{}),
+	}
+	if spec, ok := created.Object["spec"].(map[string]interface{}); ok {
+		session.Spec = parseSpec(spec)
+	}
+	c.JSON(http.StatusCreated, session)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authnv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Runners authenticate with a projected token of their session's
+// ServiceAccount, bound to the runner pod and to the backend's audience
+// (RUNNER_TOKEN_AUDIENCE, default "ambient-backend") rather than the API
+// server's. The backend checks it with TokenReview for that audience, lets it
+// call only its own session's runner endpoints, and reaches the API server
+// with a short-lived token of the same ServiceAccount, so RBAC still limits
+// the runner to its session. Tokens of runners started before the audience was
+// set are API server tokens and keep working as before.
+
+const (
+	defaultRunnerTokenAudience = "ambient-backend"
+	// runnerServiceAccountPrefix names session ServiceAccounts, which the
+	// operator creates as ambient-session-<session>
+	runnerServiceAccountPrefix = "ambient-session-"
+	runnerAPITokenSeconds      = 600
+
+	runnerSessionKey   = "runnerSession"
+	runnerNamespaceKey = "runnerNamespace"
+)
+
+// runnerRoutes are the endpoints a runner calls, always for its own session
+var runnerRoutes = map[string]bool{
+	"GET /api/projects/:projectName/agentic-sessions/:sessionName":                true,
+	"PUT /api/projects/:projectName/agentic-sessions/:sessionName/status":         true,
+	"PUT /api/projects/:projectName/agentic-sessions/:sessionName/displayname":    true,
+	"POST /api/projects/:projectName/agentic-sessions/:sessionName/heartbeat":     true,
+	"GET /api/projects/:projectName/agentic-sessions/:sessionName/inbox":          true,
+	"POST /api/projects/:projectName/agentic-sessions/:sessionName/artifacts":     true,
+	"POST /api/projects/:projectName/agentic-sessions/:sessionName/pull-requests": true,
+	"GET /api/projects/:projectName/agents/:persona/markdown":                     true,
+}
+
+func runnerTokenAudience() string {
+	if v, ok := os.LookupEnv("RUNNER_TOKEN_AUDIENCE"); ok {
+		return strings.TrimSpace(v)
+	}
+	return defaultRunnerTokenAudience
+}
+
+// isRunnerToken reports whether a token claims the runner audience. The claim
+// only routes the token; reviewRunnerToken verifies it.
+func isRunnerToken(token string) bool {
+	aud := runnerTokenAudience()
+	return aud != "" && audienceContains(unverifiedClaims(token)["aud"], aud)
+}
+
+// reviewRunnerToken authenticates a runner token for the backend's audience
+// and resolves the session it belongs to
+func reviewRunnerToken(ctx context.Context, token string) (*UserIdentity, error) {
+	ident, err := reviewToken(ctx, token, runnerTokenAudience())
+	if err != nil {
+		return nil, err
+	}
+	ns, session, ok := runnerSessionOf(ident.UserName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a session ServiceAccount", errTokenInvalid, ident.UserName)
+	}
+	ident.Source = "runner"
+	ident.RunnerNamespace = ns
+	ident.RunnerSession = session
+	return ident, nil
+}
+
+// runnerSessionOf resolves the session of a runner ServiceAccount's user name
+func runnerSessionOf(userName string) (ns, session string, ok bool) {
+	rest, isSA := strings.CutPrefix(userName, "system:serviceaccount:")
+	ns, sa, _ := strings.Cut(rest, ":")
+	session, isSession := strings.CutPrefix(sa, runnerServiceAccountPrefix)
+	return ns, session, isSA && ns != "" && isSession && session != ""
+}
+
+// runnerIdentity returns the session of a request authenticated with a runner token
+func runnerIdentity(c *gin.Context) (session, ns string, ok bool) {
+	session, ns = c.GetString(runnerSessionKey), c.GetString(runnerNamespaceKey)
+	return session, ns, session != "" && ns != ""
+}
+
+// runnerScopeMiddleware limits runner tokens to runnerRoutes in their own
+// project and session
+func runnerScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ns, ok := runnerIdentity(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		route := c.Request.Method + " " + c.FullPath()
+		if !runnerRoutes[route] || c.Param("projectName") != ns || (c.Param("sessionName") != "" && c.Param("sessionName") != session) {
+			logWarnf(c, "Runner of %s/%s refused %s %s", ns, session, c.Request.Method, c.Request.URL.Path)
+			abortWithError(c, http.StatusForbidden, "Runner tokens may only call their own session's runner endpoints")
+			return
+		}
+		c.Next()
+	}
+}
+
+type cachedRunnerToken struct {
+	token   string
+	refresh time.Time
+}
+
+var (
+	runnerAPITokensMu sync.Mutex
+	runnerAPITokens   = map[string]cachedRunnerToken{}
+)
+
+// runnerAPIToken returns an API server token of a session's ServiceAccount,
+// minted by the backend and reused for most of its lifetime. uid is the
+// ServiceAccount the runner token was issued for, so a session recreated under
+// the same name gets its own token.
+func runnerAPIToken(ctx context.Context, ns, session, uid string) (string, error) {
+	key := ns + "/" + session + "/" + uid
+	now := time.Now()
+	runnerAPITokensMu.Lock()
+	cached, ok := runnerAPITokens[key]
+	runnerAPITokensMu.Unlock()
+	if ok && now.Before(cached.refresh) {
+		return cached.token, nil
+	}
+
+	name := runnerServiceAccountPrefix + session
+	sas := k8sClient.CoreV1().ServiceAccounts(ns)
+	sa, err := sas.Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if sa.Labels["app"] != "ambient-runner" || sa.Labels["agentic-session"] != session {
+		return "", fmt.Errorf("ServiceAccount %s is not the runner of session %s", name, session)
+	}
+	if string(sa.UID) != uid {
+		return "", fmt.Errorf("ServiceAccount %s was recreated since the runner token was issued", name)
+	}
+	expiry := int64(runnerAPITokenSeconds)
+	tok, err := sas.CreateToken(ctx, name, &authnv1.TokenRequest{Spec: authnv1.TokenRequestSpec{ExpirationSeconds: &expiry}}, v1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	runnerAPITokensMu.Lock()
+	for k, t := range runnerAPITokens {
+		if now.After(t.refresh) {
+			delete(runnerAPITokens, k)
+		}
+	}
+	// Refreshed at 80% of its lifetime, like kubelet-projected tokens
+	runnerAPITokens[key] = cachedRunnerToken{token: tok.Status.Token, refresh: now.Add(runnerAPITokenSeconds * time.Second * 4 / 5)}
+	runnerAPITokensMu.Unlock()
+	return tok.Status.Token, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// With RUNNER_MTLS=true, for clusters without a service mesh, the backend also
+// serves the API on RUNNER_MTLS_PORT (default 8443) over mutual TLS. Clients
+// must present a certificate of the platform CA in RUNNER_CA_SECRET, which the
+// operator issues to each session's runner; the certificate maps to the
+// session's runner identity like a runner token does, with the same endpoint
+// scoping. The serving certificate is issued from the same CA, which runners
+// trust. Runners keep sending their token, which the backend forwards to the
+// content service as before.
+
+const (
+	defaultRunnerCASecret = "ambient-runner-ca"
+	defaultRunnerMTLSPort = "8443"
+	// runnerCAReload is how long the CA is used before the Secret is read again
+	runnerCAReload           = 10 * time.Minute
+	runnerServingValidity    = 30 * 24 * time.Hour
+	runnerServingRenewBefore = 10 * 24 * time.Hour
+)
+
+func runnerMTLSEnabled() bool {
+	return os.Getenv("RUNNER_MTLS") == "true"
+}
+
+func runnerCASecretName() string {
+	if v := strings.TrimSpace(os.Getenv("RUNNER_CA_SECRET")); v != "" {
+		return v
+	}
+	return defaultRunnerCASecret
+}
+
+func runnerMTLSPort() string {
+	if v := strings.TrimSpace(os.Getenv("RUNNER_MTLS_PORT")); v != "" {
+		return v
+	}
+	return defaultRunnerMTLSPort
+}
+
+type runnerTLSState struct {
+	pool     *x509.CertPool
+	serving  *tls.Certificate
+	caPEM    string
+	loadedAt time.Time
+}
+
+var (
+	runnerTLSMu sync.Mutex
+	runnerTLS   *runnerTLSState
+)
+
+// runnerTLSConfig is resolved per connection, so the backend starts before the
+// operator has created the CA and picks up a replaced CA
+func runnerTLSConfig(*tls.ClientHelloInfo) (*tls.Config, error) {
+	runnerTLSMu.Lock()
+	defer runnerTLSMu.Unlock()
+	now := time.Now()
+	if runnerTLS == nil || now.Sub(runnerTLS.loadedAt) > runnerCAReload || now.Add(runnerServingRenewBefore).After(runnerTLS.serving.Leaf.NotAfter) {
+		state, err := loadRunnerTLS(context.Background(), runnerTLS)
+		if err != nil {
+			if runnerTLS == nil || now.After(runnerTLS.serving.Leaf.NotAfter) {
+				return nil, fmt.Errorf("runner CA unavailable: %v", err)
+			}
+			log.Printf("Failed to reload runner CA, keeping the previous one: %v", err)
+		} else {
+			runnerTLS = state
+		}
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    runnerTLS.pool,
+		Certificates: []tls.Certificate{*runnerTLS.serving},
+	}, nil
+}
+
+// loadRunnerTLS reads the CA and issues a serving certificate for the backend
+// Service, reusing prev's while the CA is unchanged and it is not due for renewal
+func loadRunnerTLS(ctx context.Context, prev *runnerTLSState) (*runnerTLSState, error) {
+	secret, err := k8sClient.CoreV1().Secrets(namespace).Get(ctx, runnerCASecretName(), v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	caPEM := string(secret.Data[corev1.TLSCertKey])
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("runner CA %s: %v", secret.Name, err)
+	}
+	ca, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("runner CA %s: %v", secret.Name, err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	now := time.Now()
+	if prev != nil && prev.caPEM == caPEM && now.Add(runnerServingRenewBefore).Before(prev.serving.Leaf.NotAfter) {
+		return &runnerTLSState{pool: pool, serving: prev.serving, caPEM: caPEM, loadedAt: now}, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	notAfter := now.Add(runnerServingValidity)
+	if notAfter.After(ca.NotAfter) {
+		notAfter = ca.NotAfter
+	}
+	host := "backend-service." + namespace
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host + ".svc"},
+		DNSNames:     []string{"backend-service", host, host + ".svc", host + ".svc.cluster.local"},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, pair.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	serving := &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	return &runnerTLSState{pool: pool, serving: serving, caPEM: caPEM, loadedAt: now}, nil
+}
+
+// newRunnerMTLSServer serves handler to runners on RUNNER_MTLS_PORT
+func newRunnerMTLSServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:      ":" + runnerMTLSPort(),
+		Handler:   handler,
+		TLSConfig: &tls.Config{GetConfigForClient: runnerTLSConfig},
+	}
+}
+
+// runnerCertIdentity maps a verified runner client certificate to its
+// session: the subject names the session ServiceAccount and carries its UID
+// as the serial number
+func runnerCertIdentity(c *gin.Context) (*UserIdentity, bool) {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	subject := c.Request.TLS.VerifiedChains[0][0].Subject
+	ns, session, ok := runnerSessionOf(subject.CommonName)
+	if !ok || subject.SerialNumber == "" {
+		return nil, false
+	}
+	return &UserIdentity{
+		UserID:          subject.SerialNumber,
+		UserName:        subject.CommonName,
+		Source:          "runner-cert",
+		RunnerNamespace: ns,
+		RunnerSession:   session,
+	}, true
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serverDraining is flipped when a termination signal is received so that
+// readiness probes fail and the endpoint is removed from the Service before
+// in-flight requests are drained.
+var serverDraining atomic.Bool
+
+// healthCheck is the liveness probe. It stays healthy while draining, so the
+// kubelet does not restart the pod in the middle of a graceful shutdown; only
+// readiness flips.
+func healthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+}
+
+// readinessCheck fails while the informer caches sync and once shutdown
+// begins; liveness stays on /health so a slow initial sync does not restart
+// the pod.
+func readinessCheck(c *gin.Context) {
+	if serverDraining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	if !informersSynced.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "syncing"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// durationFromEnv parses a Go duration from the named env var, falling back
+// to def when unset or invalid.
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Invalid %s=%q, using default %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+// runServer serves handler on addr, and the TLS servers in tlsServers, until
+// SIGINT/SIGTERM, then flips readiness, waits SHUTDOWN_READINESS_DELAY for
+// endpoints to propagate and finally drains in-flight requests for up to
+// SHUTDOWN_DRAIN_TIMEOUT.
+func runServer(addr string, handler http.Handler, tlsServers ...*http.Server) error {
+	readinessDelay := durationFromEnv("SHUTDOWN_READINESS_DELAY", 5*time.Second)
+	drainTimeout := durationFromEnv("SHUTDOWN_DRAIN_TIMEOUT", 25*time.Second)
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+	for _, s := range tlsServers {
+		go func() {
+			// Certificates come from TLSConfig
+			if err := s.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("TLS server on %s stopped: %v", s.Addr, err)
+			}
+		}()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err, ok := <-errCh:
+		if ok {
+			return err
+		}
+		return nil
+	case sig := <-sigCh:
+		log.Printf("Received %s, marking server not ready", sig)
+	}
+
+	serverDraining.Store(true)
+	if readinessDelay > 0 {
+		time.Sleep(readinessDelay)
+	}
+
+	log.Printf("Draining in-flight requests (timeout %s)", drainTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	for _, s := range tlsServers {
+		if err := s.Shutdown(ctx); err != nil {
+			log.Printf("TLS server on %s did not shut down gracefully: %v", s.Addr, err)
+			_ = s.Close()
+		}
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Graceful shutdown did not complete: %v", err)
+		_ = srv.Close()
+		return err
+	}
+	log.Printf("Server stopped gracefully")
+	return nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// sseKeepalive is how often an idle event stream sends a comment so proxies
+// do not close it
+const sseKeepalive = 25 * time.Second
+
+// SessionSnapshotEvent is the first event of a stream
+type SessionSnapshotEvent struct {
+	Phase      string                   `json:"phase"`
+	Message    string                   `json:"message,omitempty"`
+	Conditions []map[string]interface{} `json:"conditions"`
+}
+
+// SessionPhaseEvent reports a phase transition
+type SessionPhaseEvent struct {
+	Phase         string `json:"phase"`
+	PreviousPhase string `json:"previousPhase"`
+	Message       string `json:"message,omitempty"`
+	Time          string `json:"time"`
+}
+
+// SessionHistoryEvent is a Kubernetes Event recorded on the session, e.g. by the operator
+type SessionHistoryEvent struct {
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	Count   int32  `json:"count,omitempty"`
+	Time    string `json:"time"`
+}
+
+// sessionEventState remembers what was last sent so only changes are pushed
+type sessionEventState struct {
+	phase      string
+	message    string
+	conditions map[string]map[string]interface{}
+}
+
+func sessionConditions(obj *unstructured.Unstructured) []map[string]interface{} {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	out := make([]map[string]interface{}, 0, len(raw))
+	for _, item := range raw {
+		if m, ok := item.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func newSessionEventState(obj *unstructured.Unstructured) (*sessionEventState, SessionSnapshotEvent) {
+	s := &sessionEventState{conditions: map[string]map[string]interface{}{}}
+	s.phase, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+	s.message, _, _ = unstructured.NestedString(obj.Object, "status", "message")
+	conditions := sessionConditions(obj)
+	for _, cond := range conditions {
+		t, _ := cond["type"].(string)
+		s.conditions[t] = cond
+	}
+	return s, SessionSnapshotEvent{Phase: s.phase, Message: s.message, Conditions: conditions}
+}
+
+// send writes the "phase" and "condition" events for what changed in obj
+func (s *sessionEventState) send(c *gin.Context, obj *unstructured.Unstructured) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	message, _, _ := unstructured.NestedString(obj.Object, "status", "message")
+	if phase != s.phase {
+		writeSSE(c, "phase", SessionPhaseEvent{
+			Phase:         phase,
+			PreviousPhase: s.phase,
+			Message:       message,
+			Time:          time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	s.phase, s.message = phase, message
+
+	for _, cond := range sessionConditions(obj) {
+		t, _ := cond["type"].(string)
+		prev, seen := s.conditions[t]
+		if seen && prev["status"] == cond["status"] && prev["reason"] == cond["reason"] && prev["message"] == cond["message"] {
+			continue
+		}
+		s.conditions[t] = cond
+		writeSSE(c, "condition", cond)
+	}
+}
+
+func historyEvent(e *corev1.Event) SessionHistoryEvent {
+	t := e.LastTimestamp.Time
+	if t.IsZero() {
+		t = e.EventTime.Time
+	}
+	if t.IsZero() {
+		t = e.CreationTimestamp.Time
+	}
+	return SessionHistoryEvent{
+		Type:    e.Type,
+		Reason:  e.Reason,
+		Message: e.Message,
+		Count:   e.Count,
+		Time:    t.UTC().Format(time.RFC3339),
+	}
+}
+
+func writeSSE(c *gin.Context, name string, data interface{}) {
+	c.SSEvent(name, data)
+	c.Writer.Flush()
+}
+
+func watchSessionObject(ctx context.Context, reqDyn dynamic.Interface, project, name, resourceVersion string) (watch.Interface, error) {
+	return reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).Watch(ctx, v1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: resourceVersion,
+	})
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/events
+// streamSessionEvents pushes session changes as Server-Sent Events by watching the
+// AgenticSession with the caller's token. The stream opens with a "snapshot" of
+// the phase and conditions, then sends "phase" on transitions, "condition" when a
+// condition changes, "event" for Kubernetes Events recorded on the session (past
+// ones first) and "deleted" before closing when the session is removed.
+func streamSessionEvents(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	ctx := c.Request.Context()
+	gvr := getAgenticSessionV1Alpha1Resource()
+
+	obj, err := reqDyn.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+	sessionWatch, err := watchSessionObject(ctx, reqDyn, project, sessionName, obj.GetResourceVersion())
+	if err != nil {
+		logErrorf(c, "Failed to watch agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to watch agentic session")
+		return
+	}
+	defer func() { sessionWatch.Stop() }()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Disable proxy buffering so events arrive promptly
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	state, snapshot := newSessionEventState(obj)
+	writeSSE(c, "snapshot", snapshot)
+
+	// History is best effort: callers without access to Events still get status changes
+	var historyCh <-chan watch.Event
+	if reqK8s != nil {
+		selector := fields.Set{"involvedObject.kind": "AgenticSession", "involvedObject.name": sessionName}.AsSelector().String()
+		events, err := reqK8s.CoreV1().Events(project).List(ctx, v1.ListOptions{FieldSelector: selector})
+		if err == nil {
+			sort.Slice(events.Items, func(i, j int) bool {
+				return historyEvent(&events.Items[i]).Time < historyEvent(&events.Items[j]).Time
+			})
+			for i := range events.Items {
+				writeSSE(c, "event", historyEvent(&events.Items[i]))
+			}
+			var w watch.Interface
+			if w, err = reqK8s.CoreV1().Events(project).Watch(ctx, v1.ListOptions{FieldSelector: selector, ResourceVersion: events.ResourceVersion}); err == nil {
+				defer w.Stop()
+				historyCh = w.ResultChan()
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			logWarnf(c, "Session event stream for %s/%s continues without history: %v", project, sessionName, err)
+		}
+	}
+
+	// resume re-reads the session after the watch ends (server timeout or an
+	// expired resourceVersion), sends what changed meanwhile and watches again
+	resume := func() bool {
+		sessionWatch.Stop()
+		current, err := reqDyn.Resource(gvr).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			writeSSE(c, "deleted", gin.H{"name": sessionName})
+			return false
+		}
+		if err == nil {
+			state.send(c, current)
+			sessionWatch, err = watchSessionObject(ctx, reqDyn, project, sessionName, current.GetResourceVersion())
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Session event stream for %s/%s ended: %v", project, sessionName, err)
+				writeSSE(c, "error", gin.H{"error": "Lost the watch on the session; reconnect to resume"})
+			}
+			return false
+		}
+		return true
+	}
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case ev, ok := <-sessionWatch.ResultChan():
+			if !ok || ev.Type == watch.Error {
+				if !resume() {
+					return
+				}
+				continue
+			}
+			switch ev.Type {
+			case watch.Added, watch.Modified:
+				if u, ok := ev.Object.(*unstructured.Unstructured); ok {
+					state.send(c, u)
+				}
+			case watch.Deleted:
+				writeSSE(c, "deleted", gin.H{"name": sessionName})
+				return
+			}
+		case ev, ok := <-historyCh:
+			if !ok {
+				historyCh = nil
+				continue
+			}
+			if e, isEvent := ev.Object.(*corev1.Event); isEvent && (ev.Type == watch.Added || ev.Type == watch.Modified) {
+				writeSSE(c, "event", historyEvent(e))
+			}
+		}
+	}
+}
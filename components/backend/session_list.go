@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// sessionPhaseLabel and sessionFrameworkLabel index sessions for server-side
+	// filtering; the backend sets both at creation and the operator keeps the
+	// phase current
+	sessionPhaseLabel     = "ambient-code.io/phase"
+	sessionFrameworkLabel = "ambient-code.io/framework"
+)
+
+// sessionPhaseOrder ranks phases in lifecycle order for sort=phase
+var sessionPhaseOrder = map[string]int{
+	"Pending":   0,
+	"Creating":  1,
+	"Running":   2,
+	"Completed": 3,
+	"Failed":    4,
+	"Stopped":   5,
+	"Error":     6,
+}
+
+var labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+
+// sessionListSelector turns the phase, framework and labelSelector query
+// parameters into a label selector. Phase and framework take comma-separated
+// values. It returns a user-facing message for invalid input.
+func sessionListSelector(phase, framework, labelSelector string) ([]string, string) {
+	var parts []string
+	if phase != "" {
+		phases := strings.Split(phase, ",")
+		for i, p := range phases {
+			phases[i] = strings.TrimSpace(p)
+			if _, ok := sessionPhaseOrder[phases[i]]; !ok {
+				return nil, fmt.Sprintf("unknown phase %q", phases[i])
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s in (%s)", sessionPhaseLabel, strings.Join(phases, ",")))
+	}
+	if framework != "" {
+		frameworks := strings.Split(framework, ",")
+		for i, f := range frameworks {
+			frameworks[i] = strings.TrimSpace(f)
+			if !labelValuePattern.MatchString(frameworks[i]) {
+				return nil, fmt.Sprintf("invalid framework %q", frameworks[i])
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s in (%s)", sessionFrameworkLabel, strings.Join(frameworks, ",")))
+	}
+	if labelSelector = strings.TrimSpace(labelSelector); labelSelector != "" {
+		if _, err := labels.Parse(labelSelector); err != nil {
+			return nil, fmt.Sprintf("invalid labelSelector: %v", err)
+		}
+		parts = append(parts, labelSelector)
+	}
+	return parts, ""
+}
+
+// sessionSort orders sessions by createdAt or phase, ascending unless the field
+// is prefixed with "-". Name breaks ties so the order is total, which lets a
+// cursor mark a position in it.
+type sessionSort struct {
+	field string
+	desc  bool
+}
+
+// sessionSortCursor is the position after the last item of a sorted page
+type sessionSortCursor struct {
+	Key  string `json:"k"`
+	Name string `json:"n"`
+}
+
+func parseSessionSort(raw string) (sessionSort, bool) {
+	s := sessionSort{field: strings.TrimPrefix(raw, "-"), desc: strings.HasPrefix(raw, "-")}
+	switch s.field {
+	case "createdAt", "phase", "name":
+		return s, true
+	}
+	return s, false
+}
+
+func (s sessionSort) key(item *unstructured.Unstructured) string {
+	switch s.field {
+	case "createdAt":
+		// RFC3339 in UTC sorts lexically
+		return item.GetCreationTimestamp().UTC().Format("2006-01-02T15:04:05Z")
+	case "phase":
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		if phase == "" {
+			phase = "Pending"
+		}
+		rank, ok := sessionPhaseOrder[phase]
+		if !ok {
+			rank = len(sessionPhaseOrder)
+		}
+		return fmt.Sprintf("%02d", rank)
+	}
+	return ""
+}
+
+// less compares positions (key, name); the direction applies to the key only
+func (s sessionSort) less(a, b sessionSortCursor) bool {
+	if a.Key != b.Key {
+		return (a.Key < b.Key) != s.desc
+	}
+	if s.field == "name" && s.desc {
+		return a.Name > b.Name
+	}
+	return a.Name < b.Name
+}
+
+// page sorts items and returns up to limit of them after the cursor, with the
+// cursor for the next page when more remain. limit 0 returns everything.
+func (s sessionSort) page(items []unstructured.Unstructured, continueToken string, limit int64) ([]unstructured.Unstructured, string, error) {
+	pos := func(item *unstructured.Unstructured) sessionSortCursor {
+		return sessionSortCursor{Key: s.key(item), Name: item.GetName()}
+	}
+	sort.Slice(items, func(i, j int) bool { return s.less(pos(&items[i]), pos(&items[j])) })
+
+	start := 0
+	if continueToken != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(continueToken)
+		var after sessionSortCursor
+		if err == nil {
+			err = json.Unmarshal(raw, &after)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid continue token")
+		}
+		start = sort.Search(len(items), func(i int) bool { return s.less(after, pos(&items[i])) })
+	}
+	items = items[start:]
+	if limit <= 0 || int64(len(items)) <= limit {
+		return items, "", nil
+	}
+	items = items[:limit]
+	b, _ := json.Marshal(pos(&items[len(items)-1]))
+	return items, base64.RawURLEncoding.EncodeToString(b), nil
+}
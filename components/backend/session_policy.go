@@ -0,0 +1,648 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+// sessionMaxTimeout returns ProjectSettings spec.sessionPolicy.maxTimeoutSeconds,
+// or 0 when no limit is configured.
+func sessionMaxTimeout(spec map[string]interface{}) int64 {
+	if policy, ok := spec["sessionPolicy"].(map[string]interface{}); ok {
+		if v, ok := intFromSpec(policy, "maxTimeoutSeconds"); ok {
+			return v
+		}
+	}
+	return 0
+}
+
+// sessionPolicyViolation is a session admission rule the candidate spec fails
+type sessionPolicyViolation struct {
+	Status  int
+	Message string
+	// Audit is recorded with auditDeny when project policy (rather than malformed
+	// input) rejects the session
+	Audit string
+	// Code is the problem code; policy rejections default to policy_denied
+	Code string
+}
+
+// rejectSession writes the response for a policy violation
+func rejectSession(c *gin.Context, v *sessionPolicyViolation) {
+	code := v.Code
+	if v.Audit != "" {
+		auditDeny(c, v.Audit)
+		if code == "" {
+			code = problem.CodePolicyDenied
+		}
+	}
+	respondProblem(c, problem.New(v.Status, code, v.Message))
+}
+
+// sessionPolicySpec reads the project's ProjectSettings spec for admission. It
+// writes the error response and returns false when the read fails.
+func sessionPolicySpec(c *gin.Context, reqDyn dynamic.Interface, project string) (map[string]interface{}, bool) {
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project policy")
+		return nil, false
+	}
+	return spec, true
+}
+
+// checkSessionTimeout rejects timeouts that are non-positive or exceed the project's maximum
+func checkSessionTimeout(spec map[string]interface{}, timeout int64) *sessionPolicyViolation {
+	if timeout <= 0 {
+		return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: "timeout must be a positive number of seconds"}
+	}
+	if max := sessionMaxTimeout(spec); max > 0 && timeout > max {
+		return &sessionPolicyViolation{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("timeout %ds exceeds the project maximum of %ds", timeout, max),
+			Audit:   fmt.Sprintf("sessionPolicy.maxTimeout: %ds > %ds", timeout, max),
+		}
+	}
+	return nil
+}
+
+// enforceSessionTimeoutPolicy applies checkSessionTimeout. It writes the error
+// response and returns false on rejection.
+func enforceSessionTimeoutPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, timeout int64) bool {
+	if timeout <= 0 {
+		rejectSession(c, checkSessionTimeout(nil, timeout))
+		return false
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := checkSessionTimeout(spec, timeout); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// sessionResourceFields maps ResourceOverrides fields to the names used in
+// ProjectSettings spec.sessionPolicy.maxResources
+var sessionResourceFields = []string{"cpu", "memory", "ephemeralStorage", "gpu"}
+
+func (r *ResourceOverrides) quantity(field string) string {
+	switch field {
+	case "cpu":
+		return r.CPU
+	case "memory":
+		return r.Memory
+	case "ephemeralStorage":
+		return r.EphemeralStorage
+	case "gpu":
+		return r.GPU
+	}
+	return ""
+}
+
+// checkSessionResources rejects resource overrides that are not valid quantities
+// or exceed spec.sessionPolicy.maxResources
+func checkSessionResources(spec map[string]interface{}, overrides *ResourceOverrides) *sessionPolicyViolation {
+	if overrides == nil {
+		return nil
+	}
+	for _, field := range sessionResourceFields {
+		v := strings.TrimSpace(overrides.quantity(field))
+		if v == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil || q.Sign() <= 0 {
+			return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: fmt.Sprintf("resourceOverrides.%s must be a positive quantity such as 500m or 2Gi", field)}
+		}
+		maxStr, _, _ := unstructured.NestedString(spec, "sessionPolicy", "maxResources", field)
+		if maxStr == "" {
+			continue
+		}
+		max, err := resource.ParseQuantity(maxStr)
+		if err != nil {
+			continue
+		}
+		if q.Cmp(max) > 0 {
+			return &sessionPolicyViolation{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("%s %s exceeds the project maximum of %s", field, q.String(), max.String()),
+				Audit:   fmt.Sprintf("sessionPolicy.maxResources.%s: %s > %s", field, q.String(), max.String()),
+			}
+		}
+	}
+	return nil
+}
+
+// enforceSessionResourcePolicy applies checkSessionResources. It writes the
+// error response and returns false on rejection.
+func enforceSessionResourcePolicy(c *gin.Context, reqDyn dynamic.Interface, project string, overrides *ResourceOverrides) bool {
+	if v := checkSessionResources(nil, overrides); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	if overrides == nil || *overrides == (ResourceOverrides{}) {
+		return true
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := checkSessionResources(spec, overrides); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// checkSessionScheduling rejects spec.scheduling when the project sets
+// runnerScheduling.allowSessionScheduling to false
+func checkSessionScheduling(spec map[string]interface{}, scheduling *SessionScheduling) *sessionPolicyViolation {
+	if scheduling == nil {
+		return nil
+	}
+	if allowed, found, _ := unstructured.NestedBool(spec, "runnerScheduling", "allowSessionScheduling"); found && !allowed {
+		return &sessionPolicyViolation{
+			Status:  http.StatusForbidden,
+			Message: "This project does not allow sessions to set scheduling",
+			Audit:   "runnerScheduling.allowSessionScheduling: false",
+		}
+	}
+	return nil
+}
+
+// enforceSessionSchedulingPolicy applies checkSessionScheduling. It writes the
+// error response and returns false on rejection.
+func enforceSessionSchedulingPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, scheduling *SessionScheduling) bool {
+	if scheduling == nil {
+		return true
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := checkSessionScheduling(spec, scheduling); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// checkSessionScratch rejects scratch sizes that are not valid quantities or
+// exceed sessionPolicy.scratch.maxSize
+func checkSessionScratch(spec map[string]interface{}, scratch *SessionScratch) *sessionPolicyViolation {
+	if scratch == nil || strings.TrimSpace(scratch.Size) == "" {
+		return nil
+	}
+	size, err := resource.ParseQuantity(strings.TrimSpace(scratch.Size))
+	if err != nil || size.Sign() <= 0 {
+		return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: "scratch.size must be a positive quantity such as 20Gi"}
+	}
+	if maxStr, _, _ := unstructured.NestedString(spec, "sessionPolicy", "scratch", "maxSize"); maxStr != "" {
+		if max, err := resource.ParseQuantity(maxStr); err == nil && size.Cmp(max) > 0 {
+			return &sessionPolicyViolation{
+				Status:  http.StatusBadRequest,
+				Message: fmt.Sprintf("scratch size %s exceeds the project maximum of %s", size.String(), max.String()),
+				Audit:   fmt.Sprintf("sessionPolicy.scratch.maxSize: %s > %s", size.String(), max.String()),
+			}
+		}
+	}
+	return nil
+}
+
+// enforceSessionScratchPolicy applies checkSessionScratch. It writes the error
+// response and returns false on rejection.
+func enforceSessionScratchPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, scratch *SessionScratch) bool {
+	if v := checkSessionScratch(nil, scratch); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	if scratch == nil || strings.TrimSpace(scratch.Size) == "" {
+		return true
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := checkSessionScratch(spec, scratch); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// modelProviderTypes are the provider types the operator knows how to configure
+var modelProviderTypes = []string{"anthropic", "openai", "bedrock", "vertex", "azure-openai"}
+
+// checkSessionModel resolves the session's model provider from ProjectSettings
+// spec.modelProviders and rejects blocked models, unknown providers, providers
+// the framework does not support and models outside the provider's
+// allowedModels. Projects without providers only accept anthropic via the runner
+// Secret.
+func checkSessionModel(ctx context.Context, spec map[string]interface{}, framework string, llm LLMSettings) (*sessionPolicyViolation, error) {
+	if v, err := checkBlockedModel(ctx, spec, llm.Model); v != nil || err != nil {
+		return v, err
+	}
+	providers, _, _ := unstructured.NestedMap(spec, "modelProviders", "providers")
+	if len(providers) == 0 {
+		if llm.Provider != "" && llm.Provider != "anthropic" {
+			return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: fmt.Sprintf("model provider %q is not configured for this project", llm.Provider)}, nil
+		}
+		return nil, nil
+	}
+
+	name := llm.Provider
+	if name == "" {
+		name, _, _ = unstructured.NestedString(spec, "modelProviders", "default")
+	}
+	if name == "" && len(providers) == 1 {
+		for only := range providers {
+			name = only
+		}
+	}
+	provider, ok := providers[name].(map[string]interface{})
+	if !ok {
+		names := make([]string, 0, len(providers))
+		for n := range providers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		msg := fmt.Sprintf("model provider %q is not configured (available: %s)", name, strings.Join(names, ", "))
+		if name == "" {
+			msg = fmt.Sprintf("llmSettings.provider is required (available: %s)", strings.Join(names, ", "))
+		}
+		return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: msg}, nil
+	}
+	providerType, _ := provider["type"].(string)
+	if providerType == "" {
+		providerType = name
+	}
+
+	supported, err := frameworkProviders(ctx, framework)
+	if err != nil {
+		return nil, fmt.Errorf("read framework %q: %v", framework, err)
+	}
+	if len(supported) > 0 && !slices.Contains(supported, providerType) {
+		return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: fmt.Sprintf("framework does not support %s models (supported: %s)", providerType, strings.Join(supported, ", "))}, nil
+	}
+
+	allowed, _, _ := unstructured.NestedStringSlice(provider, "allowedModels")
+	if len(allowed) == 0 || strings.TrimSpace(llm.Model) == "" {
+		return nil, nil
+	}
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, llm.Model); ok {
+			return nil, nil
+		}
+	}
+	return &sessionPolicyViolation{
+		Status:  http.StatusForbidden,
+		Message: fmt.Sprintf("model %q is not allowed for provider %q (allowed: %s)", llm.Model, name, strings.Join(allowed, ", ")),
+		Audit:   fmt.Sprintf("modelProviders.providers.%s.allowedModels: %s", name, llm.Model),
+	}, nil
+}
+
+// decideSessionModel applies checkSessionModel, or the external policy's model rule
+func decideSessionModel(c *gin.Context, project string, spec map[string]interface{}, cp ClusterPolicy, framework string, llm LLMSettings) (*sessionPolicyViolation, error) {
+	input := sessionPolicyInput(c, project, spec, cp, map[string]interface{}{"framework": framework, "llmSettings": llm})
+	return decideSessionPolicy(c, cp, "model", input, func() (*sessionPolicyViolation, error) {
+		return checkSessionModel(c.Request.Context(), spec, framework, llm)
+	})
+}
+
+// enforceSessionModelPolicy applies decideSessionModel. It writes the error
+// response and returns false on rejection.
+func enforceSessionModelPolicy(c *gin.Context, reqDyn dynamic.Interface, project, framework string, llm LLMSettings) bool {
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	cp, err := loadClusterPolicy(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return false
+	}
+	v, err := decideSessionModel(c, project, spec, cp, framework, llm)
+	if err != nil {
+		logErrorf(c, "Failed to validate model policy in %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to validate model policy")
+		return false
+	}
+	if v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// decideSessionBudget applies checkSessionBudget, or the external policy's
+// budget rule with the month's limit and spend in its input. The message
+// describes the budget for policy simulations.
+func decideSessionBudget(c *gin.Context, reqDyn dynamic.Interface, project string, spec map[string]interface{}, cp ClusterPolicy, budget *SessionBudget) (*sessionPolicyViolation, string, error) {
+	limit, spent, err := projectMonthlyBudget(c.Request.Context(), reqDyn, project, cp)
+	if err != nil {
+		return nil, "", err
+	}
+	_, msg := checkSessionBudget(limit, spent, nil)
+	input := sessionPolicyInput(c, project, spec, cp, map[string]interface{}{"budget": budget, "monthlyLimitUSD": limit, "monthlySpentUSD": spent})
+	v, err := decideSessionPolicy(c, cp, "budget", input, func() (*sessionPolicyViolation, error) {
+		v, _ := checkSessionBudget(limit, spent, budget)
+		return v, nil
+	})
+	return v, msg, err
+}
+
+// enforceSessionBudgetPolicy applies decideSessionBudget, which rejects new
+// sessions once the project's monthly budget is exhausted, or when budget asks
+// for more than remains of it. It writes the error response and returns false
+// on rejection.
+func enforceSessionBudgetPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, budget *SessionBudget) bool {
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	cp, err := loadClusterPolicy(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return false
+	}
+	v, _, err := decideSessionBudget(c, reqDyn, project, spec, cp, budget)
+	if err != nil {
+		logErrorf(c, "Failed to read budget for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project budget")
+		return false
+	}
+	if v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// checkSessionTTL rejects negative TTLs and TTLs that would delete a session
+// before the cluster's retention floor
+func checkSessionTTL(ttl *int64, cp ClusterPolicy) *sessionPolicyViolation {
+	if ttl == nil {
+		return nil
+	}
+	if *ttl < 0 {
+		return &sessionPolicyViolation{Status: http.StatusBadRequest, Message: "ttlSecondsAfterFinished must not be negative"}
+	}
+	if floor := cp.retentionFloor("sessions"); floor > 0 && time.Duration(*ttl)*time.Second < floor {
+		return &sessionPolicyViolation{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("ttlSecondsAfterFinished must be at least %d (cluster policy)", int64(floor/time.Second)),
+			Audit:   fmt.Sprintf("retention.minSessions: ttl %ds < %s", *ttl, cp.RetentionFloors["minSessions"]),
+		}
+	}
+	return nil
+}
+
+// enforceSessionTTLPolicy applies checkSessionTTL. It writes the error response
+// and returns false on rejection.
+func enforceSessionTTLPolicy(c *gin.Context, ttl *int64) bool {
+	if ttl == nil {
+		return true
+	}
+	cp, err := loadClusterPolicy(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return false
+	}
+	if v := checkSessionTTL(ttl, cp); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// sessionPriorities lists spec.priority values from lowest to highest
+var sessionPriorities = []string{"low", "normal", "high"}
+
+// checkSessionPriority rejects unknown priorities and priorities above
+// sessionPolicy.maxPriority. An empty priority means normal.
+func checkSessionPriority(spec map[string]interface{}, priority string) *sessionPolicyViolation {
+	priority = strings.TrimSpace(priority)
+	if priority == "" {
+		return nil
+	}
+	rank := slices.Index(sessionPriorities, priority)
+	if rank < 0 {
+		return &sessionPolicyViolation{
+			Status:  http.StatusBadRequest,
+			Message: fmt.Sprintf("priority must be one of %s", strings.Join(sessionPriorities, ", ")),
+		}
+	}
+	maxPriority, _, _ := unstructured.NestedString(spec, "sessionPolicy", "maxPriority")
+	if max := slices.Index(sessionPriorities, maxPriority); max >= 0 && rank > max {
+		return &sessionPolicyViolation{
+			Status:  http.StatusForbidden,
+			Message: fmt.Sprintf("priority %s exceeds the project maximum of %s", priority, maxPriority),
+			Audit:   fmt.Sprintf("sessionPolicy.maxPriority: %s > %s", priority, maxPriority),
+		}
+	}
+	return nil
+}
+
+// enforceSessionPriorityPolicy applies checkSessionPriority. It writes the error
+// response and returns false on rejection.
+func enforceSessionPriorityPolicy(c *gin.Context, reqDyn dynamic.Interface, project, priority string) bool {
+	if v := checkSessionPriority(nil, priority); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	if strings.TrimSpace(priority) == "" {
+		return true
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := checkSessionPriority(spec, priority); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// checkSessionTools rejects allowed tools outside the project's
+// sessionPolicy.allowedTools or on the cluster or project blocklist, so a
+// session's list can only narrow what the policy permits
+func checkSessionTools(spec map[string]interface{}, cp ClusterPolicy, tools *SessionTools) *sessionPolicyViolation {
+	if tools == nil {
+		return nil
+	}
+	permitted, _, _ := unstructured.NestedStringSlice(spec, "sessionPolicy", "allowedTools")
+	blocked := cp.blockedTools(spec)
+	for _, t := range tools.Allowed {
+		if slices.Contains(blocked, t) {
+			return &sessionPolicyViolation{
+				Status:  http.StatusForbidden,
+				Message: fmt.Sprintf("tool %s is blocked by policy", t),
+				Audit:   fmt.Sprintf("blockedTools: %s", t),
+			}
+		}
+		if len(permitted) > 0 && !slices.Contains(permitted, t) {
+			return &sessionPolicyViolation{
+				Status:  http.StatusForbidden,
+				Message: fmt.Sprintf("tool %s is not in the project's allowed tools (%s)", t, strings.Join(permitted, ", ")),
+				Audit:   fmt.Sprintf("sessionPolicy.allowedTools: %s", t),
+			}
+		}
+	}
+	return nil
+}
+
+// decideSessionTools applies checkSessionTools, or the external policy's tools rule
+func decideSessionTools(c *gin.Context, project string, spec map[string]interface{}, cp ClusterPolicy, tools *SessionTools) *sessionPolicyViolation {
+	input := sessionPolicyInput(c, project, spec, cp, map[string]interface{}{"tools": tools})
+	v, _ := decideSessionPolicy(c, cp, "tools", input, func() (*sessionPolicyViolation, error) {
+		return checkSessionTools(spec, cp, tools), nil
+	})
+	return v
+}
+
+// enforceSessionToolsPolicy applies decideSessionTools. It writes the error
+// response and returns false on rejection.
+func enforceSessionToolsPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, tools *SessionTools) bool {
+	cp, err := loadClusterPolicy(c.Request.Context())
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return false
+	}
+	// Without an external policy only tools.allowed can violate the policy
+	if cp.ExternalPolicy == nil && (tools == nil || len(tools.Allowed) == 0) {
+		return true
+	}
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return false
+	}
+	if v := decideSessionTools(c, project, spec, cp, tools); v != nil {
+		rejectSession(c, v)
+		return false
+	}
+	return true
+}
+
+// sessionToolsToSpec converts tools for the AgenticSession spec
+func sessionToolsToSpec(tools *SessionTools) map[string]interface{} {
+	out := map[string]interface{}{}
+	for key, list := range map[string][]string{"allowed": tools.Allowed, "blocked": tools.Blocked} {
+		if len(list) == 0 {
+			continue
+		}
+		items := make([]interface{}, 0, len(list))
+		for _, t := range list {
+			items = append(items, t)
+		}
+		out[key] = items
+	}
+	return out
+}
+
+// enforceCopiedSessionPolicy admits a session whose spec is copied from an
+// existing one (clone, retry): the project's policy may have changed since the
+// source was created. It writes the error response and returns false on rejection.
+func enforceCopiedSessionPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, spec map[string]interface{}) bool {
+	if t, ok := intFromSpec(spec, "timeout"); ok {
+		if !enforceSessionTimeoutPolicy(c, reqDyn, project, t) {
+			return false
+		}
+	}
+	parsed := parseSpec(spec)
+	return enforceSessionResourcePolicy(c, reqDyn, project, parsed.ResourceOverrides) &&
+		enforceSessionSchedulingPolicy(c, reqDyn, project, parsed.Scheduling) &&
+		enforceSessionScratchPolicy(c, reqDyn, project, parsed.Scratch) &&
+		enforceSessionModelPolicy(c, reqDyn, project, parsed.Framework, parsed.LLMSettings) &&
+		enforceSessionBudgetPolicy(c, reqDyn, project, parsed.Budget) &&
+		enforceSessionTTLPolicy(c, parsed.TTLSecondsAfterFinished) &&
+		enforceSessionPriorityPolicy(c, reqDyn, project, parsed.Priority) &&
+		enforceSessionToolsPolicy(c, reqDyn, project, parsed.Tools) &&
+		enforceSessionDebugPolicy(c, reqDyn, project, parsed.Debug)
+}
+
+// checkImmutableSessionFields rejects updates that change what a session was
+// created for: its trigger and framework. Omitted fields are left as they are,
+// so clients may resend a session as read. The namespace cannot change on any
+// Kubernetes object; a session is moved by cloning it.
+func checkImmutableSessionFields(current map[string]interface{}, req *CreateAgenticSessionRequest) *sessionPolicyViolation {
+	immutable := func(field string) *sessionPolicyViolation {
+		return &sessionPolicyViolation{Status: http.StatusUnprocessableEntity, Message: fmt.Sprintf("%s cannot be changed after the session is created", field)}
+	}
+	if fw := strings.TrimSpace(req.Framework); fw != "" {
+		currentFw, _ := current["framework"].(string)
+		if currentFw == "" {
+			currentFw = defaultSessionFramework
+		}
+		if fw != currentFw {
+			return immutable("framework")
+		}
+	}
+	if v := strings.TrimSpace(req.FrameworkVersion); v != "" {
+		if currentV, _ := current["frameworkVersion"].(string); v != currentV {
+			return immutable("frameworkVersion")
+		}
+	}
+	if req.Trigger != nil {
+		var currentTrigger SessionTrigger
+		if m, ok := current["trigger"].(map[string]interface{}); ok {
+			currentTrigger = *parseTrigger(m)
+		}
+		// The fingerprint and delivery id are assigned by the backend
+		requested := *req.Trigger
+		requested.Fingerprint, requested.DeliveryID = "", ""
+		currentTrigger.Fingerprint, currentTrigger.DeliveryID = "", ""
+		if requested != currentTrigger {
+			return immutable("trigger")
+		}
+	}
+	return nil
+}
+
+// terminalSessionPhases are phases a session only leaves when the operator restarts it
+var terminalSessionPhases = []string{"Completed", "Failed", "Stopped", "Error"}
+
+// checkSessionPhaseTransition rejects moving a finished session back to an active
+// phase (e.g. Completed to Running), which would resurrect it without a Job
+func checkSessionPhaseTransition(from, to string) *sessionPolicyViolation {
+	if to == "" || to == from || !slices.Contains(terminalSessionPhases, from) {
+		return nil
+	}
+	return &sessionPolicyViolation{
+		Status:  http.StatusConflict,
+		Message: fmt.Sprintf("session phase cannot change from %s to %s", from, to),
+		Audit:   fmt.Sprintf("status.phase: %s -> %s", from, to),
+	}
+}
+
+// isOperatorServiceAccount reports whether the caller authenticated with the
+// operator's ServiceAccount token, which may move sessions between any phases
+func isOperatorServiceAccount(c *gin.Context) bool {
+	sa := os.Getenv("OPERATOR_SERVICE_ACCOUNT")
+	if sa == "" {
+		sa = "agentic-operator"
+	}
+	return c.GetString("authSource") == "tokenreview" &&
+		c.GetString("userName") == fmt.Sprintf("system:serviceaccount:%s:%s", namespace, sa)
+}
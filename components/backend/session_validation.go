@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"ambient-code-backend/pkg/problem"
+	"ambient-code-shared/money"
+)
+
+// Session requests are validated declaratively: the binding tags on
+// CreateAgenticSessionRequest and its parts are checked by gin's validator
+// when the body is bound, with the rules registered here for the formats the
+// cluster understands. Failures are answered with one fieldError per field,
+// named by its JSON path (e.g. "tools.allowed[1]"), like ProjectSettings
+// validation. Checks that need the cluster, such as the Framework registry,
+// run after binding in validateSessionRequest.
+
+var (
+	toolNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*(\([^()]*\))?$`)
+	envNamePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// sessionValidationMessages describes the custom rules in field errors
+var sessionValidationMessages = map[string]string{
+	"toolname":   "must be a tool name such as Bash, WebFetch or Bash(git:*)",
+	"envname":    "must be an environment variable name (letters, digits and _, not starting with a digit)",
+	"quantity":   "must be a positive Kubernetes quantity such as 500m, 2Gi or 1",
+	"usd":        "must be a positive decimal amount in USD with at most six decimals, e.g. 2.50",
+	"dnsname":    "must be a lowercase DNS-1123 name",
+	"labelkey":   "must be a qualified label or annotation key",
+	"labelvalue": "must be a valid label value (at most 63 characters of letters, digits, -, _ and .)",
+}
+
+// registerSessionValidators adds the custom rules to gin's validator and makes
+// it report JSON field names
+func registerSessionValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("unexpected binding validator %T", binding.Validator.Engine())
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	rules := map[string]func(string) bool{
+		"toolname": toolNamePattern.MatchString,
+		"envname":  envNamePattern.MatchString,
+		"quantity": func(s string) bool {
+			q, err := resource.ParseQuantity(s)
+			return err == nil && q.Sign() > 0
+		},
+		"usd": func(s string) bool {
+			amount, err := money.Parse(strings.TrimSpace(s))
+			return err == nil && amount > 0
+		},
+		"dnsname":    func(s string) bool { return len(validation.IsDNS1123Subdomain(s)) == 0 },
+		"labelkey":   func(s string) bool { return len(validation.IsQualifiedName(s)) == 0 },
+		"labelvalue": func(s string) bool { return len(validation.IsValidLabelValue(s)) == 0 },
+	}
+	for tag, valid := range rules {
+		if err := v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return valid(fl.Field().String())
+		}); err != nil {
+			return err
+		}
+	}
+	v.RegisterStructValidation(validateSessionTools, SessionTools{})
+	return nil
+}
+
+// validateSessionTools rejects a tool that is both allowed and blocked
+func validateSessionTools(sl validator.StructLevel) {
+	tools := sl.Current().Interface().(SessionTools)
+	for i, name := range tools.Blocked {
+		for _, allowed := range tools.Allowed {
+			if name == allowed {
+				sl.ReportError(tools.Blocked[i], fmt.Sprintf("blocked[%d]", i), "Blocked", "notallowed", "")
+				break
+			}
+		}
+	}
+}
+
+// bindingFieldErrors turns validator failures into field errors; ok is false
+// for other binding errors, such as malformed JSON
+func bindingFieldErrors(err error) ([]PolicyFieldError, bool) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil, false
+	}
+	fieldErrors := make([]PolicyFieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		// The namespace starts with the Go type of the bound value
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fieldErrors = append(fieldErrors, PolicyFieldError{Field: field, Message: fieldErrorMessage(fe)})
+	}
+	return fieldErrors, true
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	if msg, ok := sessionValidationMessages[fe.Tag()]; ok {
+		return msg
+	}
+	countable := fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map || fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "unique":
+		return "must not contain duplicates"
+	case "notallowed":
+		return "must not also be allowed"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "max", "lte":
+		if countable {
+			return "must have at most " + fe.Param() + " entries or characters"
+		}
+		return "must be at most " + fe.Param()
+	case "min", "gte":
+		if countable {
+			return "must have at least " + fe.Param() + " entries or characters"
+		}
+		return "must be at least " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	}
+	return "is invalid (" + fe.Tag() + ")"
+}
+
+// respondSessionBindError answers a failed ShouldBindJSON: 422 with field errors
+// when the body decoded but failed validation, 400 otherwise
+func respondSessionBindError(c *gin.Context, err error) {
+	if fieldErrors, ok := bindingFieldErrors(err); ok {
+		respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", "Invalid session request").With("fieldErrors", fieldErrors))
+		return
+	}
+	respondError(c, http.StatusBadRequest, err.Error())
+}
+
+// validateSessionRequest runs the checks binding tags cannot express: the
+// trigger's schema, and the framework and version against the Framework
+// registry. Dry runs report the framework in their plan instead.
+func validateSessionRequest(ctx context.Context, req *CreateAgenticSessionRequest) ([]PolicyFieldError, error) {
+	fieldErrors := validateSessionTrigger(req.Trigger)
+	if req.DryRun {
+		return fieldErrors, nil
+	}
+	fe, err := checkSessionFramework(ctx, req.Framework, req.FrameworkVersion)
+	if err != nil {
+		return nil, err
+	}
+	if fe != nil {
+		fieldErrors = append(fieldErrors, *fe)
+	}
+	return fieldErrors, nil
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// sessionWatchBufferSize is how many recent session changes are kept for
+	// watches resuming from a resourceVersion
+	sessionWatchBufferSize = 1024
+	// sessionWatchQueue is how many changes a watcher may fall behind by before
+	// its stream is closed
+	sessionWatchQueue = 256
+	// maxSessionWatchSeconds bounds a watch; clients reconnect with the last
+	// resourceVersion, which also re-checks their access
+	maxSessionWatchSeconds = 1800
+)
+
+// sessionWatchEvent is a session change seen by the shared informer. Old is
+// the previous state of a MODIFIED session.
+type sessionWatchEvent struct {
+	rv  uint64
+	typ string
+	obj *unstructured.Unstructured
+	old *unstructured.Unstructured
+}
+
+// SessionWatchEvent is one line of a session list watch. Type is ADDED,
+// MODIFIED or DELETED with the session; BOOKMARK carries only the
+// resourceVersion to resume from; ERROR ends the stream.
+type SessionWatchEvent struct {
+	Type            string          `json:"type"`
+	Object          *AgenticSession `json:"object,omitempty"`
+	ResourceVersion string          `json:"resourceVersion,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// sessionWatchHub fans the session informer's changes out to watchers and
+// keeps the most recent ones so a watch can resume where it left off
+type sessionWatchHub struct {
+	mu     sync.Mutex
+	events []sessionWatchEvent
+	// oldest is the lowest resourceVersion a watch may resume from: every
+	// change after it is still buffered. Zero until the informer has synced.
+	oldest uint64
+	latest uint64
+	subs   map[chan sessionWatchEvent]struct{}
+}
+
+var sessionWatches = &sessionWatchHub{subs: map[chan sessionWatchEvent]struct{}{}}
+
+func resourceVersionNumber(obj *unstructured.Unstructured) uint64 {
+	rv, _ := strconv.ParseUint(obj.GetResourceVersion(), 10, 64)
+	return rv
+}
+
+// handler records the informer's changes. Adds from the initial list are the
+// starting state rather than changes, and resyncs repeat a version already seen.
+func (h *sessionWatchHub) handler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if u, ok := obj.(*unstructured.Unstructured); ok && !isInInitialList {
+				h.publish(sessionWatchEvent{typ: "ADDED", obj: u})
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, okOld := oldObj.(*unstructured.Unstructured)
+			u, okNew := newObj.(*unstructured.Unstructured)
+			if okOld && okNew && old.GetResourceVersion() != u.GetResourceVersion() {
+				h.publish(sessionWatchEvent{typ: "MODIFIED", obj: u, old: old})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				h.publish(sessionWatchEvent{typ: "DELETED", obj: u})
+			}
+		},
+	}
+}
+
+// synced opens the hub for resumption from resourceVersion
+func (h *sessionWatchHub) synced(resourceVersion string) {
+	rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.oldest = rv
+	if rv > h.latest {
+		h.latest = rv
+	}
+}
+
+// currentVersion is the latest resourceVersion seen, empty before the
+// informer has synced
+func (h *sessionWatchHub) currentVersion() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.oldest == 0 {
+		return ""
+	}
+	return strconv.FormatUint(h.latest, 10)
+}
+
+func (h *sessionWatchHub) publish(ev sessionWatchEvent) {
+	ev.rv = resourceVersionNumber(ev.obj)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ev.rv > h.latest {
+		h.latest = ev.rv
+	}
+	h.events = append(h.events, ev)
+	if len(h.events) > sessionWatchBufferSize {
+		if dropped := h.events[0].rv; dropped > h.oldest {
+			h.oldest = dropped
+		}
+		h.events = h.events[1:]
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			// A watcher that fell behind is closed; it resumes from its last
+			// resourceVersion out of the buffer
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe registers a watcher. With since set it also returns the buffered
+// changes after it, and ok is false when those are no longer all buffered.
+func (h *sessionWatchHub) subscribe(since string) (ch chan sessionWatchEvent, replay []sessionWatchEvent, latest uint64, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if since != "" {
+		rv, err := strconv.ParseUint(since, 10, 64)
+		if err != nil || h.oldest == 0 || rv < h.oldest {
+			return nil, nil, 0, false
+		}
+		for _, ev := range h.events {
+			if ev.rv > rv {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	ch = make(chan sessionWatchEvent, sessionWatchQueue)
+	h.subs[ch] = struct{}{}
+	return ch, replay, h.latest, true
+}
+
+func (h *sessionWatchHub) unsubscribe(ch chan sessionWatchEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// forWatcher maps a change to what a watcher of project and selector sees: a
+// session that starts matching is ADDED and one that stops matching DELETED
+func (ev sessionWatchEvent) forWatcher(project string, selector labels.Selector) (string, bool) {
+	if ev.obj.GetNamespace() != project {
+		return "", false
+	}
+	matches := selector.Matches(labels.Set(ev.obj.GetLabels()))
+	if ev.typ != "MODIFIED" {
+		return ev.typ, matches
+	}
+	matched := selector.Matches(labels.Set(ev.old.GetLabels()))
+	switch {
+	case matches && matched:
+		return "MODIFIED", true
+	case matches:
+		return "ADDED", true
+	case matched:
+		return "DELETED", true
+	}
+	return "", false
+}
+
+// agenticSessionFromObject converts a session for API responses
+func agenticSessionFromObject(item *unstructured.Unstructured) AgenticSession {
+	session := AgenticSession{
+		APIVersion: item.GetAPIVersion(),
+		Kind:       item.GetKind(),
+		Metadata:   item.Object["metadata"].(map[string]interface{}),
+	}
+	if spec, ok := item.Object["spec"].(map[string]interface{}); ok {
+		session.Spec = parseSpec(spec)
+	}
+	if status, ok := item.Object["status"].(map[string]interface{}); ok {
+		session.Status = parseStatus(status)
+	}
+	return session
+}
+
+// watchSessions streams session changes as JSON lines from the shared
+// informer. Without resourceVersion the stream opens with an ADDED line for
+// each current session; with one it replays the buffered changes after it or
+// answers 410 when they are gone. BOOKMARK lines every sseKeepalive carry the
+// version to resume from.
+func watchSessions(c *gin.Context, project string, selector labels.Selector) {
+	if !informersSynced.Load() {
+		respondError(c, http.StatusServiceUnavailable, "session cache is not ready; retry shortly")
+		return
+	}
+	timeout := maxSessionWatchSeconds
+	if raw := c.Query("timeoutSeconds"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSessionWatchSeconds {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("timeoutSeconds must be between 1 and %d", maxSessionWatchSeconds))
+			return
+		}
+		timeout = n
+	}
+
+	since := c.Query("resourceVersion")
+	ch, replay, latest, ok := sessionWatches.subscribe(since)
+	if !ok {
+		respondError(c, http.StatusGone, "resourceVersion is too old or invalid; list again and watch from the list's resourceVersion")
+		return
+	}
+	defer sessionWatches.unsubscribe(ch)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	send := func(ev SessionWatchEvent) bool {
+		if err := enc.Encode(ev); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+	sendChange := func(ev sessionWatchEvent) bool {
+		typ, visible := ev.forWatcher(project, selector)
+		if !visible {
+			return true
+		}
+		session := agenticSessionFromObject(ev.obj)
+		return send(SessionWatchEvent{Type: typ, Object: &session, ResourceVersion: ev.obj.GetResourceVersion()})
+	}
+
+	lastRV := strconv.FormatUint(latest, 10)
+	if since == "" {
+		items, _ := cachedSessions(project, selector)
+		sort.Slice(items, func(i, j int) bool { return items[i].GetName() < items[j].GetName() })
+		for i := range items {
+			session := agenticSessionFromObject(&items[i])
+			if !send(SessionWatchEvent{Type: "ADDED", Object: &session, ResourceVersion: items[i].GetResourceVersion()}) {
+				return
+			}
+		}
+	} else {
+		lastRV = since
+		for _, ev := range replay {
+			if !sendChange(ev) {
+				return
+			}
+			lastRV = strconv.FormatUint(ev.rv, 10)
+		}
+	}
+	if !send(SessionWatchEvent{Type: "BOOKMARK", ResourceVersion: lastRV}) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	deadline := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer deadline.Stop()
+	bookmark := time.NewTicker(sseKeepalive)
+	defer bookmark.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			send(SessionWatchEvent{Type: "BOOKMARK", ResourceVersion: lastRV})
+			return
+		case <-bookmark.C:
+			if !send(SessionWatchEvent{Type: "BOOKMARK", ResourceVersion: lastRV}) {
+				return
+			}
+		case ev, open := <-ch:
+			if !open {
+				send(SessionWatchEvent{Type: "ERROR", Error: "watch fell behind; resume from the last resourceVersion"})
+				return
+			}
+			if !sendChange(ev) {
+				return
+			}
+			lastRV = strconv.FormatUint(ev.rv, 10)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+// getProjectSettingsSpec returns spec of the namespace's ProjectSettings singleton.
+// A missing ProjectSettings yields an empty map so callers fall back to defaults.
+// The spec governs what the caller may do, so once the informer cache has
+// synced it is read from there regardless of the caller's own access; until
+// then it is read with dyn.
+func getProjectSettingsSpec(ctx context.Context, dyn dynamic.Interface, project string) (map[string]interface{}, error) {
+	obj, cached := cachedProjectSettings(project)
+	if !cached {
+		var err error
+		obj, err = dyn.Resource(getProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	if obj == nil {
+		return map[string]interface{}{}, nil
+	}
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	return spec, nil
+}
+
+// intFromSpec reads an integer field from an unstructured map, accepting the
+// numeric types produced by JSON and YAML decoding.
+func intFromSpec(m map[string]interface{}, key string) (int64, bool) {
+	switch v := m[key].(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// intFromEnv parses an integer env var, returning def when unset or invalid.
+func intFromEnv(name string, def int64) int64 {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+
+	"ambient-code-backend/pkg/problem"
+	"ambient-code-shared/money"
+)
+
+// PolicyRuleResult is the outcome of one admission rule for a candidate session
+type PolicyRuleResult struct {
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// PolicySimulation reports whether a candidate session would be admitted and why
+type PolicySimulation struct {
+	Allowed bool               `json:"allowed"`
+	Rules   []PolicyRuleResult `json:"rules"`
+	// Warnings are soft issues that would not block creation
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (s *PolicySimulation) record(rule string, v *sessionPolicyViolation, passMessage string) {
+	if v != nil {
+		s.Allowed = false
+		s.Rules = append(s.Rules, PolicyRuleResult{Rule: rule, Message: v.Message})
+		return
+	}
+	s.Rules = append(s.Rules, PolicyRuleResult{Rule: rule, Passed: true, Message: passMessage})
+}
+
+// currentBudgetStatus returns ProjectSettings status.budget when it describes
+// the budget month in progress: until its periodEnd, or through the UTC
+// calendar month for statuses recorded without one. After a month ends the
+// operator's reset archives it, so a stale status reads as nothing spent.
+func currentBudgetStatus(obj *unstructured.Unstructured) (map[string]interface{}, bool) {
+	if obj == nil {
+		return nil, false
+	}
+	status, found, _ := unstructured.NestedMap(obj.Object, "status", "budget")
+	if !found {
+		return nil, false
+	}
+	now := time.Now().UTC()
+	if raw, _ := status["periodEnd"].(string); raw != "" {
+		end, err := time.Parse(time.RFC3339, raw)
+		return status, err == nil && now.Before(end)
+	}
+	return status, status["month"] == now.Format("2006-01")
+}
+
+// monthlySpend returns the budget month's spend recorded by the operator in
+// ProjectSettings status.budget, or 0 when it was recorded for an earlier month
+func monthlySpend(obj *unstructured.Unstructured) money.USD {
+	status, ok := currentBudgetStatus(obj)
+	if !ok {
+		return 0
+	}
+	spent, _ := money.FromSpec(status["spentUSD"])
+	return spent
+}
+
+// monthlyUsage returns the budget month's token and API call totals the
+// operator recorded in ProjectSettings status.budget, nil when there are none
+func monthlyUsage(obj *unstructured.Unstructured) *MonthlyUsage {
+	status, ok := currentBudgetStatus(obj)
+	usage, isMap := status["usage"].(map[string]interface{})
+	if !ok || !isMap {
+		return nil
+	}
+	out := &MonthlyUsage{}
+	out.InputTokens, _ = intFromSpec(usage, "inputTokens")
+	out.OutputTokens, _ = intFromSpec(usage, "outputTokens")
+	out.APICalls, _ = intFromSpec(usage, "apiCalls")
+	return out
+}
+
+// projectBudget returns the effective monthly limit (the lower of the project's
+// budget.monthlyLimitUSD and the cluster maximum; 0 when neither is set) and the
+// share of it at which to warn
+func projectBudget(obj *unstructured.Unstructured, cp ClusterPolicy) (money.USD, int64) {
+	var limit money.USD
+	warnPercent := int64(80)
+	if obj != nil {
+		if raw, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "budget", "monthlyLimitUSD"); found {
+			limit, _ = money.FromSpec(raw)
+		}
+		if budget, ok, _ := unstructured.NestedMap(obj.Object, "spec", "budget"); ok {
+			if v, ok := intFromSpec(budget, "warnPercent"); ok && v > 0 && v <= 100 {
+				warnPercent = v
+			}
+		}
+	}
+	if cp.MaxMonthlyCostUSD > 0 && (limit <= 0 || limit > cp.MaxMonthlyCostUSD) {
+		limit = cp.MaxMonthlyCostUSD
+	}
+	return limit, warnPercent
+}
+
+// projectMonthlyBudget returns the project's effective monthly limit (0 when none
+// is configured) and the month's spend
+func projectMonthlyBudget(ctx context.Context, reqDyn dynamic.Interface, project string, cp ClusterPolicy) (limit, spent money.USD, err error) {
+	obj, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return 0, 0, err
+	}
+	limit, _ = projectBudget(obj, cp)
+	return limit, monthlySpend(obj), nil
+}
+
+// checkSessionBudget rejects new sessions once the month's spend recorded by the
+// operator in ProjectSettings status.budget reaches the effective limit, and
+// sessions whose budget.maxCostUSD exceeds what remains of it
+func checkSessionBudget(limit, spent money.USD, budget *SessionBudget) (*sessionPolicyViolation, string) {
+	if limit <= 0 {
+		return nil, "no budget configured"
+	}
+	if spent >= limit {
+		return &sessionPolicyViolation{
+			Status:  http.StatusForbidden,
+			Message: fmt.Sprintf("monthly budget exhausted ($%s of $%s spent)", spent.Cents(), limit.Cents()),
+			Audit:   "budget.monthlyLimitUSD exhausted",
+			Code:    problem.CodeBudgetExceeded,
+		}, ""
+	}
+	if budget != nil {
+		if maxCost, err := money.Parse(strings.TrimSpace(budget.MaxCostUSD)); err == nil && spent+maxCost > limit {
+			return &sessionPolicyViolation{
+				Status:  http.StatusForbidden,
+				Message: fmt.Sprintf("session budget of $%s exceeds the $%s left of the monthly budget", maxCost.Cents(), (limit - spent).Cents()),
+				Audit:   "budget.monthlyLimitUSD: session maxCostUSD exceeds the remainder",
+				Code:    problem.CodeBudgetExceeded,
+			}, ""
+		}
+	}
+	return nil, fmt.Sprintf("$%s of $%s spent this month", spent.Cents(), limit.Cents())
+}
+
+// POST /api/projects/:projectName/policy/simulate
+// simulateSessionPolicy evaluates a candidate session (the create request body)
+// against the project and cluster policy without creating anything, so denials
+// can be debugged before submitting.
+func simulateSessionPolicy(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	var req CreateAgenticSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondSessionBindError(c, err)
+		return
+	}
+	sim, ok := evaluateSessionPolicy(c, reqDyn, project, req)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sim)
+}
+
+// evaluateSessionPolicy runs every admission rule for req, where createSession
+// stops at the first violation. It writes the error response itself and
+// returns false when a rule cannot be evaluated.
+func evaluateSessionPolicy(c *gin.Context, reqDyn dynamic.Interface, project string, req CreateAgenticSessionRequest) (*PolicySimulation, bool) {
+	ctx := c.Request.Context()
+	spec, ok := sessionPolicySpec(c, reqDyn, project)
+	if !ok {
+		return nil, false
+	}
+	clusterPolicy, err := loadClusterPolicy(ctx)
+	if err != nil {
+		logErrorf(c, "Failed to read ClusterAmbientPolicy: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to read cluster policy")
+		return nil, false
+	}
+
+	sim := &PolicySimulation{Allowed: true, Rules: []PolicyRuleResult{}}
+	if req.Timeout != nil {
+		sim.record("timeout", checkSessionTimeout(spec, int64(*req.Timeout)), "")
+	} else {
+		sim.record("timeout", nil, "unset; the operator's default deadline applies")
+	}
+	sim.record("resources", checkSessionResources(spec, req.ResourceOverrides), "")
+	sim.record("scheduling", checkSessionScheduling(spec, req.Scheduling), "")
+	sim.record("scratch", checkSessionScratch(spec, req.Scratch), "")
+	sim.record("priority", checkSessionPriority(spec, req.Priority), "")
+	sim.record("debug", checkSessionDebug(spec, req.Debug), "")
+
+	llm := sessionLLMSettings(req.LLMSettings)
+	modelViolation, err := decideSessionModel(c, project, spec, clusterPolicy, req.Framework, llm)
+	if err != nil {
+		logErrorf(c, "Failed to simulate model policy in %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to validate model policy")
+		return nil, false
+	}
+	sim.record("model", modelViolation, "")
+
+	msg, err := validateSessionFramework(ctx, req.Framework, req.FrameworkVersion)
+	if err != nil {
+		logErrorf(c, "Failed to validate framework %q in %s: %v", req.Framework, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to validate framework")
+		return nil, false
+	}
+	var frameworkViolation *sessionPolicyViolation
+	if msg != "" {
+		frameworkViolation = &sessionPolicyViolation{Status: http.StatusBadRequest, Message: msg}
+	}
+	sim.record("framework", frameworkViolation, "")
+
+	// Blocked tools only reject a session that asks for them in tools.allowed;
+	// otherwise the runner starts without them
+	blockedTools := clusterPolicy.blockedTools(spec)
+	toolsMessage := "no tools are blocked"
+	if len(blockedTools) > 0 {
+		toolsMessage = "disabled by policy: " + strings.Join(blockedTools, ", ")
+	}
+	if allowed, _, _ := unstructured.NestedStringSlice(spec, "sessionPolicy", "allowedTools"); len(allowed) > 0 {
+		toolsMessage += "; only " + strings.Join(allowed, ", ") + " may be used"
+	}
+	if max, _, _ := unstructured.NestedInt64(spec, "sessionPolicy", "maxToolViolations"); max > 0 {
+		toolsMessage += fmt.Sprintf("; the session fails after %d blocked tool calls", max)
+	}
+	if clusterPolicy.ExternalPolicy != nil {
+		toolsMessage = "decided by the external policy"
+	}
+	sim.record("tools", decideSessionTools(c, project, spec, clusterPolicy, req.Tools), toolsMessage)
+
+	budgetViolation, budgetMessage, err := decideSessionBudget(c, reqDyn, project, spec, clusterPolicy, req.Budget)
+	if err != nil {
+		logErrorf(c, "Failed to read budget for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project budget")
+		return nil, false
+	}
+	sim.record("budget", budgetViolation, budgetMessage)
+	sim.record("ttl", checkSessionTTL(req.TTLSecondsAfterFinished, clusterPolicy), "")
+
+	if len(req.Inputs) > 0 {
+		msg, err := validateSessionInputs(c, reqDyn, project, req.Inputs)
+		if err != nil {
+			logErrorf(c, "Failed to validate session inputs in %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to validate session inputs")
+			return nil, false
+		}
+		var inputsViolation *sessionPolicyViolation
+		if msg != "" {
+			inputsViolation = &sessionPolicyViolation{Status: http.StatusBadRequest, Message: msg}
+		}
+		sim.record("inputs", inputsViolation, "")
+	}
+
+	sim.Warnings = sessionAdmissionWarnings(ctx, reqDyn, project, req.Framework, req.FrameworkVersion)
+	return sim, true
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	sloMaxWindow     = 90 * 24 * time.Hour
+	sloDefaultWindow = "24h,7d"
+)
+
+// sessionTimings are a session's latencies measured from its creation: until
+// the operator started its runner (time in Pending), until the agent's first
+// output and until it finished. A zero value was not reached.
+type sessionTimings struct {
+	framework   string
+	queue       time.Duration
+	firstOutput time.Duration
+	endToEnd    time.Duration
+}
+
+func timingsOfSession(obj *unstructured.Unstructured) sessionTimings {
+	t := sessionTimings{framework: defaultSessionFramework}
+	if fw, _, _ := unstructured.NestedString(obj.Object, "spec", "framework"); fw != "" {
+		t.framework = fw
+	}
+	created := obj.GetCreationTimestamp().Time
+	since := func(field string) time.Duration {
+		raw, _, _ := unstructured.NestedString(obj.Object, "status", field)
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil || at.Before(created) {
+			return 0
+		}
+		// A zero duration would read as "not reached"
+		return max(at.Sub(created), time.Nanosecond)
+	}
+	t.queue = since("startTime")
+	t.firstOutput = since("firstOutputTime")
+	t.endToEnd = since("completionTime")
+	return t
+}
+
+// LatencySummary is the distribution of one latency over a window
+type LatencySummary struct {
+	Count      int     `json:"count"`
+	P50Seconds float64 `json:"p50Seconds"`
+	P95Seconds float64 `json:"p95Seconds"`
+}
+
+func summarizeLatencies(d []time.Duration) LatencySummary {
+	if len(d) == 0 {
+		return LatencySummary{}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	// Nearest-rank percentile
+	rank := func(p float64) float64 {
+		i := int(p*float64(len(d))+0.999999) - 1
+		return d[min(max(i, 0), len(d)-1)].Seconds()
+	}
+	return LatencySummary{Count: len(d), P50Seconds: rank(0.5), P95Seconds: rank(0.95)}
+}
+
+// FrameworkSLO summarizes the sessions of one framework created in a window
+type FrameworkSLO struct {
+	Framework         string         `json:"framework"`
+	Sessions          int            `json:"sessions"`
+	QueueTime         LatencySummary `json:"queueTime"`
+	TimeToFirstOutput LatencySummary `json:"timeToFirstOutput"`
+	EndToEnd          LatencySummary `json:"endToEnd"`
+}
+
+// SLOWindow is the SLO report for sessions created since Since
+type SLOWindow struct {
+	Window     string         `json:"window"`
+	Since      string         `json:"since"`
+	All        FrameworkSLO   `json:"all"`
+	Frameworks []FrameworkSLO `json:"frameworks"`
+}
+
+// ProjectSLO is the response of GET /api/projects/:projectName/slo
+type ProjectSLO struct {
+	Namespace string      `json:"namespace"`
+	Windows   []SLOWindow `json:"windows"`
+}
+
+type latencySamples struct {
+	sessions                     int
+	queue, firstOutput, endToEnd []time.Duration
+}
+
+func (s *latencySamples) add(t sessionTimings) {
+	s.sessions++
+	if t.queue > 0 {
+		s.queue = append(s.queue, t.queue)
+	}
+	if t.firstOutput > 0 {
+		s.firstOutput = append(s.firstOutput, t.firstOutput)
+	}
+	if t.endToEnd > 0 {
+		s.endToEnd = append(s.endToEnd, t.endToEnd)
+	}
+}
+
+func (s *latencySamples) summary(framework string) FrameworkSLO {
+	return FrameworkSLO{
+		Framework:         framework,
+		Sessions:          s.sessions,
+		QueueTime:         summarizeLatencies(s.queue),
+		TimeToFirstOutput: summarizeLatencies(s.firstOutput),
+		EndToEnd:          summarizeLatencies(s.endToEnd),
+	}
+}
+
+// sloWindow summarizes the sessions created within window before now, overall
+// and per framework
+func sloWindow(sessions []unstructured.Unstructured, label string, window time.Duration, now time.Time) SLOWindow {
+	since := now.Add(-window)
+	all := &latencySamples{}
+	byFramework := map[string]*latencySamples{}
+	for i := range sessions {
+		if sessions[i].GetCreationTimestamp().Time.Before(since) {
+			continue
+		}
+		t := timingsOfSession(&sessions[i])
+		all.add(t)
+		if byFramework[t.framework] == nil {
+			byFramework[t.framework] = &latencySamples{}
+		}
+		byFramework[t.framework].add(t)
+	}
+	out := SLOWindow{Window: label, Since: since.UTC().Format(time.RFC3339), All: all.summary(""), Frameworks: []FrameworkSLO{}}
+	for fw, s := range byFramework {
+		out.Frameworks = append(out.Frameworks, s.summary(fw))
+	}
+	sort.Slice(out.Frameworks, func(i, j int) bool { return out.Frameworks[i].Framework < out.Frameworks[j].Framework })
+	return out
+}
+
+// GET /api/projects/:projectName/slo
+// getProjectSLO reports p50 and p95 of time in Pending, time to first output and
+// end-to-end duration of the project's sessions, overall and per framework.
+// ?windows= takes a comma-separated list of durations such as 1h,24h,7d (default
+// 24h,7d, at most 90d); sessions count in a window by creation time. Sessions
+// already removed by retention are not included.
+func getProjectSLO(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	raw := c.DefaultQuery("windows", sloDefaultWindow)
+	type window struct {
+		label string
+		d     time.Duration
+	}
+	var windows []window
+	for _, w := range strings.Split(raw, ",") {
+		w = strings.TrimSpace(w)
+		d, err := parseRetentionDuration(w)
+		if err != nil || d <= 0 || d > sloMaxWindow {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("window %q must be a duration such as 24h or 7d, at most 90d", w))
+			return
+		}
+		windows = append(windows, window{w, d})
+	}
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		sessions = list.Items
+	}
+
+	now := time.Now()
+	out := ProjectSLO{Namespace: project}
+	for _, w := range windows {
+		out.Windows = append(out.Windows, sloWindow(sessions, w.label, w.d, now))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// sessionSLOMetrics renders the session latencies of every namespace and
+// framework over SLO_METRICS_WINDOW (default 24h) as Prometheus summaries
+func sessionSLOMetrics() string {
+	if !informersSynced.Load() {
+		return ""
+	}
+	window := 24 * time.Hour
+	if d, err := parseRetentionDuration(os.Getenv("SLO_METRICS_WINDOW")); err == nil && d > 0 {
+		window = d
+	}
+	objs, err := sessionLister.List(labels.Everything())
+	if err != nil {
+		return ""
+	}
+	byNamespace := map[string][]unstructured.Unstructured{}
+	for _, obj := range objs {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			byNamespace[u.GetNamespace()] = append(byNamespace[u.GetNamespace()], *u)
+		}
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	metrics := []struct {
+		name, help string
+		pick       func(FrameworkSLO) LatencySummary
+	}{
+		{"ambient_session_queue_seconds", "Time sessions spent Pending before their runner started", func(f FrameworkSLO) LatencySummary { return f.QueueTime }},
+		{"ambient_session_first_output_seconds", "Time from session creation to the agent's first output", func(f FrameworkSLO) LatencySummary { return f.TimeToFirstOutput }},
+		{"ambient_session_duration_seconds", "Time from session creation to completion", func(f FrameworkSLO) LatencySummary { return f.EndToEnd }},
+	}
+	now := time.Now()
+	summaries := map[string][]FrameworkSLO{}
+	for _, ns := range namespaces {
+		summaries[ns] = sloWindow(byNamespace[ns], "", window, now).Frameworks
+	}
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s over the last %s\n# TYPE %s summary\n", m.name, m.help, window, m.name)
+		for _, ns := range namespaces {
+			for _, f := range summaries[ns] {
+				s := m.pick(f)
+				if s.Count == 0 {
+					continue
+				}
+				labels := fmt.Sprintf("namespace=%q,framework=%q", ns, f.Framework)
+				fmt.Fprintf(&b, "%s{%s,quantile=\"0.5\"} %g\n", m.name, labels, s.P50Seconds)
+				fmt.Fprintf(&b, "%s{%s,quantile=\"0.95\"} %g\n", m.name, labels, s.P95Seconds)
+				fmt.Fprintf(&b, "%s_count{%s} %d\n", m.name, labels, s.Count)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"ambient-code-shared/money"
+)
+
+// SessionCounts tallies a project's sessions by phase. Running includes
+// Creating, Failed includes Error, and Queued is the part of Pending waiting
+// for capacity.
+type SessionCounts struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Queued    int `json:"queued"`
+	Running   int `json:"running"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Stopped   int `json:"stopped"`
+}
+
+// BudgetUsage is the month's spend recorded by the operator in ProjectSettings
+// status.budget against the effective limit (0 when none is configured)
+type BudgetUsage struct {
+	Month       string    `json:"month"`
+	SpentUSD    money.USD `json:"spentUSD"`
+	LimitUSD    money.USD `json:"limitUSD,omitempty"`
+	WarnPercent int64     `json:"warnPercent,omitempty"`
+	// ResetsAt is when the next budget month starts, per budget.resetDay
+	ResetsAt string `json:"resetsAt,omitempty"`
+	// Usage sums what runners reported in the month's sessions
+	Usage *MonthlyUsage `json:"usage,omitempty"`
+}
+
+// MonthlyUsage is ProjectSettings status.budget.usage
+type MonthlyUsage struct {
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+	APICalls     int64 `json:"apiCalls"`
+}
+
+// StorageUsage is the size of the project's session workspaces and its quota
+type StorageUsage struct {
+	UsedBytes int64        `json:"usedBytes"`
+	Files     int64        `json:"files"`
+	Quota     StorageQuota `json:"quota"`
+}
+
+// ProjectStats is the namespace-level usage. Storage is only reported by the
+// per-project stats endpoint, as it asks the project's content service.
+type ProjectStats struct {
+	Sessions SessionCounts `json:"sessions"`
+	Budget   BudgetUsage   `json:"budget"`
+	Storage  *StorageUsage `json:"storage,omitempty"`
+}
+
+// projectStats counts sessions and reads the budget, from the informer caches
+// once synced and otherwise with dyn. Callers must have checked the caller may
+// list sessions in the project.
+func projectStats(ctx context.Context, dyn dynamic.Interface, project string) (ProjectStats, error) {
+	var stats ProjectStats
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := dyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{})
+		if err != nil {
+			return stats, err
+		}
+		sessions = list.Items
+	}
+	for _, item := range sessions {
+		stats.Sessions.Total++
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		switch phase {
+		case "", "Pending":
+			stats.Sessions.Pending++
+			if _, found, _ := unstructured.NestedMap(item.Object, "status", "queue"); found {
+				stats.Sessions.Queued++
+			}
+		case "Creating", "Running":
+			stats.Sessions.Running++
+		case "Completed":
+			stats.Sessions.Completed++
+		case "Failed", "Error":
+			stats.Sessions.Failed++
+		case "Stopped":
+			stats.Sessions.Stopped++
+		}
+	}
+
+	settings, cached := cachedProjectSettings(project)
+	if !cached {
+		var err error
+		settings, err = dyn.Resource(getProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return stats, err
+		}
+	}
+	clusterPolicy, err := loadClusterPolicy(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Budget.Month = time.Now().UTC().Format("2006-01")
+	if status, ok := currentBudgetStatus(settings); ok {
+		stats.Budget.Month, _ = status["month"].(string)
+		stats.Budget.ResetsAt, _ = status["periodEnd"].(string)
+	}
+	stats.Budget.SpentUSD = monthlySpend(settings)
+	stats.Budget.Usage = monthlyUsage(settings)
+	if limit, warnPercent := projectBudget(settings, clusterPolicy); limit > 0 {
+		stats.Budget.LimitUSD, stats.Budget.WarnPercent = limit, warnPercent
+	}
+	return stats, nil
+}
+
+// canListSessions reports whether the caller may list sessions in project,
+// the access validateProjectContext requires of project routes
+func canListSessions(ctx context.Context, reqK8s kubernetes.Interface, project string) (bool, error) {
+	ssar := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Group:     "vteam.ambient-code",
+				Resource:  "agenticsessions",
+				Verb:      "list",
+				Namespace: project,
+			},
+		},
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, ssar, v1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return res.Status.Allowed, nil
+}
+
+// GET /api/projects/:projectName/stats
+// getProjectStats reports session counts by phase, the month's budget use and
+// the storage used by session workspaces alongside the configured quotas.
+func getProjectStats(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+
+	stats, err := projectStats(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to compute stats for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to compute project stats")
+		return
+	}
+
+	spec, err := getProjectSettingsSpec(c.Request.Context(), reqDyn, project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+
+	usage, err := projectContentUsage(c, project, "/sessions")
+	if err != nil {
+		logErrorf(c, "Failed to read storage usage for %s: %v", project, err)
+		respondError(c, http.StatusBadGateway, "Failed to read storage usage")
+		return
+	}
+	stats.Storage = &StorageUsage{UsedBytes: usage.Bytes, Files: usage.Files, Quota: storageQuotaFromSpec(spec)}
+
+	c.JSON(http.StatusOK, stats)
+}
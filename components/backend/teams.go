@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// A Teams outgoing webhook signs each message with the security token shown when
+// it is created. Projects store that token in the ambient-teams-trigger Secret
+// and name, in ProjectSettings spec.triggers.teams, the access key
+// ServiceAccount that triggered sessions are created as.
+const (
+	teamsTriggerSecret    = "ambient-teams-trigger"
+	teamsTriggerSecretKey = "securityToken"
+)
+
+var (
+	teamsMentionPattern = regexp.MustCompile(`(?is)<at>.*?</at>`)
+	teamsBreakPattern   = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>`)
+	teamsTagPattern     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// teamsActivity is the part of a Bot Framework message activity the trigger reads
+type teamsActivity struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	From struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		AADObjectID string `json:"aadObjectId"`
+	} `json:"from"`
+	Conversation struct {
+		ID string `json:"id"`
+	} `json:"conversation"`
+}
+
+// verifyTeamsSignature checks the Authorization: HMAC <base64> header Teams
+// sends: an HMAC-SHA256 of the body keyed with the base64-decoded security token
+func verifyTeamsSignature(body []byte, header, securityToken string) bool {
+	presented, ok := strings.CutPrefix(strings.TrimSpace(header), "HMAC ")
+	if !ok {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(presented))
+	if err != nil {
+		return false
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(securityToken))
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// teamsMessageText turns a message's HTML into the prompt, dropping the
+// mention of the outgoing webhook
+func teamsMessageText(text string) string {
+	text = teamsMentionPattern.ReplaceAllString(text, "")
+	text = teamsBreakPattern.ReplaceAllString(text, "\n")
+	text = teamsTagPattern.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(html.UnescapeString(text), "\u00a0", " ")
+	return strings.TrimSpace(text)
+}
+
+// teamsReply answers in the thread; Teams shows the text of a 200 response and
+// a generic error for anything else
+func teamsReply(c *gin.Context, status int, text string) {
+	c.JSON(status, gin.H{"type": "message", "text": text})
+}
+
+// POST /api/projects/:projectName/triggers/teams
+// teamsTrigger creates a session from a Teams outgoing webhook message. The
+// request is authenticated by its HMAC signature rather than a bearer token, and
+// the message text, without the mention, becomes the prompt. Redelivered
+// messages return the session created for the first one.
+func teamsTrigger(c *gin.Context) {
+	project := c.Param("projectName")
+	body, ok := readWebhookPayload(c, "teams")
+	if !ok {
+		return
+	}
+
+	spec, err := triggerProjectSettings(c.Request.Context(), project)
+	if err != nil {
+		logErrorf(c, "Failed to read ProjectSettings for Teams trigger in %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to read project settings")
+		return
+	}
+	cfg, _, _ := unstructured.NestedMap(spec, "triggers", "teams")
+	if enabled, _ := cfg["enabled"].(bool); !enabled {
+		respondError(c, http.StatusNotFound, "Teams trigger is not enabled for this project")
+		return
+	}
+	sec, err := k8sClient.CoreV1().Secrets(project).Get(c.Request.Context(), teamsTriggerSecret, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logWarnf(c, "Teams trigger in %s has no %s Secret", project, teamsTriggerSecret)
+		} else {
+			logErrorf(c, "Failed to read %s in %s: %v", teamsTriggerSecret, project, err)
+		}
+		respondError(c, http.StatusServiceUnavailable, "Teams trigger is not configured")
+		return
+	}
+	if !verifyTeamsSignature(body, c.GetHeader("Authorization"), string(sec.Data[teamsTriggerSecretKey])) {
+		respondError(c, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	var activity teamsActivity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid activity")
+		return
+	}
+	if activity.Type != "message" {
+		teamsReply(c, http.StatusOK, "Only messages start sessions.")
+		return
+	}
+	prompt := teamsMessageText(activity.Text)
+	if prompt == "" {
+		teamsReply(c, http.StatusOK, "Mention me with what the session should do.")
+		return
+	}
+
+	req, err := triggerRequest(cfg)
+	if err != nil {
+		logWarnf(c, "Teams trigger in %s: %v", project, err)
+		teamsReply(c, http.StatusOK, "The project's Teams session settings are invalid; ask a project admin to fix spec.triggers.teams.session.")
+		return
+	}
+	req.Prompt = prompt
+	req.Trigger = &SessionTrigger{
+		Source:     "teams",
+		Event:      "message",
+		Channel:    activity.Conversation.ID,
+		DeliveryID: activity.ID,
+	}
+	if activity.From.Name != "" {
+		if req.Annotations == nil {
+			req.Annotations = map[string]string{}
+		}
+		req.Annotations["ambient-code.io/requested-by"] = activity.From.Name
+	}
+	auditDetail(c, "teams", gin.H{"from": activity.From.Name, "aadObjectId": activity.From.AADObjectID, "conversation": activity.Conversation.ID})
+
+	result := startTrigger(c, triggerConfig{Project: project, Config: cfg}, req)
+	switch {
+	case result.Queued:
+		teamsReply(c, http.StatusOK, "Queued; the session will start shortly.")
+	case result.Error != "":
+		teamsReply(c, http.StatusOK, "Could not start a session: "+result.Error)
+	case result.Duplicate:
+		teamsReply(c, http.StatusOK, "Already started session "+triggerSessionLink(project, result.Name)+" for this message.")
+	default:
+		teamsReply(c, http.StatusOK, "Started session "+triggerSessionLink(project, result.Name)+".")
+	}
+}
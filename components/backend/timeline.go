@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// sessionHistoryLimit is how many progress reports status.history keeps
+const sessionHistoryLimit = 100
+
+// SessionTimelineEntry is one item of a session's timeline. Source is
+// "lifecycle" for the session's own timestamps, "progress" for phase and
+// message reports in status.history, "condition" for the last transition of
+// each status condition and "event" for Kubernetes Events.
+type SessionTimelineEntry struct {
+	Time   string `json:"time"`
+	Source string `json:"source"`
+	// Object the entry is about, as Kind/name, for events of the Job, PipelineRun or runner pods
+	Object string `json:"object,omitempty"`
+	// Type is the event type (Normal, Warning) or the condition type
+	Type string `json:"type,omitempty"`
+	// Status of a condition: True, False or Unknown
+	Status  string `json:"status,omitempty"`
+	Phase   string `json:"phase,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Count   int32  `json:"count,omitempty"`
+
+	at time.Time
+}
+
+// SessionTimeline is the body of GET .../timeline, oldest entry first.
+// Warnings name the sources that could not be read, e.g. Events the caller
+// may not list.
+type SessionTimeline struct {
+	Entries  []SessionTimelineEntry `json:"entries"`
+	Warnings []string               `json:"warnings,omitempty"`
+}
+
+// appendSessionHistory records a reported phase or message change in
+// status.history, dropping the oldest entries beyond sessionHistoryLimit
+func appendSessionHistory(status, update map[string]interface{}, now time.Time) {
+	phase, hasPhase := update["phase"].(string)
+	message, hasMessage := update["message"].(string)
+	if !hasPhase && !hasMessage {
+		return
+	}
+	if !hasPhase {
+		phase, _ = status["phase"].(string)
+	}
+	if !hasMessage {
+		message, _ = status["message"].(string)
+	}
+	prevPhase, _ := status["phase"].(string)
+	prevMessage, _ := status["message"].(string)
+	if phase == prevPhase && message == prevMessage {
+		return
+	}
+	history, _ := status["history"].([]interface{})
+	entry := map[string]interface{}{"time": now.UTC().Format(time.RFC3339), "phase": phase}
+	if message != "" {
+		entry["message"] = message
+	}
+	history = append(history, entry)
+	if len(history) > sessionHistoryLimit {
+		history = history[len(history)-sessionHistoryLimit:]
+	}
+	status["history"] = history
+}
+
+func parseTimelineTime(v interface{}) (time.Time, bool) {
+	s, _ := v.(string)
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// sessionStatusTimeline returns the lifecycle, progress and condition entries
+// recorded on the session itself
+func sessionStatusTimeline(obj *unstructured.Unstructured) []SessionTimelineEntry {
+	var out []SessionTimelineEntry
+	add := func(e SessionTimelineEntry) {
+		e.Time = e.at.UTC().Format(time.RFC3339)
+		out = append(out, e)
+	}
+
+	add(SessionTimelineEntry{Source: "lifecycle", Reason: "Created", at: obj.GetCreationTimestamp().Time})
+	status, _, _ := unstructured.NestedMap(obj.Object, "status")
+	for _, m := range []struct{ field, reason string }{
+		{"startTime", "Started"},
+		{"firstOutputTime", "FirstOutput"},
+		{"completionTime", "Finished"},
+	} {
+		if t, ok := parseTimelineTime(status[m.field]); ok {
+			e := SessionTimelineEntry{Source: "lifecycle", Reason: m.reason, at: t}
+			if m.field == "completionTime" {
+				e.Phase, _ = status["phase"].(string)
+			}
+			add(e)
+		}
+	}
+
+	history, _ := status["history"].([]interface{})
+	for _, raw := range history {
+		h, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, ok := parseTimelineTime(h["time"])
+		if !ok {
+			continue
+		}
+		e := SessionTimelineEntry{Source: "progress", at: t}
+		e.Phase, _ = h["phase"].(string)
+		e.Message, _ = h["message"].(string)
+		add(e)
+	}
+
+	for _, cond := range sessionConditions(obj) {
+		t, ok := parseTimelineTime(cond["lastTransitionTime"])
+		if !ok {
+			continue
+		}
+		e := SessionTimelineEntry{Source: "condition", at: t}
+		e.Type, _ = cond["type"].(string)
+		e.Status, _ = cond["status"].(string)
+		e.Reason, _ = cond["reason"].(string)
+		e.Message, _ = cond["message"].(string)
+		add(e)
+	}
+	return out
+}
+
+// sessionEventTimeline returns the Kubernetes Events of the session, its Job or
+// PipelineRun and the pods they ran. Pod events are matched by name so those of
+// pods already cleaned up are kept.
+func sessionEventTimeline(ctx context.Context, reqK8s kubernetes.Interface, obj *unstructured.Unstructured) ([]SessionTimelineEntry, []string) {
+	project, sessionName := obj.GetNamespace(), obj.GetName()
+	objects := []struct{ kind, name string }{{"AgenticSession", sessionName}}
+	var podPrefixes []string
+	if job, _, _ := unstructured.NestedString(obj.Object, "status", "jobName"); job != "" {
+		objects = append(objects, struct{ kind, name string }{"Job", job})
+		podPrefixes = append(podPrefixes, job+"-")
+	}
+	if run, _, _ := unstructured.NestedString(obj.Object, "status", "pipelineRunName"); run != "" {
+		objects = append(objects, struct{ kind, name string }{"PipelineRun", run})
+		podPrefixes = append(podPrefixes, run+"-")
+	}
+
+	var out []SessionTimelineEntry
+	var warnings []string
+	for _, o := range objects {
+		selector := fields.Set{"involvedObject.kind": o.kind, "involvedObject.name": o.name}.AsSelector().String()
+		events, err := reqK8s.CoreV1().Events(project).List(ctx, v1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("events of %s %s unavailable: %v", o.kind, o.name, err))
+			continue
+		}
+		for i := range events.Items {
+			out = append(out, eventTimelineEntry(o.kind, &events.Items[i]))
+		}
+	}
+	if len(podPrefixes) > 0 {
+		selector := fields.OneTermEqualSelector("involvedObject.kind", "Pod").String()
+		events, err := reqK8s.CoreV1().Events(project).List(ctx, v1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("events of the runner pods unavailable: %v", err))
+		} else {
+			for i := range events.Items {
+				name := events.Items[i].InvolvedObject.Name
+				for _, prefix := range podPrefixes {
+					if strings.HasPrefix(name, prefix) {
+						out = append(out, eventTimelineEntry("Pod", &events.Items[i]))
+						break
+					}
+				}
+			}
+		}
+	}
+	return out, warnings
+}
+
+func eventTimelineEntry(kind string, e *corev1.Event) SessionTimelineEntry {
+	h := historyEvent(e)
+	at, _ := time.Parse(time.RFC3339, h.Time)
+	entry := SessionTimelineEntry{
+		Time:    h.Time,
+		Source:  "event",
+		Type:    h.Type,
+		Reason:  h.Reason,
+		Message: h.Message,
+		Count:   h.Count,
+		at:      at,
+	}
+	if kind != "AgenticSession" {
+		entry.Object = kind + "/" + e.InvolvedObject.Name
+	}
+	return entry
+}
+
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/timeline
+// getSessionTimeline merges the session's lifecycle timestamps, the progress
+// reports in status.history, condition transitions and the Kubernetes Events
+// of the session, its workload and runner pods into one chronological feed.
+// Events are read with the caller's token and are best effort.
+func getSessionTimeline(c *gin.Context) {
+	project := c.GetString("project")
+	sessionName := c.Param("sessionName")
+	reqK8s, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	ctx := c.Request.Context()
+
+	obj, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).Get(ctx, sessionName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Session not found")
+			return
+		}
+		logErrorf(c, "Failed to get agentic session %s in project %s: %v", sessionName, project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get agentic session")
+		return
+	}
+
+	timeline := SessionTimeline{Entries: sessionStatusTimeline(obj)}
+	if reqK8s != nil {
+		events, warnings := sessionEventTimeline(ctx, reqK8s, obj)
+		timeline.Entries = append(timeline.Entries, events...)
+		timeline.Warnings = warnings
+	}
+	// Stable, so entries recorded at the same second keep their source order
+	sort.SliceStable(timeline.Entries, func(i, j int) bool {
+		return timeline.Entries[i].at.Before(timeline.Entries[j].at)
+	})
+	c.JSON(http.StatusOK, timeline)
+}
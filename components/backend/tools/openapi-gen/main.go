@@ -0,0 +1,701 @@
+// Command openapi-gen writes the backend's OpenAPI 3 document from the route
+// annotations on handler doc comments, e.g.
+//
+//	// GET /api/projects/:projectName/agentic-sessions/:sessionName
+//	// getSession returns ...
+//	func getSession(c *gin.Context) {
+//
+// Request bodies come from the type bound with c.ShouldBindJSON, responses from
+// the status codes and values passed to c.JSON, c.Data and c.Status and the
+// problem documents of respondError and respondProblem, and query parameters
+// from c.Query calls. Run it from components/backend with
+// `go generate` or `make openapi`; -check exits non-zero when the file on disk
+// is out of date.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var annotationPattern = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE) (/api[^\s?]*)(.*)$`)
+
+// statusCodes maps net/http constant names (StatusNotFound) to codes
+var statusCodes = func() map[string]int {
+	m := map[string]int{}
+	for code := 100; code < 600; code++ {
+		text := http.StatusText(code)
+		if text == "" {
+			continue
+		}
+		name := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, text)
+		m["Status"+name] = code
+	}
+	return m
+}()
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the backend package")
+	out := flag.String("out", "openapi.json", "output file, relative to -dir")
+	check := flag.Bool("check", false, "fail when the output file is out of date instead of writing it")
+	flag.Parse()
+
+	doc, err := generate(*dir)
+	if err != nil {
+		log.Fatalf("openapi-gen: %v", err)
+	}
+	path := filepath.Join(*dir, *out)
+	if *check {
+		current, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("openapi-gen: %v", err)
+		}
+		if !bytes.Equal(current, doc) {
+			log.Fatalf("openapi-gen: %s is out of date; run make openapi", path)
+		}
+		return
+	}
+	if err := os.WriteFile(path, doc, 0o644); err != nil {
+		log.Fatalf("openapi-gen: %v", err)
+	}
+}
+
+// generator resolves Go types of the backend package to JSON schemas
+type generator struct {
+	types   map[string]ast.Expr
+	funcs   map[string]*ast.FuncDecl
+	schemas map[string]interface{}
+}
+
+func generate(dir string) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{types: map[string]ast.Expr{}, funcs: map[string]*ast.FuncDecl{}, schemas: map[string]interface{}{}}
+	fset := token.NewFileSet()
+	var handlers []*ast.FuncDecl
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						g.types[ts.Name.Name] = ts.Type
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Recv != nil {
+					continue
+				}
+				g.funcs[d.Name.Name] = d
+				if d.Doc != nil {
+					handlers = append(handlers, d)
+				}
+			}
+		}
+	}
+
+	paths := map[string]map[string]interface{}{}
+	for _, fn := range handlers {
+		var method, route, extra string
+		var docLines []string
+		for _, line := range strings.Split(fn.Doc.Text(), "\n") {
+			if m := annotationPattern.FindStringSubmatch(line); m != nil && method == "" {
+				method, route, extra = m[1], m[2], m[3]
+				continue
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				docLines = append(docLines, line)
+			}
+		}
+		if method == "" {
+			continue
+		}
+		path, params := openAPIPath(route)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		if _, dup := paths[path][strings.ToLower(method)]; dup {
+			return nil, fmt.Errorf("%s %s is annotated on more than one handler", method, route)
+		}
+		paths[path][strings.ToLower(method)] = g.operation(fn, path, params, extra, docLines)
+	}
+
+	g.schemas["Problem"] = map[string]interface{}{
+		"type":        "object",
+		"description": "RFC 7807 problem document. Clients branch on code; error repeats detail for older clients.",
+		"properties": map[string]interface{}{
+			"type":     map[string]interface{}{"type": "string"},
+			"title":    map[string]interface{}{"type": "string"},
+			"status":   map[string]interface{}{"type": "integer"},
+			"code":     map[string]interface{}{"type": "string"},
+			"detail":   map[string]interface{}{"type": "string"},
+			"instance": map[string]interface{}{"type": "string"},
+			"traceId":  map[string]interface{}{"type": "string"},
+			"error":    map[string]interface{}{"type": "string"},
+		},
+		"required":             []string{"type", "title", "status", "code"},
+		"additionalProperties": true,
+	}
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Ambient Code backend API",
+			"version":     "v1alpha1",
+			"description": "Generated by tools/openapi-gen from the route annotations on handler doc comments. Do not edit.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// openAPIPath converts gin parameters (:name, *name) to OpenAPI templates and
+// returns the parameter names in order
+func openAPIPath(route string) (string, []string) {
+	segments := strings.Split(route, "/")
+	var params []string
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			params = append(params, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationTag groups operations by the resource after /api or /api/projects/{projectName}
+func operationTag(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/"), "/")
+	if parts[0] == "projects" && len(parts) > 2 {
+		return parts[2]
+	}
+	return parts[0]
+}
+
+// summaryFromName turns listSessionPipelines into "List session pipelines"
+func summaryFromName(name string) string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		boundary := i == len(runes) ||
+			(unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1])) ||
+			(unicode.IsUpper(runes[i]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))
+		if !boundary {
+			continue
+		}
+		word := string(runes[start:i])
+		if len(word) == 1 || strings.ToUpper(word) != word {
+			word = strings.ToLower(word)
+		}
+		words = append(words, word)
+		start = i
+	}
+	s := strings.Join(words, " ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (g *generator) operation(fn *ast.FuncDecl, path string, pathParams []string, extra string, docLines []string) map[string]interface{} {
+	op := map[string]interface{}{
+		"operationId": fn.Name.Name,
+		"summary":     summaryFromName(fn.Name.Name),
+		"tags":        []string{operationTag(path)},
+	}
+	if len(docLines) > 0 {
+		op["description"] = strings.Join(docLines, " ")
+	}
+
+	h := g.analyzeHandler(fn)
+	var params []interface{}
+	for _, p := range pathParams {
+		params = append(params, map[string]interface{}{
+			"name": p, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	// Query parameters named in the annotation (?after=N&wait=30) and read by the handler
+	queries := h.query
+	if fields := strings.Fields(extra); len(fields) > 0 && strings.HasPrefix(fields[0], "?") {
+		for _, kv := range strings.Split(fields[0][1:], "&") {
+			if name, _, _ := strings.Cut(kv, "="); name != "" {
+				queries = append(queries, name)
+			}
+		}
+	}
+	sort.Strings(queries)
+	for i, q := range queries {
+		if i > 0 && queries[i-1] == q {
+			continue
+		}
+		params = append(params, map[string]interface{}{
+			"name": q, "in": "query", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if h.body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": h.body}},
+		}
+	}
+	responses := map[string]interface{}{}
+	for code, r := range h.responses {
+		responses[strconv.Itoa(code)] = r
+	}
+	if len(responses) == 0 {
+		responses["200"] = map[string]interface{}{"description": "OK"}
+	}
+	responses["default"] = map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{problemContentType: map[string]interface{}{
+			"schema": problemSchema(),
+		}},
+	}
+	op["responses"] = responses
+	return op
+}
+
+type handlerInfo struct {
+	body      map[string]interface{}
+	query     []string
+	responses map[int]map[string]interface{}
+}
+
+// analyzeHandler walks a handler body for the calls on its *gin.Context that
+// bind the request and write responses
+func (g *generator) analyzeHandler(fn *ast.FuncDecl) handlerInfo {
+	info := handlerInfo{responses: map[int]map[string]interface{}{}}
+	ctx := ""
+	for _, field := range fn.Type.Params.List {
+		if star, ok := field.Type.(*ast.StarExpr); ok && exprString(star.X) == "gin.Context" && len(field.Names) > 0 {
+			ctx = field.Names[0].Name
+		}
+	}
+	locals := map[string]ast.Expr{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.GenDecl:
+			for _, spec := range s.Specs {
+				if vs, ok := spec.(*ast.ValueSpec); ok {
+					for i, name := range vs.Names {
+						if vs.Type != nil {
+							locals[name.Name] = vs.Type
+						} else if i < len(vs.Values) {
+							locals[name.Name] = g.exprType(vs.Values[i], locals)
+						}
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE {
+				break
+			}
+			if len(s.Rhs) == len(s.Lhs) {
+				for i, lhs := range s.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && id.Name != "_" {
+						if t := g.exprType(s.Rhs[i], locals); t != nil {
+							locals[id.Name] = t
+						}
+					}
+				}
+			} else if call, ok := s.Rhs[0].(*ast.CallExpr); ok {
+				results := g.resultTypes(call)
+				for i, lhs := range s.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && id.Name != "_" && i < len(results) {
+						locals[id.Name] = results[i]
+					}
+				}
+			}
+		case *ast.CallExpr:
+			if id, ok := s.Fun.(*ast.Ident); ok && ctx != "" && len(s.Args) >= 2 && exprString(s.Args[0]) == ctx {
+				problemCall(&info, id.Name, s.Args[1:])
+				break
+			}
+			sel, ok := s.Fun.(*ast.SelectorExpr)
+			if !ok || ctx == "" || exprString(sel.X) != ctx {
+				break
+			}
+			g.contextCall(&info, sel.Sel.Name, s.Args, locals)
+		}
+		return true
+	})
+	return info
+}
+
+func (g *generator) contextCall(info *handlerInfo, method string, args []ast.Expr, locals map[string]ast.Expr) {
+	switch method {
+	case "ShouldBindJSON", "BindJSON":
+		if len(args) == 1 && info.body == nil {
+			if t := g.exprType(args[0], locals); t != nil {
+				info.body = g.schema(t)
+			}
+		}
+	case "Query", "DefaultQuery", "GetQuery":
+		if lit, ok := args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			name, _ := strconv.Unquote(lit.Value)
+			info.query = append(info.query, name)
+		}
+	case "JSON", "IndentedJSON", "AbortWithStatusJSON":
+		if code := statusCode(args[0]); code > 0 && len(args) == 2 {
+			addResponse(info, code, "application/json", g.valueSchema(args[1], locals))
+		}
+	case "Status", "AbortWithStatus":
+		if code := statusCode(args[0]); code > 0 {
+			addResponse(info, code, "", nil)
+		}
+	case "Data":
+		if code := statusCode(args[0]); code > 0 && len(args) == 3 {
+			contentType := "application/octet-stream"
+			if lit, ok := args[1].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				contentType, _ = strconv.Unquote(lit.Value)
+			}
+			addResponse(info, code, contentType, map[string]interface{}{"type": "string", "format": "binary"})
+		}
+	case "String":
+		if code := statusCode(args[0]); code > 0 {
+			addResponse(info, code, "text/plain", map[string]interface{}{"type": "string"})
+		}
+	}
+}
+
+// problemCall records the problem documents written by the backend's error
+// helpers: respondError(c, status, ...) and respondProblem(c, problem.New(status, ...))
+func problemCall(info *handlerInfo, fn string, args []ast.Expr) {
+	var code int
+	switch fn {
+	case "respondSessionBindError":
+		// Malformed bodies are 400; bodies that fail validation 422
+		addResponse(info, http.StatusBadRequest, problemContentType, problemSchema())
+		code = http.StatusUnprocessableEntity
+	case "respondError", "abortWithError":
+		code = statusCode(args[0])
+	case "respondProblem", "abortWithProblem":
+		code = problemStatus(args[0])
+	default:
+		return
+	}
+	if code > 0 {
+		addResponse(info, code, problemContentType, problemSchema())
+	}
+}
+
+// problemStatus finds the status of a problem.New call, through any .With
+// calls chained on it
+func problemStatus(e ast.Expr) int {
+	for {
+		call, ok := e.(*ast.CallExpr)
+		if !ok {
+			return 0
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return 0
+		}
+		if exprString(sel.X) == "problem" && sel.Sel.Name == "New" {
+			if len(call.Args) == 0 {
+				return 0
+			}
+			return statusCode(call.Args[0])
+		}
+		e = sel.X
+	}
+}
+
+const problemContentType = "application/problem+json"
+
+func problemSchema() map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/Problem"}
+}
+
+// addResponse records the first body seen for a status code
+func addResponse(info *handlerInfo, code int, contentType string, schema map[string]interface{}) {
+	if _, seen := info.responses[code]; seen {
+		return
+	}
+	r := map[string]interface{}{"description": http.StatusText(code)}
+	if contentType != "" {
+		if isErrorSchema(schema) && code < 400 {
+			schema = map[string]interface{}{}
+		}
+		r["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+	}
+	info.responses[code] = r
+}
+
+func isErrorSchema(schema map[string]interface{}) bool {
+	return schema["$ref"] == "#/components/schemas/Problem"
+}
+
+func statusCode(e ast.Expr) int {
+	switch v := e.(type) {
+	case *ast.SelectorExpr:
+		if exprString(v.X) == "http" {
+			return statusCodes[v.Sel.Name]
+		}
+	case *ast.BasicLit:
+		code, _ := strconv.Atoi(v.Value)
+		return code
+	}
+	return 0
+}
+
+// valueSchema describes a response value; gin.H literals become objects with
+// their keys, {"error": ...} the shared Problem
+func (g *generator) valueSchema(e ast.Expr, locals map[string]ast.Expr) map[string]interface{} {
+	if lit, ok := e.(*ast.CompositeLit); ok && exprString(lit.Type) == "gin.H" {
+		props := map[string]interface{}{}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.BasicLit)
+			if !ok || key.Kind != token.STRING {
+				continue
+			}
+			name, _ := strconv.Unquote(key.Value)
+			props[name] = g.valueSchema(kv.Value, locals)
+		}
+		if len(props) == 1 && props["error"] != nil {
+			return problemSchema()
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	}
+	if lit, ok := e.(*ast.BasicLit); ok {
+		switch lit.Kind {
+		case token.STRING:
+			return map[string]interface{}{"type": "string"}
+		case token.INT:
+			return map[string]interface{}{"type": "integer"}
+		}
+	}
+	if t := g.exprType(e, locals); t != nil {
+		return g.schema(t)
+	}
+	return map[string]interface{}{}
+}
+
+// exprType infers the static type of simple expressions: literals, address-of,
+// make/new, locals and calls of package functions
+func (g *generator) exprType(e ast.Expr, locals map[string]ast.Expr) ast.Expr {
+	switch v := e.(type) {
+	case *ast.Ident:
+		return locals[v.Name]
+	case *ast.CompositeLit:
+		return v.Type
+	case *ast.UnaryExpr:
+		if v.Op == token.AND {
+			return g.exprType(v.X, locals)
+		}
+	case *ast.StarExpr:
+		if t := g.exprType(v.X, locals); t != nil {
+			if star, ok := t.(*ast.StarExpr); ok {
+				return star.X
+			}
+			return t
+		}
+	case *ast.CallExpr:
+		if id, ok := v.Fun.(*ast.Ident); ok && (id.Name == "make" || id.Name == "new") && len(v.Args) > 0 {
+			return v.Args[0]
+		}
+		if results := g.resultTypes(v); len(results) > 0 {
+			return results[0]
+		}
+	}
+	return nil
+}
+
+func (g *generator) resultTypes(call *ast.CallExpr) []ast.Expr {
+	id, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	fn := g.funcs[id.Name]
+	if fn == nil || fn.Type.Results == nil {
+		return nil
+	}
+	var out []ast.Expr
+	for _, field := range fn.Type.Results.List {
+		n := max(len(field.Names), 1)
+		for range n {
+			out = append(out, field.Type)
+		}
+	}
+	return out
+}
+
+// schema converts a Go type expression to a JSON schema; named package types
+// become component references
+func (g *generator) schema(t ast.Expr) map[string]interface{} {
+	switch v := t.(type) {
+	case *ast.Ident:
+		switch v.Name {
+		case "string":
+			return map[string]interface{}{"type": "string"}
+		case "bool":
+			return map[string]interface{}{"type": "boolean"}
+		case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+			return map[string]interface{}{"type": "integer"}
+		case "int64", "uint64":
+			return map[string]interface{}{"type": "integer", "format": "int64"}
+		case "float32", "float64":
+			return map[string]interface{}{"type": "number"}
+		}
+		if decl, ok := g.types[v.Name]; ok {
+			if _, done := g.schemas[v.Name]; !done {
+				g.schemas[v.Name] = map[string]interface{}{}
+				g.schemas[v.Name] = g.schema(decl)
+			}
+			return map[string]interface{}{"$ref": "#/components/schemas/" + v.Name}
+		}
+	case *ast.StarExpr:
+		return g.schema(v.X)
+	case *ast.ArrayType:
+		if id, ok := v.Elt.(*ast.Ident); ok && id.Name == "byte" {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(v.Elt)}
+	case *ast.MapType:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(v.Value)}
+	case *ast.StructType:
+		return g.structSchema(v)
+	case *ast.SelectorExpr:
+		switch exprString(v) {
+		case "time.Time":
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		case "money.USD":
+			return map[string]interface{}{"type": "number"}
+		case "gin.H", "unstructured.Unstructured":
+			return map[string]interface{}{"type": "object"}
+		}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *generator) structSchema(st *ast.StructType) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		jsonName, _, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if len(field.Names) == 0 {
+			// Embedded struct: promote its fields
+			if id, ok := field.Type.(*ast.Ident); ok {
+				if embedded, ok := g.types[id.Name].(*ast.StructType); ok {
+					for k, p := range g.structSchema(embedded)["properties"].(map[string]interface{}) {
+						props[k] = p
+					}
+				}
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			key := jsonName
+			if key == "" {
+				key = name.Name
+			}
+			s := g.schema(field.Type)
+			if doc := fieldDoc(field); doc != "" {
+				if _, isRef := s["$ref"]; isRef {
+					// Siblings of $ref are ignored in OpenAPI 3.0
+					s = map[string]interface{}{"allOf": []interface{}{s}, "description": doc}
+				} else {
+					s["description"] = doc
+				}
+			}
+			props[key] = s
+			if bindingRequired(tag.Get("binding")) {
+				required = append(required, key)
+			}
+		}
+	}
+	out := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+// bindingRequired reports whether a binding tag requires the field itself;
+// rules after dive apply to its elements
+func bindingRequired(tag string) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "dive" {
+			return false
+		}
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldDoc(field *ast.Field) string {
+	for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if text := strings.TrimSpace(group.Text()); text != "" {
+			return strings.Join(strings.Fields(text), " ")
+		}
+	}
+	return ""
+}
+
+func exprString(e ast.Expr) string {
+	switch v := e.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.SelectorExpr:
+		return exprString(v.X) + "." + v.Sel.Name
+	}
+	return ""
+}
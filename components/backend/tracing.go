@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traceparentAnnotation carries the W3C trace context of the request that
+// created a session, so the operator and runner continue the same trace
+const traceparentAnnotation = "ambient-code.io/traceparent"
+
+const (
+	spanKindServer = 2
+
+	spanExportBatch    = 128
+	spanExportInterval = 5 * time.Second
+)
+
+var traceparentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// span is one timed operation of a trace, exported over OTLP/HTTP
+type span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	kind     int
+	start    time.Time
+	attrs    map[string]string
+	errMsg   string
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseTraceparent returns the trace and parent span ids of a W3C traceparent,
+// rejecting the all-zero ids the spec marks invalid
+func parseTraceparent(h string) (string, string, bool) {
+	m := traceparentPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(h)))
+	if m == nil || strings.Trim(m[1], "0") == "" || strings.Trim(m[2], "0") == "" {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// startSpan begins a span under parent, a traceparent value, or a new trace
+// when parent is empty or invalid
+func startSpan(name, parent string, kind int) *span {
+	s := &span{spanID: randomHex(8), name: name, kind: kind, start: time.Now(), attrs: map[string]string{}}
+	if traceID, parentID, ok := parseTraceparent(parent); ok {
+		s.traceID, s.parentID = traceID, parentID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	return s
+}
+
+func (s *span) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.traceID, s.spanID)
+}
+
+func (s *span) set(key, value string) {
+	if value != "" {
+		s.attrs[key] = value
+	}
+}
+
+// end finishes the span and queues it for export
+func (s *span) end() {
+	if spanExporter != nil {
+		spanExporter.enqueue(s, time.Now())
+	}
+}
+
+// otlpSpanExporter batches finished spans and posts them as OTLP/HTTP JSON
+type otlpSpanExporter struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+
+	mu      sync.Mutex
+	pending []map[string]interface{}
+}
+
+// spanExporter is nil unless OTEL_EXPORTER_OTLP_ENDPOINT is configured; spans
+// are still created so trace context propagates to the operator and runners
+var spanExporter *otlpSpanExporter
+
+// initTracing configures OTLP export from the standard OpenTelemetry variables:
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (used as is) or OTEL_EXPORTER_OTLP_ENDPOINT
+// (with /v1/traces appended), OTEL_EXPORTER_OTLP_HEADERS and OTEL_SERVICE_NAME.
+func initTracing(defaultService string) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = defaultService
+	}
+	headers := map[string]string{}
+	for _, kv := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.TrimSpace(k) != "" {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	spanExporter = &otlpSpanExporter{endpoint: endpoint, headers: headers, service: service, client: &http.Client{Timeout: 10 * time.Second}}
+	go func() {
+		for range time.Tick(spanExportInterval) {
+			spanExporter.flush()
+		}
+	}()
+	log.Printf("Exporting traces to %s as %s", endpoint, service)
+}
+
+func (e *otlpSpanExporter) enqueue(s *span, end time.Time) {
+	attrs := make([]map[string]interface{}, 0, len(s.attrs))
+	for k, v := range s.attrs {
+		attrs = append(attrs, map[string]interface{}{"key": k, "value": map[string]string{"stringValue": v}})
+	}
+	status := map[string]interface{}{"code": 1}
+	if s.errMsg != "" {
+		status = map[string]interface{}{"code": 2, "message": s.errMsg}
+	}
+	out := map[string]interface{}{
+		"traceId":           s.traceID,
+		"spanId":            s.spanID,
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+		"attributes":        attrs,
+		"status":            status,
+	}
+	if s.parentID != "" {
+		out["parentSpanId"] = s.parentID
+	}
+	e.mu.Lock()
+	// Drop spans rather than grow without bound while the collector is down
+	if len(e.pending) < 16*spanExportBatch {
+		e.pending = append(e.pending, out)
+	}
+	full := len(e.pending) >= spanExportBatch
+	e.mu.Unlock()
+	if full {
+		go e.flush()
+	}
+}
+
+func (e *otlpSpanExporter) flush() {
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": []interface{}{
+				map[string]interface{}{"key": "service.name", "value": map[string]string{"stringValue": e.service}},
+			}},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "ambient-code"},
+				"spans": batch,
+			}},
+		}},
+	})
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("tracing: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Printf("tracing: failed to export %d spans: %v", len(batch), err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Printf("tracing: collector rejected %d spans: %s", len(batch), resp.Status)
+	}
+}
+
+// tracingMiddleware records a server span per request, continuing the caller's
+// traceparent header (webhook senders, runners) when present
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		s := startSpan(c.Request.Method+" "+route, c.GetHeader("traceparent"), spanKindServer)
+		c.Set("span", s)
+		c.Next()
+
+		status := c.Writer.Status()
+		s.set("http.request.method", c.Request.Method)
+		s.set("http.route", route)
+		s.set("http.response.status_code", strconv.Itoa(status))
+		s.set("ambient.project", c.Param("projectName"))
+		s.set("ambient.session", c.Param("sessionName"))
+		if name := c.GetString("createdSession"); name != "" {
+			s.set("ambient.session", name)
+		}
+		if status >= http.StatusInternalServerError {
+			s.errMsg = http.StatusText(status)
+		}
+		s.end()
+	}
+}
+
+// traceparentFromContext is the traceparent of the request's span, for
+// propagation into the resources it creates
+func traceparentFromContext(c *gin.Context) string {
+	if v, ok := c.Get("span"); ok {
+		if s, ok := v.(*span); ok {
+			return s.traceparent()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// triggerFingerprintLabel indexes sessions by fingerprint. Label values are capped
+// at 63 characters, so it holds a prefix; spec.trigger.fingerprint has the full value.
+const (
+	triggerFingerprintLabel  = "ambient-code.io/trigger-fingerprint"
+	triggerFingerprintLabelN = 40
+)
+
+// SessionTrigger records what caused a session to be created (webhook, schedule, ...)
+type SessionTrigger struct {
+	Source   string `json:"source" binding:"required"`
+	Event    string `json:"event,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	PRNumber int    `json:"prNumber,omitempty" binding:"gte=0"`
+	HeadSHA  string `json:"headSha,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+	IssueKey string `json:"issueKey,omitempty"`
+	// Channel is the chat conversation or thread, e.g. a Teams conversation ID
+	Channel string `json:"channel,omitempty"`
+	// Incident is the alert group key or PagerDuty incident ID
+	Incident    string `json:"incident,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// DeliveryID identifies one webhook delivery (e.g. X-GitHub-Delivery). It is
+	// used for replay deduplication and is not part of the fingerprint.
+	DeliveryID string `json:"deliveryId,omitempty"`
+}
+
+// triggerFingerprint hashes the normalized identifying fields of a trigger. It is
+// deterministic so the same PR revision always maps to the same value.
+func triggerFingerprint(t SessionTrigger) string {
+	fields := map[string]string{
+		"source":   strings.ToLower(strings.TrimSpace(t.Source)),
+		"event":    strings.ToLower(strings.TrimSpace(t.Event)),
+		"repo":     strings.ToLower(strings.TrimSuffix(strings.TrimSpace(t.Repo), ".git")),
+		"headSha":  strings.ToLower(strings.TrimSpace(t.HeadSHA)),
+		"ref":      strings.TrimSpace(t.Ref),
+		"issueKey": strings.ToUpper(strings.TrimSpace(t.IssueKey)),
+		"channel":  strings.TrimSpace(t.Channel),
+		"incident": strings.TrimSpace(t.Incident),
+	}
+	if t.PRNumber > 0 {
+		fields["prNumber"] = strconv.Itoa(t.PRNumber)
+	}
+	keys := make([]string, 0, len(fields))
+	for k, v := range fields {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, fields[k])
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func triggerFingerprintLabelValue(fingerprint string) string {
+	if len(fingerprint) > triggerFingerprintLabelN {
+		return fingerprint[:triggerFingerprintLabelN]
+	}
+	return fingerprint
+}
+
+// triggerToSpec converts a trigger into its spec form with the fingerprint filled in
+func triggerToSpec(t SessionTrigger) map[string]interface{} {
+	m := map[string]interface{}{
+		"source":      t.Source,
+		"fingerprint": triggerFingerprint(t),
+	}
+	if t.Event != "" {
+		m["event"] = t.Event
+	}
+	if t.Repo != "" {
+		m["repo"] = t.Repo
+	}
+	if t.PRNumber > 0 {
+		m["prNumber"] = int64(t.PRNumber)
+	}
+	if t.HeadSHA != "" {
+		m["headSha"] = t.HeadSHA
+	}
+	if t.Ref != "" {
+		m["ref"] = t.Ref
+	}
+	if t.IssueKey != "" {
+		m["issueKey"] = t.IssueKey
+	}
+	if t.Channel != "" {
+		m["channel"] = t.Channel
+	}
+	if t.Incident != "" {
+		m["incident"] = t.Incident
+	}
+	if t.DeliveryID != "" {
+		m["deliveryId"] = t.DeliveryID
+	}
+	return m
+}
+
+func parseTrigger(m map[string]interface{}) *SessionTrigger {
+	t := &SessionTrigger{}
+	t.Source, _ = m["source"].(string)
+	t.Event, _ = m["event"].(string)
+	t.Repo, _ = m["repo"].(string)
+	t.HeadSHA, _ = m["headSha"].(string)
+	t.Ref, _ = m["ref"].(string)
+	t.IssueKey, _ = m["issueKey"].(string)
+	t.Channel, _ = m["channel"].(string)
+	t.Incident, _ = m["incident"].(string)
+	t.Fingerprint, _ = m["fingerprint"].(string)
+	t.DeliveryID, _ = m["deliveryId"].(string)
+	if v, ok := intFromSpec(m, "prNumber"); ok {
+		t.PRNumber = int(v)
+	}
+	return t
+}
+
+// sessionsByFingerprint returns sessions whose spec.trigger.fingerprint equals fingerprint,
+// using the label index to avoid scanning every session in the project.
+func sessionsByFingerprint(ctx context.Context, dyn dynamic.Interface, project, fingerprint string) ([]unstructured.Unstructured, error) {
+	list, err := dyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", triggerFingerprintLabel, triggerFingerprintLabelValue(fingerprint)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]unstructured.Unstructured, 0, len(list.Items))
+	for _, item := range list.Items {
+		fp, _, _ := unstructured.NestedString(item.Object, "spec", "trigger", "fingerprint")
+		if fp == fingerprint {
+			out = append(out, item)
+		}
+	}
+	// Newest first
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].GetCreationTimestamp().After(out[j].GetCreationTimestamp().Time)
+	})
+	return out, nil
+}
+
+// GET /api/projects/:projectName/trigger-fingerprints/:fingerprint
+// getSessionsByFingerprint returns the sessions created for a trigger fingerprint, newest first.
+func getSessionsByFingerprint(c *gin.Context) {
+	project := c.GetString("project")
+	fingerprint := strings.ToLower(c.Param("fingerprint"))
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	items, err := sessionsByFingerprint(c.Request.Context(), reqDyn, project, fingerprint)
+	if err != nil {
+		logErrorf(c, "Failed to look up sessions by fingerprint in project %s: %v", project, err)
+		respondError(c, http.StatusInternalServerError, "Failed to look up sessions")
+		return
+	}
+	sessions := make([]AgenticSession, 0, len(items))
+	for _, item := range items {
+		session := AgenticSession{
+			APIVersion: item.GetAPIVersion(),
+			Kind:       item.GetKind(),
+			Metadata:   item.Object["metadata"].(map[string]interface{}),
+		}
+		if spec, ok := item.Object["spec"].(map[string]interface{}); ok {
+			session.Spec = parseSpec(spec)
+		}
+		if status, ok := item.Object["status"].(map[string]interface{}); ok {
+			session.Status = parseStatus(status)
+		}
+		sessions = append(sessions, session)
+	}
+	c.JSON(http.StatusOK, gin.H{"fingerprint": fingerprint, "items": sessions})
+}
+
+// POST /api/projects/:projectName/trigger-fingerprints
+// computeTriggerFingerprint returns the fingerprint for a trigger and any sessions
+// already created for it, so integrations need not reimplement the hashing.
+func computeTriggerFingerprint(c *gin.Context) {
+	var t SessionTrigger
+	if err := c.ShouldBindJSON(&t); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(t.Source) == "" {
+		respondError(c, http.StatusBadRequest, "source is required")
+		return
+	}
+	c.Params = append(c.Params, gin.Param{Key: "fingerprint", Value: triggerFingerprint(t)})
+	getSessionsByFingerprint(c)
+}
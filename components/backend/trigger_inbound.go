@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	authnv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+)
+
+// Inbound triggers (Teams, Alertmanager, PagerDuty) authenticate their callers
+// themselves and create sessions through createSession as an access key
+// ServiceAccount the project names in spec.triggers, so triggered sessions pass
+// the same admission, policy and delivery deduplication as API requests.
+
+// triggerTokenSeconds is the lifetime of the token minted per triggered
+// session; 600 is the shortest the API server issues
+const triggerTokenSeconds = 600
+
+var (
+	triggerSettingsClientOnce sync.Once
+	triggerSettingsClient     dynamic.Interface
+	triggerSettingsClientErr  error
+)
+
+// TriggeredSession is the outcome of an inbound trigger in one project
+type TriggeredSession struct {
+	Project   string `json:"project"`
+	Name      string `json:"name,omitempty"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// DeadLetter names the WebhookDeadLetter the failed request is retried from
+	DeadLetter string `json:"deadLetter,omitempty"`
+	// Queued is set when the session is created later by a webhook queue worker
+	Queued bool `json:"queued,omitempty"`
+}
+
+// TriggerResponse lists the sessions an alert or incident created, one per
+// project whose trigger matched and authenticated it
+type TriggerResponse struct {
+	Sessions []TriggeredSession `json:"sessions"`
+	Message  string             `json:"message,omitempty"`
+}
+
+// triggerConfig is one project's spec.triggers entry for a source
+type triggerConfig struct {
+	Project string
+	Config  map[string]interface{}
+}
+
+// triggerClient reads ProjectSettings for inbound triggers. Trigger callers
+// have no Kubernetes identity, so this is the backend's own ServiceAccount.
+func triggerClient() (dynamic.Interface, error) {
+	triggerSettingsClientOnce.Do(func() {
+		triggerSettingsClient, triggerSettingsClientErr = dynamic.NewForConfig(baseKubeConfig)
+	})
+	return triggerSettingsClient, triggerSettingsClientErr
+}
+
+// triggerProjectSettings reads a project's settings for an inbound trigger,
+// preferring the informer cache
+func triggerProjectSettings(ctx context.Context, project string) (map[string]interface{}, error) {
+	dyn, err := triggerClient()
+	if err != nil {
+		return nil, err
+	}
+	return getProjectSettingsSpec(ctx, dyn, project)
+}
+
+// enabledTriggers returns spec.triggers.<source> of every project that enables
+// it, ordered by project
+func enabledTriggers(ctx context.Context, source string) ([]triggerConfig, error) {
+	var objs []*unstructured.Unstructured
+	if informersSynced.Load() {
+		cached, err := projectSettingsLister.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range cached {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				objs = append(objs, u)
+			}
+		}
+	} else {
+		dyn, err := triggerClient()
+		if err != nil {
+			return nil, err
+		}
+		list, err := dyn.Resource(getProjectSettingsResource()).List(ctx, v1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	}
+	out := []triggerConfig{}
+	for _, obj := range objs {
+		cfg, _, _ := unstructured.NestedMap(obj.Object, "spec", "triggers", source)
+		if enabled, _ := cfg["enabled"].(bool); enabled {
+			out = append(out, triggerConfig{Project: obj.GetNamespace(), Config: cfg})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Project < out[j].Project })
+	return out, nil
+}
+
+// triggerRequest starts a session request from the trigger's session template
+func triggerRequest(cfg map[string]interface{}) (CreateAgenticSessionRequest, error) {
+	var req CreateAgenticSessionRequest
+	if template, ok := cfg["session"].(map[string]interface{}); ok {
+		if err := triggerSessionTemplate(template, &req); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// triggerSessionTemplate applies a ProjectSettings session template to req. The
+// template holds CreateAgenticSessionRequest fields; the prompt and trigger come
+// from the triggering event and may not be set.
+func triggerSessionTemplate(template map[string]interface{}, req *CreateAgenticSessionRequest) error {
+	for _, key := range []string{"prompt", "trigger"} {
+		if _, ok := template[key]; ok {
+			return fmt.Errorf("%s is set from the triggering event and may not be in the template", key)
+		}
+	}
+	b, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return fmt.Errorf("invalid session template: %v", err)
+	}
+	return nil
+}
+
+// triggerServiceAccountToken mints a short-lived token for an access key
+// ServiceAccount, so a triggered session is admitted with that key's role
+func triggerServiceAccountToken(ctx context.Context, project, name string) (string, error) {
+	sas := k8sClient.CoreV1().ServiceAccounts(project)
+	sa, err := sas.Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if sa.Labels["app"] != "ambient-access-key" {
+		return "", fmt.Errorf("ServiceAccount %s is not an access key", name)
+	}
+	expiry := int64(triggerTokenSeconds)
+	tok, err := sas.CreateToken(ctx, name, &authnv1.TokenRequest{Spec: authnv1.TokenRequestSpec{ExpirationSeconds: &expiry}}, v1.CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return tok.Status.Token, nil
+}
+
+// capturedResponse buffers a handler's response so a trigger can translate it
+// for its caller
+type capturedResponse struct {
+	gin.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *capturedResponse) Header() http.Header         { return w.header }
+func (w *capturedResponse) WriteHeader(code int)        { w.status = code }
+func (w *capturedResponse) WriteHeaderNow()             {}
+func (w *capturedResponse) Status() int                 { return w.status }
+func (w *capturedResponse) Size() int                   { return w.body.Len() }
+func (w *capturedResponse) Written() bool               { return w.body.Len() > 0 }
+func (w *capturedResponse) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *capturedResponse) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// createTriggeredSession runs createSession for req as the access key
+// ServiceAccount sa, so triggered sessions pass the same admission, policy and
+// delivery deduplication as API requests. It returns createSession's status
+// and JSON response.
+func createTriggeredSession(c *gin.Context, project, sa string, req CreateAgenticSessionRequest) (int, map[string]interface{}, error) {
+	token, err := triggerServiceAccountToken(c.Request.Context(), project, sa)
+	if err != nil {
+		return 0, nil, fmt.Errorf("mint token for %s: %v", sa, err)
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(payload))
+	c.Request.ContentLength = int64(len(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Authorization", "Bearer "+token)
+	c.Request.Header.Del("X-Forwarded-Access-Token")
+	// Trigger routes sit outside /api, so forwarded identity headers were not
+	// replaced by a validated token; the session is the ServiceAccount's alone
+	clearRequestIdentity(c)
+	identity := fmt.Sprintf("system:serviceaccount:%s:%s", project, sa)
+	c.Set("project", project)
+	c.Set("userID", identity)
+	c.Set("userName", identity)
+
+	rec := &capturedResponse{ResponseWriter: c.Writer, header: http.Header{}, status: http.StatusOK}
+	writer := c.Writer
+	c.Writer = rec
+	createSession(c)
+	c.Writer = writer
+
+	resp := map[string]interface{}{}
+	_ = json.Unmarshal(rec.body.Bytes(), &resp)
+	return rec.status, resp, nil
+}
+
+// triggerSessionLink is the UI link for a session, or just its name when
+// AMBIENT_UI_URL is not set
+func triggerSessionLink(project, name string) string {
+	base := strings.TrimRight(os.Getenv("AMBIENT_UI_URL"), "/")
+	if base == "" {
+		return name
+	}
+	return fmt.Sprintf("[%s](%s/projects/%s/sessions/%s)", name, base, url.PathEscape(project), url.PathEscape(name))
+}
+
+// runTrigger creates req in t's project as the trigger's ServiceAccount and
+// reports the outcome. Failures that may pass on a later try are kept as dead
+// letters and replayed in the background.
+func runTrigger(c *gin.Context, t triggerConfig, req CreateAgenticSessionRequest) TriggeredSession {
+	result, retryable := attemptTrigger(c, t, req)
+	if retryable {
+		name, err := deadLetterTrigger(c, t.Project, req, result.Error)
+		if err != nil {
+			logErrorf(c, "Failed to record %s dead letter for %s: %v", req.Trigger.Source, t.Project, err)
+		} else {
+			result.DeadLetter = name
+			result.Error += "; it will be retried"
+		}
+	}
+	return result
+}
+
+// attemptTrigger makes one attempt at creating req and reports whether a
+// failure is worth retrying: the API server or the token mint failed rather
+// than the request being refused
+func attemptTrigger(c *gin.Context, t triggerConfig, req CreateAgenticSessionRequest) (TriggeredSession, bool) {
+	result := TriggeredSession{Project: t.Project}
+	sa, _ := t.Config["serviceAccount"].(string)
+	status, resp, err := createTriggeredSession(c, t.Project, sa, req)
+	if err != nil {
+		logErrorf(c, "Trigger %s in %s: %v", req.Trigger.Source, t.Project, err)
+		result.Error = "the trigger's service account is unavailable"
+		return result, true
+	}
+	result.Name, _ = resp["name"].(string)
+	result.Duplicate, _ = resp["duplicate"].(bool)
+	if status != http.StatusCreated && !result.Duplicate {
+		result.Error, _ = resp["error"].(string)
+		if result.Error == "" {
+			result.Error = http.StatusText(status)
+		}
+		return result, status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+	}
+	return result, false
+}
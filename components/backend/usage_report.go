@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"ambient-code-shared/money"
+)
+
+const usageReportTopTriggers = 10
+
+// UsageTotals is what a group of sessions consumed
+type UsageTotals struct {
+	Sessions     int64     `json:"sessions"`
+	CostUSD      money.USD `json:"costUsd"`
+	InputTokens  int64     `json:"inputTokens"`
+	OutputTokens int64     `json:"outputTokens"`
+	APICalls     int64     `json:"apiCalls"`
+}
+
+// sessionCostUSD is a session's cost by the operator's budget rule: its token
+// usage at the ModelPricing rates of its model, else the result's cost, or the
+// usage the runner reported when it failed before producing one
+func sessionCostUSD(obj *unstructured.Unstructured) money.USD {
+	if cost, ok := pricedSessionCost(obj); ok {
+		return cost
+	}
+	raw, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "status", "total_cost_usd")
+	if !found {
+		raw, _, _ = unstructured.NestedFieldNoCopy(obj.Object, "status", "usage", "cost_usd")
+	}
+	cost, _ := money.FromSpec(raw)
+	return cost
+}
+
+func (t *UsageTotals) add(obj *unstructured.Unstructured) {
+	t.Sessions++
+	t.CostUSD += sessionCostUSD(obj)
+	usage, _, _ := unstructured.NestedMap(obj.Object, "status", "usage")
+	t.InputTokens += int64(numberFromSpec(usage["input_tokens"]))
+	t.OutputTokens += int64(numberFromSpec(usage["output_tokens"]))
+	t.APICalls += int64(numberFromSpec(usage["api_calls"]))
+}
+
+// ModelUsage is the usage of the sessions that ran one model
+type ModelUsage struct {
+	Model string `json:"model"`
+	UsageTotals
+}
+
+// TriggerUsage is the usage of the sessions one trigger created
+type TriggerUsage struct {
+	Source string `json:"source"`
+	Event  string `json:"event,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	UsageTotals
+}
+
+// ArtifactStorageUsage is the artifact storage of the month's sessions
+type ArtifactStorageUsage struct {
+	Artifacts     int64 `json:"artifacts"`
+	ArtifactBytes int64 `json:"artifactBytes"`
+}
+
+// UsageReport is the chargeback report of GET .../reports/usage
+type UsageReport struct {
+	Namespace   string               `json:"namespace"`
+	Month       string               `json:"month"`
+	GeneratedAt string               `json:"generatedAt"`
+	Total       UsageTotals          `json:"total"`
+	ByPhase     map[string]int64     `json:"byPhase"`
+	Models      []ModelUsage         `json:"models"`
+	TopTriggers []TriggerUsage       `json:"topTriggers"`
+	Storage     ArtifactStorageUsage `json:"storage"`
+}
+
+// sessionStartTime is when a session counts toward spend: its status.startTime,
+// or else its creation, as the operator's budget does
+func sessionStartTime(obj *unstructured.Unstructured) time.Time {
+	if v, _, _ := unstructured.NestedString(obj.Object, "status", "startTime"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return obj.GetCreationTimestamp().Time
+}
+
+// sessionStartedIn reports whether a session counts toward [start, end)
+func sessionStartedIn(obj *unstructured.Unstructured, start, end time.Time) bool {
+	started := sessionStartTime(obj)
+	return !started.Before(start) && started.Before(end)
+}
+
+// buildUsageReport aggregates the sessions that started in month
+func buildUsageReport(c *gin.Context, project string, month time.Time, sessions []unstructured.Unstructured) UsageReport {
+	end := month.AddDate(0, 1, 0)
+	report := UsageReport{
+		Namespace:   project,
+		Month:       month.Format("2006-01"),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		ByPhase:     map[string]int64{},
+		Models:      []ModelUsage{},
+		TopTriggers: []TriggerUsage{},
+	}
+	models := map[string]*ModelUsage{}
+	triggers := map[string]*TriggerUsage{}
+	for i := range sessions {
+		s := &sessions[i]
+		if !sessionStartedIn(s, month, end) {
+			continue
+		}
+		report.Total.add(s)
+		phase, _, _ := unstructured.NestedString(s.Object, "status", "phase")
+		if phase == "" {
+			phase = "Pending"
+		}
+		report.ByPhase[phase]++
+
+		model, _, _ := unstructured.NestedString(s.Object, "spec", "llmSettings", "model")
+		if model == "" {
+			model = "unknown"
+		}
+		if models[model] == nil {
+			models[model] = &ModelUsage{Model: model}
+		}
+		models[model].add(s)
+
+		t := TriggerUsage{Source: "manual"}
+		if trig, ok, _ := unstructured.NestedMap(s.Object, "spec", "trigger"); ok {
+			if parsed := parseTrigger(trig); parsed.Source != "" {
+				t = TriggerUsage{Source: parsed.Source, Event: parsed.Event, Repo: parsed.Repo}
+			}
+		}
+		key := t.Source + "\x00" + t.Event + "\x00" + t.Repo
+		if triggers[key] == nil {
+			triggers[key] = &t
+		}
+		triggers[key].add(s)
+
+		// Storage is best effort: a session whose index cannot be read counts as empty
+		if index, err := artifacts.LoadIndex(c, project, s.GetName()); err == nil {
+			for _, a := range index {
+				report.Storage.Artifacts++
+				report.Storage.ArtifactBytes += a.Size
+			}
+		}
+	}
+	for _, m := range models {
+		report.Models = append(report.Models, *m)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		if report.Models[i].CostUSD != report.Models[j].CostUSD {
+			return report.Models[i].CostUSD > report.Models[j].CostUSD
+		}
+		return report.Models[i].Model < report.Models[j].Model
+	})
+	for _, t := range triggers {
+		report.TopTriggers = append(report.TopTriggers, *t)
+	}
+	sort.Slice(report.TopTriggers, func(i, j int) bool {
+		a, b := report.TopTriggers[i], report.TopTriggers[j]
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		if a.CostUSD != b.CostUSD {
+			return a.CostUSD > b.CostUSD
+		}
+		return a.Source+a.Event+a.Repo < b.Source+b.Event+b.Repo
+	})
+	if len(report.TopTriggers) > usageReportTopTriggers {
+		report.TopTriggers = report.TopTriggers[:usageReportTopTriggers]
+	}
+	return report
+}
+
+// writeUsageReportCSV flattens a report into one row per line item, keyed by
+// month, section (total, phase, model, trigger or storage) and name
+func writeUsageReportCSV(w *csv.Writer, r UsageReport) error {
+	row := func(section, name string, t UsageTotals, bytes string) []string {
+		return []string{r.Month, section, name, strconv.FormatInt(t.Sessions, 10), t.CostUSD.String(),
+			strconv.FormatInt(t.InputTokens, 10), strconv.FormatInt(t.OutputTokens, 10), strconv.FormatInt(t.APICalls, 10), bytes}
+	}
+	rows := [][]string{
+		{"month", "section", "name", "sessions", "cost_usd", "input_tokens", "output_tokens", "api_calls", "bytes"},
+		row("total", r.Namespace, r.Total, ""),
+	}
+	phases := make([]string, 0, len(r.ByPhase))
+	for p := range r.ByPhase {
+		phases = append(phases, p)
+	}
+	sort.Strings(phases)
+	for _, p := range phases {
+		rows = append(rows, []string{r.Month, "phase", p, strconv.FormatInt(r.ByPhase[p], 10), "", "", "", "", ""})
+	}
+	for _, m := range r.Models {
+		rows = append(rows, row("model", m.Model, m.UsageTotals, ""))
+	}
+	for _, t := range r.TopTriggers {
+		name := strings.Trim(strings.Join([]string{t.Source, t.Event, t.Repo}, "/"), "/")
+		rows = append(rows, row("trigger", name, t.UsageTotals, ""))
+	}
+	rows = append(rows, []string{r.Month, "storage", "artifacts", "", "", "", "", strconv.FormatInt(r.Storage.Artifacts, 10), strconv.FormatInt(r.Storage.ArtifactBytes, 10)})
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// GET /api/projects/:projectName/reports/usage
+// getUsageReport aggregates the sessions that started in a UTC month (?month=
+// YYYY-MM, default the current month) for chargeback: totals, sessions per
+// phase, usage per model, the top triggers and artifact storage. ?format=csv
+// returns the same line items as CSV. Sessions removed by retention are not
+// included, so reports of past months should be exported before they expire.
+func getUsageReport(c *gin.Context) {
+	project := c.GetString("project")
+	_, reqDyn := getK8sClientsForRequest(c)
+	if reqDyn == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return
+	}
+	month := time.Now().UTC()
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if raw := c.Query("month"); raw != "" {
+		m, err := time.Parse("2006-01", raw)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "month must be YYYY-MM")
+			return
+		}
+		month = m
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		respondError(c, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	sessions, cached := cachedSessions(project, labels.Everything())
+	if !cached {
+		list, err := reqDyn.Resource(getAgenticSessionV1Alpha1Resource()).Namespace(project).List(c.Request.Context(), v1.ListOptions{})
+		if err != nil {
+			logErrorf(c, "Failed to list agentic sessions in project %s: %v", project, err)
+			respondError(c, http.StatusInternalServerError, "Failed to list agentic sessions")
+			return
+		}
+		sessions = list.Items
+	}
+
+	report := buildUsageReport(c, project, month, sessions)
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-usage-%s.csv", project, report.Month)))
+	c.Status(http.StatusOK)
+	if err := writeUsageReportCSV(csv.NewWriter(c.Writer), report); err != nil {
+		logErrorf(c, "Failed to write usage report of %s: %v", project, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// sessionAdmissionWarnings returns soft issues with a candidate session that do
+// not block creation: a deprecated framework version, a monthly budget near
+// exhaustion and no tool restrictions. Lookups that fail are skipped; warnings
+// never fail a request.
+func sessionAdmissionWarnings(ctx context.Context, reqDyn dynamic.Interface, project, framework, version string) []string {
+	warnings := []string{}
+
+	framework, version = strings.TrimSpace(framework), strings.TrimSpace(version)
+	if framework == "" {
+		framework = defaultSessionFramework
+	}
+	if registry, err := frameworkRegistry(); err == nil {
+		if obj, err := registry.Get(ctx, framework, v1.GetOptions{}); err == nil {
+			fw := frameworkFromUnstructured(obj)
+			if version == "" {
+				version = fw.DefaultVersion
+			}
+			if version != "" && slices.Contains(fw.DeprecatedVersions, version) {
+				warnings = append(warnings, fmt.Sprintf("framework %s version %s is deprecated", framework, version))
+			}
+		}
+	}
+
+	cp, err := loadClusterPolicy(ctx)
+	if err != nil {
+		return warnings
+	}
+	obj, err := reqDyn.Resource(getProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return warnings
+	}
+	if errors.IsNotFound(err) {
+		obj = nil
+	}
+	if limit, warnPercent := projectBudget(obj, cp); limit > 0 {
+		if spent := monthlySpend(obj); spent < limit && spent >= limit.Percent(warnPercent) {
+			warnings = append(warnings, fmt.Sprintf("monthly budget nearly used: $%s of $%s spent", spent.Cents(), limit.Cents()))
+		}
+	}
+	var spec map[string]interface{}
+	if obj != nil {
+		spec, _, _ = unstructured.NestedMap(obj.Object, "spec")
+	}
+	if len(cp.blockedTools(spec)) == 0 {
+		warnings = append(warnings, "no runner tools are restricted; set sessionPolicy.blockedTools to limit what sessions can do")
+	}
+	return warnings
+}
+
+// setWarningHeaders adds a Warning header per message, in the form the
+// Kubernetes API uses for admission warnings
+func setWarningHeaders(c *gin.Context, warnings []string) {
+	for _, w := range warnings {
+		c.Writer.Header().Add("Warning", "299 - "+strconv.Quote(w))
+	}
+}
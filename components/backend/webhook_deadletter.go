@@ -0,0 +1,516 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+// An inbound trigger whose session could not be created because the API server
+// failed or throttled it is kept as a WebhookDeadLetter in the backend's
+// namespace, holding the session request built after the webhook was
+// authenticated. Replays create that request again as the trigger's current
+// ServiceAccount, and the delivery ID keeps a replay from creating a second
+// session when the original did get through.
+
+const (
+	// webhookPayloadKey holds the raw webhook body on the request context
+	webhookPayloadKey = "webhookPayload"
+	// maxDeadLetterPayloadBytes is the largest payload kept with a dead letter
+	maxDeadLetterPayloadBytes = 256 << 10
+
+	defaultDeadLetterMaxAttempts = 8
+	deadLetterBaseBackoff        = 30 * time.Second
+	deadLetterMaxBackoff         = time.Hour
+)
+
+func getWebhookDeadLetterResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "webhookdeadletters",
+	}
+}
+
+// WebhookDeadLetter is an inbound trigger waiting to be replayed. Phase is
+// Pending while retries remain, Replayed once a session was created and
+// Exhausted when retries ran out. Payload and Request are only returned for a
+// single dead letter.
+type WebhookDeadLetter struct {
+	Name           string                       `json:"name"`
+	Source         string                       `json:"source"`
+	Project        string                       `json:"project"`
+	DeliveryID     string                       `json:"deliveryId,omitempty"`
+	ReceivedAt     string                       `json:"receivedAt,omitempty"`
+	Reason         string                       `json:"reason,omitempty"`
+	Phase          string                       `json:"phase"`
+	Attempts       int64                        `json:"attempts"`
+	LastAttemptAt  string                       `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt  string                       `json:"nextAttemptAt,omitempty"`
+	LastError      string                       `json:"lastError,omitempty"`
+	Session        string                       `json:"session,omitempty"`
+	Payload        string                       `json:"payload,omitempty"`
+	PayloadOmitted bool                         `json:"payloadOmitted,omitempty"`
+	Request        *CreateAgenticSessionRequest `json:"request,omitempty"`
+}
+
+// WebhookDeadLetterList is the dead letter queue, newest first
+type WebhookDeadLetterList struct {
+	Items []WebhookDeadLetter `json:"items"`
+}
+
+// deadLetterMaxAttempts is how many replays a dead letter gets before it is
+// Exhausted (WEBHOOK_DLQ_MAX_ATTEMPTS, default 8; 0 disables automatic replay)
+func deadLetterMaxAttempts() int64 {
+	return max(intFromEnv("WEBHOOK_DLQ_MAX_ATTEMPTS", defaultDeadLetterMaxAttempts), 0)
+}
+
+// deadLetterBackoff is the wait before replay attempts+1: 30s doubling up to an hour
+func deadLetterBackoff(attempts int64) time.Duration {
+	if attempts >= 7 {
+		return deadLetterMaxBackoff
+	}
+	return min(deadLetterBaseBackoff<<attempts, deadLetterMaxBackoff)
+}
+
+// deadLetterName names a delivery's dead letter so a webhook redelivered while
+// its first delivery waits for replay is queued once
+func deadLetterName(source, project, deliveryID string) string {
+	if deliveryID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(project + "/" + deliveryID))
+	return fmt.Sprintf("webhook-%s-%s", source, hex.EncodeToString(sum[:])[:16])
+}
+
+// deadLetterTrigger queues req, which failed in project with reason, for
+// replay and returns the dead letter's name
+func deadLetterTrigger(c *gin.Context, project string, req CreateAgenticSessionRequest, reason string) (string, error) {
+	dyn, err := triggerClient()
+	if err != nil {
+		return "", err
+	}
+	source := req.Trigger.Source
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	request := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return "", err
+	}
+	spec := map[string]interface{}{
+		"source":     source,
+		"project":    project,
+		"deliveryId": req.Trigger.DeliveryID,
+		"receivedAt": time.Now().UTC().Format(time.RFC3339),
+		"reason":     reason,
+		"request":    request,
+	}
+	if v, ok := c.Get(webhookPayloadKey); ok {
+		if payload, _ := v.([]byte); len(payload) > maxDeadLetterPayloadBytes {
+			spec["payloadOmitted"] = true
+		} else if len(payload) > 0 {
+			spec["payload"] = string(payload)
+		}
+	}
+	metadata := map[string]interface{}{
+		"namespace": namespace,
+		"labels": map[string]interface{}{
+			"ambient-code.io/trigger-source": source,
+			"ambient-code.io/project":        project,
+		},
+	}
+	name := deadLetterName(source, project, req.Trigger.DeliveryID)
+	if name != "" {
+		metadata["name"] = name
+	} else {
+		metadata["generateName"] = "webhook-" + source + "-"
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "WebhookDeadLetter",
+		"metadata":   metadata,
+		"spec":       spec,
+	}}
+	created, err := dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).Create(c.Request.Context(), obj, v1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return name, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return created.GetName(), nil
+}
+
+// deadLetterFromObject converts a dead letter for API responses; full
+// includes the payload and the session request
+func deadLetterFromObject(obj *unstructured.Unstructured, full bool) WebhookDeadLetter {
+	str := func(fields ...string) string {
+		s, _, _ := unstructured.NestedString(obj.Object, fields...)
+		return s
+	}
+	dl := WebhookDeadLetter{
+		Name:          obj.GetName(),
+		Source:        str("spec", "source"),
+		Project:       str("spec", "project"),
+		DeliveryID:    str("spec", "deliveryId"),
+		ReceivedAt:    str("spec", "receivedAt"),
+		Reason:        str("spec", "reason"),
+		Phase:         str("status", "phase"),
+		LastAttemptAt: str("status", "lastAttemptAt"),
+		LastError:     str("status", "lastError"),
+		Session:       str("status", "session"),
+	}
+	dl.Attempts, _, _ = unstructured.NestedInt64(obj.Object, "status", "attempts")
+	if dl.ReceivedAt == "" {
+		dl.ReceivedAt = obj.GetCreationTimestamp().UTC().Format(time.RFC3339)
+	}
+	if dl.Phase == "" {
+		dl.Phase = "Pending"
+	}
+	if dl.Phase == "Pending" && dl.Attempts < deadLetterMaxAttempts() {
+		last := dl.LastAttemptAt
+		if last == "" {
+			last = dl.ReceivedAt
+		}
+		if t, err := time.Parse(time.RFC3339, last); err == nil {
+			dl.NextAttemptAt = t.Add(deadLetterBackoff(dl.Attempts)).UTC().Format(time.RFC3339)
+		}
+	}
+	if full {
+		dl.Payload = str("spec", "payload")
+		dl.PayloadOmitted, _, _ = unstructured.NestedBool(obj.Object, "spec", "payloadOmitted")
+		if request, ok, _ := unstructured.NestedMap(obj.Object, "spec", "request"); ok {
+			var req CreateAgenticSessionRequest
+			if b, err := json.Marshal(request); err == nil && json.Unmarshal(b, &req) == nil {
+				dl.Request = &req
+			}
+		}
+	}
+	return dl
+}
+
+// backgroundTriggerContext is the context createSession runs in when a dead
+// letter is replayed outside the webhook's request. It has no writer of its
+// own; createTriggeredSession captures the response.
+func backgroundTriggerContext(ctx context.Context) *gin.Context {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	return &gin.Context{Request: req}
+}
+
+// replayDeadLetter creates the dead letter's session request again and
+// reports whether a failure is worth another try. A trigger the project has
+// since disabled is not.
+func replayDeadLetter(ctx context.Context, obj *unstructured.Unstructured) (TriggeredSession, bool) {
+	project, _, _ := unstructured.NestedString(obj.Object, "spec", "project")
+	source, _, _ := unstructured.NestedString(obj.Object, "spec", "source")
+	result := TriggeredSession{Project: project}
+
+	dl := deadLetterFromObject(obj, true)
+	if dl.Request == nil || dl.Request.Trigger == nil {
+		result.Error = "the dead letter has no session request"
+		return result, false
+	}
+	spec, err := triggerProjectSettings(ctx, project)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read project settings: %v", err)
+		return result, true
+	}
+	cfg, _, _ := unstructured.NestedMap(spec, "triggers", source)
+	if enabled, _ := cfg["enabled"].(bool); !enabled {
+		result.Error = fmt.Sprintf("the %s trigger is no longer enabled", source)
+		return result, false
+	}
+	return attemptTrigger(backgroundTriggerContext(ctx), triggerConfig{Project: project, Config: cfg}, *dl.Request)
+}
+
+// claimDeadLetter records a replay attempt before it is made. The update
+// fails with a conflict when another replica claimed the dead letter first.
+func claimDeadLetter(ctx context.Context, dyn dynamic.Interface, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	claimed := obj.DeepCopy()
+	attempts, _, _ := unstructured.NestedInt64(claimed.Object, "status", "attempts")
+	_ = unstructured.SetNestedField(claimed.Object, attempts+1, "status", "attempts")
+	_ = unstructured.SetNestedField(claimed.Object, time.Now().UTC().Format(time.RFC3339), "status", "lastAttemptAt")
+	_ = unstructured.SetNestedField(claimed.Object, "Pending", "status", "phase")
+	return dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).UpdateStatus(ctx, claimed, v1.UpdateOptions{})
+}
+
+// finishDeadLetter records a replay's outcome on the dead letter it claimed
+func finishDeadLetter(ctx context.Context, dyn dynamic.Interface, claimed *unstructured.Unstructured, result TriggeredSession, retryable bool) (*unstructured.Unstructured, error) {
+	obj := claimed.DeepCopy()
+	attempts, _, _ := unstructured.NestedInt64(obj.Object, "status", "attempts")
+	switch {
+	case result.Error == "":
+		_ = unstructured.SetNestedField(obj.Object, "Replayed", "status", "phase")
+		_ = unstructured.SetNestedField(obj.Object, result.Name, "status", "session")
+		unstructured.RemoveNestedField(obj.Object, "status", "lastError")
+	case retryable && attempts < deadLetterMaxAttempts():
+		_ = unstructured.SetNestedField(obj.Object, result.Error, "status", "lastError")
+	default:
+		_ = unstructured.SetNestedField(obj.Object, "Exhausted", "status", "phase")
+		_ = unstructured.SetNestedField(obj.Object, result.Error, "status", "lastError")
+	}
+	return dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).UpdateStatus(ctx, obj, v1.UpdateOptions{})
+}
+
+// replayAndRecord claims, replays and records one attempt at a dead letter
+func replayAndRecord(ctx context.Context, dyn dynamic.Interface, obj *unstructured.Unstructured) (*unstructured.Unstructured, TriggeredSession, error) {
+	claimed, err := claimDeadLetter(ctx, dyn, obj)
+	if err != nil {
+		return nil, TriggeredSession{}, err
+	}
+	result, retryable := replayDeadLetter(ctx, claimed)
+	updated, err := finishDeadLetter(ctx, dyn, claimed, result, retryable)
+	return updated, result, err
+}
+
+// runDeadLetterRetries replays due dead letters every WEBHOOK_DLQ_RETRY_INTERVAL
+// (default 30s) and deletes replayed ones WEBHOOK_DLQ_RETENTION (default 7
+// days) after their replay. Exhausted dead letters stay until an administrator
+// replays or deletes them. Every replica runs it; claims keep two from
+// replaying the same attempt.
+func runDeadLetterRetries() {
+	dyn, err := triggerClient()
+	if err != nil {
+		log.Printf("webhook dead letters: retries disabled, failed to create dynamic client: %v", err)
+		return
+	}
+	interval := durationFromEnv("WEBHOOK_DLQ_RETRY_INTERVAL", deadLetterBaseBackoff)
+	retention := durationFromEnv("WEBHOOK_DLQ_RETENTION", 7*24*time.Hour)
+	for {
+		time.Sleep(interval)
+		retryDueDeadLetters(dyn, retention)
+	}
+}
+
+func retryDueDeadLetters(dyn dynamic.Interface, retention time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	list, err := dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("webhook dead letters: failed to list: %v", err)
+		return
+	}
+	now := time.Now()
+	for i := range list.Items {
+		obj := &list.Items[i]
+		dl := deadLetterFromObject(obj, false)
+		switch dl.Phase {
+		case "Replayed":
+			last, err := time.Parse(time.RFC3339, dl.LastAttemptAt)
+			if retention == 0 || err != nil || now.Sub(last) < retention {
+				continue
+			}
+			if err := dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).Delete(ctx, obj.GetName(), v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				log.Printf("webhook dead letters: failed to delete %s: %v", obj.GetName(), err)
+			}
+		case "Pending":
+			next, err := time.Parse(time.RFC3339, dl.NextAttemptAt)
+			if err != nil || next.After(now) {
+				continue
+			}
+			_, result, err := replayAndRecord(ctx, dyn, obj)
+			switch {
+			case errors.IsConflict(err):
+			case err != nil:
+				log.Printf("webhook dead letters: failed to replay %s: %v", obj.GetName(), err)
+			case result.Error != "":
+				log.Printf("webhook dead letters: replay %d of %s (%s in %s) failed: %s", dl.Attempts+1, obj.GetName(), dl.Source, dl.Project, result.Error)
+			default:
+				log.Printf("webhook dead letters: replayed %s as session %s in %s", obj.GetName(), result.Name, dl.Project)
+			}
+		}
+	}
+}
+
+// requireClusterAdmin answers 401 or 403 and returns false unless the caller
+// may update the ClusterAmbientPolicy, i.e. is a cluster administrator
+func requireClusterAdmin(c *gin.Context) bool {
+	reqK8s, _ := getK8sClientsForRequest(c)
+	if reqK8s == nil {
+		respondError(c, http.StatusUnauthorized, "Invalid or missing token")
+		return false
+	}
+	ssar := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Group:    "vteam.ambient-code",
+				Resource: "clusterambientpolicies",
+				Verb:     "update",
+			},
+		},
+	}
+	res, err := reqK8s.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Request.Context(), ssar, v1.CreateOptions{})
+	if err != nil {
+		logErrorf(c, "Failed to check cluster administrator permission: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to check permissions")
+		return false
+	}
+	if !res.Status.Allowed {
+		auditDeny(c, "admin")
+		respondError(c, http.StatusForbidden, "Only cluster administrators may manage webhook dead letters")
+		return false
+	}
+	return true
+}
+
+// deadLetterForRequest reads the :name dead letter, answering 404 or 500 itself
+func deadLetterForRequest(c *gin.Context, dyn dynamic.Interface) (*unstructured.Unstructured, bool) {
+	name := c.Param("name")
+	obj, err := dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).Get(c.Request.Context(), name, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("Dead letter %s not found", name))
+		return nil, false
+	}
+	if err != nil {
+		logErrorf(c, "Failed to get webhook dead letter %s: %v", name, err)
+		respondError(c, http.StatusInternalServerError, "Failed to get dead letter")
+		return nil, false
+	}
+	return obj, true
+}
+
+// GET /api/admin/webhooks/deadletter?source=S&project=P&phase=Pending|Replayed|Exhausted
+// listWebhookDeadLetters lists inbound triggers that failed to create a
+// session, newest first. Cluster administrators only.
+func listWebhookDeadLetters(c *gin.Context) {
+	if !requireClusterAdmin(c) {
+		return
+	}
+	dyn, err := triggerClient()
+	if err != nil {
+		logErrorf(c, "Failed to create client for webhook dead letters: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to list dead letters")
+		return
+	}
+	selector := labels.Set{}
+	if source := c.Query("source"); source != "" {
+		selector["ambient-code.io/trigger-source"] = source
+	}
+	if project := c.Query("project"); project != "" {
+		selector["ambient-code.io/project"] = project
+	}
+	list, err := dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).List(c.Request.Context(), v1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logErrorf(c, "Failed to list webhook dead letters: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to list dead letters")
+		return
+	}
+	phase := c.Query("phase")
+	resp := WebhookDeadLetterList{Items: []WebhookDeadLetter{}}
+	for i := range list.Items {
+		if dl := deadLetterFromObject(&list.Items[i], false); phase == "" || dl.Phase == phase {
+			resp.Items = append(resp.Items, dl)
+		}
+	}
+	sort.SliceStable(resp.Items, func(i, j int) bool { return resp.Items[i].ReceivedAt > resp.Items[j].ReceivedAt })
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /api/admin/webhooks/deadletter/:name
+// getWebhookDeadLetter returns a dead letter with its payload and session
+// request. Cluster administrators only.
+func getWebhookDeadLetter(c *gin.Context) {
+	if !requireClusterAdmin(c) {
+		return
+	}
+	dyn, err := triggerClient()
+	if err != nil {
+		logErrorf(c, "Failed to create client for webhook dead letters: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to get dead letter")
+		return
+	}
+	obj, ok := deadLetterForRequest(c, dyn)
+	if !ok {
+		return
+	}
+	dl := deadLetterFromObject(obj, true)
+	c.JSON(http.StatusOK, dl)
+}
+
+// POST /api/admin/webhooks/deadletter/:name/replay
+// replayWebhookDeadLetter replays a Pending or Exhausted dead letter now,
+// answering 502 with the updated dead letter when the session still cannot be
+// created. Cluster administrators only.
+func replayWebhookDeadLetter(c *gin.Context) {
+	if !requireClusterAdmin(c) {
+		return
+	}
+	dyn, err := triggerClient()
+	if err != nil {
+		logErrorf(c, "Failed to create client for webhook dead letters: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to replay dead letter")
+		return
+	}
+	obj, ok := deadLetterForRequest(c, dyn)
+	if !ok {
+		return
+	}
+	if dl := deadLetterFromObject(obj, false); dl.Phase == "Replayed" {
+		respondError(c, http.StatusConflict, fmt.Sprintf("Dead letter %s was already replayed as session %s", dl.Name, dl.Session))
+		return
+	}
+	auditDetail(c, "deadLetter", obj.GetName())
+	updated, result, err := replayAndRecord(c.Request.Context(), dyn, obj)
+	if errors.IsConflict(err) {
+		respondError(c, http.StatusConflict, "Dead letter is being replayed; retry shortly")
+		return
+	}
+	if err != nil {
+		logErrorf(c, "Failed to replay webhook dead letter %s: %v", obj.GetName(), err)
+		respondError(c, http.StatusInternalServerError, "Failed to replay dead letter")
+		return
+	}
+	dl := deadLetterFromObject(updated, false)
+	if result.Error != "" {
+		respondProblem(c, problem.New(http.StatusBadGateway, "", result.Error).With("deadLetter", dl))
+		return
+	}
+	auditDetail(c, "created", result.Name)
+	c.JSON(http.StatusOK, dl)
+}
+
+// DELETE /api/admin/webhooks/deadletter/:name
+// deleteWebhookDeadLetter discards a dead letter. Cluster administrators only.
+func deleteWebhookDeadLetter(c *gin.Context) {
+	if !requireClusterAdmin(c) {
+		return
+	}
+	dyn, err := triggerClient()
+	if err != nil {
+		logErrorf(c, "Failed to create client for webhook dead letters: %v", err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete dead letter")
+		return
+	}
+	name := c.Param("name")
+	err = dyn.Resource(getWebhookDeadLetterResource()).Namespace(namespace).Delete(c.Request.Context(), name, v1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("Dead letter %s not found", name))
+		return
+	}
+	if err != nil {
+		logErrorf(c, "Failed to delete webhook dead letter %s: %v", name, err)
+		respondError(c, http.StatusInternalServerError, "Failed to delete dead letter")
+		return
+	}
+	auditDetail(c, "deadLetter", name)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// With WEBHOOK_QUEUE set, inbound triggers answer once their session request
+// is queued, and workers create the sessions at most
+// WEBHOOK_QUEUE_PROJECT_RATE per minute in each project, so a burst of alerts
+// or incidents neither holds webhook callers nor floods the API server.
+// Sessions whose creation fails transiently become dead letters, as they do
+// without the queue.
+
+const (
+	defaultWebhookQueueSize        = 10000
+	defaultWebhookQueueWorkers     = 4
+	defaultWebhookQueueProjectRate = 30
+	defaultWebhookQueueBurst       = 5
+	// webhookQueueLaneSize is how many queued triggers a project may have
+	// waiting on its rate limit before the queue stops handing out more
+	webhookQueueLaneSize = 64
+	// queuedTriggerTimeout bounds creating one queued session
+	queuedTriggerTimeout = 2 * time.Minute
+)
+
+var errWebhookQueueFull = errors.New("webhook queue is full")
+
+// queuedTrigger is a session request waiting in the webhook queue. It was
+// built after the webhook was authenticated; the trigger's ServiceAccount is
+// looked up again when it is created.
+type queuedTrigger struct {
+	Source   string                      `json:"source"`
+	Project  string                      `json:"project"`
+	Request  CreateAgenticSessionRequest `json:"request"`
+	Payload  []byte                      `json:"payload,omitempty"`
+	QueuedAt time.Time                   `json:"queuedAt"`
+}
+
+// webhookQueue buffers triggers between the webhook handlers and the workers
+type webhookQueue interface {
+	// Enqueue returns errWebhookQueueFull when the backlog is at its limit
+	Enqueue(ctx context.Context, item queuedTrigger) error
+	// Consume hands queued triggers to handle until ctx ends; handle calls ack
+	// once the session request has been made
+	Consume(ctx context.Context, handle func(item queuedTrigger, ack func()))
+}
+
+var (
+	webhookTriggerQueue webhookQueue
+
+	webhookQueued         atomic.Int64
+	webhookQueueFallbacks atomic.Int64
+	webhookQueueProcessed atomic.Int64
+)
+
+// initWebhookQueue selects the webhook queue from WEBHOOK_QUEUE: "none"
+// (default) creates sessions within the webhook request, "memory" buffers up
+// to WEBHOOK_QUEUE_SIZE triggers in this replica and "redis" in the Redis
+// stream at WEBHOOK_QUEUE_REDIS_URL, shared by every replica and kept across
+// restarts.
+func initWebhookQueue() error {
+	size := max(intFromEnv("WEBHOOK_QUEUE_SIZE", defaultWebhookQueueSize), 1)
+	switch os.Getenv("WEBHOOK_QUEUE") {
+	case "", "none":
+		return nil
+	case "memory":
+		webhookTriggerQueue = &memoryWebhookQueue{items: make(chan queuedTrigger, size)}
+	case "redis":
+		raw := os.Getenv("WEBHOOK_QUEUE_REDIS_URL")
+		if raw == "" {
+			return fmt.Errorf("WEBHOOK_QUEUE=redis requires WEBHOOK_QUEUE_REDIS_URL")
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			return fmt.Errorf("WEBHOOK_QUEUE_REDIS_URL must be a redis:// or rediss:// URL")
+		}
+		stream := os.Getenv("WEBHOOK_QUEUE_REDIS_STREAM")
+		if stream == "" {
+			stream = "ambient-webhooks"
+		}
+		consumer, _ := os.Hostname()
+		if consumer == "" {
+			consumer = "backend"
+		}
+		webhookTriggerQueue = &redisWebhookQueue{
+			url:       u,
+			stream:    stream,
+			group:     "ambient-backend",
+			consumer:  consumer,
+			size:      size,
+			claimIdle: durationFromEnv("WEBHOOK_QUEUE_REDIS_CLAIM_IDLE", 5*time.Minute),
+		}
+	default:
+		return fmt.Errorf("unknown WEBHOOK_QUEUE %q", os.Getenv("WEBHOOK_QUEUE"))
+	}
+	go runWebhookQueueWorkers(context.Background(), webhookTriggerQueue)
+	log.Printf("Inbound triggers are queued (%s, up to %d)", os.Getenv("WEBHOOK_QUEUE"), size)
+	return nil
+}
+
+// startTrigger queues req for t's project when a webhook queue is configured
+// and otherwise creates it now. A trigger that cannot be queued is created
+// now rather than dropped.
+func startTrigger(c *gin.Context, t triggerConfig, req CreateAgenticSessionRequest) TriggeredSession {
+	if webhookTriggerQueue == nil {
+		return runTrigger(c, t, req)
+	}
+	item := queuedTrigger{Source: req.Trigger.Source, Project: t.Project, Request: req, QueuedAt: time.Now().UTC()}
+	if v, ok := c.Get(webhookPayloadKey); ok {
+		if payload, _ := v.([]byte); len(payload) <= maxDeadLetterPayloadBytes {
+			item.Payload = payload
+		}
+	}
+	if err := webhookTriggerQueue.Enqueue(c.Request.Context(), item); err != nil {
+		logWarnf(c, "Failed to queue %s trigger for %s, creating it now: %v", item.Source, t.Project, err)
+		webhookQueueFallbacks.Add(1)
+		return runTrigger(c, t, req)
+	}
+	webhookQueued.Add(1)
+	return TriggeredSession{Project: t.Project, Queued: true}
+}
+
+// anyTriggerQueued reports whether a session of resp was queued rather than
+// created, which webhook callers are told with 202
+func anyTriggerQueued(resp TriggerResponse) bool {
+	for _, s := range resp.Sessions {
+		if s.Queued {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingTrigger is a queued trigger handed to its project's lane
+type pendingTrigger struct {
+	item queuedTrigger
+	ack  func()
+}
+
+// runWebhookQueueWorkers creates the queued sessions with up to
+// WEBHOOK_QUEUE_WORKERS (default 4) at once. Each project has a lane that
+// waits on its own rate limit of WEBHOOK_QUEUE_PROJECT_RATE sessions a minute
+// (default 30, bursts of WEBHOOK_QUEUE_PROJECT_BURST, default 5), so one busy
+// project does not delay the others.
+func runWebhookQueueWorkers(ctx context.Context, q webhookQueue) {
+	workers := make(chan struct{}, max(intFromEnv("WEBHOOK_QUEUE_WORKERS", defaultWebhookQueueWorkers), 1))
+	perMinute := max(intFromEnv("WEBHOOK_QUEUE_PROJECT_RATE", defaultWebhookQueueProjectRate), 1)
+	burst := max(intFromEnv("WEBHOOK_QUEUE_PROJECT_BURST", defaultWebhookQueueBurst), 1)
+
+	var mu sync.Mutex
+	lanes := map[string]chan pendingTrigger{}
+	lane := func(project string) chan pendingTrigger {
+		mu.Lock()
+		defer mu.Unlock()
+		if ch, ok := lanes[project]; ok {
+			return ch
+		}
+		ch := make(chan pendingTrigger, webhookQueueLaneSize)
+		lanes[project] = ch
+		limiter := flowcontrol.NewTokenBucketRateLimiter(float32(perMinute)/60, int(burst))
+		go func() {
+			for p := range ch {
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+				workers <- struct{}{}
+				go func(p pendingTrigger) {
+					defer func() { <-workers }()
+					processQueuedTrigger(ctx, p.item)
+					p.ack()
+				}(p)
+			}
+		}()
+		return ch
+	}
+	q.Consume(ctx, func(item queuedTrigger, ack func()) {
+		select {
+		case lane(item.Project) <- pendingTrigger{item: item, ack: ack}:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// processQueuedTrigger creates a queued session as the trigger's current
+// ServiceAccount. A trigger the project has since disabled is dropped.
+func processQueuedTrigger(parent context.Context, item queuedTrigger) {
+	ctx, cancel := context.WithTimeout(parent, queuedTriggerTimeout)
+	defer cancel()
+	webhookQueueProcessed.Add(1)
+	if item.Request.Trigger == nil {
+		log.Printf("webhook queue: dropping %s trigger for %s without trigger metadata", item.Source, item.Project)
+		return
+	}
+	c := backgroundTriggerContext(ctx)
+	if len(item.Payload) > 0 {
+		c.Set(webhookPayloadKey, item.Payload)
+	}
+	spec, err := triggerProjectSettings(ctx, item.Project)
+	if err != nil {
+		name, derr := deadLetterTrigger(c, item.Project, item.Request, fmt.Sprintf("failed to read project settings: %v", err))
+		if derr != nil {
+			log.Printf("webhook queue: lost %s trigger for %s: failed to read ProjectSettings (%v) and to record a dead letter (%v)", item.Source, item.Project, err, derr)
+		} else {
+			log.Printf("webhook queue: %s trigger for %s kept as dead letter %s: failed to read ProjectSettings: %v", item.Source, item.Project, name, err)
+		}
+		return
+	}
+	cfg, _, _ := unstructured.NestedMap(spec, "triggers", item.Source)
+	if enabled, _ := cfg["enabled"].(bool); !enabled {
+		log.Printf("webhook queue: dropping %s trigger for %s, the trigger is no longer enabled", item.Source, item.Project)
+		return
+	}
+	result := runTrigger(c, triggerConfig{Project: item.Project, Config: cfg}, item.Request)
+	switch {
+	case result.DeadLetter != "":
+		log.Printf("webhook queue: %s trigger for %s failed and was kept as dead letter %s: %s", item.Source, item.Project, result.DeadLetter, result.Error)
+	case result.Error != "":
+		log.Printf("webhook queue: %s trigger for %s failed: %s", item.Source, item.Project, result.Error)
+	default:
+		log.Printf("webhook queue: %s trigger for %s created session %s after %s", item.Source, item.Project, result.Name, time.Since(item.QueuedAt).Round(time.Second))
+	}
+}
+
+// webhookQueueMetrics reports queue activity in Prometheus text format
+func webhookQueueMetrics() string {
+	return fmt.Sprintf("# HELP webhook_triggers_queued_total Inbound triggers queued for a worker\n# TYPE webhook_triggers_queued_total counter\nwebhook_triggers_queued_total %d\n", webhookQueued.Load()) +
+		fmt.Sprintf("# HELP webhook_triggers_queue_fallbacks_total Inbound triggers created in the request because they could not be queued\n# TYPE webhook_triggers_queue_fallbacks_total counter\nwebhook_triggers_queue_fallbacks_total %d\n", webhookQueueFallbacks.Load()) +
+		fmt.Sprintf("# HELP webhook_triggers_dequeued_total Queued inbound triggers taken by a worker\n# TYPE webhook_triggers_dequeued_total counter\nwebhook_triggers_dequeued_total %d\n", webhookQueueProcessed.Load())
+}
+
+// memoryWebhookQueue buffers triggers in this replica; they are lost if it
+// stops before they are created
+type memoryWebhookQueue struct {
+	items chan queuedTrigger
+}
+
+func (q *memoryWebhookQueue) Enqueue(_ context.Context, item queuedTrigger) error {
+	select {
+	case q.items <- item:
+		return nil
+	default:
+		return errWebhookQueueFull
+	}
+}
+
+func (q *memoryWebhookQueue) Consume(ctx context.Context, handle func(queuedTrigger, func())) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-q.items:
+			handle(item, func() {})
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisWebhookQueue keeps queued triggers in a Redis stream read by a consumer
+// group, so each trigger goes to one replica. Entries are acknowledged and
+// deleted once their session request has been made; entries a stopped replica
+// left unacknowledged are claimed by another after WEBHOOK_QUEUE_REDIS_CLAIM_IDLE
+// (default 5m), and delivery IDs keep a claimed trigger from creating a second
+// session. It speaks just enough RESP for the stream commands it needs.
+type redisWebhookQueue struct {
+	url       *url.URL
+	stream    string
+	group     string
+	consumer  string
+	size      int64
+	claimIdle time.Duration
+
+	mu   sync.Mutex
+	conn *redisConn
+}
+
+const (
+	redisDialTimeout    = 10 * time.Second
+	redisCommandTimeout = 10 * time.Second
+	// redisReadBlock is how long a read waits for new entries
+	redisReadBlock = 5 * time.Second
+	redisReadCount = 16
+)
+
+// redisError is an error reply
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialRedis connects to a redis:// or rediss:// URL, authenticating with its
+// user and password and selecting its database
+func dialRedis(ctx context.Context, u *url.URL) (*redisConn, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	var conn net.Conn
+	var err error
+	if u.Scheme == "rediss" {
+		d := &tls.Dialer{NetDialer: &net.Dialer{Timeout: redisDialTimeout}, Config: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		d := &net.Dialer{Timeout: redisDialTimeout}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := u.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err := rc.do(redisCommandTimeout, args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis AUTH: %v", err)
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" && db != "0" {
+		if _, err := rc.do(redisCommandTimeout, "SELECT", db); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis SELECT %s: %v", db, err)
+		}
+	}
+	return rc, nil
+}
+
+func (rc *redisConn) Close() error { return rc.conn.Close() }
+
+// do sends a command and reads its reply within timeout. Error replies are
+// returned as redisError; other errors leave the connection unusable.
+func (rc *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if err := rc.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(rc.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return rc.readReply()
+}
+
+func (rc *redisConn) readReply() (interface{}, error) {
+	line, err := rc.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rc.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			// An error inside an array belongs to that element
+			item, err := rc.readReply()
+			if _, ok := err.(redisError); err != nil && !ok {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// command runs a command on the shared connection, reconnecting after a
+// connection error
+func (q *redisWebhookQueue) command(ctx context.Context, args ...string) (interface{}, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.conn == nil {
+		conn, err := dialRedis(ctx, q.url)
+		if err != nil {
+			return nil, err
+		}
+		q.conn = conn
+	}
+	reply, err := q.conn.do(redisCommandTimeout, args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		q.conn.Close()
+		q.conn = nil
+	}
+	return reply, err
+}
+
+func (q *redisWebhookQueue) Enqueue(ctx context.Context, item queuedTrigger) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	n, err := q.command(ctx, "XLEN", q.stream)
+	if err != nil {
+		return err
+	}
+	if length, _ := n.(int64); length >= q.size {
+		return errWebhookQueueFull
+	}
+	_, err = q.command(ctx, "XADD", q.stream, "*", "trigger", string(data))
+	return err
+}
+
+// Consume reads the stream on its own connection, since reads block. It
+// starts with this consumer's own unacknowledged entries, left by a previous
+// run under the same hostname, and claims other consumers' idle entries
+// every claimIdle.
+func (q *redisWebhookQueue) Consume(ctx context.Context, handle func(queuedTrigger, func())) {
+	var conn *redisConn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	// pendingFrom walks this consumer's unacknowledged entries once; claimFrom
+	// walks the group's idle entries
+	pendingFrom, claimFrom := "0", "0-0"
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if conn == nil {
+			c, err := dialRedis(ctx, q.url)
+			if err != nil {
+				log.Printf("webhook queue: failed to connect to Redis: %v", err)
+				time.Sleep(redisReadBlock)
+				continue
+			}
+			conn = c
+			if _, err := conn.do(redisCommandTimeout, "XGROUP", "CREATE", q.stream, q.group, "0", "MKSTREAM"); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				log.Printf("webhook queue: failed to create consumer group %s on %s: %v", q.group, q.stream, err)
+				conn.Close()
+				conn = nil
+				time.Sleep(redisReadBlock)
+				continue
+			}
+		}
+
+		var entries []interface{}
+		var err error
+		switch {
+		case pendingFrom != "":
+			// Entries delivered to this consumer before it restarted
+			var reply interface{}
+			reply, err = conn.do(redisCommandTimeout, "XREADGROUP", "GROUP", q.group, q.consumer, "COUNT", strconv.Itoa(redisReadCount), "STREAMS", q.stream, pendingFrom)
+			entries = redisStreamEntries(reply)
+			pendingFrom = ""
+			if n := len(entries); n > 0 {
+				pendingFrom, _, _ = redisQueuedTrigger(entries[n-1])
+			}
+		case time.Since(lastClaim) >= q.claimIdle:
+			var reply interface{}
+			reply, err = conn.do(redisCommandTimeout, "XAUTOCLAIM", q.stream, q.group, q.consumer, strconv.FormatInt(q.claimIdle.Milliseconds(), 10), claimFrom, "COUNT", strconv.Itoa(redisReadCount))
+			if parts, ok := reply.([]interface{}); ok && len(parts) >= 2 {
+				claimFrom, _ = parts[0].(string)
+				entries, _ = parts[1].([]interface{})
+			}
+			// A cursor of 0-0 means every idle entry was claimed
+			if claimFrom == "" || claimFrom == "0-0" {
+				claimFrom = "0-0"
+				lastClaim = time.Now()
+			}
+		default:
+			var reply interface{}
+			reply, err = conn.do(redisReadBlock+redisCommandTimeout, "XREADGROUP", "GROUP", q.group, q.consumer, "COUNT", strconv.Itoa(redisReadCount), "BLOCK", strconv.FormatInt(redisReadBlock.Milliseconds(), 10), "STREAMS", q.stream, ">")
+			entries = redisStreamEntries(reply)
+		}
+		if err != nil {
+			log.Printf("webhook queue: failed to read %s: %v", q.stream, err)
+			if _, ok := err.(redisError); !ok {
+				conn.Close()
+				conn = nil
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+		for _, e := range entries {
+			id, item, err := redisQueuedTrigger(e)
+			if id == "" {
+				continue
+			}
+			ack := func() {
+				ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+				defer cancel()
+				if _, err := q.command(ctx, "XACK", q.stream, q.group, id); err != nil {
+					log.Printf("webhook queue: failed to acknowledge %s: %v", id, err)
+					return
+				}
+				if _, err := q.command(ctx, "XDEL", q.stream, id); err != nil {
+					log.Printf("webhook queue: failed to delete %s: %v", id, err)
+				}
+			}
+			if err != nil {
+				log.Printf("webhook queue: dropping unreadable entry %s: %v", id, err)
+				ack()
+				continue
+			}
+			handle(item, ack)
+		}
+	}
+}
+
+// redisStreamEntries returns the entries of the single stream in an
+// XREADGROUP reply
+func redisStreamEntries(reply interface{}) []interface{} {
+	streams, _ := reply.([]interface{})
+	if len(streams) == 0 {
+		return nil
+	}
+	stream, _ := streams[0].([]interface{})
+	if len(stream) < 2 {
+		return nil
+	}
+	entries, _ := stream[1].([]interface{})
+	return entries
+}
+
+// redisQueuedTrigger decodes a stream entry, [id, [field, value, ...]]. The
+// fields of an entry deleted while pending are nil.
+func redisQueuedTrigger(entry interface{}) (string, queuedTrigger, error) {
+	var item queuedTrigger
+	parts, _ := entry.([]interface{})
+	if len(parts) < 2 {
+		return "", item, nil
+	}
+	id, _ := parts[0].(string)
+	fields, _ := parts[1].([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		if name, _ := fields[i].(string); name == "trigger" {
+			data, _ := fields[i+1].(string)
+			return id, item, json.Unmarshal([]byte(data), &item)
+		}
+	}
+	return id, item, fmt.Errorf("entry has no trigger field")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	oaerrors "k8s.io/kube-openapi/pkg/validation/errors"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+
+	"ambient-code-backend/pkg/problem"
+)
+
+// defaultWebhookMaxBodyBytes bounds inbound webhook payloads unless
+// WEBHOOK_MAX_BODY_BYTES is set
+const defaultWebhookMaxBodyBytes = 1 << 20
+
+// webhookSchemaFiles holds a JSON schema per webhook payload (teams,
+// alertmanager, pagerduty, ...) and per trigger source sessions may be created
+// for (trigger-<source>). They use the OpenAPI subset the API server applies
+// to custom resources.
+//
+//go:embed webhook_schemas/*.json
+var webhookSchemaFiles embed.FS
+
+var webhookSchemas = loadWebhookSchemas()
+
+func loadWebhookSchemas() map[string]*validate.SchemaValidator {
+	files, err := webhookSchemaFiles.ReadDir("webhook_schemas")
+	if err != nil {
+		panic(err)
+	}
+	out := map[string]*validate.SchemaValidator{}
+	for _, f := range files {
+		raw, err := webhookSchemaFiles.ReadFile("webhook_schemas/" + f.Name())
+		if err != nil {
+			panic(err)
+		}
+		var schema spec.Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			panic(fmt.Sprintf("webhook schema %s: %v", f.Name(), err))
+		}
+		name := strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
+		out[name] = validate.NewSchemaValidator(&schema, nil, "", strfmt.Default)
+	}
+	return out
+}
+
+// webhookMaxBodyBytes is the largest webhook payload accepted (WEBHOOK_MAX_BODY_BYTES, default 1 MiB)
+func webhookMaxBodyBytes() int64 {
+	if n := intFromEnv("WEBHOOK_MAX_BODY_BYTES", defaultWebhookMaxBodyBytes); n > 0 {
+		return n
+	}
+	return defaultWebhookMaxBodyBytes
+}
+
+// validateWebhookValue checks value against the named schema and returns the
+// violations sorted by field; a name without a schema accepts anything
+func validateWebhookValue(name string, value interface{}) []PolicyFieldError {
+	validator, ok := webhookSchemas[name]
+	if !ok {
+		return nil
+	}
+	result := validator.Validate(value)
+	if result.IsValid() {
+		return nil
+	}
+	fieldErrors := make([]PolicyFieldError, 0, len(result.Errors))
+	for _, err := range result.Errors {
+		// Messages read "<name> in body <problem>"; required fields are named
+		// ".<field>" relative to their object
+		fe := PolicyFieldError{Field: "(root)", Message: err.Error()}
+		var v *oaerrors.Validation
+		if errors.As(err, &v) {
+			if name := strings.TrimPrefix(v.Name, "."); name != "" {
+				fe.Field = name
+			}
+			fe.Message = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(err.Error(), v.Name), " in body"))
+		}
+		fieldErrors = append(fieldErrors, fe)
+	}
+	sort.SliceStable(fieldErrors, func(i, j int) bool { return fieldErrors[i].Field < fieldErrors[j].Field })
+	return fieldErrors
+}
+
+// respondInvalidWebhook answers 422 with the payload's schema violations
+func respondInvalidWebhook(c *gin.Context, source string, fieldErrors []PolicyFieldError) {
+	respondProblem(c, problem.New(http.StatusUnprocessableEntity, "", fmt.Sprintf("Invalid %s payload", source)).
+		With("source", source).
+		With("fieldErrors", fieldErrors))
+}
+
+// readWebhookPayload reads a webhook body of at most webhookMaxBodyBytes and
+// validates it against source's schema, answering 413, 400 or 422 itself when
+// it is too large, not JSON or missing what the source requires. The body is
+// kept on the context for dead letters.
+func readWebhookPayload(c *gin.Context, source string) ([]byte, bool) {
+	limit := webhookMaxBodyBytes()
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondProblem(c, problem.New(http.StatusRequestEntityTooLarge, "", fmt.Sprintf("Payload exceeds the %d byte limit", limit)).
+				With("source", source).
+				With("limit", limit))
+			return nil, false
+		}
+		respondError(c, http.StatusBadRequest, "Failed to read payload")
+		return nil, false
+	}
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		respondProblem(c, problem.New(http.StatusBadRequest, "", fmt.Sprintf("Payload is not valid JSON: %v", err)).With("source", source))
+		return nil, false
+	}
+	if fieldErrors := validateWebhookValue(source, value); len(fieldErrors) > 0 {
+		respondInvalidWebhook(c, source, fieldErrors)
+		return nil, false
+	}
+	c.Set(webhookPayloadKey, body)
+	return body, true
+}
+
+// validateSessionTrigger checks a session's trigger against the schema for
+// its source, so forwarded webhooks cannot create sessions that integrations
+// will fail to report back to
+func validateSessionTrigger(t *SessionTrigger) []PolicyFieldError {
+	if t == nil {
+		return nil
+	}
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return []PolicyFieldError{{Field: "trigger", Message: err.Error()}}
+	}
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return []PolicyFieldError{{Field: "trigger", Message: err.Error()}}
+	}
+	fieldErrors := validateWebhookValue("trigger-"+strings.ToLower(strings.TrimSpace(t.Source)), value)
+	for i := range fieldErrors {
+		if fieldErrors[i].Field == "(root)" {
+			fieldErrors[i].Field = "trigger"
+		} else {
+			fieldErrors[i].Field = "trigger." + fieldErrors[i].Field
+		}
+	}
+	return fieldErrors
+}
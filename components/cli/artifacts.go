@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"vteamctl/pkg/client"
+)
+
+func newArtifactsListCommand(conn *connection) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list <session>",
+		Aliases: []string{"ls"},
+		Short:   "List a session's artifacts",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			items, err := api.ListArtifacts(cmd.Context(), conn.Project, args[0])
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tKIND\tSIZE\tUPLOADED")
+			for _, a := range items {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", a.Name, a.Kind, a.Size, a.UploadedAt)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newArtifactsGetCommand(conn *connection) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "get <session> <artifact>",
+		Short: "Download an artifact",
+		Long:  "Download an artifact to the current directory under its base name, to -o <file>, or to stdout with -o -.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			body, err := api.DownloadArtifact(cmd.Context(), conn.Project, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+
+			if output == "-" {
+				_, err := io.Copy(cmd.OutOrStdout(), body)
+				return err
+			}
+			if output == "" {
+				output = path.Base(args[1])
+			}
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(f, body)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(output)
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote %s (%d bytes)\n", output, n)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write; - writes to stdout")
+	return cmd
+}
+
+func newArtifactsDeleteCommand(conn *connection) *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <session> <artifact>...",
+		Aliases: []string{"rm"},
+		Short:   "Delete artifacts of a session",
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			for _, name := range args[1:] {
+				if err := api.DeleteArtifact(cmd.Context(), conn.Project, args[0], name); err != nil {
+					return fmt.Errorf("delete %s: %w", name, err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "deleted %s\n", name)
+			}
+			return nil
+		},
+	}
+}
+
+func newArtifactsSearchCommand(conn *connection) *cobra.Command {
+	var search client.ArtifactSearch
+	var since time.Duration
+	cmd := &cobra.Command{
+		Use:   "search [words...]",
+		Short: "Search artifacts across the project's sessions",
+		Long: `Search artifacts by name, tags, tool and the text of text artifacts. Every word
+must match; --type, --tool, --tag, --session and --since narrow the results.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			search.Query = strings.Join(args, " ")
+			if since > 0 {
+				search.After = time.Now().Add(-since)
+			}
+			hits, total, err := api.SearchArtifacts(cmd.Context(), conn.Project, search)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SESSION\tNAME\tKIND\tTAGS\tUPLOADED")
+			for _, h := range hits {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", h.Session, h.Artifact.Name, h.Artifact.Kind, strings.Join(h.Artifact.Tags, ","), h.Artifact.UploadedAt)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if total > len(hits) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "showing %d of %d matches; raise --limit to see more\n", len(hits), total)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&search.Kind, "type", "", "artifact kind: report, document, log, patch, data, image or binary")
+	cmd.Flags().StringVar(&search.Tool, "tool", "", "only artifacts produced by this tool")
+	cmd.Flags().StringVar(&search.Tag, "tag", "", "only artifacts with this tag")
+	cmd.Flags().StringVar(&search.Session, "session", "", "only this session's artifacts")
+	cmd.Flags().DurationVar(&since, "since", 0, "only artifacts uploaded within this long, e.g. 24h")
+	cmd.Flags().IntVar(&search.Limit, "limit", 0, "maximum matches to show (backend default 50)")
+	return cmd
+}
+
+// newArtifactsHoldCommand builds "hold" and "release", which add or remove the
+// hold tag while keeping the artifact's other tags
+func newArtifactsHoldCommand(conn *connection, hold bool) *cobra.Command {
+	use, short := "hold", "Exempt an artifact from retention (project admins)"
+	if !hold {
+		use, short = "release", "Let retention delete a held artifact again (project admins)"
+	}
+	return &cobra.Command{
+		Use:   use + " <session> <artifact>",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			items, err := api.ListArtifacts(cmd.Context(), conn.Project, args[0])
+			if err != nil {
+				return err
+			}
+			i := slices.IndexFunc(items, func(a client.Artifact) bool { return a.Name == strings.TrimLeft(args[1], "/") })
+			if i < 0 {
+				return fmt.Errorf("artifact %q not found in session %s", args[1], args[0])
+			}
+			tags := slices.DeleteFunc(items[i].Tags, func(t string) bool { return t == "hold" })
+			if hold {
+				tags = append(tags, "hold")
+			}
+			if _, err := api.SetArtifactTags(cmd.Context(), conn.Project, args[0], items[i].Name, tags); err != nil {
+				return err
+			}
+			if hold {
+				fmt.Fprintf(cmd.ErrOrStderr(), "held %s\n", items[i].Name)
+			} else {
+				fmt.Fprintf(cmd.ErrOrStderr(), "released %s\n", items[i].Name)
+			}
+			return nil
+		},
+	}
+}
+
+func newArtifactsExpiringCommand(conn *connection) *cobra.Command {
+	return &cobra.Command{
+		Use:   "expiring",
+		Short: "Show the artifacts the next retention pass would delete",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			report, err := api.RetentionReport(cmd.Context(), conn.Project)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SESSION\tNAME\tSIZE\tEXPIRED")
+			for _, it := range report.Items {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", it.Session, it.Name, it.Size, it.ExpiredAt)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%d artifacts, %d bytes; %d held\n", report.Artifacts, report.Bytes, report.Held)
+			return nil
+		},
+	}
+}
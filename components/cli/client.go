@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"vteamctl/pkg/client"
+)
+
+// connection holds the connection flags shared by every command
+type connection struct {
+	Server     string
+	Token      string
+	Project    string
+	Kubeconfig string
+	HTTP       *http.Client
+}
+
+// addConnectionFlags registers the persistent flags every command accepts,
+// defaulting to the VTEAM_* environment variables.
+func addConnectionFlags(root *cobra.Command) *connection {
+	c := &connection{HTTP: &http.Client{}}
+	fs := root.PersistentFlags()
+	fs.StringVar(&c.Server, "server", os.Getenv("VTEAM_SERVER"), "backend URL")
+	fs.StringVar(&c.Token, "token", os.Getenv("VTEAM_TOKEN"), "bearer token; defaults to the kubeconfig context's token")
+	fs.StringVarP(&c.Project, "project", "n", os.Getenv("VTEAM_PROJECT"), "project namespace; defaults to the kubeconfig context's namespace")
+	fs.StringVar(&c.Kubeconfig, "kubeconfig", "", "kubeconfig to read the token and namespace from (default $KUBECONFIG or ~/.kube/config)")
+	return c
+}
+
+// sdk returns a pkg/client Client, filling the token and project from the
+// kubeconfig when they were not given
+func (c *connection) sdk() (*client.Client, error) {
+	if c.Server == "" {
+		return nil, fmt.Errorf("no server configured; set --server or VTEAM_SERVER")
+	}
+	if c.Token == "" || c.Project == "" {
+		if err := c.fromKubeconfig(); err != nil {
+			return nil, err
+		}
+	}
+	if c.Project == "" {
+		return nil, fmt.Errorf("no project configured; set -n/--project or VTEAM_PROJECT")
+	}
+	return client.New(c.Server, client.WithToken(c.Token), client.WithHTTPClient(c.HTTP))
+}
+
+// fromKubeconfig reads the current context's bearer token and namespace. Only
+// token-based users are supported; exec and auth-provider plugins need --token.
+func (c *connection) fromKubeconfig() error {
+	kc, err := client.LoadKubeconfig(c.Kubeconfig, "")
+	if err != nil {
+		if c.Token != "" {
+			return nil
+		}
+		return fmt.Errorf("no token given and %v", err)
+	}
+	if c.Project == "" {
+		c.Project = kc.Namespace
+	}
+	if c.Token == "" {
+		if kc.Token == "" {
+			return fmt.Errorf("the kubeconfig context has no bearer token; set --token or VTEAM_TOKEN")
+		}
+		c.Token = kc.Token
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+// kubectl-ambient is a kubectl plugin for Ambient agentic sessions. Installed on
+// PATH it runs as "kubectl ambient"; it authenticates to the backend with the
+// kubeconfig context's token and uses its namespace as the project.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"vteamctl/pkg/client"
+)
+
+// options holds the connection flags; they mirror kubectl's where they overlap
+type options struct {
+	server     string
+	token      string
+	namespace  string
+	kubeconfig string
+	context    string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	o := &options{}
+	root := &cobra.Command{
+		Use:   "kubectl ambient",
+		Short: "Run and inspect Ambient agentic sessions",
+		Long: `Run and inspect Ambient agentic sessions.
+
+The backend URL comes from --server or VTEAM_SERVER. The bearer token and namespace
+default to the current kubeconfig context, as for kubectl.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	fs := root.PersistentFlags()
+	fs.StringVar(&o.server, "server", os.Getenv("VTEAM_SERVER"), "Ambient backend URL")
+	fs.StringVar(&o.token, "token", "", "bearer token; defaults to the kubeconfig context's token")
+	fs.StringVarP(&o.namespace, "namespace", "n", "", "project namespace; defaults to the kubeconfig context's namespace")
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&o.context, "context", "", "kubeconfig context to use")
+
+	root.AddCommand(newRunCommand(o), newLogsCommand(o), newTopCommand(o))
+	return root
+}
+
+// connect returns a client and the namespace to use. requireNamespace is false
+// for commands that can span namespaces.
+func (o *options) connect(requireNamespace bool) (*client.Client, string, error) {
+	if o.server == "" {
+		return nil, "", fmt.Errorf("no backend configured; set --server or VTEAM_SERVER")
+	}
+	token, namespace := o.token, o.namespace
+	if token == "" || namespace == "" {
+		kc, err := client.LoadKubeconfig(o.kubeconfig, o.context)
+		if err != nil && token == "" {
+			return nil, "", err
+		}
+		if kc != nil {
+			if token == "" {
+				token = kc.Token
+			}
+			if namespace == "" {
+				namespace = kc.Namespace
+			}
+		}
+	}
+	if token == "" {
+		return nil, "", fmt.Errorf("the kubeconfig context has no bearer token; log in again or pass --token")
+	}
+	if namespace == "" && requireNamespace {
+		return nil, "", fmt.Errorf("no namespace; pass -n or set one on the kubeconfig context")
+	}
+	c, err := client.New(o.server, client.WithToken(token))
+	return c, namespace, err
+}
+
+func newRunCommand(o *options) *cobra.Command {
+	var req client.CreateSessionRequest
+	var model string
+	var timeout int
+	var watch bool
+	cmd := &cobra.Command{
+		Use:     "run --prompt <prompt>",
+		Short:   "Create a session",
+		Example: `  kubectl ambient run --framework claude-code --prompt "Fix the failing unit tests" -w`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(req.Prompt) == "" {
+				return fmt.Errorf("--prompt is required")
+			}
+			if model != "" {
+				req.LLMSettings = &client.LLMSettings{Model: model}
+			}
+			if cmd.Flags().Changed("timeout") {
+				req.Timeout = &timeout
+			}
+			c, ns, err := o.connect(true)
+			if err != nil {
+				return err
+			}
+			created, err := c.CreateSession(cmd.Context(), ns, req)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "agenticsession/%s created\n", created.Name)
+			for _, w := range created.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", w)
+			}
+			if !watch {
+				return nil
+			}
+			s, err := c.WatchSession(cmd.Context(), ns, created.Name, func(s *client.Session) error {
+				fmt.Fprintf(out, "%s\t%s\n", phaseOf(s), messageOf(s))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if phaseOf(s) != client.PhaseCompleted {
+				return fmt.Errorf("agenticsession/%s %s", created.Name, strings.ToLower(phaseOf(s)))
+			}
+			return nil
+		},
+	}
+	fs := cmd.Flags()
+	fs.StringVar(&req.Prompt, "prompt", "", "prompt for the agent")
+	fs.StringVar(&req.Framework, "framework", "", "runner framework, e.g. claude-code")
+	fs.StringVar(&req.FrameworkVersion, "framework-version", "", "runner framework version")
+	fs.StringVar(&req.DisplayName, "display-name", "", "display name")
+	fs.StringVar(&model, "model", "", "model; defaults to the project's")
+	fs.IntVar(&timeout, "timeout", 0, "session timeout in seconds")
+	fs.StringToStringVarP(&req.Labels, "labels", "l", nil, "labels to set, as key=value")
+	fs.BoolVarP(&watch, "watch", "w", false, "wait for the session to finish, printing phase changes")
+	return cmd
+}
+
+func newLogsCommand(o *options) *cobra.Command {
+	opts := client.LogOptions{}
+	cmd := &cobra.Command{
+		Use:   "logs <session>",
+		Short: "Print a session's runner logs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, ns, err := o.connect(true)
+			if err != nil {
+				return err
+			}
+			body, err := c.SessionLogs(cmd.Context(), ns, args[0], opts)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			_, err = io.Copy(cmd.OutOrStdout(), body)
+			if cmd.Context().Err() != nil {
+				return nil
+			}
+			return err
+		},
+	}
+	fs := cmd.Flags()
+	fs.BoolVarP(&opts.Follow, "follow", "f", false, "follow the log as the session runs")
+	fs.StringVar(&opts.Since, "since", "", "only show lines newer than a relative duration, e.g. 5m")
+	fs.IntVar(&opts.Tail, "tail", -1, "number of recent lines to show; -1 shows all")
+	fs.BoolVar(&opts.Timestamps, "timestamps", false, "include pod log timestamps")
+	return cmd
+}
+
+// namespaceUsage is one row of kubectl ambient top
+type namespaceUsage struct {
+	namespace    string
+	running      int
+	queued       int
+	total        int
+	monthCost    float64
+	storageBytes int64
+	storageQuota int64
+}
+
+func newTopCommand(o *options) *cobra.Command {
+	var allNamespaces bool
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show session and storage usage per namespace",
+		Long: `Show, per namespace, the running and queued sessions, the total session count,
+this month's (UTC) spend counted against the namespace budget and the storage
+used by session workspaces against the quota.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, ns, err := o.connect(!allNamespaces)
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			namespaces := []string{ns}
+			if allNamespaces {
+				projects, err := c.ListProjects(ctx)
+				if err != nil {
+					return err
+				}
+				namespaces = namespaces[:0]
+				for _, p := range projects {
+					namespaces = append(namespaces, p.Name)
+				}
+				sort.Strings(namespaces)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 3, ' ', 0)
+			fmt.Fprintln(w, "NAMESPACE\tRUNNING\tQUEUED\tSESSIONS\tCOST(MONTH)\tSTORAGE")
+			for _, name := range namespaces {
+				u, err := namespaceUsageFor(ctx, c, name)
+				if err != nil {
+					// Skip namespaces the caller cannot read when listing all of them
+					if allNamespaces && client.IsForbidden(err) {
+						continue
+					}
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				storage := formatBytes(u.storageBytes)
+				if u.storageQuota > 0 {
+					storage += "/" + formatBytes(u.storageQuota)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t$%.2f\t%s\n", u.namespace, u.running, u.queued, u.total, u.monthCost, storage)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "show every project the caller can see")
+	return cmd
+}
+
+func namespaceUsageFor(ctx context.Context, c *client.Client, namespace string) (*namespaceUsage, error) {
+	stats, err := c.ProjectStats(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	u := &namespaceUsage{
+		namespace: namespace,
+		running:   stats.Sessions.Running,
+		queued:    stats.Sessions.Queued,
+		total:     stats.Sessions.Total,
+		monthCost: stats.Budget.SpentUSD,
+	}
+	if stats.Storage != nil {
+		u.storageBytes = stats.Storage.UsedBytes
+		u.storageQuota = stats.Storage.Quota.MaxTotalBytes
+	}
+	return u, nil
+}
+
+func phaseOf(s *client.Session) string {
+	if s.Status == nil || s.Status.Phase == "" {
+		return client.PhasePending
+	}
+	return s.Status.Phase
+}
+
+func messageOf(s *client.Session) string {
+	if s.Status == nil {
+		return ""
+	}
+	return s.Status.Message
+}
+
+// formatBytes renders a size with binary units, like kubectl top
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"vteamctl/pkg/client"
+)
+
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+	ansiGray   = "\033[90m"
+)
+
+var (
+	// Runner lines look like "2025-01-02 15:04:05,000 - INFO - message"
+	logLevelPattern   = regexp.MustCompile(`^\S+ \S+ - (DEBUG|INFO|WARNING|ERROR|CRITICAL) - `)
+	toolUsePattern    = regexp.MustCompile(`ToolUseBlock\(id='[^']*', name='([^']+)'`)
+	toolResultPattern = regexp.MustCompile(`ToolResultBlock\(tool_use_id='([^']*)'`)
+)
+
+// logPrinter formats runner log lines for a terminal
+type logPrinter struct {
+	out   io.Writer
+	color bool
+	fold  bool
+}
+
+func newSessionLogsCommand(conn *connection) *cobra.Command {
+	var opts client.LogOptions
+	var noFold bool
+	var colorMode string
+	cmd := &cobra.Command{
+		Use:     "logs <session>",
+		Aliases: []string{"log"},
+		Short:   "Print or follow a session's runner logs",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			color, err := useColor(colorMode)
+			if err != nil {
+				return err
+			}
+			body, err := api.SessionLogs(cmd.Context(), conn.Project, args[0], opts)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+
+			p := &logPrinter{out: cmd.OutOrStdout(), color: color, fold: !noFold}
+			return p.copy(body)
+		},
+	}
+	fs := cmd.Flags()
+	fs.BoolVarP(&opts.Follow, "follow", "f", false, "follow the log as the session runs")
+	fs.StringVar(&opts.Since, "since", "", "only show lines newer than a relative duration, e.g. 5m or 1h")
+	fs.IntVar(&opts.Tail, "tail", -1, "number of recent lines to show; -1 shows all")
+	fs.BoolVar(&opts.Timestamps, "timestamps", false, "prefix each line with the pod log timestamp")
+	fs.BoolVar(&noFold, "no-fold", false, "print tool calls and results in full instead of folding them")
+	fs.StringVar(&colorMode, "color", "auto", "colorize output: auto, always or never")
+	return cmd
+}
+
+// useColor resolves --color; auto colors only when stdout is a terminal and
+// NO_COLOR is unset.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid --color %q; use auto, always or never", mode)
+	}
+}
+
+func (p *logPrinter) copy(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	w := bufio.NewWriter(p.out)
+	for scanner.Scan() {
+		fmt.Fprintln(w, p.format(scanner.Text()))
+		// Flush per line so followed logs appear immediately
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// format colors a line by level and folds tool calls and results to one line
+func (p *logPrinter) format(line string) string {
+	if p.fold {
+		if m := toolUsePattern.FindStringSubmatch(line); m != nil {
+			return p.paint(ansiCyan, fmt.Sprintf("%s ▸ tool call %s (%d chars folded)", linePrefix(line), m[1], len(line)))
+		}
+		if m := toolResultPattern.FindStringSubmatch(line); m != nil {
+			marker := "tool result"
+			if strings.Contains(line, "is_error=True") {
+				return p.paint(ansiRed, fmt.Sprintf("%s ◂ %s %s failed (%d chars folded)", linePrefix(line), marker, m[1], len(line)))
+			}
+			return p.paint(ansiGray, fmt.Sprintf("%s ◂ %s %s (%d chars folded)", linePrefix(line), marker, m[1], len(line)))
+		}
+	}
+	m := logLevelPattern.FindStringSubmatch(line)
+	if m == nil {
+		return line
+	}
+	switch m[1] {
+	case "ERROR", "CRITICAL":
+		return p.paint(ansiRed, line)
+	case "WARNING":
+		return p.paint(ansiYellow, line)
+	case "DEBUG":
+		return p.paint(ansiGray, line)
+	}
+	return line
+}
+
+// linePrefix returns the "<time> - <LEVEL> -" part of a runner line, if any
+func linePrefix(line string) string {
+	if m := logLevelPattern.FindString(line); m != "" {
+		return strings.TrimSuffix(m, " ")
+	}
+	return ""
+}
+
+func (p *logPrinter) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + ansiReset
+}
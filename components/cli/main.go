@@ -0,0 +1,64 @@
+// vteam is a command line client for the vTeam backend API.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "vteam",
+		Short: "vteam controls vTeam agentic sessions",
+		Long: `vteam controls vTeam agentic sessions through the backend API.
+
+Connection flags default to VTEAM_SERVER, VTEAM_TOKEN and VTEAM_PROJECT. Without a
+token, the bearer token and namespace of the current kubeconfig context are used
+(e.g. after oc login).`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	conn := addConnectionFlags(root)
+
+	sessions := &cobra.Command{
+		Use:     "sessions",
+		Aliases: []string{"session"},
+		Short:   "Create, list, follow and cancel sessions",
+	}
+	sessions.AddCommand(
+		newSessionsCreateCommand(conn),
+		newSessionsListCommand(conn),
+		newSessionLogsCommand(conn),
+		newSessionsCancelCommand(conn),
+	)
+
+	artifacts := &cobra.Command{
+		Use:     "artifacts",
+		Aliases: []string{"artifact"},
+		Short:   "List, search, download and hold session artifacts",
+	}
+	artifacts.AddCommand(newArtifactsListCommand(conn), newArtifactsGetCommand(conn), newArtifactsDeleteCommand(conn), newArtifactsSearchCommand(conn),
+		newArtifactsHoldCommand(conn, true), newArtifactsHoldCommand(conn, false), newArtifactsExpiringCommand(conn))
+
+	policy := &cobra.Command{
+		Use:   "policy",
+		Short: "Inspect project and cluster session policy",
+	}
+	policy.AddCommand(newPolicySimulateCommand(conn))
+
+	root.AddCommand(sessions, artifacts, policy)
+	return root
+}
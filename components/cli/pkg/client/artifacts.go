@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListArtifacts returns the artifact index of a session
+func (c *Client) ListArtifacts(ctx context.Context, project, session string) ([]Artifact, error) {
+	var out struct {
+		Items []Artifact `json:"items"`
+	}
+	if err := c.getJSON(ctx, projectPath(project, "agentic-sessions", session, "artifacts"), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// DownloadArtifact streams an artifact's content; name is the artifact's
+// Name and may contain slashes. The caller closes the returned reader.
+func (c *Client) DownloadArtifact(ctx context.Context, project, session, name string) (io.ReadCloser, error) {
+	segments := append([]string{"agentic-sessions", session, "artifacts", "download"}, strings.Split(strings.TrimLeft(name, "/"), "/")...)
+	resp, err := c.do(ctx, http.MethodGet, projectPath(project, segments...), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PreviewArtifact renders a preview of an artifact without downloading it.
+// lines caps text previews and size the thumbnail's longer side; 0 keeps the
+// backend defaults.
+func (c *Client) PreviewArtifact(ctx context.Context, project, session, name string, lines, size int) (*ArtifactPreview, error) {
+	segments := append([]string{"agentic-sessions", session, "artifacts", "preview"}, strings.Split(strings.TrimLeft(name, "/"), "/")...)
+	q := url.Values{}
+	if lines > 0 {
+		q.Set("lines", strconv.Itoa(lines))
+	}
+	if size > 0 {
+		q.Set("size", strconv.Itoa(size))
+	}
+	var out ArtifactPreview
+	if err := c.getJSON(ctx, projectPath(project, segments...), q, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteArtifact removes an artifact. Held artifacts are refused with a conflict.
+func (c *Client) DeleteArtifact(ctx context.Context, project, session, name string) error {
+	segments := append([]string{"agentic-sessions", session, "artifacts"}, strings.Split(strings.TrimLeft(name, "/"), "/")...)
+	return c.sendJSON(ctx, http.MethodDelete, projectPath(project, segments...), nil, nil, nil)
+}
+
+// ArtifactSearch selects artifacts across a project's sessions. Every word
+// of Query must match an artifact's name, a tag, its tool or its text; the
+// other fields filter exactly and are ignored when empty.
+type ArtifactSearch struct {
+	Query   string
+	Kind    string
+	Tool    string
+	Tag     string
+	Session string
+	After   time.Time
+	// Limit caps the hits returned (the backend allows up to 200, default 50)
+	Limit int
+}
+
+// SearchArtifacts returns the best matching artifacts first and the total
+// number of matches
+func (c *Client) SearchArtifacts(ctx context.Context, project string, search ArtifactSearch) ([]ArtifactSearchHit, int, error) {
+	q := url.Values{}
+	for key, v := range map[string]string{"q": search.Query, "type": search.Kind, "tool": search.Tool, "tag": search.Tag, "session": search.Session} {
+		if v != "" {
+			q.Set(key, v)
+		}
+	}
+	if !search.After.IsZero() {
+		q.Set("after", search.After.UTC().Format(time.RFC3339))
+	}
+	if search.Limit > 0 {
+		q.Set("limit", strconv.Itoa(search.Limit))
+	}
+	var out struct {
+		Items []ArtifactSearchHit `json:"items"`
+		Total int                 `json:"total"`
+	}
+	if err := c.getJSON(ctx, projectPath(project, "artifacts", "search"), q, &out); err != nil {
+		return nil, 0, err
+	}
+	return out.Items, out.Total, nil
+}
+
+// SetArtifactTags replaces an artifact's tags. Adding or removing the "hold"
+// tag, which exempts the artifact from retention, takes project admin.
+func (c *Client) SetArtifactTags(ctx context.Context, project, session, name string, tags []string) (*Artifact, error) {
+	segments := append([]string{"agentic-sessions", session, "artifacts", "tags"}, strings.Split(strings.TrimLeft(name, "/"), "/")...)
+	var out Artifact
+	if err := c.sendJSON(ctx, http.MethodPut, projectPath(project, segments...), nil, map[string][]string{"tags": tags}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RetentionReport lists the artifacts the next retention pass would delete
+func (c *Client) RetentionReport(ctx context.Context, project string) (*RetentionReport, error) {
+	var out RetentionReport
+	if err := c.getJSON(ctx, projectPath(project, "retention", "report"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,264 @@
+// Package client is a Go client for the vTeam backend API. It covers the
+// session lifecycle (create, list, watch, logs) and session artifacts, and
+// handles bearer authentication, retries of transient failures and
+// list pagination.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenSource returns the bearer token for a request. It is called once per
+// attempt so short-lived tokens can be refreshed.
+type TokenSource func(ctx context.Context) (string, error)
+
+// Client talks to the backend's /api routes. It is safe for concurrent use.
+type Client struct {
+	server       string
+	token        TokenSource
+	http         *http.Client
+	retries      int
+	backoff      time.Duration
+	pollInterval time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken authenticates every request with a static bearer token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = func(context.Context) (string, error) { return token, nil }
+	}
+}
+
+// WithTokenSource authenticates requests with a token fetched per attempt
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) { c.token = ts }
+}
+
+// WithHTTPClient replaces the default http.Client, e.g. to configure TLS
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries sets how many times a transient failure is retried and the
+// initial backoff, which doubles per attempt. Zero retries disables retrying.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithPollInterval sets how often WatchSession polls the session
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Client) { c.pollInterval = d }
+}
+
+// New returns a client for the backend at server, e.g. https://vteam.apps.example.com
+func New(server string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", server)
+	}
+	c := &Client{
+		server:       strings.TrimRight(server, "/"),
+		http:         &http.Client{},
+		retries:      3,
+		backoff:      500 * time.Millisecond,
+		pollInterval: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// APIError is returned for non-2xx responses and carries the backend's error
+// message and, from problem documents, its machine-readable code
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// IsNotFound reports whether err is an APIError with status 404
+func IsNotFound(err error) bool {
+	return statusCode(err) == http.StatusNotFound
+}
+
+// IsForbidden reports whether err is an APIError with status 403
+func IsForbidden(err error) bool {
+	return statusCode(err) == http.StatusForbidden
+}
+
+// IsConflict reports whether err is an APIError with status 409
+func IsConflict(err error) bool {
+	return statusCode(err) == http.StatusConflict
+}
+
+func statusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e struct {
+		Code   string `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Error  string `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil {
+		msg := e.Detail
+		if msg == "" {
+			msg = e.Error
+		}
+		if msg == "" {
+			msg = e.Title
+		}
+		if msg != "" {
+			return &APIError{StatusCode: resp.StatusCode, Code: e.Code, Message: msg}
+		}
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+// projectPath builds /api/projects/<project>/<segments...>, escaping each segment
+func projectPath(project string, segments ...string) string {
+	p := "/api/projects/" + url.PathEscape(project)
+	for _, s := range segments {
+		p += "/" + url.PathEscape(s)
+	}
+	return p
+}
+
+// retryable reports whether a response status is worth retrying. POST is only
+// retried when the backend clearly did not process the request.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method != http.MethodPost
+	}
+	return false
+}
+
+// do sends a request and returns the response when the status is 2xx. Network
+// errors and retryable statuses are retried with exponential backoff, honoring
+// Retry-After. The caller closes the body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	u := c.server + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, reader)
+		if err != nil {
+			return nil, err
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != nil {
+			token, err := c.token(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("get token: %w", err)
+			}
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+
+		resp, err := c.http.Do(req)
+		last := attempt >= c.retries
+		if err != nil {
+			// A POST that failed in transit may have been applied; only retry idempotent methods
+			if last || method == http.MethodPost || ctx.Err() != nil {
+				return nil, err
+			}
+		} else if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		} else if last || !retryable(method, resp.StatusCode) {
+			defer resp.Body.Close()
+			return nil, apiError(resp)
+		} else {
+			if after := retryAfter(resp); after > 0 {
+				delay = after
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+// getJSON issues a GET and decodes the JSON response into out
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.sendJSON(ctx, http.MethodGet, path, query, nil, out)
+}
+
+func (c *Client) sendJSON(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubeconfigContext is what a kubeconfig context contributes to a connection
+type KubeconfigContext struct {
+	// Token is the context user's bearer token; empty for exec and auth-provider users
+	Token string
+	// Namespace is the context's namespace; empty when the context sets none
+	Namespace string
+}
+
+// LoadKubeconfig reads a context from the kubeconfig at path, or from
+// $KUBECONFIG and ~/.kube/config when path is empty. An empty context name
+// selects the current context.
+func LoadKubeconfig(path, context string) (*KubeconfigContext, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		rules.ExplicitPath = path
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	raw, err := cc.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %v", err)
+	}
+	out := &KubeconfigContext{}
+	name := context
+	if name == "" {
+		name = raw.CurrentContext
+	}
+	// Namespace() falls back to "default"; only use a namespace the context sets
+	if ctx := raw.Contexts[name]; ctx != nil {
+		out.Namespace = ctx.Namespace
+	}
+
+	cfg, err := cc.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %v", err)
+	}
+	switch {
+	case cfg.BearerToken != "":
+		out.Token = cfg.BearerToken
+	case cfg.BearerTokenFile != "":
+		b, err := os.ReadFile(cfg.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read kubeconfig token file: %v", err)
+		}
+		out.Token = strings.TrimSpace(string(b))
+	}
+	return out, nil
+}
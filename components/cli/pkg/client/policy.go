@@ -0,0 +1,16 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// SimulatePolicy evaluates req against the project and cluster policy without
+// creating a session
+func (c *Client) SimulatePolicy(ctx context.Context, project string, req CreateSessionRequest) (*PolicySimulation, error) {
+	var out PolicySimulation
+	if err := c.sendJSON(ctx, http.MethodPost, projectPath(project, "policy", "simulate"), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// Project is a namespace labeled as an Ambient project
+type Project struct {
+	Name              string `json:"name"`
+	DisplayName       string `json:"displayName"`
+	Description       string `json:"description,omitempty"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	Status            string `json:"status"`
+	// Role (admin, edit or view) and Permissions are the caller's access
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Stats is only set by ListProjectsWithStats, for projects whose sessions
+	// the caller can list
+	Stats *ProjectStats `json:"stats,omitempty"`
+}
+
+// ProjectStats is the namespace-level usage reported by /stats. Storage is
+// only reported by ProjectStats.
+type ProjectStats struct {
+	Sessions struct {
+		Total     int `json:"total"`
+		Pending   int `json:"pending"`
+		Queued    int `json:"queued"`
+		Running   int `json:"running"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+		Stopped   int `json:"stopped"`
+	} `json:"sessions"`
+	// Budget is the budget month's spend against the limit, 0 when unset; Month
+	// ("2006-01") is when it started and ResetsAt when the next one starts
+	Budget struct {
+		Month       string  `json:"month"`
+		SpentUSD    float64 `json:"spentUSD"`
+		LimitUSD    float64 `json:"limitUSD,omitempty"`
+		WarnPercent int64   `json:"warnPercent,omitempty"`
+		ResetsAt    string  `json:"resetsAt,omitempty"`
+		Usage       *struct {
+			InputTokens  int64 `json:"inputTokens"`
+			OutputTokens int64 `json:"outputTokens"`
+			APICalls     int64 `json:"apiCalls"`
+		} `json:"usage,omitempty"`
+	} `json:"budget"`
+	Storage *struct {
+		UsedBytes int64 `json:"usedBytes"`
+		Files     int64 `json:"files"`
+		Quota     struct {
+			MaxTotalBytes          int64 `json:"maxTotalBytes,omitempty"`
+			MaxArtifactsPerSession int64 `json:"maxArtifactsPerSession,omitempty"`
+		} `json:"quota"`
+	} `json:"storage,omitempty"`
+}
+
+// ListProjects returns the projects the caller can see
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	return c.listProjects(ctx, nil)
+}
+
+// ListProjectsWithStats returns the projects the caller can see with their
+// session counts and budget use
+func (c *Client) ListProjectsWithStats(ctx context.Context) ([]Project, error) {
+	return c.listProjects(ctx, url.Values{"include": {"stats"}})
+}
+
+func (c *Client) listProjects(ctx context.Context, query url.Values) ([]Project, error) {
+	var out struct {
+		Items []Project `json:"items"`
+	}
+	if err := c.getJSON(ctx, "/api/projects", query, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// ProjectStats returns session counts, budget use and storage usage for a project
+func (c *Client) ProjectStats(ctx context.Context, project string) (*ProjectStats, error) {
+	var out ProjectStats
+	if err := c.getJSON(ctx, projectPath(project, "stats"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions controls ListSessions. PageSize is the number of sessions fetched
+// per request (the backend caps it at 500); zero lets the backend return all.
+// Filters and sorting are applied by the backend.
+type ListOptions struct {
+	PageSize int
+	// Phases and Frameworks match any of the listed values
+	Phases        []string
+	Frameworks    []string
+	LabelSelector string
+	// Sort is createdAt, phase or name, prefixed with "-" for descending
+	Sort string
+}
+
+// CreateSession creates a session in project
+func (c *Client) CreateSession(ctx context.Context, project string, req CreateSessionRequest) (*CreateSessionResponse, error) {
+	var out CreateSessionResponse
+	if err := c.sendJSON(ctx, http.MethodPost, projectPath(project, "agentic-sessions"), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSession returns a session; a missing session yields an error for which
+// IsNotFound is true
+func (c *Client) GetSession(ctx context.Context, project, name string) (*Session, error) {
+	var out Session
+	if err := c.getJSON(ctx, projectPath(project, "agentic-sessions", name), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSessions returns every session in project, following continue tokens
+// when opts.PageSize is set
+func (c *Client) ListSessions(ctx context.Context, project string, opts ListOptions) ([]Session, error) {
+	var all []Session
+	token := ""
+	for {
+		q := url.Values{}
+		if len(opts.Phases) > 0 {
+			q.Set("phase", strings.Join(opts.Phases, ","))
+		}
+		if len(opts.Frameworks) > 0 {
+			q.Set("framework", strings.Join(opts.Frameworks, ","))
+		}
+		if opts.LabelSelector != "" {
+			q.Set("labelSelector", opts.LabelSelector)
+		}
+		if opts.Sort != "" {
+			q.Set("sort", opts.Sort)
+		}
+		if opts.PageSize > 0 {
+			q.Set("limit", strconv.Itoa(opts.PageSize))
+		}
+		if token != "" {
+			q.Set("continue", token)
+		}
+		var page struct {
+			Items    []Session `json:"items"`
+			Continue string    `json:"continue"`
+		}
+		if err := c.getJSON(ctx, projectPath(project, "agentic-sessions"), q, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		if page.Continue == "" {
+			return all, nil
+		}
+		token = page.Continue
+	}
+}
+
+// DeleteSession deletes a session
+func (c *Client) DeleteSession(ctx context.Context, project, name string) error {
+	return c.sendJSON(ctx, http.MethodDelete, projectPath(project, "agentic-sessions", name), nil, nil, nil)
+}
+
+// StopSession stops a running session
+func (c *Client) StopSession(ctx context.Context, project, name string) error {
+	return c.sendJSON(ctx, http.MethodPost, projectPath(project, "agentic-sessions", name, "stop"), nil, nil, nil)
+}
+
+// WatchSession polls a session and calls fn whenever its phase or message
+// changes, starting with the current state. It returns the session once it has
+// finished, the first error from fn, or ctx's error.
+func (c *Client) WatchSession(ctx context.Context, project, name string, fn func(*Session) error) (*Session, error) {
+	var lastPhase, lastMessage string
+	first := true
+	for {
+		s, err := c.GetSession(ctx, project, name)
+		if err != nil {
+			return nil, err
+		}
+		phase, message := "", ""
+		if s.Status != nil {
+			phase, message = s.Status.Phase, s.Status.Message
+		}
+		if fn != nil && (first || phase != lastPhase || message != lastMessage) {
+			if err := fn(s); err != nil {
+				return s, err
+			}
+		}
+		first, lastPhase, lastMessage = false, phase, message
+		if s.Finished() {
+			return s, nil
+		}
+		select {
+		case <-ctx.Done():
+			return s, ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// LogOptions mirror the query parameters of the logs endpoint
+type LogOptions struct {
+	Follow     bool
+	Timestamps bool
+	// Since is a relative duration such as 5m or 1h
+	Since string
+	// Tail limits output to the last N lines; negative shows all
+	Tail int
+}
+
+// SessionLogs streams the runner's log. The caller closes the returned reader;
+// with Follow it stays open until the session's runner exits.
+func (c *Client) SessionLogs(ctx context.Context, project, name string, opts LogOptions) (io.ReadCloser, error) {
+	q := url.Values{}
+	if opts.Follow {
+		q.Set("follow", "true")
+	}
+	if opts.Timestamps {
+		q.Set("timestamps", "true")
+	}
+	if opts.Since != "" {
+		q.Set("since", opts.Since)
+	}
+	if opts.Tail >= 0 {
+		q.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	resp, err := c.do(ctx, http.MethodGet, projectPath(project, "agentic-sessions", name, "logs"), q, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,236 @@
+package client
+
+// Session phases reported in SessionStatus.Phase
+const (
+	PhasePending   = "Pending"
+	PhaseCreating  = "Creating"
+	PhaseRunning   = "Running"
+	PhaseCompleted = "Completed"
+	PhaseFailed    = "Failed"
+	PhaseStopped   = "Stopped"
+	PhaseError     = "Error"
+)
+
+// Session is an AgenticSession as returned by the backend. Only commonly used
+// fields are mapped; the full schema is served at /openapi.json.
+type Session struct {
+	Metadata SessionMetadata `json:"metadata"`
+	Spec     SessionSpec     `json:"spec"`
+	Status   *SessionStatus  `json:"status,omitempty"`
+}
+
+type SessionMetadata struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	CreationTimestamp string            `json:"creationTimestamp"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+}
+
+type SessionSpec struct {
+	Prompt           string      `json:"prompt"`
+	DisplayName      string      `json:"displayName,omitempty"`
+	Interactive      bool        `json:"interactive,omitempty"`
+	LLMSettings      LLMSettings `json:"llmSettings"`
+	Timeout          int         `json:"timeout,omitempty"`
+	Framework        string      `json:"framework,omitempty"`
+	FrameworkVersion string      `json:"frameworkVersion,omitempty"`
+	Priority         string      `json:"priority,omitempty"`
+	RetryOf          string      `json:"retryOf,omitempty"`
+	RetryAttempt     int64       `json:"retryAttempt,omitempty"`
+	DryRun           bool        `json:"dryRun,omitempty"`
+}
+
+type LLMSettings struct {
+	Model       string  `json:"model,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	MaxTokens   int     `json:"maxTokens,omitempty"`
+	Provider    string  `json:"provider,omitempty"`
+}
+
+type SessionStatus struct {
+	Phase           string   `json:"phase,omitempty"`
+	Message         string   `json:"message,omitempty"`
+	StartTime       *string  `json:"startTime,omitempty"`
+	CompletionTime  *string  `json:"completionTime,omitempty"`
+	FirstOutputTime string   `json:"firstOutputTime,omitempty"`
+	JobName         string   `json:"jobName,omitempty"`
+	PipelineRunName string   `json:"pipelineRunName,omitempty"`
+	IsError         bool     `json:"is_error,omitempty"`
+	NumTurns        int      `json:"num_turns,omitempty"`
+	TotalCostUSD    *float64 `json:"total_cost_usd,omitempty"`
+	Result          *string  `json:"result,omitempty"`
+	// Usage holds the runner's token counts, api_calls, cost_usd and peak_memory_bytes
+	Usage map[string]interface{} `json:"usage,omitempty"`
+	// Queue is set while the session waits for capacity
+	Queue *SessionQueueStatus `json:"queue,omitempty"`
+	// PullRequests the session opened with its changes
+	PullRequests []SessionPullRequest `json:"pullRequests,omitempty"`
+	// Redactions counts secrets and personal data removed from the session's artifacts
+	Redactions *SessionRedactions `json:"redactions,omitempty"`
+	// Plan is what a dry-run session would have run
+	Plan *SessionPlan `json:"plan,omitempty"`
+}
+
+type SessionPlan struct {
+	Allowed               bool                         `json:"allowed"`
+	Workload              string                       `json:"workload"`
+	WorkloadName          string                       `json:"workloadName"`
+	Image                 string                       `json:"image,omitempty"`
+	Resources             map[string]map[string]string `json:"resources,omitempty"`
+	ActiveDeadlineSeconds int64                        `json:"activeDeadlineSeconds,omitempty"`
+	EstimatedCost         SessionCostEstimate          `json:"estimatedCost"`
+	Policy                []PolicyRuleResult           `json:"policy"`
+}
+
+type SessionCostEstimate struct {
+	CostUSD *float64 `json:"costUSD,omitempty"`
+	Samples int64    `json:"samples"`
+	Basis   string   `json:"basis,omitempty"`
+}
+
+type SessionRedactions struct {
+	Total      int64            `json:"total"`
+	Artifacts  int64            `json:"artifacts"`
+	ByDetector map[string]int64 `json:"byDetector,omitempty"`
+}
+
+type SessionPullRequest struct {
+	Repo    string `json:"repo"`
+	Branch  string `json:"branch"`
+	State   string `json:"state"`
+	Number  int64  `json:"number,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type SessionQueueStatus struct {
+	Reason   string `json:"reason,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	QueuedAt string `json:"queuedAt,omitempty"`
+}
+
+// Finished reports whether the session reached a phase it will not leave on its own
+func (s *Session) Finished() bool {
+	if s.Status == nil {
+		return false
+	}
+	switch s.Status.Phase {
+	case PhaseCompleted, PhaseFailed, PhaseStopped, PhaseError:
+		return true
+	}
+	return false
+}
+
+// CreateSessionRequest is the body of POST /agentic-sessions. Prompt is required;
+// unset fields take the project's defaults.
+type CreateSessionRequest struct {
+	Prompt                  string            `json:"prompt"`
+	DisplayName             string            `json:"displayName,omitempty"`
+	LLMSettings             *LLMSettings      `json:"llmSettings,omitempty"`
+	Timeout                 *int              `json:"timeout,omitempty"`
+	Interactive             *bool             `json:"interactive,omitempty"`
+	EnvironmentVariables    map[string]string `json:"environmentVariables,omitempty"`
+	Labels                  map[string]string `json:"labels,omitempty"`
+	Annotations             map[string]string `json:"annotations,omitempty"`
+	Framework               string            `json:"framework,omitempty"`
+	FrameworkVersion        string            `json:"frameworkVersion,omitempty"`
+	TTLSecondsAfterFinished *int64            `json:"ttlSecondsAfterFinished,omitempty"`
+	Priority                string            `json:"priority,omitempty"`
+	Tools                   *SessionTools     `json:"tools,omitempty"`
+	Budget                  *SessionBudget    `json:"budget,omitempty"`
+	// DryRun plans the session without running it; see SessionStatus.Plan
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// SessionTools narrows the runner's tools; Allowed must stay within the
+// project's allowed tools
+type SessionTools struct {
+	Allowed []string `json:"allowed,omitempty"`
+	Blocked []string `json:"blocked,omitempty"`
+}
+
+// SessionBudget refuses the session when MaxCostUSD, a decimal amount, exceeds
+// what remains of the project's monthly budget
+type SessionBudget struct {
+	MaxCostUSD string `json:"maxCostUSD"`
+}
+
+// CreateSessionResponse names the created session; Warnings lists admission
+// warnings such as a deprecated framework version
+type CreateSessionResponse struct {
+	Name     string   `json:"name"`
+	UID      string   `json:"uid"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Artifact is an entry of a session's artifact index
+type Artifact struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Size        int64    `json:"size"`
+	SHA256      string   `json:"sha256"`
+	ContentType string   `json:"contentType"`
+	Kind        string   `json:"kind"`
+	UploadedAt  string   `json:"uploadedAt"`
+	UploadedBy  string   `json:"uploadedBy,omitempty"`
+	Tool        string   `json:"tool,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	StorageKey  string   `json:"storageKey,omitempty"`
+	ExpiresAt   string   `json:"expiresAt,omitempty"`
+}
+
+// ArtifactSearchHit is an artifact found by SearchArtifacts
+type ArtifactSearchHit struct {
+	Session  string   `json:"session"`
+	Artifact Artifact `json:"artifact"`
+	Score    int      `json:"score"`
+}
+
+// ArtifactPreview is a small rendering of an artifact. Type is html (markdown
+// rendered to sanitized HTML), text or json (the first lines), image (a PNG
+// thumbnail data URL) or none.
+type ArtifactPreview struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"contentType"`
+	Type        string `json:"type"`
+	HTML        string `json:"html,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// RetentionReportItem is an artifact the next retention pass would delete
+type RetentionReportItem struct {
+	Session   string `json:"session"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	ExpiredAt string `json:"expiredAt"`
+}
+
+// RetentionReport previews artifact retention for a project
+type RetentionReport struct {
+	ArtifactRetention string                `json:"artifactRetention,omitempty"`
+	Artifacts         int64                 `json:"artifacts"`
+	Bytes             int64                 `json:"bytes"`
+	Held              int64                 `json:"held"`
+	Items             []RetentionReportItem `json:"items"`
+}
+
+// PolicyRuleResult is the outcome of one admission rule
+type PolicyRuleResult struct {
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// PolicySimulation reports whether a candidate session would be admitted
+type PolicySimulation struct {
+	Allowed  bool               `json:"allowed"`
+	Rules    []PolicyRuleResult `json:"rules"`
+	Warnings []string           `json:"warnings,omitempty"`
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"vteamctl/pkg/client"
+)
+
+func newPolicySimulateCommand(conn *connection) *cobra.Command {
+	var flags sessionRequestFlags
+	cmd := &cobra.Command{
+		Use:   "simulate [prompt...]",
+		Short: "Check whether a session would be admitted, without creating it",
+		Long: `Evaluate a candidate session against the project and cluster policy. Takes the
+same flags as sessions create and exits non-zero when the session would be rejected.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.prompt == "" && len(args) == 0 {
+				// Policy does not depend on the prompt
+				flags.prompt = "policy simulation"
+			}
+			req, err := flags.request(cmd.Flags(), args)
+			if err != nil {
+				return err
+			}
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			sim, err := api.SimulatePolicy(cmd.Context(), conn.Project, req)
+			if err != nil {
+				return err
+			}
+			if err := printPolicyRules(cmd.OutOrStdout(), sim.Rules); err != nil {
+				return err
+			}
+			for _, warning := range sim.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", warning)
+			}
+			if !sim.Allowed {
+				return fmt.Errorf("the session would be rejected")
+			}
+			return nil
+		},
+	}
+	flags.register(cmd.Flags())
+	return cmd
+}
+
+// printPolicyRules prints one row per admission rule, as policy simulate and
+// dry-run sessions report them
+func printPolicyRules(out io.Writer, rules []client.PolicyRuleResult) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tRESULT\tMESSAGE")
+	for _, r := range rules {
+		result := "pass"
+		if !r.Passed {
+			result = "DENY"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Rule, result, r.Message)
+	}
+	return w.Flush()
+}
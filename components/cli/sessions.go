@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"vteamctl/pkg/client"
+)
+
+// sessionRequestFlags builds a CreateSessionRequest; shared by sessions create
+// and policy simulate so a simulation matches the real request
+type sessionRequestFlags struct {
+	prompt           string
+	displayName      string
+	model            string
+	temperature      float64
+	maxTokens        int
+	timeout          int
+	interactive      bool
+	framework        string
+	frameworkVersion string
+	priority         string
+	ttl              int64
+	labels           map[string]string
+	env              map[string]string
+}
+
+func (f *sessionRequestFlags) register(fs *pflag.FlagSet) {
+	fs.StringVarP(&f.prompt, "prompt", "p", "", "prompt for the agent; may also be given as arguments")
+	fs.StringVar(&f.displayName, "display-name", "", "display name")
+	fs.StringVar(&f.model, "model", "", "model; defaults to the project's")
+	fs.Float64Var(&f.temperature, "temperature", 0, "sampling temperature")
+	fs.IntVar(&f.maxTokens, "max-tokens", 0, "maximum output tokens")
+	fs.IntVar(&f.timeout, "timeout", 0, "session timeout in seconds")
+	fs.BoolVar(&f.interactive, "interactive", false, "keep the session open for follow-up messages")
+	fs.StringVar(&f.framework, "framework", "", "runner framework")
+	fs.StringVar(&f.frameworkVersion, "framework-version", "", "runner framework version")
+	fs.StringVar(&f.priority, "priority", "", "queue priority: low, normal or high")
+	fs.Int64Var(&f.ttl, "ttl", 0, "delete the session this many seconds after it finishes")
+	fs.StringToStringVar(&f.labels, "label", nil, "label to set, as key=value; repeatable")
+	fs.StringToStringVar(&f.env, "env", nil, "environment variable for the runner, as KEY=value; repeatable")
+}
+
+// request returns the request body; only flags that were set are sent so the
+// backend applies the project's defaults for the rest
+func (f *sessionRequestFlags) request(fs *pflag.FlagSet, args []string) (client.CreateSessionRequest, error) {
+	prompt := f.prompt
+	if prompt == "" {
+		prompt = strings.Join(args, " ")
+	} else if len(args) > 0 {
+		return client.CreateSessionRequest{}, fmt.Errorf("give the prompt either with --prompt or as arguments, not both")
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return client.CreateSessionRequest{}, fmt.Errorf("a prompt is required")
+	}
+	req := client.CreateSessionRequest{
+		Prompt:               prompt,
+		DisplayName:          f.displayName,
+		Framework:            f.framework,
+		FrameworkVersion:     f.frameworkVersion,
+		Priority:             f.priority,
+		Labels:               f.labels,
+		EnvironmentVariables: f.env,
+	}
+	if fs.Changed("model") || fs.Changed("temperature") || fs.Changed("max-tokens") {
+		req.LLMSettings = &client.LLMSettings{Model: f.model, Temperature: f.temperature, MaxTokens: f.maxTokens}
+	}
+	if fs.Changed("timeout") {
+		req.Timeout = &f.timeout
+	}
+	if fs.Changed("interactive") {
+		req.Interactive = &f.interactive
+	}
+	if fs.Changed("ttl") {
+		req.TTLSecondsAfterFinished = &f.ttl
+	}
+	return req, nil
+}
+
+func newSessionsCreateCommand(conn *connection) *cobra.Command {
+	var flags sessionRequestFlags
+	var watch, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "create [prompt...]",
+		Short: "Create a session",
+		Example: `  vteam sessions create "Summarize the open issues" --model claude-sonnet-4 --watch
+  vteam sessions create -p "$(cat task.md)" --label team=docs
+  vteam sessions create "Refactor the parser" --model claude-opus-4 --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := flags.request(cmd.Flags(), args)
+			if err != nil {
+				return err
+			}
+			// A dry run finishes as soon as it is planned; wait for the plan
+			req.DryRun = dryRun
+			watch = watch || dryRun
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			created, err := api.CreateSession(cmd.Context(), conn.Project, req)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "session %s created\n", created.Name)
+			for _, w := range created.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", w)
+			}
+			if !watch {
+				return nil
+			}
+			return watchSession(cmd.Context(), api, conn.Project, created.Name, out)
+		},
+	}
+	flags.register(cmd.Flags())
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "follow the session's phase until it finishes")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "evaluate policy, estimate cost and plan the runner without running it")
+	return cmd
+}
+
+// watchSession prints each phase or message change with the time since the
+// watch started, then a summary. It fails when the session does not complete.
+func watchSession(ctx context.Context, api *client.Client, project, name string, out io.Writer) error {
+	start := time.Now()
+	s, err := api.WatchSession(ctx, project, name, func(s *client.Session) error {
+		phase, message := sessionPhase(s), ""
+		if s.Status != nil {
+			message = s.Status.Message
+		}
+		line := fmt.Sprintf("[%s] %-9s", formatElapsed(time.Since(start)), phase)
+		if message != "" {
+			line += " " + message
+		}
+		fmt.Fprintln(out, line)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if st := s.Status; st != nil {
+		summary := fmt.Sprintf("%s after %s", st.Phase, formatElapsed(time.Since(start)))
+		if st.NumTurns > 0 {
+			summary += fmt.Sprintf(", %d turns", st.NumTurns)
+		}
+		if st.TotalCostUSD != nil {
+			summary += fmt.Sprintf(", $%.4f", *st.TotalCostUSD)
+		}
+		fmt.Fprintln(out, summary)
+		if st.Plan != nil {
+			return printSessionPlan(out, st.Plan)
+		}
+		if st.Phase != client.PhaseCompleted {
+			return fmt.Errorf("session %s %s", name, strings.ToLower(st.Phase))
+		}
+	}
+	return nil
+}
+
+func newSessionsListCommand(conn *connection) *cobra.Command {
+	var watch bool
+	var interval time.Duration
+	opts := client.ListOptions{PageSize: 100}
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List sessions in the project",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			sessions, err := api.ListSessions(ctx, conn.Project, opts)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tPHASE\tMODEL\tAGE")
+			printed := map[string]string{}
+			printRows := func(sessions []client.Session) {
+				for i := range sessions {
+					s := &sessions[i]
+					phase := sessionPhase(s)
+					if p, ok := printed[s.Metadata.Name]; ok && p == phase {
+						continue
+					}
+					printed[s.Metadata.Name] = phase
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Metadata.Name, phase, s.Spec.LLMSettings.Model, sessionAge(s))
+				}
+				w.Flush()
+			}
+			printRows(sessions)
+			if !watch {
+				return nil
+			}
+			// Like kubectl get -w: print a row again whenever its phase changes
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+				sessions, err := api.ListSessions(ctx, conn.Project, opts)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				printRows(sessions)
+			}
+		},
+	}
+	cmd.Flags().StringSliceVar(&opts.Phases, "phase", nil, "only sessions in these phases, e.g. Running,Pending")
+	cmd.Flags().StringSliceVar(&opts.Frameworks, "framework", nil, "only sessions of these frameworks")
+	cmd.Flags().StringVarP(&opts.LabelSelector, "selector", "l", "", "label selector, e.g. team=docs")
+	cmd.Flags().StringVar(&opts.Sort, "sort", "", "sort by createdAt, phase or name; prefix - for descending")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep running and print sessions whose phase changes")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "polling interval for --watch")
+	return cmd
+}
+
+func newSessionsCancelCommand(conn *connection) *cobra.Command {
+	return &cobra.Command{
+		Use:     "cancel <session>...",
+		Aliases: []string{"stop"},
+		Short:   "Stop running sessions",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			api, err := conn.sdk()
+			if err != nil {
+				return err
+			}
+			for _, name := range args {
+				if err := api.StopSession(cmd.Context(), conn.Project, name); err != nil {
+					return fmt.Errorf("cancel %s: %w", name, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "session %s cancelled\n", name)
+			}
+			return nil
+		},
+	}
+}
+
+func sessionPhase(s *client.Session) string {
+	if s.Status == nil || s.Status.Phase == "" {
+		return client.PhasePending
+	}
+	return s.Status.Phase
+}
+
+func sessionAge(s *client.Session) string {
+	created, err := time.Parse(time.RFC3339, s.Metadata.CreationTimestamp)
+	if err != nil {
+		return "<unknown>"
+	}
+	d := time.Since(created)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// formatElapsed renders a duration as mm:ss, or h:mm:ss past an hour
+func formatElapsed(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s%3600/60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+// printSessionPlan prints what a dry-run session would have run. It fails
+// when the session would be rejected, like policy simulate.
+func printSessionPlan(out io.Writer, p *client.SessionPlan) error {
+	fmt.Fprintf(out, "%s %s\n", p.Workload, p.WorkloadName)
+	fmt.Fprintf(out, "  image:     %s\n", p.Image)
+	for _, kind := range []string{"requests", "limits"} {
+		if list := p.Resources[kind]; len(list) > 0 {
+			keys := make([]string, 0, len(list))
+			for k := range list {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			parts := make([]string, 0, len(keys))
+			for _, k := range keys {
+				parts = append(parts, k+"="+list[k])
+			}
+			fmt.Fprintf(out, "  %-10s %s\n", kind+":", strings.Join(parts, ", "))
+		}
+	}
+	if p.ActiveDeadlineSeconds > 0 {
+		fmt.Fprintf(out, "  deadline:  %ds\n", p.ActiveDeadlineSeconds)
+	}
+	if est := p.EstimatedCost; est.CostUSD != nil {
+		fmt.Fprintf(out, "  estimate:  $%.4f (%s, %d sessions)\n", *est.CostUSD, est.Basis, est.Samples)
+	} else {
+		fmt.Fprintf(out, "  estimate:  none (%s)\n", est.Basis)
+	}
+	fmt.Fprintln(out)
+	if err := printPolicyRules(out, p.Policy); err != nil {
+		return err
+	}
+	if !p.Allowed {
+		return fmt.Errorf("the session would be rejected")
+	}
+	return nil
+}
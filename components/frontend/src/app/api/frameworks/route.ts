@@ -0,0 +1,30 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+import { buildForwardHeadersAsync } from "@/lib/auth";
+
+// GET /api/frameworks - registered runner frameworks
+export async function GET(request: NextRequest) {
+  try {
+    const headers = await buildForwardHeadersAsync(request);
+
+    const response = await fetch(`${BACKEND_URL}/frameworks`, {
+      method: 'GET',
+      headers,
+    });
+
+    const data = await response.text();
+
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to fetch frameworks:", error);
+    return NextResponse.json(
+      { error: "Failed to fetch frameworks" },
+      { status: 500 }
+    );
+  }
+}
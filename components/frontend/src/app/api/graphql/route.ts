@@ -0,0 +1,32 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+import { buildForwardHeadersAsync } from "@/lib/auth";
+
+// POST /api/graphql - dashboard queries across projects, sessions and artifacts
+export async function POST(request: NextRequest) {
+  try {
+    const headers = await buildForwardHeadersAsync(request);
+    const body = await request.text();
+
+    const response = await fetch(`${BACKEND_URL}/graphql`, {
+      method: 'POST',
+      headers: { "Content-Type": "application/json", ...headers },
+      body,
+    });
+
+    const data = await response.text();
+
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to run GraphQL query:", error);
+    return NextResponse.json(
+      { errors: [{ message: "Failed to run GraphQL query" }] },
+      { status: 500 }
+    );
+  }
+}
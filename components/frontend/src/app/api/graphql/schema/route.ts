@@ -0,0 +1,30 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+import { buildForwardHeadersAsync } from "@/lib/auth";
+
+// GET /api/graphql/schema - the GraphQL schema in SDL
+export async function GET(request: NextRequest) {
+  try {
+    const headers = await buildForwardHeadersAsync(request);
+
+    const response = await fetch(`${BACKEND_URL}/graphql/schema`, {
+      method: 'GET',
+      headers,
+    });
+
+    const data = await response.text();
+
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": response.headers.get("Content-Type") ?? "text/plain",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to fetch GraphQL schema:", error);
+    return NextResponse.json(
+      { error: "Failed to fetch GraphQL schema" },
+      { status: 500 }
+    );
+  }
+}
@@ -0,0 +1,25 @@
+import { BACKEND_URL } from '@/lib/config'
+import { buildForwardHeadersAsync } from '@/lib/auth'
+
+// GET /api/projects/[name]/agentic-sessions/[sessionName]/artifacts/download/[...path]
+// Streams an artifact; used by result links posted to GitHub and other integrations.
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ name: string; sessionName: string; path: string[] }> },
+) {
+  const { name, sessionName, path } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const range = request.headers.get('range')
+  if (range) headers['Range'] = range
+  const rel = path.map(encodeURIComponent).join('/')
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/artifacts/download/${rel}`,
+    { headers },
+  )
+  const out = new Headers()
+  for (const h of ['content-type', 'content-length', 'content-disposition', 'content-range', 'accept-ranges', 'last-modified']) {
+    const v = resp.headers.get(h)
+    if (v) out.set(h, v)
+  }
+  return new Response(resp.body, { status: resp.status, headers: out })
+}
@@ -0,0 +1,28 @@
+import { BACKEND_URL } from '@/lib/config'
+import { buildForwardHeadersAsync } from '@/lib/auth'
+
+// GET /api/projects/[name]/agentic-sessions/[sessionName]/events
+// Relays the backend's Server-Sent Events stream of session status changes.
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ name: string; sessionName: string }> },
+) {
+  const { name, sessionName } = await params
+  const headers = await buildForwardHeadersAsync(request)
+  const resp = await fetch(
+    `${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/events`,
+    { headers: { ...headers, Accept: 'text/event-stream' }, signal: request.signal },
+  )
+  if (!resp.ok || !resp.body) {
+    const data = await resp.text()
+    return new Response(data, { status: resp.status, headers: { 'Content-Type': 'application/json' } })
+  }
+  return new Response(resp.body, {
+    status: resp.status,
+    headers: {
+      'Content-Type': 'text/event-stream',
+      'Cache-Control': 'no-cache',
+      'X-Accel-Buffering': 'no',
+    },
+  })
+}
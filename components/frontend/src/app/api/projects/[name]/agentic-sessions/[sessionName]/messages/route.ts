@@ -7,7 +7,9 @@ export async function GET(
 ) {
   const { name, sessionName } = await params
   const headers = await buildForwardHeadersAsync(request)
-  const resp = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/messages`, {
+  // Forward ?offset, limit, role, tool and contentType for paginated transcripts
+  const search = new URL(request.url).search
+  const resp = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions/${encodeURIComponent(sessionName)}/messages${search}`, {
     method: 'GET',
     headers,
   })
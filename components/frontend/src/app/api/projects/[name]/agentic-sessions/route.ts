@@ -9,7 +9,17 @@ export async function GET(
   try {
     const { name } = await params;
     const headers = await buildForwardHeadersAsync(request);
-    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions`, { headers });
+    // Forward ?phase, framework, labelSelector, sort, limit and continue, and
+    // watch, resourceVersion and timeoutSeconds for a watch
+    const url = new URL(request.url);
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/agentic-sessions${url.search}`, { headers, signal: request.signal });
+    // A watch streams JSON lines; relay it unbuffered
+    if (url.searchParams.get('watch') === 'true' && response.ok && response.body) {
+      return new Response(response.body, {
+        status: response.status,
+        headers: { 'Content-Type': 'application/x-ndjson', 'Cache-Control': 'no-cache', 'X-Accel-Buffering': 'no' },
+      });
+    }
     const text = await response.text();
     return new Response(text, { status: response.status, headers: { 'Content-Type': 'application/json' } });
   } catch (error) {
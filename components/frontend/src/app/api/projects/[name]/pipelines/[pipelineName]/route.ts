@@ -0,0 +1,36 @@
+import { BACKEND_URL } from '@/lib/config';
+import { buildForwardHeadersAsync } from '@/lib/auth';
+
+type Ctx = { params: Promise<{ name: string; pipelineName: string }> };
+
+// GET /api/projects/[name]/pipelines/[pipelineName]
+export async function GET(request: Request, { params }: Ctx) {
+  try {
+    const { name, pipelineName } = await params;
+    const headers = await buildForwardHeadersAsync(request);
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/pipelines/${encodeURIComponent(pipelineName)}`, { headers });
+    const text = await response.text();
+    return new Response(text, { status: response.status, headers: { 'Content-Type': 'application/json' } });
+  } catch (error) {
+    console.error('Error fetching session pipeline:', error);
+    return Response.json({ error: 'Failed to fetch session pipeline' }, { status: 500 });
+  }
+}
+
+// DELETE /api/projects/[name]/pipelines/[pipelineName]
+export async function DELETE(request: Request, { params }: Ctx) {
+  try {
+    const { name, pipelineName } = await params;
+    const headers = await buildForwardHeadersAsync(request);
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/pipelines/${encodeURIComponent(pipelineName)}`, {
+      method: 'DELETE',
+      headers,
+    });
+    if (response.status === 204) return new Response(null, { status: 204 });
+    const text = await response.text();
+    return new Response(text, { status: response.status, headers: { 'Content-Type': 'application/json' } });
+  } catch (error) {
+    console.error('Error deleting session pipeline:', error);
+    return Response.json({ error: 'Failed to delete session pipeline' }, { status: 500 });
+  }
+}
@@ -0,0 +1,35 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+import { buildForwardHeadersAsync } from "@/lib/auth";
+
+// POST evaluates a candidate session against project and cluster policy without creating it
+export async function POST(
+  request: NextRequest,
+  { params }: { params: Promise<{ name: string }> }
+) {
+  try {
+    const { name: projectName } = await params;
+    const body = await request.text();
+    const headers = await buildForwardHeadersAsync(request);
+
+    const response = await fetch(`${BACKEND_URL}/projects/${projectName}/policy/simulate`, {
+      method: "POST",
+      headers: { ...headers, "Content-Type": "application/json" },
+      body,
+    });
+
+    const data = await response.text();
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to simulate session policy:", error);
+    return NextResponse.json(
+      { error: "Failed to simulate session policy" },
+      { status: 500 }
+    );
+  }
+}
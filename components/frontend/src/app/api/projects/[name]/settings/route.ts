@@ -1,5 +1,6 @@
 import { NextRequest, NextResponse } from "next/server";
 import { BACKEND_URL } from "@/lib/config";
+import { buildForwardHeadersAsync } from "@/lib/auth";
 
 export async function GET(
   request: NextRequest,
@@ -7,16 +8,12 @@ export async function GET(
 ) {
   try {
     const { name: projectName } = await params;
+    const headers = await buildForwardHeadersAsync(request);
 
     // Forward the request to the backend
     const response = await fetch(`${BACKEND_URL}/projects/${projectName}/settings`, {
       method: "GET",
-      headers: {
-        "Content-Type": "application/json",
-        // Forward authentication headers from the client request
-        "X-User-ID": request.headers.get("X-User-ID") || "",
-        "X-User-Groups": request.headers.get("X-User-Groups") || "",
-      },
+      headers,
     });
 
     // Forward the response from backend
@@ -44,16 +41,14 @@ export async function PUT(
   try {
     const { name: projectName } = await params;
     const body = await request.text();
+    const headers = await buildForwardHeadersAsync(request);
+    // dryRun=true validates without saving; force=true takes over conflicting fields
+    const search = new URL(request.url).search;
 
     // Forward the request to the backend
-    const response = await fetch(`${BACKEND_URL}/projects/${projectName}/settings`, {
+    const response = await fetch(`${BACKEND_URL}/projects/${projectName}/settings${search}`, {
       method: "PUT",
-      headers: {
-        "Content-Type": "application/json",
-        // Forward authentication headers from the client request
-        "X-User-ID": request.headers.get("X-User-ID") || "",
-        "X-User-Groups": request.headers.get("X-User-Groups") || "",
-      },
+      headers,
       body: body,
     });
 
@@ -0,0 +1,38 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+
+// POST /api/projects/[name]/triggers/teams - Teams outgoing webhook messages.
+// Teams signs the body in the Authorization header, so the raw body and that
+// header are passed through unchanged instead of the user's forwarded token.
+export async function POST(
+  request: NextRequest,
+  { params }: { params: Promise<{ name: string }> }
+) {
+  try {
+    const { name } = await params;
+    const body = await request.text();
+    const headers: Record<string, string> = { "Content-Type": "application/json" };
+    const signature = request.headers.get("authorization");
+    if (signature) {
+      headers["Authorization"] = signature;
+    }
+
+    const response = await fetch(`${BACKEND_URL}/projects/${encodeURIComponent(name)}/triggers/teams`, {
+      method: "POST",
+      headers,
+      body,
+    });
+
+    const data = await response.text();
+
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to forward Teams message:", error);
+    return NextResponse.json({ error: "Failed to forward Teams message" }, { status: 500 });
+  }
+}
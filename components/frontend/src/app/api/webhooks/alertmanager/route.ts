@@ -0,0 +1,34 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+
+// POST /api/webhooks/alertmanager - Alertmanager webhook notifications.
+// The bearer token is a project webhook API key, not a user token, so it is
+// passed through unchanged.
+export async function POST(request: NextRequest) {
+  try {
+    const body = await request.text();
+    const headers: Record<string, string> = { "Content-Type": "application/json" };
+    const authorization = request.headers.get("authorization");
+    if (authorization) {
+      headers["Authorization"] = authorization;
+    }
+
+    const response = await fetch(`${BACKEND_URL}/webhooks/alertmanager`, {
+      method: "POST",
+      headers,
+      body,
+    });
+
+    const data = await response.text();
+
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to forward Alertmanager notification:", error);
+    return NextResponse.json({ error: "Failed to forward Alertmanager notification" }, { status: 500 });
+  }
+}
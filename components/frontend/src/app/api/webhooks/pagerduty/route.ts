@@ -0,0 +1,33 @@
+import { NextRequest, NextResponse } from "next/server";
+import { BACKEND_URL } from "@/lib/config";
+
+// POST /api/webhooks/pagerduty - PagerDuty V3 webhooks. The backend verifies
+// X-PagerDuty-Signature over the raw body, so both are passed through unchanged.
+export async function POST(request: NextRequest) {
+  try {
+    const body = await request.text();
+    const headers: Record<string, string> = { "Content-Type": "application/json" };
+    const signature = request.headers.get("x-pagerduty-signature");
+    if (signature) {
+      headers["X-PagerDuty-Signature"] = signature;
+    }
+
+    const response = await fetch(`${BACKEND_URL}/webhooks/pagerduty`, {
+      method: "POST",
+      headers,
+      body,
+    });
+
+    const data = await response.text();
+
+    return new NextResponse(data, {
+      status: response.status,
+      headers: {
+        "Content-Type": "application/json",
+      },
+    });
+  } catch (error) {
+    console.error("Failed to forward PagerDuty webhook:", error);
+    return NextResponse.json({ error: "Failed to forward PagerDuty webhook" }, { status: 500 });
+  }
+}
@@ -1,6 +1,6 @@
 "use client";
 
-import { useState, useEffect, useCallback, useMemo } from "react";
+import { useState, useEffect, useCallback, useMemo, useRef } from "react";
 import Link from "next/link";
 import { formatDistanceToNow, format } from "date-fns";
 import {
@@ -208,21 +208,33 @@ export default function ProjectSessionDetailPage({ params }: { params: Promise<{
     } catch {}
   }, [chatInput, projectName, sessionName, fetchSession])
 
+  const phaseRef = useRef(session?.status?.phase);
+  phaseRef.current = session?.status?.phase;
+
   useEffect(() => {
-    if (projectName && sessionName) {
-      fetchSession();
-      // Poll for updates every 5 seconds if the session is still running
-      const interval = setInterval(() => {
-        if (
-          session?.status?.phase === "Pending" ||
-          session?.status?.phase === "Running"
-        ) {
-          fetchSession();
-        }
-      }, 5000);
-      return () => clearInterval(interval);
-    }
-  }, [projectName, sessionName, session?.status?.phase, fetchSession]);
+    if (!projectName || !sessionName) return;
+    fetchSession();
+    // Refetch when the backend pushes a status change; EventSource reconnects on its own
+    const events = new EventSource(
+      `${getApiUrl()}/projects/${encodeURIComponent(projectName)}/agentic-sessions/${encodeURIComponent(sessionName)}/events`
+    );
+    let live = false;
+    events.onopen = () => { live = true; };
+    events.onerror = () => { live = false; };
+    events.addEventListener("phase", () => { fetchSession(); });
+    events.addEventListener("condition", () => { fetchSession(); });
+    events.addEventListener("deleted", () => { events.close(); fetchSession(); });
+    // Fall back to polling every 5 seconds while the stream is down and the session is active
+    const interval = setInterval(() => {
+      if (!live && (phaseRef.current === "Pending" || phaseRef.current === "Running")) {
+        fetchSession();
+      }
+    }, 5000);
+    return () => {
+      events.close();
+      clearInterval(interval);
+    };
+  }, [projectName, sessionName, fetchSession]);
 
 
   const workspaceBasePath = session?.spec?.paths?.workspace || `/agentic-sessions/${encodeURIComponent(sessionName)}/workspace`
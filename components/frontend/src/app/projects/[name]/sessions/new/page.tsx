@@ -84,7 +84,7 @@ export default function NewProjectSessionPage({ params }: { params: Promise<{ na
       model: "claude-3-7-sonnet-latest",
       temperature: 0.7,
       maxTokens: 4000,
-      timeout: 300,
+      timeout: 1800,
       interactive: false,
       gitUserName: "",
       gitUserEmail: "",
@@ -23,7 +23,7 @@ export default function ProjectSessionsListPage({ params }: { params: Promise<{
     try {
       setLoading(true);
       const apiUrl = getApiUrl();
-      const res = await fetch(`${apiUrl}/projects/${encodeURIComponent(projectName)}/agentic-sessions`);
+      const res = await fetch(`${apiUrl}/projects/${encodeURIComponent(projectName)}/agentic-sessions?sort=-createdAt`);
       if (!res.ok) throw new Error("Failed to fetch sessions");
       const data = await res.json();
       setSessions(Array.isArray(data?.items) ? data.items : []);
@@ -147,13 +147,7 @@ export default function ProjectSessionsListPage({ params }: { params: Promise<{
                   </TableRow>
                 </TableHeader>
                 <TableBody>
-                  {[...sessions]
-                    .sort((a, b) => {
-                      const aTime = a?.metadata?.creationTimestamp ? new Date(a.metadata.creationTimestamp).getTime() : 0;
-                      const bTime = b?.metadata?.creationTimestamp ? new Date(b.metadata.creationTimestamp).getTime() : 0;
-                      return bTime - aTime;
-                    })
-                    .map((session) => (
+                  {sessions.map((session) => (
                     <TableRow key={session.metadata?.uid || session.metadata?.name}>
                       <TableCell className="font-medium min-w-[180px]">
                         <Link href={`/projects/${projectName}/sessions/${session.metadata.name}`} className="text-blue-600 hover:underline hover:text-blue-800 transition-colors block">
@@ -162,6 +156,9 @@ export default function ProjectSessionsListPage({ params }: { params: Promise<{
                             {session.spec.displayName && (
                               <div className="text-xs text-gray-500 font-normal">{session.metadata.name}</div>
                             )}
+                            {session.status?.report?.preview && (
+                              <div className="text-xs text-gray-600 font-normal line-clamp-2 max-w-[420px]">{session.status.report.preview}</div>
+                            )}
                           </div>
                         </Link>
                       </TableCell>
@@ -1,9 +1,13 @@
 export type AgenticSessionPhase = "Pending" | "Creating" | "Running" | "Completed" | "Failed" | "Stopped" | "Error";
 
+export type ModelProviderType = "anthropic" | "openai" | "bedrock" | "vertex" | "azure-openai";
+
 export type LLMSettings = {
 	model: string;
 	temperature: number;
 	maxTokens: number;
+	// Entry of the project's modelProviders; defaults to the project default
+	provider?: string;
 };
 
 export type GitUser = {
@@ -28,6 +32,52 @@ export type GitConfig = {
 	repositories?: GitRepository[];
 };
 
+export type SessionTrigger = {
+	source: string;
+	event?: string;
+	repo?: string;
+	prNumber?: number;
+	headSha?: string;
+	ref?: string;
+	issueKey?: string;
+	// Chat conversation or thread, e.g. a Teams conversation ID
+	channel?: string;
+	// Alertmanager group key or PagerDuty incident ID
+	incident?: string;
+	fingerprint?: string;
+	deliveryId?: string;
+};
+
+// Orders queued sessions; a queued session rises one level per 30 minutes waited
+export type SessionPriority = "low" | "normal" | "high";
+
+// Recreate the session after a runner failure, waiting backoffSeconds (default
+// 30) before the first retry and doubling it for each next one
+// Narrows the runner's tools; allowed must stay within the project's allowedTools
+export type SessionTools = {
+	allowed?: string[];
+	blocked?: string[];
+};
+
+// Refused when maxCostUSD (a decimal string) exceeds what remains of the monthly budget
+export type SessionBudget = {
+	maxCostUSD: string;
+};
+
+export type SessionRetryPolicy = {
+	maxRetries: number;
+	backoffSeconds?: number;
+};
+
+// Artifacts of an earlier session copied into the workspace before the runner starts
+export type SessionInput = {
+	sessionRef: string;
+	// Globs relative to the source artifacts directory; empty selects all
+	artifactSelector?: string[];
+	// Destination inside the workspace (default inputs/<sessionRef>)
+	path?: string;
+};
+
 export type AgenticSessionSpec = {
 	prompt: string;
 	llmSettings: LLMSettings;
@@ -36,6 +86,26 @@ export type AgenticSessionSpec = {
 	gitConfig?: GitConfig;
 	project?: string;
 	interactive?: boolean;
+	summaryReport?: boolean;
+	trigger?: SessionTrigger;
+	// Name of a registered Framework (default claude-code)
+	framework?: string;
+	frameworkVersion?: string;
+	resourceOverrides?: ResourceOverrides;
+	scheduling?: SessionScheduling;
+	scratch?: { size: string };
+	inputs?: SessionInput[];
+	// Delete this long after finishing; default the project's retention.sessions
+	ttlSecondsAfterFinished?: number;
+	retryPolicy?: SessionRetryPolicy;
+	priority?: SessionPriority;
+	tools?: SessionTools;
+	budget?: SessionBudget;
+	// Planned but never run; the plan is in status.plan
+	dryRun?: boolean;
+	// Set on retries: the original session and the attempt number (from 1)
+	retryOf?: string;
+	retryAttempt?: number;
 	paths?: {
 		workspace?: string;
 	}
@@ -84,6 +154,9 @@ export type UserMessage = {
 	type: "user_message";
 	content: ContentBlock | string;
 	timestamp: string;
+	// Set on follow-up messages posted to a running session
+	id?: string;
+	sender?: string;
 }
 export type AssistantMessage = {
 	type: "assistant_message";
@@ -111,6 +184,15 @@ export type ResultMessage = {
 	timestamp: string;
 }
 
+// GET .../messages?offset=&limit=&role=&tool=&contentType=
+export type SessionMessagesPage = {
+	items: (Message & { index: number })[];
+	total: number;
+	offset: number;
+	limit: number;
+	nextOffset?: number;
+};
+
 // Backwards-compatible message type consumed by frontend components.
 // Prefer using StreamMessage going forward.
 export type MessageObject = Message;
@@ -121,6 +203,8 @@ export type AgenticSessionStatus = {
 	startTime?: string;
 	completionTime?: string;
 	jobName?: string;
+	// Set instead of jobName when the session runs as a Tekton PipelineRun
+	pipelineRunName?: string;
   	// Storage & counts (align with CRD)
   	stateDir?: string;
 	// Runner result summary fields
@@ -131,6 +215,116 @@ export type AgenticSessionStatus = {
 	total_cost_usd?: number | null;
 	usage?: Record<string, any> | null;
 	result?: string | null;
+	// Executive summary report (when spec.summaryReport is enabled)
+	report?: {
+		path: string;
+		preview?: string;
+		generatedAt?: string;
+	};
+	// Present while waiting for capacity under concurrency limits
+	queue?: {
+		reason?: string;
+		framework?: string;
+		priority?: SessionPriority;
+		queuedAt?: string;
+	};
+	// Runner image the operator started the session with
+	runner?: {
+		image?: string;
+		track?: "stable" | "canary";
+	};
+	// Per-session scratch PVC mounted at /scratch
+	scratch?: {
+		claimName: string;
+		size?: string;
+	};
+	// Outcome of reporting the result to each outbound integration (e.g. github)
+	integrations?: Record<string, {
+		state: "Posted" | "Failed";
+		url?: string;
+		attemptedAt?: string;
+		message?: string;
+	}>;
+	// Automatic retry after a runner failure: scheduled, then created
+	retry?: {
+		nextAttemptTime?: string;
+		session?: string;
+	};
+	// What a dry-run session would have run
+	plan?: SessionPlan;
+};
+
+export type SessionPlan = {
+	// False when a policy rule failed and the session would be rejected
+	allowed: boolean;
+	workload: "Job" | "PipelineRun";
+	workloadName: string;
+	image?: string;
+	track?: "stable" | "canary";
+	resources?: { requests?: Record<string, string>; limits?: Record<string, string> };
+	activeDeadlineSeconds?: number;
+	serviceAccountName?: string;
+	priorityClassName?: string;
+	nodeSelector?: Record<string, string>;
+	initContainers?: string[];
+	// Runner environment variable names only
+	env?: string[];
+	// Mean of the project's recent finished sessions, preferring the same model
+	estimatedCost: {
+		costUSD?: number;
+		inputTokens?: number;
+		outputTokens?: number;
+		samples: number;
+		basis?: string;
+	};
+	policy: { rule: string; passed: boolean; message?: string }[];
+	generatedAt?: string;
+};
+
+// Payloads of GET .../agentic-sessions/:sessionName/events (Server-Sent Events),
+// keyed by event name
+export type SessionStatusCondition = {
+	type: string;
+	status: "True" | "False" | "Unknown";
+	reason?: string;
+	message?: string;
+	lastTransitionTime?: string;
+};
+
+export type SessionEventPayloads = {
+	snapshot: { phase: AgenticSessionPhase | ""; message?: string; conditions: SessionStatusCondition[] };
+	phase: { phase: AgenticSessionPhase; previousPhase: AgenticSessionPhase | ""; message?: string; time: string };
+	condition: SessionStatusCondition;
+	event: { type: "Normal" | "Warning"; reason: string; message: string; count?: number; time: string };
+	deleted: { name: string };
+	error: { error: string };
+};
+
+// One line of GET .../agentic-sessions?watch=true. Resume a closed watch with
+// ?resourceVersion= the last one received; 410 means list again.
+export type SessionWatchEvent =
+	| { type: "ADDED" | "MODIFIED" | "DELETED"; object: AgenticSession; resourceVersion: string }
+	| { type: "BOOKMARK"; resourceVersion: string }
+	| { type: "ERROR"; error: string };
+
+// GET .../agentic-sessions/:sessionName/timeline, oldest entry first
+export type SessionTimelineEntry = {
+	time: string;
+	source: "lifecycle" | "progress" | "condition" | "event";
+	// Kind/name of the Job, PipelineRun or runner pod an event is about
+	object?: string;
+	// Event type (Normal, Warning) or condition type
+	type?: string;
+	status?: SessionStatusCondition["status"];
+	phase?: string;
+	reason?: string;
+	message?: string;
+	count?: number;
+};
+
+export type SessionTimeline = {
+	entries: SessionTimelineEntry[];
+	warnings?: string[];
 };
 
 export type AgenticSession = {
@@ -158,8 +352,102 @@ export type CreateAgenticSessionRequest = {
 	workspacePath?: string;
 	labels?: Record<string, string>;
 	annotations?: Record<string, string>;
+	summaryReport?: boolean;
+	trigger?: SessionTrigger;
+	// Name of a registered Framework (default claude-code)
+	framework?: string;
+	frameworkVersion?: string;
+	resourceOverrides?: ResourceOverrides;
+	scheduling?: SessionScheduling;
+	scratch?: { size: string };
+	inputs?: SessionInput[];
+	// Delete this long after finishing; default the project's retention.sessions
+	ttlSecondsAfterFinished?: number;
+	retryPolicy?: SessionRetryPolicy;
+	priority?: SessionPriority;
+	tools?: SessionTools;
+	budget?: SessionBudget;
+	// Evaluate policy, estimate cost and plan the runner without running it
+	dryRun?: boolean;
+};
+
+// One node of a SessionPipeline; template is the step session's spec
+export type SessionPipelineStep = {
+	name: string;
+	dependsOn?: string[];
+	// Artifacts of an upstream step (which must be in dependsOn) placed in the workspace
+	inputs?: { step: string; artifactSelector?: string[]; path?: string }[];
+	template: Partial<AgenticSessionSpec> & { prompt: string };
 };
 
+export type SessionPipelinePhase = "Pending" | "Running" | "Succeeded" | "Failed";
+
+export type SessionPipelineStepStatus = {
+	name: string;
+	phase: "Waiting" | "Running" | "Succeeded" | "Failed" | "Skipped";
+	session?: string;
+	message?: string;
+};
+
+export type SessionPipeline = {
+	name: string;
+	creationTimestamp?: string;
+	steps: SessionPipelineStep[];
+	status?: {
+		phase?: SessionPipelinePhase;
+		message?: string;
+		startTime?: string;
+		completionTime?: string;
+		steps?: SessionPipelineStepStatus[];
+	};
+};
+
+export type CreateSessionPipelineRequest = {
+	name: string;
+	steps: SessionPipelineStep[];
+};
+
+// Runner container resources; each quantity sets both request and limit
+export type ResourceOverrides = {
+	cpu?: string;
+	memory?: string;
+	ephemeralStorage?: string;
+	// GPU count of the project's GPU resource (default nvidia.com/gpu)
+	gpu?: string;
+	storageClass?: string;
+	priorityClass?: string;
+};
+
+// Runner pod placement in Kubernetes form
+export type SessionScheduling = {
+	nodeSelector?: Record<string, string>;
+	tolerations?: {
+		key?: string;
+		operator?: "Exists" | "Equal";
+		value?: string;
+		effect?: "NoSchedule" | "PreferNoSchedule" | "NoExecute";
+		tolerationSeconds?: number;
+	}[];
+	nodeAffinity?: Record<string, unknown>;
+};
+
+// Registered runner from GET /api/frameworks
+export type Framework = {
+	name: string;
+	displayName?: string;
+	description?: string;
+	versions?: string[];
+	deprecatedVersions?: string[];
+	defaultVersion?: string;
+	requests?: Record<string, string>;
+	limits?: Record<string, string>;
+	providers?: ModelProviderType[];
+	workload?: RunnerWorkload;
+};
+
+// How the operator runs a session: a Kubernetes Job or a Tekton PipelineRun
+export type RunnerWorkload = "Job" | "PipelineRun";
+
 // New types for RFE workflows
 export type WorkflowPhase = "pre" | "ideate" | "specify" | "plan" | "tasks" | "review" | "completed";
 
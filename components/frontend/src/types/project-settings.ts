@@ -1,3 +1,5 @@
+import type { ModelProviderType, RunnerWorkload, SessionPriority, SessionScheduling } from "./agentic-session";
+
 export type LLMSettings = {
   model: string;
   temperature: number;
@@ -38,4 +40,189 @@ export type ProjectSettingsUpdateRequest = {
   adminUsers: string[];
   defaultSettings: ProjectDefaultSettings;
   resourceLimits: ProjectResourceLimits;
-};
\ No newline at end of file
+};
+export type NotificationEvent =
+  | "session.created"
+  | "session.completed"
+  | "session.failed"
+  | "budget.warning"
+  | "budget.exceeded"
+  | "report.usage"
+  | `${string}.*`;
+
+// Policy document edited through GET/PUT /api/projects/[name]/settings
+export type ProjectPolicySpec = {
+  groupAccess: { groupName: string; role: "admin" | "edit" | "view" }[];
+  runnerSecretsName?: string;
+  // Overrides the framework's workload; default Job
+  runnerWorkload?: RunnerWorkload;
+  // Keyed by framework name; digest takes precedence over tag
+  runnerImages?: Record<string, { repository?: string; tag?: string; digest?: string }>;
+  imagePullSecrets?: string[];
+  gitBootstrap?: {
+    enabled?: boolean;
+    // Secret with a token key or an ssh-privatekey deploy key
+    credentialsSecret?: string;
+    depth?: number;
+    baseURLs?: { github?: string; gitlab?: string };
+  };
+  runnerScheduling?: SessionScheduling & {
+    allowSessionScheduling?: boolean;
+    // Job PriorityClass for each session priority
+    priorityClasses?: Partial<Record<SessionPriority, string>>;
+  };
+  modelProviders?: {
+    default?: string;
+    providers?: Record<
+      string,
+      {
+        type?: ModelProviderType;
+        secretName?: string;
+        region?: string;
+        projectID?: string;
+        baseURL?: string;
+        endpoint?: string;
+        apiVersion?: string;
+        deployment?: string;
+        allowedModels?: string[];
+      }
+    >;
+  };
+  // Egress for runner pods; allowedDomains go through the egress proxy
+  network?: {
+    egress?: "unrestricted" | "restricted" | "none";
+    allowedCIDRs?: string[];
+    allowedDomains?: string[];
+  };
+  sessionPolicy?: Record<string, unknown>;
+  runnerDisruption?: Record<string, unknown>;
+  runnerCanary?: Record<string, unknown>;
+  providerKeys?: Record<string, unknown>;
+  retention?: { sessions?: string; artifacts?: string; auditLogs?: string; scratch?: string; dryRun?: boolean };
+  storageQuota?: { maxTotalBytes?: number; maxArtifactsPerSession?: number };
+  // Monthly spend limit, capped by the cluster policy's maxMonthlyCostUSD
+  budget?: {
+    monthlyLimitUSD?: number;
+    warnPercent?: number;
+    // Day (1-28) and IANA time zone at which the budget month starts
+    resetDay?: number;
+    timezone?: string;
+    // Warn when a day's spend is this many standard deviations above normal
+    anomalyZScore?: number;
+  };
+  integrations?: {
+    github?: { enabled?: boolean; mode?: "comment" | "check-run"; credentialsSecret?: string; apiURL?: string };
+    jira?: {
+      enabled?: boolean;
+      comment?: boolean;
+      transitions?: { onPhase: "Completed" | "Failed" | "Stopped" | "Error"; to: string }[];
+    };
+  };
+  // Inbound endpoints that create sessions; the Teams security token is in
+  // the ambient-teams-trigger Secret
+  triggers?: {
+    teams?: {
+      enabled?: boolean;
+      // Access key ServiceAccount that sessions are created as
+      serviceAccount?: string;
+      // Session request fields applied to every triggered session
+      session?: Record<string, unknown>;
+    };
+    // Firing alert groups whose common labels include matchLabels, sent with a
+    // project webhook API key
+    alertmanager?: {
+      enabled?: boolean;
+      serviceAccount?: string;
+      matchLabels?: Record<string, string>;
+      // Placed before the alert details in the prompt
+      instructions?: string;
+      session?: Record<string, unknown>;
+    };
+    // Triggered incidents on these services; the signing secret is in the
+    // ambient-pagerduty-trigger Secret
+    pagerduty?: {
+      enabled?: boolean;
+      serviceAccount?: string;
+      serviceIds?: string[];
+      instructions?: string;
+      session?: Record<string, unknown>;
+    };
+  };
+  notifications?: {
+    webhooks?: {
+      name?: string;
+      url: string;
+      type?: "slack" | "teams" | "http";
+      events?: NotificationEvent[];
+      secretRef?: { name: string; key: string };
+    }[];
+    email?: {
+      recipients: string[];
+      events?: NotificationEvent[];
+      mode?: "immediate" | "digest";
+      digestInterval?: string;
+      // Go text/template overrides keyed by event name
+      templates?: Partial<Record<NotificationEvent, { subject?: string; body?: string }>>;
+    };
+    // Send last month's usage report as report.usage
+    usageReport?: boolean;
+  };
+};
+
+// GET /api/cluster-policy; projects may only tighten these bounds
+export type ClusterPolicy = {
+  blockedModels?: string[];
+  blockedTools?: string[];
+  maxMonthlyCostUSD?: number;
+  // Keyed by minSessions, minArtifacts and minAuditLogs
+  retentionFloors?: Record<string, string>;
+};
+
+// GET and PUT /api/model-pricing; a session's model is priced by the first
+// entry whose name or glob matches it
+export type ModelPrice = {
+  model: string;
+  inputUSDPerMillion: number;
+  outputUSDPerMillion: number;
+};
+
+export type ModelPricing = {
+  models: ModelPrice[];
+  // Send back on PUT to reject the update if the pricing changed in between
+  resourceVersion?: string;
+};
+
+// POST /api/projects/:name/policy/simulate with a CreateAgenticSessionRequest body
+export type PolicySimulation = {
+  allowed: boolean;
+  rules: {
+    rule: "timeout" | "resources" | "scheduling" | "scratch" | "priority" | "model" | "framework" | "tools" | "budget" | "ttl" | "inputs";
+    passed: boolean;
+    message?: string;
+  }[];
+  warnings?: string[];
+};
+
+export type ProjectPolicyDocument = {
+  spec: ProjectPolicySpec;
+  status?: Record<string, unknown>;
+  resourceVersion: string;
+};
+
+export type ProjectPolicyUpdateRequest = {
+  spec: ProjectPolicySpec;
+  resourceVersion?: string;
+};
+
+// Field paths are relative to spec, e.g. "sessionPolicy.maxTimeoutSeconds"
+export type PolicyFieldError = {
+  field: string;
+  message: string;
+};
+
+export type ProjectPolicyErrorResponse = {
+  error: string;
+  code?: string;
+  fieldErrors?: PolicyFieldError[];
+  conflicts?: PolicyFieldError[];
+};
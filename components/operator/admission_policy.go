@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The simple session constraints of a namespace's sessionPolicy (allowed and
+// blocked frameworks, the name pattern and required labels) are compiled into
+// a ValidatingAdmissionPolicy and binding for that namespace, so the API
+// server enforces them on every AgenticSession create without a webhook round
+// trip, whoever creates the session. Policies are regenerated when
+// ProjectSettings change and deleted with them or once the constraints are
+// removed. Clusters without admissionregistration.k8s.io/v1 policies, or with
+// NAMESPACE_ADMISSION_POLICIES=false, skip this.
+
+const (
+	namespacePolicyLabel          = "ambient-code.io/namespace-policy"
+	namespacePolicyHashAnnotation = "ambient-code.io/constraints-hash"
+)
+
+var (
+	namespaceAdmissionPoliciesOnce sync.Once
+	namespaceAdmissionPoliciesOK   bool
+)
+
+// namespaceAdmissionPoliciesEnabled reports whether the API server serves
+// ValidatingAdmissionPolicies and they are not turned off
+func namespaceAdmissionPoliciesEnabled() bool {
+	namespaceAdmissionPoliciesOnce.Do(func() {
+		if os.Getenv("NAMESPACE_ADMISSION_POLICIES") == "false" {
+			return
+		}
+		resources, err := k8sClient.Discovery().ServerResourcesForGroupVersion(admissionv1.SchemeGroupVersion.String())
+		if err != nil {
+			log.Printf("Namespace admission policies disabled: %v", err)
+			return
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == "validatingadmissionpolicies" {
+				namespaceAdmissionPoliciesOK = true
+				return
+			}
+		}
+		log.Printf("Namespace admission policies disabled: the API server does not serve ValidatingAdmissionPolicies")
+	})
+	return namespaceAdmissionPoliciesOK
+}
+
+func namespaceAdmissionPolicyName(ns string) string {
+	return "agenticsession-" + ns + ".namespace.vteam.ambient-code"
+}
+
+// sessionAdmissionConstraints are the sessionPolicy fields compiled into the
+// namespace's admission policy
+type sessionAdmissionConstraints struct {
+	AllowedFrameworks []string `json:"allowedFrameworks,omitempty"`
+	BlockedFrameworks []string `json:"blockedFrameworks,omitempty"`
+	NamePattern       string   `json:"namePattern,omitempty"`
+	RequiredLabels    []string `json:"requiredLabels,omitempty"`
+}
+
+func sessionAdmissionConstraintsFromSpec(psSpec map[string]interface{}) sessionAdmissionConstraints {
+	var c sessionAdmissionConstraints
+	c.AllowedFrameworks, _, _ = unstructured.NestedStringSlice(psSpec, "sessionPolicy", "allowedFrameworks")
+	c.BlockedFrameworks, _, _ = unstructured.NestedStringSlice(psSpec, "sessionPolicy", "blockedFrameworks")
+	c.NamePattern, _, _ = unstructured.NestedString(psSpec, "sessionPolicy", "namePattern")
+	c.RequiredLabels, _, _ = unstructured.NestedStringSlice(psSpec, "sessionPolicy", "requiredLabels")
+	c.NamePattern = strings.TrimSpace(c.NamePattern)
+	return c
+}
+
+func (c sessionAdmissionConstraints) empty() bool {
+	return len(c.AllowedFrameworks) == 0 && len(c.BlockedFrameworks) == 0 && c.NamePattern == "" && len(c.RequiredLabels) == 0
+}
+
+// celStringList writes items as a CEL list of string literals
+func celStringList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, s := range items {
+		quoted = append(quoted, strconv.Quote(s))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// validations compiles the constraints to CEL. The name pattern is anchored,
+// since CEL's matches() finds a match anywhere in the string.
+func (c sessionAdmissionConstraints) validations() ([]admissionv1.Validation, error) {
+	var out []admissionv1.Validation
+	if len(c.AllowedFrameworks) > 0 {
+		out = append(out, admissionv1.Validation{
+			Expression: "variables.framework in " + celStringList(c.AllowedFrameworks),
+			Message:    "spec.framework must be one of " + strings.Join(c.AllowedFrameworks, ", ") + " in this namespace",
+		})
+	}
+	if len(c.BlockedFrameworks) > 0 {
+		out = append(out, admissionv1.Validation{
+			Expression:        "!(variables.framework in " + celStringList(c.BlockedFrameworks) + ")",
+			MessageExpression: "'framework ' + variables.framework + ' is blocked in this namespace'",
+		})
+	}
+	if c.NamePattern != "" {
+		anchored := "^(?:" + c.NamePattern + ")$"
+		if _, err := regexp.Compile(anchored); err != nil {
+			return nil, fmt.Errorf("sessionPolicy.namePattern: %v", err)
+		}
+		out = append(out, admissionv1.Validation{
+			Expression: "object.metadata.name.matches(" + strconv.Quote(anchored) + ")",
+			Message:    "session names must match " + c.NamePattern + " in this namespace",
+		})
+	}
+	if len(c.RequiredLabels) > 0 {
+		out = append(out, admissionv1.Validation{
+			Expression: celStringList(c.RequiredLabels) + ".all(k, has(object.metadata.labels) && k in object.metadata.labels)",
+			Message:    "sessions in this namespace must carry the labels " + strings.Join(c.RequiredLabels, ", "),
+		})
+	}
+	return out, nil
+}
+
+// syncNamespaceAdmissionPolicy creates, updates or deletes the namespace's
+// policy and binding to match the constraints
+func syncNamespaceAdmissionPolicy(ns string, c sessionAdmissionConstraints) error {
+	if !namespaceAdmissionPoliciesEnabled() {
+		return nil
+	}
+	if c.empty() {
+		return deleteNamespaceAdmissionPolicy(ns)
+	}
+	validations, err := c.validations()
+	if err != nil {
+		return err
+	}
+	raw, _ := json.Marshal(c)
+	sum := sha256.Sum256(raw)
+	hash := hex.EncodeToString(sum[:8])
+
+	name := namespaceAdmissionPolicyName(ns)
+	meta := v1.ObjectMeta{
+		Name:        name,
+		Labels:      map[string]string{namespacePolicyLabel: ns},
+		Annotations: map[string]string{namespacePolicyHashAnnotation: hash},
+	}
+	inNamespace := &v1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": ns}}
+	fail := admissionv1.Fail
+	policy := &admissionv1.ValidatingAdmissionPolicy{
+		ObjectMeta: meta,
+		Spec: admissionv1.ValidatingAdmissionPolicySpec{
+			FailurePolicy: &fail,
+			MatchConstraints: &admissionv1.MatchResources{
+				NamespaceSelector: inNamespace,
+				ResourceRules: []admissionv1.NamedRuleWithOperations{{
+					RuleWithOperations: admissionv1.RuleWithOperations{
+						Operations: []admissionv1.OperationType{admissionv1.Create},
+						Rule: admissionv1.Rule{
+							APIGroups:   []string{"vteam.ambient-code"},
+							APIVersions: []string{"*"},
+							Resources:   []string{"agenticsessions"},
+						},
+					},
+				}},
+			},
+			Variables: []admissionv1.Variable{{
+				Name:       "framework",
+				Expression: fmt.Sprintf("has(object.spec.framework) && object.spec.framework != '' ? object.spec.framework : %s", strconv.Quote(defaultSessionFramework)),
+			}},
+			Validations: validations,
+		},
+	}
+	binding := &admissionv1.ValidatingAdmissionPolicyBinding{
+		ObjectMeta: meta,
+		Spec: admissionv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName:        name,
+			MatchResources:    &admissionv1.MatchResources{NamespaceSelector: inNamespace},
+			ValidationActions: []admissionv1.ValidationAction{admissionv1.Deny},
+		},
+	}
+
+	policies := k8sClient.AdmissionregistrationV1().ValidatingAdmissionPolicies()
+	if existing, err := policies.Get(context.TODO(), name, v1.GetOptions{}); errors.IsNotFound(err) {
+		if _, err := policies.Create(context.TODO(), policy, v1.CreateOptions{}); err != nil {
+			return err
+		}
+		log.Printf("Created admission policy %s for namespace %s", name, ns)
+	} else if err != nil {
+		return err
+	} else if existing.Annotations[namespacePolicyHashAnnotation] != hash {
+		existing.Labels, existing.Annotations, existing.Spec = policy.Labels, policy.Annotations, policy.Spec
+		if _, err := policies.Update(context.TODO(), existing, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+		log.Printf("Updated admission policy %s for namespace %s", name, ns)
+	}
+
+	bindings := k8sClient.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings()
+	if _, err := bindings.Create(context.TODO(), binding, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteNamespaceAdmissionPolicy removes the namespace's policy and binding
+func deleteNamespaceAdmissionPolicy(ns string) error {
+	if !namespaceAdmissionPoliciesEnabled() {
+		return nil
+	}
+	name := namespaceAdmissionPolicyName(ns)
+	api := k8sClient.AdmissionregistrationV1()
+	if err := api.ValidatingAdmissionPolicyBindings().Delete(context.TODO(), name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err := api.ValidatingAdmissionPolicies().Delete(context.TODO(), name, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// pruneNamespaceAdmissionPolicies deletes the policies of namespaces whose
+// ProjectSettings were removed while the operator was not watching
+func pruneNamespaceAdmissionPolicies() {
+	if !namespaceAdmissionPoliciesEnabled() {
+		return
+	}
+	list, err := k8sClient.AdmissionregistrationV1().ValidatingAdmissionPolicies().List(context.TODO(), v1.ListOptions{LabelSelector: namespacePolicyLabel})
+	if err != nil {
+		log.Printf("Failed to list namespace admission policies: %v", err)
+		return
+	}
+	settings, err := dynamicClient.Resource(getProjectSettingsResource()).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list ProjectSettings for admission policy cleanup: %v", err)
+		return
+	}
+	var namespaces []string
+	for _, ps := range settings.Items {
+		namespaces = append(namespaces, ps.GetNamespace())
+	}
+	for _, p := range list.Items {
+		ns := p.Labels[namespacePolicyLabel]
+		if slices.Contains(namespaces, ns) {
+			continue
+		}
+		if err := deleteNamespaceAdmissionPolicy(ns); err != nil {
+			log.Printf("Failed to delete admission policy of namespace %s: %v", ns, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// artifactHoldTag exempts an artifact from retention until the tag is removed
+const artifactHoldTag = "hold"
+
+// artifactRecord is the part of a backend artifact index entry retention needs.
+// The raw entry is kept so the index is rewritten without losing fields.
+type artifactRecord struct {
+	Name       string   `json:"name"`
+	Size       int64    `json:"size"`
+	UploadedAt string   `json:"uploadedAt"`
+	ExpiresAt  string   `json:"expiresAt"`
+	Tags       []string `json:"tags"`
+	raw        json.RawMessage
+}
+
+// artifactSweep is the outcome of retention for one session's artifacts
+type artifactSweep struct {
+	Deleted        int64
+	BytesReclaimed int64
+	Held           int64
+}
+
+// expiry returns when an artifact falls due: its own expiresAt, kept at least
+// floor after upload, or else retention after the session finished. It
+// reports false for held artifacts and ones kept indefinitely.
+func (a artifactRecord) expiry(finishedAt time.Time, retention, floor time.Duration) (time.Time, bool) {
+	if slices.Contains(a.Tags, artifactHoldTag) {
+		return time.Time{}, false
+	}
+	if a.ExpiresAt != "" {
+		if t, err := time.Parse(time.RFC3339, a.ExpiresAt); err == nil {
+			if uploaded, err := time.Parse(time.RFC3339, a.UploadedAt); err == nil && t.Before(uploaded.Add(floor)) {
+				t = uploaded.Add(floor)
+			}
+			return t, true
+		}
+	}
+	if retention <= 0 {
+		return time.Time{}, false
+	}
+	return finishedAt.Add(retention), true
+}
+
+// sweepSessionArtifacts deletes the session's expired artifacts. When the
+// session-wide retention has passed and nothing is held the whole artifacts
+// directory goes, including files that were never indexed; otherwise expired
+// artifacts are deleted one by one and the index is rewritten without them.
+// Retention only visits finished sessions, which no longer upload, so the
+// index is rewritten without coordinating with the backend.
+func sweepSessionArtifacts(ns, session string, finishedAt time.Time, policy retentionPolicy, floor time.Duration, now time.Time) (artifactSweep, error) {
+	var sweep artifactSweep
+	index, err := loadArtifactIndex(ns, session)
+	if err != nil {
+		return sweep, err
+	}
+	var keep, expired []artifactRecord
+	for _, a := range index {
+		if slices.Contains(a.Tags, artifactHoldTag) {
+			sweep.Held++
+		}
+		if due, ok := a.expiry(finishedAt, policy.Artifacts, floor); ok && now.After(due) {
+			expired = append(expired, a)
+		} else {
+			keep = append(keep, a)
+		}
+	}
+	wholeDir := policy.Artifacts > 0 && now.Sub(finishedAt) > policy.Artifacts && sweep.Held == 0
+	if len(expired) == 0 && !wholeDir {
+		return sweep, nil
+	}
+	if wholeDir {
+		// Entries kept only by their own later expiresAt go with the directory
+		expired, keep = index, nil
+	}
+
+	if policy.DryRun {
+		for _, a := range expired {
+			log.Printf("Retention (dry-run): would delete artifact %s of %s/%s", a.Name, ns, session)
+			sweep.Deleted++
+			sweep.BytesReclaimed += a.Size
+		}
+		return sweep, nil
+	}
+
+	if wholeDir {
+		if err := deleteSessionContent(ns, fmt.Sprintf("/sessions/%s/workspace/artifacts", session)); err != nil {
+			return sweep, err
+		}
+		for _, a := range expired {
+			sweep.Deleted++
+			sweep.BytesReclaimed += a.Size
+		}
+	} else {
+		for _, a := range expired {
+			if err := deleteSessionContent(ns, fmt.Sprintf("/sessions/%s/workspace/artifacts/%s", session, a.Name)); err != nil {
+				log.Printf("Retention: failed to delete artifact %s of %s/%s: %v", a.Name, ns, session, err)
+				keep = append(keep, a)
+				continue
+			}
+			sweep.Deleted++
+			sweep.BytesReclaimed += a.Size
+		}
+	}
+	if sweep.Deleted > 0 {
+		log.Printf("Retention: deleted %d artifacts (%d bytes) of %s/%s", sweep.Deleted, sweep.BytesReclaimed, ns, session)
+	}
+	return sweep, saveArtifactIndex(ns, session, keep)
+}
+
+// sessionHeldArtifacts counts the session's artifacts tagged hold
+func sessionHeldArtifacts(ns, session string) (int64, error) {
+	index, err := loadArtifactIndex(ns, session)
+	if err != nil {
+		return 0, err
+	}
+	var held int64
+	for _, a := range index {
+		if slices.Contains(a.Tags, artifactHoldTag) {
+			held++
+		}
+	}
+	return held, nil
+}
+
+func artifactIndexContentPath(session string) string {
+	return fmt.Sprintf("/sessions/%s/artifacts-index.json", session)
+}
+
+// loadArtifactIndex reads the session's artifact index from the namespace
+// content service; a session without one has no indexed artifacts
+func loadArtifactIndex(ns, session string) ([]artifactRecord, error) {
+	u := fmt.Sprintf("http://ambient-content.%s.svc:8080/content/file?path=%s", ns, url.QueryEscape(artifactIndexContentPath(session)))
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content read failed: status %d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("corrupt artifact index: %v", err)
+	}
+	index := make([]artifactRecord, 0, len(raw))
+	for _, r := range raw {
+		var a artifactRecord
+		if err := json.Unmarshal(r, &a); err != nil {
+			return nil, fmt.Errorf("corrupt artifact index: %v", err)
+		}
+		a.raw = r
+		index = append(index, a)
+	}
+	return index, nil
+}
+
+func saveArtifactIndex(ns, session string, index []artifactRecord) error {
+	raw := make([]json.RawMessage, 0, len(index))
+	for _, a := range index {
+		raw = append(raw, a.raw)
+	}
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(map[string]string{"path": artifactIndexContentPath(session), "content": string(content)})
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(fmt.Sprintf("http://ambient-content.%s.svc:8080/content/write", ns), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("content write failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
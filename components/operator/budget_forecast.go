@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ambient-code-shared/money"
+)
+
+const (
+	defaultAnomalyZScore = 3.0
+	// Daily spend is compared with up to this many previous days, and only
+	// once a namespace has that many days of history
+	anomalyLookbackDays = 28
+	anomalyMinDays      = 7
+)
+
+// spendForecast is the budget month's projected spend and today's deviation
+// from the previous days; the backend's GET .../budget/forecast reports the same
+type spendForecast struct {
+	ProjectedUSD money.USD
+	// HasHistory is false until anomalyMinDays of history exist
+	HasHistory bool
+	Today      string
+	TodayUSD   money.USD
+	MeanUSD    money.USD
+	ZScore     float64
+}
+
+// forecastSpend projects the period's spend at its daily run rate so far and
+// scores today's spend against the previous days in the budget's time zone
+func forecastSpend(sessions []unstructured.Unstructured, start, end, now time.Time) spendForecast {
+	loc := start.Location()
+	day := func(t time.Time) string { return t.In(loc).Format("2006-01-02") }
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	lookback := today.AddDate(0, 0, -anomalyLookbackDays)
+
+	var f spendForecast
+	var spent money.USD
+	byDay := map[string]money.USD{}
+	var first time.Time
+	for i := range sessions {
+		started := sessionStartTime(&sessions[i])
+		if started.After(now) {
+			continue
+		}
+		cost := sessionCostUSD(&sessions[i])
+		if !started.Before(start) && started.Before(end) {
+			spent += cost
+		}
+		if !started.Before(lookback) {
+			byDay[day(started)] += cost
+		}
+		if first.IsZero() || started.Before(first) {
+			first = started
+		}
+	}
+	// Run rate over at least a day, so the first hours do not extrapolate wildly
+	elapsed := int64(max(now.Sub(start), 24*time.Hour) / time.Second)
+	f.ProjectedUSD = spent + spent.MulDiv(int64(max(end.Sub(now), 0)/time.Second), elapsed)
+
+	// Days before the namespace's first session are not history
+	var history []float64
+	for d := today.AddDate(0, 0, -1); !d.Before(lookback); d = d.AddDate(0, 0, -1) {
+		if first.IsZero() || d.AddDate(0, 0, 1).Before(first) {
+			break
+		}
+		history = append(history, byDay[day(d)].Float64())
+	}
+	if len(history) < anomalyMinDays {
+		return f
+	}
+	var mean, variance float64
+	for _, v := range history {
+		mean += v
+	}
+	mean /= float64(len(history))
+	for _, v := range history {
+		variance += (v - mean) * (v - mean)
+	}
+	f.HasHistory = true
+	f.Today = day(today)
+	f.TodayUSD = byDay[f.Today]
+	f.MeanUSD = money.FromFloat(mean)
+	// Flat history has no spread to measure against
+	if stddev := math.Sqrt(variance / float64(len(history))); stddev > 0 {
+		f.ZScore = (f.TodayUSD.Float64() - mean) / stddev
+	}
+	return f
+}
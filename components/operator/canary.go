@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	runnerTrackLabel             = "ambient-code.io/runner-track"
+	runnerTrackStable            = "stable"
+	runnerTrackCanary            = "canary"
+	canaryStateActive            = "Active"
+	canaryStateRolledBack        = "RolledBack"
+	eventReasonCanaryRolledBack  = "CanaryRolledBack"
+	defaultCanaryEvalSeconds     = 60
+	defaultCanaryMinSamples      = 5
+	defaultCanaryWindow          = 50
+	defaultCanaryMaxFailureDelta = 10
+	defaultCanaryMaxCostIncrease = 25
+)
+
+// runnerCanaryPolicy mirrors ProjectSettings spec.runnerCanary. Image defaults to
+// CANARY_RUNNER_IMAGE, the next runner version rolled out cluster-wide.
+type runnerCanaryPolicy struct {
+	Image   string
+	Percent int64
+	// MinSamples finished canary sessions are needed before rollback is considered
+	MinSamples int64
+	// Window is the number of most recent finished sessions per track compared
+	Window int64
+	// MaxFailureRateDelta is the allowed canary failure rate above stable, in percentage points
+	MaxFailureRateDelta int64
+	// MaxCostIncreasePercent is the allowed increase of mean cost per session over stable
+	MaxCostIncreasePercent int64
+}
+
+// canaryTrackStats summarizes recent finished sessions on one runner track
+type canaryTrackStats struct {
+	Sessions int64
+	Failed   int64
+	costSum  float64
+	costN    int64
+}
+
+func (s canaryTrackStats) failureRate() float64 {
+	if s.Sessions == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Sessions)
+}
+
+func (s canaryTrackStats) meanCost() float64 {
+	if s.costN == 0 {
+		return 0
+	}
+	return s.costSum / float64(s.costN)
+}
+
+func (s canaryTrackStats) toStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"sessions":           s.Sessions,
+		"failed":             s.Failed,
+		"failureRatePercent": int64(s.failureRate()*100 + 0.5),
+		"meanCostUSD":        fmt.Sprintf("%.4f", s.meanCost()),
+	}
+}
+
+func runnerCanaryPolicyFromSpec(psSpec map[string]interface{}) *runnerCanaryPolicy {
+	m, found, _ := unstructured.NestedMap(psSpec, "runnerCanary")
+	if !found {
+		return nil
+	}
+	p := &runnerCanaryPolicy{
+		MinSamples:             defaultCanaryMinSamples,
+		Window:                 defaultCanaryWindow,
+		MaxFailureRateDelta:    defaultCanaryMaxFailureDelta,
+		MaxCostIncreasePercent: defaultCanaryMaxCostIncrease,
+	}
+	p.Image, _, _ = unstructured.NestedString(m, "image")
+	if p.Image == "" {
+		p.Image = os.Getenv("CANARY_RUNNER_IMAGE")
+	}
+	p.Percent, _, _ = unstructured.NestedInt64(m, "percent")
+	if v, ok, _ := unstructured.NestedInt64(m, "minSamples"); ok && v > 0 {
+		p.MinSamples = v
+	}
+	if v, ok, _ := unstructured.NestedInt64(m, "window"); ok && v > 0 {
+		p.Window = v
+	}
+	if v, ok, _ := unstructured.NestedInt64(m, "maxFailureRateDelta"); ok && v >= 0 {
+		p.MaxFailureRateDelta = v
+	}
+	if v, ok, _ := unstructured.NestedInt64(m, "maxCostIncreasePercent"); ok && v >= 0 {
+		p.MaxCostIncreasePercent = v
+	}
+	if p.Image == "" || p.Image == ambientCodeRunnerImage || p.Percent <= 0 {
+		return nil
+	}
+	if p.Percent > 100 {
+		p.Percent = 100
+	}
+	return p
+}
+
+// canaryRolledBack reports whether the namespace already rolled back this canary
+// image. A new image starts a fresh canary.
+func canaryRolledBack(psStatus map[string]interface{}, image string) bool {
+	state, _, _ := unstructured.NestedString(psStatus, "runnerCanary", "state")
+	rolledBackImage, _, _ := unstructured.NestedString(psStatus, "runnerCanary", "image")
+	return state == canaryStateRolledBack && rolledBackImage == image
+}
+
+// selectRunnerImage picks the runner image for a new session. A stable hash of the
+// session UID decides the canary bucket so retries keep the same track. Only
+// sessions on the default runner image take part; other frameworks and pinned
+// versions always run their registered image.
+func selectRunnerImage(session *unstructured.Unstructured, psObj *unstructured.Unstructured, fw runnerFramework) (string, string) {
+	if fw.Image != ambientCodeRunnerImage {
+		return fw.Image, runnerTrackStable
+	}
+	if psObj == nil {
+		return ambientCodeRunnerImage, runnerTrackStable
+	}
+	psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	policy := runnerCanaryPolicyFromSpec(psSpec)
+	if policy == nil {
+		return ambientCodeRunnerImage, runnerTrackStable
+	}
+	psStatus, _, _ := unstructured.NestedMap(psObj.Object, "status")
+	if canaryRolledBack(psStatus, policy.Image) {
+		return ambientCodeRunnerImage, runnerTrackStable
+	}
+	h := fnv.New32a()
+	h.Write([]byte(session.GetUID()))
+	if int64(h.Sum32()%100) < policy.Percent {
+		return policy.Image, runnerTrackCanary
+	}
+	return ambientCodeRunnerImage, runnerTrackStable
+}
+
+// collectCanaryStats gathers failure and cost figures for the most recent finished
+// sessions on each track that ran the current stable or canary image.
+func collectCanaryStats(ns string, policy *runnerCanaryPolicy) (canaryTrackStats, canaryTrackStats, error) {
+	list, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return canaryTrackStats{}, canaryTrackStats{}, err
+	}
+	type finished struct {
+		at  time.Time
+		obj *unstructured.Unstructured
+	}
+	byTrack := map[string][]finished{}
+	for i := range list.Items {
+		item := &list.Items[i]
+		at, done := sessionFinishedAt(item)
+		if !done {
+			continue
+		}
+		// Stopped sessions were ended by a user and say nothing about the runner
+		if phase, _, _ := unstructured.NestedString(item.Object, "status", "phase"); phase == "Stopped" {
+			continue
+		}
+		track, _, _ := unstructured.NestedString(item.Object, "status", "runner", "track")
+		image, _, _ := unstructured.NestedString(item.Object, "status", "runner", "image")
+		switch {
+		case track == runnerTrackCanary && image == policy.Image:
+		case track == runnerTrackStable && image == ambientCodeRunnerImage:
+		default:
+			continue
+		}
+		byTrack[track] = append(byTrack[track], finished{at: at, obj: item})
+	}
+
+	summarize := func(items []finished) canaryTrackStats {
+		sort.Slice(items, func(i, j int) bool { return items[i].at.After(items[j].at) })
+		if int64(len(items)) > policy.Window {
+			items = items[:policy.Window]
+		}
+		var s canaryTrackStats
+		for _, f := range items {
+			s.Sessions++
+			phase, _, _ := unstructured.NestedString(f.obj.Object, "status", "phase")
+			if phase == "Failed" || phase == "Error" {
+				s.Failed++
+			}
+			if v, ok, _ := unstructured.NestedFieldNoCopy(f.obj.Object, "status", "total_cost_usd"); ok {
+				switch c := v.(type) {
+				case float64:
+					s.costSum += c
+					s.costN++
+				case int64:
+					s.costSum += float64(c)
+					s.costN++
+				}
+			}
+		}
+		return s
+	}
+	return summarize(byTrack[runnerTrackStable]), summarize(byTrack[runnerTrackCanary]), nil
+}
+
+// evaluateCanary compares the canary against stable and returns a rollback reason
+// when the canary regresses beyond the policy thresholds.
+func evaluateCanary(policy *runnerCanaryPolicy, stable, canary canaryTrackStats) string {
+	if canary.Sessions < policy.MinSamples {
+		return ""
+	}
+	delta := (canary.failureRate() - stable.failureRate()) * 100
+	if delta > float64(policy.MaxFailureRateDelta) {
+		return fmt.Sprintf("canary failure rate %.0f%% exceeds stable %.0f%% by more than %d points",
+			canary.failureRate()*100, stable.failureRate()*100, policy.MaxFailureRateDelta)
+	}
+	if stable.meanCost() > 0 && canary.costN > 0 {
+		increase := (canary.meanCost()/stable.meanCost() - 1) * 100
+		if increase > float64(policy.MaxCostIncreasePercent) {
+			return fmt.Sprintf("canary mean cost $%.4f is %.0f%% above stable $%.4f (limit %d%%)",
+				canary.meanCost(), increase, stable.meanCost(), policy.MaxCostIncreasePercent)
+		}
+	}
+	return ""
+}
+
+// reconcileRunnerCanary refreshes status.runnerCanary for a namespace and rolls the
+// canary back when it regresses. Rolled back canaries stay off until the image changes.
+func reconcileRunnerCanary(psObj *unstructured.Unstructured) error {
+	ns := psObj.GetNamespace()
+	psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	psStatus, _, _ := unstructured.NestedMap(psObj.Object, "status")
+	policy := runnerCanaryPolicyFromSpec(psSpec)
+	if policy == nil {
+		if _, found := psStatus["runnerCanary"]; found {
+			return updateProjectSettingsStatus(ns, psObj.GetName(), map[string]interface{}{"runnerCanary": nil})
+		}
+		return nil
+	}
+
+	stable, canary, err := collectCanaryStats(ns, policy)
+	if err != nil {
+		return fmt.Errorf("collect canary stats: %v", err)
+	}
+	status := map[string]interface{}{
+		"image":         policy.Image,
+		"stableImage":   ambientCodeRunnerImage,
+		"percent":       policy.Percent,
+		"state":         canaryStateActive,
+		"stable":        stable.toStatus(),
+		"canary":        canary.toStatus(),
+		"lastEvaluated": time.Now().UTC().Format(time.RFC3339),
+	}
+	if canaryRolledBack(psStatus, policy.Image) {
+		status["state"] = canaryStateRolledBack
+		status["reason"], _, _ = unstructured.NestedString(psStatus, "runnerCanary", "reason")
+		status["rolledBackAt"], _, _ = unstructured.NestedString(psStatus, "runnerCanary", "rolledBackAt")
+	} else if reason := evaluateCanary(policy, stable, canary); reason != "" {
+		status["state"] = canaryStateRolledBack
+		status["reason"] = reason
+		status["rolledBackAt"] = time.Now().UTC().Format(time.RFC3339)
+		log.Printf("Canary: rolling back %s in %s: %s", policy.Image, ns, reason)
+		recordEvent(psObj, corev1.EventTypeWarning, eventReasonCanaryRolledBack, "Rolled back runner canary %s: %s", policy.Image, reason)
+	}
+	return updateProjectSettingsStatus(ns, psObj.GetName(), map[string]interface{}{"runnerCanary": status})
+}
+
+// runCanaryLoop periodically evaluates runner canaries in managed namespaces.
+func runCanaryLoop() {
+	interval := defaultCanaryEvalSeconds * time.Second
+	if v := os.Getenv("CANARY_EVAL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		}
+	}
+	for {
+		time.Sleep(interval)
+		nsList, err := k8sClient.CoreV1().Namespaces().List(context.TODO(), v1.ListOptions{
+			LabelSelector: "ambient-code.io/managed=true",
+		})
+		if err != nil {
+			log.Printf("Canary: failed to list managed namespaces: %v", err)
+			continue
+		}
+		for _, ns := range nsList.Items {
+			psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns.Name).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+			if err != nil {
+				continue
+			}
+			if err := reconcileRunnerCanary(psObj); err != nil {
+				log.Printf("Canary: evaluation failed in %s: %v", ns.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	defaultCapacityEvalSeconds = 60
+	defaultCapacityLookahead   = 30 * time.Minute
+	hoursPerWeek               = 7 * 24
+	// diurnalAlpha weights the newest observation of an hour-of-week bucket
+	diurnalAlpha = 0.3
+
+	placeholderDeploymentName = "ambient-capacity-placeholder"
+	placeholderPriorityClass  = "ambient-capacity-placeholder"
+)
+
+// capacityForecast is the latest capacity picture. It is exported as gauges on
+// /metrics and as JSON on /capacity for external autoscalers.
+type capacityForecast struct {
+	GeneratedAt         time.Time `json:"generatedAt"`
+	Running             int64     `json:"running"`
+	Queued              int64     `json:"queued"`
+	PendingRunnerPods   int64     `json:"pendingRunnerPods"`
+	PendingCPUMillis    int64     `json:"pendingCpuMillicores"`
+	PendingMemoryBytes  int64     `json:"pendingMemoryBytes"`
+	LookaheadSeconds    int64     `json:"lookaheadSeconds"`
+	ExpectedStarts      float64   `json:"expectedStarts"`
+	AvgDurationSeconds  float64   `json:"avgDurationSeconds"`
+	ForecastRunners     int64     `json:"forecastRunners"`
+	RecommendedHeadroom int64     `json:"recommendedHeadroom"`
+}
+
+// diurnalModel keeps an exponentially weighted session-start rate per hour of
+// the week, so the forecast anticipates recurring bursts such as Monday mornings.
+type diurnalModel struct {
+	rate       [hoursPerWeek]float64
+	seen       [hoursPerWeek]bool
+	lastFolded time.Time
+}
+
+var (
+	capacityMu     sync.RWMutex
+	latestCapacity capacityForecast
+	diurnal        diurnalModel
+)
+
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// fold adds every completed hour since the last call. On the first call it
+// replays the past week from the creation times of sessions still present.
+func (m *diurnalModel) fold(created []time.Time, now time.Time) {
+	current := now.UTC().Truncate(time.Hour)
+	if m.lastFolded.IsZero() {
+		m.lastFolded = current.Add(-hoursPerWeek * time.Hour)
+	}
+	for h := m.lastFolded; h.Before(current); h = h.Add(time.Hour) {
+		count := 0
+		for _, t := range created {
+			if !t.Before(h) && t.Before(h.Add(time.Hour)) {
+				count++
+			}
+		}
+		b := hourOfWeek(h)
+		if m.seen[b] {
+			m.rate[b] = diurnalAlpha*float64(count) + (1-diurnalAlpha)*m.rate[b]
+		} else {
+			m.rate[b] = float64(count)
+			m.seen[b] = true
+		}
+	}
+	m.lastFolded = current
+}
+
+// expected returns forecast session starts between now and now+d, and the
+// highest hourly rate within that window.
+func (m *diurnalModel) expected(now time.Time, d time.Duration) (starts, peak float64) {
+	for t := now; t.Before(now.Add(d)); {
+		next := t.Truncate(time.Hour).Add(time.Hour)
+		if end := now.Add(d); next.After(end) {
+			next = end
+		}
+		r := m.rate[hourOfWeek(t)]
+		starts += r * next.Sub(t).Hours()
+		peak = math.Max(peak, r)
+		t = next
+	}
+	return starts, peak
+}
+
+func capacityLookahead() time.Duration {
+	if v := os.Getenv("CAPACITY_LOOKAHEAD"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultCapacityLookahead
+}
+
+// forecastCapacity gathers queue depth, pending runner pods and the diurnal
+// model into a forecast of runners needed at the end of the lookahead window.
+func forecastCapacity(now time.Time) (capacityForecast, error) {
+	f := capacityForecast{GeneratedAt: now.UTC()}
+	list, err := dynamicClient.Resource(getAgenticSessionResource()).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return f, fmt.Errorf("list sessions: %v", err)
+	}
+	created := make([]time.Time, 0, len(list.Items))
+	var durations float64
+	var finished int
+	for i := range list.Items {
+		s := &list.Items[i]
+		created = append(created, s.GetCreationTimestamp().Time)
+		phase, _, _ := unstructured.NestedString(s.Object, "status", "phase")
+		switch phase {
+		case "Running", "Creating":
+			f.Running++
+		case "", "Pending":
+			f.Queued++
+		}
+		if end, done := sessionFinishedAt(s); done && now.Sub(end) < 24*time.Hour {
+			if ts, ok, _ := unstructured.NestedString(s.Object, "status", "startTime"); ok {
+				if start, err := time.Parse(time.RFC3339, ts); err == nil && end.After(start) {
+					durations += end.Sub(start).Seconds()
+					finished++
+				}
+			}
+		}
+	}
+
+	pods, err := k8sClient.CoreV1().Pods("").List(context.TODO(), v1.ListOptions{
+		LabelSelector: "app=ambient-code-runner",
+		FieldSelector: "status.phase=Pending",
+	})
+	if err != nil {
+		return f, fmt.Errorf("list pending runner pods: %v", err)
+	}
+	for _, p := range pods.Items {
+		f.PendingRunnerPods++
+		for _, c := range p.Spec.Containers {
+			f.PendingCPUMillis += c.Resources.Requests.Cpu().MilliValue()
+			f.PendingMemoryBytes += c.Resources.Requests.Memory().Value()
+		}
+	}
+
+	lookahead := capacityLookahead()
+	f.LookaheadSeconds = int64(lookahead.Seconds())
+	diurnal.fold(created, now)
+	starts, peak := diurnal.expected(now, lookahead)
+	f.ExpectedStarts = math.Round(starts*100) / 100
+	if finished > 0 {
+		f.AvgDurationSeconds = math.Round(durations / float64(finished))
+	} else {
+		f.AvgDurationSeconds = float64(defaultSessionTimeoutSeconds)
+	}
+	// Little's law: concurrent new sessions ≈ arrival rate × duration, bounded by
+	// the number of starts expected in the window.
+	concurrentNew := math.Min(starts, peak*f.AvgDurationSeconds/3600)
+	f.ForecastRunners = f.Running + f.Queued + int64(math.Ceil(concurrentNew))
+	if h := f.ForecastRunners - f.Running; h > 0 {
+		f.RecommendedHeadroom = h
+	}
+	return f, nil
+}
+
+// reconcileCapacityPlaceholders scales a Deployment of low-priority pause pods to
+// the recommended headroom. Cluster autoscaler adds nodes for them ahead of
+// demand; runner pods preempt them when the sessions arrive.
+func reconcileCapacityPlaceholders(f capacityForecast) error {
+	replicas := f.RecommendedHeadroom
+	if v := os.Getenv("CAPACITY_PLACEHOLDER_MAX"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 32); err == nil && replicas > n {
+			replicas = n
+		}
+	}
+	cpu := resource.MustParse("500m")
+	if v := os.Getenv("CAPACITY_PLACEHOLDER_CPU"); v != "" {
+		if q, err := resource.ParseQuantity(v); err == nil {
+			cpu = q
+		}
+	}
+	mem := resource.MustParse("1Gi")
+	if v := os.Getenv("CAPACITY_PLACEHOLDER_MEMORY"); v != "" {
+		if q, err := resource.ParseQuantity(v); err == nil {
+			mem = q
+		}
+	}
+
+	labels := map[string]string{"app": placeholderDeploymentName}
+	deployments := k8sClient.AppsV1().Deployments(namespace)
+	existing, err := deployments.Get(context.TODO(), placeholderDeploymentName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		dep := &appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: placeholderDeploymentName, Namespace: namespace, Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: int32Ptr(int32(replicas)),
+				Selector: &v1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: v1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						PriorityClassName:             placeholderPriorityClass,
+						TerminationGracePeriodSeconds: int64Ptr(0),
+						Containers: []corev1.Container{{
+							Name:  "pause",
+							Image: "registry.k8s.io/pause:3.10",
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: mem},
+							},
+						}},
+					},
+				},
+			},
+		}
+		_, err = deployments.Create(context.TODO(), dep, v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Spec.Replicas != nil && int64(*existing.Spec.Replicas) == replicas {
+		return nil
+	}
+	existing.Spec.Replicas = int32Ptr(int32(replicas))
+	_, err = deployments.Update(context.TODO(), existing, v1.UpdateOptions{})
+	return err
+}
+
+// runCapacityLoop refreshes the capacity forecast every CAPACITY_EVAL_INTERVAL and,
+// with CAPACITY_PLACEHOLDERS=true, keeps placeholder pods at the forecast headroom.
+func runCapacityLoop() {
+	interval := defaultCapacityEvalSeconds * time.Second
+	if v := os.Getenv("CAPACITY_EVAL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		}
+	}
+	placeholders := os.Getenv("CAPACITY_PLACEHOLDERS") == "true"
+	for {
+		f, err := forecastCapacity(time.Now())
+		if err != nil {
+			log.Printf("Capacity: forecast failed: %v", err)
+		} else {
+			capacityMu.Lock()
+			latestCapacity = f
+			capacityMu.Unlock()
+			if placeholders {
+				if err := reconcileCapacityPlaceholders(f); err != nil {
+					log.Printf("Capacity: failed to scale placeholder pods: %v", err)
+				}
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+func currentCapacity() capacityForecast {
+	capacityMu.RLock()
+	defer capacityMu.RUnlock()
+	return latestCapacity
+}
+
+// writeCapacityMetrics exports the latest forecast as gauges
+func writeCapacityMetrics(w http.ResponseWriter) {
+	f := currentCapacity()
+	writeGauge(w, "ambient_capacity_running_sessions", "Sessions currently running or starting", float64(f.Running))
+	writeGauge(w, "ambient_capacity_queued_sessions", "Sessions waiting for admission or a runner", float64(f.Queued))
+	writeGauge(w, "ambient_capacity_pending_runner_pods", "Runner pods not yet scheduled", float64(f.PendingRunnerPods))
+	writeGauge(w, "ambient_capacity_pending_cpu_millicores", "CPU requested by pending runner pods", float64(f.PendingCPUMillis))
+	writeGauge(w, "ambient_capacity_pending_memory_bytes", "Memory requested by pending runner pods", float64(f.PendingMemoryBytes))
+	writeGauge(w, "ambient_capacity_expected_session_starts", "Session starts forecast within the lookahead window", f.ExpectedStarts)
+	writeGauge(w, "ambient_capacity_forecast_runners", "Runners forecast to be needed at the end of the lookahead window", float64(f.ForecastRunners))
+	writeGauge(w, "ambient_capacity_recommended_headroom", "Additional runners to provision ahead of demand", float64(f.RecommendedHeadroom))
+}
+
+// serveCapacity returns the latest forecast as a scaling recommendation
+func serveCapacity(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(currentCapacity())
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	// budget.timezone must resolve in images without a zoneinfo database
+	_ "time/tzdata"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"ambient-code-shared/money"
+)
+
+// clusterPolicyName is the ClusterAmbientPolicy the operator and backend evaluate
+const clusterPolicyName = "default"
+
+const (
+	eventReasonBudgetExceeded = "BudgetExceeded"
+	eventReasonBudgetReset    = "BudgetReset"
+	eventReasonSpendAnomaly   = "SpendAnomaly"
+	defaultBudgetWarnPercent  = 80
+	// Closed budget months kept in status.budget.history
+	budgetHistoryMonths      = 12
+	budgetResetCheckInterval = 10 * time.Minute
+)
+
+// getClusterPolicyResource returns the GroupVersionResource for the cluster-scoped ClusterAmbientPolicy
+func getClusterPolicyResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "clusterambientpolicies",
+	}
+}
+
+// clusterPolicy mirrors ClusterAmbientPolicy spec. Namespaces inherit it and may
+// only tighten it: blocklists are unioned, the budget is the lower of the two and
+// retention never drops below the floors.
+type clusterPolicy struct {
+	BlockedModels []string
+	BlockedTools  []string
+	// MaxMonthlyCostUSD caps every namespace's monthly budget (0 means no cap)
+	MaxMonthlyCostUSD money.USD
+	// Retention floors for sessions and artifacts (0 means no floor)
+	MinSessions  time.Duration
+	MinArtifacts time.Duration
+}
+
+// loadClusterPolicy reads the ClusterAmbientPolicy. A missing policy (or CRD)
+// imposes no constraints.
+func loadClusterPolicy() (clusterPolicy, error) {
+	var p clusterPolicy
+	obj, err := dynamicClient.Resource(getClusterPolicyResource()).Get(context.TODO(), clusterPolicyName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return p, nil
+		}
+		return p, fmt.Errorf("failed to read ClusterAmbientPolicy: %v", err)
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	p.BlockedModels, _, _ = unstructured.NestedStringSlice(spec, "blockedModels")
+	p.BlockedTools, _, _ = unstructured.NestedStringSlice(spec, "blockedTools")
+	p.MaxMonthlyCostUSD = amountFromSpec(spec, "budget", "maxMonthlyCostUSD")
+	if v, _, _ := unstructured.NestedString(spec, "retention", "minSessions"); v != "" {
+		if p.MinSessions, err = parseRetentionDuration(v); err != nil {
+			log.Printf("Ignoring ClusterAmbientPolicy retention.minSessions: %v", err)
+		}
+	}
+	if v, _, _ := unstructured.NestedString(spec, "retention", "minArtifacts"); v != "" {
+		if p.MinArtifacts, err = parseRetentionDuration(v); err != nil {
+			log.Printf("Ignoring ClusterAmbientPolicy retention.minArtifacts: %v", err)
+		}
+	}
+	return p, nil
+}
+
+// floatFromSpec reads a number that may be decoded as int64 or float64
+func floatFromSpec(m map[string]interface{}, fields ...string) float64 {
+	raw, found, _ := unstructured.NestedFieldNoCopy(m, fields...)
+	if !found {
+		return 0
+	}
+	switch n := raw.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// amountFromSpec reads a dollar amount stored as a number or decimal string
+func amountFromSpec(m map[string]interface{}, fields ...string) money.USD {
+	raw, _, _ := unstructured.NestedFieldNoCopy(m, fields...)
+	a, _ := money.FromSpec(raw)
+	return a
+}
+
+// mergedBlocklist unions the cluster list with the namespace's sessionPolicy list
+func mergedBlocklist(cluster []string, psSpec map[string]interface{}, field string) []string {
+	out := slices.Clone(cluster)
+	ns, _, _ := unstructured.NestedStringSlice(psSpec, "sessionPolicy", field)
+	for _, item := range ns {
+		if item = strings.TrimSpace(item); item != "" && !slices.Contains(out, item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// blockedModelPattern returns the first blocklist glob matching model
+func blockedModelPattern(model string, blocked []string) (string, bool) {
+	if strings.TrimSpace(model) == "" {
+		return "", false
+	}
+	for _, pattern := range blocked {
+		if ok, _ := path.Match(pattern, model); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// applyFloors raises configured retention periods to the cluster
+// floors. Unset periods keep everything and already satisfy any floor.
+func (p retentionPolicy) applyFloors(cp clusterPolicy) retentionPolicy {
+	if p.Sessions > 0 && p.Sessions < cp.MinSessions {
+		p.Sessions = cp.MinSessions
+	}
+	if p.Artifacts > 0 && p.Artifacts < cp.MinArtifacts {
+		p.Artifacts = cp.MinArtifacts
+	}
+	return p
+}
+
+// namespaceBudget is the effective monthly budget of a namespace
+type namespaceBudget struct {
+	LimitUSD    money.USD
+	WarnPercent int64
+	// ResetDay (1-28) and Location start each budget month; default the 1st, UTC
+	ResetDay int
+	Location *time.Location
+	// AnomalyZScore is how far above the usual daily spend a day may go before
+	// budget.warning is sent
+	AnomalyZScore float64
+}
+
+// namespaceBudgetFromSpec merges ProjectSettings spec.budget with the cluster
+// maximum; the lower limit wins.
+func namespaceBudgetFromSpec(psSpec map[string]interface{}, cp clusterPolicy) namespaceBudget {
+	b := namespaceBudget{LimitUSD: amountFromSpec(psSpec, "budget", "monthlyLimitUSD"), WarnPercent: defaultBudgetWarnPercent, ResetDay: 1, Location: time.UTC, AnomalyZScore: defaultAnomalyZScore}
+	if cp.MaxMonthlyCostUSD > 0 && (b.LimitUSD <= 0 || b.LimitUSD > cp.MaxMonthlyCostUSD) {
+		b.LimitUSD = cp.MaxMonthlyCostUSD
+	}
+	if v, found, _ := unstructured.NestedInt64(psSpec, "budget", "warnPercent"); found && v > 0 && v <= 100 {
+		b.WarnPercent = v
+	}
+	if v, found, _ := unstructured.NestedInt64(psSpec, "budget", "resetDay"); found && v >= 1 && v <= 28 {
+		b.ResetDay = int(v)
+	}
+	if z := floatFromSpec(psSpec, "budget", "anomalyZScore"); z > 0 {
+		b.AnomalyZScore = z
+	}
+	if tz, _, _ := unstructured.NestedString(psSpec, "budget", "timezone"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			b.Location = loc
+		}
+	}
+	return b
+}
+
+// period returns the budget month containing now: from midnight of ResetDay in
+// Location until the same time a month later
+func (b namespaceBudget) period(now time.Time) (time.Time, time.Time) {
+	local := now.In(b.Location)
+	start := time.Date(local.Year(), local.Month(), b.ResetDay, 0, 0, 0, 0, b.Location)
+	if local.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start, start.AddDate(0, 1, 0)
+}
+
+// monthlyUsage is what a namespace's sessions started in a budget month consumed
+type monthlyUsage struct {
+	CostUSD      money.USD
+	InputTokens  int64
+	OutputTokens int64
+	APICalls     int64
+}
+
+// sessionStartTime is when a session counts toward spend: its
+// status.startTime, or else its creation
+func sessionStartTime(s *unstructured.Unstructured) time.Time {
+	if v, _, _ := unstructured.NestedString(s.Object, "status", "startTime"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return s.GetCreationTimestamp().Time
+}
+
+// sessionCostUSD is a session's token usage priced at its model's ModelPricing
+// entry, or else its status.total_cost_usd, or the usage.cost_usd its runner
+// reported when it failed before producing a result
+func sessionCostUSD(s *unstructured.Unstructured) money.USD {
+	if cost, ok := pricedSessionCost(s); ok {
+		return cost
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(s.Object, "status", "total_cost_usd"); found {
+		return amountFromSpec(s.Object, "status", "total_cost_usd")
+	}
+	return amountFromSpec(s.Object, "status", "usage", "cost_usd")
+}
+
+// sumSessionUsage sums the cost and status.usage of the sessions started in
+// [start, end)
+func sumSessionUsage(sessions []unstructured.Unstructured, start, end time.Time) monthlyUsage {
+	var total monthlyUsage
+	for i := range sessions {
+		s := &sessions[i]
+		if started := sessionStartTime(s); started.Before(start) || !started.Before(end) {
+			continue
+		}
+		total.CostUSD += sessionCostUSD(s)
+		total.InputTokens += int64(floatFromSpec(s.Object, "status", "usage", "input_tokens"))
+		total.OutputTokens += int64(floatFromSpec(s.Object, "status", "usage", "output_tokens"))
+		total.APICalls += int64(floatFromSpec(s.Object, "status", "usage", "api_calls"))
+	}
+	return total
+}
+
+// periodSessionUsage sums the usage of the namespace's sessions started in
+// [start, end)
+func periodSessionUsage(ns string, start, end time.Time) (monthlyUsage, error) {
+	sessions, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return monthlyUsage{}, fmt.Errorf("list sessions: %v", err)
+	}
+	return sumSessionUsage(sessions.Items, start, end), nil
+}
+
+// budgetPeriodStatus renders one budget month for status.budget and its history
+func budgetPeriodStatus(start, end time.Time, limitUSD string, usage monthlyUsage) map[string]interface{} {
+	return map[string]interface{}{
+		"month":       start.Format("2006-01"),
+		"periodStart": start.UTC().Format(time.RFC3339),
+		"periodEnd":   end.UTC().Format(time.RFC3339),
+		"limitUSD":    limitUSD,
+		"spentUSD":    usage.CostUSD.Cents(),
+		"usage": map[string]interface{}{
+			"inputTokens":  usage.InputTokens,
+			"outputTokens": usage.OutputTokens,
+			"apiCalls":     usage.APICalls,
+		},
+	}
+}
+
+// sameBudgetPeriod reports whether a recorded status.budget belongs to the
+// period starting at start; statuses written before periods were recorded
+// match by calendar month
+func sameBudgetPeriod(prev map[string]interface{}, start time.Time) bool {
+	if v, ok := prev["periodStart"].(string); ok && v != "" {
+		return v == start.UTC().Format(time.RFC3339)
+	}
+	return prev["month"] == start.Format("2006-01")
+}
+
+// closeBudgetPeriod archives the totals of the budget month before start at
+// the head of status.budget.history, keeping budgetHistoryMonths entries
+func closeBudgetPeriod(ns string, psObj *unstructured.Unstructured, prev map[string]interface{}, start time.Time) ([]interface{}, error) {
+	history, _, _ := unstructured.NestedSlice(prev, "history")
+	prevStart := start.AddDate(0, -1, 0)
+	if len(history) > 0 {
+		if last, ok := history[0].(map[string]interface{}); ok && last["periodStart"] == prevStart.UTC().Format(time.RFC3339) {
+			return history, nil
+		}
+	}
+	usage, err := periodSessionUsage(ns, prevStart, start)
+	if err != nil {
+		return history, err
+	}
+	limit, _ := prev["limitUSD"].(string)
+	entry := budgetPeriodStatus(prevStart, start, limit, usage)
+	if v, ok := prev["exceededAt"].(string); ok && prev["month"] == entry["month"] {
+		entry["exceededAt"] = v
+	}
+	recordEvent(psObj, corev1.EventTypeNormal, eventReasonBudgetReset, "Budget month %s closed at $%s spent; usage reset for the month starting %s", entry["month"], entry["spentUSD"], start.Format("2006-01-02 MST"))
+	history = append([]interface{}{entry}, history...)
+	if len(history) > budgetHistoryMonths {
+		history = history[:budgetHistoryMonths]
+	}
+	return history, nil
+}
+
+// refreshNamespaceBudget recomputes the budget month's spend and usage into
+// ProjectSettings status.budget and sends budget.warning and budget.exceeded
+// once per month. When a new month has begun, the previous one is archived in
+// status.budget.history first. It reports whether the budget is exhausted.
+func refreshNamespaceBudget(ns string, psObj *unstructured.Unstructured, cp clusterPolicy) (bool, error) {
+	if psObj == nil {
+		return false, nil
+	}
+	psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	budget := namespaceBudgetFromSpec(psSpec, cp)
+	if budget.LimitUSD <= 0 {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	start, end := budget.period(now)
+	sessions, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("list sessions: %v", err)
+	}
+	usage := sumSessionUsage(sessions.Items, start, end)
+	spent := usage.CostUSD
+
+	month := start.Format("2006-01")
+	prev, _, _ := unstructured.NestedMap(psObj.Object, "status", "budget")
+	history, _, _ := unstructured.NestedSlice(prev, "history")
+	if !sameBudgetPeriod(prev, start) {
+		if prev["month"] != nil {
+			if history, err = closeBudgetPeriod(ns, psObj, prev, start); err != nil {
+				return false, err
+			}
+		}
+		prev = map[string]interface{}{}
+	}
+	status := budgetPeriodStatus(start, end, budget.LimitUSD.Cents(), usage)
+	if len(history) > 0 {
+		status["history"] = history
+	}
+	for _, key := range []string{"warnedAt", "exceededAt", "forecastWarnedAt", "anomalyDate"} {
+		if v, ok := prev[key].(string); ok {
+			status[key] = v
+		}
+	}
+	forecast := forecastSpend(sessions.Items, start, end, now)
+	status["projectedUSD"] = forecast.ProjectedUSD.Cents()
+	details := map[string]interface{}{
+		"month":    month,
+		"spentUSD": status["spentUSD"],
+		"limitUSD": status["limitUSD"],
+	}
+	exceeded := spent >= budget.LimitUSD
+	switch {
+	case exceeded && status["exceededAt"] == nil:
+		status["exceededAt"] = now.Format(time.RFC3339)
+		recordEvent(psObj, corev1.EventTypeWarning, eventReasonBudgetExceeded, "Monthly budget of $%s exceeded ($%s spent)", budget.LimitUSD.Cents(), spent.Cents())
+		notifyProject(ns, notifyBudgetExceeded, details)
+	case !exceeded && status["warnedAt"] == nil && spent >= budget.LimitUSD.Percent(budget.WarnPercent):
+		status["warnedAt"] = now.Format(time.RFC3339)
+		details["reason"] = "threshold"
+		details["warnPercent"] = budget.WarnPercent
+		notifyProject(ns, notifyBudgetWarning, details)
+	case !exceeded && status["forecastWarnedAt"] == nil && forecast.ProjectedUSD > budget.LimitUSD:
+		// Once per month, like the threshold warning
+		status["forecastWarnedAt"] = now.Format(time.RFC3339)
+		details["reason"] = "forecast"
+		details["projectedUSD"] = status["projectedUSD"]
+		notifyProject(ns, notifyBudgetWarning, details)
+	}
+	if forecast.HasHistory && forecast.ZScore > budget.AnomalyZScore && status["anomalyDate"] != forecast.Today {
+		// At most once per day
+		status["anomalyDate"] = forecast.Today
+		recordEvent(psObj, corev1.EventTypeWarning, eventReasonSpendAnomaly, "Spend of $%s on %s is %.1f standard deviations above the daily mean of $%s", forecast.TodayUSD.Cents(), forecast.Today, forecast.ZScore, forecast.MeanUSD.Cents())
+		notifyProject(ns, notifyBudgetWarning, map[string]interface{}{
+			"month":        month,
+			"reason":       "anomaly",
+			"date":         forecast.Today,
+			"dailyUSD":     forecast.TodayUSD.Cents(),
+			"meanDailyUSD": forecast.MeanUSD.Cents(),
+			"zScore":       strconv.FormatFloat(forecast.ZScore, 'f', 1, 64),
+		})
+	}
+	if err := updateProjectSettingsStatus(ns, psObj.GetName(), map[string]interface{}{"budget": status}); err != nil {
+		log.Printf("Failed to record budget status in %s: %v", ns, err)
+	}
+	return exceeded, nil
+}
+
+// refreshFinishedSessionBudget updates the namespace budget once a session that
+// counts toward this budget month's spend has finished
+func refreshFinishedSessionBudget(session *unstructured.Unstructured) {
+	finishedAt, ok := sessionFinishedAt(session)
+	if !ok {
+		return
+	}
+	ns := session.GetNamespace()
+	psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		return
+	}
+	cp, err := loadClusterPolicy()
+	if err != nil {
+		log.Printf("Budget: %v", err)
+		return
+	}
+	psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	if start, _ := namespaceBudgetFromSpec(psSpec, cp).period(time.Now()); finishedAt.Before(start) {
+		return
+	}
+	if _, err := refreshNamespaceBudget(ns, psObj, cp); err != nil {
+		log.Printf("Budget: failed to refresh %s: %v", ns, err)
+	}
+}
+
+// runBudgetResetLoop starts each namespace's budget month on its resetDay even
+// when no session runs: it archives the month that ended and zeroes the spend
+// the backend admits new sessions against
+func runBudgetResetLoop() {
+	for {
+		time.Sleep(budgetResetCheckInterval)
+		cp, err := loadClusterPolicy()
+		if err != nil {
+			log.Printf("Budget: %v", err)
+			continue
+		}
+		nsList, err := k8sClient.CoreV1().Namespaces().List(context.TODO(), v1.ListOptions{
+			LabelSelector: "ambient-code.io/managed=true",
+		})
+		if err != nil {
+			log.Printf("Budget: failed to list managed namespaces: %v", err)
+			continue
+		}
+		now := time.Now()
+		for _, ns := range nsList.Items {
+			psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns.Name).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+			if err != nil {
+				continue
+			}
+			psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+			budget := namespaceBudgetFromSpec(psSpec, cp)
+			prev, _, _ := unstructured.NestedMap(psObj.Object, "status", "budget")
+			if start, _ := budget.period(now); budget.LimitUSD <= 0 || sameBudgetPeriod(prev, start) {
+				continue
+			}
+			if _, err := refreshNamespaceBudget(ns.Name, psObj, cp); err != nil {
+				log.Printf("Budget: failed to reset %s: %v", ns.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	conditionAdmitted          = "Admitted"
+	eventReasonQueued          = "Queued"
+	defaultSessionFramework    = "claude-code"
+	sessionBurstWindow         = time.Minute
+	defaultQueueRecheckSeconds = 10
+)
+
+// concurrencyPolicy mirrors ProjectSettings spec.sessionPolicy concurrency fields.
+// Zero means unlimited.
+type concurrencyPolicy struct {
+	MaxConcurrent int64
+	Frameworks    map[string]frameworkLimit
+}
+
+type frameworkLimit struct {
+	MaxConcurrent int64
+	// Burst caps session starts per framework within sessionBurstWindow
+	Burst int64
+}
+
+// admissionMu serializes the admission check and Job creation so the watch and
+// the queue loop cannot both admit into the last free slot.
+var admissionMu sync.Mutex
+
+// recentStarts records admission times per namespace/framework for burst limiting
+var (
+	recentStartsMu sync.Mutex
+	recentStarts   = map[string][]time.Time{}
+)
+
+// sessionFramework returns spec.framework, defaulting to the Claude Code runner
+func sessionFramework(spec map[string]interface{}) string {
+	if f, ok := spec["framework"].(string); ok && f != "" {
+		return f
+	}
+	return defaultSessionFramework
+}
+
+func concurrencyPolicyFromSpec(psSpec map[string]interface{}) concurrencyPolicy {
+	p := concurrencyPolicy{Frameworks: map[string]frameworkLimit{}}
+	p.MaxConcurrent, _, _ = unstructured.NestedInt64(psSpec, "sessionPolicy", "maxConcurrentSessions")
+	limits, _, _ := unstructured.NestedMap(psSpec, "sessionPolicy", "frameworkLimits")
+	for name, raw := range limits {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		l := frameworkLimit{}
+		l.MaxConcurrent, _, _ = unstructured.NestedInt64(m, "maxConcurrent")
+		l.Burst, _, _ = unstructured.NestedInt64(m, "burst")
+		p.Frameworks[name] = l
+	}
+	return p
+}
+
+// sessionLoad summarizes a namespace's sessions for admission
+type sessionLoad struct {
+	// Total and ByFramework count sessions holding a slot (Creating or Running)
+	Total       int64
+	ByFramework map[string]int64
+	// QueuedAhead counts queued sessions of the same framework served before self
+	QueuedAhead int64
+}
+
+func namespaceSessionLoad(self *unstructured.Unstructured, framework string) (sessionLoad, error) {
+	load := sessionLoad{ByFramework: map[string]int64{}}
+	list, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(self.GetNamespace()).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return load, err
+	}
+	now := time.Now()
+	for _, item := range list.Items {
+		if item.GetName() == self.GetName() {
+			continue
+		}
+		spec, _, _ := unstructured.NestedMap(item.Object, "spec")
+		fw := sessionFramework(spec)
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		switch phase {
+		case "Creating", "Running":
+			load.Total++
+			load.ByFramework[fw]++
+		case "Pending":
+			_, queued, _ := unstructured.NestedMap(item.Object, "status", "queue")
+			if queued && fw == framework && queuedAhead(&item, self, now) {
+				load.QueuedAhead++
+			}
+		}
+	}
+	return load, nil
+}
+
+// burstCount prunes and returns the starts recorded within the burst window
+func burstCount(key string, now time.Time) int64 {
+	recentStartsMu.Lock()
+	defer recentStartsMu.Unlock()
+	kept := recentStarts[key][:0]
+	for _, t := range recentStarts[key] {
+		if now.Sub(t) < sessionBurstWindow {
+			kept = append(kept, t)
+		}
+	}
+	recentStarts[key] = kept
+	return int64(len(kept))
+}
+
+func recordSessionStart(key string, now time.Time) {
+	recentStartsMu.Lock()
+	defer recentStartsMu.Unlock()
+	recentStarts[key] = append(recentStarts[key], now)
+}
+
+// admitSession decides whether a Pending session may start now. When it may not,
+// the session stays Pending with an Admitted=False condition and status.queue, and
+// the queue loop retries it. Must be called with admissionMu held.
+func admitSession(session *unstructured.Unstructured) (bool, error) {
+	ns, name := session.GetNamespace(), session.GetName()
+	spec, _, _ := unstructured.NestedMap(session.Object, "spec")
+	framework := sessionFramework(spec)
+
+	var psSpec map[string]interface{}
+	if psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
+		psSpec, _, _ = unstructured.NestedMap(psObj.Object, "spec")
+	}
+	policy := concurrencyPolicyFromSpec(psSpec)
+	limit := policy.Frameworks[framework]
+
+	reason, message := "", ""
+	if policy.MaxConcurrent > 0 || limit.MaxConcurrent > 0 || limit.Burst > 0 {
+		load, err := namespaceSessionLoad(session, framework)
+		if err != nil {
+			return false, fmt.Errorf("count active sessions: %v", err)
+		}
+		switch {
+		case load.QueuedAhead > 0:
+			// By priority, then first come, first served within a framework
+			reason = "QueuedBehindOthers"
+			message = fmt.Sprintf("%d %s sessions are queued ahead", load.QueuedAhead, framework)
+		case policy.MaxConcurrent > 0 && load.Total >= policy.MaxConcurrent:
+			reason = "NamespaceConcurrencyLimit"
+			message = fmt.Sprintf("%d of %d concurrent sessions running in namespace", load.Total, policy.MaxConcurrent)
+		case limit.MaxConcurrent > 0 && load.ByFramework[framework] >= limit.MaxConcurrent:
+			reason = "FrameworkConcurrencyLimit"
+			message = fmt.Sprintf("%d of %d concurrent %s sessions running", load.ByFramework[framework], limit.MaxConcurrent, framework)
+		}
+	}
+	now := time.Now()
+	burstKey := ns + "/" + framework
+	if reason == "" && limit.Burst > 0 {
+		if n := burstCount(burstKey, now); n >= limit.Burst {
+			reason = "FrameworkBurstLimit"
+			message = fmt.Sprintf("%d %s sessions started in the last %s (burst %d)", n, framework, sessionBurstWindow, limit.Burst)
+		}
+	}
+
+	if reason != "" {
+		queuedAt, _, _ := unstructured.NestedString(session.Object, "status", "queue", "queuedAt")
+		if queuedAt == "" {
+			queuedAt = now.UTC().Format(time.RFC3339)
+			recordEvent(session, corev1.EventTypeNormal, eventReasonQueued, "Queued: %s", message)
+		}
+		return false, updateAgenticSessionStatus(ns, name, map[string]interface{}{
+			"phase":   "Pending",
+			"message": "Queued: " + message,
+			"queue": map[string]interface{}{
+				"reason":    reason,
+				"framework": framework,
+				"priority":  sessionPriority(spec),
+				"queuedAt":  queuedAt,
+			},
+		}, v1.Condition{
+			Type:    conditionAdmitted,
+			Status:  v1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		})
+	}
+
+	recordSessionStart(burstKey, now)
+	return true, updateAgenticSessionStatus(ns, name, map[string]interface{}{
+		"queue": nil,
+	}, v1.Condition{
+		Type:    conditionAdmitted,
+		Status:  v1.ConditionTrue,
+		Reason:  "Admitted",
+		Message: fmt.Sprintf("Admitted as %s session", framework),
+	})
+}
+
+// runQueueLoop periodically retries queued sessions, highest priority and then
+// oldest first, so they start as soon as capacity frees up.
+func runQueueLoop() {
+	interval := defaultQueueRecheckSeconds * time.Second
+	if v := os.Getenv("QUEUE_RECHECK_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		}
+	}
+	for {
+		time.Sleep(interval)
+		list, err := dynamicClient.Resource(getAgenticSessionResource()).List(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Queue: failed to list sessions: %v", err)
+			continue
+		}
+		queued := make([]unstructured.Unstructured, 0)
+		for _, item := range list.Items {
+			phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+			if _, found, _ := unstructured.NestedMap(item.Object, "status", "queue"); phase == "Pending" && found {
+				queued = append(queued, item)
+			}
+		}
+		now := time.Now()
+		sort.Slice(queued, func(i, j int) bool {
+			return queuedAhead(&queued[i], &queued[j], now)
+		})
+		for i := range queued {
+			if err := handleAgenticSessionEvent(&queued[i]); err != nil {
+				log.Printf("Queue: failed to process %s/%s: %v", queued[i].GetNamespace(), queued[i].GetName(), err)
+			}
+		}
+	}
+}
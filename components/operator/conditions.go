@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported on AgenticSession status
+const (
+	conditionJobCreated = "JobCreated"
+	conditionSucceeded  = "Succeeded"
+	// conditionStalled is True while the runner has stopped sending heartbeats
+	conditionStalled = "Stalled"
+)
+
+// Condition types reported on ProjectSettings status
+const (
+	conditionReconciled = "Reconciled"
+)
+
+// applyStatusConditions upserts conditions into status["conditions"] keyed by
+// type, stamping each with the object's generation. lastTransitionTime only
+// moves when a condition's status actually changes.
+func applyStatusConditions(status map[string]interface{}, generation int64, conditions ...v1.Condition) {
+	if len(conditions) == 0 {
+		return
+	}
+
+	var existing []v1.Condition
+	if raw, ok := status["conditions"]; ok && raw != nil {
+		if b, err := json.Marshal(raw); err == nil {
+			// Entries that do not parse as metav1.Condition are dropped
+			_ = json.Unmarshal(b, &existing)
+		}
+	}
+
+	for _, c := range conditions {
+		c.ObservedGeneration = generation
+		meta.SetStatusCondition(&existing, c)
+	}
+
+	var out []interface{}
+	if b, err := json.Marshal(existing); err == nil {
+		_ = json.Unmarshal(b, &out)
+	}
+	status["conditions"] = out
+}
+
+// statusSnapshot encodes status for change detection. Comparing encodings
+// lets numeric types decoded from the API server match values set in code.
+func statusSnapshot(status map[string]interface{}) []byte {
+	b, _ := json.Marshal(status)
+	return b
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// debugKeepAliveSeconds is how long a failed runner keeps its pod alive for
+// inspection: spec.debug.keepAliveSeconds capped by the namespace's
+// sessionPolicy.maxDebugKeepAliveSeconds, and 0 when the policy does not
+// enable debug mode. The backend rejects requests above the maximum; the cap
+// also covers sessions created before the policy changed.
+func debugKeepAliveSeconds(spec, psSpec map[string]interface{}) int64 {
+	seconds, _, _ := unstructured.NestedInt64(spec, "debug", "keepAliveSeconds")
+	max, _, _ := unstructured.NestedInt64(psSpec, "sessionPolicy", "maxDebugKeepAliveSeconds")
+	if seconds <= 0 || max <= 0 {
+		return 0
+	}
+	return min(seconds, max)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	runnerPDBName                  = "ambient-runner-pdb"
+	disruptionProtectedLabel       = "ambient-code.io/disruption-protected"
+	defaultLongRunningSeconds      = 3600
+	runnerDisruptionSyncInterval   = 30 * time.Second
+	defaultRunnerMaxUnavailable    = 1
+	defaultRunnerSpreadMaxSkew     = 1
+	defaultRunnerSpreadTopologyKey = "kubernetes.io/hostname"
+)
+
+// runnerDisruptionPolicy mirrors ProjectSettings spec.runnerDisruption
+type runnerDisruptionPolicy struct {
+	Enabled            bool
+	MaxUnavailable     int32
+	LongRunningOnly    bool
+	LongRunningSeconds int64
+	SpreadTopologyKey  string
+	SpreadMaxSkew      int32
+	SpreadHard         bool
+}
+
+func runnerDisruptionPolicyFromSpec(psSpec map[string]interface{}) runnerDisruptionPolicy {
+	m, found, _ := unstructured.NestedMap(psSpec, "runnerDisruption")
+	if !found {
+		return runnerDisruptionPolicy{}
+	}
+	p := runnerDisruptionPolicy{
+		Enabled:            true,
+		MaxUnavailable:     defaultRunnerMaxUnavailable,
+		LongRunningSeconds: defaultLongRunningSeconds,
+	}
+	if v, ok, _ := unstructured.NestedInt64(m, "maxUnavailable"); ok && v >= 0 {
+		p.MaxUnavailable = int32(v)
+	}
+	p.LongRunningOnly, _, _ = unstructured.NestedBool(m, "longRunningOnly")
+	if v, ok, _ := unstructured.NestedInt64(m, "longRunningSeconds"); ok && v > 0 {
+		p.LongRunningSeconds = v
+	}
+	if spread, ok, _ := unstructured.NestedMap(m, "spread"); ok {
+		p.SpreadTopologyKey, _, _ = unstructured.NestedString(spread, "topologyKey")
+		if p.SpreadTopologyKey == "" {
+			p.SpreadTopologyKey = defaultRunnerSpreadTopologyKey
+		}
+		p.SpreadMaxSkew = defaultRunnerSpreadMaxSkew
+		if v, ok, _ := unstructured.NestedInt64(spread, "maxSkew"); ok && v > 0 {
+			p.SpreadMaxSkew = int32(v)
+		}
+		p.SpreadHard, _, _ = unstructured.NestedBool(spread, "required")
+	}
+	return p
+}
+
+// sessionIsProtected reports whether a session's runner pod is covered by the PDB
+func (p runnerDisruptionPolicy) sessionIsProtected(sessionSpec map[string]interface{}) bool {
+	if !p.Enabled {
+		return false
+	}
+	if !p.LongRunningOnly {
+		return true
+	}
+	if interactive, _, _ := unstructured.NestedBool(sessionSpec, "interactive"); interactive {
+		return true
+	}
+	timeout, _, _ := unstructured.NestedInt64(sessionSpec, "timeout")
+	return timeout >= p.LongRunningSeconds
+}
+
+// applyRunnerDisruptionPolicy labels the runner pod for the PDB and adds topology
+// spread constraints across runner pods in the namespace.
+func applyRunnerDisruptionPolicy(template *corev1.PodTemplateSpec, policy runnerDisruptionPolicy, sessionSpec map[string]interface{}) {
+	if !policy.Enabled {
+		return
+	}
+	if policy.sessionIsProtected(sessionSpec) {
+		template.Labels[disruptionProtectedLabel] = "true"
+	}
+	if policy.SpreadTopologyKey != "" {
+		when := corev1.ScheduleAnyway
+		if policy.SpreadHard {
+			when = corev1.DoNotSchedule
+		}
+		template.Spec.TopologySpreadConstraints = append(template.Spec.TopologySpreadConstraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           policy.SpreadMaxSkew,
+			TopologyKey:       policy.SpreadTopologyKey,
+			WhenUnsatisfiable: when,
+			LabelSelector:     &v1.LabelSelector{MatchLabels: map[string]string{"app": "ambient-code-runner"}},
+		})
+	}
+}
+
+// syncRunnerPDB keeps the namespace's runner PDB in line with policy. Runner pods
+// belong to Jobs, which a PDB can only guard with an integer minAvailable, so the
+// operator recomputes minAvailable from the protected pods currently running.
+func syncRunnerPDB(ns string, policy runnerDisruptionPolicy) error {
+	pdbs := k8sClient.PolicyV1().PodDisruptionBudgets(ns)
+	existing, err := pdbs.Get(context.TODO(), runnerPDBName, v1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	if !policy.Enabled {
+		if found {
+			return pdbs.Delete(context.TODO(), runnerPDBName, v1.DeleteOptions{})
+		}
+		return nil
+	}
+
+	pods, err := k8sClient.CoreV1().Pods(ns).List(context.TODO(), v1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=ambient-code-runner,%s=true", disruptionProtectedLabel),
+	})
+	if err != nil {
+		return fmt.Errorf("list runner pods: %v", err)
+	}
+	var running int32
+	for _, p := range pods.Items {
+		if p.Status.Phase == corev1.PodRunning && p.DeletionTimestamp == nil {
+			running++
+		}
+	}
+	minAvailable := running - policy.MaxUnavailable
+	if minAvailable < 0 {
+		minAvailable = 0
+	}
+	desired := intstr.FromInt32(minAvailable)
+
+	if found {
+		if existing.Spec.MinAvailable != nil && *existing.Spec.MinAvailable == desired {
+			return nil
+		}
+		existing.Spec.MinAvailable = &desired
+		_, err = pdbs.Update(context.TODO(), existing, v1.UpdateOptions{})
+		return err
+	}
+	_, err = pdbs.Create(context.TODO(), &policyv1.PodDisruptionBudget{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      runnerPDBName,
+			Namespace: ns,
+			Labels:    map[string]string{"app": "ambient-code-runner"},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &desired,
+			Selector: &v1.LabelSelector{MatchLabels: map[string]string{
+				"app":                    "ambient-code-runner",
+				disruptionProtectedLabel: "true",
+			}},
+		},
+	}, v1.CreateOptions{})
+	return err
+}
+
+// runDisruptionBudgetLoop periodically resyncs runner PDBs in managed namespaces
+// as sessions start and finish.
+func runDisruptionBudgetLoop() {
+	for {
+		time.Sleep(runnerDisruptionSyncInterval)
+		nsList, err := k8sClient.CoreV1().Namespaces().List(context.TODO(), v1.ListOptions{
+			LabelSelector: "ambient-code.io/managed=true",
+		})
+		if err != nil {
+			log.Printf("Disruption budgets: failed to list managed namespaces: %v", err)
+			continue
+		}
+		for _, ns := range nsList.Items {
+			var psSpec map[string]interface{}
+			if psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns.Name).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
+				psSpec, _, _ = unstructured.NestedMap(psObj.Object, "spec")
+			}
+			if err := syncRunnerPDB(ns.Name, runnerDisruptionPolicyFromSpec(psSpec)); err != nil {
+				log.Printf("Disruption budgets: sync failed in %s: %v", ns.Name, err)
+			}
+		}
+	}
+}
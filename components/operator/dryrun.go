@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ambient-code-shared/money"
+)
+
+const (
+	// policyEvaluationAnnotation carries the backend's admission result for a
+	// dry-run session, which the backend evaluates instead of rejecting
+	policyEvaluationAnnotation = "ambient-code.io/policy-evaluation"
+	// The cost estimate averages up to this many of the namespace's recent
+	// finished sessions
+	dryRunEstimateSamples = 20
+	dryRunEstimateWindow  = 30 * 24 * time.Hour
+)
+
+// sessionDryRun reports spec.dryRun: the session is planned but its workload is
+// never created
+func sessionDryRun(session *unstructured.Unstructured) bool {
+	v, _, _ := unstructured.NestedBool(session.Object, "spec", "dryRun")
+	return v
+}
+
+// sessionPlan collects the policy outcomes of a dry run. A nil plan records
+// nothing, so the reconcile path calls it unconditionally.
+type sessionPlan struct {
+	rules []interface{}
+}
+
+// newSessionPlan starts from the backend's admission rules when the session
+// carries them
+func newSessionPlan(session *unstructured.Unstructured) *sessionPlan {
+	p := &sessionPlan{rules: []interface{}{}}
+	raw := session.GetAnnotations()[policyEvaluationAnnotation]
+	if raw == "" {
+		return p
+	}
+	var rules []struct {
+		Rule    string `json:"rule"`
+		Passed  bool   `json:"passed"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		log.Printf("Ignoring invalid %s on %s/%s: %v", policyEvaluationAnnotation, session.GetNamespace(), session.GetName(), err)
+		return p
+	}
+	for _, r := range rules {
+		p.record(r.Rule, r.Passed, r.Message)
+	}
+	return p
+}
+
+// record adds a rule outcome, replacing an earlier one of the same rule unless
+// that failed and this passed: the operator's check runs against the current
+// policy, but does not overrule a backend denial
+func (p *sessionPlan) record(rule string, passed bool, message string) {
+	if p == nil {
+		return
+	}
+	entry := map[string]interface{}{"rule": rule, "passed": passed}
+	if message != "" {
+		entry["message"] = message
+	}
+	for i, r := range p.rules {
+		if prev := r.(map[string]interface{}); prev["rule"] == rule {
+			if prevPassed, _ := prev["passed"].(bool); prevPassed || !passed {
+				p.rules[i] = entry
+			}
+			return
+		}
+	}
+	p.rules = append(p.rules, entry)
+}
+
+func (p *sessionPlan) allowed() bool {
+	for _, r := range p.rules {
+		if passed, _ := r.(map[string]interface{})["passed"].(bool); !passed {
+			return false
+		}
+	}
+	return true
+}
+
+// costEstimate is the mean usage of comparable finished sessions
+type costEstimate struct {
+	CostUSD      money.USD
+	InputTokens  int64
+	OutputTokens int64
+	Samples      int
+	Basis        string
+}
+
+// estimateSessionCost averages the most recent finished sessions of the
+// namespace that ran model, or of any model when none did. A model with a
+// ModelPricing entry has the mean token counts priced at its rates.
+func estimateSessionCost(ns, model string, now time.Time) (costEstimate, error) {
+	list, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return costEstimate{}, fmt.Errorf("list sessions: %v", err)
+	}
+	var sameModel, anyModel []*unstructured.Unstructured
+	for i := range list.Items {
+		s := &list.Items[i]
+		end, done := sessionFinishedAt(s)
+		if !done || sessionDryRun(s) || now.Sub(end) > dryRunEstimateWindow || sessionCostUSD(s) <= 0 {
+			continue
+		}
+		anyModel = append(anyModel, s)
+		if m, _, _ := unstructured.NestedString(s.Object, "spec", "llmSettings", "model"); m == model {
+			sameModel = append(sameModel, s)
+		}
+	}
+	samples, basis := sameModel, fmt.Sprintf("recent sessions with model %s", model)
+	if len(samples) == 0 {
+		samples, basis = anyModel, "recent sessions of any model"
+	}
+	if len(samples) == 0 {
+		return costEstimate{Basis: "no finished sessions to estimate from"}, nil
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return sessionStartTime(samples[i]).After(sessionStartTime(samples[j]))
+	})
+	if len(samples) > dryRunEstimateSamples {
+		samples = samples[:dryRunEstimateSamples]
+	}
+	var total money.USD
+	est := costEstimate{Samples: len(samples), Basis: basis}
+	for _, s := range samples {
+		total += sessionCostUSD(s)
+		est.InputTokens += int64(floatFromSpec(s.Object, "status", "usage", "input_tokens"))
+		est.OutputTokens += int64(floatFromSpec(s.Object, "status", "usage", "output_tokens"))
+	}
+	n := int64(len(samples))
+	est.CostUSD = total.MulDiv(1, n)
+	est.InputTokens /= n
+	est.OutputTokens /= n
+	if price, ok := priceForModel(model); ok {
+		est.CostUSD = price.cost(est.InputTokens, est.OutputTokens)
+		est.Basis += ", priced at the model's ModelPricing rates"
+	}
+	return est, nil
+}
+
+// completeSessionDryRun writes the plan of a dry-run session to status.plan and
+// finishes it without creating its workload
+func completeSessionDryRun(session *unstructured.Unstructured, plan *sessionPlan, job *batchv1.Job, workload, workloadName, track string) error {
+	ns, name := session.GetNamespace(), session.GetName()
+	now := time.Now()
+	pod := job.Spec.Template.Spec
+
+	model, _, _ := unstructured.NestedString(session.Object, "spec", "llmSettings", "model")
+	est, err := estimateSessionCost(ns, model, now)
+	if err != nil {
+		log.Printf("Failed to estimate cost of %s/%s: %v", ns, name, err)
+		est.Basis = "estimate unavailable"
+	}
+	estimate := map[string]interface{}{"basis": est.Basis, "samples": int64(est.Samples)}
+	if est.Samples > 0 {
+		estimate["costUSD"] = est.CostUSD.Round(4).String()
+		estimate["inputTokens"] = est.InputTokens
+		estimate["outputTokens"] = est.OutputTokens
+	}
+
+	containerNames := func(cs []corev1.Container) []interface{} {
+		out := make([]interface{}, 0, len(cs))
+		for _, c := range cs {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	out := map[string]interface{}{
+		"allowed":        plan.allowed(),
+		"workload":       workload,
+		"workloadName":   workloadName,
+		"track":          track,
+		"initContainers": containerNames(pod.InitContainers),
+		"estimatedCost":  estimate,
+		"policy":         plan.rules,
+		"generatedAt":    now.UTC().Format(time.RFC3339),
+	}
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		out["activeDeadlineSeconds"] = *job.Spec.ActiveDeadlineSeconds
+	}
+	if pod.ServiceAccountName != "" {
+		out["serviceAccountName"] = pod.ServiceAccountName
+	}
+	if pod.PriorityClassName != "" {
+		out["priorityClassName"] = pod.PriorityClassName
+	}
+	if len(pod.NodeSelector) > 0 {
+		selector := map[string]interface{}{}
+		for k, v := range pod.NodeSelector {
+			selector[k] = v
+		}
+		out["nodeSelector"] = selector
+	}
+	if len(pod.Containers) > 0 {
+		runner := pod.Containers[0]
+		out["image"] = runner.Image
+		resources := map[string]interface{}{}
+		for key, list := range map[string]corev1.ResourceList{"requests": runner.Resources.Requests, "limits": runner.Resources.Limits} {
+			if len(list) == 0 {
+				continue
+			}
+			m := map[string]interface{}{}
+			for res, q := range list {
+				m[string(res)] = q.String()
+			}
+			resources[key] = m
+		}
+		out["resources"] = resources
+		// Names only: values may hold the prompt or credentials
+		env := make([]interface{}, 0, len(runner.Env))
+		for _, e := range runner.Env {
+			env = append(env, e.Name)
+		}
+		out["env"] = env
+	}
+
+	message := fmt.Sprintf("Dry run: %s %s was planned but not created", workload, workloadName)
+	if !plan.allowed() {
+		message = "Dry run: the session would be rejected by policy"
+	}
+	return updateAgenticSessionStatus(ns, name, map[string]interface{}{
+		"phase":          "Completed",
+		"message":        message,
+		"completionTime": now.UTC().Format(time.RFC3339),
+		"plan":           out,
+	})
+}
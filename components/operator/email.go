@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	defaultEmailDigestInterval = time.Hour
+	emailDigestCheckInterval   = time.Minute
+	eventReasonEmailFailed     = "EmailDeliveryFailed"
+)
+
+// smtpConfig is the cluster-wide mail relay, configured on the operator Deployment
+type smtpConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// StartTLS upgrades plain connections; port 465 always uses implicit TLS
+	StartTLS bool
+}
+
+func smtpConfigFromEnv() (smtpConfig, bool) {
+	c := smtpConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     587,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		StartTLS: os.Getenv("SMTP_STARTTLS") != "false",
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil {
+			c.Port = p
+		}
+	}
+	return c, c.Host != "" && c.From != ""
+}
+
+// Default templates per event; namespaces override them with
+// spec.notifications.email.templates.<event>.{subject,body}.
+var defaultEmailTemplates = map[string][2]string{
+	notifySessionCreated:   {"[Ambient] Session {{.Display}} started in {{.Namespace}}", "Session {{.Display}} ({{.Session}}) was created in {{.Namespace}}.\n{{if .SessionURL}}\n{{.SessionURL}}\n{{end}}"},
+	notifySessionCompleted: {"[Ambient] Session {{.Display}} completed", "Session {{.Display}} in {{.Namespace}} completed.\n{{if .Summary}}\n{{.Summary}}\n{{end}}{{if .CostUSD}}\nCost: ${{printf \"%.2f\" .CostUSD}}\n{{end}}{{range .Artifacts}}\n- {{.}}{{end}}\n{{if .SessionURL}}\n{{.SessionURL}}\n{{end}}"},
+	notifySessionFailed:    {"[Ambient] Session {{.Display}} {{.Phase}}", "Session {{.Display}} in {{.Namespace}} ended with phase {{.Phase}}.\n{{if .Summary}}\n{{.Summary}}\n{{end}}{{if .SessionURL}}\n{{.SessionURL}}\n{{end}}"},
+	notifyBudgetWarning:    {"[Ambient] Budget warning in {{.Namespace}}", "The budget for {{.Namespace}} is nearly used.\n{{range $k, $v := .Details}}\n{{$k}}: {{$v}}{{end}}\n"},
+	notifyBudgetExceeded:   {"[Ambient] Budget exceeded in {{.Namespace}}", "The budget for {{.Namespace}} has been exceeded.\n{{range $k, $v := .Details}}\n{{$k}}: {{$v}}{{end}}\n"},
+	notifyUsageReport:      {"[Ambient] Usage report for {{index .Details \"month\"}} in {{.Namespace}}", "Usage of {{.Namespace}} in {{index .Details \"month\"}}:\n{{range $k, $v := .Details}}\n{{$k}}: {{$v}}{{end}}\n"},
+}
+
+// emailSettings mirrors ProjectSettings spec.notifications.email
+type emailSettings struct {
+	Recipients []string
+	Digest     bool
+	Interval   time.Duration
+	Templates  map[string]interface{}
+	// raw is the section itself, used for the events filter
+	raw map[string]interface{}
+}
+
+func emailSettingsFromSpec(psSpec map[string]interface{}) (emailSettings, bool) {
+	m, found, _ := unstructured.NestedMap(psSpec, "notifications", "email")
+	if !found {
+		return emailSettings{}, false
+	}
+	s := emailSettings{raw: m, Interval: defaultEmailDigestInterval}
+	s.Recipients, _, _ = unstructured.NestedStringSlice(m, "recipients")
+	mode, _, _ := unstructured.NestedString(m, "mode")
+	s.Digest = mode == "digest"
+	if v, _, _ := unstructured.NestedString(m, "digestInterval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= time.Minute {
+			s.Interval = d
+		}
+	}
+	s.Templates, _, _ = unstructured.NestedMap(m, "templates")
+	return s, len(s.Recipients) > 0
+}
+
+// emailRecipientsFor returns the recipients subscribed to event
+func emailRecipientsFor(psSpec map[string]interface{}, event string) []string {
+	s, ok := emailSettingsFromSpec(psSpec)
+	if !ok || !subscribed(s.raw, event) {
+		return nil
+	}
+	return s.Recipients
+}
+
+// renderEmail executes the subject and body templates for n
+func renderEmail(s emailSettings, n notification) (string, string, error) {
+	def := defaultEmailTemplates[n.Event]
+	subject, body := def[0], def[1]
+	if subject == "" {
+		subject, body = "[Ambient] {{.Event}} in {{.Namespace}}", "{{.Event}} in {{.Namespace}}\n"
+	}
+	if v, _, _ := unstructured.NestedString(s.Templates, n.Event, "subject"); v != "" {
+		subject = v
+	}
+	if v, _, _ := unstructured.NestedString(s.Templates, n.Event, "body"); v != "" {
+		body = v
+	}
+	render := func(name, text string) (string, error) {
+		t, err := template.New(name).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("template %s for %s: %v", name, n.Event, err)
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, n); err != nil {
+			return "", fmt.Errorf("template %s for %s: %v", name, n.Event, err)
+		}
+		return b.String(), nil
+	}
+	sub, err := render("subject", subject)
+	if err != nil {
+		return "", "", err
+	}
+	text, err := render("body", body)
+	if err != nil {
+		return "", "", err
+	}
+	// Header injection guard: subjects are single-line
+	return strings.Join(strings.Fields(sub), " "), text, nil
+}
+
+// pendingDigests holds rendered messages per namespace until the digest interval elapses
+var (
+	pendingDigestsMu sync.Mutex
+	pendingDigests   = map[string]*emailDigest{}
+)
+
+type emailDigest struct {
+	recipients []string
+	interval   time.Duration
+	since      time.Time
+	entries    []string
+}
+
+// enqueueEmailNotification sends n immediately, or adds it to the namespace digest.
+// It returns 1 when an immediate send failed.
+func enqueueEmailNotification(ns string, psSpec map[string]interface{}, n notification) int {
+	recipients := emailRecipientsFor(psSpec, n.Event)
+	if len(recipients) == 0 {
+		return 0
+	}
+	s, _ := emailSettingsFromSpec(psSpec)
+	subject, body, err := renderEmail(s, n)
+	if err != nil {
+		recordEmailStatus(ns, err)
+		return 1
+	}
+	if s.Digest {
+		pendingDigestsMu.Lock()
+		d := pendingDigests[ns]
+		if d == nil {
+			d = &emailDigest{since: time.Now()}
+			pendingDigests[ns] = d
+		}
+		d.recipients, d.interval = recipients, s.Interval
+		d.entries = append(d.entries, fmt.Sprintf("== %s ==\n%s", subject, body))
+		pendingDigestsMu.Unlock()
+		return 0
+	}
+	err = deliverWithRetry(func() error { return sendEmail(recipients, subject, body) })
+	recordEmailStatus(ns, err)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// runEmailDigestLoop flushes namespace digests whose interval has elapsed
+func runEmailDigestLoop() {
+	for {
+		time.Sleep(emailDigestCheckInterval)
+		now := time.Now()
+		due := map[string]*emailDigest{}
+		pendingDigestsMu.Lock()
+		for ns, d := range pendingDigests {
+			if now.Sub(d.since) >= d.interval {
+				due[ns] = d
+				delete(pendingDigests, ns)
+			}
+		}
+		pendingDigestsMu.Unlock()
+		for ns, d := range due {
+			subject := fmt.Sprintf("[Ambient] %d notification(s) for %s", len(d.entries), ns)
+			body := strings.Join(d.entries, "\n\n")
+			err := deliverWithRetry(func() error { return sendEmail(d.recipients, subject, body) })
+			recordEmailStatus(ns, err)
+		}
+	}
+}
+
+// sendEmail delivers a plain-text message through the configured SMTP relay
+func sendEmail(to []string, subject, body string) error {
+	cfg, ok := smtpConfigFromEnv()
+	if !ok {
+		return fmt.Errorf("SMTP is not configured (SMTP_HOST and SMTP_FROM)")
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n",
+		cfg.From, strings.Join(to, ", "), subject, time.Now().Format(time.RFC1123Z))
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	if cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: cfg.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if cfg.Port != 465 && cfg.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s: %v", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// recordEmailStatus writes the outcome of a delivery to status.notifications.email
+func recordEmailStatus(ns string, sendErr error) {
+	psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		return
+	}
+	email, _, _ := unstructured.NestedMap(psObj.Object, "status", "notifications", "email")
+	if email == nil {
+		email = map[string]interface{}{}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if sendErr != nil {
+		notificationsFailedTotal.Add(1)
+		log.Printf("Email notification in %s failed: %v", ns, sendErr)
+		recordEvent(psObj, corev1.EventTypeWarning, eventReasonEmailFailed, "Email notification failed: %v", sendErr)
+		failed, _, _ := unstructured.NestedInt64(email, "failedTotal")
+		email["failedTotal"] = failed + 1
+		email["lastError"] = sendErr.Error()
+		email["lastErrorAt"] = now
+	} else {
+		notificationsSentTotal.Add(1)
+		email["lastSentAt"] = now
+	}
+	mergeNotificationsStatus(ns, psObj, map[string]interface{}{"email": email})
+}
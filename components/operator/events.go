@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons emitted on AgenticSession and ProjectSettings objects
+const (
+	eventReasonPhaseChanged         = "PhaseChanged"
+	eventReasonJobCreated           = "JobCreated"
+	eventReasonJobCreateFailed      = "JobCreateFailed"
+	eventReasonJobFailed            = "JobFailed"
+	eventReasonTimeout              = "Timeout"
+	eventReasonPolicyViolation      = "PolicyViolation"
+	eventReasonGroupBindingError    = "GroupBindingFailed"
+	eventReasonDeprecated           = "DeprecatedFrameworkVersion"
+	eventReasonAdmissionPolicyError = "AdmissionPolicyFailed"
+)
+
+var eventRecorder record.EventRecorder
+
+// initEventRecorder starts an event broadcaster that writes core/v1 Events
+// through the operator's client so they show up in `kubectl describe`.
+func initEventRecorder() {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(log.Printf)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	eventRecorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "agentic-operator"})
+}
+
+// recordEvent emits an Event on obj. obj must carry apiVersion and kind, which
+// objects read through the dynamic client always do.
+func recordEvent(obj *unstructured.Unstructured, eventType, reason, messageFmt string, args ...interface{}) {
+	if eventRecorder == nil || obj == nil {
+		return
+	}
+	eventRecorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
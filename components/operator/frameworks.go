@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// getFrameworkResource returns the GroupVersionResource for the cluster-scoped Framework registry
+func getFrameworkResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "frameworks",
+	}
+}
+
+// runnerFramework is the runner a session's spec.framework resolves to
+type runnerFramework struct {
+	Name      string
+	Version   string
+	Image     string
+	Resources corev1.ResourceRequirements
+	// Providers lists the model provider types the runner supports; empty means any
+	Providers []string
+	// Deprecated is set when the selected version is marked deprecated in the registry
+	Deprecated bool
+	// Workload is the framework's preferred runner workload (Job or PipelineRun)
+	Workload string
+}
+
+// resolveRunnerFramework looks up spec.framework in the Framework registry and
+// picks the image for spec.frameworkVersion (or the framework's defaultVersion).
+// The default framework works without a registry entry and runs the operator's
+// configured runner image.
+func resolveRunnerFramework(spec map[string]interface{}) (runnerFramework, error) {
+	fw := runnerFramework{Name: sessionFramework(spec)}
+	fw.Version, _, _ = unstructured.NestedString(spec, "frameworkVersion")
+
+	obj, err := dynamicClient.Resource(getFrameworkResource()).Get(context.TODO(), fw.Name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) && fw.Name == defaultSessionFramework && fw.Version == "" {
+			fw.Image = ambientCodeRunnerImage
+			return fw, nil
+		}
+		if errors.IsNotFound(err) {
+			return fw, fmt.Errorf("framework %q is not registered", fw.Name)
+		}
+		return fw, fmt.Errorf("failed to read framework %q: %v", fw.Name, err)
+	}
+
+	fwSpec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if fw.Version == "" {
+		fw.Version, _, _ = unstructured.NestedString(fwSpec, "defaultVersion")
+	}
+	fw.Image, _, _ = unstructured.NestedString(fwSpec, "runnerImage")
+	if fw.Version != "" {
+		versions, _, _ := unstructured.NestedSlice(fwSpec, "versions")
+		found := false
+		for _, raw := range versions {
+			m, ok := raw.(map[string]interface{})
+			if !ok || m["name"] != fw.Version {
+				continue
+			}
+			found = true
+			fw.Deprecated, _ = m["deprecated"].(bool)
+			if img, _ := m["image"].(string); img != "" {
+				fw.Image = img
+			}
+			break
+		}
+		if !found {
+			return fw, fmt.Errorf("framework %q does not support version %q", fw.Name, fw.Version)
+		}
+	}
+	if fw.Image == "" {
+		if fw.Name != defaultSessionFramework {
+			return fw, fmt.Errorf("framework %q has no runner image", fw.Name)
+		}
+		fw.Image = ambientCodeRunnerImage
+	}
+
+	fw.Providers, _, _ = unstructured.NestedStringSlice(fwSpec, "providers")
+	fw.Workload, _, _ = unstructured.NestedString(fwSpec, "workload")
+	if res, ok, _ := unstructured.NestedMap(fwSpec, "resources"); ok {
+		fw.Resources.Requests = resourceListFromMap(res, "requests")
+		fw.Resources.Limits = resourceListFromMap(res, "limits")
+	}
+	return fw, nil
+}
+
+// resourceListFromMap parses {cpu: "500m", memory: "1Gi"} under key, skipping invalid quantities
+func resourceListFromMap(m map[string]interface{}, key string) corev1.ResourceList {
+	raw, ok, _ := unstructured.NestedStringMap(m, key)
+	if !ok || len(raw) == 0 {
+		return nil
+	}
+	out := corev1.ResourceList{}
+	for name, v := range raw {
+		if q, err := resource.ParseQuantity(v); err == nil {
+			out[corev1.ResourceName(name)] = q
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	eventReasonGitBootstrap   = "GitBootstrap"
+	gitBootstrapContainerName = "git-bootstrap"
+	gitBootstrapVolumeName    = "source"
+	gitBootstrapSecretVolume  = "git-bootstrap-credentials"
+	gitBootstrapSecretMount   = "/etc/git-bootstrap"
+	// The runner works in /tmp/workdir; the checkout appears there as a subdirectory
+	runnerWorkdir = "/tmp/workdir"
+)
+
+// gitBootstrapScript fetches exactly one ref into /source. A token is sent as HTTP
+// basic auth; a deploy key is copied out of the read-only Secret volume because
+// ssh refuses keys readable by others.
+const gitBootstrapScript = `set -eu
+cd /source
+git init -q .
+git remote add origin "$REPO_URL"
+if [ -f "$SECRET_DIR/ssh-privatekey" ]; then
+  cp "$SECRET_DIR/ssh-privatekey" /tmp/git-bootstrap-key && chmod 600 /tmp/git-bootstrap-key
+  export GIT_SSH_COMMAND="ssh -i /tmp/git-bootstrap-key -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/tmp/git-bootstrap-known-hosts"
+elif [ -f "$SECRET_DIR/token" ]; then
+  auth=$(printf '%s:%s' "$GIT_USERNAME" "$(cat "$SECRET_DIR/token")" | base64 | tr -d '\n')
+  git config http.extraHeader "Authorization: Basic $auth"
+fi
+git fetch -q --depth "$DEPTH" origin "$REF"
+git checkout -q FETCH_HEAD
+git config --unset-all http.extraHeader || true
+echo "Checked out $(git rev-parse HEAD) from $REF"
+`
+
+// gitBootstrap is the checkout for a GitHub- or GitLab-triggered session, per
+// ProjectSettings spec.gitBootstrap
+type gitBootstrap struct {
+	URL      string
+	Ref      string
+	Dir      string
+	Username string
+	Secret   string
+	Depth    int64
+}
+
+// gitBootstrapForSession returns the repository and ref to check out before the
+// runner starts. The ref is the trigger's headSha, else its ref, else the pull or
+// merge request head.
+func gitBootstrapForSession(spec, psSpec map[string]interface{}) (gitBootstrap, bool) {
+	if enabled, _, _ := unstructured.NestedBool(psSpec, "gitBootstrap", "enabled"); !enabled {
+		return gitBootstrap{}, false
+	}
+	source, _, _ := unstructured.NestedString(spec, "trigger", "source")
+	source = strings.ToLower(source)
+	if source != "github" && source != "gitlab" {
+		return gitBootstrap{}, false
+	}
+	repo, _, _ := unstructured.NestedString(spec, "trigger", "repo")
+	repo = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(repo), "/"), ".git")
+	if repo == "" {
+		return gitBootstrap{}, false
+	}
+
+	b := gitBootstrap{Depth: 1, Username: "x-access-token"}
+	if source == "gitlab" {
+		b.Username = "oauth2"
+	}
+	b.Secret, _, _ = unstructured.NestedString(psSpec, "gitBootstrap", "credentialsSecret")
+	if d, ok, _ := unstructured.NestedInt64(psSpec, "gitBootstrap", "depth"); ok && d > 0 {
+		b.Depth = d
+	}
+
+	switch {
+	case strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@"):
+		b.URL = repo + ".git"
+	default:
+		base, _, _ := unstructured.NestedString(psSpec, "gitBootstrap", "baseURLs", source)
+		if base == "" {
+			base = "https://" + source + ".com"
+		}
+		b.URL = strings.TrimRight(base, "/") + "/" + repo + ".git"
+	}
+	b.Dir = path.Join(runnerWorkdir, path.Base(repo))
+
+	sha, _, _ := unstructured.NestedString(spec, "trigger", "headSha")
+	ref, _, _ := unstructured.NestedString(spec, "trigger", "ref")
+	pr, _, _ := unstructured.NestedInt64(spec, "trigger", "prNumber")
+	switch {
+	case sha != "":
+		b.Ref = sha
+	case ref != "":
+		b.Ref = ref
+	case pr > 0 && source == "gitlab":
+		b.Ref = fmt.Sprintf("refs/merge-requests/%d/head", pr)
+	case pr > 0:
+		b.Ref = fmt.Sprintf("refs/pull/%d/head", pr)
+	default:
+		b.Ref = "HEAD"
+	}
+	return b, true
+}
+
+// applyGitBootstrap adds the clone init container and mounts the checkout in the runner
+func applyGitBootstrap(pod *corev1.PodSpec, image string, b gitBootstrap) {
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name:         gitBootstrapVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	mounts := []corev1.VolumeMount{{Name: gitBootstrapVolumeName, MountPath: "/source"}}
+	if b.Secret != "" {
+		pod.Volumes = append(pod.Volumes, corev1.Volume{
+			Name: gitBootstrapSecretVolume,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: b.Secret,
+				Items: []corev1.KeyToPath{
+					{Key: "token", Path: "token"},
+					{Key: "ssh-privatekey", Path: "ssh-privatekey"},
+				},
+				Optional: boolPtr(true),
+			}},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: gitBootstrapSecretVolume, MountPath: gitBootstrapSecretMount, ReadOnly: true})
+	}
+	pod.InitContainers = append(pod.InitContainers, corev1.Container{
+		Name:            gitBootstrapContainerName,
+		Image:           image,
+		ImagePullPolicy: imagePullPolicy,
+		Command:         []string{"/bin/sh", "-c", gitBootstrapScript},
+		Env: []corev1.EnvVar{
+			{Name: "REPO_URL", Value: b.URL},
+			{Name: "REF", Value: b.Ref},
+			{Name: "DEPTH", Value: fmt.Sprintf("%d", b.Depth)},
+			{Name: "GIT_USERNAME", Value: b.Username},
+			{Name: "SECRET_DIR", Value: gitBootstrapSecretMount},
+			{Name: "HOME", Value: "/tmp"},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+		VolumeMounts: mounts,
+	})
+	for i := range pod.Containers {
+		pod.Containers[i].VolumeMounts = append(pod.Containers[i].VolumeMounts, corev1.VolumeMount{Name: gitBootstrapVolumeName, MountPath: b.Dir})
+		pod.Containers[i].Env = append(pod.Containers[i].Env, corev1.EnvVar{Name: "GIT_BOOTSTRAP_DIR", Value: b.Dir})
+	}
+}
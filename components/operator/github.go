@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	defaultGitHubAPIURL            = "https://api.github.com"
+	defaultGitHubCredentialsSecret = "github-credentials"
+	githubCheckRunName             = "Ambient session"
+)
+
+// githubReporter posts the result of GitHub-triggered sessions back to the pull
+// request, as a comment or a check-run, per ProjectSettings spec.integrations.github.
+var githubReporter = sessionReporter{
+	name: "github",
+	applies: func(session *unstructured.Unstructured, psSpec map[string]interface{}) bool {
+		enabled, _, _ := unstructured.NestedBool(psSpec, "integrations", "github", "enabled")
+		source, _, _ := unstructured.NestedString(session.Object, "spec", "trigger", "source")
+		return enabled && strings.EqualFold(source, "github")
+	},
+	report: reportToGitHub,
+}
+
+// githubIntegration mirrors ProjectSettings spec.integrations.github
+type githubIntegration struct {
+	APIURL            string
+	CredentialsSecret string
+	// Mode is "comment" (default) or "check-run"; check-runs need a GitHub App
+	Mode string
+}
+
+func githubIntegrationFromSpec(psSpec map[string]interface{}) githubIntegration {
+	g := githubIntegration{APIURL: defaultGitHubAPIURL, CredentialsSecret: defaultGitHubCredentialsSecret, Mode: "comment"}
+	if v, _, _ := unstructured.NestedString(psSpec, "integrations", "github", "apiURL"); v != "" {
+		g.APIURL = strings.TrimRight(v, "/")
+	}
+	if v, _, _ := unstructured.NestedString(psSpec, "integrations", "github", "credentialsSecret"); v != "" {
+		g.CredentialsSecret = v
+	}
+	if v, _, _ := unstructured.NestedString(psSpec, "integrations", "github", "mode"); v != "" {
+		g.Mode = v
+	}
+	return g
+}
+
+// parseGitHubRepo accepts owner/repo or a GitHub URL
+func parseGitHubRepo(repo string) (string, string, bool) {
+	repo = strings.TrimSpace(repo)
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	if i := strings.Index(repo, "://"); i >= 0 {
+		repo = repo[i+3:]
+		if j := strings.Index(repo, "/"); j >= 0 {
+			repo = repo[j+1:]
+		}
+	} else if strings.HasPrefix(repo, "git@") {
+		if j := strings.Index(repo, ":"); j >= 0 {
+			repo = repo[j+1:]
+		}
+	}
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func reportToGitHub(session *unstructured.Unstructured, psSpec map[string]interface{}, summary sessionSummary) (string, error) {
+	cfg := githubIntegrationFromSpec(psSpec)
+	repo, _, _ := unstructured.NestedString(session.Object, "spec", "trigger", "repo")
+	owner, name, ok := parseGitHubRepo(repo)
+	if !ok {
+		return "", fmt.Errorf("trigger repo %q is not a GitHub repository", repo)
+	}
+	token, err := githubToken(session.GetNamespace(), cfg, owner, name)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Mode == "check-run" {
+		sha, _, _ := unstructured.NestedString(session.Object, "spec", "trigger", "headSha")
+		if sha == "" {
+			return "", fmt.Errorf("trigger has no headSha for a check-run")
+		}
+		conclusion := "failure"
+		switch summary.Phase {
+		case "Completed":
+			conclusion = "success"
+		case "Stopped":
+			conclusion = "cancelled"
+		}
+		body := map[string]interface{}{
+			"name":       githubCheckRunName,
+			"head_sha":   sha,
+			"status":     "completed",
+			"conclusion": conclusion,
+			"output": map[string]interface{}{
+				"title":   fmt.Sprintf("Session %s", strings.ToLower(summary.Phase)),
+				"summary": summary.markdown(),
+			},
+		}
+		if summary.SessionURL != "" {
+			body["details_url"] = summary.SessionURL
+		}
+		var resp struct {
+			HTMLURL string `json:"html_url"`
+		}
+		if err := githubRequest(http.MethodPost, fmt.Sprintf("%s/repos/%s/%s/check-runs", cfg.APIURL, owner, name), "token "+token, body, &resp); err != nil {
+			return "", err
+		}
+		return resp.HTMLURL, nil
+	}
+
+	pr, _, _ := unstructured.NestedInt64(session.Object, "spec", "trigger", "prNumber")
+	if pr <= 0 {
+		return "", fmt.Errorf("trigger has no prNumber to comment on")
+	}
+	var resp struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := githubRequest(http.MethodPost, fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", cfg.APIURL, owner, name, pr), "token "+token, map[string]string{"body": summary.markdown()}, &resp); err != nil {
+		return "", err
+	}
+	return resp.HTMLURL, nil
+}
+
+// installationTokens caches GitHub App installation tokens until shortly before expiry
+var (
+	installationTokensMu sync.Mutex
+	installationTokens   = map[string]cachedGitHubToken{}
+)
+
+type cachedGitHubToken struct {
+	token   string
+	expires time.Time
+}
+
+// githubToken returns a token from the namespace's credentials Secret: either a
+// static "token", or a GitHub App ("appId", "privateKey", optional
+// "installationId") exchanged for an installation token.
+func githubToken(ns string, cfg githubIntegration, owner, repo string) (string, error) {
+	sec, err := k8sClient.CoreV1().Secrets(ns).Get(context.TODO(), cfg.CredentialsSecret, v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("read GitHub credentials secret %s: %v", cfg.CredentialsSecret, err)
+	}
+	if t := strings.TrimSpace(string(sec.Data["token"])); t != "" {
+		return t, nil
+	}
+	appID := strings.TrimSpace(string(sec.Data["appId"]))
+	keyPEM := sec.Data["privateKey"]
+	if appID == "" || len(keyPEM) == 0 {
+		return "", fmt.Errorf("secret %s needs either token or appId and privateKey", cfg.CredentialsSecret)
+	}
+
+	installationID := strings.TrimSpace(string(sec.Data["installationId"]))
+	cacheKey := fmt.Sprintf("%s/%s/%s/%s", ns, appID, installationID, owner+"/"+repo)
+	installationTokensMu.Lock()
+	cached, ok := installationTokens[cacheKey]
+	installationTokensMu.Unlock()
+	if ok && time.Until(cached.expires) > 5*time.Minute {
+		return cached.token, nil
+	}
+
+	jwt, err := githubAppJWT(appID, keyPEM)
+	if err != nil {
+		return "", err
+	}
+	if installationID == "" {
+		var inst struct {
+			ID int64 `json:"id"`
+		}
+		if err := githubRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/%s/installation", cfg.APIURL, owner, repo), "Bearer "+jwt, nil, &inst); err != nil {
+			return "", fmt.Errorf("find GitHub App installation for %s/%s: %v", owner, repo, err)
+		}
+		installationID = fmt.Sprint(inst.ID)
+	}
+	var tok struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := githubRequest(http.MethodPost, fmt.Sprintf("%s/app/installations/%s/access_tokens", cfg.APIURL, installationID), "Bearer "+jwt, nil, &tok); err != nil {
+		return "", fmt.Errorf("create installation token: %v", err)
+	}
+	installationTokensMu.Lock()
+	installationTokens[cacheKey] = cachedGitHubToken{token: tok.Token, expires: tok.ExpiresAt}
+	installationTokensMu.Unlock()
+	return tok.Token, nil
+}
+
+// githubAppJWT signs the short-lived RS256 JWT a GitHub App uses to authenticate as itself
+func githubAppJWT(appID string, keyPEM []byte) (string, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return "", fmt.Errorf("privateKey is not PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rk, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("privateKey is not an RSA key")
+		}
+		key = rk
+	} else {
+		return "", fmt.Errorf("parse privateKey: %v", err)
+	}
+
+	now := time.Now()
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		// Backdated to tolerate clock drift, as GitHub recommends
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	signingInput := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
+
+func githubRequest(method, url, auth string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", auth)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GitHub %s %s: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
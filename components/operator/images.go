@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// runnerImageOverride mirrors one ProjectSettings spec.runnerImages.<framework> entry
+type runnerImageOverride struct {
+	// Repository replaces the image repository, e.g. a mirror in an internal registry
+	Repository string
+	Tag        string
+	// Digest (sha256:...) pins the image and takes precedence over Tag
+	Digest string
+}
+
+func runnerImageOverrideFromSpec(psSpec map[string]interface{}, framework string) (runnerImageOverride, bool) {
+	m, found, _ := unstructured.NestedMap(psSpec, "runnerImages", framework)
+	if !found {
+		return runnerImageOverride{}, false
+	}
+	o := runnerImageOverride{}
+	o.Repository, _ = m["repository"].(string)
+	o.Tag, _ = m["tag"].(string)
+	o.Digest, _ = m["digest"].(string)
+	return o, o.Repository != "" || o.Tag != "" || o.Digest != ""
+}
+
+// splitImageReference splits repo[:tag][@digest]; a colon before the last slash
+// belongs to a registry port, not a tag.
+func splitImageReference(image string) (repo, tag, digest string) {
+	repo = image
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, digest = repo[:i], repo[i+1:]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, tag = repo[:i], repo[i+1:]
+	}
+	return repo, tag, digest
+}
+
+// apply returns image with the override's repository, tag or digest substituted
+func (o runnerImageOverride) apply(image string) string {
+	repo, tag, digest := splitImageReference(image)
+	if o.Repository != "" {
+		repo = o.Repository
+	}
+	if o.Tag != "" {
+		tag, digest = o.Tag, ""
+	}
+	if o.Digest != "" {
+		tag, digest = "", o.Digest
+	}
+	out := repo
+	if tag != "" {
+		out += ":" + tag
+	}
+	if digest != "" {
+		out += "@" + digest
+	}
+	return out
+}
+
+// runnerImagePullSecrets returns spec.imagePullSecrets as pod references
+func runnerImagePullSecrets(psSpec map[string]interface{}) []corev1.LocalObjectReference {
+	names, _, _ := unstructured.NestedStringSlice(psSpec, "imagePullSecrets")
+	refs := make([]corev1.LocalObjectReference, 0, len(names))
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			refs = append(refs, corev1.LocalObjectReference{Name: n})
+		}
+	}
+	return refs
+}
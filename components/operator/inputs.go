@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	inputsInitContainerName     = "materialize-inputs"
+	eventReasonInputNotFinished = "InputSessionNotFinished"
+	eventReasonInputsMounted    = "InputsMounted"
+)
+
+// materializeInputsScript runs in the runner image before the runner starts. It
+// copies matching artifact files from each source session into the new session's
+// workspace on the project PVC, which the runner then syncs into its workdir.
+const materializeInputsScript = `
+import fnmatch, json, os, shutil
+root = "/workspace"
+for spec in json.loads(os.environ["SESSION_INPUTS"]):
+    src = os.path.join(root, spec["source"].lstrip("/"))
+    dst = os.path.join(root, spec["dest"].lstrip("/"))
+    patterns = spec.get("patterns") or ["*"]
+    if not os.path.isdir(src):
+        print(f"input {spec['sessionRef']}: no artifacts at {spec['source']}", flush=True)
+        continue
+    copied = 0
+    for dirpath, _, files in os.walk(src):
+        for name in files:
+            full = os.path.join(dirpath, name)
+            rel = os.path.relpath(full, src)
+            if os.path.islink(full) or not any(fnmatch.fnmatchcase(rel, p) for p in patterns):
+                continue
+            target = os.path.join(dst, rel)
+            os.makedirs(os.path.dirname(target), exist_ok=True)
+            shutil.copyfile(full, target)
+            copied += 1
+    print(f"input {spec['sessionRef']}: copied {copied} artifact(s) to {spec['dest']}", flush=True)
+`
+
+// resolvedInput is one spec.inputs entry with PVC paths resolved
+type resolvedInput struct {
+	SessionRef string   `json:"sessionRef"`
+	Source     string   `json:"source"`
+	Dest       string   `json:"dest"`
+	Patterns   []string `json:"patterns,omitempty"`
+}
+
+// sessionWorkspacePath returns a session's workspace path on the project PVC
+func sessionWorkspacePath(name string, spec map[string]interface{}) string {
+	if ws, ok, _ := unstructured.NestedString(spec, "paths", "workspace"); ok && ws != "" {
+		return ws
+	}
+	return fmt.Sprintf("/sessions/%s/workspace", name)
+}
+
+// resolveSessionInputs maps spec.inputs to source artifact directories and
+// destinations inside workspacePath. Source sessions must exist in the same
+// namespace; sessions that have not finished yet are used as they are, with a
+// warning event.
+func resolveSessionInputs(session *unstructured.Unstructured, workspacePath string) ([]resolvedInput, error) {
+	raw, found, _ := unstructured.NestedSlice(session.Object, "spec", "inputs")
+	if !found || len(raw) == 0 {
+		return nil, nil
+	}
+	ns := session.GetNamespace()
+	out := make([]resolvedInput, 0, len(raw))
+	for i, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref, _ := m["sessionRef"].(string)
+		if ref == "" || ref == session.GetName() {
+			return nil, fmt.Errorf("inputs[%d]: invalid sessionRef %q", i, ref)
+		}
+		dest, _ := m["path"].(string)
+		if dest == "" {
+			dest = "inputs/" + ref
+		}
+		dest = path.Clean(dest)
+		if path.IsAbs(dest) || dest == ".." || strings.HasPrefix(dest, "../") {
+			return nil, fmt.Errorf("inputs[%d]: path %q escapes the workspace", i, dest)
+		}
+
+		src, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).Get(context.TODO(), ref, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, fmt.Errorf("input session %q not found", ref)
+			}
+			return nil, fmt.Errorf("get input session %q: %v", ref, err)
+		}
+		if _, done := sessionFinishedAt(src); !done {
+			recordEvent(session, corev1.EventTypeWarning, eventReasonInputNotFinished, "Input session %s has not finished; its artifacts may be incomplete", ref)
+		}
+		srcSpec, _, _ := unstructured.NestedMap(src.Object, "spec")
+
+		in := resolvedInput{
+			SessionRef: ref,
+			Source:     sessionWorkspacePath(ref, srcSpec) + "/artifacts",
+			Dest:       path.Join(workspacePath, dest),
+		}
+		if sel, ok := m["artifactSelector"].([]interface{}); ok {
+			for _, s := range sel {
+				if p, ok := s.(string); ok && p != "" {
+					in.Patterns = append(in.Patterns, p)
+				}
+			}
+		}
+		out = append(out, in)
+	}
+	return out, nil
+}
+
+// inputsInitContainer builds the init container that copies resolved inputs
+// into the workspace. It mounts the project PVC read-write.
+func inputsInitContainer(image string, inputs []resolvedInput) (corev1.Container, error) {
+	b, err := json.Marshal(inputs)
+	if err != nil {
+		return corev1.Container{}, err
+	}
+	return corev1.Container{
+		Name:            inputsInitContainerName,
+		Image:           image,
+		ImagePullPolicy: imagePullPolicy,
+		Command:         []string{"python3", "-c", materializeInputsScript},
+		Env:             []corev1.EnvVar{{Name: "SESSION_INPUTS", Value: string(b)}},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "workspace", MountPath: "/workspace"}},
+	}, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// jiraReporter comments the result on the issue of Jira-triggered sessions and
+// applies the transition configured for the final phase, per ProjectSettings
+// spec.integrations.jira. Credentials come from the runner secret (JIRA_URL,
+// JIRA_API_TOKEN), as for the backend's Jira endpoints.
+var jiraReporter = sessionReporter{
+	name: "jira",
+	applies: func(session *unstructured.Unstructured, psSpec map[string]interface{}) bool {
+		enabled, _, _ := unstructured.NestedBool(psSpec, "integrations", "jira", "enabled")
+		source, _, _ := unstructured.NestedString(session.Object, "spec", "trigger", "source")
+		return enabled && strings.EqualFold(source, "jira")
+	},
+	report: reportToJira,
+}
+
+// jiraTransitionRule moves the issue to status To when the session ends in OnPhase
+type jiraTransitionRule struct {
+	OnPhase string
+	To      string
+}
+
+func jiraTransitionRules(psSpec map[string]interface{}) []jiraTransitionRule {
+	raw, _, _ := unstructured.NestedSlice(psSpec, "integrations", "jira", "transitions")
+	rules := make([]jiraTransitionRule, 0, len(raw))
+	for _, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule := jiraTransitionRule{}
+		rule.OnPhase, _ = m["onPhase"].(string)
+		rule.To, _ = m["to"].(string)
+		if rule.OnPhase != "" && rule.To != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func reportToJira(session *unstructured.Unstructured, psSpec map[string]interface{}, summary sessionSummary) (string, error) {
+	key, _, _ := unstructured.NestedString(session.Object, "spec", "trigger", "issueKey")
+	key = strings.ToUpper(strings.TrimSpace(key))
+	if key == "" {
+		return "", fmt.Errorf("trigger has no issueKey")
+	}
+	secretName, _, _ := unstructured.NestedString(psSpec, "runnerSecretsName")
+	if strings.TrimSpace(secretName) == "" {
+		secretName = "ambient-runner-secrets"
+	}
+	sec, err := k8sClient.CoreV1().Secrets(session.GetNamespace()).Get(context.TODO(), secretName, v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("read runner secret %s: %v", secretName, err)
+	}
+	base := strings.TrimRight(strings.TrimSpace(string(sec.Data["JIRA_URL"])), "/")
+	token := strings.TrimSpace(string(sec.Data["JIRA_API_TOKEN"]))
+	if base == "" || token == "" {
+		return "", fmt.Errorf("runner secret %s lacks JIRA_URL or JIRA_API_TOKEN", secretName)
+	}
+	issueURL := fmt.Sprintf("%s/rest/api/2/issue/%s", base, url.PathEscape(key))
+
+	comment := true
+	if v, found, _ := unstructured.NestedBool(psSpec, "integrations", "jira", "comment"); found {
+		comment = v
+	}
+	if comment {
+		if err := jiraRequest(http.MethodPost, issueURL+"/comment", token, map[string]string{"body": summary.jiraWiki()}, nil); err != nil {
+			return "", err
+		}
+	}
+
+	for _, rule := range jiraTransitionRules(psSpec) {
+		if !strings.EqualFold(rule.OnPhase, summary.Phase) {
+			continue
+		}
+		var available struct {
+			Transitions []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+				To   struct {
+					Name string `json:"name"`
+				} `json:"to"`
+			} `json:"transitions"`
+		}
+		if err := jiraRequest(http.MethodGet, issueURL+"/transitions", token, nil, &available); err != nil {
+			return "", err
+		}
+		id := ""
+		for _, t := range available.Transitions {
+			if strings.EqualFold(t.To.Name, rule.To) || strings.EqualFold(t.Name, rule.To) {
+				id = t.ID
+				break
+			}
+		}
+		if id == "" {
+			return "", fmt.Errorf("no transition to %q is available for %s", rule.To, key)
+		}
+		body := map[string]interface{}{"transition": map[string]string{"id": id}}
+		if err := jiraRequest(http.MethodPost, issueURL+"/transitions", token, body, nil); err != nil {
+			return "", err
+		}
+		break
+	}
+	return fmt.Sprintf("%s/browse/%s", base, url.PathEscape(key)), nil
+}
+
+func jiraRequest(method, u, token string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Jira %s %s: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// jiraWiki renders the summary in Jira wiki markup
+func (s sessionSummary) jiraWiki() string {
+	var b strings.Builder
+	icon := "(/)"
+	if s.Phase != "Completed" {
+		icon = "(x)"
+	}
+	fmt.Fprintf(&b, "h3. %s Ambient session %s: %s\n\n", icon, strings.ToLower(s.Phase), s.Display)
+	if s.Summary != "" {
+		fmt.Fprintf(&b, "{noformat}\n%s\n{noformat}\n\n", s.Summary)
+	}
+	if stats := s.stats(); len(stats) > 0 {
+		fmt.Fprintf(&b, "_%s_\n\n", strings.Join(stats, " · "))
+	}
+	if len(s.Artifacts) > 0 {
+		b.WriteString("*Artifacts*\n")
+		for _, a := range s.Artifacts {
+			fmt.Fprintf(&b, "* [%s|%s]\n", a.Name, a.URL)
+		}
+		b.WriteString("\n")
+	}
+	if s.SessionURL != "" {
+		fmt.Fprintf(&b, "[View session|%s]\n", s.SessionURL)
+	}
+	return b.String()
+}
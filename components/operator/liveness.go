@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	eventReasonStalled = "Stalled"
+	// defaultHeartbeatTimeout applies when neither spec.liveness.timeoutSeconds
+	// nor RUNNER_HEARTBEAT_TIMEOUT is set
+	defaultHeartbeatTimeout = 5 * time.Minute
+)
+
+// sessionLivenessPolicy mirrors AgenticSession spec.liveness
+type sessionLivenessPolicy struct {
+	Timeout     time.Duration
+	KillOnStall bool
+}
+
+func livenessPolicyFromSpec(spec map[string]interface{}) sessionLivenessPolicy {
+	p := sessionLivenessPolicy{Timeout: defaultHeartbeatTimeout}
+	if v := os.Getenv("RUNNER_HEARTBEAT_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			p.Timeout = d
+		}
+	}
+	if v, found, _ := unstructured.NestedInt64(spec, "liveness", "timeoutSeconds"); found && v > 0 {
+		p.Timeout = time.Duration(v) * time.Second
+	}
+	p.KillOnStall, _, _ = unstructured.NestedBool(spec, "liveness", "killOnStall")
+	return p
+}
+
+// sessionStalled reports whether the session carries Stalled=True
+func sessionStalled(obj *unstructured.Unstructured) bool {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range raw {
+		m, ok := c.(map[string]interface{})
+		if ok && m["type"] == conditionStalled {
+			return m["status"] == string(v1.ConditionTrue)
+		}
+	}
+	return false
+}
+
+// checkRunnerLiveness compares a running session's status.lastHeartbeatTime
+// with its liveness timeout. A session whose heartbeats stopped is marked
+// Stalled, and cleared once they resume. With killOnStall, kill stops the
+// workload and the session fails with reason Stalled so retryPolicy applies;
+// it then reports true and the caller stops monitoring. Sessions that never
+// sent a heartbeat run a runner without them and are not tracked.
+func checkRunnerLiveness(obj *unstructured.Unstructured, workload string, kill func() error) bool {
+	if obj == nil {
+		return false
+	}
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "Running" {
+		return false
+	}
+	raw, _, _ := unstructured.NestedString(obj.Object, "status", "lastHeartbeatTime")
+	last, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return false
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	policy := livenessPolicyFromSpec(spec)
+	ns, name := obj.GetNamespace(), obj.GetName()
+	silent := time.Since(last).Round(time.Second)
+
+	if silent <= policy.Timeout {
+		if sessionStalled(obj) {
+			updateAgenticSessionStatus(ns, name, nil, v1.Condition{
+				Type:    conditionStalled,
+				Status:  v1.ConditionFalse,
+				Reason:  "HeartbeatResumed",
+				Message: fmt.Sprintf("Runner heartbeat received at %s", raw),
+			})
+		}
+		return false
+	}
+
+	if !sessionStalled(obj) {
+		log.Printf("Session %s/%s stalled: no runner heartbeat for %s", ns, name, silent)
+		updateAgenticSessionStatus(ns, name, nil, v1.Condition{
+			Type:    conditionStalled,
+			Status:  v1.ConditionTrue,
+			Reason:  "HeartbeatTimeout",
+			Message: fmt.Sprintf("No runner heartbeat since %s (timeout %s)", raw, policy.Timeout),
+		})
+		recordEvent(obj, corev1.EventTypeWarning, eventReasonStalled, "No runner heartbeat for %s", silent)
+	}
+	if !policy.KillOnStall {
+		return false
+	}
+
+	if err := kill(); err != nil {
+		log.Printf("Failed to kill stalled workload %s of %s/%s: %v", workload, ns, name, err)
+		return false
+	}
+	recordEvent(obj, corev1.EventTypeWarning, eventReasonStalled, "Killed %s after %s without a runner heartbeat", workload, silent)
+	updateAgenticSessionStatus(ns, name, map[string]interface{}{
+		"phase":          "Failed",
+		"message":        fmt.Sprintf("Runner stalled: no heartbeat for %s", silent),
+		"completionTime": time.Now().Format(time.RFC3339),
+	}, v1.Condition{
+		Type:    conditionSucceeded,
+		Status:  v1.ConditionFalse,
+		Reason:  "Stalled",
+		Message: fmt.Sprintf("%s killed after %s without a runner heartbeat", workload, silent),
+	})
+	return true
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,6 +28,27 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// defaultSessionTimeoutSeconds is the Job deadline used when a session has no timeout
+const defaultSessionTimeoutSeconds int64 = 1800
+
+// defaultInteractiveSessionTimeoutSeconds is the Job deadline of interactive
+// sessions without a timeout; they wait on people, so it is a working day
+const defaultInteractiveSessionTimeoutSeconds int64 = 8 * 3600
+
+// defaultJobDeadline returns the Job deadline of a session without a timeout.
+// INTERACTIVE_SESSION_TIMEOUT_SECONDS overrides it for interactive sessions.
+func defaultJobDeadline(interactive bool) int64 {
+	if !interactive {
+		return defaultSessionTimeoutSeconds
+	}
+	if v := os.Getenv("INTERACTIVE_SESSION_TIMEOUT_SECONDS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultInteractiveSessionTimeoutSeconds
+}
+
 var (
 	k8sClient              *kubernetes.Clientset
 	dynamicClient          dynamic.Interface
@@ -41,6 +64,7 @@ func main() {
 	if err := initK8sClients(); err != nil {
 		log.Fatalf("Failed to initialize Kubernetes clients: %v", err)
 	}
+	initEventRecorder()
 
 	// Get namespace from environment or use default
 	namespace = os.Getenv("NAMESPACE")
@@ -85,6 +109,41 @@ func main() {
 	// Start watching ProjectSettings resources
 	go watchProjectSettings()
 
+	// Remove admission policies left behind by deleted ProjectSettings
+	go pruneNamespaceAdmissionPolicies()
+
+	// Advance SessionPipelines as their step sessions complete
+	go watchSessionPipelines()
+
+	// Periodically delete sessions and artifacts past their retention
+	go runRetentionLoop()
+
+	// Retry sessions queued by concurrency limits
+	go runQueueLoop()
+
+	// Keep runner PodDisruptionBudgets in step with running sessions
+	go runDisruptionBudgetLoop()
+
+	// Evaluate runner canaries and roll back regressions
+	go runCanaryLoop()
+
+	// Forecast runner capacity for cluster autoscaling
+	go runCapacityLoop()
+
+	// Send batched email notifications for namespaces in digest mode
+	go runEmailDigestLoop()
+
+	// Start budget months on each namespace's resetDay
+	go runBudgetResetLoop()
+
+	// Deliver monthly usage reports to namespaces that opted in
+	go runUsageReportLoop()
+
+	startMetricsServer()
+
+	// OTLP trace export from the OTEL_* environment
+	initTracing("ambient-operator")
+
 	// Keep the operator running
 	select {}
 }
@@ -198,7 +257,7 @@ func watchAgenticSessions() {
 	}
 }
 
-func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
+func handleAgenticSessionEvent(obj *unstructured.Unstructured) (reconcileErr error) {
 	name := obj.GetName()
 	sessionNamespace := obj.GetNamespace()
 
@@ -226,19 +285,50 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 	if phase == "" {
 		_ = updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{"phase": "Pending"})
 		phase = "Pending"
+		notifySessionCreatedAsync(currentObj)
 	}
 
 	log.Printf("Processing AgenticSession %s with phase %s", name, phase)
 
+	span := startSessionSpan("reconcile AgenticSession", currentObj)
+	span.set("ambient.phase", phase)
+	defer func() {
+		if reconcileErr != nil {
+			span.errMsg = reconcileErr.Error()
+		}
+		span.end()
+	}()
+
+	openPendingPullRequests(currentObj)
+
+	// Finished sessions only need their result reported to integrations; dry
+	// runs have nothing to report
+	dryRun := sessionDryRun(currentObj)
+	if _, done := sessionFinishedAt(currentObj); done {
+		if dryRun {
+			return nil
+		}
+		reportSessionResult(currentObj)
+		refreshFinishedSessionBudget(currentObj)
+		scheduleSessionRetry(currentObj)
+		reconcilePipelineOfSession(currentObj)
+		if hasSessionScratch(currentObj) {
+			releaseFinishedSessionScratch(currentObj)
+		}
+		return nil
+	}
+
 	// Only process if status is Pending
 	if phase != "Pending" {
 		return nil
 	}
 
 	// Ensure a per-project workspace PVC exists for runner artifacts
-	if err := ensureProjectWorkspacePVC(sessionNamespace); err != nil {
-		log.Printf("Failed to ensure workspace PVC in %s: %v", sessionNamespace, err)
-		// Continue; job may still run with ephemeral storage
+	if !dryRun {
+		if err := ensureProjectWorkspacePVC(sessionNamespace); err != nil {
+			log.Printf("Failed to ensure workspace PVC in %s: %v", sessionNamespace, err)
+			// Continue; job may still run with ephemeral storage
+		}
 	}
 
 	// Create a Kubernetes Job for this AgenticSession
@@ -250,12 +340,37 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		log.Printf("Job %s already exists for AgenticSession %s", jobName, name)
 		return nil
 	}
+	if sessionPipelineRunExists(sessionNamespace, name) {
+		log.Printf("PipelineRun %s already exists for AgenticSession %s", pipelineRunName(name), name)
+		return nil
+	}
+
+	// Enforce namespace and per-framework concurrency/burst limits; queued sessions
+	// are retried by runQueueLoop. Dry runs never take a slot.
+	if !dryRun {
+		admissionMu.Lock()
+		defer admissionMu.Unlock()
+		admitted, err := admitSession(currentObj)
+		if err != nil {
+			log.Printf("Admission for AgenticSession %s/%s: %v", sessionNamespace, name, err)
+		}
+		if !admitted {
+			return err
+		}
+	}
+
+	// Dry runs collect policy outcomes in their plan instead of failing on them
+	var plan *sessionPlan
+	if dryRun {
+		plan = newSessionPlan(currentObj)
+	}
 
 	// Extract spec information from the fresh object
 	spec, _, _ := unstructured.NestedMap(currentObj.Object, "spec")
 	prompt, _, _ := unstructured.NestedString(spec, "prompt")
 	timeout, _, _ := unstructured.NestedInt64(spec, "timeout")
 	interactive, _, _ := unstructured.NestedBool(spec, "interactive")
+	summaryReport, _, _ := unstructured.NestedBool(spec, "summaryReport")
 
 	llmSettings, _, _ := unstructured.NestedMap(spec, "llmSettings")
 	model, _, _ := unstructured.NestedString(llmSettings, "model")
@@ -270,6 +385,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 	sshKeySecret, _, _ := unstructured.NestedString(gitConfig, "authentication", "sshKeySecret")
 	tokenSecret, _, _ := unstructured.NestedString(gitConfig, "authentication", "tokenSecret")
 	repositories, _, _ := unstructured.NestedSlice(gitConfig, "repositories")
+	pullRequests, _, _ := unstructured.NestedBool(gitConfig, "pullRequests")
 
 	// Marshal repositories to JSON string for runner env var
 	reposJSON := "[]"
@@ -281,19 +397,170 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		}
 	}
 
-	// Read runner secrets configuration from ProjectSettings in the session's namespace
+	// Read runner secrets configuration and session policy from ProjectSettings in the session's namespace
 	runnerSecretsName := ""
+	var maxTimeoutSeconds int64
+	var disruptionPolicy runnerDisruptionPolicy
+	var psObj *unstructured.Unstructured
+	psSpec := map[string]interface{}{}
 	{
 		psGvr := getProjectSettingsResource()
-		if psObj, err := dynamicClient.Resource(psGvr).Namespace(sessionNamespace).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
-			if psSpec, ok := psObj.Object["spec"].(map[string]interface{}); ok {
+		if obj, err := dynamicClient.Resource(psGvr).Namespace(sessionNamespace).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
+			psObj = obj
+			if spec, ok := psObj.Object["spec"].(map[string]interface{}); ok {
+				psSpec = spec
 				if v, ok := psSpec["runnerSecretsName"].(string); ok {
 					runnerSecretsName = strings.TrimSpace(v)
 				}
+				maxTimeoutSeconds, _, _ = unstructured.NestedInt64(psSpec, "sessionPolicy", "maxTimeoutSeconds")
+				disruptionPolicy = runnerDisruptionPolicyFromSpec(psSpec)
 			}
 		}
 	}
 
+	// The session timeout becomes the Job deadline, capped by the namespace
+	// policy; sessions that set none get the default for their kind
+	activeDeadlineSeconds := timeout
+	if activeDeadlineSeconds <= 0 {
+		activeDeadlineSeconds = defaultJobDeadline(interactive)
+	}
+	if maxTimeoutSeconds > 0 && activeDeadlineSeconds > maxTimeoutSeconds {
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonPolicyViolation, "Timeout %ds exceeds project maximum %ds; capping Job deadline", activeDeadlineSeconds, maxTimeoutSeconds)
+		plan.record("timeout", true, fmt.Sprintf("capped from %ds to the project maximum %ds", activeDeadlineSeconds, maxTimeoutSeconds))
+		activeDeadlineSeconds = maxTimeoutSeconds
+	}
+
+	// Organization-wide policy merged with the namespace's: blocked models and
+	// tools, and the monthly budget
+	clusterPol, err := loadClusterPolicy()
+	if err != nil {
+		log.Printf("Failed to load cluster policy for %s/%s: %v", sessionNamespace, name, err)
+		return err
+	}
+	if pattern, blocked := blockedModelPattern(model, mergedBlocklist(clusterPol.BlockedModels, psSpec, "blockedModels")); blocked && dryRun {
+		plan.record("model", false, fmt.Sprintf("Model %s is blocked by policy (%s)", model, pattern))
+	} else if blocked {
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonPolicyViolation, "Model %s is blocked by policy (%s)", model, pattern)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Model %s is blocked by policy (%s)", model, pattern),
+		})
+		return fmt.Errorf("model %s is blocked by policy", model)
+	}
+	if exceeded, err := refreshNamespaceBudget(sessionNamespace, psObj, clusterPol); err != nil {
+		log.Printf("Failed to check budget in %s: %v", sessionNamespace, err)
+	} else if exceeded && dryRun {
+		plan.record("budget", false, "The namespace's monthly budget is exhausted")
+	} else if exceeded {
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonBudgetExceeded, "Monthly budget of namespace %s is exhausted", sessionNamespace)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": "The namespace's monthly budget is exhausted",
+		})
+		return fmt.Errorf("monthly budget of %s is exhausted", sessionNamespace)
+	}
+	toolEnv := runnerToolPolicyFromSpec(clusterPol, psSpec).narrowedBy(spec).env()
+	debugKeepAlive := debugKeepAliveSeconds(spec, psSpec)
+	snapshotEnv := workspaceSnapshotPolicyFromSpec(psSpec).env()
+	traceEnv := runnerTraceEnv(span)
+
+	// Runner image and default resources come from the Framework registry
+	framework, err := resolveRunnerFramework(spec)
+	if err != nil {
+		log.Printf("Failed to resolve framework for %s/%s: %v", sessionNamespace, name, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to resolve runner framework: %v", err)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Failed to resolve runner framework: %v", err),
+		})
+		return fmt.Errorf("failed to resolve runner framework: %v", err)
+	}
+	if framework.Deprecated {
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonDeprecated, "Framework %s version %s is deprecated", framework.Name, framework.Version)
+	}
+	// Namespaces may mirror or pin the runner image; overridden images skip the canary
+	if override, ok := runnerImageOverrideFromSpec(psSpec, framework.Name); ok {
+		framework.Image = override.apply(framework.Image)
+	}
+
+	// Model provider credentials and settings from the namespace's providers
+	provider, err := resolveModelProvider(spec, psSpec, framework)
+	if err != nil {
+		log.Printf("Invalid model provider for %s/%s: %v", sessionNamespace, name, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonPolicyViolation, "Invalid model provider: %v", err)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Invalid model provider: %v", err),
+		})
+		return fmt.Errorf("invalid model provider: %v", err)
+	}
+
+	// Session resource overrides on top of the framework defaults, capped by policy
+	runnerResources, capped := sessionResources(framework.Resources, spec, psSpec)
+	if len(capped) > 0 {
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonPolicyViolation, "Resources exceed project maximum (%s); capping", strings.Join(capped, ", "))
+		plan.record("resources", true, "capped to the project maximum: "+strings.Join(capped, ", "))
+	}
+
+	// Node placement from the namespace policy and the session
+	scheduling, err := resolveRunnerScheduling(spec, psSpec)
+	if err != nil {
+		log.Printf("Invalid scheduling for %s/%s: %v", sessionNamespace, name, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonPolicyViolation, "Invalid runner scheduling: %v", err)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Invalid runner scheduling: %v", err),
+		})
+		return fmt.Errorf("invalid runner scheduling: %v", err)
+	}
+
+	// Optional per-session scratch PVC, created before the Job so the pod can bind it
+	scratch, scratchCapped, wantScratch := sessionScratchFromSpec(spec, psSpec)
+	if scratchCapped != "" {
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonPolicyViolation, "Scratch volume exceeds project maximum (%s); capping", scratchCapped)
+		plan.record("scratch", true, "capped to the project maximum "+scratchCapped)
+	}
+	if wantScratch && !dryRun {
+		if err := ensureSessionScratchPVC(currentObj, scratch); err != nil {
+			log.Printf("Failed to create scratch PVC for %s/%s: %v", sessionNamespace, name, err)
+			recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to create scratch volume: %v", err)
+			updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+				"phase":   "Error",
+				"message": fmt.Sprintf("Failed to create scratch volume: %v", err),
+			})
+			return fmt.Errorf("failed to create scratch PVC: %v", err)
+		}
+	}
+
+	// The runner authenticates to the backend as its own ServiceAccount
+	if !dryRun {
+		if err := ensureSessionServiceAccount(currentObj); err != nil {
+			log.Printf("Failed to create ServiceAccount for %s/%s: %v", sessionNamespace, name, err)
+			recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to create runner ServiceAccount: %v", err)
+			updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+				"phase":   "Error",
+				"message": fmt.Sprintf("Failed to create runner ServiceAccount: %v", err),
+			})
+			return fmt.Errorf("failed to create runner ServiceAccount: %v", err)
+		}
+	}
+
+	// With RUNNER_MTLS the runner also presents a client certificate of the session
+	if runnerMTLSEnabled() && !dryRun {
+		if err := ensureSessionClientCert(currentObj, runnerCertLifetime(activeDeadlineSeconds, maxTimeoutSeconds, debugKeepAlive, psSpec)); err != nil {
+			log.Printf("Failed to issue runner certificate for %s/%s: %v", sessionNamespace, name, err)
+			recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to issue runner certificate: %v", err)
+			updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+				"phase":   "Error",
+				"message": fmt.Sprintf("Failed to issue runner certificate: %v", err),
+			})
+			return fmt.Errorf("failed to issue runner certificate: %v", err)
+		}
+	}
+
+	// A configured share of sessions runs the canary runner image
+	runnerImage, runnerTrack := selectRunnerImage(currentObj, psObj, framework)
+
 	// Create the Job
 	job := &batchv1.Job{
 		ObjectMeta: v1.ObjectMeta{
@@ -317,12 +584,13 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		},
 		Spec: batchv1.JobSpec{
 			BackoffLimit:          int32Ptr(3),
-			ActiveDeadlineSeconds: int64Ptr(1800), // 30 minute timeout for safety
+			ActiveDeadlineSeconds: int64Ptr(activeDeadlineSeconds),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: v1.ObjectMeta{
 					Labels: map[string]string{
 						"agentic-session": name,
 						"app":             "ambient-code-runner",
+						runnerTrackLabel:  runnerTrack,
 					},
 					// If you run a service mesh that injects sidecars and causes egress issues for Jobs:
 					// Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
@@ -343,7 +611,8 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 							},
 						},
 					},
-					RestartPolicy: corev1.RestartPolicyNever,
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: runnerImagePullSecrets(psSpec),
 					Volumes: []corev1.Volume{
 						{
 							Name: "workspace",
@@ -358,7 +627,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 					Containers: []corev1.Container{
 						{
 							Name:            "ambient-code-runner",
-							Image:           ambientCodeRunnerImage,
+							Image:           runnerImage,
 							ImagePullPolicy: imagePullPolicy,
 							// 🔒 Container-level security (SCC-compatible, no privileged capabilities)
 							SecurityContext: &corev1.SecurityContext{
@@ -377,13 +646,14 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								base := []corev1.EnvVar{
 									{Name: "DEBUG", Value: "false"},
 									{Name: "INTERACTIVE", Value: fmt.Sprintf("%t", interactive)},
+									{Name: "GENERATE_SUMMARY_REPORT", Value: fmt.Sprintf("%t", summaryReport)},
 									{Name: "AGENTIC_SESSION_NAME", Value: name},
 									{Name: "AGENTIC_SESSION_NAMESPACE", Value: sessionNamespace},
 									{Name: "PROMPT", Value: prompt},
 									{Name: "LLM_MODEL", Value: model},
 									{Name: "LLM_TEMPERATURE", Value: fmt.Sprintf("%.2f", temperature)},
 									{Name: "LLM_MAX_TOKENS", Value: fmt.Sprintf("%d", maxTokens)},
-									{Name: "TIMEOUT", Value: fmt.Sprintf("%d", timeout)},
+									{Name: "TIMEOUT", Value: fmt.Sprintf("%d", activeDeadlineSeconds)},
 									{Name: "BACKEND_API_URL", Value: fmt.Sprintf("http://backend-service.%s.svc.cluster.local:8080/api", backendNamespace)},
 									{Name: "PVC_PROXY_API_URL", Value: fmt.Sprintf("http://ambient-content.%s.svc:8080", sessionNamespace)},
 									{Name: "WORKSPACE_STORE_PATH", Value: func() string {
@@ -403,27 +673,26 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 									{Name: "GIT_SSH_KEY_SECRET", Value: sshKeySecret},
 									{Name: "GIT_TOKEN_SECRET", Value: tokenSecret},
 									{Name: "GIT_REPOSITORIES", Value: reposJSON},
+									{Name: "GIT_PULL_REQUESTS", Value: fmt.Sprintf("%t", pullRequests)},
+									{Name: "BLOCKED_TOOLS", Value: toolEnv["BLOCKED_TOOLS"]},
+									{Name: "ALLOWED_TOOLS", Value: toolEnv["ALLOWED_TOOLS"]},
+									{Name: "MAX_TOOL_VIOLATIONS", Value: toolEnv["MAX_TOOL_VIOLATIONS"]},
+									{Name: "DEBUG_KEEP_ALIVE_SECONDS", Value: fmt.Sprintf("%d", debugKeepAlive)},
+									{Name: "WORKSPACE_SNAPSHOT", Value: snapshotEnv["WORKSPACE_SNAPSHOT"]},
+									{Name: "WORKSPACE_SNAPSHOT_MAX_BYTES", Value: snapshotEnv["WORKSPACE_SNAPSHOT_MAX_BYTES"]},
+									{Name: "WORKSPACE_SNAPSHOT_IGNORE", Value: snapshotEnv["WORKSPACE_SNAPSHOT_IGNORE"]},
 								}
-								// If backend annotated the session with a runner token secret, inject bot token envs without refetching the CR
-								if meta, ok := currentObj.Object["metadata"].(map[string]interface{}); ok {
-									if anns, ok := meta["annotations"].(map[string]interface{}); ok {
-										if v, ok := anns["ambient-code.io/runner-token-secret"].(string); ok && strings.TrimSpace(v) != "" {
-											secretName := strings.TrimSpace(v)
-											base = append(base, corev1.EnvVar{Name: "AUTH_MODE", Value: "bot_token"})
-											base = append(base, corev1.EnvVar{
-												Name: "BOT_TOKEN",
-												ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
-													LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
-													Key:                  "token",
-												}},
-											})
-										}
-									}
-								}
+								base = append(base, traceEnv...)
 								// Add CR-provided envs last (override base when same key)
 								if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
 									if envMap, ok := spec["environmentVariables"].(map[string]interface{}); ok {
 										for k, v := range envMap {
+											// Sessions cannot lift the tool policy, the debug keep-alive cap
+											// or the workspace snapshot policy
+											_, tools := toolEnv[k]
+											if _, policy := snapshotEnv[k]; policy || tools || k == "DEBUG_KEEP_ALIVE_SECONDS" {
+												continue
+											}
 											if vs, ok := v.(string); ok {
 												// replace if exists
 												replaced := false
@@ -455,7 +724,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 								return []corev1.EnvFromSource{}
 							}(),
 
-							Resources: corev1.ResourceRequirements{},
+							Resources: runnerResources,
 						},
 					},
 				},
@@ -463,6 +732,52 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		},
 	}
 
+	applySessionServiceAccount(&job.Spec.Template.Spec, name)
+	if runnerMTLSEnabled() {
+		applyRunnerMTLS(&job.Spec.Template.Spec, name)
+	}
+	if provider != nil {
+		provider.apply(&job.Spec.Template.Spec)
+	}
+	scheduling.apply(&job.Spec.Template.Spec)
+	job.Spec.Template.Spec.PriorityClassName = runnerPriorityClass(spec, psSpec)
+	if wantScratch {
+		mountSessionScratch(&job.Spec.Template.Spec, name)
+	}
+
+	// Check out the triggering repository before the runner starts
+	if checkout, ok := gitBootstrapForSession(spec, psSpec); ok {
+		applyGitBootstrap(&job.Spec.Template.Spec, runnerImage, checkout)
+		recordEvent(currentObj, corev1.EventTypeNormal, eventReasonGitBootstrap, "Checking out %s at %s", checkout.URL, checkout.Ref)
+	}
+
+	// PDB membership and topology spread per the namespace's disruption policy
+	applyRunnerDisruptionPolicy(&job.Spec.Template, disruptionPolicy, spec)
+
+	// Copy artifacts of earlier sessions declared in spec.inputs into the workspace
+	inputs, err := resolveSessionInputs(currentObj, sessionWorkspacePath(name, spec))
+	if err != nil {
+		log.Printf("Failed to resolve inputs for %s/%s: %v", sessionNamespace, name, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to resolve session inputs: %v", err)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Failed to resolve session inputs: %v", err),
+		})
+		return fmt.Errorf("failed to resolve session inputs: %v", err)
+	}
+	if len(inputs) > 0 {
+		initContainer, err := inputsInitContainer(runnerImage, inputs)
+		if err != nil {
+			return fmt.Errorf("failed to build inputs init container: %v", err)
+		}
+		job.Spec.Template.Spec.InitContainers = append(job.Spec.Template.Spec.InitContainers, initContainer)
+		refs := make([]string, 0, len(inputs))
+		for _, in := range inputs {
+			refs = append(refs, in.SessionRef)
+		}
+		recordEvent(currentObj, corev1.EventTypeNormal, eventReasonInputsMounted, "Mounting artifacts from %s", strings.Join(refs, ", "))
+	}
+
 	// If a runner secret is configured, mount it as a volume in addition to EnvFrom
 	if runnerSecretsName != "" {
 		job.Spec.Template.Spec.Volumes = append(job.Spec.Template.Spec.Volumes, corev1.Volume{
@@ -480,35 +795,117 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 		}
 	}
 
+	// Lease the provider key from an external secret manager when configured.
+	// Env entries take precedence over the runner secret's EnvFrom. Dry runs
+	// lease nothing.
+	var keyEnv *corev1.EnvVar
+	if !dryRun {
+		keyEnv, err = leaseProviderKey(currentObj)
+	}
+	if err != nil {
+		log.Printf("Failed to lease provider key for %s/%s: %v", sessionNamespace, name, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonCredentialFailed, "Failed to lease provider key: %v", err)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Failed to lease provider key: %v", err),
+		})
+		return fmt.Errorf("failed to lease provider key: %v", err)
+	}
+	if keyEnv != nil && len(job.Spec.Template.Spec.Containers) > 0 {
+		job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, *keyEnv)
+	}
+
+	// Egress restrictions from the namespace network policy. This runs last so the
+	// proxy settings reach every container. Dry runs only plan the proxy.
+	networkPolicy := sessionNetworkPolicyFromSpec(psSpec)
+	if dryRun {
+		_, err = addSessionEgressProxy(&job.Spec.Template.Spec, networkPolicy)
+	} else {
+		err = applySessionNetwork(currentObj, &job.Spec.Template.Spec, networkPolicy)
+	}
+	if err != nil {
+		log.Printf("Failed to apply network policy for %s/%s: %v", sessionNamespace, name, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to apply network policy: %v", err)
+		releaseProviderKey(sessionNamespace, name, currentObj)
+		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+			"phase":   "Error",
+			"message": fmt.Sprintf("Failed to apply network policy: %v", err),
+		})
+		return fmt.Errorf("failed to apply network policy: %v", err)
+	}
+
+	// The Job spec is the runner definition for every workload; Tekton clusters
+	// run it as a PipelineRun instead
+	workload := sessionWorkload(psSpec, framework)
+	workloadName := jobName
+	if workload == workloadPipelineRun {
+		workloadName = pipelineRunName(name)
+	}
+	if dryRun {
+		return completeSessionDryRun(currentObj, plan, job, workload, workloadName, runnerTrack)
+	}
+
 	// Update status to Creating before attempting job creation
-	if err := updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+	creatingMessage := "Creating Kubernetes job"
+	if workload == workloadPipelineRun {
+		creatingMessage = "Creating Tekton PipelineRun"
+	}
+	creating := map[string]interface{}{
 		"phase":   "Creating",
-		"message": "Creating Kubernetes job",
-	}); err != nil {
+		"message": creatingMessage,
+		"runner":  map[string]interface{}{"image": runnerImage, "track": runnerTrack},
+	}
+	if wantScratch {
+		creating["scratch"] = map[string]interface{}{"claimName": scratchPVCName(name), "size": scratch.Size.String()}
+	}
+	if err := updateAgenticSessionStatus(sessionNamespace, name, creating); err != nil {
 		log.Printf("Failed to update AgenticSession status to Creating: %v", err)
 		// Continue anyway - resource might have been deleted
 	}
 
 	// Create the job
-	_, err = k8sClient.BatchV1().Jobs(sessionNamespace).Create(context.TODO(), job, v1.CreateOptions{})
+	switch workload {
+	case workloadPipelineRun:
+		err = createSessionPipelineRun(job, workloadName)
+	default:
+		_, err = k8sClient.BatchV1().Jobs(sessionNamespace).Create(context.TODO(), job, v1.CreateOptions{})
+	}
 	if err != nil {
-		log.Printf("Failed to create job %s: %v", jobName, err)
+		log.Printf("Failed to create %s %s: %v", workload, workloadName, err)
+		recordEvent(currentObj, corev1.EventTypeWarning, eventReasonJobCreateFailed, "Failed to create %s %s: %v", workload, workloadName, err)
+		releaseProviderKey(sessionNamespace, name, currentObj)
 		// Update status to Error if job creation fails and resource still exists
 		updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
 			"phase":   "Error",
-			"message": fmt.Sprintf("Failed to create job: %v", err),
+			"message": fmt.Sprintf("Failed to create %s: %v", workload, err),
+		}, v1.Condition{
+			Type:    conditionJobCreated,
+			Status:  v1.ConditionFalse,
+			Reason:  workload + "CreateFailed",
+			Message: err.Error(),
 		})
-		return fmt.Errorf("failed to create job: %v", err)
+		return fmt.Errorf("failed to create %s: %v", workload, err)
 	}
 
-	log.Printf("Created job %s for AgenticSession %s", jobName, name)
+	log.Printf("Created %s %s for AgenticSession %s", workload, workloadName, name)
+	recordEvent(currentObj, corev1.EventTypeNormal, eventReasonJobCreated, "Created %s %s", workload, workloadName)
 
 	// Update AgenticSession status to Running
-	if err := updateAgenticSessionStatus(sessionNamespace, name, map[string]interface{}{
+	running := map[string]interface{}{
 		"phase":     "Running",
-		"message":   "Job created and running",
+		"message":   workload + " created and running",
 		"startTime": time.Now().Format(time.RFC3339),
-		"jobName":   jobName,
+	}
+	if workload == workloadPipelineRun {
+		running["pipelineRunName"] = workloadName
+	} else {
+		running["jobName"] = jobName
+	}
+	if err := updateAgenticSessionStatus(sessionNamespace, name, running, v1.Condition{
+		Type:    conditionJobCreated,
+		Status:  v1.ConditionTrue,
+		Reason:  workload + "Created",
+		Message: fmt.Sprintf("Created %s %s", workload, workloadName),
 	}); err != nil {
 		log.Printf("Failed to update AgenticSession status to Running: %v", err)
 		// Don't return error here - the job was created successfully
@@ -516,7 +913,11 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 	}
 
 	// Start monitoring the job
-	go monitorJob(jobName, name, sessionNamespace)
+	if workload == workloadPipelineRun {
+		go monitorPipelineRun(workloadName, name, sessionNamespace)
+	} else {
+		go monitorJob(jobName, name, sessionNamespace)
+	}
 
 	return nil
 }
@@ -633,9 +1034,11 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 
 		// First check if the AgenticSession still exists
 		gvr := getAgenticSessionResource()
-		if _, err := dynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{}); err != nil {
+		sessionObj, err := dynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{})
+		if err != nil {
 			if errors.IsNotFound(err) {
 				log.Printf("AgenticSession %s no longer exists, stopping job monitoring for %s", sessionName, jobName)
+				releaseProviderKey(sessionNamespace, sessionName, nil)
 				return
 			}
 			log.Printf("Error checking AgenticSession %s existence: %v", sessionName, err)
@@ -646,43 +1049,112 @@ func monitorJob(jobName, sessionName, sessionNamespace string) {
 		if err != nil {
 			if errors.IsNotFound(err) {
 				log.Printf("Job %s not found, stopping monitoring", jobName)
+				releaseProviderKey(sessionNamespace, sessionName, sessionObj)
 				return
 			}
 			log.Printf("Error getting job %s: %v", jobName, err)
 			continue
 		}
 
+		// Runner finished; its provider key lease is no longer needed
+		if job.Status.Succeeded > 0 {
+			releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+			return
+		}
+
+		stalledKilled := checkRunnerLiveness(sessionObj, "Job "+jobName, func() error {
+			policy := v1.DeletePropagationBackground
+			err := k8sClient.BatchV1().Jobs(sessionNamespace).Delete(context.TODO(), jobName, v1.DeleteOptions{PropagationPolicy: &policy})
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		})
+		if stalledKilled {
+			releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+			return
+		}
+
+		// Deadline exceeded: the Job controller has killed the runner
+		if jobHasFailedWithReason(job, batchv1.JobReasonDeadlineExceeded) {
+			var deadline int64
+			if job.Spec.ActiveDeadlineSeconds != nil {
+				deadline = *job.Spec.ActiveDeadlineSeconds
+			}
+			log.Printf("Job %s exceeded its deadline of %ds", jobName, deadline)
+			updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+				"phase":          "Failed",
+				"message":        fmt.Sprintf("Session timed out after %ds", deadline),
+				"completionTime": time.Now().Format(time.RFC3339),
+			}, v1.Condition{
+				Type:    conditionSucceeded,
+				Status:  v1.ConditionFalse,
+				Reason:  "Timeout",
+				Message: fmt.Sprintf("Job %s exceeded activeDeadlineSeconds=%d", jobName, deadline),
+			})
+			recordEvent(sessionObj, corev1.EventTypeWarning, eventReasonTimeout, "Session timed out after %ds", deadline)
+			releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+			return
+		}
+
 		if job.Status.Failed >= *job.Spec.BackoffLimit {
 			log.Printf("Job %s failed after %d attempts", jobName, job.Status.Failed)
+			recordEvent(sessionObj, corev1.EventTypeWarning, eventReasonJobFailed, "Job %s failed after %d attempts", jobName, job.Status.Failed)
 
 			// Get pod logs for error information
-			errorMessage := "Job failed"
-			if pods, err := k8sClient.CoreV1().Pods(sessionNamespace).List(context.TODO(), v1.ListOptions{
-				LabelSelector: fmt.Sprintf("job-name=%s", jobName),
-			}); err == nil && len(pods.Items) > 0 {
-				// Try to get logs from the first pod
-				pod := pods.Items[0]
-				if logs, err := k8sClient.CoreV1().Pods(sessionNamespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(context.TODO()); err == nil {
-					errorMessage = fmt.Sprintf("Job failed: %s", string(logs))
-					if len(errorMessage) > 500 {
-						errorMessage = errorMessage[:500] + "..."
-					}
-				}
-			}
+			errorMessage := runnerPodLogsMessage(sessionNamespace, fmt.Sprintf("job-name=%s", jobName), "Job failed")
 
 			// Update AgenticSession status to Failed
 			updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
 				"phase":          "Failed",
 				"message":        errorMessage,
 				"completionTime": time.Now().Format(time.RFC3339),
+			}, v1.Condition{
+				Type:    conditionSucceeded,
+				Status:  v1.ConditionFalse,
+				Reason:  "BackoffLimitExceeded",
+				Message: fmt.Sprintf("Job %s failed after %d attempts", jobName, job.Status.Failed),
 			})
+			releaseProviderKey(sessionNamespace, sessionName, sessionObj)
 			// OwnerReferences handle cleanup after failure
 			return
 		}
 	}
 }
 
-func updateAgenticSessionStatus(sessionNamespace, name string, statusUpdate map[string]interface{}) error {
+// runnerPodLogsMessage prefixes the logs of the first runner pod matching
+// selector with prefix, truncated for the session status message. It returns
+// prefix alone when no logs are available.
+func runnerPodLogsMessage(namespace, selector, prefix string) string {
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{LabelSelector: selector})
+	if err != nil || len(pods.Items) == 0 {
+		return prefix
+	}
+	// Try to get logs from the first pod
+	logs, err := k8sClient.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{}).DoRaw(context.TODO())
+	if err != nil {
+		return prefix
+	}
+	message := fmt.Sprintf("%s: %s", prefix, string(logs))
+	if len(message) > 500 {
+		message = message[:500] + "..."
+	}
+	return message
+}
+
+// jobHasFailedWithReason reports whether the Job carries a Failed=True condition with reason
+func jobHasFailedWithReason(job *batchv1.Job, reason string) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// updateAgenticSessionStatus merges statusUpdate and upserts conditions into the
+// session status. The write is skipped when nothing changed.
+func updateAgenticSessionStatus(sessionNamespace, name string, statusUpdate map[string]interface{}, conditions ...v1.Condition) error {
 	gvr := getAgenticSessionResource()
 
 	// Get current resource
@@ -701,9 +1173,20 @@ func updateAgenticSessionStatus(sessionNamespace, name string, statusUpdate map[
 	}
 
 	status := obj.Object["status"].(map[string]interface{})
+	before := statusSnapshot(status)
+	oldPhase, _ := status["phase"].(string)
 	for key, value := range statusUpdate {
+		// A nil value removes the field
+		if value == nil {
+			delete(status, key)
+			continue
+		}
 		status[key] = value
 	}
+	applyStatusConditions(status, obj.GetGeneration(), conditions...)
+	if bytes.Equal(before, statusSnapshot(status)) {
+		return nil
+	}
 
 	// Update the resource with retry logic
 	_, err = dynamicClient.Resource(gvr).Namespace(sessionNamespace).UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
@@ -714,6 +1197,17 @@ func updateAgenticSessionStatus(sessionNamespace, name string, statusUpdate map[
 		}
 		return fmt.Errorf("failed to update AgenticSession status: %v", err)
 	}
+	newPhase, _ := status["phase"].(string)
+	syncSessionIndexLabels(obj, newPhase)
+
+	if newPhase != oldPhase && newPhase != "" {
+		eventType := corev1.EventTypeNormal
+		if newPhase == "Failed" || newPhase == "Error" {
+			eventType = corev1.EventTypeWarning
+		}
+		msg, _ := status["message"].(string)
+		recordEvent(obj, eventType, eventReasonPhaseChanged, "Phase changed from %q to %q: %s", oldPhase, newPhase, msg)
+	}
 
 	return nil
 }
@@ -791,6 +1285,9 @@ func watchProjectSettings() {
 				settingsName := obj.GetName()
 				settingsNamespace := obj.GetNamespace()
 				log.Printf("ProjectSettings %s/%s deleted", settingsNamespace, settingsName)
+				if err := deleteNamespaceAdmissionPolicy(settingsNamespace); err != nil {
+					log.Printf("Error deleting admission policy for namespace %s: %v", settingsNamespace, err)
+				}
 			case watch.Error:
 				obj := event.Object.(*unstructured.Unstructured)
 				log.Printf("Watch error for ProjectSettings: %v", obj)
@@ -869,6 +1366,7 @@ func reconcileProjectSettings(obj *unstructured.Unstructured) error {
 
 	// Reconcile group access (RoleBindings)
 	groupBindingsCreated := 0
+	var bindingErrors []string
 	if groupAccess, found, _ := unstructured.NestedSlice(spec, "groupAccess"); found {
 		for _, accessInterface := range groupAccess {
 			access := accessInterface.(map[string]interface{})
@@ -877,6 +1375,8 @@ func reconcileProjectSettings(obj *unstructured.Unstructured) error {
 			if groupName != "" && role != "" {
 				if err := ensureRoleBinding(namespace, groupName, role); err != nil {
 					log.Printf("Error creating RoleBinding for group %s in namespace %s: %v", groupName, namespace, err)
+					recordEvent(obj, corev1.EventTypeWarning, eventReasonGroupBindingError, "Failed to bind group %s to role %s: %v", groupName, role, err)
+					bindingErrors = append(bindingErrors, fmt.Sprintf("%s: %v", groupName, err))
 					continue
 				}
 				groupBindingsCreated++
@@ -889,7 +1389,28 @@ func reconcileProjectSettings(obj *unstructured.Unstructured) error {
 		"groupBindingsCreated": groupBindingsCreated,
 	}
 
-	return updateProjectSettingsStatus(namespace, name, statusUpdate)
+	reconciled := v1.Condition{
+		Type:    conditionReconciled,
+		Status:  v1.ConditionTrue,
+		Reason:  "GroupBindingsReady",
+		Message: fmt.Sprintf("%d group RoleBindings in place", groupBindingsCreated),
+	}
+	if len(bindingErrors) > 0 {
+		reconciled.Status = v1.ConditionFalse
+		reconciled.Reason = "GroupBindingFailed"
+		reconciled.Message = strings.Join(bindingErrors, "; ")
+	}
+
+	if err := syncRunnerPDB(namespace, runnerDisruptionPolicyFromSpec(spec)); err != nil {
+		log.Printf("Error syncing runner PDB in namespace %s: %v", namespace, err)
+	}
+
+	if err := syncNamespaceAdmissionPolicy(namespace, sessionAdmissionConstraintsFromSpec(spec)); err != nil {
+		log.Printf("Error syncing admission policy for namespace %s: %v", namespace, err)
+		recordEvent(obj, corev1.EventTypeWarning, eventReasonAdmissionPolicyError, "Failed to generate the namespace admission policy: %v", err)
+	}
+
+	return updateProjectSettingsStatus(namespace, name, statusUpdate, reconciled)
 }
 
 // Bot ServiceAccounts are no longer managed here; access keys handle authentication.
@@ -955,7 +1476,10 @@ func mapRoleToKubernetesRole(role string) string {
 	}
 }
 
-func updateProjectSettingsStatus(namespace, name string, statusUpdate map[string]interface{}) error {
+// updateProjectSettingsStatus merges statusUpdate and upserts conditions into the
+// ProjectSettings status. The write is skipped when nothing changed so that the
+// resulting watch event does not trigger another status write.
+func updateProjectSettingsStatus(namespace, name string, statusUpdate map[string]interface{}, conditions ...v1.Condition) error {
 	gvr := getProjectSettingsResource()
 
 	// Get current resource
@@ -974,9 +1498,14 @@ func updateProjectSettingsStatus(namespace, name string, statusUpdate map[string
 	}
 
 	status := obj.Object["status"].(map[string]interface{})
+	before := statusSnapshot(status)
 	for key, value := range statusUpdate {
 		status[key] = value
 	}
+	applyStatusConditions(status, obj.GetGeneration(), conditions...)
+	if bytes.Equal(before, statusSnapshot(status)) {
+		return nil
+	}
 
 	// Update the resource
 	_, err = dynamicClient.Resource(gvr).Namespace(namespace).UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+// startMetricsServer exposes operator counters in Prometheus text format on
+// METRICS_ADDR (default :8080), plus the capacity recommendation on /capacity.
+func startMetricsServer() {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeCounter(w, "ambient_retention_runs_total", "Retention cleanup passes executed", retentionRunsTotal.Load())
+		writeCounter(w, "ambient_retention_sessions_deleted_total", "AgenticSessions deleted by retention", retentionSessionsDeletedTotal.Load())
+		writeCounter(w, "ambient_retention_jobs_deleted_total", "Runner Jobs deleted by retention", retentionJobsDeletedTotal.Load())
+		writeCounter(w, "ambient_retention_artifacts_deleted_total", "Session artifacts deleted by retention", retentionArtifactsDeletedTotal.Load())
+		writeCounter(w, "ambient_retention_artifact_bytes_reclaimed_total", "Bytes of session artifacts deleted by retention", retentionArtifactBytesReclaimedTotal.Load())
+		writeCounter(w, "ambient_notifications_sent_total", "Notifications delivered to webhooks", notificationsSentTotal.Load())
+		writeCounter(w, "ambient_notifications_failed_total", "Notifications that failed after retries", notificationsFailedTotal.Load())
+		writeCapacityMetrics(w)
+	})
+	mux.HandleFunc("/capacity", serveCapacity)
+	go func() {
+		log.Printf("Metrics listening on %s", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+}
+
+func writeCounter(w http.ResponseWriter, name, help string, v int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+}
+
+func writeGauge(w http.ResponseWriter, name, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, v)
+}
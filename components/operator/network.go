@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	networkEgressUnrestricted = "unrestricted"
+	networkEgressRestricted   = "restricted"
+	networkEgressNone         = "none"
+
+	egressProxyContainerName = "egress-proxy"
+	defaultEgressProxyPort   = 3128
+	eventReasonNetworkPolicy = "NetworkPolicy"
+)
+
+// sessionNetworkPolicy mirrors ProjectSettings spec.network
+type sessionNetworkPolicy struct {
+	// Egress is unrestricted (default, no NetworkPolicy), restricted or none
+	Egress string
+	// AllowedCIDRs are reachable on any port in restricted mode
+	AllowedCIDRs []string
+	// AllowedDomains are enforced by the egress proxy sidecar; NetworkPolicy
+	// cannot match host names
+	AllowedDomains []string
+}
+
+func sessionNetworkPolicyFromSpec(psSpec map[string]interface{}) sessionNetworkPolicy {
+	p := sessionNetworkPolicy{Egress: networkEgressUnrestricted}
+	if v, _, _ := unstructured.NestedString(psSpec, "network", "egress"); v != "" {
+		p.Egress = v
+	}
+	p.AllowedCIDRs, _, _ = unstructured.NestedStringSlice(psSpec, "network", "allowedCIDRs")
+	p.AllowedDomains, _, _ = unstructured.NestedStringSlice(psSpec, "network", "allowedDomains")
+	return p
+}
+
+// egressProxy is the cluster's domain-allowlisting proxy, configured on the
+// operator Deployment. The image must read ALLOWED_DOMAINS (comma separated,
+// *.example.com wildcards) and listen on PORT.
+func egressProxy() (string, int) {
+	port := defaultEgressProxyPort
+	if v, err := strconv.Atoi(os.Getenv("EGRESS_PROXY_PORT")); err == nil && v > 0 {
+		port = v
+	}
+	return os.Getenv("EGRESS_PROXY_IMAGE"), port
+}
+
+func sessionNetworkPolicyName(session string) string {
+	return session + "-egress"
+}
+
+// ensureSessionNetworkPolicy creates the egress NetworkPolicy for a runner pod.
+// Every mode keeps DNS, the backend API and the namespace content service
+// reachable. With allowed domains, HTTP(S) egress is opened for the proxy sidecar,
+// which is what enforces the domain list.
+func ensureSessionNetworkPolicy(session *unstructured.Unstructured, p sessionNetworkPolicy, proxied bool) error {
+	ns, name := session.GetNamespace(), session.GetName()
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	port := func(proto *corev1.Protocol, n int) networkingv1.NetworkPolicyPort {
+		v := intstr.FromInt(n)
+		return networkingv1.NetworkPolicyPort{Protocol: proto, Port: &v}
+	}
+
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		// Cluster DNS
+		{Ports: []networkingv1.NetworkPolicyPort{port(&udp, 53), port(&tcp, 53)}},
+		// Backend API for status updates and messages
+		{To: []networkingv1.NetworkPolicyPeer{{
+			NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": backendNamespace}},
+			PodSelector:       &v1.LabelSelector{MatchLabels: map[string]string{"app": "backend-api"}},
+		}}},
+		// Namespace content service for workspace sync
+		{To: []networkingv1.NetworkPolicyPeer{{
+			PodSelector: &v1.LabelSelector{MatchLabels: map[string]string{"app": "ambient-content"}},
+		}}},
+	}
+	if p.Egress == networkEgressRestricted {
+		for _, cidr := range p.AllowedCIDRs {
+			rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+				To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}},
+			})
+		}
+		if proxied {
+			rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+				Ports: []networkingv1.NetworkPolicyPort{port(&tcp, 80), port(&tcp, 443)},
+			})
+		}
+	}
+
+	np := &networkingv1.NetworkPolicy{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      sessionNetworkPolicyName(name),
+			Namespace: ns,
+			Labels:    map[string]string{"app": "ambient-code-runner", "agentic-session": name},
+			OwnerReferences: []v1.OwnerReference{{
+				APIVersion: "vteam.ambient-code/v1",
+				Kind:       "AgenticSession",
+				Name:       name,
+				UID:        session.GetUID(),
+				Controller: boolPtr(true),
+			}},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: v1.LabelSelector{MatchLabels: map[string]string{"agentic-session": name}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      rules,
+		},
+	}
+	_, err := k8sClient.NetworkingV1().NetworkPolicies(ns).Create(context.TODO(), np, v1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		existing, gerr := k8sClient.NetworkingV1().NetworkPolicies(ns).Get(context.TODO(), np.Name, v1.GetOptions{})
+		if gerr != nil {
+			return gerr
+		}
+		existing.Spec = np.Spec
+		_, err = k8sClient.NetworkingV1().NetworkPolicies(ns).Update(context.TODO(), existing, v1.UpdateOptions{})
+	}
+	return err
+}
+
+// applySessionNetwork creates the NetworkPolicy for restricted namespaces and adds
+// the egress proxy sidecar when domains are allowlisted. An error means the policy
+// cannot be honoured and the session must not start.
+func applySessionNetwork(session *unstructured.Unstructured, pod *corev1.PodSpec, p sessionNetworkPolicy) error {
+	if p.Egress == networkEgressUnrestricted {
+		return nil
+	}
+	proxied, err := addSessionEgressProxy(pod, p)
+	if err != nil {
+		return err
+	}
+	if err := ensureSessionNetworkPolicy(session, p, proxied); err != nil {
+		return err
+	}
+	recordEvent(session, corev1.EventTypeNormal, eventReasonNetworkPolicy, "Egress %s (%d CIDRs, %d domains)", p.Egress, len(p.AllowedCIDRs), len(p.AllowedDomains))
+	return nil
+}
+
+// addSessionEgressProxy adds the egress proxy sidecar when the policy allowlists
+// domains, without creating the NetworkPolicy; dry runs plan the pod with it
+func addSessionEgressProxy(pod *corev1.PodSpec, p sessionNetworkPolicy) (bool, error) {
+	if p.Egress != networkEgressRestricted || len(p.AllowedDomains) == 0 {
+		return false, nil
+	}
+	image, port := egressProxy()
+	if image == "" {
+		return false, fmt.Errorf("network.allowedDomains requires EGRESS_PROXY_IMAGE on the operator")
+	}
+	addEgressProxySidecar(pod, image, port, p.AllowedDomains)
+	return true, nil
+}
+
+// addEgressProxySidecar runs the proxy as a native sidecar (an init container that
+// keeps running) so it starts before the checkout and inputs init containers and
+// does not hold the Job open after the runner exits. The other containers' HTTP
+// clients are pointed at it; in-cluster traffic bypasses the proxy.
+func addEgressProxySidecar(pod *corev1.PodSpec, image string, port int, domains []string) {
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	noProxy := "localhost,127.0.0.1,.svc,.svc.cluster.local,.cluster.local"
+	proxyEnv := []corev1.EnvVar{
+		{Name: "HTTP_PROXY", Value: proxyURL},
+		{Name: "HTTPS_PROXY", Value: proxyURL},
+		{Name: "http_proxy", Value: proxyURL},
+		{Name: "https_proxy", Value: proxyURL},
+		{Name: "NO_PROXY", Value: noProxy},
+		{Name: "no_proxy", Value: noProxy},
+	}
+	for i := range pod.InitContainers {
+		pod.InitContainers[i].Env = append(pod.InitContainers[i].Env, proxyEnv...)
+	}
+	for i := range pod.Containers {
+		pod.Containers[i].Env = append(pod.Containers[i].Env, proxyEnv...)
+	}
+
+	always := corev1.ContainerRestartPolicyAlways
+	sidecar := corev1.Container{
+		Name:            egressProxyContainerName,
+		Image:           image,
+		ImagePullPolicy: imagePullPolicy,
+		RestartPolicy:   &always,
+		Env: []corev1.EnvVar{
+			{Name: "ALLOWED_DOMAINS", Value: strings.Join(domains, ",")},
+			{Name: "PORT", Value: strconv.Itoa(port)},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+	}
+	pod.InitContainers = append([]corev1.Container{sidecar}, pod.InitContainers...)
+}
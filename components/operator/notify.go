@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Notification events configurable in ProjectSettings spec.notifications
+const (
+	notifySessionCreated   = "session.created"
+	notifySessionCompleted = "session.completed"
+	notifySessionFailed    = "session.failed"
+	notifyBudgetWarning    = "budget.warning"
+	notifyBudgetExceeded   = "budget.exceeded"
+	notifyUsageReport      = "report.usage"
+
+	eventReasonNotificationFailed = "NotificationFailed"
+	notifyMaxAttempts             = 4
+)
+
+var (
+	notificationsSentTotal   atomic.Int64
+	notificationsFailedTotal atomic.Int64
+)
+
+// notificationsReporter delivers session.completed and session.failed through the
+// session reporting path, so each finished session notifies exactly once.
+var notificationsReporter = sessionReporter{
+	name: "notifications",
+	// Endpoints are retried individually in deliverWithRetry
+	attempts: 1,
+	applies: func(session *unstructured.Unstructured, psSpec map[string]interface{}) bool {
+		phase, _, _ := unstructured.NestedString(session.Object, "status", "phase")
+		event := sessionNotifyEvent(phase)
+		return len(notificationWebhooksFor(psSpec, event)) > 0 || len(emailRecipientsFor(psSpec, event)) > 0
+	},
+	report: func(session *unstructured.Unstructured, psSpec map[string]interface{}, summary sessionSummary) (string, error) {
+		n := sessionNotification(sessionNotifyEvent(summary.Phase), session, summary)
+		if failed := dispatchNotification(session.GetNamespace(), psSpec, n); failed > 0 {
+			return "", fmt.Errorf("%d notification endpoint(s) failed", failed)
+		}
+		return "", nil
+	},
+}
+
+func sessionNotifyEvent(phase string) string {
+	if phase == "Completed" {
+		return notifySessionCompleted
+	}
+	return notifySessionFailed
+}
+
+// notification is the JSON body sent to generic HTTP endpoints
+type notification struct {
+	Event      string                 `json:"event"`
+	Namespace  string                 `json:"namespace"`
+	Session    string                 `json:"session,omitempty"`
+	Display    string                 `json:"displayName,omitempty"`
+	Phase      string                 `json:"phase,omitempty"`
+	Summary    string                 `json:"summary,omitempty"`
+	CostUSD    float64                `json:"costUsd,omitempty"`
+	SessionURL string                 `json:"sessionUrl,omitempty"`
+	Artifacts  []string               `json:"artifacts,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	Timestamp  string                 `json:"timestamp"`
+}
+
+func sessionNotification(event string, session *unstructured.Unstructured, s sessionSummary) notification {
+	n := notification{
+		Event:      event,
+		Namespace:  session.GetNamespace(),
+		Session:    session.GetName(),
+		Display:    s.Display,
+		Phase:      s.Phase,
+		Summary:    s.Summary,
+		CostUSD:    s.CostUSD,
+		SessionURL: s.SessionURL,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, a := range s.Artifacts {
+		n.Artifacts = append(n.Artifacts, a.URL)
+	}
+	return n
+}
+
+// notificationWebhook mirrors one spec.notifications.webhooks entry
+type notificationWebhook struct {
+	Name string
+	URL  string
+	// Type is "slack" or "teams" (incoming webhooks) or "http" (JSON POST, the default)
+	Type string
+	// SecretName/SecretKey select an HMAC key; requests then carry
+	// X-Ambient-Signature: sha256=<hex hmac of the body>
+	SecretName string
+	SecretKey  string
+}
+
+func subscribed(raw map[string]interface{}, event string) bool {
+	events, found, _ := unstructured.NestedStringSlice(raw, "events")
+	if !found || len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event || (strings.HasSuffix(e, ".*") && strings.HasPrefix(event, strings.TrimSuffix(e, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationWebhooksFor returns the webhooks subscribed to event
+func notificationWebhooksFor(psSpec map[string]interface{}, event string) []notificationWebhook {
+	raw, _, _ := unstructured.NestedSlice(psSpec, "notifications", "webhooks")
+	out := []notificationWebhook{}
+	for i, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok || !subscribed(m, event) {
+			continue
+		}
+		w := notificationWebhook{Type: "http"}
+		w.URL, _ = m["url"].(string)
+		if w.URL == "" {
+			continue
+		}
+		w.Name, _ = m["name"].(string)
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("webhooks[%d]", i)
+		}
+		if t, _ := m["type"].(string); t != "" {
+			w.Type = t
+		}
+		w.SecretName, _, _ = unstructured.NestedString(m, "secretRef", "name")
+		w.SecretKey, _, _ = unstructured.NestedString(m, "secretRef", "key")
+		out = append(out, w)
+	}
+	return out
+}
+
+// notifyProject sends a project-level event such as budget.warning. It runs in
+// the background and never blocks the caller.
+func notifyProject(ns, event string, details map[string]interface{}) {
+	go func() {
+		psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+		if err != nil {
+			return
+		}
+		psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+		dispatchNotification(ns, psSpec, notification{
+			Event:     event,
+			Namespace: ns,
+			Details:   details,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}()
+}
+
+// notifySessionCreatedAsync sends session.created for a session the operator has just picked up
+func notifySessionCreatedAsync(session *unstructured.Unstructured) {
+	go func() {
+		psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(session.GetNamespace()).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+		if err != nil {
+			return
+		}
+		psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+		if len(notificationWebhooksFor(psSpec, notifySessionCreated)) == 0 && len(emailRecipientsFor(psSpec, notifySessionCreated)) == 0 {
+			return
+		}
+		n := sessionNotification(notifySessionCreated, session, buildSessionSummary(session))
+		dispatchNotification(session.GetNamespace(), psSpec, n)
+	}()
+}
+
+// dispatchNotification delivers n to every subscribed channel and returns the
+// number of endpoints that still failed after retries. The latest failure is
+// recorded in ProjectSettings status.notifications.
+func dispatchNotification(ns string, psSpec map[string]interface{}, n notification) int {
+	failed := 0
+	for _, w := range notificationWebhooksFor(psSpec, n.Event) {
+		if err := deliverWithRetry(func() error { return deliverWebhook(ns, w, n) }); err != nil {
+			failed++
+			recordNotificationFailure(ns, w.Name, n.Event, err)
+		} else {
+			notificationsSentTotal.Add(1)
+		}
+	}
+	return failed + enqueueEmailNotification(ns, psSpec, n)
+}
+
+func deliverWithRetry(send func() error) error {
+	var err error
+	for attempt := 1; attempt <= notifyMaxAttempts; attempt++ {
+		if err = send(); err == nil {
+			return nil
+		}
+		if attempt < notifyMaxAttempts {
+			time.Sleep(time.Duration(1<<attempt) * time.Second)
+		}
+	}
+	return err
+}
+
+func recordNotificationFailure(ns, channel, event string, err error) {
+	notificationsFailedTotal.Add(1)
+	log.Printf("Notification %s to %s in %s failed: %v", event, channel, ns, err)
+	psObj, gerr := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if gerr != nil {
+		return
+	}
+	recordEvent(psObj, corev1.EventTypeWarning, eventReasonNotificationFailed, "Notification %s to %s failed: %v", event, channel, err)
+	total, _, _ := unstructured.NestedInt64(psObj.Object, "status", "notifications", "failedTotal")
+	mergeNotificationsStatus(ns, psObj, map[string]interface{}{
+		"failedTotal": total + 1,
+		"lastFailure": map[string]interface{}{
+			"channel": channel,
+			"event":   event,
+			"message": err.Error(),
+			"at":      time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// mergeNotificationsStatus sets fields of status.notifications, keeping the others
+func mergeNotificationsStatus(ns string, psObj *unstructured.Unstructured, fields map[string]interface{}) {
+	current, _, _ := unstructured.NestedMap(psObj.Object, "status", "notifications")
+	if current == nil {
+		current = map[string]interface{}{}
+	}
+	for k, v := range fields {
+		current[k] = v
+	}
+	if err := updateProjectSettingsStatus(ns, psObj.GetName(), map[string]interface{}{"notifications": current}); err != nil {
+		log.Printf("Failed to update notification status in %s: %v", ns, err)
+	}
+}
+
+func deliverWebhook(ns string, w notificationWebhook, n notification) error {
+	var body []byte
+	switch w.Type {
+	case "slack":
+		body, _ = json.Marshal(map[string]string{"text": n.slackText()})
+	case "teams":
+		body, _ = json.Marshal(n.teamsMessage())
+	default:
+		body, _ = json.Marshal(n)
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Ambient-Event", n.Event)
+	if w.SecretName != "" && w.SecretKey != "" {
+		sec, err := k8sClient.CoreV1().Secrets(ns).Get(context.TODO(), w.SecretName, v1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("read signing secret %s: %v", w.SecretName, err)
+		}
+		mac := hmac.New(sha256.New, sec.Data[w.SecretKey])
+		mac.Write(body)
+		req.Header.Set("X-Ambient-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := reportHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// subject is a one-line description of the notification
+func (n notification) subject() string {
+	switch n.Event {
+	case notifySessionCreated:
+		return fmt.Sprintf("Session %s started in %s", n.Display, n.Namespace)
+	case notifySessionCompleted:
+		return fmt.Sprintf("Session %s completed in %s", n.Display, n.Namespace)
+	case notifySessionFailed:
+		return fmt.Sprintf("Session %s %s in %s", n.Display, strings.ToLower(n.Phase), n.Namespace)
+	case notifyBudgetWarning:
+		return fmt.Sprintf("Budget warning in %s", n.Namespace)
+	case notifyBudgetExceeded:
+		return fmt.Sprintf("Budget exceeded in %s", n.Namespace)
+	case notifyUsageReport:
+		return fmt.Sprintf("Usage report for %v in %s", n.Details["month"], n.Namespace)
+	}
+	return fmt.Sprintf("%s in %s", n.Event, n.Namespace)
+}
+
+// slackText renders n in Slack mrkdwn
+func (n notification) slackText() string {
+	var b strings.Builder
+	icon := ":information_source:"
+	switch n.Event {
+	case notifySessionCompleted:
+		icon = ":white_check_mark:"
+	case notifySessionFailed, notifyBudgetExceeded:
+		icon = ":x:"
+	case notifyBudgetWarning:
+		icon = ":warning:"
+	case notifyUsageReport:
+		icon = ":bar_chart:"
+	}
+	fmt.Fprintf(&b, "%s *%s*", icon, n.subject())
+	if n.Summary != "" {
+		summary := n.Summary
+		if len(summary) > 500 {
+			summary = summary[:500] + "…"
+		}
+		fmt.Fprintf(&b, "\n>%s", strings.ReplaceAll(summary, "\n", "\n>"))
+	}
+	for k, v := range n.Details {
+		fmt.Fprintf(&b, "\n• %s: %v", k, v)
+	}
+	if n.CostUSD > 0 {
+		fmt.Fprintf(&b, "\nCost: $%.2f", n.CostUSD)
+	}
+	if n.SessionURL != "" {
+		fmt.Fprintf(&b, "\n<%s|View session>", n.SessionURL)
+	}
+	return b.String()
+}
+
+// teamsMessage renders n as an Adaptive Card message, which both Teams
+// incoming webhook connectors and Workflows webhooks accept
+func (n notification) teamsMessage() map[string]interface{} {
+	color := "Default"
+	switch n.Event {
+	case notifySessionCompleted:
+		color = "Good"
+	case notifySessionFailed, notifyBudgetExceeded:
+		color = "Attention"
+	case notifyBudgetWarning:
+		color = "Warning"
+	}
+	body := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "text": n.subject(), "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+	}
+	if n.Summary != "" {
+		summary := n.Summary
+		if len(summary) > 500 {
+			summary = summary[:500] + "…"
+		}
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": summary, "wrap": true})
+	}
+	keys := make([]string, 0, len(n.Details))
+	for k := range n.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	facts := []interface{}{}
+	for _, k := range keys {
+		facts = append(facts, map[string]interface{}{"title": k, "value": fmt.Sprint(n.Details[k])})
+	}
+	if n.CostUSD > 0 {
+		facts = append(facts, map[string]interface{}{"title": "Cost", "value": fmt.Sprintf("$%.2f", n.CostUSD)})
+	}
+	if len(facts) > 0 {
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if n.SessionURL != "" {
+		card["actions"] = []interface{}{
+			map[string]interface{}{"type": "Action.OpenUrl", "title": "View session", "url": n.SessionURL},
+		}
+	}
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// pipelineLabel and pipelineStepLabel mark the sessions a SessionPipeline created
+	pipelineLabel       = "ambient-code.io/pipeline"
+	pipelineStepLabel   = "ambient-code.io/pipeline-step"
+	eventReasonPipeline = "PipelineStep"
+)
+
+// pipelineMu serializes reconciles; they run from the pipeline watch and from
+// session completion
+var pipelineMu sync.Mutex
+
+// getSessionPipelineResource returns the GroupVersionResource for SessionPipeline
+func getSessionPipelineResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "sessionpipelines",
+	}
+}
+
+// pipelineStep mirrors one entry of SessionPipeline spec.steps
+type pipelineStep struct {
+	Name      string
+	DependsOn []string
+	Inputs    []map[string]interface{}
+	Template  map[string]interface{}
+}
+
+// pipelineStepStatus is one entry of SessionPipeline status.steps
+type pipelineStepStatus struct {
+	Name    string
+	Phase   string
+	Session string
+	Message string
+}
+
+func (s pipelineStepStatus) toMap() map[string]interface{} {
+	m := map[string]interface{}{"name": s.Name, "phase": s.Phase}
+	if s.Session != "" {
+		m["session"] = s.Session
+	}
+	if s.Message != "" {
+		m["message"] = s.Message
+	}
+	return m
+}
+
+// pipelineSteps parses spec.steps and returns them in dependency order. It
+// rejects duplicate names, unknown dependencies, inputs from steps not listed
+// in dependsOn, and cycles.
+func pipelineSteps(spec map[string]interface{}) ([]pipelineStep, error) {
+	raw, _, _ := unstructured.NestedSlice(spec, "steps")
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("spec.steps is empty")
+	}
+	byName := map[string]pipelineStep{}
+	order := []string{}
+	for i, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("steps[%d] is not an object", i)
+		}
+		step := pipelineStep{}
+		step.Name, _ = m["name"].(string)
+		if step.Name == "" {
+			return nil, fmt.Errorf("steps[%d].name is required", i)
+		}
+		if _, dup := byName[step.Name]; dup {
+			return nil, fmt.Errorf("step %q is defined twice", step.Name)
+		}
+		step.DependsOn, _, _ = unstructured.NestedStringSlice(m, "dependsOn")
+		step.Template, _, _ = unstructured.NestedMap(m, "template")
+		if inputs, ok := m["inputs"].([]interface{}); ok {
+			for _, in := range inputs {
+				if im, ok := in.(map[string]interface{}); ok {
+					step.Inputs = append(step.Inputs, im)
+				}
+			}
+		}
+		byName[step.Name] = step
+		order = append(order, step.Name)
+	}
+
+	indegree := map[string]int{}
+	dependents := map[string][]string{}
+	for _, name := range order {
+		step := byName[name]
+		for _, dep := range step.DependsOn {
+			if _, ok := byName[dep]; !ok || dep == name {
+				return nil, fmt.Errorf("step %q depends on unknown step %q", name, dep)
+			}
+			indegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+		for _, in := range step.Inputs {
+			from, _ := in["step"].(string)
+			if !slices.Contains(step.DependsOn, from) {
+				return nil, fmt.Errorf("step %q takes inputs from %q, which is not in its dependsOn", name, from)
+			}
+		}
+	}
+
+	// Kahn's algorithm, keeping spec order among ready steps
+	sorted := make([]pipelineStep, 0, len(order))
+	ready := []string{}
+	for _, name := range order {
+		if indegree[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, byName[name])
+		for _, d := range dependents[name] {
+			if indegree[d]--; indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(sorted) != len(order) {
+		return nil, fmt.Errorf("steps contain a dependency cycle")
+	}
+	return sorted, nil
+}
+
+// pipelineSessionName is the session created for a step
+func pipelineSessionName(pipeline, step string) string {
+	return fmt.Sprintf("%s-%s", pipeline, step)
+}
+
+// stepPhase maps the latest session of a step (the original or its newest
+// retry) to a step phase. A failure with a retry scheduled is still running.
+func stepPhase(session *unstructured.Unstructured) (string, string) {
+	phase, _, _ := unstructured.NestedString(session.Object, "status", "phase")
+	message, _, _ := unstructured.NestedString(session.Object, "status", "message")
+	switch phase {
+	case "Completed":
+		return "Succeeded", ""
+	case "Failed":
+		next, _, _ := unstructured.NestedString(session.Object, "status", "retry", "nextAttemptTime")
+		if retried, _, _ := unstructured.NestedString(session.Object, "status", "retry", "session"); next != "" && retried == "" {
+			return "Running", "retry scheduled for " + next
+		}
+		return "Failed", message
+	case "Error", "Stopped":
+		return "Failed", message
+	}
+	return "Running", ""
+}
+
+// reconcileSessionPipeline creates the sessions of steps whose dependencies
+// have completed, skips steps downstream of a failure and aggregates status.
+func reconcileSessionPipeline(obj *unstructured.Unstructured) error {
+	pipelineMu.Lock()
+	defer pipelineMu.Unlock()
+
+	gvr := getSessionPipelineResource()
+	ns, name := obj.GetNamespace(), obj.GetName()
+	pipeline, err := dynamicClient.Resource(gvr).Namespace(ns).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	status, _, _ := unstructured.NestedMap(pipeline.Object, "status")
+	if status == nil {
+		status = map[string]interface{}{}
+	}
+	if done, _ := status["completionTime"].(string); done != "" {
+		return nil
+	}
+	if _, ok := status["startTime"]; !ok {
+		status["startTime"] = now
+	}
+
+	spec, _, _ := unstructured.NestedMap(pipeline.Object, "spec")
+	steps, err := pipelineSteps(spec)
+	if err != nil {
+		status["phase"] = "Failed"
+		status["message"] = err.Error()
+		status["completionTime"] = now
+		return updateSessionPipelineStatus(pipeline, status)
+	}
+
+	list, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", pipelineLabel, name),
+	})
+	if err != nil {
+		return fmt.Errorf("list sessions: %v", err)
+	}
+	// Retries carry the step label; the newest attempt decides the step
+	latest := map[string]*unstructured.Unstructured{}
+	for i := range list.Items {
+		s := &list.Items[i]
+		step := s.GetLabels()[pipelineStepLabel]
+		if cur, ok := latest[step]; ok {
+			a, _, _ := unstructured.NestedInt64(s.Object, "spec", "retryAttempt")
+			b, _, _ := unstructured.NestedInt64(cur.Object, "spec", "retryAttempt")
+			if a < b {
+				continue
+			}
+		}
+		latest[step] = s
+	}
+
+	results := map[string]pipelineStepStatus{}
+	out := make([]interface{}, 0, len(steps))
+	counts := map[string]int{}
+	for _, step := range steps {
+		st := pipelineStepStatus{Name: step.Name, Phase: "Waiting"}
+		if s, ok := latest[step.Name]; ok {
+			st.Session = s.GetName()
+			st.Phase, st.Message = stepPhase(s)
+		} else {
+			ready := true
+			for _, dep := range step.DependsOn {
+				switch results[dep].Phase {
+				case "Failed", "Skipped":
+					st.Phase, st.Message = "Skipped", fmt.Sprintf("upstream step %s did not succeed", dep)
+				case "Succeeded":
+				default:
+					ready = false
+				}
+			}
+			if st.Phase == "Waiting" && ready {
+				session, err := createPipelineSession(pipeline, step, results)
+				if err != nil {
+					return err
+				}
+				st.Phase, st.Session = "Running", session
+			}
+		}
+		results[step.Name] = st
+		counts[st.Phase]++
+		out = append(out, st.toMap())
+	}
+	status["steps"] = out
+
+	switch {
+	case counts["Succeeded"] == len(steps):
+		status["phase"] = "Succeeded"
+		status["message"] = fmt.Sprintf("%d steps succeeded", len(steps))
+		status["completionTime"] = now
+	case counts["Running"] == 0 && counts["Waiting"] == 0:
+		status["phase"] = "Failed"
+		status["message"] = fmt.Sprintf("%d steps failed, %d skipped", counts["Failed"], counts["Skipped"])
+		status["completionTime"] = now
+	default:
+		status["phase"] = "Running"
+		status["message"] = fmt.Sprintf("%d of %d steps succeeded", counts["Succeeded"], len(steps))
+	}
+	return updateSessionPipelineStatus(pipeline, status)
+}
+
+// createPipelineSession creates the session for a step from its template,
+// wiring step inputs to the upstream steps' sessions
+func createPipelineSession(pipeline *unstructured.Unstructured, step pipelineStep, results map[string]pipelineStepStatus) (string, error) {
+	ns := pipeline.GetNamespace()
+	name := pipelineSessionName(pipeline.GetName(), step.Name)
+	spec := map[string]interface{}{}
+	if step.Template != nil {
+		spec = runtime.DeepCopyJSONValue(step.Template).(map[string]interface{})
+	}
+	spec["project"] = ns
+	if dn, _ := spec["displayName"].(string); dn == "" {
+		spec["displayName"] = fmt.Sprintf("%s / %s", pipeline.GetName(), step.Name)
+	}
+	inputs, _ := spec["inputs"].([]interface{})
+	for _, in := range step.Inputs {
+		from, _ := in["step"].(string)
+		ref := map[string]interface{}{"sessionRef": results[from].Session}
+		if p, _ := in["path"].(string); p != "" {
+			ref["path"] = p
+		}
+		if sel, ok := in["artifactSelector"]; ok {
+			ref["artifactSelector"] = runtime.DeepCopyJSONValue(sel)
+		}
+		inputs = append(inputs, ref)
+	}
+	if len(inputs) > 0 {
+		spec["inputs"] = inputs
+	}
+
+	session := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": ns,
+			"labels": map[string]interface{}{
+				pipelineLabel:     pipeline.GetName(),
+				pipelineStepLabel: step.Name,
+			},
+			"ownerReferences": []interface{}{map[string]interface{}{
+				"apiVersion": pipeline.GetAPIVersion(),
+				"kind":       pipeline.GetKind(),
+				"name":       pipeline.GetName(),
+				"uid":        string(pipeline.GetUID()),
+				"controller": true,
+			}},
+		},
+		"spec": spec,
+	}}
+	_, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).Create(context.TODO(), session, v1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		recordEvent(pipeline, corev1.EventTypeWarning, eventReasonPipeline, "Failed to create session for step %s: %v", step.Name, err)
+		return "", fmt.Errorf("create session for step %s: %v", step.Name, err)
+	}
+	if err == nil {
+		recordEvent(pipeline, corev1.EventTypeNormal, eventReasonPipeline, "Started step %s as session %s", step.Name, name)
+	}
+	return name, nil
+}
+
+func updateSessionPipelineStatus(pipeline *unstructured.Unstructured, status map[string]interface{}) error {
+	before, _, _ := unstructured.NestedMap(pipeline.Object, "status")
+	if bytes.Equal(statusSnapshot(before), statusSnapshot(status)) {
+		return nil
+	}
+	pipeline.Object["status"] = status
+	_, err := dynamicClient.Resource(getSessionPipelineResource()).Namespace(pipeline.GetNamespace()).UpdateStatus(context.TODO(), pipeline, v1.UpdateOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to update SessionPipeline status: %v", err)
+	}
+	return nil
+}
+
+// reconcilePipelineOfSession advances the pipeline a finished session belongs to
+func reconcilePipelineOfSession(session *unstructured.Unstructured) {
+	name := session.GetLabels()[pipelineLabel]
+	if name == "" {
+		return
+	}
+	ref := &unstructured.Unstructured{}
+	ref.SetNamespace(session.GetNamespace())
+	ref.SetName(name)
+	if err := reconcileSessionPipeline(ref); err != nil {
+		log.Printf("Pipeline %s/%s: %v", session.GetNamespace(), name, err)
+	}
+}
+
+func watchSessionPipelines() {
+	gvr := getSessionPipelineResource()
+	for {
+		watcher, err := dynamicClient.Resource(gvr).Watch(context.TODO(), v1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to create SessionPipeline watcher: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		log.Println("Watching for SessionPipeline events...")
+
+		for event := range watcher.ResultChan() {
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				obj := event.Object.(*unstructured.Unstructured)
+				if err := reconcileSessionPipeline(obj); err != nil {
+					log.Printf("Error reconciling SessionPipeline %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+				}
+			case watch.Error:
+				log.Printf("Watch error for SessionPipelines: %v", event.Object)
+			}
+		}
+
+		log.Println("SessionPipeline watch channel closed, restarting...")
+		watcher.Stop()
+		time.Sleep(2 * time.Second)
+	}
+}
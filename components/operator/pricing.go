@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"ambient-code-shared/money"
+)
+
+// modelPricingName is the singleton ModelPricing evaluated by the backend and operator
+const modelPricingName = "default"
+
+// tokensPerPriceUnit is the number of tokens prices are quoted for
+const tokensPerPriceUnit = 1_000_000
+
+const defaultModelPricingRefresh = 30 * time.Second
+
+func getModelPricingResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "modelpricings",
+	}
+}
+
+// modelPrice is one ModelPricing entry: a model name or glob and its prices in
+// USD per million tokens
+type modelPrice struct {
+	Model               string
+	InputUSDPerMillion  money.USD
+	OutputUSDPerMillion money.USD
+}
+
+var (
+	modelPricingMu       sync.Mutex
+	modelPricingCache    []modelPrice
+	modelPricingLoadedAt time.Time
+)
+
+// currentModelPricing returns the ModelPricing entries, reloaded at most every
+// MODEL_PRICING_REFRESH (default 30s) so price updates apply without a restart.
+// A missing pricing (or CRD) prices nothing; a failed reload keeps the last
+// entries read.
+func currentModelPricing() []modelPrice {
+	modelPricingMu.Lock()
+	defer modelPricingMu.Unlock()
+	refresh := defaultModelPricingRefresh
+	if v := os.Getenv("MODEL_PRICING_REFRESH"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			refresh = d
+		}
+	}
+	if time.Since(modelPricingLoadedAt) < refresh {
+		return modelPricingCache
+	}
+	modelPricingLoadedAt = time.Now()
+	obj, err := dynamicClient.Resource(getModelPricingResource()).Get(context.TODO(), modelPricingName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		modelPricingCache = nil
+		return nil
+	}
+	if err != nil {
+		log.Printf("Failed to reload ModelPricing, keeping the previous prices: %v", err)
+		return modelPricingCache
+	}
+	models, _, _ := unstructured.NestedSlice(obj.Object, "spec", "models")
+	prices := make([]modelPrice, 0, len(models))
+	for _, raw := range models {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p := modelPrice{}
+		p.Model, _ = m["model"].(string)
+		p.InputUSDPerMillion = amountFromSpec(m, "inputUSDPerMillion")
+		p.OutputUSDPerMillion = amountFromSpec(m, "outputUSDPerMillion")
+		if p.Model != "" {
+			prices = append(prices, p)
+		}
+	}
+	modelPricingCache = prices
+	return prices
+}
+
+// priceForModel returns the first ModelPricing entry matching model, by name or glob
+func priceForModel(model string) (modelPrice, bool) {
+	if strings.TrimSpace(model) == "" {
+		return modelPrice{}, false
+	}
+	for _, p := range currentModelPricing() {
+		if ok, _ := path.Match(p.Model, model); ok {
+			return p, true
+		}
+	}
+	return modelPrice{}, false
+}
+
+// cost prices token counts exactly, rounding each direction to the nearest
+// millionth of a dollar
+func (p modelPrice) cost(inputTokens, outputTokens int64) money.USD {
+	return p.InputUSDPerMillion.MulDiv(inputTokens, tokensPerPriceUnit) + p.OutputUSDPerMillion.MulDiv(outputTokens, tokensPerPriceUnit)
+}
+
+// pricedSessionCost prices a session's reported token usage at its model's
+// ModelPricing entry, and the runner's title and summary calls (usage.helper_models)
+// at theirs or else the session model's. ok is false for unpriced models and
+// sessions without token counts.
+func pricedSessionCost(s *unstructured.Unstructured) (money.USD, bool) {
+	usage, found, _ := unstructured.NestedMap(s.Object, "status", "usage")
+	if !found {
+		return 0, false
+	}
+	_, hasInput := usage["input_tokens"]
+	_, hasOutput := usage["output_tokens"]
+	if !hasInput && !hasOutput {
+		return 0, false
+	}
+	model, _, _ := unstructured.NestedString(s.Object, "spec", "llmSettings", "model")
+	price, ok := priceForModel(model)
+	if !ok {
+		return 0, false
+	}
+	cost := price.cost(int64(floatFromSpec(usage, "input_tokens")), int64(floatFromSpec(usage, "output_tokens")))
+	helpers, _, _ := unstructured.NestedMap(usage, "helper_models")
+	for helperModel, raw := range helpers {
+		tokens, _ := raw.(map[string]interface{})
+		helperPrice, ok := priceForModel(helperModel)
+		if !ok {
+			helperPrice = price
+		}
+		cost += helperPrice.cost(int64(floatFromSpec(tokens, "input_tokens")), int64(floatFromSpec(tokens, "output_tokens")))
+	}
+	return cost, true
+}
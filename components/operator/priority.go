@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// sessionPriorities lists spec.priority values from lowest to highest
+var sessionPriorities = []string{"low", "normal", "high"}
+
+const (
+	defaultSessionPriority = "normal"
+	// defaultPriorityAging raises a queued session one priority level per
+	// interval waited, so low-priority work is not starved
+	defaultPriorityAging = 30 * time.Minute
+)
+
+var priorityAging = func() time.Duration {
+	if v := os.Getenv("QUEUE_PRIORITY_AGING"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid QUEUE_PRIORITY_AGING=%q, using %s", v, defaultPriorityAging)
+	}
+	return defaultPriorityAging
+}()
+
+// sessionPriority returns spec.priority, defaulting to normal
+func sessionPriority(spec map[string]interface{}) string {
+	p, _, _ := unstructured.NestedString(spec, "priority")
+	if p = strings.TrimSpace(p); slices.Contains(sessionPriorities, p) {
+		return p
+	}
+	return defaultSessionPriority
+}
+
+// effectivePriority is the session's priority rank raised by the time it has waited
+func effectivePriority(session *unstructured.Unstructured, now time.Time) int {
+	spec, _, _ := unstructured.NestedMap(session.Object, "spec")
+	rank := slices.Index(sessionPriorities, sessionPriority(spec))
+	waited := now.Sub(session.GetCreationTimestamp().Time)
+	return min(rank+int(waited/priorityAging), len(sessionPriorities)-1)
+}
+
+// queuedAhead reports whether queued session a is served before b: higher
+// effective priority first, then older first
+func queuedAhead(a, b *unstructured.Unstructured, now time.Time) bool {
+	pa, pb := effectivePriority(a, now), effectivePriority(b, now)
+	if pa != pb {
+		return pa > pb
+	}
+	return a.GetCreationTimestamp().Time.Before(b.GetCreationTimestamp().Time)
+}
+
+// runnerPriorityClass returns the PriorityClass ProjectSettings
+// runnerScheduling.priorityClasses maps the session's priority to, if any
+func runnerPriorityClass(spec, psSpec map[string]interface{}) string {
+	name, _, _ := unstructured.NestedString(psSpec, "runnerScheduling", "priorityClasses", sessionPriority(spec))
+	return strings.TrimSpace(name)
+}
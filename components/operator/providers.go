@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	modelProviderVolumeName = "model-provider-credentials"
+	modelProviderMountPath  = "/var/run/secrets/model-provider"
+)
+
+// modelProviderSecretKeys lists, per provider type, the Secret keys exposed to the
+// runner as env vars of the same name. The first key is required.
+var modelProviderSecretKeys = map[string][]string{
+	"anthropic":    {"ANTHROPIC_API_KEY"},
+	"openai":       {"OPENAI_API_KEY", "OPENAI_ORG_ID"},
+	"bedrock":      {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"},
+	"vertex":       {},
+	"azure-openai": {"AZURE_OPENAI_API_KEY"},
+}
+
+// modelProvider is one entry of ProjectSettings spec.modelProviders.providers
+type modelProvider struct {
+	Name          string
+	Type          string
+	SecretName    string
+	Region        string
+	ProjectID     string
+	BaseURL       string
+	Endpoint      string
+	APIVersion    string
+	Deployment    string
+	AllowedModels []string
+}
+
+func modelProviderFromMap(name string, m map[string]interface{}) modelProvider {
+	p := modelProvider{Name: name}
+	p.Type, _, _ = unstructured.NestedString(m, "type")
+	p.SecretName, _, _ = unstructured.NestedString(m, "secretName")
+	p.Region, _, _ = unstructured.NestedString(m, "region")
+	p.ProjectID, _, _ = unstructured.NestedString(m, "projectID")
+	p.BaseURL, _, _ = unstructured.NestedString(m, "baseURL")
+	p.Endpoint, _, _ = unstructured.NestedString(m, "endpoint")
+	p.APIVersion, _, _ = unstructured.NestedString(m, "apiVersion")
+	p.Deployment, _, _ = unstructured.NestedString(m, "deployment")
+	p.AllowedModels, _, _ = unstructured.NestedStringSlice(m, "allowedModels")
+	if p.Type == "" {
+		p.Type = name
+	}
+	return p
+}
+
+// allowsModel matches the model against allowedModels globs; an empty list allows any model
+func (p modelProvider) allowsModel(model string) bool {
+	if len(p.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedModels {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveModelProvider picks the provider for spec.llmSettings.provider, else the
+// namespace default, else the only configured provider. Namespaces without
+// spec.modelProviders keep the runner Secret's ANTHROPIC_API_KEY and get nil.
+// The backend rejects these errors on create; this covers sessions created
+// directly through the API server.
+func resolveModelProvider(spec, psSpec map[string]interface{}, fw runnerFramework) (*modelProvider, error) {
+	requested, _, _ := unstructured.NestedString(spec, "llmSettings", "provider")
+	model, _, _ := unstructured.NestedString(spec, "llmSettings", "model")
+	providers, _, _ := unstructured.NestedMap(psSpec, "modelProviders", "providers")
+	if len(providers) == 0 {
+		if requested != "" && requested != "anthropic" {
+			return nil, fmt.Errorf("model provider %q is not configured for this project", requested)
+		}
+		return nil, nil
+	}
+
+	name := requested
+	if name == "" {
+		name, _, _ = unstructured.NestedString(psSpec, "modelProviders", "default")
+	}
+	if name == "" && len(providers) == 1 {
+		for only := range providers {
+			name = only
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no model provider requested and the project has no default")
+	}
+	raw, ok := providers[name].(map[string]interface{})
+	if !ok {
+		names := make([]string, 0, len(providers))
+		for n := range providers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("model provider %q is not configured (available: %s)", name, strings.Join(names, ", "))
+	}
+	p := modelProviderFromMap(name, raw)
+	if _, known := modelProviderSecretKeys[p.Type]; !known {
+		return nil, fmt.Errorf("model provider %q has unsupported type %q", name, p.Type)
+	}
+	if len(fw.Providers) > 0 && !slices.Contains(fw.Providers, p.Type) {
+		return nil, fmt.Errorf("framework %q does not support %s models (supported: %s)", fw.Name, p.Type, strings.Join(fw.Providers, ", "))
+	}
+	if !p.allowsModel(model) {
+		return nil, fmt.Errorf("model %q is not allowed for provider %q", model, name)
+	}
+	return &p, nil
+}
+
+// apply wires the provider's credentials and settings into the runner container.
+// Env entries take precedence over keys of the same name in the runner Secret.
+func (p modelProvider) apply(pod *corev1.PodSpec) {
+	env := []corev1.EnvVar{{Name: "LLM_PROVIDER", Value: p.Type}}
+	// The runner picks the models of its title and summary calls from these
+	if len(p.AllowedModels) > 0 {
+		env = append(env, corev1.EnvVar{Name: "LLM_ALLOWED_MODELS", Value: strings.Join(p.AllowedModels, ",")})
+	}
+	for i, key := range modelProviderSecretKeys[p.Type] {
+		if p.SecretName == "" {
+			break
+		}
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: p.SecretName},
+				Key:                  key,
+				Optional:             boolPtr(i > 0),
+			}},
+		})
+	}
+	setting := func(name, value string) {
+		if value != "" {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+	switch p.Type {
+	case "anthropic":
+		setting("ANTHROPIC_BASE_URL", p.BaseURL)
+	case "openai":
+		setting("OPENAI_BASE_URL", p.BaseURL)
+	case "bedrock":
+		setting("CLAUDE_CODE_USE_BEDROCK", "1")
+		setting("AWS_REGION", p.Region)
+	case "vertex":
+		setting("CLAUDE_CODE_USE_VERTEX", "1")
+		setting("CLOUD_ML_REGION", p.Region)
+		setting("ANTHROPIC_VERTEX_PROJECT_ID", p.ProjectID)
+		if p.SecretName != "" {
+			setting("GOOGLE_APPLICATION_CREDENTIALS", modelProviderMountPath+"/credentials.json")
+			pod.Volumes = append(pod.Volumes, corev1.Volume{
+				Name: modelProviderVolumeName,
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+					SecretName: p.SecretName,
+					Items:      []corev1.KeyToPath{{Key: "credentials.json", Path: "credentials.json"}},
+				}},
+			})
+		}
+	case "azure-openai":
+		setting("AZURE_OPENAI_ENDPOINT", p.Endpoint)
+		setting("OPENAI_API_VERSION", p.APIVersion)
+		setting("AZURE_OPENAI_DEPLOYMENT", p.Deployment)
+	}
+
+	for i := range pod.Containers {
+		if pod.Containers[i].Name != "ambient-code-runner" {
+			continue
+		}
+		pod.Containers[i].Env = append(pod.Containers[i].Env, env...)
+		if p.Type == "vertex" && p.SecretName != "" {
+			pod.Containers[i].VolumeMounts = append(pod.Containers[i].VolumeMounts, corev1.VolumeMount{
+				Name: modelProviderVolumeName, MountPath: modelProviderMountPath, ReadOnly: true,
+			})
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	eventReasonPullRequestOpened = "PullRequestOpened"
+	eventReasonPullRequestFailed = "PullRequestFailed"
+)
+
+var (
+	pullRequestsInFlightMu sync.Mutex
+	pullRequestsInFlight   = map[string]bool{}
+	changeSetHTTPClient    = &http.Client{Timeout: 60 * time.Second}
+)
+
+// pullRequestChangeSet is what the backend stored for a status.pullRequests entry
+type pullRequestChangeSet struct {
+	BaseSHA string `json:"baseSha"`
+	Body    string `json:"body"`
+	Draft   bool   `json:"draft"`
+	Files   []struct {
+		Path       string `json:"path"`
+		Content    string `json:"content"`
+		Deleted    bool   `json:"deleted"`
+		Executable bool   `json:"executable"`
+	} `json:"files"`
+}
+
+// openPendingPullRequests opens the session's Pending status.pullRequests
+// entries in the background, one session at a time.
+func openPendingPullRequests(session *unstructured.Unstructured) {
+	entries, _, _ := unstructured.NestedSlice(session.Object, "status", "pullRequests")
+	var pending []map[string]interface{}
+	for _, raw := range entries {
+		if m, ok := raw.(map[string]interface{}); ok && m["state"] == "Pending" {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	key := string(session.GetUID())
+	pullRequestsInFlightMu.Lock()
+	if pullRequestsInFlight[key] {
+		pullRequestsInFlightMu.Unlock()
+		return
+	}
+	pullRequestsInFlight[key] = true
+	pullRequestsInFlightMu.Unlock()
+
+	go func() {
+		defer func() {
+			pullRequestsInFlightMu.Lock()
+			delete(pullRequestsInFlight, key)
+			pullRequestsInFlightMu.Unlock()
+		}()
+		ns, name := session.GetNamespace(), session.GetName()
+		psSpec := map[string]interface{}{}
+		if psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
+			if spec, ok, _ := unstructured.NestedMap(psObj.Object, "spec"); ok {
+				psSpec = spec
+			}
+		}
+		for _, entry := range pending {
+			result := map[string]interface{}{}
+			number, link, sha, err := openPullRequest(session, psSpec, entry)
+			if err != nil {
+				result["state"] = "Failed"
+				result["message"] = err.Error()
+				recordEvent(session, corev1.EventTypeWarning, eventReasonPullRequestFailed, "Failed to open pull request for %v: %v", entry["repo"], err)
+			} else {
+				result["state"] = "Open"
+				result["number"] = number
+				result["url"] = link
+				result["commitSha"] = sha
+				result["message"] = nil
+				recordEvent(session, corev1.EventTypeNormal, eventReasonPullRequestOpened, "Opened pull request %s", link)
+			}
+			changeSet, _ := entry["changeSet"].(string)
+			if err := setPullRequestStatus(ns, name, changeSet, result); err != nil {
+				log.Printf("Failed to record pull request status for %s/%s: %v", ns, name, err)
+			}
+		}
+	}()
+}
+
+// openPullRequest commits an entry's change set on top of its base commit with
+// the GitHub Git Data API, points the branch at it and opens the pull request.
+// An entry that already carries a number re-pushes the branch of that pull
+// request; otherwise an existing branch is left alone and the request fails.
+func openPullRequest(session *unstructured.Unstructured, psSpec map[string]interface{}, entry map[string]interface{}) (int64, string, string, error) {
+	enabled, _, _ := unstructured.NestedBool(psSpec, "integrations", "github", "enabled")
+	allowed, _, _ := unstructured.NestedBool(psSpec, "integrations", "github", "pullRequests")
+	if !enabled || !allowed {
+		return 0, "", "", fmt.Errorf("pull requests are not enabled for this project")
+	}
+	repo, _ := entry["repo"].(string)
+	branch, _ := entry["branch"].(string)
+	base, _ := entry["baseBranch"].(string)
+	title, _ := entry["title"].(string)
+	changeSetPath, _ := entry["changeSet"].(string)
+	existing, _, _ := unstructured.NestedInt64(entry, "number")
+	existingURL, _ := entry["url"].(string)
+	owner, name, ok := parseGitHubRepo(repo)
+	if !ok {
+		return 0, "", "", fmt.Errorf("%q is not a GitHub repository", repo)
+	}
+	cs, err := loadPullRequestChangeSet(session.GetNamespace(), changeSetPath)
+	if err != nil {
+		return 0, "", "", err
+	}
+	cfg := githubIntegrationFromSpec(psSpec)
+	token, err := githubToken(session.GetNamespace(), cfg, owner, name)
+	if err != nil {
+		return 0, "", "", err
+	}
+	auth := "token " + token
+	api := fmt.Sprintf("%s/repos/%s/%s", cfg.APIURL, owner, name)
+
+	tree := make([]map[string]interface{}, 0, len(cs.Files))
+	for _, f := range cs.Files {
+		mode := "100644"
+		if f.Executable {
+			mode = "100755"
+		}
+		item := map[string]interface{}{"path": f.Path, "mode": mode, "type": "blob", "sha": nil}
+		if !f.Deleted {
+			var blob struct {
+				SHA string `json:"sha"`
+			}
+			if err := githubRequest(http.MethodPost, api+"/git/blobs", auth, map[string]string{"content": f.Content, "encoding": "base64"}, &blob); err != nil {
+				return 0, "", "", fmt.Errorf("upload %s: %v", f.Path, err)
+			}
+			item["sha"] = blob.SHA
+		}
+		tree = append(tree, item)
+	}
+	var newTree struct {
+		SHA string `json:"sha"`
+	}
+	if err := githubRequest(http.MethodPost, api+"/git/trees", auth, map[string]interface{}{"base_tree": cs.BaseSHA, "tree": tree}, &newTree); err != nil {
+		return 0, "", "", fmt.Errorf("create tree: %v", err)
+	}
+	commitReq := map[string]interface{}{"message": title, "tree": newTree.SHA, "parents": []string{cs.BaseSHA}}
+	userName, _, _ := unstructured.NestedString(session.Object, "spec", "gitConfig", "user", "name")
+	userEmail, _, _ := unstructured.NestedString(session.Object, "spec", "gitConfig", "user", "email")
+	if userName != "" && userEmail != "" {
+		commitReq["author"] = map[string]string{"name": userName, "email": userEmail}
+	}
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := githubRequest(http.MethodPost, api+"/git/commits", auth, commitReq, &commit); err != nil {
+		return 0, "", "", fmt.Errorf("create commit: %v", err)
+	}
+
+	if err := githubRequest(http.MethodPost, api+"/git/refs", auth, map[string]string{"ref": "refs/heads/" + branch, "sha": commit.SHA}, nil); err != nil {
+		if existing == 0 {
+			return 0, "", "", fmt.Errorf("create branch %s: %v", branch, err)
+		}
+		if err := githubRequest(http.MethodPatch, api+"/git/refs/heads/"+branch, auth, map[string]interface{}{"sha": commit.SHA, "force": true}, nil); err != nil {
+			return 0, "", "", fmt.Errorf("update branch %s: %v", branch, err)
+		}
+	}
+	if existing > 0 {
+		return existing, existingURL, commit.SHA, nil
+	}
+
+	body := cs.Body
+	if s := buildSessionSummary(session); s.SessionURL != "" {
+		body += fmt.Sprintf("\n\n---\nOpened by Ambient session [%s](%s)", s.Display, s.SessionURL)
+	}
+	var pr struct {
+		Number  int64  `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	prReq := map[string]interface{}{"title": title, "head": branch, "base": base, "body": body, "draft": cs.Draft}
+	if err := githubRequest(http.MethodPost, api+"/pulls", auth, prReq, &pr); err != nil {
+		return 0, "", commit.SHA, fmt.Errorf("open pull request: %v", err)
+	}
+	return pr.Number, pr.HTMLURL, commit.SHA, nil
+}
+
+// loadPullRequestChangeSet reads a change set from the namespace content service
+func loadPullRequestChangeSet(ns, path string) (*pullRequestChangeSet, error) {
+	if path == "" {
+		return nil, fmt.Errorf("entry has no change set")
+	}
+	u := fmt.Sprintf("http://ambient-content.%s.svc:8080/content/file?path=%s", ns, url.QueryEscape(path))
+	resp, err := changeSetHTTPClient.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("read change set: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("read change set: status %d", resp.StatusCode)
+	}
+	var cs pullRequestChangeSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&cs); err != nil {
+		return nil, fmt.Errorf("corrupt change set: %v", err)
+	}
+	if cs.BaseSHA == "" || len(cs.Files) == 0 {
+		return nil, fmt.Errorf("change set is empty")
+	}
+	return &cs, nil
+}
+
+// setPullRequestStatus merges result into the status.pullRequests entry of
+// changeSet, preserving the other entries. A nil value removes the field.
+func setPullRequestStatus(ns, name, changeSet string, result map[string]interface{}) error {
+	obj, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	entries, _, _ := unstructured.NestedSlice(obj.Object, "status", "pullRequests")
+	for _, raw := range entries {
+		m, ok := raw.(map[string]interface{})
+		if !ok || m["changeSet"] != changeSet {
+			continue
+		}
+		for k, v := range result {
+			if v == nil {
+				delete(m, k)
+				continue
+			}
+			m[k] = v
+		}
+	}
+	return updateAgenticSessionStatus(ns, name, map[string]interface{}{"pullRequests": entries})
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	eventReasonResultReported     = "ResultReported"
+	eventReasonResultReportFailed = "ResultReportFailed"
+
+	reportStatePosted = "Posted"
+	reportStateFailed = "Failed"
+
+	// Sessions that finished longer ago than this are not reported, so an
+	// operator restart does not post results for old sessions.
+	reportMaxAge       = time.Hour
+	reportMaxAttempts  = 3
+	reportSummaryLimit = 4000
+)
+
+// sessionReporter posts a finished session's result to one external system.
+// Results are recorded in status.integrations.<name>.
+type sessionReporter struct {
+	name string
+	// attempts overrides reportMaxAttempts for reporters that retry internally
+	attempts int
+	// applies reports whether this session should be reported to the target
+	applies func(session *unstructured.Unstructured, psSpec map[string]interface{}) bool
+	// report posts the summary and returns a link to what it created
+	report func(session *unstructured.Unstructured, psSpec map[string]interface{}, summary sessionSummary) (string, error)
+}
+
+// sessionReporters run in order for every session that reaches a terminal phase
+var sessionReporters = []sessionReporter{githubReporter, jiraReporter, notificationsReporter}
+
+// sessionSummary is the portable result of a finished session
+type sessionSummary struct {
+	Namespace  string
+	Name       string
+	Display    string
+	Phase      string
+	Summary    string
+	CostUSD    float64
+	NumTurns   int64
+	Duration   time.Duration
+	SessionURL string
+	Artifacts  []artifactLink
+}
+
+type artifactLink struct {
+	Name string
+	URL  string
+}
+
+var (
+	reportsInFlightMu sync.Mutex
+	reportsInFlight   = map[string]bool{}
+	reportHTTPClient  = &http.Client{Timeout: 20 * time.Second}
+)
+
+// reportSessionResult runs pending reporters for a finished session in the
+// background. Each target is attempted until it posts or reportMaxAttempts fail.
+func reportSessionResult(session *unstructured.Unstructured) {
+	end, done := sessionFinishedAt(session)
+	if !done || time.Since(end) > reportMaxAge {
+		return
+	}
+	pending := false
+	for _, r := range sessionReporters {
+		if state, _, _ := unstructured.NestedString(session.Object, "status", "integrations", r.name, "state"); state == "" {
+			pending = true
+		}
+	}
+	if !pending {
+		return
+	}
+
+	key := string(session.GetUID())
+	reportsInFlightMu.Lock()
+	if reportsInFlight[key] {
+		reportsInFlightMu.Unlock()
+		return
+	}
+	reportsInFlight[key] = true
+	reportsInFlightMu.Unlock()
+
+	go func() {
+		defer func() {
+			reportsInFlightMu.Lock()
+			delete(reportsInFlight, key)
+			reportsInFlightMu.Unlock()
+		}()
+		ns := session.GetNamespace()
+		psSpec := map[string]interface{}{}
+		if psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
+			if spec, ok, _ := unstructured.NestedMap(psObj.Object, "spec"); ok {
+				psSpec = spec
+			}
+		}
+		summary := buildSessionSummary(session)
+		for _, r := range sessionReporters {
+			if state, _, _ := unstructured.NestedString(session.Object, "status", "integrations", r.name, "state"); state != "" {
+				continue
+			}
+			if !r.applies(session, psSpec) {
+				continue
+			}
+			result := map[string]interface{}{}
+			var link string
+			var err error
+			attempts := reportMaxAttempts
+			if r.attempts > 0 {
+				attempts = r.attempts
+			}
+			for attempt := 1; attempt <= attempts; attempt++ {
+				if link, err = r.report(session, psSpec, summary); err == nil {
+					break
+				}
+				log.Printf("Report %s for %s/%s failed (attempt %d): %v", r.name, ns, session.GetName(), attempt, err)
+				if attempt < attempts {
+					time.Sleep(time.Duration(attempt*attempt) * 5 * time.Second)
+				}
+			}
+			result["attemptedAt"] = time.Now().UTC().Format(time.RFC3339)
+			if err != nil {
+				result["state"] = reportStateFailed
+				result["message"] = err.Error()
+				recordEvent(session, corev1.EventTypeWarning, eventReasonResultReportFailed, "Failed to report result to %s: %v", r.name, err)
+			} else {
+				result["state"] = reportStatePosted
+				if link != "" {
+					result["url"] = link
+				}
+				recordEvent(session, corev1.EventTypeNormal, eventReasonResultReported, "Reported result to %s %s", r.name, link)
+			}
+			if err := setSessionIntegrationStatus(ns, session.GetName(), r.name, result); err != nil {
+				log.Printf("Failed to record %s report status for %s/%s: %v", r.name, ns, session.GetName(), err)
+			}
+		}
+	}()
+}
+
+// setSessionIntegrationStatus records one target's outcome in status.integrations,
+// preserving the other targets' entries.
+func setSessionIntegrationStatus(ns, name, target string, result map[string]interface{}) error {
+	obj, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	integrations, _, _ := unstructured.NestedMap(obj.Object, "status", "integrations")
+	if integrations == nil {
+		integrations = map[string]interface{}{}
+	}
+	integrations[target] = result
+	return updateAgenticSessionStatus(ns, name, map[string]interface{}{"integrations": integrations})
+}
+
+// buildSessionSummary collects the result fields the runner reported, plus
+// links to the session and its artifacts when AMBIENT_UI_URL is set.
+func buildSessionSummary(session *unstructured.Unstructured) sessionSummary {
+	ns, name := session.GetNamespace(), session.GetName()
+	s := sessionSummary{Namespace: ns, Name: name, Display: name}
+	if v, _, _ := unstructured.NestedString(session.Object, "spec", "displayName"); v != "" {
+		s.Display = v
+	}
+	s.Phase, _, _ = unstructured.NestedString(session.Object, "status", "phase")
+	for _, path := range [][]string{{"status", "report", "preview"}, {"status", "result"}, {"status", "message"}} {
+		if v, _, _ := unstructured.NestedString(session.Object, path...); strings.TrimSpace(v) != "" {
+			s.Summary = strings.TrimSpace(v)
+			break
+		}
+	}
+	if len(s.Summary) > reportSummaryLimit {
+		s.Summary = s.Summary[:reportSummaryLimit] + "…"
+	}
+	if v, ok, _ := unstructured.NestedFieldNoCopy(session.Object, "status", "total_cost_usd"); ok {
+		switch n := v.(type) {
+		case float64:
+			s.CostUSD = n
+		case int64:
+			s.CostUSD = float64(n)
+		}
+	}
+	s.NumTurns, _, _ = unstructured.NestedInt64(session.Object, "status", "num_turns")
+	if ts, ok, _ := unstructured.NestedString(session.Object, "status", "startTime"); ok {
+		if start, err := time.Parse(time.RFC3339, ts); err == nil {
+			if end, done := sessionFinishedAt(session); done && end.After(start) {
+				s.Duration = end.Sub(start).Round(time.Second)
+			}
+		}
+	}
+
+	base := strings.TrimRight(os.Getenv("AMBIENT_UI_URL"), "/")
+	if base == "" {
+		return s
+	}
+	s.SessionURL = fmt.Sprintf("%s/projects/%s/sessions/%s", base, url.PathEscape(ns), url.PathEscape(name))
+	for _, a := range loadArtifactNames(ns, name) {
+		s.Artifacts = append(s.Artifacts, artifactLink{
+			Name: a,
+			URL:  fmt.Sprintf("%s/api/projects/%s/agentic-sessions/%s/artifacts/download/%s", base, url.PathEscape(ns), url.PathEscape(name), url.PathEscape(a)),
+		})
+	}
+	return s
+}
+
+// loadArtifactNames reads the session's artifact index from the namespace content service
+func loadArtifactNames(ns, name string) []string {
+	u := fmt.Sprintf("http://ambient-content.%s.svc:8080/content/file?path=%s", ns, url.QueryEscape(fmt.Sprintf("/sessions/%s/artifacts-index.json", name)))
+	resp, err := reportHTTPClient.Get(u)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var index []struct {
+		Name string `json:"name"`
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil || json.Unmarshal(b, &index) != nil {
+		return nil
+	}
+	names := make([]string, 0, len(index))
+	for _, a := range index {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// stats lists duration, turns and cost when known
+func (s sessionSummary) stats() []string {
+	var stats []string
+	if s.Duration > 0 {
+		stats = append(stats, "duration "+s.Duration.String())
+	}
+	if s.NumTurns > 0 {
+		stats = append(stats, fmt.Sprintf("%d turns", s.NumTurns))
+	}
+	if s.CostUSD > 0 {
+		stats = append(stats, fmt.Sprintf("cost $%.2f", s.CostUSD))
+	}
+	return stats
+}
+
+// markdown renders the summary for targets that accept Markdown
+func (s sessionSummary) markdown() string {
+	var b strings.Builder
+	icon := "✅"
+	if s.Phase != "Completed" {
+		icon = "❌"
+	}
+	fmt.Fprintf(&b, "### %s Ambient session %s: %s\n\n", icon, strings.ToLower(s.Phase), s.Display)
+	if s.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", s.Summary)
+	}
+	if stats := s.stats(); len(stats) > 0 {
+		fmt.Fprintf(&b, "_%s_\n\n", strings.Join(stats, " · "))
+	}
+	if len(s.Artifacts) > 0 {
+		b.WriteString("**Artifacts**\n")
+		for _, a := range s.Artifacts {
+			fmt.Fprintf(&b, "- [%s](%s)\n", a.Name, a.URL)
+		}
+		b.WriteString("\n")
+	}
+	if s.SessionURL != "" {
+		fmt.Fprintf(&b, "[View session](%s)\n", s.SessionURL)
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// sessionResourceNames maps spec.resourceOverrides and sessionPolicy.maxResources
+// fields to container resources
+var sessionResourceNames = map[string]corev1.ResourceName{
+	"cpu":              corev1.ResourceCPU,
+	"memory":           corev1.ResourceMemory,
+	"ephemeralStorage": corev1.ResourceEphemeralStorage,
+}
+
+// sessionResources layers spec.resourceOverrides over the framework defaults. An
+// override sets both request and limit. Values above the namespace's
+// sessionPolicy.maxResources are capped; the backend rejects them on create, so
+// capping only applies to sessions created directly through the API server.
+func sessionResources(base corev1.ResourceRequirements, spec, psSpec map[string]interface{}) (corev1.ResourceRequirements, []string) {
+	out := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	for k, v := range base.Requests {
+		out.Requests[k] = v
+	}
+	for k, v := range base.Limits {
+		out.Limits[k] = v
+	}
+	var capped []string
+	for field, name := range sessionResourceNames {
+		v, _, _ := unstructured.NestedString(spec, "resourceOverrides", field)
+		q, err := resource.ParseQuantity(strings.TrimSpace(v))
+		if v == "" || err != nil || q.Sign() <= 0 {
+			continue
+		}
+		if maxStr, _, _ := unstructured.NestedString(psSpec, "sessionPolicy", "maxResources", field); maxStr != "" {
+			if max, err := resource.ParseQuantity(maxStr); err == nil && q.Cmp(max) > 0 {
+				capped = append(capped, fmt.Sprintf("%s %s > %s", field, q.String(), max.String()))
+				q = max
+			}
+		}
+		out.Requests[name] = q
+		out.Limits[name] = q
+	}
+	if gpus, capMsg := sessionGPUs(spec, psSpec); !gpus.IsZero() {
+		out.Requests[gpuResourceName(psSpec)] = gpus
+		out.Limits[gpuResourceName(psSpec)] = gpus
+		if capMsg != "" {
+			capped = append(capped, capMsg)
+		}
+	}
+	if len(out.Requests) == 0 {
+		out.Requests = nil
+	}
+	if len(out.Limits) == 0 {
+		out.Limits = nil
+	}
+	return out, capped
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const eventReasonRetentionCleanup = "RetentionCleanup"
+
+// retentionPolicy mirrors ProjectSettings spec.retention
+type retentionPolicy struct {
+	Sessions  time.Duration
+	Artifacts time.Duration
+	// Scratch keeps per-session scratch PVCs after the session ends (0 deletes at once)
+	Scratch time.Duration
+	DryRun  bool
+}
+
+// retentionResult counts objects removed (or that would be removed in dry-run) in one pass
+type retentionResult struct {
+	SessionsDeleted        int64
+	JobsDeleted            int64
+	ArtifactsDeleted       int64
+	ArtifactBytesReclaimed int64
+	// ArtifactsHeld counts artifacts of finished sessions kept by a hold tag
+	ArtifactsHeld int64
+}
+
+// Cumulative counters exported on /metrics
+var (
+	retentionSessionsDeletedTotal        atomic.Int64
+	retentionJobsDeletedTotal            atomic.Int64
+	retentionArtifactsDeletedTotal       atomic.Int64
+	retentionArtifactBytesReclaimedTotal atomic.Int64
+	retentionRunsTotal                   atomic.Int64
+)
+
+// parseRetentionDuration accepts Go durations ("720h") and whole days ("30d").
+func parseRetentionDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
+
+func retentionPolicyFromSpec(spec map[string]interface{}) (retentionPolicy, error) {
+	p := retentionPolicy{DryRun: os.Getenv("RETENTION_DRY_RUN") == "true"}
+	ret, found, _ := unstructured.NestedMap(spec, "retention")
+	if !found {
+		return p, nil
+	}
+	var err error
+	if v, ok := ret["sessions"].(string); ok {
+		if p.Sessions, err = parseRetentionDuration(v); err != nil {
+			return p, err
+		}
+	}
+	if v, ok := ret["artifacts"].(string); ok {
+		if p.Artifacts, err = parseRetentionDuration(v); err != nil {
+			return p, err
+		}
+	}
+	if v, ok := ret["scratch"].(string); ok {
+		if p.Scratch, err = parseRetentionDuration(v); err != nil {
+			return p, err
+		}
+	}
+	if v, ok := ret["dryRun"].(bool); ok && v {
+		p.DryRun = true
+	}
+	return p, nil
+}
+
+// runRetentionLoop periodically applies each managed namespace's retention policy.
+func runRetentionLoop() {
+	interval := 1 * time.Hour
+	if v := os.Getenv("RETENTION_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("Invalid RETENTION_INTERVAL=%q, using %s", v, interval)
+		}
+	}
+	log.Printf("Retention cleanup running every %s", interval)
+
+	for {
+		time.Sleep(interval)
+		runRetentionPass()
+	}
+}
+
+func runRetentionPass() {
+	nsList, err := k8sClient.CoreV1().Namespaces().List(context.TODO(), v1.ListOptions{
+		LabelSelector: "ambient-code.io/managed=true",
+	})
+	if err != nil {
+		log.Printf("Retention: failed to list managed namespaces: %v", err)
+		return
+	}
+	retentionRunsTotal.Add(1)
+	for _, ns := range nsList.Items {
+		if err := cleanupNamespaceRetention(ns.Name); err != nil {
+			log.Printf("Retention: cleanup failed in %s: %v", ns.Name, err)
+		}
+	}
+}
+
+// cleanupNamespaceRetention deletes finished sessions (with their Job and PVC data)
+// older than retention.sessions, and artifacts of finished sessions past
+// retention.artifacts or their own expiresAt unless tagged hold. Results are
+// recorded on the ProjectSettings status.
+func cleanupNamespaceRetention(ns string) error {
+	psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	spec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	policy, err := retentionPolicyFromSpec(spec)
+	if err != nil {
+		recordEvent(psObj, corev1.EventTypeWarning, eventReasonRetentionCleanup, "Invalid retention policy: %v", err)
+		return err
+	}
+	// Namespaces may not keep data for less than the organization-wide floors
+	clusterPol, err := loadClusterPolicy()
+	if err != nil {
+		return err
+	}
+	policy = policy.applyFloors(clusterPol)
+
+	sessions, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list sessions: %v", err)
+	}
+	// Artifacts may carry their own expiresAt, so every finished session is
+	// visited even without a project retention policy
+	referenced := inputReferencedSessions(sessions.Items)
+
+	now := time.Now()
+	var result retentionResult
+	for i := range sessions.Items {
+		s := &sessions.Items[i]
+		finishedAt, ok := sessionFinishedAt(s)
+		if !ok {
+			continue
+		}
+		age := now.Sub(finishedAt)
+
+		if ttl, ok := policy.sessionTTL(s, clusterPol); ok && age > ttl {
+			// Unfinished sessions still have to copy this session's artifacts
+			if referenced[s.GetName()] {
+				log.Printf("Retention: keeping %s/%s past its TTL; an unfinished session takes its artifacts as input", ns, s.GetName())
+				continue
+			}
+			// Deleting the session deletes its content, so a hold keeps the
+			// session; its other artifacts are still swept below
+			held, err := sessionHeldArtifacts(ns, s.GetName())
+			if err != nil {
+				log.Printf("Retention: keeping %s/%s past its TTL; cannot check its artifacts for holds: %v", ns, s.GetName(), err)
+				continue
+			}
+			if held == 0 {
+				if err := deleteExpiredSession(s, policy.DryRun, &result); err != nil {
+					log.Printf("Retention: failed to delete session %s/%s: %v", ns, s.GetName(), err)
+				}
+				continue
+			}
+			if policy.DryRun {
+				log.Printf("Retention (dry-run): would keep %s/%s past its TTL; %d artifacts are on hold", ns, s.GetName(), held)
+			} else {
+				log.Printf("Retention: keeping %s/%s past its TTL; %d artifacts are on hold", ns, s.GetName(), held)
+			}
+		}
+		if policy.Scratch > 0 && hasSessionScratch(s) && !policy.DryRun {
+			releaseSessionScratch(s, policy.Scratch)
+		}
+		sweep, err := sweepSessionArtifacts(ns, s.GetName(), finishedAt, policy, clusterPol.MinArtifacts, now)
+		if err != nil {
+			log.Printf("Retention: failed to sweep artifacts of %s/%s: %v", ns, s.GetName(), err)
+		}
+		result.ArtifactsDeleted += sweep.Deleted
+		result.ArtifactBytesReclaimed += sweep.BytesReclaimed
+		result.ArtifactsHeld += sweep.Held
+	}
+
+	if !policy.DryRun {
+		retentionSessionsDeletedTotal.Add(result.SessionsDeleted)
+		retentionJobsDeletedTotal.Add(result.JobsDeleted)
+		retentionArtifactsDeletedTotal.Add(result.ArtifactsDeleted)
+		retentionArtifactBytesReclaimedTotal.Add(result.ArtifactBytesReclaimed)
+	}
+	if result.SessionsDeleted+result.ArtifactsDeleted > 0 {
+		verb := "Deleted"
+		if policy.DryRun {
+			verb = "Dry-run: would delete"
+		}
+		recordEvent(psObj, corev1.EventTypeNormal, eventReasonRetentionCleanup, "%s %d sessions, %d jobs, %d artifacts (%d bytes)",
+			verb, result.SessionsDeleted, result.JobsDeleted, result.ArtifactsDeleted, result.ArtifactBytesReclaimed)
+	}
+
+	return updateProjectSettingsStatus(ns, psObj.GetName(), map[string]interface{}{
+		"retention": map[string]interface{}{
+			"lastRunTime":            now.UTC().Format(time.RFC3339),
+			"dryRun":                 policy.DryRun,
+			"sessionsDeleted":        result.SessionsDeleted,
+			"jobsDeleted":            result.JobsDeleted,
+			"artifactsDeleted":       result.ArtifactsDeleted,
+			"artifactBytesReclaimed": result.ArtifactBytesReclaimed,
+			"artifactsHeld":          result.ArtifactsHeld,
+		},
+	})
+}
+
+// sessionTTL returns how long after finishing a session is deleted: its
+// spec.ttlSecondsAfterFinished (0 deletes on the next pass), raised to the
+// cluster floor, or else retention.sessions. It reports false when the session
+// is kept indefinitely.
+func (p retentionPolicy) sessionTTL(s *unstructured.Unstructured, cp clusterPolicy) (time.Duration, bool) {
+	ttl, found, _ := unstructured.NestedInt64(s.Object, "spec", "ttlSecondsAfterFinished")
+	if !found {
+		return p.Sessions, p.Sessions > 0
+	}
+	return max(time.Duration(ttl)*time.Second, cp.MinSessions), true
+}
+
+// inputReferencedSessions returns the sessions named in spec.inputs of sessions
+// that have not finished yet; deleting them would leave those inputs dangling.
+func inputReferencedSessions(sessions []unstructured.Unstructured) map[string]bool {
+	out := map[string]bool{}
+	for i := range sessions {
+		if _, finished := sessionFinishedAt(&sessions[i]); finished {
+			continue
+		}
+		inputs, _, _ := unstructured.NestedSlice(sessions[i].Object, "spec", "inputs")
+		for _, raw := range inputs {
+			if m, ok := raw.(map[string]interface{}); ok {
+				if ref, _ := m["sessionRef"].(string); ref != "" {
+					out[ref] = true
+				}
+			}
+		}
+	}
+	return out
+}
+
+// sessionFinishedAt returns when a session reached a terminal phase.
+func sessionFinishedAt(obj *unstructured.Unstructured) (time.Time, bool) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Completed", "Failed", "Stopped", "Error":
+	default:
+		return time.Time{}, false
+	}
+	if ts, ok, _ := unstructured.NestedString(obj.Object, "status", "completionTime"); ok && ts != "" {
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			return t, true
+		}
+	}
+	// Terminal without a completion time: fall back to creation
+	return obj.GetCreationTimestamp().Time, true
+}
+
+func deleteExpiredSession(s *unstructured.Unstructured, dryRun bool, result *retentionResult) error {
+	ns, name := s.GetNamespace(), s.GetName()
+	jobName := fmt.Sprintf("%s-job", name)
+	if v, ok, _ := unstructured.NestedString(s.Object, "status", "jobName"); ok && v != "" {
+		jobName = v
+	}
+
+	if dryRun {
+		log.Printf("Retention (dry-run): would delete session %s/%s, job %s and its PVC data", ns, name, jobName)
+		result.SessionsDeleted++
+		result.JobsDeleted++
+		return nil
+	}
+
+	background := v1.DeletePropagationBackground
+	err := k8sClient.BatchV1().Jobs(ns).Delete(context.TODO(), jobName, v1.DeleteOptions{PropagationPolicy: &background})
+	if err == nil {
+		result.JobsDeleted++
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("delete job %s: %v", jobName, err)
+	}
+
+	if err := deleteSessionContent(ns, fmt.Sprintf("/sessions/%s", name)); err != nil {
+		log.Printf("Retention: failed to delete PVC data for %s/%s: %v", ns, name, err)
+	}
+
+	err = dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).Delete(context.TODO(), name, v1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	log.Printf("Retention: deleted session %s/%s", ns, name)
+	result.SessionsDeleted++
+	return nil
+}
+
+// deleteSessionContent removes a path from the namespace's content service PVC
+func deleteSessionContent(ns, path string) error {
+	body, _ := json.Marshal(map[string]string{"path": path})
+	url := fmt.Sprintf("http://ambient-content.%s.svc:8080/content/delete", ns)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("content delete failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// retryOfLabel on a retry names the original session (same label as the backend)
+	retryOfLabel               = "ambient-code.io/retry-of"
+	eventReasonRetry           = "Retrying"
+	defaultRetryBackoffSeconds = 30
+	maxRetryBackoff            = time.Hour
+)
+
+// sessionRetryPolicy mirrors AgenticSession spec.retryPolicy
+type sessionRetryPolicy struct {
+	MaxRetries int64
+	Backoff    time.Duration
+}
+
+func retryPolicyFromSpec(spec map[string]interface{}) (sessionRetryPolicy, bool) {
+	p := sessionRetryPolicy{Backoff: defaultRetryBackoffSeconds * time.Second}
+	p.MaxRetries, _, _ = unstructured.NestedInt64(spec, "retryPolicy", "maxRetries")
+	if v, found, _ := unstructured.NestedInt64(spec, "retryPolicy", "backoffSeconds"); found && v > 0 {
+		p.Backoff = time.Duration(v) * time.Second
+	}
+	return p, p.MaxRetries > 0
+}
+
+// delay returns the wait before the given attempt: the backoff doubled for each
+// earlier retry, capped at maxRetryBackoff
+func (p sessionRetryPolicy) delay(attempt int64) time.Duration {
+	d := p.Backoff
+	for i := int64(1); i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// pendingRetries holds namespace/name of failed sessions with a retry timer running
+var pendingRetries sync.Map
+
+// failedTransiently reports whether the runner Job itself failed or was killed
+// after its heartbeats stopped. Timeouts and configuration errors (phase Error)
+// would fail again and are not retried.
+func failedTransiently(obj *unstructured.Unstructured) bool {
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "Failed" {
+		return false
+	}
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range raw {
+		m, ok := c.(map[string]interface{})
+		if ok && m["type"] == conditionSucceeded && m["status"] == string(v1.ConditionFalse) {
+			return m["reason"] == "BackoffLimitExceeded" || m["reason"] == "Stalled"
+		}
+	}
+	return false
+}
+
+// scheduleSessionRetry starts a timer that creates the next attempt of a session
+// that failed transiently, if its retryPolicy allows another one. The due time
+// derives from the completion time, so a restarted operator resumes the wait.
+func scheduleSessionRetry(obj *unstructured.Unstructured) {
+	if !failedTransiently(obj) {
+		return
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	policy, ok := retryPolicyFromSpec(spec)
+	if !ok {
+		return
+	}
+	attempt, _, _ := unstructured.NestedInt64(spec, "retryAttempt")
+	if attempt >= policy.MaxRetries {
+		return
+	}
+	if done, _, _ := unstructured.NestedString(obj.Object, "status", "retry", "session"); done != "" {
+		return
+	}
+	finishedAt, _ := sessionFinishedAt(obj)
+	due := finishedAt.Add(policy.delay(attempt + 1))
+
+	ns, name := obj.GetNamespace(), obj.GetName()
+	key := ns + "/" + name
+	if _, running := pendingRetries.LoadOrStore(key, true); running {
+		return
+	}
+	if err := updateAgenticSessionStatus(ns, name, map[string]interface{}{
+		"retry": map[string]interface{}{"nextAttemptTime": due.UTC().Format(time.RFC3339)},
+	}); err != nil {
+		log.Printf("Retry: failed to record next attempt for %s: %v", key, err)
+	}
+	time.AfterFunc(time.Until(due), func() {
+		defer pendingRetries.Delete(key)
+		if err := createSessionRetry(ns, name); err != nil {
+			log.Printf("Retry: %s: %v", key, err)
+		}
+	})
+}
+
+// createSessionRetry creates the next attempt of a failed session and links it
+// from the session's status.retry
+func createSessionRetry(ns, name string) error {
+	gvr := getAgenticSessionResource()
+	source, err := dynamicClient.Resource(gvr).Namespace(ns).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if done, _, _ := unstructured.NestedString(source.Object, "status", "retry", "session"); done != "" {
+		return nil
+	}
+
+	retry := retrySessionObject(source)
+	_, err = dynamicClient.Resource(gvr).Namespace(ns).Create(context.TODO(), retry, v1.CreateOptions{})
+	switch {
+	case errors.IsAlreadyExists(err):
+		// Retried manually in the meantime; link that attempt instead
+	case err != nil:
+		recordEvent(source, corev1.EventTypeWarning, eventReasonRetry, "Failed to create retry %s: %v", retry.GetName(), err)
+		return fmt.Errorf("create %s: %v", retry.GetName(), err)
+	default:
+		attempt, _, _ := unstructured.NestedInt64(retry.Object, "spec", "retryAttempt")
+		maxRetries, _, _ := unstructured.NestedInt64(retry.Object, "spec", "retryPolicy", "maxRetries")
+		recordEvent(source, corev1.EventTypeNormal, eventReasonRetry, "Retrying as %s (attempt %d of %d)", retry.GetName(), attempt, maxRetries)
+	}
+	return updateAgenticSessionStatus(ns, name, map[string]interface{}{
+		"retry": map[string]interface{}{"session": retry.GetName()},
+	})
+}
+
+// retryLabels marks a retry with its original session and keeps the pipeline
+// step labels, so the pipeline follows the newest attempt of a step
+func retryLabels(source *unstructured.Unstructured, original string) map[string]interface{} {
+	labels := map[string]interface{}{retryOfLabel: original}
+	for _, key := range []string{pipelineLabel, pipelineStepLabel} {
+		if v := source.GetLabels()[key]; v != "" {
+			labels[key] = v
+		}
+	}
+	return labels
+}
+
+// retrySessionObject builds the next attempt of source: the same spec, named
+// <original>-retry-<attempt>. It matches the backend's retry endpoint.
+func retrySessionObject(source *unstructured.Unstructured) *unstructured.Unstructured {
+	spec := runtime.DeepCopyJSONValue(source.Object["spec"]).(map[string]interface{})
+	original, _ := spec["retryOf"].(string)
+	if original == "" {
+		original = source.GetName()
+	}
+	attempt, _, _ := unstructured.NestedInt64(spec, "retryAttempt")
+	attempt++
+	spec["retryOf"] = original
+	spec["retryAttempt"] = attempt
+
+	retry := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": source.GetAPIVersion(),
+		"kind":       source.GetKind(),
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("%s-retry-%d", original, attempt),
+			"namespace": source.GetNamespace(),
+			"labels":    retryLabels(source, original),
+		},
+		"spec": spec,
+	}}
+	// Attempts stay in the trace of the original request
+	if tp := source.GetAnnotations()[traceparentAnnotation]; tp != "" {
+		retry.SetAnnotations(map[string]string{traceparentAnnotation: tp})
+	}
+	return retry
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// With RUNNER_MTLS=true, for clusters without a service mesh, runners reach the
+// backend over mutual TLS. The operator keeps a platform CA in the backend's
+// namespace (RUNNER_CA_SECRET, created on first use unless an administrator
+// provides one) and issues every session a client certificate naming its
+// ServiceAccount, valid for the Job deadline. The backend serves runners on
+// RUNNER_MTLS_PORT and maps the certificate to the session.
+
+const (
+	defaultRunnerCASecret  = "ambient-runner-ca"
+	defaultRunnerMTLSPort  = "8443"
+	runnerTLSVolumeName    = "runner-tls"
+	runnerTLSMountPath     = "/var/run/secrets/ambient-tls"
+	runnerCAValidity       = 5 * 365 * 24 * time.Hour
+	runnerCertGracePeriod  = time.Hour
+	runnerCertClockSkew    = 5 * time.Minute
+	runnerCACommonName     = "ambient-code runner CA"
+	runnerCertSecretSuffix = "-tls"
+)
+
+func runnerMTLSEnabled() bool {
+	return os.Getenv("RUNNER_MTLS") == "true"
+}
+
+func runnerCASecretName() string {
+	if v := strings.TrimSpace(os.Getenv("RUNNER_CA_SECRET")); v != "" {
+		return v
+	}
+	return defaultRunnerCASecret
+}
+
+func runnerMTLSPort() string {
+	if v := strings.TrimSpace(os.Getenv("RUNNER_MTLS_PORT")); v != "" {
+		return v
+	}
+	return defaultRunnerMTLSPort
+}
+
+func sessionCertSecretName(session string) string {
+	return sessionServiceAccountName(session) + runnerCertSecretSuffix
+}
+
+type runnerCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+}
+
+var (
+	runnerCAMu     sync.Mutex
+	runnerCACached *runnerCA
+)
+
+// loadRunnerCA reads the platform CA, creating a self-signed one when the
+// Secret does not exist yet
+func loadRunnerCA() (*runnerCA, error) {
+	runnerCAMu.Lock()
+	defer runnerCAMu.Unlock()
+	if runnerCACached != nil && time.Now().Before(runnerCACached.cert.NotAfter) {
+		return runnerCACached, nil
+	}
+
+	secrets := k8sClient.CoreV1().Secrets(backendNamespace)
+	secret, err := secrets.Get(context.TODO(), runnerCASecretName(), v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		secret, err = newRunnerCASecret()
+		if err == nil {
+			var created *corev1.Secret
+			created, err = secrets.Create(context.TODO(), secret, v1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				created, err = secrets.Get(context.TODO(), secret.Name, v1.GetOptions{})
+			} else if err == nil {
+				log.Printf("Created runner CA %s/%s", backendNamespace, secret.Name)
+			}
+			secret = created
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("runner CA %s: %v", secret.Name, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("runner CA %s: %v", secret.Name, err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !cert.IsCA {
+		return nil, fmt.Errorf("runner CA %s must be an ECDSA CA certificate", secret.Name)
+	}
+	runnerCACached = &runnerCA{cert: cert, key: key, certPEM: secret.Data[corev1.TLSCertKey]}
+	return runnerCACached, nil
+}
+
+func newRunnerCASecret() (*corev1.Secret, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: runnerCACommonName},
+		NotBefore:             now.Add(-runnerCertClockSkew),
+		NotAfter:              now.Add(runnerCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	certPEM, keyPEM, err := signRunnerCert(tmpl, key, nil, key)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: runnerCASecretName(), Namespace: backendNamespace, Labels: map[string]string{"app": "ambient-runner-ca"}},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM},
+	}, nil
+}
+
+// signRunnerCert signs tmpl for key with the parent certificate and its key;
+// a nil parent self-signs
+func signRunnerCert(tmpl *x509.Certificate, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl.SerialNumber = serial
+	if parent == nil {
+		parent = tmpl
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// runnerCertLifetime is the longest a runner may live: its Job deadline grown
+// by every extension sessionPolicy allows (maxExtensions of
+// maxExtensionSeconds, or MAX_SESSION_EXTENSIONS and
+// MAX_SESSION_EXTENSION_SECONDS as on the backend) up to maxTimeoutSeconds,
+// then the debug keep-alive of a failed runner. Neither an extension nor the
+// keep-alive reissues the certificate, so it covers them from the start.
+func runnerCertLifetime(deadline, maxTimeout, debugKeepAlive int64, psSpec map[string]interface{}) time.Duration {
+	maxCount, maxSeconds := int64(3), int64(3600)
+	if v, err := strconv.ParseInt(os.Getenv("MAX_SESSION_EXTENSIONS"), 10, 64); err == nil {
+		maxCount = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("MAX_SESSION_EXTENSION_SECONDS"), 10, 64); err == nil {
+		maxSeconds = v
+	}
+	if v, found, _ := unstructured.NestedInt64(psSpec, "sessionPolicy", "maxExtensions"); found {
+		maxCount = v
+	}
+	if v, found, _ := unstructured.NestedInt64(psSpec, "sessionPolicy", "maxExtensionSeconds"); found {
+		maxSeconds = v
+	}
+	lifetime := deadline + max(maxCount, 0)*max(maxSeconds, 0)
+	if maxTimeout > 0 && lifetime > maxTimeout {
+		lifetime = max(maxTimeout, deadline)
+	}
+	return time.Duration(lifetime+debugKeepAlive) * time.Second
+}
+
+// ensureSessionClientCert issues the runner's client certificate into a Secret
+// owned by the session. The subject is the session ServiceAccount, with its UID
+// as the subject serial number so a session recreated under the same name does
+// not accept an earlier certificate. Each Job gets a fresh certificate valid
+// for the runner's lifetime (see runnerCertLifetime) and a grace period.
+func ensureSessionClientCert(session *unstructured.Unstructured, lifetime time.Duration) error {
+	ca, err := loadRunnerCA()
+	if err != nil {
+		return fmt.Errorf("failed to load runner CA: %v", err)
+	}
+	ns, name := session.GetNamespace(), session.GetName()
+	sa, err := k8sClient.CoreV1().ServiceAccounts(ns).Get(context.TODO(), sessionServiceAccountName(name), v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	notAfter := now.Add(lifetime + runnerCertGracePeriod)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	tmpl := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:   "system:serviceaccount:" + ns + ":" + sa.Name,
+			Organization: []string{ns},
+			SerialNumber: string(sa.UID),
+		},
+		NotBefore:   now.Add(-runnerCertClockSkew),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certPEM, keyPEM, err := signRunnerCert(tmpl, key, ca.cert, ca.key)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: sessionRunnerObjectMeta(session, sessionCertSecretName(name)),
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+			"ca.crt":                ca.certPEM,
+		},
+	}
+	secrets := k8sClient.CoreV1().Secrets(ns)
+	if _, err := secrets.Create(context.TODO(), secret, v1.CreateOptions{}); errors.IsAlreadyExists(err) {
+		existing, err := secrets.Get(context.TODO(), secret.Name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		existing.Data = secret.Data
+		if _, err := secrets.Update(context.TODO(), existing, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// applyRunnerMTLS mounts the session's client certificate into the runner and
+// points it at the backend's mutual TLS port
+func applyRunnerMTLS(pod *corev1.PodSpec, session string) {
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name: runnerTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+			SecretName: sessionCertSecretName(session),
+		}},
+	})
+	backendURL := fmt.Sprintf("https://backend-service.%s.svc.cluster.local:%s/api", backendNamespace, runnerMTLSPort())
+	for i := range pod.Containers {
+		c := &pod.Containers[i]
+		if c.Name != "ambient-code-runner" {
+			continue
+		}
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name: runnerTLSVolumeName, MountPath: runnerTLSMountPath, ReadOnly: true,
+		})
+		for j := range c.Env {
+			if c.Env[j].Name == "BACKEND_API_URL" {
+				c.Env[j].Value = backendURL
+			}
+		}
+		c.Env = append(c.Env,
+			corev1.EnvVar{Name: "RUNNER_TLS_CERT", Value: runnerTLSMountPath + "/" + corev1.TLSCertKey},
+			corev1.EnvVar{Name: "RUNNER_TLS_KEY", Value: runnerTLSMountPath + "/" + corev1.TLSPrivateKeyKey},
+			corev1.EnvVar{Name: "RUNNER_TLS_CA", Value: runnerTLSMountPath + "/ca.crt"})
+	}
+}
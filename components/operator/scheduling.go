@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const defaultGPUResourceName = "nvidia.com/gpu"
+
+// runnerScheduling is the node placement for a runner pod: ProjectSettings
+// spec.runnerScheduling merged with the session's spec.scheduling.
+type runnerScheduling struct {
+	NodeSelector map[string]string
+	Tolerations  []corev1.Toleration
+	NodeAffinity *corev1.NodeAffinity
+}
+
+// schedulingFromMap converts a {nodeSelector, tolerations, nodeAffinity} object
+// in Kubernetes JSON form into typed fields.
+func schedulingFromMap(m map[string]interface{}) (runnerScheduling, error) {
+	s := runnerScheduling{}
+	if m == nil {
+		return s, nil
+	}
+	s.NodeSelector, _, _ = unstructured.NestedStringMap(m, "nodeSelector")
+	if raw, ok, _ := unstructured.NestedSlice(m, "tolerations"); ok {
+		for i, t := range raw {
+			tm, ok := t.(map[string]interface{})
+			if !ok {
+				return s, fmt.Errorf("tolerations[%d] is not an object", i)
+			}
+			var tol corev1.Toleration
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(tm, &tol); err != nil {
+				return s, fmt.Errorf("tolerations[%d]: %v", i, err)
+			}
+			s.Tolerations = append(s.Tolerations, tol)
+		}
+	}
+	if raw, ok, _ := unstructured.NestedMap(m, "nodeAffinity"); ok {
+		na := &corev1.NodeAffinity{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, na); err != nil {
+			return s, fmt.Errorf("nodeAffinity: %v", err)
+		}
+		s.NodeAffinity = na
+	}
+	return s, nil
+}
+
+// resolveRunnerScheduling merges namespace and session placement. Namespace
+// nodeSelector keys win over the session's, tolerations are combined, and the
+// session's nodeAffinity replaces the namespace's. Sessions may only set
+// placement when the namespace allows it (allowSessionScheduling, default true).
+func resolveRunnerScheduling(spec, psSpec map[string]interface{}) (runnerScheduling, error) {
+	nsRaw, _, _ := unstructured.NestedMap(psSpec, "runnerScheduling")
+	out, err := schedulingFromMap(nsRaw)
+	if err != nil {
+		return out, fmt.Errorf("ProjectSettings runnerScheduling: %v", err)
+	}
+	sessRaw, found, _ := unstructured.NestedMap(spec, "scheduling")
+	if !found {
+		return out, nil
+	}
+	if allowed, ok, _ := unstructured.NestedBool(psSpec, "runnerScheduling", "allowSessionScheduling"); ok && !allowed {
+		return out, fmt.Errorf("project policy does not allow sessions to set scheduling")
+	}
+	sess, err := schedulingFromMap(sessRaw)
+	if err != nil {
+		return out, fmt.Errorf("spec.scheduling: %v", err)
+	}
+	merged := map[string]string{}
+	for k, v := range sess.NodeSelector {
+		merged[k] = v
+	}
+	for k, v := range out.NodeSelector {
+		merged[k] = v
+	}
+	if len(merged) > 0 {
+		out.NodeSelector = merged
+	}
+	out.Tolerations = append(out.Tolerations, sess.Tolerations...)
+	if sess.NodeAffinity != nil {
+		out.NodeAffinity = sess.NodeAffinity
+	}
+	return out, nil
+}
+
+// apply sets placement on the runner pod, keeping its content-service pod affinity
+func (s runnerScheduling) apply(pod *corev1.PodSpec) {
+	if len(s.NodeSelector) > 0 {
+		pod.NodeSelector = s.NodeSelector
+	}
+	pod.Tolerations = append(pod.Tolerations, s.Tolerations...)
+	if s.NodeAffinity != nil {
+		if pod.Affinity == nil {
+			pod.Affinity = &corev1.Affinity{}
+		}
+		pod.Affinity.NodeAffinity = s.NodeAffinity
+	}
+}
+
+// gpuResourceName is the extended resource sessions request with resourceOverrides.gpu
+func gpuResourceName(psSpec map[string]interface{}) corev1.ResourceName {
+	if v, _, _ := unstructured.NestedString(psSpec, "sessionPolicy", "gpuResourceName"); strings.TrimSpace(v) != "" {
+		return corev1.ResourceName(strings.TrimSpace(v))
+	}
+	return defaultGPUResourceName
+}
+
+// sessionGPUs returns spec.resourceOverrides.gpu capped by sessionPolicy.maxResources.gpu.
+// Extended resources cannot be overcommitted, so request and limit are equal.
+func sessionGPUs(spec, psSpec map[string]interface{}) (resource.Quantity, string) {
+	v, _, _ := unstructured.NestedString(spec, "resourceOverrides", "gpu")
+	q, err := resource.ParseQuantity(strings.TrimSpace(v))
+	if v == "" || err != nil || q.Sign() <= 0 {
+		return resource.Quantity{}, ""
+	}
+	if maxStr, _, _ := unstructured.NestedString(psSpec, "sessionPolicy", "maxResources", "gpu"); maxStr != "" {
+		if max, err := resource.ParseQuantity(maxStr); err == nil && q.Cmp(max) > 0 {
+			return max, fmt.Sprintf("gpu %s > %s", q.String(), max.String())
+		}
+	}
+	return q, ""
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// The project PVC is mounted read-only at /workspace, so scratch space gets its own path
+	scratchMountPath      = "/scratch"
+	scratchVolumeName     = "scratch"
+	eventReasonScratchPVC = "ScratchVolume"
+)
+
+// sessionScratch is the per-session scratch PVC requested by spec.scratch or
+// ProjectSettings spec.sessionPolicy.scratch
+type sessionScratch struct {
+	Size         resource.Quantity
+	StorageClass string
+}
+
+func scratchPVCName(session string) string {
+	return session + "-scratch"
+}
+
+// sessionScratchFromSpec returns the scratch volume for a session, if any. The size
+// is spec.scratch.size, else sessionPolicy.scratch.defaultSize, capped by maxSize.
+// The storage class is spec.resourceOverrides.storageClass, else the policy's.
+func sessionScratchFromSpec(spec, psSpec map[string]interface{}) (sessionScratch, string, bool) {
+	size, _, _ := unstructured.NestedString(spec, "scratch", "size")
+	if strings.TrimSpace(size) == "" {
+		size, _, _ = unstructured.NestedString(psSpec, "sessionPolicy", "scratch", "defaultSize")
+	}
+	q, err := resource.ParseQuantity(strings.TrimSpace(size))
+	if size == "" || err != nil || q.Sign() <= 0 {
+		return sessionScratch{}, "", false
+	}
+	s := sessionScratch{Size: q}
+	capped := ""
+	if maxStr, _, _ := unstructured.NestedString(psSpec, "sessionPolicy", "scratch", "maxSize"); maxStr != "" {
+		if max, err := resource.ParseQuantity(maxStr); err == nil && q.Cmp(max) > 0 {
+			capped = fmt.Sprintf("scratch %s > %s", q.String(), max.String())
+			s.Size = max
+		}
+	}
+	s.StorageClass, _, _ = unstructured.NestedString(spec, "resourceOverrides", "storageClass")
+	if s.StorageClass == "" {
+		s.StorageClass, _, _ = unstructured.NestedString(psSpec, "sessionPolicy", "scratch", "storageClass")
+	}
+	return s, capped, true
+}
+
+// ensureSessionScratchPVC creates the session's scratch PVC, owned by the session
+// so that deleting the session also removes it.
+func ensureSessionScratchPVC(session *unstructured.Unstructured, s sessionScratch) error {
+	ns, name := session.GetNamespace(), session.GetName()
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      scratchPVCName(name),
+			Namespace: ns,
+			Labels:    map[string]string{"app": "ambient-scratch", "agentic-session": name},
+			OwnerReferences: []v1.OwnerReference{{
+				APIVersion: "vteam.ambient-code/v1",
+				Kind:       "AgenticSession",
+				Name:       name,
+				UID:        session.GetUID(),
+				Controller: boolPtr(true),
+			}},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: s.Size},
+			},
+		},
+	}
+	if s.StorageClass != "" {
+		pvc.Spec.StorageClassName = &s.StorageClass
+	}
+	if _, err := k8sClient.CoreV1().PersistentVolumeClaims(ns).Create(context.TODO(), pvc, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// mountSessionScratch adds the scratch PVC to the runner pod at /scratch
+func mountSessionScratch(pod *corev1.PodSpec, session string) {
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name: scratchVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: scratchPVCName(session)},
+		},
+	})
+	for i := range pod.Containers {
+		pod.Containers[i].VolumeMounts = append(pod.Containers[i].VolumeMounts, corev1.VolumeMount{Name: scratchVolumeName, MountPath: scratchMountPath})
+		pod.Containers[i].Env = append(pod.Containers[i].Env, corev1.EnvVar{Name: "SCRATCH_DIR", Value: scratchMountPath})
+	}
+}
+
+// releaseSessionScratch deletes a finished session's scratch PVC once
+// ProjectSettings spec.retention.scratch has elapsed; unset deletes it as soon as
+// the session ends. It is called when a finished session is reconciled and on
+// every retention pass.
+func releaseSessionScratch(session *unstructured.Unstructured, retain time.Duration) {
+	end, done := sessionFinishedAt(session)
+	if !done || time.Since(end) < retain {
+		return
+	}
+	ns, name := session.GetNamespace(), session.GetName()
+	err := k8sClient.CoreV1().PersistentVolumeClaims(ns).Delete(context.TODO(), scratchPVCName(name), v1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete scratch PVC for %s/%s: %v", ns, name, err)
+		return
+	}
+	recordEvent(session, corev1.EventTypeNormal, eventReasonScratchPVC, "Deleted scratch volume %s", scratchPVCName(name))
+}
+
+// hasSessionScratch reports whether the operator created a scratch PVC for the session
+func hasSessionScratch(session *unstructured.Unstructured) bool {
+	_, found, _ := unstructured.NestedString(session.Object, "status", "scratch", "claimName")
+	return found
+}
+
+// releaseFinishedSessionScratch applies the namespace's scratch retention to a session that just finished
+func releaseFinishedSessionScratch(session *unstructured.Unstructured) {
+	var retain time.Duration
+	if psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(session.GetNamespace()).Get(context.TODO(), "projectsettings", v1.GetOptions{}); err == nil {
+		psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+		if policy, err := retentionPolicyFromSpec(psSpec); err == nil {
+			retain = policy.Scratch
+		}
+	}
+	releaseSessionScratch(session, retain)
+}
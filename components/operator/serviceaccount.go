@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	runnerTokenVolumeName = "runner-token"
+	runnerTokenMountPath  = "/var/run/secrets/ambient"
+	// Bound tokens are rotated by the kubelet at 80% of their lifetime; 600 is
+	// the shortest lifetime the API server accepts
+	runnerTokenExpirationSeconds = 600
+	defaultRunnerTokenAudience   = "ambient-backend"
+)
+
+// runnerTokenAudience is the audience runner tokens are bound to, which the
+// backend reviews them for (RUNNER_TOKEN_AUDIENCE). Set but empty, tokens are
+// API server tokens as before.
+func runnerTokenAudience() string {
+	if v, ok := os.LookupEnv("RUNNER_TOKEN_AUDIENCE"); ok {
+		return strings.TrimSpace(v)
+	}
+	return defaultRunnerTokenAudience
+}
+
+func sessionServiceAccountName(session string) string {
+	return "ambient-session-" + session
+}
+
+// sessionRunnerRules is everything a runner does with its token: the backend's
+// project check (list), status updates on its own session, and artifact uploads,
+// which require update on the session. Everything except list is limited to the
+// session by name.
+func sessionRunnerRules(session string) []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{"vteam.ambient-code"},
+			Resources: []string{"agenticsessions"},
+			Verbs:     []string{"list"},
+		},
+		{
+			APIGroups:     []string{"vteam.ambient-code"},
+			Resources:     []string{"agenticsessions", "agenticsessions/status"},
+			ResourceNames: []string{session},
+			Verbs:         []string{"get", "update", "patch"},
+		},
+	}
+}
+
+// sessionRunnerObjectMeta labels a runner object of the session and makes the
+// session own it
+func sessionRunnerObjectMeta(session *unstructured.Unstructured, objName string) v1.ObjectMeta {
+	return v1.ObjectMeta{
+		Name:      objName,
+		Namespace: session.GetNamespace(),
+		Labels:    map[string]string{"app": "ambient-runner", "agentic-session": session.GetName()},
+		OwnerReferences: []v1.OwnerReference{{
+			APIVersion: "vteam.ambient-code/v1",
+			Kind:       "AgenticSession",
+			Name:       session.GetName(),
+			UID:        session.GetUID(),
+			Controller: boolPtr(true),
+		}},
+	}
+}
+
+// ensureSessionServiceAccount creates the runner's ServiceAccount, Role and
+// RoleBinding, all owned by the session. An existing Role is narrowed to the
+// current rules, which replaces the namespace-wide grants older backends created.
+func ensureSessionServiceAccount(session *unstructured.Unstructured) error {
+	ns, name := session.GetNamespace(), session.GetName()
+	saName := sessionServiceAccountName(name)
+	meta := func(objName string) v1.ObjectMeta { return sessionRunnerObjectMeta(session, objName) }
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta:                   meta(saName),
+		AutomountServiceAccountToken: boolPtr(false),
+	}
+	if _, err := k8sClient.CoreV1().ServiceAccounts(ns).Create(context.TODO(), sa, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	role := &rbacv1.Role{ObjectMeta: meta(saName + "-role"), Rules: sessionRunnerRules(name)}
+	if _, err := k8sClient.RbacV1().Roles(ns).Create(context.TODO(), role, v1.CreateOptions{}); errors.IsAlreadyExists(err) {
+		existing, err := k8sClient.RbacV1().Roles(ns).Get(context.TODO(), role.Name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		existing.Rules = role.Rules
+		if _, err := k8sClient.RbacV1().Roles(ns).Update(context.TODO(), existing, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: meta(saName + "-rb"),
+		RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: role.Name},
+		Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: saName, Namespace: ns}},
+	}
+	if _, err := k8sClient.RbacV1().RoleBindings(ns).Create(context.TODO(), rb, v1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// applySessionServiceAccount runs the pod as the session's ServiceAccount. Only the
+// runner container gets a token: a short-lived token bound to the pod and to the
+// backend's audience, projected into /var/run/secrets/ambient, which the runner
+// re-reads for every backend call.
+func applySessionServiceAccount(pod *corev1.PodSpec, session string) {
+	pod.ServiceAccountName = sessionServiceAccountName(session)
+	pod.AutomountServiceAccountToken = boolPtr(false)
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name: runnerTokenVolumeName,
+		VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+			Sources: []corev1.VolumeProjection{{
+				ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+					Path:              "token",
+					Audience:          runnerTokenAudience(),
+					ExpirationSeconds: int64Ptr(runnerTokenExpirationSeconds),
+				},
+			}},
+		}},
+	})
+	for i := range pod.Containers {
+		if pod.Containers[i].Name != "ambient-code-runner" {
+			continue
+		}
+		pod.Containers[i].VolumeMounts = append(pod.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name: runnerTokenVolumeName, MountPath: runnerTokenMountPath, ReadOnly: true,
+		})
+		pod.Containers[i].Env = append(pod.Containers[i].Env,
+			corev1.EnvVar{Name: "AUTH_MODE", Value: "kubernetes"},
+			corev1.EnvVar{Name: "RUNNER_TOKEN_PATH", Value: runnerTokenMountPath + "/token"})
+	}
+}
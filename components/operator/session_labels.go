@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"regexp"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// sessionPhaseLabel and sessionFrameworkLabel let the backend filter session
+	// lists with a label selector; they mirror status.phase and spec.framework
+	sessionPhaseLabel     = "ambient-code.io/phase"
+	sessionFrameworkLabel = "ambient-code.io/framework"
+)
+
+var labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+
+// syncSessionIndexLabels patches the index labels when they no longer match
+// the session. Failures are logged only: the labels serve list filtering and
+// are corrected on the next status update.
+func syncSessionIndexLabels(obj *unstructured.Unstructured, phase string) {
+	want := map[string]string{}
+	if phase != "" {
+		want[sessionPhaseLabel] = phase
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if fw := sessionFramework(spec); labelValuePattern.MatchString(fw) {
+		want[sessionFrameworkLabel] = fw
+	}
+	current := obj.GetLabels()
+	changed := map[string]string{}
+	for k, v := range want {
+		if current[k] != v {
+			changed[k] = v
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	patch, _ := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"labels": changed}})
+	_, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(obj.GetNamespace()).Patch(context.TODO(), obj.GetName(), types.MergePatchType, patch, v1.PatchOptions{})
+	if err != nil {
+		log.Printf("Failed to update index labels of AgenticSession %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+}
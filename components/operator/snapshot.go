@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// defaultWorkspaceSnapshotMaxBytes matches the backend's default artifact upload limit
+const defaultWorkspaceSnapshotMaxBytes = 100 << 20
+
+// workspaceSnapshotPolicy mirrors ProjectSettings sessionPolicy.workspaceSnapshot:
+// whether runners upload their final workspace as an archive artifact, the
+// largest archive they upload and the paths left out of it
+type workspaceSnapshotPolicy struct {
+	Enabled  bool
+	MaxBytes int64
+	Ignore   []string
+}
+
+func workspaceSnapshotPolicyFromSpec(psSpec map[string]interface{}) workspaceSnapshotPolicy {
+	p := workspaceSnapshotPolicy{MaxBytes: defaultWorkspaceSnapshotMaxBytes}
+	p.Enabled, _, _ = unstructured.NestedBool(psSpec, "sessionPolicy", "workspaceSnapshot", "enabled")
+	if v, _, _ := unstructured.NestedString(psSpec, "sessionPolicy", "workspaceSnapshot", "maxSize"); v != "" {
+		if q, err := resource.ParseQuantity(v); err == nil && q.Sign() > 0 {
+			p.MaxBytes = q.Value()
+		}
+	}
+	p.Ignore, _, _ = unstructured.NestedStringSlice(psSpec, "sessionPolicy", "workspaceSnapshot", "ignore")
+	return p
+}
+
+// env is the runner configuration for the snapshot; sessions cannot override it
+func (p workspaceSnapshotPolicy) env() map[string]string {
+	return map[string]string{
+		"WORKSPACE_SNAPSHOT":           strconv.FormatBool(p.Enabled),
+		"WORKSPACE_SNAPSHOT_MAX_BYTES": strconv.FormatInt(p.MaxBytes, 10),
+		"WORKSPACE_SNAPSHOT_IGNORE":    strings.Join(p.Ignore, ","),
+	}
+}
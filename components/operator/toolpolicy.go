@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// runnerToolPolicy is what the runner's tool gatekeeper enforces: the cluster
+// and namespace blocklists, the namespace's optional sessionPolicy.allowedTools
+// and sessionPolicy.maxToolViolations, after which the session fails. A
+// session's spec.tools can only narrow it.
+type runnerToolPolicy struct {
+	Blocked       []string
+	Allowed       []string
+	MaxViolations int64
+}
+
+func runnerToolPolicyFromSpec(cluster clusterPolicy, psSpec map[string]interface{}) runnerToolPolicy {
+	p := runnerToolPolicy{Blocked: mergedBlocklist(cluster.BlockedTools, psSpec, "blockedTools")}
+	allowed, _, _ := unstructured.NestedStringSlice(psSpec, "sessionPolicy", "allowedTools")
+	for _, t := range allowed {
+		if t = strings.TrimSpace(t); t != "" {
+			p.Allowed = append(p.Allowed, t)
+		}
+	}
+	if v, found, _ := unstructured.NestedInt64(psSpec, "sessionPolicy", "maxToolViolations"); found && v > 0 {
+		p.MaxViolations = v
+	}
+	return p
+}
+
+// narrowedBy applies a session's spec.tools: its blocked tools are added and
+// its allowed tools replace the allowlist, less any the namespace does not
+// permit. When none of them remain permitted the namespace's list applies.
+func (p runnerToolPolicy) narrowedBy(sessionSpec map[string]interface{}) runnerToolPolicy {
+	blocked, _, _ := unstructured.NestedStringSlice(sessionSpec, "tools", "blocked")
+	for _, t := range blocked {
+		if t = strings.TrimSpace(t); t != "" && !slices.Contains(p.Blocked, t) {
+			p.Blocked = append(p.Blocked, t)
+		}
+	}
+	allowed, _, _ := unstructured.NestedStringSlice(sessionSpec, "tools", "allowed")
+	var narrowed []string
+	for _, t := range allowed {
+		t = strings.TrimSpace(t)
+		if t == "" || slices.Contains(p.Blocked, t) || (len(p.Allowed) > 0 && !slices.Contains(p.Allowed, t)) {
+			continue
+		}
+		narrowed = append(narrowed, t)
+	}
+	if len(narrowed) > 0 {
+		p.Allowed = narrowed
+	}
+	return p
+}
+
+// env is the runner configuration for the gatekeeper; sessions cannot override it
+func (p runnerToolPolicy) env() map[string]string {
+	return map[string]string{
+		"BLOCKED_TOOLS":       strings.Join(p.Blocked, ","),
+		"ALLOWED_TOOLS":       strings.Join(p.Allowed, ","),
+		"MAX_TOOL_VIOLATIONS": strconv.FormatInt(p.MaxViolations, 10),
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ambient-code-shared/money"
+)
+
+const usageReportCheckInterval = time.Hour
+
+// runUsageReportLoop delivers the previous month's usage report to namespaces
+// with spec.notifications.usageReport enabled, once per month
+func runUsageReportLoop() {
+	for {
+		time.Sleep(usageReportCheckInterval)
+		nsList, err := k8sClient.CoreV1().Namespaces().List(context.TODO(), v1.ListOptions{
+			LabelSelector: "ambient-code.io/managed=true",
+		})
+		if err != nil {
+			log.Printf("Usage report: failed to list managed namespaces: %v", err)
+			continue
+		}
+		for _, ns := range nsList.Items {
+			if err := deliverUsageReport(ns.Name, time.Now().UTC()); err != nil {
+				log.Printf("Usage report: delivery failed in %s: %v", ns.Name, err)
+			}
+		}
+	}
+}
+
+// deliverUsageReport sends report.usage for the month before now unless
+// status.notifications.usageReportMonth shows it was already sent
+func deliverUsageReport(ns string, now time.Time) error {
+	psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	if enabled, _, _ := unstructured.NestedBool(psSpec, "notifications", "usageReport"); !enabled {
+		return nil
+	}
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	label := month.Format("2006-01")
+	if sent, _, _ := unstructured.NestedString(psObj.Object, "status", "notifications", "usageReportMonth"); sent == label {
+		return nil
+	}
+	details, err := usageReportDetails(ns, month)
+	if err != nil {
+		return err
+	}
+	// Record the month first: a failed endpoint is reported in
+	// status.notifications.lastFailure rather than retried every hour
+	mergeNotificationsStatus(ns, psObj, map[string]interface{}{"usageReportMonth": label})
+	notifyProject(ns, notifyUsageReport, details)
+	return nil
+}
+
+// usageReportDetails summarizes the sessions started in month the way the
+// backend's GET .../reports/usage does, without artifact storage; reportUrl
+// links the full CSV when AMBIENT_UI_URL is set
+func usageReportDetails(ns string, month time.Time) (map[string]interface{}, error) {
+	sessions, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(ns).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %v", err)
+	}
+	end := month.AddDate(0, 1, 0)
+	count, failed := 0, 0
+	costByModel := map[string]money.USD{}
+	byTrigger := map[string]int{}
+	total := sumSessionUsage(sessions.Items, month, end)
+	for i := range sessions.Items {
+		s := &sessions.Items[i]
+		if started := sessionStartTime(s); started.Before(month) || !started.Before(end) {
+			continue
+		}
+		count++
+		if phase, _, _ := unstructured.NestedString(s.Object, "status", "phase"); phase == "Failed" || phase == "Error" {
+			failed++
+		}
+
+		model, _, _ := unstructured.NestedString(s.Object, "spec", "llmSettings", "model")
+		if model == "" {
+			model = "unknown"
+		}
+		costByModel[model] += sessionCostUSD(s)
+		trigger := "manual"
+		if src, _, _ := unstructured.NestedString(s.Object, "spec", "trigger", "source"); src != "" {
+			trigger = src
+			if repo, _, _ := unstructured.NestedString(s.Object, "spec", "trigger", "repo"); repo != "" {
+				trigger += "/" + repo
+			}
+		}
+		byTrigger[trigger]++
+	}
+
+	details := map[string]interface{}{
+		"month":        month.Format("2006-01"),
+		"sessions":     count,
+		"failed":       failed,
+		"costUSD":      total.CostUSD.Cents(),
+		"inputTokens":  total.InputTokens,
+		"outputTokens": total.OutputTokens,
+		"apiCalls":     total.APICalls,
+	}
+	if len(costByModel) > 0 {
+		models := make([]string, 0, len(costByModel))
+		for m := range costByModel {
+			models = append(models, m)
+		}
+		sort.Slice(models, func(i, j int) bool { return costByModel[models[i]] > costByModel[models[j]] })
+		parts := make([]string, 0, len(models))
+		for _, m := range models {
+			parts = append(parts, fmt.Sprintf("%s $%s", m, costByModel[m].Cents()))
+		}
+		details["models"] = strings.Join(parts, ", ")
+	}
+	if len(byTrigger) > 0 {
+		triggers := make([]string, 0, len(byTrigger))
+		for t := range byTrigger {
+			triggers = append(triggers, t)
+		}
+		sort.Slice(triggers, func(i, j int) bool {
+			if byTrigger[triggers[i]] != byTrigger[triggers[j]] {
+				return byTrigger[triggers[i]] > byTrigger[triggers[j]]
+			}
+			return triggers[i] < triggers[j]
+		})
+		if len(triggers) > 5 {
+			triggers = triggers[:5]
+		}
+		parts := make([]string, 0, len(triggers))
+		for _, t := range triggers {
+			parts = append(parts, fmt.Sprintf("%s (%d)", t, byTrigger[t]))
+		}
+		details["topTriggers"] = strings.Join(parts, ", ")
+	}
+	if base := strings.TrimRight(os.Getenv("AMBIENT_UI_URL"), "/"); base != "" {
+		details["reportUrl"] = fmt.Sprintf("%s/api/projects/%s/reports/usage?month=%s&format=csv", base, url.PathEscape(ns), month.Format("2006-01"))
+	}
+	return details, nil
+}
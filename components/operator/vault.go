@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	eventReasonCredentialLeased  = "CredentialLeased"
+	eventReasonCredentialRevoked = "CredentialRevoked"
+	eventReasonCredentialFailed  = "CredentialLeaseFailed"
+	defaultProviderKeyEnv        = "ANTHROPIC_API_KEY"
+	serviceAccountTokenPath      = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// vaultKeySource mirrors ProjectSettings spec.providerKeys.vault
+type vaultKeySource struct {
+	Address   string
+	AuthMount string
+	Role      string
+	Path      string
+	Key       string
+	EnvName   string
+	TTL       string
+}
+
+// vaultLease is a provider key lease held for a running session
+type vaultLease struct {
+	Source     vaultKeySource
+	LeaseID    string
+	SecretName string
+}
+
+// activeLeases tracks leases by namespace/session so they can be revoked even
+// after the session object is gone
+var (
+	activeLeasesMu sync.Mutex
+	activeLeases   = map[string]vaultLease{}
+)
+
+var vaultHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+func vaultKeySourceFromSpec(psSpec map[string]interface{}) *vaultKeySource {
+	m, found, _ := unstructured.NestedMap(psSpec, "providerKeys", "vault")
+	if !found {
+		return nil
+	}
+	src := &vaultKeySource{}
+	src.Address, _ = m["address"].(string)
+	src.AuthMount, _ = m["authMount"].(string)
+	src.Role, _ = m["role"].(string)
+	src.Path, _ = m["path"].(string)
+	src.Key, _ = m["key"].(string)
+	src.EnvName, _ = m["envName"].(string)
+	src.TTL, _ = m["ttl"].(string)
+	if src.Address == "" {
+		src.Address = os.Getenv("VAULT_ADDR")
+	}
+	if src.AuthMount == "" {
+		src.AuthMount = "kubernetes"
+	}
+	if src.Key == "" {
+		src.Key = "api_key"
+	}
+	if src.EnvName == "" {
+		src.EnvName = defaultProviderKeyEnv
+	}
+	if src.Address == "" || src.Role == "" || src.Path == "" {
+		return nil
+	}
+	src.Address = strings.TrimRight(src.Address, "/")
+	return src
+}
+
+// vaultRequest performs a Vault API call and decodes the JSON response into out
+func vaultRequest(method, u, token string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := vaultHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("vault %s %s: status %d: %s", method, u, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// vaultLogin authenticates with the operator's ServiceAccount token via Vault's Kubernetes auth method
+func vaultLogin(src vaultKeySource) (string, error) {
+	jwt, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("read service account token: %v", err)
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	u := fmt.Sprintf("%s/v1/auth/%s/login", src.Address, strings.Trim(src.AuthMount, "/"))
+	if err := vaultRequest(http.MethodPost, u, "", map[string]string{"role": src.Role, "jwt": strings.TrimSpace(string(jwt))}, &resp); err != nil {
+		return "", err
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault login returned no token")
+	}
+	return resp.Auth.ClientToken, nil
+}
+
+// leaseProviderKey requests a short-lived provider key for the session from Vault,
+// stores it in a per-session Secret owned by the session, and returns the env var
+// that references it. It returns nil when the namespace has no Vault source.
+func leaseProviderKey(session *unstructured.Unstructured) (*corev1.EnvVar, error) {
+	ns, name := session.GetNamespace(), session.GetName()
+	psObj, err := dynamicClient.Resource(getProjectSettingsResource()).Namespace(ns).Get(context.TODO(), "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	psSpec, _, _ := unstructured.NestedMap(psObj.Object, "spec")
+	src := vaultKeySourceFromSpec(psSpec)
+	if src == nil {
+		return nil, nil
+	}
+
+	token, err := vaultLogin(*src)
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/v1/%s", src.Address, strings.Trim(src.Path, "/"))
+	if src.TTL != "" {
+		u += "?ttl=" + url.QueryEscape(src.TTL)
+	}
+	var resp struct {
+		LeaseID       string                 `json:"lease_id"`
+		LeaseDuration int64                  `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := vaultRequest(http.MethodGet, u, token, nil, &resp); err != nil {
+		return nil, err
+	}
+	data := resp.Data
+	// KV v2 nests the secret under data.data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		data = inner
+	}
+	value, _ := data[src.Key].(string)
+	if value == "" {
+		return nil, fmt.Errorf("vault path %s has no %q field", src.Path, src.Key)
+	}
+
+	secretName := fmt.Sprintf("%s-provider-key", name)
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      secretName,
+			Namespace: ns,
+			Labels:    map[string]string{"agentic-session": name},
+			OwnerReferences: []v1.OwnerReference{{
+				APIVersion: "vteam.ambient-code/v1alpha1",
+				Kind:       "AgenticSession",
+				Name:       name,
+				UID:        session.GetUID(),
+				Controller: boolPtr(true),
+			}},
+		},
+		StringData: map[string]string{src.Key: value},
+	}
+	if _, err := k8sClient.CoreV1().Secrets(ns).Create(context.TODO(), secret, v1.CreateOptions{}); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			revokeVaultLease(*src, resp.LeaseID)
+			return nil, fmt.Errorf("create provider key secret: %v", err)
+		}
+		if _, err := k8sClient.CoreV1().Secrets(ns).Update(context.TODO(), secret, v1.UpdateOptions{}); err != nil {
+			revokeVaultLease(*src, resp.LeaseID)
+			return nil, fmt.Errorf("update provider key secret: %v", err)
+		}
+	}
+
+	activeLeasesMu.Lock()
+	activeLeases[ns+"/"+name] = vaultLease{Source: *src, LeaseID: resp.LeaseID, SecretName: secretName}
+	activeLeasesMu.Unlock()
+
+	lease := map[string]interface{}{
+		"provider":   "vault",
+		"secretName": secretName,
+		"leasedAt":   time.Now().UTC().Format(time.RFC3339),
+	}
+	if resp.LeaseID != "" {
+		lease["leaseId"] = resp.LeaseID
+	}
+	if resp.LeaseDuration > 0 {
+		lease["expiresAt"] = time.Now().Add(time.Duration(resp.LeaseDuration) * time.Second).UTC().Format(time.RFC3339)
+	}
+	_ = updateAgenticSessionStatus(ns, name, map[string]interface{}{"credentialLease": lease})
+	recordEvent(session, corev1.EventTypeNormal, eventReasonCredentialLeased, "Leased provider key from Vault for %ds", resp.LeaseDuration)
+
+	return &corev1.EnvVar{
+		Name: src.EnvName,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			Key:                  src.Key,
+		}},
+	}, nil
+}
+
+func revokeVaultLease(src vaultKeySource, leaseID string) error {
+	if leaseID == "" {
+		return nil
+	}
+	token, err := vaultLogin(src)
+	if err != nil {
+		return err
+	}
+	return vaultRequest(http.MethodPut, src.Address+"/v1/sys/leases/revoke", token, map[string]string{"lease_id": leaseID}, nil)
+}
+
+// releaseProviderKey revokes the session's Vault lease and deletes its key Secret.
+// Safe to call for sessions that never held a lease.
+func releaseProviderKey(ns, name string, sessionObj *unstructured.Unstructured) {
+	key := ns + "/" + name
+	activeLeasesMu.Lock()
+	lease, ok := activeLeases[key]
+	delete(activeLeases, key)
+	activeLeasesMu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := revokeVaultLease(lease.Source, lease.LeaseID); err != nil {
+		log.Printf("Failed to revoke Vault lease for %s: %v", key, err)
+		recordEvent(sessionObj, corev1.EventTypeWarning, eventReasonCredentialFailed, "Failed to revoke provider key lease: %v", err)
+	}
+	if err := k8sClient.CoreV1().Secrets(ns).Delete(context.TODO(), lease.SecretName, v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		log.Printf("Failed to delete provider key secret %s/%s: %v", ns, lease.SecretName, err)
+	}
+	if sessionObj != nil {
+		_ = updateAgenticSessionStatus(ns, name, map[string]interface{}{
+			"credentialLease": map[string]interface{}{
+				"provider":   "vault",
+				"secretName": lease.SecretName,
+				"revokedAt":  time.Now().UTC().Format(time.RFC3339),
+			},
+		})
+		recordEvent(sessionObj, corev1.EventTypeNormal, eventReasonCredentialRevoked, "Revoked provider key lease")
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Runner workloads a session can execute as. Job is the default; PipelineRun
+// runs the same pod spec as a single-task Tekton PipelineRun for clusters that
+// standardize on Tekton.
+const (
+	workloadJob         = "Job"
+	workloadPipelineRun = "PipelineRun"
+)
+
+// getPipelineRunResource returns the GroupVersionResource for Tekton PipelineRuns
+func getPipelineRunResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "tekton.dev",
+		Version:  "v1",
+		Resource: "pipelineruns",
+	}
+}
+
+// sessionWorkload picks the runner workload: the project's runnerWorkload
+// when set, otherwise the framework's workload, otherwise a Job.
+func sessionWorkload(psSpec map[string]interface{}, framework runnerFramework) string {
+	project, _, _ := unstructured.NestedString(psSpec, "runnerWorkload")
+	for _, w := range []string{project, framework.Workload} {
+		if w == workloadJob || w == workloadPipelineRun {
+			return w
+		}
+	}
+	return workloadJob
+}
+
+// pipelineRunName is the PipelineRun created for a session
+func pipelineRunName(session string) string {
+	return fmt.Sprintf("%s-run", session)
+}
+
+// sessionPipelineRunExists reports whether the session's PipelineRun was
+// already created. Clusters without Tekton report false.
+func sessionPipelineRunExists(namespace, session string) bool {
+	_, err := dynamicClient.Resource(getPipelineRunResource()).Namespace(namespace).Get(context.TODO(), pipelineRunName(session), v1.GetOptions{})
+	return err == nil
+}
+
+// pipelineRunFromJob converts the runner Job into a PipelineRun with one
+// embedded task. Init containers become steps ahead of the runner; native
+// sidecars and extra containers become Tekton sidecars. Pod-level settings
+// move to the task run pod template, and Job labels and pod labels both go on
+// the PipelineRun, which Tekton propagates to the pod.
+func pipelineRunFromJob(job *batchv1.Job, name string) (*unstructured.Unstructured, error) {
+	pod := job.Spec.Template.Spec
+	steps := []interface{}{}
+	sidecars := []interface{}{}
+	for _, c := range pod.InitContainers {
+		m, err := tektonContainer(c)
+		if err != nil {
+			return nil, err
+		}
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars = append(sidecars, m)
+		} else {
+			delete(m, "ports")
+			steps = append(steps, m)
+		}
+	}
+	for i, c := range pod.Containers {
+		m, err := tektonContainer(c)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			delete(m, "ports")
+			steps = append(steps, m)
+		} else {
+			sidecars = append(sidecars, m)
+		}
+	}
+
+	volumes := make([]interface{}, 0, len(pod.Volumes))
+	for i := range pod.Volumes {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&pod.Volumes[i])
+		if err != nil {
+			return nil, fmt.Errorf("convert volume %s: %v", pod.Volumes[i].Name, err)
+		}
+		volumes = append(volumes, m)
+	}
+	taskSpec := map[string]interface{}{"steps": steps}
+	if len(sidecars) > 0 {
+		taskSpec["sidecars"] = sidecars
+	}
+	if len(volumes) > 0 {
+		taskSpec["volumes"] = volumes
+	}
+	task := map[string]interface{}{"name": "runner", "taskSpec": taskSpec}
+	if job.Spec.BackoffLimit != nil && *job.Spec.BackoffLimit > 1 {
+		// monitorJob fails a session after backoffLimit failed pods
+		task["retries"] = int64(*job.Spec.BackoffLimit - 1)
+	}
+
+	// Only the pod fields Tekton's pod template accepts
+	podTemplate, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&corev1.PodSpec{
+		NodeSelector:                 pod.NodeSelector,
+		Tolerations:                  pod.Tolerations,
+		Affinity:                     pod.Affinity,
+		SecurityContext:              pod.SecurityContext,
+		ImagePullSecrets:             pod.ImagePullSecrets,
+		PriorityClassName:            pod.PriorityClassName,
+		TopologySpreadConstraints:    pod.TopologySpreadConstraints,
+		AutomountServiceAccountToken: pod.AutomountServiceAccountToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("convert pod template: %v", err)
+	}
+	delete(podTemplate, "containers")
+	taskRunTemplate := map[string]interface{}{}
+	if len(podTemplate) > 0 {
+		taskRunTemplate["podTemplate"] = podTemplate
+	}
+	if pod.ServiceAccountName != "" {
+		taskRunTemplate["serviceAccountName"] = pod.ServiceAccountName
+	}
+
+	labels := map[string]interface{}{}
+	for k, v := range job.Labels {
+		labels[k] = v
+	}
+	for k, v := range job.Spec.Template.Labels {
+		labels[k] = v
+	}
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": job.Namespace,
+		"labels":    labels,
+	}
+	if len(job.Spec.Template.Annotations) > 0 {
+		annotations := map[string]interface{}{}
+		for k, v := range job.Spec.Template.Annotations {
+			annotations[k] = v
+		}
+		metadata["annotations"] = annotations
+	}
+	refs := make([]interface{}, 0, len(job.OwnerReferences))
+	for i := range job.OwnerReferences {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&job.OwnerReferences[i])
+		if err != nil {
+			return nil, fmt.Errorf("convert owner reference: %v", err)
+		}
+		refs = append(refs, m)
+	}
+	metadata["ownerReferences"] = refs
+
+	spec := map[string]interface{}{
+		"pipelineSpec":    map[string]interface{}{"tasks": []interface{}{task}},
+		"taskRunTemplate": taskRunTemplate,
+	}
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		spec["timeouts"] = map[string]interface{}{"pipeline": fmt.Sprintf("%ds", *job.Spec.ActiveDeadlineSeconds)}
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tekton.dev/v1",
+		"kind":       "PipelineRun",
+		"metadata":   metadata,
+		"spec":       spec,
+	}}, nil
+}
+
+// tektonContainer converts a container to a Tekton step or sidecar, which name
+// the resources field computeResources and have no restartPolicy
+func tektonContainer(c corev1.Container) (map[string]interface{}, error) {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&c)
+	if err != nil {
+		return nil, fmt.Errorf("convert container %s: %v", c.Name, err)
+	}
+	if res, ok := m["resources"]; ok {
+		m["computeResources"] = res
+		delete(m, "resources")
+	}
+	delete(m, "restartPolicy")
+	delete(m, "resizePolicy")
+	return m, nil
+}
+
+// createSessionPipelineRun creates the PipelineRun for a session's runner Job
+func createSessionPipelineRun(job *batchv1.Job, name string) error {
+	run, err := pipelineRunFromJob(job, name)
+	if err != nil {
+		return err
+	}
+	_, err = dynamicClient.Resource(getPipelineRunResource()).Namespace(job.Namespace).Create(context.TODO(), run, v1.CreateOptions{})
+	return err
+}
+
+// pipelineRunSucceeded returns the PipelineRun's Succeeded condition; status is
+// "" while Tekton has not reported one
+func pipelineRunSucceeded(run *unstructured.Unstructured) (status, reason, message string) {
+	conditions, _, _ := unstructured.NestedSlice(run.Object, "status", "conditions")
+	for _, raw := range conditions {
+		c, ok := raw.(map[string]interface{})
+		if !ok || c["type"] != "Succeeded" {
+			continue
+		}
+		status, _ = c["status"].(string)
+		reason, _ = c["reason"].(string)
+		message, _ = c["message"].(string)
+	}
+	return status, reason, message
+}
+
+// monitorPipelineRun maps a session's PipelineRun back to session phases the
+// way monitorJob does for Jobs: success leaves the final status to the runner,
+// a Tekton timeout fails the session as Timeout, and other failures fail it
+// with reason BackoffLimitExceeded so retry policies apply. A session stopped
+// through the API cancels its PipelineRun.
+func monitorPipelineRun(runName, sessionName, sessionNamespace string) {
+	log.Printf("Starting PipelineRun monitoring for %s (session: %s/%s)", runName, sessionNamespace, sessionName)
+	runs := dynamicClient.Resource(getPipelineRunResource()).Namespace(sessionNamespace)
+
+	for {
+		time.Sleep(10 * time.Second)
+
+		sessionObj, err := dynamicClient.Resource(getAgenticSessionResource()).Namespace(sessionNamespace).Get(context.TODO(), sessionName, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Printf("AgenticSession %s no longer exists, stopping PipelineRun monitoring for %s", sessionName, runName)
+				releaseProviderKey(sessionNamespace, sessionName, nil)
+				return
+			}
+			log.Printf("Error checking AgenticSession %s existence: %v", sessionName, err)
+		}
+
+		run, err := runs.Get(context.TODO(), runName, v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				log.Printf("PipelineRun %s not found, stopping monitoring", runName)
+				releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+				return
+			}
+			log.Printf("Error getting PipelineRun %s: %v", runName, err)
+			continue
+		}
+
+		sessionPhase := ""
+		if sessionObj != nil {
+			sessionPhase, _, _ = unstructured.NestedString(sessionObj.Object, "status", "phase")
+		}
+		status, reason, message := pipelineRunSucceeded(run)
+		if status == "" || status == string(v1.ConditionUnknown) {
+			stalledKilled := checkRunnerLiveness(sessionObj, "PipelineRun "+runName, func() error {
+				_, err := runs.Patch(context.TODO(), runName, types.MergePatchType, []byte(`{"spec":{"status":"Cancelled"}}`), v1.PatchOptions{})
+				if errors.IsNotFound(err) {
+					return nil
+				}
+				return err
+			})
+			if stalledKilled {
+				releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+				return
+			}
+			if sessionPhase == "Stopped" {
+				patch := []byte(`{"spec":{"status":"Cancelled"}}`)
+				if _, err := runs.Patch(context.TODO(), runName, types.MergePatchType, patch, v1.PatchOptions{}); err != nil && !errors.IsNotFound(err) {
+					log.Printf("Failed to cancel PipelineRun %s: %v", runName, err)
+					continue
+				}
+				log.Printf("Cancelled PipelineRun %s for stopped session %s", runName, sessionName)
+				releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+				return
+			}
+			continue
+		}
+
+		if status == string(v1.ConditionTrue) {
+			releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+			return
+		}
+
+		switch reason {
+		case "Cancelled", "CancelledRunFinally", "StoppedRunFinally":
+			// Cancelled outside the API, e.g. with tkn; the API records Stopped itself
+			if sessionPhase != "Stopped" {
+				updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+					"phase":          "Stopped",
+					"message":        fmt.Sprintf("PipelineRun %s was cancelled", runName),
+					"completionTime": time.Now().Format(time.RFC3339),
+				})
+			}
+		case "PipelineRunTimeout":
+			timeout, _, _ := unstructured.NestedString(run.Object, "spec", "timeouts", "pipeline")
+			log.Printf("PipelineRun %s exceeded its timeout of %s", runName, timeout)
+			updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+				"phase":          "Failed",
+				"message":        fmt.Sprintf("Session timed out after %s", timeout),
+				"completionTime": time.Now().Format(time.RFC3339),
+			}, v1.Condition{
+				Type:    conditionSucceeded,
+				Status:  v1.ConditionFalse,
+				Reason:  "Timeout",
+				Message: fmt.Sprintf("PipelineRun %s exceeded timeouts.pipeline=%s", runName, timeout),
+			})
+			recordEvent(sessionObj, corev1.EventTypeWarning, eventReasonTimeout, "Session timed out after %s", timeout)
+		default:
+			log.Printf("PipelineRun %s failed: %s: %s", runName, reason, message)
+			recordEvent(sessionObj, corev1.EventTypeWarning, eventReasonJobFailed, "PipelineRun %s failed: %s", runName, reason)
+			errorMessage := runnerPodLogsMessage(sessionNamespace, fmt.Sprintf("tekton.dev/pipelineRun=%s", runName), "PipelineRun failed")
+			if errorMessage == "PipelineRun failed" && strings.TrimSpace(message) != "" {
+				errorMessage = "PipelineRun failed: " + message
+			}
+			updateAgenticSessionStatus(sessionNamespace, sessionName, map[string]interface{}{
+				"phase":          "Failed",
+				"message":        errorMessage,
+				"completionTime": time.Now().Format(time.RFC3339),
+			}, v1.Condition{
+				Type:    conditionSucceeded,
+				Status:  v1.ConditionFalse,
+				Reason:  "BackoffLimitExceeded",
+				Message: fmt.Sprintf("PipelineRun %s failed: %s", runName, reason),
+			})
+		}
+		releaseProviderKey(sessionNamespace, sessionName, sessionObj)
+		return
+	}
+}
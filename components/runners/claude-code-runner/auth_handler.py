@@ -10,6 +10,8 @@ import logging
 import jwt
 from typing import Optional, Dict, Any
 
+from tracing import tracer
+
 logger = logging.getLogger(__name__)
 
 
@@ -19,7 +21,44 @@ class AuthHandler:
     def __init__(self):
         self.auth_mode = os.getenv("AUTH_MODE", "kubernetes")  # kubernetes or bot_token
         self.bot_token = os.getenv("BOT_TOKEN", "")
-        self.service_account_token_path = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+        # The operator projects a short-lived, auto-rotated token for the session's
+        # ServiceAccount; it is re-read on every call
+        self.service_account_token_path = os.getenv(
+            "RUNNER_TOKEN_PATH", "/var/run/secrets/kubernetes.io/serviceaccount/token"
+        )
+        # With RUNNER_MTLS the operator mounts a client certificate of the session,
+        # which the backend API requires, and the CA that signed the backend's
+        self.tls_cert_path = os.getenv("RUNNER_TLS_CERT", "")
+        self.tls_key_path = os.getenv("RUNNER_TLS_KEY", "")
+        self.tls_ca_path = os.getenv("RUNNER_TLS_CA", "")
+        self._ssl_context = None
+
+    def requests_tls(self) -> Dict[str, Any]:
+        """
+        Keyword arguments that make a requests call to the backend present the
+        session's client certificate; empty without mutual TLS.
+        """
+        if not (self.tls_cert_path and self.tls_key_path):
+            return {}
+        kwargs: Dict[str, Any] = {"cert": (self.tls_cert_path, self.tls_key_path)}
+        if self.tls_ca_path:
+            kwargs["verify"] = self.tls_ca_path
+        return kwargs
+
+    def aiohttp_tls(self) -> Dict[str, Any]:
+        """
+        Keyword arguments that make an aiohttp call to the backend present the
+        session's client certificate; empty without mutual TLS.
+        """
+        if not (self.tls_cert_path and self.tls_key_path):
+            return {}
+        if self._ssl_context is None:
+            import ssl
+
+            ctx = ssl.create_default_context(cafile=self.tls_ca_path or None)
+            ctx.load_cert_chain(self.tls_cert_path, self.tls_key_path)
+            self._ssl_context = ctx
+        return {"ssl": self._ssl_context}
 
     def get_auth_headers(self) -> Dict[str, str]:
         """
@@ -47,6 +86,11 @@ class AuthHandler:
         else:
             logger.warning("No authentication method available")
 
+        # Backend requests join the session's trace
+        traceparent = tracer.traceparent()
+        if traceparent:
+            headers["traceparent"] = traceparent
+
         return headers
 
     def get_project_context(self) -> Optional[str]:
@@ -192,7 +236,8 @@ class BackendClient:
                 async with session.put(
                     endpoint,
                     headers=headers,
-                    data=json.dumps(status_data)
+                    data=json.dumps(status_data),
+                    **self.auth_handler.aiohttp_tls(),
                 ) as response:
                     if response.status == 200:
                         logger.info(f"Successfully updated session status")
@@ -204,6 +249,40 @@ class BackendClient:
             logger.error(f"Error updating session status: {e}")
             return False
 
+    async def poll_inbox(self, session_name: str, after: int, wait: int) -> Optional[tuple]:
+        """
+        Long-poll the backend for follow-up messages posted to the session.
+
+        Args:
+            session_name: Name of the session
+            after: Number of inbox lines already processed
+            wait: Seconds the backend may hold the request open
+
+        Returns:
+            (messages, next_offset), or None when the backend is unreachable
+        """
+        import aiohttp
+
+        endpoint = self.get_api_endpoint(f"/agentic-sessions/{session_name}/inbox")
+        headers = self.get_request_headers()
+        try:
+            timeout = aiohttp.ClientTimeout(total=wait + 15)
+            async with aiohttp.ClientSession(timeout=timeout) as session:
+                async with session.get(
+                    endpoint,
+                    headers=headers,
+                    params={"after": str(after), "wait": str(wait)},
+                    **self.auth_handler.aiohttp_tls(),
+                ) as response:
+                    if response.status != 200:
+                        logger.debug(f"Inbox poll failed: {response.status}")
+                        return None
+                    data = await response.json()
+                    return data.get("messages") or [], int(data.get("next", after))
+        except Exception as e:
+            logger.debug(f"Error polling inbox: {e}")
+            return None
+
     async def update_session_display_name(self, session_name: str, display_name: str) -> bool:
         """
         Update only the display name for a given session.
@@ -227,6 +306,7 @@ class BackendClient:
                     endpoint,
                     headers=headers,
                     data=json.dumps({"displayName": display_name}),
+                    **self.auth_handler.aiohttp_tls(),
                 ) as response:
                     if response.status == 200:
                         logger.info("Successfully updated session display name")
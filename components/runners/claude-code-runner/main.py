@@ -1,10 +1,18 @@
 #!/usr/bin/env python3
 
 from dataclasses import asdict
+import base64
 import logging
 import os
 import sys
+import fnmatch
+import io
 import json
+import resource
+import subprocess
+import tarfile
+import threading
+import time
 from datetime import datetime, timezone
 from pathlib import Path
 from typing import Dict, Any, List
@@ -15,6 +23,8 @@ from anthropic import Anthropic
 
 from auth_handler import AuthHandler, BackendClient
 from git_integration import GitIntegration
+from tool_gatekeeper import ToolGatekeeper, ToolPolicyViolation
+from tracing import tracer
 
 
 log_level = logging.DEBUG if os.getenv("DEBUG", "").lower() in ("true", "1", "yes") else logging.INFO
@@ -41,6 +51,8 @@ class SimpleClaudeRunner:
 
         # Git integration (multi-repo via GIT_REPOSITORIES)
         self.git = GitIntegration()
+        # Cloned repositories: url -> (path, branch, commit checked out)
+        self._repo_bases: Dict[str, tuple[Path, str, str]] = {}
         
         # Derived
         self.workdir = Path("/tmp/workdir")
@@ -49,17 +61,81 @@ class SimpleClaudeRunner:
         # Track last pushed file state to send only deltas (path -> (mtime, size))
         self._last_push_index: Dict[str, tuple[float, int]] = {}
 
-        if not self.session_name or not self.prompt or not self.api_key:
-            missing = [k for k, v in {
-                "AGENTIC_SESSION_NAME": self.session_name,
-                "PROMPT": self.prompt,
-                "ANTHROPIC_API_KEY": self.api_key,
-            }.items() if not v]
+        # Bedrock and Vertex AI authenticate with cloud credentials instead of an API key
+        self.llm_provider = os.getenv("LLM_PROVIDER", "anthropic").strip() or "anthropic"
+        required = {"AGENTIC_SESSION_NAME": self.session_name, "PROMPT": self.prompt}
+        if self.llm_provider == "anthropic":
+            required["ANTHROPIC_API_KEY"] = self.api_key
+        if not all(required.values()):
+            missing = [k for k, v in required.items() if not v]
             raise RuntimeError(f"Missing required environment variables: {', '.join(missing)}")
 
         self.auth = AuthHandler()
         self.backend = BackendClient(self.backend_api_url, self.auth)
 
+        # Liveness: heartbeats every interval while the agent made progress within the idle limit
+        self.heartbeat_interval = int(os.getenv("HEARTBEAT_INTERVAL_SECONDS", "30") or "30")
+        self.heartbeat_idle_limit = int(os.getenv("HEARTBEAT_IDLE_LIMIT_SECONDS", "1800") or "1800")
+        self._last_activity = time.monotonic()
+        self._heartbeat_stop = threading.Event()
+        # Model API calls of the agent and the title and summary calls, reported in status.usage
+        self._api_calls = 0
+        # Tokens of the title and summary calls by model, priced with the session
+        self._helper_usage: Dict[str, Dict[str, int]] = {}
+        # Tool policy enforced on every tool call the agent makes
+        self.gatekeeper = ToolGatekeeper.from_env()
+        self._reported_violations = 0
+        # Time to first output is reported for the session SLO
+        self._first_output_reported = False
+
+    # ---------------- Model helpers ----------------
+    def _model_allowed(self, model: str) -> bool:
+        """Match the provider's allowedModels globs (LLM_ALLOWED_MODELS); none allows any model."""
+        patterns = [p.strip() for p in os.getenv("LLM_ALLOWED_MODELS", "").split(",") if p.strip()]
+        return not patterns or any(fnmatch.fnmatchcase(model, p) for p in patterns)
+
+    def _helper_model(self, env_name: str, anthropic_default: str) -> str:
+        """Model of a title or summary call: env_name or, on the anthropic provider,
+        anthropic_default when the provider allows it, else the session's own model."""
+        candidates = [os.getenv(env_name, "").strip()]
+        if self.llm_provider == "anthropic":
+            candidates.append(anthropic_default)
+        for model in candidates:
+            if model and self._model_allowed(model):
+                return model
+        return os.getenv("LLM_MODEL", "").strip()
+
+    def _messages_client(self):
+        """Messages API client for the session's provider, configured from the same
+        environment the agent SDK reads; None for providers without one."""
+        if self.llm_provider == "anthropic":
+            if not self.api_key:
+                return None
+            return Anthropic(api_key=self.api_key, base_url=os.getenv("ANTHROPIC_BASE_URL") or None)
+        if self.llm_provider == "bedrock":
+            from anthropic import AnthropicBedrock
+            return AnthropicBedrock(aws_region=os.getenv("AWS_REGION") or None)
+        if self.llm_provider == "vertex":
+            from anthropic import AnthropicVertex
+            return AnthropicVertex(region=os.getenv("CLOUD_ML_REGION") or None, project_id=os.getenv("ANTHROPIC_VERTEX_PROJECT_ID") or None)
+        return None
+
+    def _helper_message(self, env_name: str, anthropic_default: str, **kwargs: Any):
+        """Make a title or summary call through the session's provider and count its
+        tokens; None when the provider has no Messages API client."""
+        client = self._messages_client()
+        model = self._helper_model(env_name, anthropic_default)
+        if client is None or not model:
+            return None
+        msg = client.messages.create(model=model, **kwargs)
+        self._api_calls += 1
+        usage = getattr(msg, "usage", None)
+        if usage is not None:
+            counted = self._helper_usage.setdefault(model, {"input_tokens": 0, "output_tokens": 0})
+            counted["input_tokens"] += int(getattr(usage, "input_tokens", 0) or 0)
+            counted["output_tokens"] += int(getattr(usage, "output_tokens", 0) or 0)
+        return msg
+
     # ---------------- Display name helpers ----------------
     def _fallback_display_name(self, prompt: str) -> str:
         try:
@@ -76,12 +152,6 @@ class SimpleClaudeRunner:
     def _generate_display_name_from_prompt(self, prompt: str) -> str:
         """Use a lightweight model to summarize the prompt into a short display name."""
         try:
-            api_key = self.api_key
-            if not api_key:
-                return self._fallback_display_name(prompt)
-
-            model = os.getenv("CLAUDE_TITLE_MODEL", "claude-3-haiku-20240307")
-            client = Anthropic(api_key=api_key)
             system_prompt = (
                 "You generate concise, human-friendly session titles. "
                 "Return a short title (max 8 words), no punctuation at the end, "
@@ -90,12 +160,15 @@ class SimpleClaudeRunner:
             user_prompt = (
                 "Summarize this prompt into a short session display name.\n\n" + prompt
             )
-            msg = client.messages.create(
-                model=model,
+            msg = self._helper_message(
+                "CLAUDE_TITLE_MODEL",
+                "claude-3-haiku-20240307",
                 max_tokens=64,
                 system=system_prompt,
                 messages=[{"role": "user", "content": user_prompt}],
             )
+            if msg is None:
+                return self._fallback_display_name(prompt)
             # Extract first text block
             text = ""
             try:
@@ -155,7 +228,7 @@ class SimpleClaudeRunner:
             for p in personas:
                 try:
                     url = f"{base}/{p}/markdown"
-                    resp = requests.get(url, headers=self._auth_headers(), timeout=20)
+                    resp = requests.get(url, headers=self._auth_headers(), timeout=20, **self.auth.requests_tls())
                     if resp.status_code != 200:
                         logger.warning(f"Agent markdown fetch failed for {p}: HTTP {resp.status_code}")
                         continue
@@ -208,6 +281,18 @@ class SimpleClaudeRunner:
             logger.error(f"content_list error for {path}: {e}")
         return []
 
+    def upload_artifact(self, name: str, data: bytes) -> Dict[str, Any] | None:
+        """Upload an artifact through the backend, which checksums, types and indexes it."""
+        url = f"{self.backend_api_url}/projects/{self.session_namespace}/agentic-sessions/{self.session_name}/artifacts"
+        try:
+            resp = requests.post(url, headers=self._auth_headers(), files={"file": (Path(name).name, data)}, data={"name": name}, timeout=120, **self.auth.requests_tls())
+            if resp.status_code // 100 == 2:
+                return resp.json()
+            logger.error(f"upload_artifact failed for {name}: HTTP {resp.status_code} {resp.text[:200]}")
+        except Exception as e:
+            logger.error(f"upload_artifact error for {name}: {e}")
+        return None
+
     # ---------------- Workspace sync ----------------
     def _sync_workspace_from_pvc(self) -> None:
         if not self.workspace_store_path:
@@ -248,17 +333,142 @@ class SimpleClaudeRunner:
         for path in self.workdir.rglob("*"):
             if path.is_dir():
                         continue
-            rel = path.relative_to(self.workdir)
-            pvc_path = str(Path(self.workspace_store_path) / rel)
+            self._push_file(path)
+
+    def _push_file(self, path: Path) -> None:
+        """Push one workdir file: artifacts go through the backend upload API, the rest to the PVC."""
+        rel = path.relative_to(self.workdir)
+        if rel.parts and rel.parts[0] == "artifacts" and len(rel.parts) > 1:
+            if self.upload_artifact(str(Path(*rel.parts[1:])), path.read_bytes()) is not None:
+                return
+            logger.warning(f"Artifact upload failed for {rel}, falling back to direct write")
+        pvc_path = str(Path(self.workspace_store_path) / rel)
+        try:
+            content = path.read_text(encoding="utf-8")
+            self.content_write(pvc_path, content, "utf8")
+        except Exception:
             try:
-                content = path.read_text(encoding="utf-8")
-                self.content_write(pvc_path, content, "utf8")
-            except Exception:
-                try:
-                    import base64
-                    self.content_write(pvc_path, base64.b64encode(path.read_bytes()).decode("ascii"), "base64")
-                except Exception as e:
-                    logger.warning(f"Failed to push file {path} -> {pvc_path}: {e}")
+                import base64
+                self.content_write(pvc_path, base64.b64encode(path.read_bytes()).decode("ascii"), "base64")
+            except Exception as e:
+                logger.warning(f"Failed to push file {path} -> {pvc_path}: {e}")
+
+    # ---------------- Workspace snapshot ----------------
+    SNAPSHOT_NAME = "workspace-snapshot.tar.gz"
+    SNAPSHOT_DEFAULT_IGNORE = [".git", "node_modules", "__pycache__", "artifacts"]
+
+    def _snapshot_ignored(self, rel: str, patterns: List[str]) -> bool:
+        parts = rel.split("/")
+        for pattern in patterns:
+            pattern = pattern.strip().strip("/")
+            if not pattern:
+                continue
+            if fnmatch.fnmatch(rel, pattern) or any(fnmatch.fnmatch(part, pattern) for part in parts):
+                return True
+            if rel.startswith(pattern + "/"):
+                return True
+        return False
+
+    def _upload_workspace_snapshot(self) -> None:
+        """Upload the final workspace as an archive artifact when the project's
+        sessionPolicy.workspaceSnapshot enables it. Snapshots whose compressed
+        size exceeds the policy's cap are skipped rather than cut short."""
+        if os.getenv("WORKSPACE_SNAPSHOT", "false").lower() != "true":
+            return
+        max_bytes = int(os.getenv("WORKSPACE_SNAPSHOT_MAX_BYTES", "0") or "0")
+        patterns = self.SNAPSHOT_DEFAULT_IGNORE + [p for p in os.getenv("WORKSPACE_SNAPSHOT_IGNORE", "").split(",") if p.strip()]
+        buf = io.BytesIO()
+        files = 0
+        try:
+            with tarfile.open(fileobj=buf, mode="w:gz") as tar:
+                for path in sorted(self.workdir.rglob("*")):
+                    rel = path.relative_to(self.workdir).as_posix()
+                    if path.is_dir() or path.is_symlink() or self._snapshot_ignored(rel, patterns):
+                        continue
+                    tar.add(str(path), arcname=rel, recursive=False)
+                    files += 1
+                    if max_bytes > 0 and buf.tell() > max_bytes:
+                        logger.warning(f"Workspace snapshot exceeds {max_bytes} bytes; skipping upload")
+                        return
+        except Exception as e:  # noqa: BLE001
+            logger.warning(f"Workspace snapshot failed: {e}")
+            return
+        data = buf.getvalue()
+        if max_bytes > 0 and len(data) > max_bytes:
+            logger.warning(f"Workspace snapshot of {len(data)} bytes exceeds {max_bytes}; skipping upload")
+            return
+        if files == 0:
+            return
+        if self.upload_artifact(self.SNAPSHOT_NAME, data) is not None:
+            logger.info(f"Uploaded workspace snapshot ({files} files, {len(data)} bytes)")
+
+    # ---------------- Pull requests ----------------
+    PULL_REQUEST_BODY_LIMIT = 4000
+
+    def _git_output(self, repo: Path, *args: str) -> bytes:
+        return subprocess.run(["git", "-C", str(repo), *args], check=True, capture_output=True, timeout=120).stdout
+
+    def _record_repo_bases(self, cloned: Dict[str, Path]) -> None:
+        for url, path in (cloned or {}).items():
+            try:
+                sha = self._git_output(path, "rev-parse", "HEAD").decode().strip()
+                branch = self._git_output(path, "rev-parse", "--abbrev-ref", "HEAD").decode().strip()
+                self._repo_bases[url] = (path, branch, sha)
+            except Exception as e:  # noqa: BLE001
+                logger.warning(f"Could not record base commit of {url}: {e}")
+
+    def _request_pull_requests(self, result_msg: ResultMessage | None) -> None:
+        """Ask the backend to open a pull request with the changes made to each
+        cloned repository when the session has gitConfig.pullRequests. The
+        operator pushes the branch and opens it with the project's GitHub
+        credentials; links appear in the session's status.pullRequests."""
+        if os.getenv("GIT_PULL_REQUESTS", "false").lower() != "true":
+            return
+        url = f"{self.backend_api_url}/projects/{self.session_namespace}/agentic-sessions/{self.session_name}/pull-requests"
+        title = self._fallback_display_name(self.prompt)
+        body = (getattr(result_msg, "result", None) or "").strip()
+        if len(body) > self.PULL_REQUEST_BODY_LIMIT:
+            body = body[: self.PULL_REQUEST_BODY_LIMIT].rstrip() + "…"
+        for repo_url, (path, base_branch, base_sha) in self._repo_bases.items():
+            try:
+                # Compare the work tree, including anything the agent committed, with the clone
+                self._git_output(path, "add", "-A")
+                diff = self._git_output(path, "diff", "--cached", "--name-status", "--no-renames", "-z", base_sha).decode()
+            except Exception as e:  # noqa: BLE001
+                logger.warning(f"Could not collect changes of {repo_url}: {e}")
+                continue
+            fields = [f for f in diff.split("\0") if f]
+            files: List[Dict[str, Any]] = []
+            for status, rel in zip(fields[0::2], fields[1::2]):
+                target = path / rel
+                if status.startswith("D") or not target.is_file():
+                    files.append({"path": rel, "deleted": True})
+                    continue
+                files.append({
+                    "path": rel,
+                    "content": base64.b64encode(target.read_bytes()).decode(),
+                    "executable": os.access(target, os.X_OK),
+                })
+            if not files:
+                logger.info(f"No changes to propose for {repo_url}")
+                continue
+            payload = {
+                "repo": repo_url,
+                "baseBranch": base_branch,
+                "baseSha": base_sha,
+                "branch": f"ambient/{self.session_name}",
+                "title": title,
+                "body": body,
+                "files": files,
+            }
+            try:
+                resp = requests.post(url, headers={**self._auth_headers(), "Content-Type": "application/json"}, data=json.dumps(payload), timeout=120, **self.auth.requests_tls())
+                if resp.status_code // 100 == 2:
+                    logger.info(f"Requested a pull request for {repo_url} with {len(files)} changed files")
+                else:
+                    logger.warning(f"Pull request for {repo_url} refused: HTTP {resp.status_code} {resp.text[:200]}")
+            except Exception as e:  # noqa: BLE001
+                logger.warning(f"Pull request request for {repo_url} failed: {e}")
 
     # ---------------- Messaging ----------------
     def _append_message(self, message: str) -> None:
@@ -282,7 +492,13 @@ class SimpleClaudeRunner:
 
     # ---------------- Chat inbox helpers ----------------
     async def _read_inbox_lines(self, last_offset: int) -> tuple[list[dict[str, Any]], int]:
-        """Read inbox.jsonl locally when present, fallback to content service. last_offset is line count processed."""
+        """Long-poll the backend for new inbox messages; fall back to reading inbox.jsonl
+        locally or through the content service. last_offset is line count processed."""
+        polled = await self.backend.poll_inbox(
+            self.session_name, last_offset, int(os.getenv("INBOX_LONG_POLL_SEC", "25"))
+        )
+        if polled is not None:
+            return polled
         try:
             p = Path(self.inbox_store_path)
             text = ""
@@ -340,22 +556,46 @@ class SimpleClaudeRunner:
                     continue
 
             for path in files_to_push:
-                rel = path.relative_to(self.workdir)
-                pvc_path = str(Path(self.workspace_store_path) / rel)
-                try:
-                    content = path.read_text(encoding="utf-8")
-                    self.content_write(pvc_path, content, "utf8")
-                except Exception:
-                    try:
-                        import base64
-                        self.content_write(pvc_path, base64.b64encode(path.read_bytes()).decode("ascii"), "base64")
-                    except Exception as e:
-                        logger.warning(f"Failed to push file {path} -> {pvc_path}: {e}")
+                self._push_file(path)
 
             self._last_push_index = updated_index
         except Exception as e:
             logger.debug(f"push deltas failed: {e}")
 
+    def _tool_lists(self) -> tuple[list[str], list[str]]:
+        """Default tools the tool policy permits, and the blocked tools the CLI never offers."""
+        allowed_tools_env = "Read,Write,Bash,Glob,Grep,Edit,MultiEdit,WebSearch,WebFetch"
+        allowed = [t.strip() for t in allowed_tools_env.split(",") if t.strip() and self.gatekeeper.permitted(t.strip())[0]]
+        return allowed, list(self.gatekeeper.blocked)
+
+    def _gatekeeper_options(self) -> Dict[str, Any]:
+        """SDK options that route every tool call through the gatekeeper, when
+        there is a policy to enforce and the installed SDK supports can_use_tool."""
+        from dataclasses import fields
+        from claude_code_sdk import ClaudeCodeOptions
+
+        if not self.gatekeeper.active or "can_use_tool" not in {f.name for f in fields(ClaudeCodeOptions)}:
+            return {}
+        return {"can_use_tool": self.gatekeeper.can_use_tool}
+
+    def _enforce_tool_policy(self, message: Any, intercepted: bool) -> None:
+        """Check the tool calls of an agent message unless the SDK already routed
+        them through the gatekeeper, record refusals in the transcript, and stop
+        the session once the violation limit is reached."""
+        if not intercepted:
+            for block in getattr(message, "content", None) or []:
+                name = getattr(block, "name", None)
+                if isinstance(name, str) and hasattr(block, "input"):
+                    self.gatekeeper.inspect(name, getattr(block, "input", None))
+        for v in self.gatekeeper.violations[self._reported_violations:]:
+            self.messages.append({
+                "type": "system_message",
+                "content": f"Tool call refused: {v['reason']}",
+                "timestamp": v["timestamp"],
+            })
+        self._reported_violations = len(self.gatekeeper.violations)
+        self.gatekeeper.check()
+
     async def _chat_mode(self) -> None:
         from claude_code_sdk import (
             ClaudeSDKClient,
@@ -370,14 +610,16 @@ class SimpleClaudeRunner:
             ResultMessage,
         )
 
-        allowed_tools_env = "Read,Write,Bash,Glob,Grep,Edit,MultiEdit,WebSearch,WebFetch"
-        allowed_tools = [t.strip() for t in allowed_tools_env.split(",") if t.strip()]
+        allowed_tools, blocked_tools = self._tool_lists()
+        gatekeeper_options = self._gatekeeper_options()
 
         options = ClaudeCodeOptions(
             permission_mode=os.getenv("CLAUDE_PERMISSION_MODE", "acceptEdits"),
             allowed_tools=allowed_tools if allowed_tools else None,
+            disallowed_tools=blocked_tools,
             cwd=str(self.workdir),
             append_system_prompt=self.prompt + "\n\nALWAYS consult sub agents to help with this task.",
+            **gatekeeper_options,
         )
 
         # Restore cursor if present
@@ -419,10 +661,12 @@ class SimpleClaudeRunner:
                                 pass
                             return
                        
-                        # Mirror user message into outbox
+                        # Mirror user message into outbox, keeping its id and sender for history
                         self.messages.append({
                             "type": "user_message",
                             "content": text,
+                            "id": msg.get("id"),
+                            "sender": msg.get("sender"),
                             "timestamp": datetime.now(timezone.utc).isoformat(),
                         })
                         self._flush_messages()
@@ -431,6 +675,9 @@ class SimpleClaudeRunner:
                         await client.query(text)
                         async for message in client.receive_response():
                             logger.info(f"Message: {message}")
+                            if isinstance(message, AssistantMessage):
+                                self._enforce_tool_policy(message, bool(gatekeeper_options))
+                                await self._record_first_output()
                             message_type_map = {
                                 AssistantMessage: "assistant_message",
                                 UserMessage: "user_message",
@@ -495,8 +742,149 @@ class SimpleClaudeRunner:
                 await __import__("asyncio").sleep(float(os.getenv("INBOX_POLL_INTERVAL_SEC", "0.5")))
        
 
+    # ---------------- Summary report ----------------
+    def _transcript_for_summary(self, max_chars: int) -> str:
+        """Flatten collected messages into plain text, keeping the most recent content within max_chars."""
+        lines: List[str] = []
+        for m in self.messages:
+            kind = m.get("type", "")
+            content = m.get("content")
+            if isinstance(content, dict):
+                block_type = content.get("type")
+                if block_type == "text_block":
+                    lines.append(f"[{kind}] {content.get('text', '')}")
+                elif block_type == "tool_use_block":
+                    lines.append(f"[{kind}] tool {content.get('name', '')}: {json.dumps(content.get('input', {}))[:500]}")
+                elif block_type == "tool_result_block":
+                    lines.append(f"[{kind}] tool result: {str(content.get('content', ''))[:500]}")
+            elif isinstance(content, str) and content:
+                lines.append(f"[{kind}] {content}")
+            elif kind == "result_message" and m.get("result"):
+                lines.append(f"[result] {m.get('result')}")
+        text = "\n".join(lines)
+        if len(text) > max_chars:
+            text = "…" + text[-max_chars:]
+        return text
+
+    def _generate_summary_report(self) -> None:
+        """Ask the model for an executive summary of the run and store it as the report artifact."""
+        if os.getenv("GENERATE_SUMMARY_REPORT", "").lower() not in ("true", "1", "yes"):
+            return
+        try:
+            transcript = self._transcript_for_summary(int(os.getenv("SUMMARY_MAX_TRANSCRIPT_CHARS", "120000")))
+            if not transcript:
+                return
+            msg = self._helper_message(
+                "SUMMARY_REPORT_MODEL",
+                "claude-3-5-haiku-latest",
+                max_tokens=1024,
+                system=(
+                    "You write concise executive summaries of automated agent sessions. "
+                    "Use markdown with sections: Outcome, Key Changes, Open Issues. "
+                    "Be factual and brief; do not invent details not present in the transcript."
+                ),
+                messages=[{
+                    "role": "user",
+                    "content": f"Original task:\n{self.prompt}\n\nSession transcript:\n{transcript}",
+                }],
+            )
+            if msg is None:
+                logger.info(f"No summary report: the {self.llm_provider} provider has no Messages API client")
+                return
+            summary = "".join(
+                getattr(block, "text", "") for block in (getattr(msg, "content", []) or []) if getattr(block, "type", None) == "text"
+            ).strip()
+            if not summary:
+                return
+
+            report = f"# Session Report: {self.session_name}\n\n{summary}\n"
+            local_path = self.artifacts_dir / "report.md"
+            local_path.write_text(report, encoding="utf-8")
+            uploaded = self.upload_artifact("report.md", report.encode("utf-8"))
+            if not uploaded:
+                return
+            pvc_path = uploaded.get("path") or f"{self.workspace_store_path}/artifacts/report.md"
+
+            preview = " ".join(summary.split())
+            if len(preview) > 280:
+                preview = preview[:280].rstrip() + "…"
+            import asyncio as _asyncio
+            _asyncio.run(self.backend.update_session_status(self.session_name, {
+                "report": {
+                    "path": pvc_path,
+                    "preview": preview,
+                    "generatedAt": datetime.now(timezone.utc).isoformat(),
+                },
+            }))
+            logger.info(f"Wrote summary report to {pvc_path}")
+        except Exception as e:  # noqa: BLE001
+            logger.warning(f"Summary report generation failed: {e}")
+
+    # ---------------- Heartbeat ----------------
+    def _start_heartbeat(self) -> None:
+        """Report liveness to the backend from a background thread.
+
+        Beats stop once the agent has shown no activity for the idle limit, so a
+        hung agent loop is marked Stalled by the operator like a dead process.
+        """
+        if self.heartbeat_interval <= 0:
+            return
+        url = f"{self.backend_api_url}/projects/{self.session_namespace}/agentic-sessions/{self.session_name}/heartbeat"
+
+        def beat() -> None:
+            while not self._heartbeat_stop.is_set():
+                idle = time.monotonic() - self._last_activity
+                if self.heartbeat_idle_limit > 0 and idle > self.heartbeat_idle_limit:
+                    logger.warning(f"No agent activity for {int(idle)}s; withholding heartbeat")
+                else:
+                    try:
+                        resp = requests.post(url, headers=self._auth_headers(), timeout=10, **self.auth.requests_tls())
+                        if resp.status_code == 409:
+                            logger.info("Session is no longer running; stopping heartbeat")
+                            return
+                        if resp.status_code // 100 != 2:
+                            logger.debug(f"Heartbeat failed: HTTP {resp.status_code}")
+                    except Exception as e:  # noqa: BLE001
+                        logger.debug(f"Heartbeat error: {e}")
+                self._heartbeat_stop.wait(self.heartbeat_interval)
+
+        threading.Thread(target=beat, name="heartbeat", daemon=True).start()
+
+    def _mark_activity(self) -> None:
+        self._last_activity = time.monotonic()
+
+    def _debug_keep_alive(self) -> None:
+        """Keep a failed runner's pod up for inspection when spec.debug allows it."""
+        seconds = int(os.getenv("DEBUG_KEEP_ALIVE_SECONDS", "0") or "0")
+        if seconds <= 0:
+            return
+        self._heartbeat_stop.set()
+        logger.info(f"Debug mode: keeping the pod alive for {seconds}s; open a terminal through the session's debug/exec endpoint")
+        time.sleep(seconds)
+
     # ---------------- Status ----------------
+    def _resource_usage(self, result_msg: ResultMessage | None = None) -> Dict[str, Any]:
+        """Usage reported in status.usage: the SDK's token counts plus API calls,
+        cost, the tokens of title and summary calls by model and the peak memory
+        of the runner and the agent CLI it spawned."""
+        usage: Dict[str, Any] = dict(result_msg.usage or {}) if result_msg else {}
+        usage["api_calls"] = self._api_calls
+        if self._helper_usage:
+            usage["helper_models"] = {model: dict(tokens) for model, tokens in self._helper_usage.items()}
+        if self.gatekeeper.violations:
+            usage["tool_violations"] = len(self.gatekeeper.violations)
+        if result_msg and result_msg.total_cost_usd is not None:
+            usage["cost_usd"] = result_msg.total_cost_usd
+        try:
+            # ru_maxrss is in KiB on Linux
+            peak = max(resource.getrusage(resource.RUSAGE_SELF).ru_maxrss, resource.getrusage(resource.RUSAGE_CHILDREN).ru_maxrss)
+            usage["peak_memory_bytes"] = peak * 1024
+        except Exception:  # noqa: BLE001
+            pass
+        return usage
+
     def _update_status(self, phase: str, message: str | None = None, completed: bool = False, result_msg: ResultMessage | None = None) -> None:
+        self._mark_activity()
         payload: Dict[str, Any] = {"phase": phase}
         if message:
             payload["message"] = message
@@ -508,7 +896,8 @@ class SimpleClaudeRunner:
             payload["num_turns"] = result_msg.num_turns
             payload["session_id"] = result_msg.session_id
             payload["total_cost_usd"] = result_msg.total_cost_usd
-            payload["usage"] = result_msg.usage
+        if result_msg or completed:
+            payload["usage"] = self._resource_usage(result_msg)
       
         if completed:
             payload["completionTime"] = datetime.now(timezone.utc).isoformat()
@@ -527,7 +916,20 @@ class SimpleClaudeRunner:
             logger.warning(f"Failed to update status: {e}")
 
 
+    async def _record_first_output(self) -> None:
+        """Report when the agent first produced output, once per session."""
+        if self._first_output_reported:
+            return
+        self._first_output_reported = True
+        try:
+            await self.backend.update_session_status(self.session_name, {
+                "firstOutputTime": datetime.now(timezone.utc).strftime("%Y-%m-%dT%H:%M:%SZ"),
+            })
+        except Exception as e:
+            logger.warning(f"Failed to record first output: {e}")
+
     async def update_status_async(self, phase: str, message: str | None = None, completed: bool = False, result_msg: ResultMessage | None = None) -> None:
+        self._mark_activity()
         payload: Dict[str, Any] = {"phase": phase}
         if message:
             payload["message"] = message
@@ -539,7 +941,8 @@ class SimpleClaudeRunner:
             payload["num_turns"] = result_msg.num_turns
             payload["session_id"] = result_msg.session_id
             payload["total_cost_usd"] = result_msg.total_cost_usd
-            payload["usage"] = result_msg.usage
+        if result_msg or completed:
+            payload["usage"] = self._resource_usage(result_msg)
 
         if completed:
             payload["completionTime"] = datetime.now(timezone.utc).isoformat()
@@ -559,6 +962,7 @@ class SimpleClaudeRunner:
         async def run_with_client() -> None:
             from claude_code_sdk import (
                 query,
+                ClaudeSDKClient,
                 ClaudeCodeOptions,
                 AssistantMessage,
                 UserMessage,
@@ -572,20 +976,34 @@ class SimpleClaudeRunner:
 
             nonlocal result_message
 
-            # Allow configuring tools via env; default to common ones
-            allowed_tools_env = "Read,Write,Bash,Glob,Grep,Edit,MultiEdit,WebSearch,WebFetch"
-            allowed_tools = [t.strip() for t in allowed_tools_env.split(",") if t.strip()]
+            allowed_tools, blocked_tools = self._tool_lists()
+            gatekeeper_options = self._gatekeeper_options()
 
             options = ClaudeCodeOptions(
                 permission_mode=os.getenv("CLAUDE_PERMISSION_MODE", "acceptEdits"),
                 allowed_tools=allowed_tools if allowed_tools else None,
+                disallowed_tools=blocked_tools,
                 cwd=str(self.workdir),
                 # include_partial_messages=True, # TODO add incremental messages
+                **gatekeeper_options,
             )
 
-            stream = query(prompt=prompt, options=options)
+            # Permission callbacks need the SDK's streaming mode
+            client = None
+            if gatekeeper_options:
+                client = ClaudeSDKClient(options=options)
+                await client.connect()
+                await client.query(prompt)
+                stream = client.receive_response()
+            else:
+                stream = query(prompt=prompt, options=options)
             try:
                 async for message in stream:
+                    self._mark_activity()
+                    if isinstance(message, AssistantMessage):
+                        self._api_calls += 1
+                        self._enforce_tool_policy(message, client is not None)
+                        await self._record_first_output()
                     logger.info(f"Message: {message}")
                     if isinstance(message, StreamEvent):
                         # handle stream events
@@ -643,6 +1061,8 @@ class SimpleClaudeRunner:
                     
             except GeneratorExit:
                 logger.debug("Stream generator closed (GeneratorExit)")
+            except ToolPolicyViolation:
+                raise
             except Exception as e:  # noqa: BLE001
                 logger.error(f"Claude Code SDK streaming error: {e}")
             finally:
@@ -652,6 +1072,11 @@ class SimpleClaudeRunner:
                         await aclose()
                     except Exception as e:  # noqa: BLE001
                         logger.debug(f"Stream aclose raised: {e}")
+                if client is not None:
+                    try:
+                        await client.disconnect()
+                    except Exception as e:  # noqa: BLE001
+                        logger.debug(f"Client disconnect raised: {e}")
                         
                     
 
@@ -679,6 +1104,8 @@ class SimpleClaudeRunner:
             t.start()
             done.wait()
             if thread_error:
+                if isinstance(thread_error[0], ToolPolicyViolation):
+                    raise thread_error[0]
                 logger.error(f"Claude Code SDK streaming failed: {thread_error[0]}")
 
         # Final flush to ensure UI gets all content
@@ -693,13 +1120,15 @@ class SimpleClaudeRunner:
             self.artifacts_dir.mkdir(parents=True, exist_ok=True)
 
             self._update_status("Running", message="Initializing session")
+            self._start_heartbeat()
 
             # Update display name immediately based on the prompt
             self._set_display_name_early()
 
             # 1) Sync shared workspace from PVC (if configured)
             self._update_status("Running", message="Syncing workspace from PVC")
-            self._sync_workspace_from_pvc()
+            with tracer.span("runner.workspace.sync"):
+                self._sync_workspace_from_pvc()
 
             try:
                 self._push_workspace_deltas()
@@ -715,12 +1144,22 @@ class SimpleClaudeRunner:
                 self._update_status("Running", message="Setting up Git")
                 asyncio.run(self.git.setup_git_config())
                 self._update_status("Running", message="Cloning repositories")
-                asyncio.run(self.git.clone_repositories(self.workdir))
+                with tracer.span("runner.git.clone"):
+                    cloned = asyncio.run(self.git.clone_repositories(self.workdir))
+                    self._record_repo_bases(cloned)
             except RuntimeError:
                 # If an event loop is already running, skip async setup to avoid crash
                 pass
 
 
+            # Record the task prompt so the stored transcript starts with what was asked
+            self.messages.append({
+                "type": "user_message",
+                "content": self.prompt,
+                "timestamp": datetime.now(timezone.utc).isoformat(),
+            })
+            self._flush_messages()
+
             # Chat vs headless mode
             chat_enabled = os.getenv("INTERACTIVE", "").lower() in ("true", "1", "yes")
             if chat_enabled:
@@ -733,12 +1172,22 @@ class SimpleClaudeRunner:
 
             # 3) Headless one-shot
             self._update_status("Running", message="Claude is running")
-            result_msg = self._run_llm_streaming(self.prompt)
+            with tracer.span("runner.agent", model=os.getenv("LLM_MODEL", "")) as agent_span:
+                result_msg = self._run_llm_streaming(self.prompt)
+                if result_msg is not None:
+                    agent_span.attributes["ambient.num_turns"] = str(result_msg.num_turns)
+                    agent_span.attributes["ambient.cost_usd"] = str(result_msg.total_cost_usd or 0)
             
 
             # 4) Push entire workspace back to PVC
             self._update_status("Running", message="Pushing workspace to PVC")
-            self._push_workspace_to_pvc()
+            with tracer.span("runner.workspace.push"):
+                self._push_workspace_to_pvc()
+
+            # 5) Optional executive summary report artifact
+            if result_msg is not None:
+                self._update_status("Running", message="Generating summary report")
+                self._generate_summary_report()
 
             if result_msg is not None:
                 try:
@@ -762,23 +1211,36 @@ class SimpleClaudeRunner:
                 except Exception as e:
                     logger.warning(f"Failed to send result summary: {e}")
 
-            self._update_status("Completed", message="Session completed", completed=True, result_msg=result_msg)
+            with tracer.span("runner.report"):
+                self._upload_workspace_snapshot()
+                self._request_pull_requests(result_msg)
+                self._update_status("Completed", message="Session completed", completed=True, result_msg=result_msg)
             logger.info("Session completed successfully")
             return 0
 
         except Exception as e:
             logger.error(f"Session failed: {e}")
+            self._upload_workspace_snapshot()
             self._update_status("Failed", message=str(e), completed=True)
+            self._debug_keep_alive()
             return 1
 
 
 def main() -> None:
+    rc = 1
     try:
-        rc = SimpleClaudeRunner().run()
-        sys.exit(rc)
+        with tracer.span(
+            "runner.session",
+            **{"ambient.project": os.getenv("AGENTIC_SESSION_NAMESPACE", ""), "ambient.session": os.getenv("AGENTIC_SESSION_NAME", "")},
+        ) as session_span:
+            rc = SimpleClaudeRunner().run()
+            if rc != 0:
+                session_span.error = "session failed"
     except Exception as e:
         logger.error(f"Fatal error: {e}")
-        sys.exit(1)
+    finally:
+        tracer.flush()
+    sys.exit(rc)
 
 
 if __name__ == "__main__":
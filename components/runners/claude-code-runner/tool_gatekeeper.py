@@ -0,0 +1,90 @@
+#!/usr/bin/env python3
+
+"""
+Tool-use enforcement for the Claude Code Runner.
+Checks every tool the agent invokes against the namespace's tool policy,
+refuses disallowed ones and fails the session after repeated violations.
+"""
+
+import fnmatch
+import logging
+import os
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Tuple
+
+logger = logging.getLogger(__name__)
+
+
+class ToolPolicyViolation(Exception):
+    """Raised once the agent exceeded the allowed number of tool violations."""
+
+
+class ToolGatekeeper:
+    """Decides which tool invocations may run.
+
+    BLOCKED_TOOLS and ALLOWED_TOOLS hold tool names or globs (e.g. mcp__*) set
+    by the operator from the cluster and namespace policy; an empty allowlist
+    permits every tool that is not blocked. After MAX_TOOL_VIOLATIONS refused
+    calls the session fails; 0 only logs them.
+    """
+
+    def __init__(self, blocked: List[str], allowed: List[str], max_violations: int = 0):
+        self.blocked = blocked
+        self.allowed = allowed
+        self.max_violations = max_violations
+        self.violations: List[Dict[str, Any]] = []
+
+    @classmethod
+    def from_env(cls) -> "ToolGatekeeper":
+        def names(var: str) -> List[str]:
+            return [t.strip() for t in os.getenv(var, "").split(",") if t.strip()]
+
+        try:
+            max_violations = int(os.getenv("MAX_TOOL_VIOLATIONS", "0") or "0")
+        except ValueError:
+            max_violations = 0
+        return cls(names("BLOCKED_TOOLS"), names("ALLOWED_TOOLS"), max(max_violations, 0))
+
+    @property
+    def active(self) -> bool:
+        return bool(self.blocked or self.allowed)
+
+    def permitted(self, tool_name: str) -> Tuple[bool, str]:
+        for pattern in self.blocked:
+            if fnmatch.fnmatchcase(tool_name, pattern):
+                return False, f"tool {tool_name} is blocked by policy ({pattern})"
+        if self.allowed and not any(fnmatch.fnmatchcase(tool_name, p) for p in self.allowed):
+            return False, f"tool {tool_name} is not in the allowed tools"
+        return True, ""
+
+    @property
+    def exceeded(self) -> bool:
+        return self.max_violations > 0 and len(self.violations) >= self.max_violations
+
+    def inspect(self, tool_name: str, tool_input: Dict[str, Any] | None = None) -> bool:
+        """Record a violation when tool_name is not permitted; returns whether it is."""
+        ok, reason = self.permitted(tool_name)
+        if ok:
+            return True
+        self.violations.append({
+            "tool": tool_name,
+            "reason": reason,
+            "timestamp": datetime.now(timezone.utc).isoformat(),
+        })
+        limit = f"/{self.max_violations}" if self.max_violations > 0 else ""
+        logger.warning(f"Tool policy violation {len(self.violations)}{limit}: {reason}; input keys: {sorted((tool_input or {}).keys())}")
+        return False
+
+    def check(self) -> None:
+        if self.exceeded:
+            raise ToolPolicyViolation(f"Session stopped after {len(self.violations)} calls to disallowed tools (last: {self.violations[-1]['reason']})")
+
+    async def can_use_tool(self, tool_name: str, tool_input: Dict[str, Any], context: Any = None) -> Any:
+        """Permission callback for the Claude Code SDK: denies disallowed tools
+        and interrupts the agent once the violation limit is reached."""
+        from claude_code_sdk import PermissionResultAllow, PermissionResultDeny
+
+        if self.inspect(tool_name, tool_input):
+            return PermissionResultAllow()
+        _, reason = self.permitted(tool_name)
+        return PermissionResultDeny(message=f"Refused: {reason}", interrupt=self.exceeded)
@@ -0,0 +1,122 @@
+#!/usr/bin/env python3
+
+"""
+Distributed tracing for the Claude Code Runner.
+Continues the trace the operator hands over in TRACEPARENT, records spans for
+the runner's phases and exports them over OTLP/HTTP JSON when
+OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set.
+"""
+
+import contextlib
+import logging
+import os
+import re
+import secrets
+import threading
+import time
+from typing import Any, Dict, Iterator, List
+
+import requests
+
+logger = logging.getLogger(__name__)
+
+_TRACEPARENT = re.compile(r"^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$")
+
+
+class Span:
+    def __init__(self, name: str, trace_id: str, parent_id: str, attributes: Dict[str, Any]):
+        self.name = name
+        self.trace_id = trace_id
+        self.span_id = secrets.token_hex(8)
+        self.parent_id = parent_id
+        self.attributes = {k: str(v) for k, v in attributes.items() if v not in (None, "")}
+        self.start_ns = time.time_ns()
+        self.end_ns = 0
+        self.error = ""
+
+    @property
+    def traceparent(self) -> str:
+        return f"00-{self.trace_id}-{self.span_id}-01"
+
+    def to_otlp(self) -> Dict[str, Any]:
+        out: Dict[str, Any] = {
+            "traceId": self.trace_id,
+            "spanId": self.span_id,
+            "name": self.name,
+            "kind": 1,
+            "startTimeUnixNano": str(self.start_ns),
+            "endTimeUnixNano": str(self.end_ns),
+            "attributes": [{"key": k, "value": {"stringValue": v}} for k, v in self.attributes.items()],
+            "status": {"code": 2, "message": self.error} if self.error else {"code": 1},
+        }
+        if self.parent_id:
+            out["parentSpanId"] = self.parent_id
+        return out
+
+
+class Tracer:
+    """Keeps the runner's spans on one stack; the innermost open span is the
+    parent of new spans and of the traceparent sent to the backend."""
+
+    def __init__(self) -> None:
+        self.service = os.getenv("OTEL_SERVICE_NAME", "") or "ambient-runner"
+        endpoint = os.getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+        if not endpoint and os.getenv("OTEL_EXPORTER_OTLP_ENDPOINT", ""):
+            endpoint = os.getenv("OTEL_EXPORTER_OTLP_ENDPOINT", "").rstrip("/") + "/v1/traces"
+        self.endpoint = endpoint
+        self.headers = {"Content-Type": "application/json"}
+        for kv in os.getenv("OTEL_EXPORTER_OTLP_HEADERS", "").split(","):
+            k, sep, v = kv.partition("=")
+            if sep and k.strip():
+                self.headers[k.strip()] = v.strip()
+
+        match = _TRACEPARENT.match(os.getenv("TRACEPARENT", "").strip().lower())
+        self.trace_id, self.root_parent = (match.group(1), match.group(2)) if match else (secrets.token_hex(16), "")
+        self._stack: List[Span] = []
+        self._finished: List[Dict[str, Any]] = []
+        self._lock = threading.Lock()
+
+    def traceparent(self) -> str:
+        with self._lock:
+            if self._stack:
+                return self._stack[-1].traceparent
+        return f"00-{self.trace_id}-{self.root_parent}-01" if self.root_parent else ""
+
+    @contextlib.contextmanager
+    def span(self, name: str, **attributes: Any) -> Iterator[Span]:
+        with self._lock:
+            parent = self._stack[-1].span_id if self._stack else self.root_parent
+            s = Span(name, self.trace_id, parent, attributes)
+            self._stack.append(s)
+        try:
+            yield s
+        except BaseException as e:
+            s.error = str(e) or type(e).__name__
+            raise
+        finally:
+            s.end_ns = time.time_ns()
+            with self._lock:
+                if s in self._stack:
+                    self._stack.remove(s)
+                self._finished.append(s.to_otlp())
+
+    def flush(self) -> None:
+        with self._lock:
+            batch, self._finished = self._finished, []
+        if not self.endpoint or not batch:
+            return
+        body = {
+            "resourceSpans": [{
+                "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": self.service}}]},
+                "scopeSpans": [{"scope": {"name": "ambient-code"}, "spans": batch}],
+            }]
+        }
+        try:
+            resp = requests.post(self.endpoint, headers=self.headers, json=body, timeout=10)
+            if resp.status_code >= 300:
+                logger.warning(f"Trace collector rejected {len(batch)} spans: HTTP {resp.status_code}")
+        except Exception as e:  # noqa: BLE001
+            logger.warning(f"Failed to export {len(batch)} spans: {e}")
+
+
+tracer = Tracer()
@@ -0,0 +1,3 @@
+module ambient-code-shared
+
+go 1.24.0
@@ -0,0 +1,218 @@
+// Package money represents US dollar amounts exactly, as integer millionths of
+// a dollar, so budgets and spend sum without floating point drift. Model costs
+// are reported with up to six decimals, which this keeps.
+//
+// It lives in the shared module so the backend and the operator, which build
+// from separate modules, use the same code.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// USD is an amount in millionths of a dollar
+type USD int64
+
+// Scale is the number of USD units per dollar
+const Scale = 1_000_000
+
+const decimals = 6
+
+// exponentNumber is a JSON number with an exponent; maxExponent bounds it
+var exponentNumber = regexp.MustCompile(`^-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?[eE]([+-]?[0-9]+)$`)
+
+const maxExponent = 64
+
+// Parse reads a plain decimal amount such as "12", "12.5" or "-0.000125".
+// Exponents, currency symbols, separators, whitespace and more than six
+// decimals are rejected.
+func Parse(s string) (USD, error) {
+	in := s
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || !digitsOnly(whole) || (hasPoint && (frac == "" || !digitsOnly(frac))) {
+		return 0, fmt.Errorf("invalid amount %q", in)
+	}
+	if len(frac) > decimals {
+		return 0, fmt.Errorf("invalid amount %q: more than %d decimals", in, decimals)
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	f, _ := strconv.ParseInt(frac+strings.Repeat("0", decimals-len(frac)), 10, 64)
+	if err != nil || w > math.MaxInt64/Scale || (w == math.MaxInt64/Scale && f > math.MaxInt64%Scale) {
+		return 0, fmt.Errorf("invalid amount %q: out of range", in)
+	}
+	v := USD(w*Scale + f)
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FromFloat converts a reported cost, rounding half away from zero to the
+// nearest millionth; NaN, infinities and out of range values yield 0
+func FromFloat(f float64) USD {
+	v := math.Round(f * Scale)
+	if math.IsNaN(v) || math.Abs(v) >= math.MaxInt64 {
+		return 0
+	}
+	return USD(v)
+}
+
+// FromSpec converts a JSON value decoded from a custom resource: an int64 or
+// float64 number, or a string for Parse. ok is false for other types and
+// strings that do not parse.
+func FromSpec(raw interface{}) (USD, bool) {
+	switch n := raw.(type) {
+	case int64:
+		if n > math.MaxInt64/Scale || n < math.MinInt64/Scale {
+			return 0, false
+		}
+		return USD(n * Scale), true
+	case int:
+		return FromSpec(int64(n))
+	case float64:
+		if math.IsNaN(n) || math.Abs(n*Scale) >= math.MaxInt64 {
+			return 0, false
+		}
+		return FromFloat(n), true
+	case json.Number:
+		return FromSpec(n.String())
+	case string:
+		v, err := Parse(n)
+		return v, err == nil
+	}
+	return 0, false
+}
+
+// Float64 is the amount in dollars, for metrics and JSON numbers
+func (a USD) Float64() float64 {
+	return float64(a) / Scale
+}
+
+// Percent returns p percent of a, rounded half away from zero
+func (a USD) Percent(p int64) USD {
+	return a.MulDiv(p, 100)
+}
+
+// MulDiv returns a*n/d rounded half away from zero; the product is computed
+// exactly, so it does not overflow for large factors
+func (a USD) MulDiv(n, d int64) USD {
+	if d == 0 {
+		return 0
+	}
+	p := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(n))
+	den := big.NewInt(d)
+	q, r := new(big.Int).QuoRem(p, den, new(big.Int))
+	if new(big.Int).Lsh(new(big.Int).Abs(r), 1).Cmp(new(big.Int).Abs(den)) >= 0 {
+		if p.Sign()*den.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0
+	}
+	return USD(q.Int64())
+}
+
+// Round rounds a to places decimals (0-6), half away from zero
+func (a USD) Round(places int) USD {
+	if places >= decimals {
+		return a
+	}
+	unit := int64(math.Pow10(decimals - max(places, 0)))
+	return a.MulDiv(1, unit) * USD(unit)
+}
+
+// String formats a with at least two and at most six decimals, without
+// trailing zeros beyond the cents: "12.50", "0.000125", "-3.00"
+func (a USD) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%06d", v%Scale), "0")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	return fmt.Sprintf("%s%d.%s", sign, v/Scale, frac)
+}
+
+// Cents formats a rounded to the cent, as status fields and messages show it
+func (a USD) Cents() string {
+	return a.Round(2).String()
+}
+
+// MarshalJSON writes a as a JSON number of dollars, so API fields keep the
+// shape they had as float64
+func (a USD) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads a JSON string of dollars with Parse's rules, or a JSON
+// number, which may also use an exponent as some encoders write small
+// amounts ("1e-05")
+func (a *USD) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unq, err := strconv.Unquote(s); err == nil {
+		s = unq
+	} else if strings.ContainsAny(s, "eE") {
+		v, err := parseExponent(s)
+		if err != nil {
+			return err
+		}
+		*a = v
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// parseExponent reads a JSON number with an exponent exactly; like Parse it
+// rejects amounts finer than a millionth and out of range ones
+func parseExponent(s string) (USD, error) {
+	m := exponentNumber.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	// Bounding the exponent keeps the exact conversion cheap
+	if exp, err := strconv.Atoi(m[1]); err != nil || exp > maxExponent || exp < -maxExponent {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(Scale))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("invalid amount %q: more than %d decimals", s, decimals)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	return USD(r.Num().Int64()), nil
+}
@@ -0,0 +1,240 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    USD
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "12", want: 12 * Scale},
+		{in: "12.5", want: 12_500_000},
+		{in: "12.50", want: 12_500_000},
+		{in: "0.000125", want: 125},
+		{in: "1.234567", want: 1_234_567},
+		{in: "-3", want: -3 * Scale},
+		{in: "-0.000125", want: -125},
+		{in: "9223372036854.775807", want: math.MaxInt64},
+		{in: "-9223372036854.775807", want: -math.MaxInt64},
+		{in: "1.2345678", wantErr: true},
+		{in: "0.0000001", wantErr: true},
+		{in: "9223372036854.775808", wantErr: true},
+		{in: "9223372036855", wantErr: true},
+		{in: "-9223372036855", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1.5E-2", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "1.", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: " 1", wantErr: true},
+		{in: "$1", wantErr: true},
+		{in: "1,000", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   USD
+		want string
+	}{
+		{0, "0.00"},
+		{12_500_000, "12.50"},
+		{3 * Scale, "3.00"},
+		{-3 * Scale, "-3.00"},
+		{10_000, "0.01"},
+		{100_000, "0.10"},
+		{125, "0.000125"},
+		{-125, "-0.000125"},
+		{1_234_567, "1.234567"},
+		{1_230_000, "1.23"},
+		{math.MaxInt64, "9223372036854.775807"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("USD(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+		if back, err := Parse(tt.in.String()); err != nil || back != tt.in {
+			t.Errorf("Parse(%q) = %d, %v, want %d", tt.in.String(), back, err, tt.in)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		a    USD
+		n, d int64
+		want USD
+	}{
+		{100, 1, 3, 33},
+		{200, 1, 3, 67},
+		{150, 1, 100, 2},
+		{149, 1, 100, 1},
+		{-150, 1, 100, -2},
+		{-149, 1, 100, -1},
+		{150, -1, 100, -2},
+		{150, 1, -100, -2},
+		{-150, 1, -100, 2},
+		{5, 1, 0, 0},
+		{12 * Scale, 80, 100, 9_600_000},
+		// The product exceeds int64 but the result does not
+		{math.MaxInt64, 3, 3, math.MaxInt64},
+		{math.MaxInt64 / 2, 4, 2, math.MaxInt64 - 1},
+		// Results out of range yield 0
+		{math.MaxInt64, 2, 1, 0},
+		{math.MinInt64, 2, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.MulDiv(tt.n, tt.d); got != tt.want {
+			t.Errorf("USD(%d).MulDiv(%d, %d) = %d, want %d", int64(tt.a), tt.n, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		a      USD
+		places int
+		want   USD
+	}{
+		{1_234_567, 2, 1_230_000},
+		{1_235_000, 2, 1_240_000},
+		{1_234_999, 2, 1_230_000},
+		{-1_235_000, 2, -1_240_000},
+		{-1_234_999, 2, -1_230_000},
+		{2_500_000, 0, 3 * Scale},
+		{-2_500_000, 0, -3 * Scale},
+		{2_499_999, 0, 2 * Scale},
+		{1_234_567, 6, 1_234_567},
+		{1_234_567, 9, 1_234_567},
+		{1_600_000, -1, 2 * Scale},
+		{4_999, 2, 0},
+		{5_000, 2, 10_000},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Round(tt.places); got != tt.want {
+			t.Errorf("USD(%d).Round(%d) = %d, want %d", int64(tt.a), tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestFromSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     interface{}
+		want   USD
+		wantOK bool
+	}{
+		{"int64", int64(3), 3 * Scale, true},
+		{"negative int64", int64(-3), -3 * Scale, true},
+		{"int", 2, 2 * Scale, true},
+		{"largest int64", int64(math.MaxInt64 / Scale), math.MaxInt64 / Scale * Scale, true},
+		{"int64 overflow", int64(math.MaxInt64/Scale + 1), 0, false},
+		{"negative int64 overflow", int64(math.MinInt64/Scale - 1), 0, false},
+		{"float64", 0.1, 100_000, true},
+		{"float64 rounds half away from zero", 0.0000025, 3, true},
+		{"negative float64", -12.5, -12_500_000, true},
+		{"float64 beyond six decimals", 1.23456789, 1_234_568, true},
+		{"float64 overflow", 1e19, 0, false},
+		{"negative float64 overflow", -1e19, 0, false},
+		{"NaN", math.NaN(), 0, false},
+		{"infinity", math.Inf(1), 0, false},
+		{"string", "12.5", 12_500_000, true},
+		{"negative string", "-0.5", -500_000, true},
+		{"string beyond six decimals", "0.0000001", 0, false},
+		{"string exponent", "1e3", 0, false},
+		{"invalid string", "ten", 0, false},
+		{"json.Number", json.Number("1.25"), 1_250_000, true},
+		{"bool", true, 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := FromSpec(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: FromSpec(%v) = %d, %v, want %d, %v", tt.name, tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    USD
+		wantErr bool
+	}{
+		{in: `12.5`, want: 12_500_000},
+		{in: `"12.5"`, want: 12_500_000},
+		{in: `-0.000125`, want: -125},
+		{in: `"-3"`, want: -3 * Scale},
+		{in: `0`, want: 0},
+		{in: `1e-05`, want: 10},
+		{in: `1E-6`, want: 1},
+		{in: `1.5e2`, want: 150 * Scale},
+		{in: `-2.5E+1`, want: -25 * Scale},
+		{in: `1e-7`, wantErr: true},
+		{in: `1.2345678`, wantErr: true},
+		{in: `1e13`, wantErr: true},
+		{in: `1e300`, wantErr: true},
+		{in: `1e999999999`, wantErr: true},
+		{in: `9223372036855`, wantErr: true},
+		{in: `"1e3"`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `true`, wantErr: true},
+		{in: `1e`, wantErr: true},
+		{in: `0x1p3`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got USD
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestMarshalJSONRoundTrip(t *testing.T) {
+	in := struct {
+		Budget USD `json:"budget"`
+	}{Budget: 12_500_125}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"budget":12.500125}` {
+		t.Errorf("Marshal = %s", b)
+	}
+	var out struct {
+		Budget USD `json:"budget"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil || out != in {
+		t.Errorf("round trip = %+v, %v, want %+v", out, err, in)
+	}
+}